@@ -29,6 +29,7 @@ var (
 	promQLCommand         commands.PromQLCommand
 	pushGateway           commands.PushGatewayConfig
 	remoteReadCommand     commands.RemoteReadCommand
+	rewriteBlocksCommand  commands.RewriteBlocksCommand
 	ruleCommand           commands.RuleCommand
 	backfillCommand       commands.BackfillCommand
 	runtimeConfigCommand  commands.RuntimeConfigCommand
@@ -50,6 +51,7 @@ func main() {
 	promQLCommand.Register(app, envVars)
 	pushGateway.Register(app, envVars)
 	remoteReadCommand.Register(app, envVars)
+	rewriteBlocksCommand.Register(app, envVars)
 	ruleCommand.Register(app, envVars, prometheus.DefaultRegisterer)
 	runtimeConfigCommand.Register(app)
 
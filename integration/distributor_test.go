@@ -376,12 +376,15 @@ overrides:
 func TestDistributorRemoteWrite2(t *testing.T) {
 	queryEnd := time.Now().Round(time.Second)
 	queryStart := queryEnd.Add(-1 * time.Hour)
+	queryStep := 10 * time.Minute
 
 	testCases := map[string]struct {
 		inRemoteWrite   []*promRW2.Request
 		runtimeConfig   string
+		expectedWritten []string
 		queries         map[string]model.Matrix
 		exemplarQueries map[string][]promv1.ExemplarQueryResult
+		metadata        map[string]promv1.Metadata
 	}{
 		"no special features": {
 			inRemoteWrite: []*promRW2.Request{
@@ -395,12 +398,72 @@ func TestDistributorRemoteWrite2(t *testing.T) {
 					0,
 					nil),
 			},
+			expectedWritten: []string{"1", "0", "0"},
 			queries: map[string]model.Matrix{
 				"foobar": {{
 					Metric: model.Metric{"__name__": "foobar"},
 					Values: []model.SamplePair{{Timestamp: model.Time(queryStart.UnixMilli()), Value: model.SampleValue(100)}},
 				}},
 			},
+			metadata: map[string]promv1.Metadata{
+				"foobar": {Type: promv1.MetricTypeCounter, Help: "some help", Unit: "someunit"},
+			},
+		},
+
+		"exemplars": {
+			inRemoteWrite: []*promRW2.Request{
+				func() *promRW2.Request {
+					req := rw2.AddFloatSeries(
+						nil,
+						labels.FromStrings("__name__", "foobar_with_exemplars"),
+						[]promRW2.Sample{{Timestamp: queryStart.UnixMilli(), Value: 100}},
+						promRW2.Metadata_METRIC_TYPE_GAUGE,
+						"",
+						"",
+						0,
+						nil)
+					req.Symbols = append(req.Symbols, "traceID", "123")
+					req.Timeseries[0].Exemplars = []promRW2.Exemplar{{
+						LabelsRefs: []uint32{uint32(len(req.Symbols) - 2), uint32(len(req.Symbols) - 1)},
+						Value:      123,
+						Timestamp:  queryStart.UnixMilli(),
+					}}
+					return req
+				}(),
+			},
+			expectedWritten: []string{"1", "0", "1"},
+			exemplarQueries: map[string][]promv1.ExemplarQueryResult{
+				"foobar_with_exemplars": {{
+					SeriesLabels: model.LabelSet{"__name__": "foobar_with_exemplars"},
+					Exemplars: []promv1.Exemplar{{
+						Labels:    model.LabelSet{"traceID": "123"},
+						Value:     123,
+						Timestamp: model.Time(queryStart.UnixMilli()),
+					}},
+				}},
+			},
+		},
+
+		"created timestamp": {
+			inRemoteWrite: []*promRW2.Request{
+				rw2.AddFloatSeries(
+					nil,
+					labels.FromStrings("__name__", "counter_with_created_timestamp"),
+					[]promRW2.Sample{{Timestamp: queryStart.Add(time.Minute).UnixMilli(), Value: 5}},
+					promRW2.Metadata_METRIC_TYPE_COUNTER,
+					"",
+					"",
+					queryStart.UnixMilli(),
+					nil),
+			},
+			runtimeConfig:   fmt.Sprintf("overrides:\n  \"%s\":\n    remote_write2_created_timestamp_zero_ingestion_enabled: true\n", userID),
+			expectedWritten: []string{"1", "0", "0"},
+			queries: map[string]model.Matrix{
+				"counter_with_created_timestamp": {{
+					Metric: model.Metric{"__name__": "counter_with_created_timestamp"},
+					Values: []model.SamplePair{{Timestamp: model.Time(queryStart.UnixMilli()), Value: model.SampleValue(0)}},
+				}},
+			},
 		},
 	}
 
@@ -490,10 +553,34 @@ func TestDistributorRemoteWrite2(t *testing.T) {
 
 				res, err := client.PushRW2(ser)
 				require.NoError(t, err)
-				require.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+				require.Equal(t, http.StatusOK, res.StatusCode, res.Status)
+				require.Equal(t, tc.expectedWritten, []string{
+					res.Header.Get("X-Prometheus-Remote-Write-Samples-Written"),
+					res.Header.Get("X-Prometheus-Remote-Write-Histograms-Written"),
+					res.Header.Get("X-Prometheus-Remote-Write-Exemplars-Written"),
+				})
+			}
+
+			for q, res := range tc.queries {
+				result, err := client.QueryRange(q, queryStart, queryEnd, queryStep)
+				require.NoError(t, err)
+
+				require.Equal(t, res.String(), result.String())
+			}
+
+			for q, expResult := range tc.exemplarQueries {
+				result, err := client.QueryExemplars(q, queryStart, queryEnd)
+				require.NoError(t, err)
+
+				require.Equal(t, expResult, result)
 			}
 
-			// Placeholder for actual query tests.
+			for metric, expMetadata := range tc.metadata {
+				result, err := client.Metadata(metric)
+				require.NoError(t, err)
+
+				require.Equal(t, map[string][]promv1.Metadata{metric: {expMetadata}}, result)
+			}
 		})
 	}
 }
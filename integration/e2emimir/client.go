@@ -493,6 +493,14 @@ func (c *Client) LabelNames(start, end time.Time, matches []string, opts ...prom
 	return result, err
 }
 
+// Metadata gets the metadata of the metric, or of all metrics if metric is empty.
+func (c *Client) Metadata(metric string) (map[string][]promv1.Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	return c.querierClient.Metadata(ctx, metric, "")
+}
+
 // LabelNamesAndValues returns distinct label values per label name.
 func (c *Client) LabelNamesAndValues(selector string, limit int) (*api.LabelNamesCardinalityResponse, error) {
 	body := make(url.Values)
@@ -21,6 +21,7 @@ import (
 	"github.com/grafana/dskit/server"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 
 	"github.com/grafana/mimir/pkg/alertmanager"
 	"github.com/grafana/mimir/pkg/alertmanager/alertmanagerpb"
@@ -41,6 +42,7 @@ import (
 	"github.com/grafana/mimir/pkg/scheduler/schedulerpb"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
 	"github.com/grafana/mimir/pkg/util/gziphandler"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -258,27 +260,56 @@ func (a *API) RegisterRuntimeConfig(runtimeConfigHandler http.HandlerFunc, userL
 const PrometheusPushEndpoint = "/api/v1/push"
 const OTLPPushEndpoint = "/otlp/v1/metrics"
 const InfluxPushEndpoint = "/api/v1/push/influx/write"
+const GraphitePushEndpoint = "/api/v1/push/graphite"
+const DatadogPushEndpoint = "/api/v1/push/datadog"
 
 // RegisterDistributor registers the endpoints associated with the distributor.
 func (a *API) RegisterDistributor(d *distributor.Distributor, pushConfig distributor.Config, reg prometheus.Registerer, limits *validation.Overrides) {
 	distributorpb.RegisterDistributorServer(a.server.GRPC, d)
 
-	a.RegisterRoute(PrometheusPushEndpoint, distributor.Handler(
+	// The source of push requests is added to their context for the ingestion pipeline.
+	a.RegisterRoute(PrometheusPushEndpoint, distributor.PushSourceHandler(a.sourceIPs, distributor.Handler(
 		pushConfig.MaxRecvMsgSize, d.RequestBufferPool, a.sourceIPs, a.cfg.SkipLabelNameValidationHeader,
 		a.cfg.SkipLabelCountValidationHeader, limits, pushConfig.RetryConfig, d.PushWithMiddlewares, d.PushMetrics, a.logger,
-	), true, false, "POST")
+	)), true, false, "POST")
 
 	if pushConfig.EnableInfluxEndpoint {
 		// The Influx Push endpoint is experimental.
-		a.RegisterRoute(InfluxPushEndpoint, distributor.InfluxHandler(
+		a.RegisterRoute(InfluxPushEndpoint, distributor.PushSourceHandler(a.sourceIPs, distributor.InfluxHandler(
 			pushConfig.MaxInfluxRequestSize, d.RequestBufferPool, a.sourceIPs, pushConfig.RetryConfig, d.PushWithMiddlewares, d.PushMetrics, a.logger,
-		), true, false, "POST")
+		)), true, false, "POST")
 	}
 
-	a.RegisterRoute(OTLPPushEndpoint, distributor.OTLPHandler(
+	if pushConfig.Graphite.EndpointEnabled {
+		// The Graphite Push endpoint is experimental.
+		a.RegisterRoute(GraphitePushEndpoint, distributor.PushSourceHandler(a.sourceIPs, distributor.GraphiteHandler(
+			pushConfig.Graphite.MaxRequestSize, a.sourceIPs, limits, pushConfig.RetryConfig, d.PushWithMiddlewares, d.PushMetrics, a.logger,
+		)), true, false, "POST")
+	}
+
+	if pushConfig.Datadog.EndpointEnabled {
+		// The Datadog Push endpoint is experimental.
+		datadogHandler := distributor.PushSourceHandler(a.sourceIPs, distributor.DatadogHandler(
+			pushConfig.Datadog.MaxRequestSize, a.sourceIPs, pushConfig.RetryConfig, d.PushWithMiddlewares, d.PushMetrics, a.logger,
+		))
+		for _, path := range []string{distributor.DatadogSeriesV1Path, distributor.DatadogSeriesV2Path, distributor.DatadogSketchesPath} {
+			a.RegisterRoute(DatadogPushEndpoint+path, datadogHandler, true, false, "POST")
+		}
+		a.RegisterRoute(DatadogPushEndpoint+distributor.DatadogValidatePath, distributor.DatadogValidateHandler(), true, false, "GET")
+	}
+
+	a.RegisterRoute(OTLPPushEndpoint, distributor.PushSourceHandler(a.sourceIPs, distributor.OTLPHandler(
 		pushConfig.MaxOTLPRequestSize, d.RequestBufferPool, a.sourceIPs, limits, pushConfig.OTelResourceAttributePromotionConfig,
 		pushConfig.RetryConfig, pushConfig.EnableStartTimeQuietZero, d.PushWithMiddlewares, d.PushMetrics, reg, a.logger,
-	), true, false, "POST")
+	)), true, false, "POST")
+
+	if pushConfig.EnableOTLPGRPCReceiver {
+		// The OTLP gRPC receiver is experimental.
+		pmetricotlp.RegisterGRPCServer(a.server.GRPC, distributor.NewOTLPGRPCServer(
+			pushConfig.MaxOTLPRequestSize, limits, pushConfig.OTelResourceAttributePromotionConfig,
+			pushConfig.RetryConfig, pushConfig.EnableStartTimeQuietZero, d.PushWithMiddlewares, d.PushMetrics, reg, a.logger,
+		))
+	}
 
 	a.indexPage.AddLinks(defaultWeight, "Distributor", []IndexPageLink{
 		{Desc: "Ring status", Path: "/distributor/ring"},
@@ -289,6 +320,9 @@ func (a *API) RegisterDistributor(d *distributor.Distributor, pushConfig distrib
 	a.RegisterRoute("/distributor/ring", d, false, true, "GET", "POST")
 	a.RegisterRoute("/distributor/all_user_stats", http.HandlerFunc(d.AllUserStatsHandler), false, true, "GET")
 	a.RegisterRoute("/distributor/ha_tracker", d.HATracker, false, true, "GET")
+	a.RegisterRoute("/distributor/ha_tracker/failover", http.HandlerFunc(d.HATracker.FailoverHandler), false, true, "POST")
+	a.RegisterRoute("/distributor/ha_tracker/pin", http.HandlerFunc(d.HATracker.PinHandler), false, true, "POST", "DELETE")
+	a.RegisterRoute("/distributor/ha_tracker/clear", http.HandlerFunc(d.HATracker.ClearHandler), false, true, "POST")
 }
 
 // RegisterCostAttribution registers a Prometheus HTTP handler for the cost attribution metrics.
@@ -420,6 +454,12 @@ func (a *API) RegisterCompactor(c *compactor.MultitenantCompactor) {
 	a.RegisterRoute("/api/v1/upload/block/{block}/check", http.HandlerFunc(c.GetBlockUploadStateHandler), true, false, http.MethodGet)
 	a.RegisterRoute("/compactor/delete_tenant", http.HandlerFunc(c.DeleteTenant), true, true, "POST")
 	a.RegisterRoute("/compactor/delete_tenant_status", http.HandlerFunc(c.DeleteTenantStatus), true, true, "GET")
+	a.RegisterRoute("/compactor/delete_series", http.HandlerFunc(c.DeleteSeries), true, true, "POST")
+	a.RegisterRoute("/compactor/delete_series_status", http.HandlerFunc(c.DeleteSeriesStatus), true, true, "GET")
+	a.RegisterRoute("/compactor/rewrite_blocks", http.HandlerFunc(c.RewriteBlocks), true, true, "POST")
+	a.RegisterRoute("/compactor/rewrite_blocks_status", http.HandlerFunc(c.RewriteBlocksStatus), true, true, "GET")
+	a.RegisterRoute("/compactor/migrate_tenant", http.HandlerFunc(c.MigrateTenant), true, true, "POST")
+	a.RegisterRoute("/compactor/migrate_tenant_status", http.HandlerFunc(c.MigrateTenantStatus), true, true, "GET")
 	a.RegisterRoute("/compactor/tenants", http.HandlerFunc(c.TenantsHandler), false, true, "GET")
 	a.RegisterRoute("/compactor/tenant/{tenant}/planned_jobs", http.HandlerFunc(c.PlannedJobsHandler), false, true, "GET")
 }
@@ -470,6 +510,12 @@ func (a *API) RegisterQueryAPI(handler http.Handler, buildInfoHandler http.Handl
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/active_series"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/active_native_histogram_metrics"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/format_query"), handler, true, true, "GET", "POST")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/query_explain"), handler, true, true, "GET", "POST")
+}
+
+// RegisterRemoteExecution registers the gRPC service used by other queriers to evaluate expressions on this querier.
+func (a *API) RegisterRemoteExecution(s remoteexec.RemoteExecutionServer) {
+	remoteexec.RegisterRemoteExecutionServer(a.server.GRPC, s)
 }
 
 // RegisterQueryFrontendHandler registers the Prometheus routes supported by the
@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/sharding"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// rewriteGroup is a group of blocks which are rewritten together: the blocks with the same resolution, external
+// labels other than the shard ID, and time range. These are the shards of a split compacted block.
+type rewriteGroup struct {
+	key   string
+	metas []*block.Meta
+}
+
+// rewriteBlocksUser applies the block rewrite requests of a user to the blocks overlapping their time range: the
+// series of each group of blocks are relabeled with the relabel configs of the requests not applied to all the
+// blocks of the group yet, and written to new blocks sharded like the original ones. The original blocks are then
+// marked for deletion. The new blocks record the IDs of the applied requests in their meta, and have the sources of
+// all the blocks of the group, so that the original blocks get deduplicated. Blocks which are going to be compacted
+// are rewritten once they've been compacted.
+//
+// It returns the metas updated with the rewritten blocks.
+func (c *MultitenantCompactor) rewriteBlocksUser(ctx context.Context, userID string, userBucket objstore.Bucket, grouper Grouper, metas map[ulid.ULID]*block.Meta, requests []*mimir_tsdb.BlockRewriteRequest, userLogger log.Logger) (map[ulid.ULID]*block.Meta, error) {
+	if len(requests) == 0 {
+		return metas, nil
+	}
+
+	compacting, err := blocksInCompactionJobs(grouper, metas)
+	if err != nil {
+		return metas, err
+	}
+
+	metas = maps.Clone(metas)
+	for _, group := range rewriteGroups(metas) {
+		if err := ctx.Err(); err != nil {
+			return metas, err
+		}
+
+		if slices.ContainsFunc(group.metas, func(meta *block.Meta) bool { return compacting[meta.ULID] }) {
+			continue
+		}
+
+		applied := blockRewriteRequestsToApply(group.metas, requests)
+		if len(applied) == 0 {
+			continue
+		}
+		// The requests applied together share the same aggregation: the following ones are applied in the next cycles.
+		if ix := slices.IndexFunc(applied, func(req *mimir_tsdb.BlockRewriteRequest) bool { return req.Aggregation != applied[0].Aggregation }); ix > 0 {
+			applied = applied[:ix]
+		}
+
+		// Rewriting a group of blocks is owned by a single compactor, like compaction jobs.
+		first := group.metas[0]
+		job := newJob(userID, fmt.Sprintf("rewrite-%s", group.key), labelsWithoutShard(first.Thanos.Labels), first.Thanos.Downsample.Resolution, false, 0, fmt.Sprintf("%s-rewrite-%s", userID, group.key))
+		if ok, err := c.shardingStrategy.ownJob(job); err != nil {
+			level.Warn(userLogger).Log("msg", "skipped block rewrite because unable to check whether the blocks are owned by the compactor instance", "group", group.key, "err", err)
+			continue
+		} else if !ok {
+			continue
+		}
+
+		newMetas, err := c.rewriteBlocks(ctx, userID, userBucket, group.metas, applied, userLogger)
+		if errors.Is(err, block.ErrSeriesCollision) && len(applied) > 1 {
+			// The request whose relabel configs make different series collide can't be told apart when several
+			// requests are applied together, so only the oldest one is applied, and the others in the next cycles.
+			applied = applied[:1]
+			newMetas, err = c.rewriteBlocks(ctx, userID, userBucket, group.metas, applied, userLogger)
+		}
+		if errors.Is(err, block.ErrSeriesCollision) {
+			c.failBlockRewriteRequest(ctx, userID, applied[0], err, userLogger)
+		}
+		if err != nil {
+			c.blockRewriteFailed.Add(float64(len(group.metas)))
+			level.Warn(userLogger).Log("msg", "failed to rewrite blocks", "group", group.key, "blocks", len(group.metas), "err", err)
+			continue
+		}
+
+		c.blocksRewritten.Add(float64(len(group.metas)))
+		for _, meta := range group.metas {
+			delete(metas, meta.ULID)
+		}
+		for _, meta := range newMetas {
+			metas[meta.ULID] = meta
+		}
+	}
+
+	c.updateBlockRewriteRequestsStatus(ctx, userID, requests, metas, userLogger)
+	return metas, nil
+}
+
+// rewriteGroups returns the groups of blocks rewritten together, sorted by key, with the blocks of each group
+// sorted by ID.
+func rewriteGroups(metas map[ulid.ULID]*block.Meta) []rewriteGroup {
+	groups := map[string][]*block.Meta{}
+	for _, meta := range metas {
+		key := fmt.Sprintf("%s-%d-%d", defaultGroupKeyWithoutShardID(meta.Thanos), meta.MinTime, meta.MaxTime)
+		groups[key] = append(groups[key], meta)
+	}
+
+	result := make([]rewriteGroup, 0, len(groups))
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		group := groups[key]
+		slices.SortFunc(group, func(a, b *block.Meta) int { return a.ULID.Compare(b.ULID) })
+		result = append(result, rewriteGroup{key: key, metas: group})
+	}
+	return result
+}
+
+// blockRewriteRequestsToApply returns the block rewrite requests which haven't failed nor been applied to all the
+// blocks yet, whose time range overlaps the blocks.
+func blockRewriteRequestsToApply(metas []*block.Meta, requests []*mimir_tsdb.BlockRewriteRequest) []*mimir_tsdb.BlockRewriteRequest {
+	appliedToAllBlocks := appliedToAll(metas, func(meta *block.Meta) []string { return meta.Thanos.BlockRewriteRequests })
+
+	var result []*mimir_tsdb.BlockRewriteRequest
+	for _, req := range requests {
+		if req.Status == mimir_tsdb.BlockRewriteRequestFailed {
+			continue
+		}
+		// Block max time is exclusive, while request end time is inclusive.
+		if req.StartTime >= metas[0].MaxTime || req.EndTime < metas[0].MinTime {
+			continue
+		}
+		if _, applied := slices.BinarySearch(appliedToAllBlocks, req.RequestID); applied {
+			continue
+		}
+		result = append(result, req)
+	}
+	return result
+}
+
+// rewriteShardLabels returns the external labels of the blocks written when rewriting the blocks: one block per
+// shard if the blocks are the shards of a split compacted block, or a single block otherwise.
+func rewriteShardLabels(metas []*block.Meta) ([]map[string]string, error) {
+	var shardCount uint64
+	for i, meta := range metas {
+		count := uint64(0)
+		if value, ok := meta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel]; ok {
+			var err error
+			if _, count, err = sharding.ParseShardIDLabelValue(value); err != nil {
+				return nil, errors.Wrapf(err, "block %s", meta.ULID)
+			}
+		}
+		if i > 0 && count != shardCount {
+			return nil, errors.Errorf("blocks %s and %s have a different number of shards", metas[0].ULID, meta.ULID)
+		}
+		shardCount = count
+	}
+
+	if shardCount == 0 {
+		return []map[string]string{maps.Clone(metas[0].Thanos.Labels)}, nil
+	}
+
+	result := make([]map[string]string, 0, shardCount)
+	for ix := uint64(0); ix < shardCount; ix++ {
+		lbls := labelsWithoutShard(metas[0].Thanos.Labels).Map()
+		lbls[mimir_tsdb.CompactorShardIDExternalLabel] = sharding.FormatShardIDLabelValue(ix, shardCount)
+		result = append(result, lbls)
+	}
+	return result, nil
+}
+
+// rewriteBlocks relabels the series of the blocks with the relabel configs of the requests, writes them to new blocks,
+// and marks the original blocks for deletion. The requests must have the same aggregation. It returns the metas of the
+// new blocks.
+func (c *MultitenantCompactor) rewriteBlocks(ctx context.Context, userID string, userBucket objstore.Bucket, metas []*block.Meta, requests []*mimir_tsdb.BlockRewriteRequest, userLogger log.Logger) ([]*block.Meta, error) {
+	begin := time.Now()
+	dir := filepath.Join(c.compactorCfg.DataDir, "rewrite", userID)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, errors.Wrap(err, "clean block rewrite directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Error(userLogger).Log("msg", "failed to remove block rewrite directory", "path", dir, "err", err)
+		}
+	}()
+
+	shardLabels, err := rewriteShardLabels(metas)
+	if err != nil {
+		return nil, err
+	}
+
+	bdirs := make([]string, 0, len(metas))
+	for _, meta := range metas {
+		bdir := filepath.Join(dir, meta.ULID.String())
+		if err := block.Download(ctx, userLogger, userBucket, meta.ULID, bdir); err != nil {
+			return nil, errors.Wrapf(err, "download block %s", meta.ULID)
+		}
+		bdirs = append(bdirs, bdir)
+	}
+
+	// The relabel configs of the requests are applied in the order the requests have been created.
+	var relabelConfigs []*relabel.Config
+	for _, req := range requests {
+		relabelConfigs = append(relabelConfigs, req.RelabelConfigs...)
+	}
+
+	sumCollisions := requests[0].Aggregation == mimir_tsdb.BlockRewriteAggregationSum
+	ids, err := block.Relabel(ctx, userLogger, metas, bdirs, dir, relabelConfigs, sumCollisions, shardLabels)
+	if err != nil {
+		return nil, errors.Wrap(err, "relabel blocks")
+	}
+
+	// The new blocks record the applied requests, in addition to the ones applied to all the original blocks.
+	// The series deletion requests and series retention rules applied to all the original blocks have been
+	// applied to the new blocks too.
+	applied := appliedToAll(metas, func(meta *block.Meta) []string { return meta.Thanos.BlockRewriteRequests })
+	for _, req := range requests {
+		applied = append(applied, req.RequestID)
+	}
+	slices.Sort(applied)
+
+	var newMetas []*block.Meta
+	for ix, id := range ids {
+		if id == (ulid.ULID{}) {
+			continue
+		}
+		resdir := filepath.Join(dir, id.String())
+
+		newMeta, err := block.InjectThanosMeta(userLogger, resdir, block.ThanosMeta{
+			Labels:                 shardLabels[ix],
+			Downsample:             block.ThanosDownsample{Resolution: metas[0].Thanos.Downsample.Resolution},
+			Source:                 block.CompactorRewriteSource,
+			SegmentFiles:           block.GetSegmentFiles(resdir),
+			SeriesDeletionRequests: appliedToAll(metas, func(meta *block.Meta) []string { return meta.Thanos.SeriesDeletionRequests }),
+			SeriesRetentionRules:   appliedToAll(metas, func(meta *block.Meta) []string { return meta.Thanos.SeriesRetentionRules }),
+			BlockRewriteRequests:   applied,
+		}, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to finalize the block %s", id)
+		}
+
+		if err := block.VerifyBlock(ctx, userLogger, resdir, newMeta.MinTime, newMeta.MaxTime, false); err != nil {
+			return nil, errors.Wrapf(err, "invalid rewritten block %s", id)
+		}
+		newMetas = append(newMetas, newMeta)
+	}
+
+	// All the new blocks are uploaded before any original block is marked for deletion.
+	for _, newMeta := range newMetas {
+		resdir := filepath.Join(dir, newMeta.ULID.String())
+		if err := block.Upload(ctx, userLogger, userBucket, resdir, nil, objstore.WithUploadConcurrency(c.cfgProvider.CompactorMaxPerBlockUploadConcurrency(userID))); err != nil {
+			return nil, errors.Wrapf(err, "upload rewritten block %s", newMeta.ULID)
+		}
+	}
+
+	for _, meta := range metas {
+		if err := block.MarkForDeletion(ctx, userLogger, userBucket, meta.ULID, "source of block rewritten for block rewrite request", c.blocksMarkedForDeletion); err != nil {
+			return nil, errors.Wrapf(err, "mark block %s for deletion", meta.ULID)
+		}
+	}
+
+	level.Info(userLogger).Log("msg", "rewrote blocks", "blocks", len(metas), "rewritten_blocks", len(newMetas), "requests", len(requests), "duration", time.Since(begin))
+	return newMetas, nil
+}
+
+// updateBlockRewriteRequestsStatus marks the pending block rewrite requests as processed once they've been applied
+// to all blocks. A request is pending at least until twice the smallest block range has elapsed since its creation,
+// so that ingesters have uploaded the blocks with samples older than the request.
+func (c *MultitenantCompactor) updateBlockRewriteRequestsStatus(ctx context.Context, userID string, requests []*mimir_tsdb.BlockRewriteRequest, metas map[ulid.ULID]*block.Meta, userLogger log.Logger) {
+	now := time.Now()
+	gracePeriod := 2 * c.compactorCfg.BlockRanges[0]
+
+	for _, req := range requests {
+		if req.Status != mimir_tsdb.BlockRewriteRequestPending || req.CreatedTime.Time().Add(gracePeriod).After(now) {
+			continue
+		}
+
+		processed := true
+		for _, meta := range metas {
+			if slices.Contains(blockRewriteRequestsToApply([]*block.Meta{meta}, []*mimir_tsdb.BlockRewriteRequest{req}), req) {
+				processed = false
+				break
+			}
+		}
+		if !processed {
+			continue
+		}
+
+		req.Status = mimir_tsdb.BlockRewriteRequestProcessed
+		req.ProcessedTime = util.UnixSecondsFromTime(now)
+		if err := mimir_tsdb.WriteBlockRewriteRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+			level.Warn(userLogger).Log("msg", "failed to update block rewrite request status", "request_id", req.RequestID, "err", err)
+			continue
+		}
+		level.Info(userLogger).Log("msg", "block rewrite request processed", "request_id", req.RequestID)
+	}
+}
+
+// failBlockRewriteRequest marks the block rewrite request as failed, so that it's not applied to any block anymore.
+// The blocks it's been applied to already are kept.
+func (c *MultitenantCompactor) failBlockRewriteRequest(ctx context.Context, userID string, req *mimir_tsdb.BlockRewriteRequest, reqErr error, userLogger log.Logger) {
+	req.Status = mimir_tsdb.BlockRewriteRequestFailed
+	req.ProcessedTime = util.UnixSecondsFromTime(time.Now())
+	req.Error = reqErr.Error()
+	if err := mimir_tsdb.WriteBlockRewriteRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+		level.Warn(userLogger).Log("msg", "failed to update block rewrite request status", "request_id", req.RequestID, "err", err)
+		return
+	}
+	level.Warn(userLogger).Log("msg", "block rewrite request failed", "request_id", req.RequestID, "err", reqErr)
+}
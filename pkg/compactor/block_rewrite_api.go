@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/relabel"
+	"gopkg.in/yaml.v3"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// RewriteBlocksRequest is the YAML body of a block rewrite request.
+type RewriteBlocksRequest struct {
+	RelabelConfigs []*relabel.Config                  `yaml:"relabel_configs"`
+	Aggregation    mimir_tsdb.BlockRewriteAggregation `yaml:"aggregation"`
+}
+
+// RewriteBlocks creates a block rewrite request from the relabel configs and aggregation of the YAML request body, and the optional
+// start and end times, which default to the Unix epoch and the current time.
+func (c *MultitenantCompactor) RewriteBlocks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		// When Mimir is running, it uses Auth Middleware for checking X-Scope-OrgID and injecting tenant into context.
+		// Auth Middleware sends http.StatusUnauthorized if X-Scope-OrgID is missing, so we do too here, for consistency.
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	query := r.URL.Query()
+	startTime, endTime := int64(0), util.TimeToMillis(now)
+	if s := query.Get("start"); s != "" {
+		if startTime, err = util.ParseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := query.Get("end"); s != "" {
+		if endTime, err = util.ParseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var body RewriteBlocksRequest
+	decoder := yaml.NewDecoder(r.Body)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&body); err != nil {
+		http.Error(w, errors.Wrap(err, "invalid request body").Error(), http.StatusBadRequest)
+		return
+	}
+
+	req, err := mimir_tsdb.NewBlockRewriteRequest(body.RelabelConfigs, body.Aggregation, startTime, endTime, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := mimir_tsdb.WriteBlockRewriteRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+		level.Error(c.logger).Log("msg", "failed to write block rewrite request", "user", userID, "err", err)
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(c.logger).Log("msg", "block rewrite request created", "user", userID, "request_id", req.RequestID, "relabel_configs", len(req.RelabelConfigs), "aggregation", req.Aggregation, "start", startTime, "end", endTime)
+
+	util.WriteJSONResponse(w, req)
+}
+
+type RewriteBlocksStatusResponse struct {
+	TenantID string                            `json:"tenant_id"`
+	Requests []*mimir_tsdb.BlockRewriteRequest `json:"requests"`
+}
+
+// RewriteBlocksStatus returns the block rewrite requests of the tenant.
+func (c *MultitenantCompactor) RewriteBlocksStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requests, err := mimir_tsdb.ReadBlockRewriteRequests(ctx, c.bucketClient, userID, c.cfgProvider, c.logger)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	util.WriteJSONResponse(w, RewriteBlocksStatusResponse{TenantID: userID, Requests: requests})
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+func TestRewriteBlocks(t *testing.T) {
+	bkt := objstore.NewInMemBucket()
+	cfg := prepareConfig(t)
+	c, _, _, _, _ := prepare(t, cfg, bkt)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	rewriteBlocks := func(ctx context.Context, query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/compactor/rewrite_blocks?"+query, strings.NewReader(body)).WithContext(ctx)
+		resp := httptest.NewRecorder()
+		c.RewriteBlocks(resp, req)
+		return resp
+	}
+
+	const body = `
+relabel_configs:
+  - regex: pod
+    action: labeldrop
+aggregation: sum
+`
+
+	// Missing tenant.
+	resp := rewriteBlocks(context.Background(), "", body)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	ctx := user.InjectOrgID(context.Background(), "fake")
+
+	for name, tc := range map[string]struct{ query, body string }{
+		"no relabel config":       {body: "relabel_configs: []"},
+		"invalid relabel config":  {body: "relabel_configs:\n  - action: replace\n"},
+		"unknown field":           {body: "unknown: true"},
+		"unsupported aggregation": {body: "relabel_configs:\n  - regex: pod\n    action: labeldrop\naggregation: avg\n"},
+		"invalid start time":      {query: "start=invalid", body: body},
+		"end before start":        {query: "start=20&end=10", body: body},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := rewriteBlocks(ctx, tc.query, tc.body)
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+	}
+
+	resp = rewriteBlocks(ctx, "start=10&end=20", body)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	created := &tsdb.BlockRewriteRequest{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), created))
+	require.Len(t, created.RelabelConfigs, 1)
+	assert.Equal(t, relabel.LabelDrop, created.RelabelConfigs[0].Action)
+	assert.Equal(t, "pod", created.RelabelConfigs[0].Regex.String())
+	assert.Equal(t, tsdb.BlockRewriteAggregationSum, created.Aggregation)
+	assert.Equal(t, int64(10000), created.StartTime)
+	assert.Equal(t, int64(20000), created.EndTime)
+	assert.Equal(t, tsdb.BlockRewriteRequestPending, created.Status)
+
+	// The request is returned by the status endpoint.
+	resp = httptest.NewRecorder()
+	c.RewriteBlocksStatus(resp, httptest.NewRequest(http.MethodGet, "/compactor/rewrite_blocks_status", nil).WithContext(ctx))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	status := RewriteBlocksStatusResponse{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, RewriteBlocksStatusResponse{TenantID: "fake", Requests: []*tsdb.BlockRewriteRequest{created}}, status)
+}
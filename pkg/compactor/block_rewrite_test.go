@@ -0,0 +1,342 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"maps"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// noJobsGrouper is a Grouper which doesn't plan any compaction job.
+type noJobsGrouper struct{}
+
+func (noJobsGrouper) Groups(map[ulid.ULID]*block.Meta) ([]*Job, error) { return nil, nil }
+
+func TestMultitenantCompactor_RewriteBlocksUser(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	bkt := objstore.NewInMemBucket()
+	userBucket := bucket.NewUserBucketClient(userID, bkt, nil)
+	c, _, _, _, _ := prepare(t, prepareConfig(t), bkt)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	appendSamples := func(lset labels.Labels, timestamps ...int64) func(*tsdb.DB) {
+		return func(db *tsdb.DB) {
+			app := db.Appender(ctx)
+			for _, ts := range timestamps {
+				_, err := app.Append(0, lset, ts, float64(ts))
+				require.NoError(t, err)
+			}
+			require.NoError(t, app.Commit())
+		}
+	}
+	const maxT = 2*time.Hour/time.Millisecond - 1
+
+	// Both blocks span the same time range, like the shards of a split compacted block.
+	id1 := createCustomTSDBBlock(t, bkt, userID, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_2"}, func(db *tsdb.DB) {
+		appendSamples(labels.FromStrings(labels.MetricName, "a", "pod", "1"), 0, 1000)(db)
+		appendSamples(labels.FromStrings(labels.MetricName, "c", "pod", "1"), int64(maxT))(db)
+	})
+	id2 := createCustomTSDBBlock(t, bkt, userID, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "2_of_2"}, func(db *tsdb.DB) {
+		appendSamples(labels.FromStrings(labels.MetricName, "d", "pod", "2"), 2000)(db)
+		appendSamples(labels.FromStrings(labels.MetricName, "b", "pod", "2"), 0, int64(maxT))(db)
+	})
+
+	metas := map[ulid.ULID]*block.Meta{}
+	for _, id := range []ulid.ULID{id1, id2} {
+		meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), userBucket, id)
+		require.NoError(t, err)
+		metas[id] = &meta
+	}
+
+	req := &mimir_tsdb.BlockRewriteRequest{
+		RequestID:      "request",
+		RelabelConfigs: []*relabel.Config{{Regex: relabel.MustNewRegexp("pod"), Action: relabel.LabelDrop}},
+		EndTime:        int64(maxT),
+		Status:         mimir_tsdb.BlockRewriteRequestPending,
+		CreatedTime:    util.UnixSecondsFromTime(time.Now().Add(-24 * time.Hour)),
+	}
+	require.NoError(t, mimir_tsdb.WriteBlockRewriteRequest(ctx, bkt, userID, nil, req))
+
+	newMetas, err := c.rewriteBlocksUser(ctx, userID, userBucket, noJobsGrouper{}, metas, []*mimir_tsdb.BlockRewriteRequest{req}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	// The original blocks are replaced by rewritten blocks with the same shards.
+	require.NotContains(t, newMetas, id1)
+	require.NotContains(t, newMetas, id2)
+	var numSeries, numSamples uint64
+	shards := map[string]bool{}
+	for _, meta := range newMetas {
+		assert.Equal(t, block.CompactorRewriteSource, meta.Thanos.Source)
+		assert.Equal(t, []string{"request"}, meta.Thanos.BlockRewriteRequests)
+		assert.ElementsMatch(t, []ulid.ULID{id1, id2}, meta.Compaction.Sources)
+		shards[meta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel]] = true
+		numSeries += meta.Stats.NumSeries
+		numSamples += meta.Stats.NumSamples
+
+		exists, err := userBucket.Exists(ctx, path.Join(meta.ULID.String(), block.MetaFilename))
+		require.NoError(t, err)
+		assert.True(t, exists)
+	}
+	assert.Subset(t, []string{"1_of_2", "2_of_2"}, slices.Collect(maps.Keys(shards)))
+	assert.Equal(t, uint64(4), numSeries)
+	assert.Equal(t, uint64(6), numSamples)
+
+	for _, id := range []ulid.ULID{id1, id2} {
+		exists, err := userBucket.Exists(ctx, path.Join(id.String(), block.DeletionMarkFilename))
+		require.NoError(t, err)
+		assert.True(t, exists)
+	}
+
+	// The request has been applied to all blocks.
+	requests, err := mimir_tsdb.ReadBlockRewriteRequests(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, mimir_tsdb.BlockRewriteRequestProcessed, requests[0].Status)
+
+	// The rewritten blocks aren't rewritten again.
+	again, err := c.rewriteBlocksUser(ctx, userID, userBucket, noJobsGrouper{}, newMetas, requests, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, newMetas, again)
+}
+
+func TestMultitenantCompactor_RewriteBlocksUser_SeriesCollision(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	bkt := objstore.NewInMemBucket()
+	userBucket := bucket.NewUserBucketClient(userID, bkt, nil)
+	c, _, _, _, _ := prepare(t, prepareConfig(t), bkt)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	const maxT = 2*time.Hour/time.Millisecond - 1
+	id := createCustomTSDBBlock(t, bkt, userID, nil, func(db *tsdb.DB) {
+		app := db.Appender(ctx)
+		for _, lset := range []labels.Labels{
+			labels.FromStrings(labels.MetricName, "a", "pod", "1", "zone", "z"),
+			labels.FromStrings(labels.MetricName, "a", "pod", "2", "zone", "z"),
+		} {
+			_, err := app.Append(0, lset, 0, 1)
+			require.NoError(t, err)
+		}
+		require.NoError(t, app.Commit())
+	})
+	meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), userBucket, id)
+	require.NoError(t, err)
+	metas := map[ulid.ULID]*block.Meta{id: &meta}
+
+	newRequest := func(requestID, label string) *mimir_tsdb.BlockRewriteRequest {
+		req := &mimir_tsdb.BlockRewriteRequest{
+			RequestID:      requestID,
+			RelabelConfigs: []*relabel.Config{{Regex: relabel.MustNewRegexp(label), Action: relabel.LabelDrop}},
+			EndTime:        int64(maxT),
+			Status:         mimir_tsdb.BlockRewriteRequestPending,
+			CreatedTime:    util.UnixSecondsFromTime(time.Now().Add(-24 * time.Hour)),
+		}
+		require.NoError(t, mimir_tsdb.WriteBlockRewriteRequest(ctx, bkt, userID, nil, req))
+		return req
+	}
+	// Dropping the pod label makes the series collide, while dropping the zone label doesn't.
+	dropZone := newRequest("01", "zone")
+	dropPod := newRequest("02", "pod")
+
+	// The requests applied together make the series collide, so only the oldest one is applied.
+	newMetas, err := c.rewriteBlocksUser(ctx, userID, userBucket, noJobsGrouper{}, metas, []*mimir_tsdb.BlockRewriteRequest{dropZone, dropPod}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NotContains(t, newMetas, id)
+	require.Len(t, newMetas, 1)
+	for _, newMeta := range newMetas {
+		assert.Equal(t, []string{"01"}, newMeta.Thanos.BlockRewriteRequests)
+		assert.Equal(t, uint64(2), newMeta.Stats.NumSeries)
+	}
+
+	// The request making the series collide fails, and the blocks are kept.
+	requests, err := mimir_tsdb.ReadBlockRewriteRequests(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	again, err := c.rewriteBlocksUser(ctx, userID, userBucket, noJobsGrouper{}, newMetas, requests, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, newMetas, again)
+
+	requests, err = mimir_tsdb.ReadBlockRewriteRequests(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, mimir_tsdb.BlockRewriteRequestProcessed, requests[0].Status)
+	assert.Equal(t, mimir_tsdb.BlockRewriteRequestFailed, requests[1].Status)
+	assert.Contains(t, requests[1].Error, block.ErrSeriesCollision.Error())
+}
+
+func TestMultitenantCompactor_RewriteBlocksUser_SumCollisions(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	bkt := objstore.NewInMemBucket()
+	userBucket := bucket.NewUserBucketClient(userID, bkt, nil)
+	c, _, _, _, _ := prepare(t, prepareConfig(t), bkt)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	const maxT = 2*time.Hour/time.Millisecond - 1
+	id := createCustomTSDBBlock(t, bkt, userID, nil, func(db *tsdb.DB) {
+		app := db.Appender(ctx)
+		for i, lset := range []labels.Labels{
+			labels.FromStrings(labels.MetricName, "a", "pod", "1", "zone", "z"),
+			labels.FromStrings(labels.MetricName, "a", "pod", "2", "zone", "z"),
+		} {
+			_, err := app.Append(0, lset, 0, float64(i+1))
+			require.NoError(t, err)
+		}
+		require.NoError(t, app.Commit())
+	})
+	meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), userBucket, id)
+	require.NoError(t, err)
+	metas := map[ulid.ULID]*block.Meta{id: &meta}
+
+	newRequest := func(requestID, label string, aggregation mimir_tsdb.BlockRewriteAggregation) *mimir_tsdb.BlockRewriteRequest {
+		req := &mimir_tsdb.BlockRewriteRequest{
+			RequestID:      requestID,
+			RelabelConfigs: []*relabel.Config{{Regex: relabel.MustNewRegexp(label), Action: relabel.LabelDrop}},
+			Aggregation:    aggregation,
+			EndTime:        int64(maxT),
+			Status:         mimir_tsdb.BlockRewriteRequestPending,
+			CreatedTime:    util.UnixSecondsFromTime(time.Now().Add(-24 * time.Hour)),
+		}
+		require.NoError(t, mimir_tsdb.WriteBlockRewriteRequest(ctx, bkt, userID, nil, req))
+		return req
+	}
+	dropZone := newRequest("01", "zone", mimir_tsdb.BlockRewriteAggregationNone)
+	sumPods := newRequest("02", "pod", mimir_tsdb.BlockRewriteAggregationSum)
+
+	// The requests with a different aggregation aren't applied together.
+	newMetas, err := c.rewriteBlocksUser(ctx, userID, userBucket, noJobsGrouper{}, metas, []*mimir_tsdb.BlockRewriteRequest{dropZone, sumPods}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, newMetas, 1)
+	for _, newMeta := range newMetas {
+		assert.Equal(t, []string{"01"}, newMeta.Thanos.BlockRewriteRequests)
+		assert.Equal(t, uint64(2), newMeta.Stats.NumSeries)
+	}
+
+	// The series colliding once the pod label is dropped are summed.
+	newMetas, err = c.rewriteBlocksUser(ctx, userID, userBucket, noJobsGrouper{}, newMetas, []*mimir_tsdb.BlockRewriteRequest{dropZone, sumPods}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, newMetas, 1)
+	for _, newMeta := range newMetas {
+		assert.Equal(t, []string{"01", "02"}, newMeta.Thanos.BlockRewriteRequests)
+		assert.Equal(t, uint64(1), newMeta.Stats.NumSeries)
+		assert.Equal(t, uint64(1), newMeta.Stats.NumSamples)
+	}
+
+	requests, err := mimir_tsdb.ReadBlockRewriteRequests(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, mimir_tsdb.BlockRewriteRequestProcessed, requests[0].Status)
+	assert.Equal(t, mimir_tsdb.BlockRewriteRequestProcessed, requests[1].Status)
+}
+
+func TestRewriteGroups(t *testing.T) {
+	newMeta := func(id uint64, minTime, maxTime int64, lbls map[string]string) *block.Meta {
+		return &block.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(id, nil), MinTime: minTime, MaxTime: maxTime},
+			Thanos:    block.ThanosMeta{Labels: lbls},
+		}
+	}
+
+	var (
+		shard1    = newMeta(1, 0, 100, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_2"})
+		shard2    = newMeta(2, 0, 100, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "2_of_2"})
+		nextRange = newMeta(3, 100, 200, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_2"})
+		unsharded = newMeta(4, 100, 200, nil)
+		other     = newMeta(5, 0, 100, map[string]string{"key": "value"})
+	)
+
+	groups := rewriteGroups(map[ulid.ULID]*block.Meta{
+		shard2.ULID: shard2, shard1.ULID: shard1, nextRange.ULID: nextRange, unsharded.ULID: unsharded, other.ULID: other,
+	})
+
+	var actual [][]*block.Meta
+	for _, group := range groups {
+		actual = append(actual, group.metas)
+	}
+	assert.ElementsMatch(t, [][]*block.Meta{{shard1, shard2}, {nextRange, unsharded}, {other}}, actual)
+}
+
+func TestBlockRewriteRequestsToApply(t *testing.T) {
+	metas := []*block.Meta{
+		{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(1, nil), MinTime: 100, MaxTime: 200}, Thanos: block.ThanosMeta{BlockRewriteRequests: []string{"applied", "partially-applied"}}},
+		{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(2, nil), MinTime: 100, MaxTime: 200}, Thanos: block.ThanosMeta{BlockRewriteRequests: []string{"applied"}}},
+	}
+
+	var (
+		before           = &mimir_tsdb.BlockRewriteRequest{RequestID: "before", StartTime: 0, EndTime: 99}
+		overlapMin       = &mimir_tsdb.BlockRewriteRequest{RequestID: "overlap-min", StartTime: 0, EndTime: 100}
+		overlapMax       = &mimir_tsdb.BlockRewriteRequest{RequestID: "overlap-max", StartTime: 199, EndTime: 300}
+		after            = &mimir_tsdb.BlockRewriteRequest{RequestID: "after", StartTime: 200, EndTime: 300}
+		applied          = &mimir_tsdb.BlockRewriteRequest{RequestID: "applied", StartTime: 0, EndTime: 300}
+		partiallyApplied = &mimir_tsdb.BlockRewriteRequest{RequestID: "partially-applied", StartTime: 0, EndTime: 300}
+		failed           = &mimir_tsdb.BlockRewriteRequest{RequestID: "failed", StartTime: 0, EndTime: 300, Status: mimir_tsdb.BlockRewriteRequestFailed}
+	)
+
+	// Requests which haven't been applied to all the blocks are applied again, unless they've failed.
+	assert.Equal(t,
+		[]*mimir_tsdb.BlockRewriteRequest{overlapMin, overlapMax, partiallyApplied},
+		blockRewriteRequestsToApply(metas, []*mimir_tsdb.BlockRewriteRequest{before, overlapMin, overlapMax, after, applied, partiallyApplied, failed}),
+	)
+}
+
+func TestRewriteShardLabels(t *testing.T) {
+	newMeta := func(lbls map[string]string) *block.Meta {
+		return &block.Meta{Thanos: block.ThanosMeta{Labels: lbls}}
+	}
+
+	t.Run("unsharded blocks", func(t *testing.T) {
+		actual, err := rewriteShardLabels([]*block.Meta{newMeta(map[string]string{"key": "value"}), newMeta(map[string]string{"key": "value"})})
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]string{{"key": "value"}}, actual)
+	})
+
+	t.Run("sharded blocks", func(t *testing.T) {
+		// The layout is kept even when some shards have no block.
+		actual, err := rewriteShardLabels([]*block.Meta{newMeta(map[string]string{"key": "value", mimir_tsdb.CompactorShardIDExternalLabel: "2_of_3"})})
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]string{
+			{"key": "value", mimir_tsdb.CompactorShardIDExternalLabel: "1_of_3"},
+			{"key": "value", mimir_tsdb.CompactorShardIDExternalLabel: "2_of_3"},
+			{"key": "value", mimir_tsdb.CompactorShardIDExternalLabel: "3_of_3"},
+		}, actual)
+	})
+
+	t.Run("different number of shards", func(t *testing.T) {
+		_, err := rewriteShardLabels([]*block.Meta{
+			newMeta(map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_2"}),
+			newMeta(map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_3"}),
+		})
+		require.Error(t, err)
+
+		_, err = rewriteShardLabels([]*block.Meta{
+			newMeta(map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_2"}),
+			newMeta(nil),
+		})
+		require.Error(t, err)
+	})
+}
@@ -237,7 +237,7 @@ func (c *MultitenantCompactor) createBlockUpload(ctx context.Context, meta *bloc
 	blockMaxTime := timestamp.Time(meta.MaxTime)
 
 	// validate data is within the retention period
-	retention := c.cfgProvider.CompactorBlocksRetentionPeriod(tenantID)
+	retention := c.cfgProvider.CompactorBlocksRetentionPeriodForResolution(tenantID, block.ResolutionRaw)
 	if retention > 0 {
 		threshold := time.Now().Add(-retention)
 		if blockMaxTime.Before(threshold) {
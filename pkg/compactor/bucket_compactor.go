@@ -413,6 +413,11 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 			Downsample:   block.ThanosDownsample{Resolution: job.Resolution()},
 			Source:       block.CompactorSource,
 			SegmentFiles: block.GetSegmentFiles(bdir),
+			// The series deletion requests, series retention rules and block rewrite requests applied to all the
+			// source blocks have been applied to the compacted block too.
+			SeriesDeletionRequests: appliedToAll(toCompact, func(meta *block.Meta) []string { return meta.Thanos.SeriesDeletionRequests }),
+			SeriesRetentionRules:   appliedToAll(toCompact, func(meta *block.Meta) []string { return meta.Thanos.SeriesRetentionRules }),
+			BlockRewriteRequests:   appliedToAll(toCompact, func(meta *block.Meta) []string { return meta.Thanos.BlockRewriteRequests }),
 		}, nil)
 
 		if err != nil {
@@ -26,6 +26,7 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid/v2"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -38,6 +39,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/util"
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
@@ -217,6 +219,17 @@ type ConfigProvider interface {
 	// CompactorBlocksRetentionPeriod returns the retention period for a given user.
 	CompactorBlocksRetentionPeriod(user string) time.Duration
 
+	// CompactorBlocksRetentionPeriodForResolution returns the retention period for a given user of the blocks
+	// with the given downsampling resolution.
+	CompactorBlocksRetentionPeriodForResolution(user string, resolution int64) time.Duration
+
+	// CompactorSeriesRetentionRules returns the retention periods applied to the series of a given user matching
+	// a series selector.
+	CompactorSeriesRetentionRules(userID string) []*validation.SeriesRetentionRule
+
+	// CompactorDownsamplingEnabled returns whether the blocks of a given user are downsampled.
+	CompactorDownsamplingEnabled(userID string) bool
+
 	// CompactorSplitAndMergeShards returns the number of shards to use when splitting blocks.
 	CompactorSplitAndMergeShards(userID string) int
 
@@ -304,6 +317,22 @@ type MultitenantCompactor struct {
 	compactionRunInterval          prometheus.Gauge
 	blocksMarkedForDeletion        prometheus.Counter
 
+	// Downsampling metrics.
+	blocksDownsampled        *prometheus.CounterVec
+	blocksDownsamplingFailed prometheus.Counter
+
+	// Series deletion metrics.
+	blocksRewrittenForSeriesDeletion prometheus.Counter
+	seriesDeletionFailed             prometheus.Counter
+
+	// Block rewrite metrics.
+	blocksRewritten    prometheus.Counter
+	blockRewriteFailed prometheus.Counter
+
+	// Tenant migration metrics.
+	blocksMigrated        prometheus.Counter
+	tenantMigrationFailed prometheus.Counter
+
 	// outOfSpace is a separate metric for out-of-space errors because this is a common issue which often requires an operator to investigate,
 	// so alerts need to be able to treat it with higher priority than other compaction errors.
 	outOfSpace prometheus.Counter
@@ -420,6 +449,38 @@ func newMultitenantCompactor(
 			Help:        blocksMarkedForDeletionHelp,
 			ConstLabels: prometheus.Labels{"reason": "compaction"},
 		}),
+		blocksDownsampled: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_downsampled_total",
+			Help: "Total number of downsampled blocks created by the compactor.",
+		}, []string{"resolution"}),
+		blocksDownsamplingFailed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_downsampling_failed_total",
+			Help: "Total number of blocks which failed to be downsampled.",
+		}),
+		blocksRewrittenForSeriesDeletion: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_series_deletion_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten by the compactor to purge the samples of series deletion requests.",
+		}),
+		seriesDeletionFailed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_series_deletion_blocks_failed_total",
+			Help: "Total number of blocks which failed to be rewritten to purge the samples of series deletion requests.",
+		}),
+		blocksRewritten: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_block_rewrite_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten by the compactor to apply the relabel configs of block rewrite requests.",
+		}),
+		blockRewriteFailed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_block_rewrite_blocks_failed_total",
+			Help: "Total number of blocks which failed to be rewritten to apply the relabel configs of block rewrite requests.",
+		}),
+		blocksMigrated: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_tenant_migration_blocks_migrated_total",
+			Help: "Total number of blocks migrated by the compactor from a source tenant to a destination tenant.",
+		}),
+		tenantMigrationFailed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_tenant_migration_failed_total",
+			Help: "Total number of tenant migration requests which failed to be processed.",
+		}),
 		blockUploadBlocks: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
 			Name: "cortex_block_upload_api_blocks_total",
 			Help: "Total number of blocks successfully uploaded and validated using the block upload API.",
@@ -831,10 +892,12 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		return errors.Wrap(err, "failed to create syncer")
 	}
 
+	grouper := c.blocksGrouperFactory(ctx, c.compactorCfg, c.cfgProvider, userID, userLogger, reg)
+
 	compactor, err := NewBucketCompactor(
 		userLogger,
 		syncer,
-		c.blocksGrouperFactory(ctx, c.compactorCfg, c.cfgProvider, userID, userLogger, reg),
+		grouper,
 		c.blocksPlanner,
 		c.blocksCompactor,
 		path.Join(c.compactorCfg.DataDir, "compact"),
@@ -859,6 +922,33 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		return errors.Wrap(err, "compaction")
 	}
 
+	// The requests of all kinds are listed once per compaction of the user.
+	requests, err := mimir_tsdb.ReadTenantRequests(ctx, c.bucketClient, userID, c.cfgProvider, userLogger)
+	if err != nil {
+		return errors.Wrap(err, "read requests")
+	}
+
+	metas, err := c.deleteSeriesUser(ctx, userID, userBucket, grouper, syncer.Metas(), requests.SeriesDeletions, userLogger)
+	if err != nil {
+		return errors.Wrap(err, "series deletion")
+	}
+
+	metas, err = c.rewriteBlocksUser(ctx, userID, userBucket, grouper, metas, requests.BlockRewrites, userLogger)
+	if err != nil {
+		return errors.Wrap(err, "block rewrite")
+	}
+
+	metas, err = c.migrateTenantUser(ctx, userID, userBucket, metaCache, metas, requests.TenantMigrations, userLogger)
+	if err != nil {
+		return errors.Wrap(err, "tenant migration")
+	}
+
+	if c.cfgProvider.CompactorDownsamplingEnabled(userID) {
+		if err := c.downsampleUser(ctx, userID, userBucket, grouper, metas, userLogger); err != nil {
+			return errors.Wrap(err, "downsampling")
+		}
+	}
+
 	if metaCache != nil {
 		items, size, hits, misses := metaCache.Stats()
 		level.Info(userLogger).Log("msg", "per-user meta cache stats after compacting user", "items", items, "bytes_size", size, "hits", hits, "misses", misses)
@@ -866,6 +956,22 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 	return nil
 }
 
+// blocksInCompactionJobs returns the IDs of the blocks which are part of a compaction job.
+func blocksInCompactionJobs(grouper Grouper, metas map[ulid.ULID]*block.Meta) (map[ulid.ULID]bool, error) {
+	jobs, err := grouper.Groups(metas)
+	if err != nil {
+		return nil, errors.Wrap(err, "build compaction jobs")
+	}
+
+	result := map[ulid.ULID]bool{}
+	for _, job := range jobs {
+		for _, id := range job.IDs() {
+			result[id] = true
+		}
+	}
+	return result, nil
+}
+
 func (c *MultitenantCompactor) discoverUsersWithRetries(ctx context.Context) ([]string, error) {
 	var lastErr error
 
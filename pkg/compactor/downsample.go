@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+// downsampleUser downsamples the blocks of a user which won't be compacted anymore: raw blocks are downsampled
+// to 5m resolution blocks, which are downsampled to 1h resolution blocks. Downsampled blocks have the same
+// sources as the block they've been downsampled from, so that downsampled blocks of a block which is later
+// compacted again get deduplicated once the new block is downsampled.
+func (c *MultitenantCompactor) downsampleUser(ctx context.Context, userID string, userBucket objstore.Bucket, grouper Grouper, metas map[ulid.ULID]*block.Meta, userLogger log.Logger) error {
+	// Blocks which are going to be compacted aren't downsampled yet.
+	compacting, err := blocksInCompactionJobs(grouper, metas)
+	if err != nil {
+		return err
+	}
+
+	all := slices.Collect(maps.Values(metas))
+	largestRange := c.compactorCfg.BlockRanges[len(c.compactorCfg.BlockRanges)-1].Milliseconds()
+	now := time.Now().UnixMilli()
+
+	for _, resolutions := range [][2]int64{{block.ResolutionRaw, block.Resolution5m}, {block.Resolution5m, block.Resolution1h}} {
+		from, to := resolutions[0], resolutions[1]
+
+		for _, meta := range blocksToDownsample(all, compacting, from, to, largestRange, now) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			// Downsampling a block is owned by a single compactor, like compaction jobs.
+			job := newJob(userID, fmt.Sprintf("downsample-%s", meta.ULID), labels.FromMap(meta.Thanos.Labels), from, false, 0, fmt.Sprintf("%s-downsample-%s", userID, meta.ULID))
+			if ok, err := c.shardingStrategy.ownJob(job); err != nil {
+				level.Warn(userLogger).Log("msg", "skipped downsampling because unable to check whether the block is owned by the compactor instance", "block", meta.ULID, "err", err)
+				continue
+			} else if !ok {
+				continue
+			}
+
+			newMeta, err := c.downsampleBlock(ctx, userID, userBucket, meta, to, userLogger)
+			if err != nil {
+				c.blocksDownsamplingFailed.Inc()
+				level.Warn(userLogger).Log("msg", "failed to downsample block", "block", meta.ULID, "resolution", to, "err", err)
+				continue
+			}
+
+			c.blocksDownsampled.WithLabelValues(resolutionString(to)).Inc()
+			all = append(all, newMeta)
+		}
+	}
+
+	return nil
+}
+
+// blocksToDownsample returns the blocks with resolution from which should be downsampled to resolution to.
+// Blocks are downsampled once they're not going to be compacted anymore: they span at most the largest block
+// range, whose end has passed, and they're not part of any compaction job. Blocks whose sources have all been
+// downsampled already are skipped.
+func blocksToDownsample(metas []*block.Meta, compacting map[ulid.ULID]bool, from, to, largestRange, now int64) []*block.Meta {
+	downsampled := map[ulid.ULID]bool{}
+	for _, meta := range metas {
+		if meta.Thanos.Downsample.Resolution == to {
+			for _, id := range meta.Compaction.Sources {
+				downsampled[id] = true
+			}
+		}
+	}
+
+	var result []*block.Meta
+	for _, meta := range metas {
+		if meta.Thanos.Downsample.Resolution != from || compacting[meta.ULID] {
+			continue
+		}
+
+		rangeStart := meta.MinTime - meta.MinTime%largestRange
+		if meta.MaxTime > rangeStart+largestRange || rangeStart+largestRange > now {
+			continue
+		}
+
+		if !slices.ContainsFunc(meta.Compaction.Sources, func(id ulid.ULID) bool { return !downsampled[id] }) {
+			continue
+		}
+
+		result = append(result, meta)
+	}
+
+	slices.SortFunc(result, func(a, b *block.Meta) int {
+		return a.ULID.Compare(b.ULID)
+	})
+	return result
+}
+
+func (c *MultitenantCompactor) downsampleBlock(ctx context.Context, userID string, userBucket objstore.Bucket, meta *block.Meta, resolution int64, userLogger log.Logger) (*block.Meta, error) {
+	begin := time.Now()
+	dir := filepath.Join(c.compactorCfg.DataDir, "downsample", userID)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, errors.Wrap(err, "clean downsampling directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Error(userLogger).Log("msg", "failed to remove downsampling directory", "path", dir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(dir, meta.ULID.String())
+	if err := block.Download(ctx, userLogger, userBucket, meta.ULID, bdir); err != nil {
+		return nil, errors.Wrapf(err, "download block %s", meta.ULID)
+	}
+
+	id, err := block.Downsample(ctx, userLogger, meta, bdir, dir, resolution)
+	if err != nil {
+		return nil, errors.Wrapf(err, "downsample block %s", meta.ULID)
+	}
+
+	resdir := filepath.Join(dir, id.String())
+	newMeta, err := block.ReadMetaFromDir(resdir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read meta of downsampled block %s", id)
+	}
+
+	if err := block.VerifyBlock(ctx, userLogger, resdir, newMeta.MinTime, newMeta.MaxTime, false); err != nil {
+		return nil, errors.Wrapf(err, "invalid downsampled block %s", id)
+	}
+
+	if err := block.Upload(ctx, userLogger, userBucket, resdir, nil, objstore.WithUploadConcurrency(c.cfgProvider.CompactorMaxPerBlockUploadConcurrency(userID))); err != nil {
+		return nil, errors.Wrapf(err, "upload downsampled block %s", id)
+	}
+
+	level.Info(userLogger).Log("msg", "downsampled block", "block", meta.ULID, "downsampled_block", id, "resolution", resolutionString(resolution), "duration", time.Since(begin))
+	return newMeta, nil
+}
+
+func resolutionString(resolution int64) string {
+	return model.Duration(time.Duration(resolution) * time.Millisecond).String()
+}
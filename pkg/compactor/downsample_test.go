@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+func TestBlocksToDownsample(t *testing.T) {
+	const day = int64(24 * time.Hour / time.Millisecond)
+
+	newMeta := func(id ulid.ULID, minT, maxT, resolution int64, sources ...ulid.ULID) *block.Meta {
+		if len(sources) == 0 {
+			sources = []ulid.ULID{id}
+		}
+		return &block.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: minT, MaxTime: maxT, Compaction: tsdb.BlockMetaCompaction{Sources: sources}},
+			Thanos:    block.ThanosMeta{Downsample: block.ThanosDownsample{Resolution: resolution}},
+		}
+	}
+
+	var (
+		block1 = ulid.MustNew(1, nil)
+		block2 = ulid.MustNew(2, nil)
+		block3 = ulid.MustNew(3, nil)
+		block4 = ulid.MustNew(4, nil)
+		block5 = ulid.MustNew(5, nil)
+		block6 = ulid.MustNew(6, nil)
+		block7 = ulid.MustNew(7, nil)
+	)
+
+	metas := []*block.Meta{
+		// Compacted block spanning a whole day: it's downsampled.
+		newMeta(block2, 0, day, block.ResolutionRaw),
+		newMeta(block1, day, day+2*time.Hour.Milliseconds(), block.ResolutionRaw),
+		// Block whose day hasn't ended yet: it's not downsampled.
+		newMeta(block3, 2*day, 2*day+2*time.Hour.Milliseconds(), block.ResolutionRaw),
+		// Block which has been downsampled already: it's not downsampled again.
+		newMeta(block4, 3*day, 4*day, block.ResolutionRaw),
+		newMeta(block5, 3*day, 4*day, block.Resolution5m, block4),
+		// Block spanning more than a day: it's not downsampled.
+		newMeta(block6, day/2, day+day/2, block.ResolutionRaw),
+		// Block of another resolution: it's not downsampled to 5m.
+		newMeta(block7, 4*day, 5*day, block.Resolution5m),
+	}
+
+	compacting := map[ulid.ULID]bool{block1: false, block3: false}
+	now := 2*day + time.Hour.Milliseconds()
+
+	var ids []ulid.ULID
+	for _, meta := range blocksToDownsample(metas, compacting, block.ResolutionRaw, block.Resolution5m, day, now) {
+		ids = append(ids, meta.ULID)
+	}
+	assert.Equal(t, []ulid.ULID{block1, block2}, ids)
+
+	// Blocks which are going to be compacted aren't downsampled.
+	compacting[block1] = true
+	ids = ids[:0]
+	for _, meta := range blocksToDownsample(metas, compacting, block.ResolutionRaw, block.Resolution5m, day, now) {
+		ids = append(ids, meta.ULID)
+	}
+	assert.Equal(t, []ulid.ULID{block2}, ids)
+
+	// 5m blocks are downsampled to 1h.
+	ids = ids[:0]
+	for _, meta := range blocksToDownsample(metas, compacting, block.Resolution5m, block.Resolution1h, day, 10*day) {
+		ids = append(ids, meta.ULID)
+	}
+	assert.Equal(t, []ulid.ULID{block5, block7}, ids)
+}
@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// deleteSeriesUser applies the series deletion requests and series retention rules of a user to the blocks which
+// have samples deleted by the requests or expired by the rules: each block is rewritten without the deleted samples,
+// and then marked for deletion. The rewritten block records the IDs of the applied requests and the selectors of
+// the applied rules in its meta, and keeps the sources of the original block, so that the original block gets
+// deduplicated. Blocks which are going to be compacted are rewritten once they've been compacted.
+//
+// It returns the metas updated with the rewritten blocks.
+func (c *MultitenantCompactor) deleteSeriesUser(ctx context.Context, userID string, userBucket objstore.Bucket, grouper Grouper, metas map[ulid.ULID]*block.Meta, requests []*mimir_tsdb.SeriesDeletionRequest, userLogger log.Logger) (map[ulid.ULID]*block.Meta, error) {
+	rules := c.cfgProvider.CompactorSeriesRetentionRules(userID)
+	if len(requests) == 0 && len(rules) == 0 {
+		return metas, nil
+	}
+
+	var err error
+	matchers := make(map[string][][]*labels.Matcher, len(requests))
+	for _, req := range requests {
+		if matchers[req.RequestID], err = req.Matchers(); err != nil {
+			return metas, errors.Wrapf(err, "series deletion request %s", req.RequestID)
+		}
+	}
+	rulesMatchers := make(map[string][]*labels.Matcher, len(rules))
+	for _, rule := range rules {
+		if rulesMatchers[rule.Match], err = rule.Matchers(); err != nil {
+			return metas, errors.Wrapf(err, "series retention rule %q", rule.Match)
+		}
+	}
+
+	compacting, err := blocksInCompactionJobs(grouper, metas)
+	if err != nil {
+		return metas, err
+	}
+
+	now := time.Now()
+	metas = maps.Clone(metas)
+	ids := slices.SortedFunc(maps.Keys(metas), func(a, b ulid.ULID) int { return a.Compare(b) })
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return metas, err
+		}
+
+		meta := metas[id]
+		if compacting[meta.ULID] {
+			continue
+		}
+
+		applied := seriesDeletionRequestsToApply(meta, requests)
+		appliedRules := seriesRetentionRulesToApply(meta, rules, now)
+		if len(applied) == 0 && len(appliedRules) == 0 {
+			continue
+		}
+
+		// Rewriting a block is owned by a single compactor, like compaction jobs.
+		job := newJob(userID, fmt.Sprintf("delete-%s", meta.ULID), labels.FromMap(meta.Thanos.Labels), meta.Thanos.Downsample.Resolution, false, 0, fmt.Sprintf("%s-delete-%s", userID, meta.ULID))
+		if ok, err := c.shardingStrategy.ownJob(job); err != nil {
+			level.Warn(userLogger).Log("msg", "skipped series deletion because unable to check whether the block is owned by the compactor instance", "block", meta.ULID, "err", err)
+			continue
+		} else if !ok {
+			continue
+		}
+
+		var deletions []block.SeriesDeletion
+		for _, req := range applied {
+			for _, ms := range matchers[req.RequestID] {
+				deletions = append(deletions, block.SeriesDeletion{Matchers: ms, MinTime: req.StartTime, MaxTime: req.EndTime})
+			}
+		}
+		// Rules are only applied to blocks which have ended before the retention boundary, so all the samples
+		// of the matching series are deleted.
+		for _, rule := range appliedRules {
+			deletions = append(deletions, block.SeriesDeletion{Matchers: rulesMatchers[rule.Match], MinTime: meta.MinTime, MaxTime: meta.MaxTime})
+		}
+
+		newMeta, err := c.deleteSeriesFromBlock(ctx, userID, userBucket, meta, applied, appliedRules, deletions, userLogger)
+		if err != nil {
+			c.seriesDeletionFailed.Inc()
+			level.Warn(userLogger).Log("msg", "failed to delete series from block", "block", meta.ULID, "err", err)
+			continue
+		}
+
+		c.blocksRewrittenForSeriesDeletion.Inc()
+		delete(metas, meta.ULID)
+		if newMeta != nil {
+			metas[newMeta.ULID] = newMeta
+		}
+	}
+
+	c.updateSeriesDeletionRequestsStatus(ctx, userID, requests, metas, userLogger)
+	return metas, nil
+}
+
+// seriesDeletionRequestsToApply returns the series deletion requests not applied to the block yet, whose time
+// range overlaps the block.
+func seriesDeletionRequestsToApply(meta *block.Meta, requests []*mimir_tsdb.SeriesDeletionRequest) []*mimir_tsdb.SeriesDeletionRequest {
+	var result []*mimir_tsdb.SeriesDeletionRequest
+	for _, req := range requests {
+		// Block max time is exclusive, while request end time is inclusive.
+		if req.StartTime >= meta.MaxTime || req.EndTime < meta.MinTime {
+			continue
+		}
+		if _, applied := slices.BinarySearch(meta.Thanos.SeriesDeletionRequests, req.RequestID); applied {
+			continue
+		}
+		result = append(result, req)
+	}
+	return result
+}
+
+// seriesRetentionRulesToApply returns the series retention rules not applied to the block yet, whose retention
+// boundary is after the end of the block.
+func seriesRetentionRulesToApply(meta *block.Meta, rules []*validation.SeriesRetentionRule, now time.Time) []*validation.SeriesRetentionRule {
+	var result []*validation.SeriesRetentionRule
+	for _, rule := range rules {
+		if meta.MaxTime > now.Add(-time.Duration(rule.RetentionPeriod)).UnixMilli() {
+			continue
+		}
+		if _, applied := slices.BinarySearch(meta.Thanos.SeriesRetentionRules, rule.Match); applied {
+			continue
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+// appliedToAll returns the sorted values applied to all the blocks, as returned by the applied function.
+func appliedToAll(metas []*block.Meta, applied func(meta *block.Meta) []string) []string {
+	if len(metas) == 0 {
+		return nil
+	}
+
+	result := slices.Clone(applied(metas[0]))
+	for _, meta := range metas[1:] {
+		result = slices.DeleteFunc(result, func(value string) bool {
+			_, found := slices.BinarySearch(applied(meta), value)
+			return !found
+		})
+	}
+	return result
+}
+
+// deleteSeriesFromBlock rewrites the block without the deleted samples, and marks the original block for deletion.
+// It returns the meta of the rewritten block, or nil if all the samples of the block have been deleted.
+func (c *MultitenantCompactor) deleteSeriesFromBlock(ctx context.Context, userID string, userBucket objstore.Bucket, meta *block.Meta, requests []*mimir_tsdb.SeriesDeletionRequest, rules []*validation.SeriesRetentionRule, deletions []block.SeriesDeletion, userLogger log.Logger) (*block.Meta, error) {
+	begin := time.Now()
+	dir := filepath.Join(c.compactorCfg.DataDir, "delete", userID)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, errors.Wrap(err, "clean series deletion directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Error(userLogger).Log("msg", "failed to remove series deletion directory", "path", dir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(dir, meta.ULID.String())
+	if err := block.Download(ctx, userLogger, userBucket, meta.ULID, bdir); err != nil {
+		return nil, errors.Wrapf(err, "download block %s", meta.ULID)
+	}
+
+	// The rewritten block records the applied requests and rules, in addition to the ones applied to the original block.
+	rewrittenMeta := *meta
+	rewrittenMeta.Thanos.SeriesDeletionRequests = slices.Clone(meta.Thanos.SeriesDeletionRequests)
+	for _, req := range requests {
+		rewrittenMeta.Thanos.SeriesDeletionRequests = append(rewrittenMeta.Thanos.SeriesDeletionRequests, req.RequestID)
+	}
+	slices.Sort(rewrittenMeta.Thanos.SeriesDeletionRequests)
+	rewrittenMeta.Thanos.SeriesRetentionRules = slices.Clone(meta.Thanos.SeriesRetentionRules)
+	for _, rule := range rules {
+		rewrittenMeta.Thanos.SeriesRetentionRules = append(rewrittenMeta.Thanos.SeriesRetentionRules, rule.Match)
+	}
+	slices.Sort(rewrittenMeta.Thanos.SeriesRetentionRules)
+	rewrittenMeta.Thanos.SeriesRetentionRules = slices.Compact(rewrittenMeta.Thanos.SeriesRetentionRules)
+
+	id, err := block.DeleteSeries(ctx, userLogger, &rewrittenMeta, bdir, dir, deletions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "delete series from block %s", meta.ULID)
+	}
+
+	var newMeta *block.Meta
+	if id != (ulid.ULID{}) {
+		resdir := filepath.Join(dir, id.String())
+		if newMeta, err = block.ReadMetaFromDir(resdir); err != nil {
+			return nil, errors.Wrapf(err, "read meta of rewritten block %s", id)
+		}
+
+		if err := block.VerifyBlock(ctx, userLogger, resdir, newMeta.MinTime, newMeta.MaxTime, false); err != nil {
+			return nil, errors.Wrapf(err, "invalid rewritten block %s", id)
+		}
+
+		if err := block.Upload(ctx, userLogger, userBucket, resdir, nil, objstore.WithUploadConcurrency(c.cfgProvider.CompactorMaxPerBlockUploadConcurrency(userID))); err != nil {
+			return nil, errors.Wrapf(err, "upload rewritten block %s", id)
+		}
+	}
+
+	if err := block.MarkForDeletion(ctx, userLogger, userBucket, meta.ULID, "source of block rewritten for series deletion", c.blocksMarkedForDeletion); err != nil {
+		return nil, errors.Wrapf(err, "mark block %s for deletion", meta.ULID)
+	}
+
+	level.Info(userLogger).Log("msg", "deleted series from block", "block", meta.ULID, "rewritten_block", id, "requests", len(requests), "retention_rules", len(rules), "duration", time.Since(begin))
+	return newMeta, nil
+}
+
+// updateSeriesDeletionRequestsStatus marks the pending series deletion requests as processed once they've been
+// applied to all blocks. A request is pending at least until twice the smallest block range has elapsed since its
+// creation, so that ingesters have uploaded the blocks with samples older than the request.
+func (c *MultitenantCompactor) updateSeriesDeletionRequestsStatus(ctx context.Context, userID string, requests []*mimir_tsdb.SeriesDeletionRequest, metas map[ulid.ULID]*block.Meta, userLogger log.Logger) {
+	now := time.Now()
+	gracePeriod := 2 * c.compactorCfg.BlockRanges[0]
+
+	for _, req := range requests {
+		if req.Status != mimir_tsdb.SeriesDeletionRequestPending || req.CreatedTime.Time().Add(gracePeriod).After(now) {
+			continue
+		}
+
+		processed := true
+		for _, meta := range metas {
+			if slices.Contains(seriesDeletionRequestsToApply(meta, []*mimir_tsdb.SeriesDeletionRequest{req}), req) {
+				processed = false
+				break
+			}
+		}
+		if !processed {
+			continue
+		}
+
+		req.Status = mimir_tsdb.SeriesDeletionRequestProcessed
+		req.ProcessedTime = util.UnixSecondsFromTime(now)
+		if err := mimir_tsdb.WriteSeriesDeletionRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+			level.Warn(userLogger).Log("msg", "failed to update series deletion request status", "request_id", req.RequestID, "err", err)
+			continue
+		}
+		level.Info(userLogger).Log("msg", "series deletion request processed", "request_id", req.RequestID)
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// DeleteSeries creates a series deletion request from the match[] series selectors and the optional start
+// and end times, which default to the Unix epoch and the current time.
+func (c *MultitenantCompactor) DeleteSeries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		// When Mimir is running, it uses Auth Middleware for checking X-Scope-OrgID and injecting tenant into context.
+		// Auth Middleware sends http.StatusUnauthorized if X-Scope-OrgID is missing, so we do too here, for consistency.
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	startTime, endTime := int64(0), util.TimeToMillis(now)
+	if s := r.Form.Get("start"); s != "" {
+		if startTime, err = util.ParseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.Form.Get("end"); s != "" {
+		if endTime, err = util.ParseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	req, err := mimir_tsdb.NewSeriesDeletionRequest(r.Form["match[]"], startTime, endTime, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := mimir_tsdb.WriteSeriesDeletionRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+		level.Error(c.logger).Log("msg", "failed to write series deletion request", "user", userID, "err", err)
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(c.logger).Log("msg", "series deletion request created", "user", userID, "request_id", req.RequestID, "selectors", len(req.Selectors), "start", startTime, "end", endTime)
+
+	util.WriteJSONResponse(w, req)
+}
+
+type DeleteSeriesStatusResponse struct {
+	TenantID string                              `json:"tenant_id"`
+	Requests []*mimir_tsdb.SeriesDeletionRequest `json:"requests"`
+}
+
+// DeleteSeriesStatus returns the series deletion requests of the tenant.
+func (c *MultitenantCompactor) DeleteSeriesStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requests, err := mimir_tsdb.ReadSeriesDeletionRequests(ctx, c.bucketClient, userID, c.cfgProvider, c.logger)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	util.WriteJSONResponse(w, DeleteSeriesStatusResponse{TenantID: userID, Requests: requests})
+}
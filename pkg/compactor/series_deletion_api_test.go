@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+func TestDeleteSeries(t *testing.T) {
+	bkt := objstore.NewInMemBucket()
+	cfg := prepareConfig(t)
+	c, _, _, _, _ := prepare(t, cfg, bkt)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	deleteSeries := func(ctx context.Context, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/compactor/delete_series", strings.NewReader(form.Encode())).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		c.DeleteSeries(resp, req)
+		return resp
+	}
+
+	// Missing tenant.
+	resp := deleteSeries(context.Background(), url.Values{"match[]": {`{__name__="up"}`}})
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	ctx := user.InjectOrgID(context.Background(), "fake")
+
+	for name, form := range map[string]url.Values{
+		"no selector":        {},
+		"invalid selector":   {"match[]": {`{__name__=`}},
+		"invalid start time": {"match[]": {`{__name__="up"}`}, "start": {"invalid"}},
+		"end before start":   {"match[]": {`{__name__="up"}`}, "start": {"20"}, "end": {"10"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := deleteSeries(ctx, form)
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+	}
+
+	resp = deleteSeries(ctx, url.Values{"match[]": {`{__name__="up"}`, `{job="test"}`}, "start": {"10"}, "end": {"20"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	created := &tsdb.SeriesDeletionRequest{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), created))
+	assert.Equal(t, []string{`{__name__="up"}`, `{job="test"}`}, created.Selectors)
+	assert.Equal(t, int64(10000), created.StartTime)
+	assert.Equal(t, int64(20000), created.EndTime)
+	assert.Equal(t, tsdb.SeriesDeletionRequestPending, created.Status)
+
+	// The request is returned by the status endpoint.
+	resp = httptest.NewRecorder()
+	c.DeleteSeriesStatus(resp, httptest.NewRequest(http.MethodGet, "/compactor/delete_series_status", nil).WithContext(ctx))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	status := DeleteSeriesStatusResponse{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, DeleteSeriesStatusResponse{TenantID: "fake", Requests: []*tsdb.SeriesDeletionRequest{created}}, status)
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestSeriesDeletionRequestsToApply(t *testing.T) {
+	meta := &block.Meta{
+		BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(1, nil), MinTime: 100, MaxTime: 200},
+		Thanos:    block.ThanosMeta{SeriesDeletionRequests: []string{"applied"}},
+	}
+
+	var (
+		before     = &mimir_tsdb.SeriesDeletionRequest{RequestID: "before", StartTime: 0, EndTime: 99}
+		overlapMin = &mimir_tsdb.SeriesDeletionRequest{RequestID: "overlap-min", StartTime: 0, EndTime: 100}
+		inside     = &mimir_tsdb.SeriesDeletionRequest{RequestID: "inside", StartTime: 150, EndTime: 160}
+		overlapMax = &mimir_tsdb.SeriesDeletionRequest{RequestID: "overlap-max", StartTime: 199, EndTime: 300}
+		after      = &mimir_tsdb.SeriesDeletionRequest{RequestID: "after", StartTime: 200, EndTime: 300}
+		applied    = &mimir_tsdb.SeriesDeletionRequest{RequestID: "applied", StartTime: 0, EndTime: 300}
+	)
+
+	assert.Equal(t,
+		[]*mimir_tsdb.SeriesDeletionRequest{overlapMin, inside, overlapMax},
+		seriesDeletionRequestsToApply(meta, []*mimir_tsdb.SeriesDeletionRequest{before, overlapMin, inside, overlapMax, after, applied}),
+	)
+}
+
+func TestSeriesRetentionRulesToApply(t *testing.T) {
+	now := time.Now()
+	meta := &block.Meta{
+		BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(1, nil), MinTime: now.Add(-48 * time.Hour).UnixMilli(), MaxTime: now.Add(-24 * time.Hour).UnixMilli()},
+		Thanos:    block.ThanosMeta{SeriesRetentionRules: []string{"applied"}},
+	}
+
+	var (
+		expired    = &validation.SeriesRetentionRule{Match: "expired", RetentionPeriod: model.Duration(12 * time.Hour)}
+		boundary   = &validation.SeriesRetentionRule{Match: "boundary", RetentionPeriod: model.Duration(24 * time.Hour)}
+		crossing   = &validation.SeriesRetentionRule{Match: "crossing", RetentionPeriod: model.Duration(36 * time.Hour)}
+		notExpired = &validation.SeriesRetentionRule{Match: "not-expired", RetentionPeriod: model.Duration(72 * time.Hour)}
+		applied    = &validation.SeriesRetentionRule{Match: "applied", RetentionPeriod: model.Duration(12 * time.Hour)}
+	)
+
+	// Rules are only applied once the block has ended before their retention boundary.
+	assert.Equal(t,
+		[]*validation.SeriesRetentionRule{expired, boundary},
+		seriesRetentionRulesToApply(meta, []*validation.SeriesRetentionRule{expired, boundary, crossing, notExpired, applied}, now),
+	)
+}
+
+func TestAppliedToAll(t *testing.T) {
+	newMeta := func(requests ...string) *block.Meta {
+		return &block.Meta{Thanos: block.ThanosMeta{SeriesDeletionRequests: requests}}
+	}
+	appliedToAllRequests := func(metas ...*block.Meta) []string {
+		return appliedToAll(metas, func(meta *block.Meta) []string { return meta.Thanos.SeriesDeletionRequests })
+	}
+
+	assert.Empty(t, appliedToAllRequests())
+	assert.Equal(t, []string{"a", "b"}, appliedToAllRequests(newMeta("a", "b")))
+	assert.Equal(t, []string{"b", "d"}, appliedToAllRequests(newMeta("a", "b", "c", "d"), newMeta("b", "d", "e"), newMeta("b", "c", "d")))
+	assert.Empty(t, appliedToAllRequests(newMeta("a", "b"), newMeta()))
+}
@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	crypto_rand "crypto/rand"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// migrateTenantUser processes the pending tenant migration requests of a user: the samples of the blocks of the
+// user migrated by a request are copied to new blocks of the destination tenant. The bucket index of the destination
+// tenant is left to the blocks cleaner, like the blocks of any tenant, so the new blocks aren't queried until its
+// next update. Blocks of the destination tenant overlapping the migrated blocks are merged with them by the
+// compaction of the destination tenant. The migrated samples are then optionally deleted from the user. A request is processed
+// once twice the smallest block range has elapsed since its creation, so that ingesters have uploaded the blocks with
+// samples older than the request.
+//
+// It returns the metas without the blocks marked for deletion.
+func (c *MultitenantCompactor) migrateTenantUser(ctx context.Context, userID string, userBucket objstore.InstrumentedBucket, metaCache *block.MetaCache, metas map[ulid.ULID]*block.Meta, requests []*mimir_tsdb.TenantMigrationRequest, userLogger log.Logger) (map[ulid.ULID]*block.Meta, error) {
+	now := time.Now()
+	gracePeriod := 2 * c.compactorCfg.BlockRanges[0]
+
+	var migratedMetas map[ulid.ULID]*block.Meta
+	for _, req := range requests {
+		if err := ctx.Err(); err != nil {
+			return metas, err
+		}
+
+		if req.Status != mimir_tsdb.TenantMigrationRequestPending || req.CreatedTime.Time().Add(gracePeriod).After(now) {
+			continue
+		}
+
+		// Processing a request is owned by a single compactor, like compaction jobs.
+		job := newJob(userID, fmt.Sprintf("migrate-%s", req.RequestID), labels.EmptyLabels(), block.ResolutionRaw, false, 0, fmt.Sprintf("%s-migrate-%s", userID, req.RequestID))
+		if ok, err := c.shardingStrategy.ownJob(job); err != nil {
+			level.Warn(userLogger).Log("msg", "skipped tenant migration because unable to check whether the request is owned by the compactor instance", "request_id", req.RequestID, "err", err)
+			continue
+		} else if !ok {
+			continue
+		}
+
+		if migratedMetas == nil {
+			var err error
+			if migratedMetas, err = c.fetchMigratedBlocks(ctx, userID, userBucket, metaCache, userLogger); err != nil {
+				return metas, err
+			}
+		}
+
+		deleted, err := c.migrateTenant(ctx, userID, userBucket, req, migratedMetas, userLogger)
+		if err != nil {
+			c.tenantMigrationFailed.Inc()
+			level.Warn(userLogger).Log("msg", "failed to migrate tenant", "request_id", req.RequestID, "destination", req.DestinationTenantID, "err", err)
+			continue
+		}
+
+		if len(deleted) > 0 {
+			metas = maps.Clone(metas)
+			for _, id := range deleted {
+				delete(metas, id)
+				delete(migratedMetas, id)
+			}
+		}
+	}
+	return metas, nil
+}
+
+// fetchMigratedBlocks returns the metas of all the blocks of the user which aren't marked for deletion, including
+// the ones marked for no-compaction or older than the compaction lookback, without the duplicate blocks.
+func (c *MultitenantCompactor) fetchMigratedBlocks(ctx context.Context, userID string, userBucket objstore.InstrumentedBucket, metaCache *block.MetaCache, userLogger log.Logger) (map[ulid.ULID]*block.Meta, error) {
+	fetcher, err := block.NewMetaFetcher(userLogger, c.compactorCfg.MetaSyncConcurrency, userBucket, c.metaSyncDirForUser(userID), nil, []block.MetadataFilter{NewShardAwareDeduplicateFilter()}, metaCache, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	metas, _, err := fetcher.FetchWithoutMarkedForDeletion(ctx)
+	return metas, errors.Wrap(err, "fetch blocks to migrate")
+}
+
+// migrateTenant migrates the blocks of the user overlapping the time range of the request, which haven't been
+// migrated yet. If the request deletes the migrated samples
+// from the user, the blocks whose samples have all been migrated are marked for deletion, and a series deletion
+// request is created for the others. It returns the IDs of the blocks marked for deletion.
+func (c *MultitenantCompactor) migrateTenant(ctx context.Context, userID string, userBucket objstore.Bucket, req *mimir_tsdb.TenantMigrationRequest, metas map[ulid.ULID]*block.Meta, userLogger log.Logger) ([]ulid.ULID, error) {
+	begin := time.Now()
+	matchers, err := req.Matchers()
+	if err != nil {
+		return nil, err
+	}
+
+	destinationBucket := bucket.NewUserBucketClient(req.DestinationTenantID, c.bucketClient, c.cfgProvider)
+
+	var (
+		migrated        []ulid.ULID
+		partlyMigrated  bool
+		migratedBlocks  int
+		ids             = slices.SortedFunc(maps.Keys(metas), func(a, b ulid.ULID) int { return a.Compare(b) })
+		migratesSamples = func(meta *block.Meta) bool {
+			return len(matchers) > 0 || req.StartTime > meta.MinTime || req.EndTime < meta.MaxTime-1
+		}
+	)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		meta := metas[id]
+		// Block max time is exclusive, while request end time is inclusive.
+		if req.StartTime >= meta.MaxTime || req.EndTime < meta.MinTime {
+			continue
+		}
+		migrated = append(migrated, id)
+		partlyMigrated = partlyMigrated || migratesSamples(meta)
+
+		if _, done := slices.BinarySearch(req.MigratedBlocks, id.String()); done {
+			continue
+		}
+
+		if err := c.migrateBlock(ctx, userID, userBucket, destinationBucket, meta, matchers, req, migratesSamples(meta), userLogger); err != nil {
+			return nil, errors.Wrapf(err, "migrate block %s", id)
+		}
+		c.blocksMigrated.Inc()
+		migratedBlocks++
+
+		// The migrated blocks are recorded in the request, so that they aren't migrated again if the migration
+		// fails before all the blocks have been migrated.
+		req.MigratedBlocks = append(req.MigratedBlocks, id.String())
+		slices.Sort(req.MigratedBlocks)
+		if err := mimir_tsdb.WriteTenantMigrationRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+			return nil, errors.Wrap(err, "update tenant migration request")
+		}
+	}
+
+	var deleted []ulid.ULID
+	if req.DeleteSource {
+		if partlyMigrated && req.SourceDeletionRequestID == "" {
+			selectors := req.Selectors
+			if len(selectors) == 0 {
+				selectors = []string{fmt.Sprintf(`{%s=~".+"}`, labels.MetricName)}
+			}
+
+			deletion, err := mimir_tsdb.NewSeriesDeletionRequest(selectors, req.StartTime, req.EndTime, time.Now())
+			if err != nil {
+				return nil, errors.Wrap(err, "create series deletion request")
+			}
+			if err := mimir_tsdb.WriteSeriesDeletionRequest(ctx, c.bucketClient, userID, c.cfgProvider, deletion); err != nil {
+				return nil, err
+			}
+			req.SourceDeletionRequestID = deletion.RequestID
+		}
+
+		for _, id := range migrated {
+			if migratesSamples(metas[id]) {
+				continue
+			}
+			if err := block.MarkForDeletion(ctx, userLogger, userBucket, id, "block migrated to another tenant", c.blocksMarkedForDeletion); err != nil {
+				return nil, errors.Wrapf(err, "mark block %s for deletion", id)
+			}
+			deleted = append(deleted, id)
+		}
+	}
+
+	req.Status = mimir_tsdb.TenantMigrationRequestProcessed
+	req.ProcessedTime = util.UnixSecondsFromTime(time.Now())
+	if err := mimir_tsdb.WriteTenantMigrationRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+		return nil, errors.Wrap(err, "update tenant migration request status")
+	}
+
+	level.Info(userLogger).Log("msg", "tenant migration request processed", "request_id", req.RequestID, "destination", req.DestinationTenantID, "blocks", len(migrated), "migrated_blocks", migratedBlocks, "deleted_blocks", len(deleted), "series_deletion_request_id", req.SourceDeletionRequestID, "duration", time.Since(begin))
+	return deleted, nil
+}
+
+// migrateBlock uploads a copy of the block to the destination bucket, with the samples migrated by the request if
+// extract is true, or all its samples otherwise. The copy gets a new ID, and is its own compaction source, so that
+// it isn't deduplicated with other blocks of the destination tenant, which may have been migrated from the same
+// source blocks by other requests. It's not created if the block has no sample migrated by the request.
+func (c *MultitenantCompactor) migrateBlock(ctx context.Context, userID string, userBucket, destinationBucket objstore.Bucket, meta *block.Meta, matchers [][]*labels.Matcher, req *mimir_tsdb.TenantMigrationRequest, extract bool, userLogger log.Logger) error {
+	dir := filepath.Join(c.compactorCfg.DataDir, "migrate", userID)
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrap(err, "clean tenant migration directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Error(userLogger).Log("msg", "failed to remove tenant migration directory", "path", dir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(dir, meta.ULID.String())
+	if err := block.Download(ctx, userLogger, userBucket, meta.ULID, bdir); err != nil {
+		return errors.Wrap(err, "download block")
+	}
+
+	var (
+		id  ulid.ULID
+		err error
+	)
+	if extract {
+		if id, err = block.ExtractSeries(ctx, userLogger, meta, bdir, dir, matchers, req.StartTime, req.EndTime); err != nil {
+			return errors.Wrap(err, "extract series")
+		}
+		if id == (ulid.ULID{}) {
+			level.Info(userLogger).Log("msg", "no sample to migrate from block", "block", meta.ULID, "request_id", req.RequestID)
+			return nil
+		}
+	} else {
+		id = ulid.MustNew(ulid.Now(), crypto_rand.Reader)
+		if err := os.Rename(bdir, filepath.Join(dir, id.String())); err != nil {
+			return errors.Wrap(err, "rename block directory")
+		}
+	}
+	resdir := filepath.Join(dir, id.String())
+
+	newMeta, err := block.ReadMetaFromDir(resdir)
+	if err != nil {
+		return errors.Wrapf(err, "read meta of migrated block %s", id)
+	}
+	newMeta.ULID = id
+	newMeta.Compaction.Sources = []ulid.ULID{id}
+	newMeta.Compaction.Parents = nil
+	// The series deletion requests, series retention rules and block rewrite requests of the source tenant don't
+	// apply to the destination tenant.
+	newMeta.Thanos = block.ThanosMeta{
+		Labels:       maps.Clone(meta.Thanos.Labels),
+		Downsample:   block.ThanosDownsample{Resolution: meta.Thanos.Downsample.Resolution},
+		Source:       block.CompactorMigrateSource,
+		SegmentFiles: block.GetSegmentFiles(resdir),
+	}
+	if err := newMeta.WriteToDir(userLogger, resdir); err != nil {
+		return errors.Wrapf(err, "write meta of migrated block %s", id)
+	}
+
+	if err := block.VerifyBlock(ctx, userLogger, resdir, newMeta.MinTime, newMeta.MaxTime, false); err != nil {
+		return errors.Wrapf(err, "invalid migrated block %s", id)
+	}
+
+	if err := block.Upload(ctx, userLogger, destinationBucket, resdir, nil, objstore.WithUploadConcurrency(c.cfgProvider.CompactorMaxPerBlockUploadConcurrency(req.DestinationTenantID))); err != nil {
+		return errors.Wrapf(err, "upload migrated block %s", id)
+	}
+
+	level.Info(userLogger).Log("msg", "migrated block", "block", meta.ULID, "migrated_block", id, "request_id", req.RequestID, "destination", req.DestinationTenantID)
+	return nil
+}
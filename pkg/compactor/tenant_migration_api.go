@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// MigrateTenant creates a tenant migration request migrating the samples of the source tenant to the destination
+// tenant. Both tenants must be in the org ID, as in source|destination, so that the request is authorized for both,
+// and the destination parameter tells which one is the destination tenant. The samples can be filtered by the
+// optional match[] series selectors, and start and end times, which default to all the samples. The migrated
+// samples are deleted from the source tenant if delete_source is true.
+func (c *MultitenantCompactor) MigrateTenant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		// When Mimir is running, it uses Auth Middleware for checking X-Scope-OrgID and injecting tenant into context.
+		// Auth Middleware sends http.StatusUnauthorized if X-Scope-OrgID is missing, so we do too here, for consistency.
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The tenant IDs are sorted, so the destination tenant is told apart by the destination parameter.
+	destination := r.Form.Get("destination")
+	if len(tenantIDs) != 2 || !slices.Contains(tenantIDs, destination) {
+		http.Error(w, "the org ID must contain both the source and the destination tenants, as in source|destination", http.StatusUnauthorized)
+		return
+	}
+	userID := tenantIDs[0]
+	if userID == destination {
+		userID = tenantIDs[1]
+	}
+
+	startTime, endTime := int64(0), int64(math.MaxInt64)
+	if s := r.Form.Get("start"); s != "" {
+		if startTime, err = util.ParseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.Form.Get("end"); s != "" {
+		if endTime, err = util.ParseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	deleteSource := false
+	if s := r.Form.Get("delete_source"); s != "" {
+		if deleteSource, err = strconv.ParseBool(s); err != nil {
+			http.Error(w, errors.Wrap(err, "invalid delete_source").Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	req, err := mimir_tsdb.NewTenantMigrationRequest(destination, r.Form["match[]"], startTime, endTime, deleteSource, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := mimir_tsdb.WriteTenantMigrationRequest(ctx, c.bucketClient, userID, c.cfgProvider, req); err != nil {
+		level.Error(c.logger).Log("msg", "failed to write tenant migration request", "user", userID, "err", err)
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(c.logger).Log("msg", "tenant migration request created", "user", userID, "request_id", req.RequestID, "destination", destination, "selectors", len(req.Selectors), "start", startTime, "end", endTime, "delete_source", deleteSource)
+
+	util.WriteJSONResponse(w, req)
+}
+
+type MigrateTenantStatusResponse struct {
+	TenantID string                               `json:"tenant_id"`
+	Requests []*mimir_tsdb.TenantMigrationRequest `json:"requests"`
+}
+
+// MigrateTenantStatus returns the tenant migration requests of the tenant.
+func (c *MultitenantCompactor) MigrateTenantStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requests, err := mimir_tsdb.ReadTenantMigrationRequests(ctx, c.bucketClient, userID, c.cfgProvider, c.logger)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	util.WriteJSONResponse(w, MigrateTenantStatusResponse{TenantID: userID, Requests: requests})
+}
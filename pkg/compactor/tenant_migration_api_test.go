@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+func TestMigrateTenant(t *testing.T) {
+	bkt := objstore.NewInMemBucket()
+	cfg := prepareConfig(t)
+	c, _, _, _, _ := prepare(t, cfg, bkt)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	migrateTenant := func(ctx context.Context, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/compactor/migrate_tenant", strings.NewReader(form.Encode())).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		c.MigrateTenant(resp, req)
+		return resp
+	}
+
+	// Missing tenant.
+	resp := migrateTenant(context.Background(), url.Values{"destination": {"other"}})
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// The request must be authorized for both the source and the destination tenants.
+	for name, tc := range map[string]struct {
+		orgID string
+		form  url.Values
+	}{
+		"only source tenant":        {orgID: "fake", form: url.Values{"destination": {"other"}}},
+		"destination not in org ID": {orgID: "fake|another", form: url.Values{"destination": {"other"}}},
+		"no destination":            {orgID: "fake|other", form: url.Values{}},
+		"same destination":          {orgID: "fake|fake", form: url.Values{"destination": {"fake"}}},
+		"invalid destination":       {orgID: "fake|..", form: url.Values{"destination": {".."}}},
+		"more than two tenants":     {orgID: "fake|other|another", form: url.Values{"destination": {"other"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := migrateTenant(user.InjectOrgID(context.Background(), tc.orgID), tc.form)
+			require.Equal(t, http.StatusUnauthorized, resp.Code)
+		})
+	}
+
+	ctx := user.InjectOrgID(context.Background(), "fake|other")
+
+	for name, form := range map[string]url.Values{
+		"invalid selector":      {"destination": {"other"}, "match[]": {"{"}},
+		"invalid start time":    {"destination": {"other"}, "start": {"invalid"}},
+		"end before start":      {"destination": {"other"}, "start": {"20"}, "end": {"10"}},
+		"invalid delete_source": {"destination": {"other"}, "delete_source": {"maybe"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := migrateTenant(ctx, form)
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+	}
+
+	// The time range defaults to all the samples.
+	resp = migrateTenant(ctx, url.Values{"destination": {"other"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	created := &tsdb.TenantMigrationRequest{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), created))
+	assert.Equal(t, "other", created.DestinationTenantID)
+	assert.Empty(t, created.Selectors)
+	assert.Equal(t, int64(0), created.StartTime)
+	assert.Equal(t, int64(math.MaxInt64), created.EndTime)
+	assert.False(t, created.DeleteSource)
+	assert.Equal(t, tsdb.TenantMigrationRequestPending, created.Status)
+
+	resp = migrateTenant(ctx, url.Values{"destination": {"other"}, "match[]": {`{__name__="a"}`}, "start": {"10"}, "end": {"20"}, "delete_source": {"true"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	filtered := &tsdb.TenantMigrationRequest{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), filtered))
+	assert.Equal(t, []string{`{__name__="a"}`}, filtered.Selectors)
+	assert.Equal(t, int64(10000), filtered.StartTime)
+	assert.Equal(t, int64(20000), filtered.EndTime)
+	assert.True(t, filtered.DeleteSource)
+
+	// The requests are returned by the status endpoint.
+	resp = httptest.NewRecorder()
+	c.MigrateTenantStatus(resp, httptest.NewRequest(http.MethodGet, "/compactor/migrate_tenant_status", nil).WithContext(user.InjectOrgID(context.Background(), "fake")))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	status := MigrateTenantStatusResponse{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, "fake", status.TenantID)
+	assert.ElementsMatch(t, []*tsdb.TenantMigrationRequest{created, filtered}, status.Requests)
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"math"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestMultitenantCompactor_MigrateTenantUser(t *testing.T) {
+	ctx := context.Background()
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepare(t, prepareConfig(t), bkt)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	appendSamples := func(lset labels.Labels, timestamps ...int64) func(*tsdb.DB) {
+		return func(db *tsdb.DB) {
+			app := db.Appender(ctx)
+			for _, ts := range timestamps {
+				_, err := app.Append(0, lset, ts, float64(ts))
+				require.NoError(t, err)
+			}
+			require.NoError(t, app.Commit())
+		}
+	}
+	createBlocks := func(userID string) map[ulid.ULID]*block.Meta {
+		id1 := createCustomTSDBBlock(t, bkt, userID, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_2"}, func(db *tsdb.DB) {
+			appendSamples(labels.FromStrings(labels.MetricName, "a"), 0, 1000)(db)
+			appendSamples(labels.FromStrings(labels.MetricName, "b"), 0, 1000)(db)
+		})
+		id2 := createCustomTSDBBlock(t, bkt, userID, nil, func(db *tsdb.DB) {
+			appendSamples(labels.FromStrings(labels.MetricName, "a"), int64(2*time.Hour/time.Millisecond))(db)
+		})
+
+		metas := map[ulid.ULID]*block.Meta{}
+		for _, id := range []ulid.ULID{id1, id2} {
+			meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), bucket.NewUserBucketClient(userID, bkt, nil), id)
+			require.NoError(t, err)
+			metas[id] = &meta
+		}
+		return metas
+	}
+	listBlocks := func(userID string) []ulid.ULID {
+		var ids []ulid.ULID
+		require.NoError(t, bkt.Iter(ctx, userID+"/", func(name string) error {
+			if id, ok := block.IsBlockDir(name); ok {
+				ids = append(ids, id)
+			}
+			return nil
+		}))
+		return ids
+	}
+	bucketIndexExists := func(userID string) bool {
+		exists, err := bkt.Exists(ctx, path.Join(userID, bucketindex.IndexCompressedFilename))
+		require.NoError(t, err)
+		return exists
+	}
+	readMeta := func(userID string, id ulid.ULID) *block.Meta {
+		meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), bucket.NewUserBucketClient(userID, bkt, nil), id)
+		require.NoError(t, err)
+		return &meta
+	}
+	markedForDeletion := func(userID string, id ulid.ULID) bool {
+		exists, err := bkt.Exists(ctx, path.Join(userID, id.String(), block.DeletionMarkFilename))
+		require.NoError(t, err)
+		return exists
+	}
+
+	t.Run("all blocks", func(t *testing.T) {
+		metas := createBlocks("source-1")
+
+		req := &mimir_tsdb.TenantMigrationRequest{
+			RequestID:           "request-1",
+			DestinationTenantID: "destination-1",
+			EndTime:             math.MaxInt64,
+			DeleteSource:        true,
+			Status:              mimir_tsdb.TenantMigrationRequestPending,
+			CreatedTime:         util.UnixSecondsFromTime(time.Now().Add(-24 * time.Hour)),
+		}
+		require.NoError(t, mimir_tsdb.WriteTenantMigrationRequest(ctx, bkt, "source-1", nil, req))
+
+		// Requests created less than twice the smallest block range ago aren't processed yet.
+		recent := &mimir_tsdb.TenantMigrationRequest{
+			RequestID:           "request-2",
+			DestinationTenantID: "destination-1",
+			EndTime:             math.MaxInt64,
+			Status:              mimir_tsdb.TenantMigrationRequestPending,
+			CreatedTime:         util.UnixSecondsFromTime(time.Now()),
+		}
+		require.NoError(t, mimir_tsdb.WriteTenantMigrationRequest(ctx, bkt, "source-1", nil, recent))
+
+		userBucket := bucket.NewUserBucketClient("source-1", bkt, nil)
+		tenantRequests, err := mimir_tsdb.ReadTenantRequests(ctx, bkt, "source-1", nil, log.NewNopLogger())
+		require.NoError(t, err)
+		newMetas, err := c.migrateTenantUser(ctx, "source-1", userBucket, nil, metas, tenantRequests.TenantMigrations, log.NewNopLogger())
+		require.NoError(t, err)
+		assert.Empty(t, newMetas)
+
+		// The source blocks have been copied to new blocks of the destination tenant, whose bucket index is left
+		// to the blocks cleaner.
+		ids := listBlocks("destination-1")
+		require.Len(t, ids, 2)
+		assert.False(t, bucketIndexExists("destination-1"))
+		var numSamples uint64
+		for _, id := range ids {
+			meta := readMeta("destination-1", id)
+			assert.NotContains(t, metas, id)
+			assert.Equal(t, []ulid.ULID{id}, meta.Compaction.Sources)
+			assert.Equal(t, block.CompactorMigrateSource, meta.Thanos.Source)
+			numSamples += meta.Stats.NumSamples
+		}
+		assert.Equal(t, uint64(5), numSamples)
+
+		// All the samples of the source blocks have been migrated, so they're marked for deletion.
+		for id := range metas {
+			assert.True(t, markedForDeletion("source-1", id))
+		}
+
+		requests, err := mimir_tsdb.ReadTenantMigrationRequests(ctx, bkt, "source-1", nil, log.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, requests, 2)
+		assert.Equal(t, mimir_tsdb.TenantMigrationRequestProcessed, requests[0].Status)
+		assert.Len(t, requests[0].MigratedBlocks, 2)
+		assert.Empty(t, requests[0].SourceDeletionRequestID)
+		assert.Equal(t, mimir_tsdb.TenantMigrationRequestPending, requests[1].Status)
+	})
+
+	t.Run("filtered series", func(t *testing.T) {
+		metas := createBlocks("source-2")
+
+		req := &mimir_tsdb.TenantMigrationRequest{
+			RequestID:           "request",
+			DestinationTenantID: "destination-2",
+			Selectors:           []string{`{__name__="a"}`},
+			StartTime:           1000,
+			EndTime:             math.MaxInt64,
+			DeleteSource:        true,
+			Status:              mimir_tsdb.TenantMigrationRequestPending,
+			CreatedTime:         util.UnixSecondsFromTime(time.Now().Add(-24 * time.Hour)),
+		}
+		require.NoError(t, mimir_tsdb.WriteTenantMigrationRequest(ctx, bkt, "source-2", nil, req))
+
+		userBucket := bucket.NewUserBucketClient("source-2", bkt, nil)
+		tenantRequests, err := mimir_tsdb.ReadTenantRequests(ctx, bkt, "source-2", nil, log.NewNopLogger())
+		require.NoError(t, err)
+		newMetas, err := c.migrateTenantUser(ctx, "source-2", userBucket, nil, metas, tenantRequests.TenantMigrations, log.NewNopLogger())
+		require.NoError(t, err)
+		assert.Equal(t, metas, newMetas)
+
+		// Only the samples of the matching series within the time range have been migrated.
+		ids := listBlocks("destination-2")
+		require.Len(t, ids, 2)
+		for _, id := range ids {
+			meta := readMeta("destination-2", id)
+			assert.Equal(t, uint64(1), meta.Stats.NumSeries)
+			assert.Equal(t, uint64(1), meta.Stats.NumSamples)
+		}
+
+		// The migrated samples are deleted from the source blocks by a series deletion request.
+		for id := range metas {
+			assert.False(t, markedForDeletion("source-2", id))
+		}
+
+		requests, err := mimir_tsdb.ReadTenantMigrationRequests(ctx, bkt, "source-2", nil, log.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, requests, 1)
+		assert.Equal(t, mimir_tsdb.TenantMigrationRequestProcessed, requests[0].Status)
+
+		deletions, err := mimir_tsdb.ReadSeriesDeletionRequests(ctx, bkt, "source-2", nil, log.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, deletions, 1)
+		assert.Equal(t, requests[0].SourceDeletionRequestID, deletions[0].RequestID)
+		assert.Equal(t, req.Selectors, deletions[0].Selectors)
+		assert.Equal(t, req.StartTime, deletions[0].StartTime)
+		assert.Equal(t, req.EndTime, deletions[0].EndTime)
+	})
+}
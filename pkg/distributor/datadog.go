@@ -0,0 +1,493 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/grpcutil"
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/grafana/mimir/pkg/distributor/datadogpush"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	utillog "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	// Paths of the Datadog intake APIs, relative to the Datadog endpoint, which the Datadog Agent uses as its
+	// site URL.
+	DatadogSeriesV1Path = "/api/v1/series"
+	DatadogSeriesV2Path = "/api/v2/series"
+	DatadogSketchesPath = "/api/beta/sketches"
+	DatadogValidatePath = "/api/v1/validate"
+
+	// dogStatsDMaxPacketSize is the maximum size of a DogStatsD UDP packet.
+	dogStatsDMaxPacketSize = 64 * 1024
+	// dogStatsDMaxLineLength is the maximum length of a line received over a DogStatsD TCP connection.
+	dogStatsDMaxLineLength = 64 * 1024
+	// dogStatsDServerLabel is the label added to the series aggregated from DogStatsD metrics, set to the
+	// configured DogStatsD server name of the distributor that aggregated them.
+	dogStatsDServerLabel = "dogstatsd_server"
+)
+
+var (
+	errDogStatsDTenantIDRequired     = errors.New("the DogStatsD tenant ID is required when a DogStatsD listen address is configured")
+	errDogStatsDServerNameRequired   = errors.New("the DogStatsD server name is required when a DogStatsD listen address is configured")
+	errInvalidDogStatsDFlushInterval = errors.New("the DogStatsD flush interval must be greater than 0")
+)
+
+// DatadogConfig configures the ingestion of Datadog metrics.
+type DatadogConfig struct {
+	EndpointEnabled           bool          `yaml:"endpoint_enabled" category:"experimental"`
+	MaxRequestSize            int           `yaml:"max_request_size" category:"experimental"`
+	DogStatsDUDPListenAddress string        `yaml:"dogstatsd_udp_listen_address" category:"experimental"`
+	DogStatsDTCPListenAddress string        `yaml:"dogstatsd_tcp_listen_address" category:"experimental"`
+	DogStatsDTenantID         string        `yaml:"dogstatsd_tenant_id" category:"experimental"`
+	DogStatsDServerName       string        `yaml:"dogstatsd_server_name" category:"experimental"`
+	DogStatsDFlushInterval    time.Duration `yaml:"dogstatsd_flush_interval" category:"experimental"`
+}
+
+func (cfg *DatadogConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.EndpointEnabled, "distributor.datadog.endpoint-enabled", false, "Enable the Datadog endpoint, which accepts the series and sketches the Datadog Agent sends to the Datadog intake APIs, when configured with the endpoint as its site URL.")
+	f.IntVar(&cfg.MaxRequestSize, "distributor.datadog.max-request-size", 100<<20, "Maximum uncompressed Datadog request size in bytes that the distributors accept. Requests exceeding this limit are rejected.")
+	f.StringVar(&cfg.DogStatsDUDPListenAddress, "distributor.datadog.dogstatsd-udp-listen-address", "", "Address to listen on for metrics in the DogStatsD protocol over UDP, for example :8125. Empty to disable.")
+	f.StringVar(&cfg.DogStatsDTCPListenAddress, "distributor.datadog.dogstatsd-tcp-listen-address", "", "Address to listen on for newline-delimited metrics in the DogStatsD protocol over TCP, for example :8125. Empty to disable.")
+	f.StringVar(&cfg.DogStatsDTenantID, "distributor.datadog.dogstatsd-tenant-id", "anonymous", "Tenant the DogStatsD metrics are ingested for, since UDP and TCP clients can't be authenticated.")
+	f.StringVar(&cfg.DogStatsDServerName, "distributor.datadog.dogstatsd-server-name", "", "Value of the "+dogStatsDServerLabel+" label added to the series aggregated from DogStatsD metrics. Each distributor aggregates the metrics it receives, so the name must be different for each distributor receiving DogStatsD metrics, so that the series they aggregate don't collide. It should be stable across restarts, such as the name of the distributor's pod in a StatefulSet, so that restarts don't create new series. Required when a DogStatsD listen address is configured.")
+	f.DurationVar(&cfg.DogStatsDFlushInterval, "distributor.datadog.dogstatsd-flush-interval", 10*time.Second, "Interval DogStatsD metrics are aggregated over before being ingested. Metrics received since the last flush are lost if the distributor crashes.")
+}
+
+func (cfg *DatadogConfig) Validate() error {
+	if cfg.DogStatsDUDPListenAddress == "" && cfg.DogStatsDTCPListenAddress == "" {
+		return nil
+	}
+	if cfg.DogStatsDTenantID == "" {
+		return errDogStatsDTenantIDRequired
+	}
+	if cfg.DogStatsDServerName == "" {
+		return errDogStatsDServerNameRequired
+	}
+	if cfg.DogStatsDFlushInterval <= 0 {
+		return errInvalidDogStatsDFlushInterval
+	}
+	return nil
+}
+
+func datadogRequestParser(ctx context.Context, r *http.Request, maxSize int, req *mimirpb.PreallocWriteRequest, logger log.Logger) error {
+	spanLogger, _ := spanlogger.NewWithLogger(ctx, logger, "Distributor.DatadogHandler.decodeAndConvert")
+	defer spanLogger.Span.Finish()
+
+	contentType := r.Header.Get("Content-Type")
+	contentEncoding := r.Header.Get("Content-Encoding")
+	spanLogger.SetTag("content_type", contentType)
+	spanLogger.SetTag("content_encoding", contentEncoding)
+	spanLogger.SetTag("content_length", r.ContentLength)
+
+	data, err := readDatadogBody(r.Body, contentEncoding, maxSize)
+	if err != nil {
+		return err
+	}
+
+	var (
+		series   []datadogpush.Series
+		sketches []datadogpush.Sketch
+	)
+	switch {
+	case strings.HasSuffix(r.URL.Path, DatadogSeriesV1Path):
+		series, err = datadogpush.ParseSeriesV1(data)
+	case strings.HasSuffix(r.URL.Path, DatadogSeriesV2Path) && strings.HasPrefix(contentType, "application/json"):
+		series, err = datadogpush.ParseSeriesV2JSON(data)
+	case strings.HasSuffix(r.URL.Path, DatadogSeriesV2Path):
+		series, err = datadogpush.ParseSeriesV2Protobuf(data)
+	case strings.HasSuffix(r.URL.Path, DatadogSketchesPath):
+		sketches, err = datadogpush.ParseSketches(data)
+	default:
+		return httpgrpc.Error(http.StatusNotFound, fmt.Sprintf("unsupported Datadog API %s", r.URL.Path))
+	}
+	level.Debug(spanLogger).Log("msg", "decodeAndConvert complete", "bytesRead", len(data), "series", len(series), "sketches", len(sketches), "err", err)
+	if err != nil {
+		return httpgrpc.Error(http.StatusBadRequest, err.Error())
+	}
+
+	req.Timeseries = datadogpush.ToTimeseries(series, sketches)
+	req.Metadata = datadogpush.ToMetadata(series, sketches)
+	return nil
+}
+
+// readDatadogBody reads and decompresses the body of a Datadog request. The Datadog Agent compresses
+// payloads with zlib, which it advertises as deflate, or zstd.
+func readDatadogBody(body io.Reader, contentEncoding string, maxSize int) ([]byte, error) {
+	switch contentEncoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, httpgrpc.Error(http.StatusBadRequest, fmt.Sprintf("gzip compression error: %s", err))
+		}
+		defer gzipReader.Close()
+		body = gzipReader
+	case "deflate":
+		zlibReader, err := zlib.NewReader(body)
+		if err != nil {
+			return nil, httpgrpc.Error(http.StatusBadRequest, fmt.Sprintf("deflate compression error: %s", err))
+		}
+		defer zlibReader.Close()
+		body = zlibReader
+	case "zstd":
+		zstdReader, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, httpgrpc.Error(http.StatusBadRequest, fmt.Sprintf("zstd compression error: %s", err))
+		}
+		defer zstdReader.Close()
+		body = zstdReader
+	default:
+		return nil, httpgrpc.Error(http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported compression: %s. Only \"gzip\", \"deflate\", \"zstd\" or no compression supported", contentEncoding))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, int64(maxSize)+1))
+	if err != nil {
+		return nil, httpgrpc.Error(http.StatusBadRequest, fmt.Sprintf("can't read body: %s", err))
+	}
+	if len(data) > maxSize {
+		return nil, httpgrpc.Error(http.StatusRequestEntityTooLarge, fmt.Sprintf("the incoming Datadog request has been rejected because its uncompressed size is larger than the allowed limit of %d bytes", maxSize))
+	}
+	return data, nil
+}
+
+// DatadogHandler is a http.Handler which accepts the series and sketches the Datadog Agent sends to the Datadog
+// intake APIs, and converts them to WriteRequests. The intake API is determined by the path of the request.
+func DatadogHandler(
+	maxRecvMsgSize int,
+	sourceIPs *middleware.SourceIPExtractor,
+	retryCfg RetryConfig,
+	push PushFunc,
+	pushMetrics *PushMetrics,
+	logger log.Logger,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := utillog.WithContext(ctx, logger)
+		if sourceIPs != nil {
+			source := sourceIPs.Get(r)
+			if source != "" {
+				logger = utillog.WithSourceIPs(source, logger)
+			}
+		}
+
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			level.Warn(logger).Log("msg", "unable to obtain tenantID", "err", err)
+			return
+		}
+
+		pushMetrics.IncDatadogRequest(tenantID)
+
+		supplier := func() (*mimirpb.WriteRequest, func(), error) {
+			var req mimirpb.PreallocWriteRequest
+			if err := datadogRequestParser(ctx, r, maxRecvMsgSize, &req, logger); err != nil {
+				return nil, nil, err
+			}
+
+			cleanup := func() {
+				mimirpb.ReuseSlice(req.Timeseries)
+			}
+			return &req.WriteRequest, cleanup, nil
+		}
+
+		req := newRequest(supplier)
+		if err := push(ctx, req); err != nil {
+			if errors.Is(err, context.Canceled) {
+				level.Warn(logger).Log("msg", "push request canceled", "err", err)
+				w.WriteHeader(statusClientClosedRequest)
+				return
+			}
+
+			var httpCode int
+			var errorMsg string
+			if st, ok := grpcutil.ErrorToStatus(err); ok {
+				// This code is needed for a correct handling of errors returned by the supplier function.
+				// These errors are created by using the httpgrpc package.
+				httpCode = int(st.Code())
+				errorMsg = st.Message()
+			} else {
+				var distributorErr Error
+				errorMsg = err.Error()
+				if errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &distributorErr) {
+					httpCode = http.StatusServiceUnavailable
+				} else {
+					httpCode = errorCauseToHTTPStatusCode(distributorErr.Cause(), false)
+				}
+			}
+			if httpCode != http.StatusAccepted {
+				msgs := []interface{}{"msg", "detected an error while ingesting Datadog metrics request (the request may have been partially ingested)", "httpCode", httpCode, "err", err}
+				if httpCode/100 == 4 {
+					// This tag makes the error message visible for our Grafana Cloud customers.
+					msgs = append(msgs, "insight", true)
+				}
+				level.Error(logger).Log(msgs...)
+			}
+			addHeaders(w, err, r, httpCode, retryCfg)
+			http.Error(w, validUTF8Message(errorMsg), httpCode)
+			return
+		}
+
+		// The Datadog intake APIs respond with 202 Accepted and the list of errors, which is always empty here
+		// since a request is either fully rejected or accepted.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	})
+}
+
+// DatadogValidateHandler is a http.Handler for the API the Datadog Agent calls to check its API key. API keys
+// aren't used by Mimir, which authenticates tenants like for any other endpoint, so it always succeeds.
+func DatadogValidateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"valid":true}`))
+	})
+}
+
+// dogStatsDServer accepts metrics in the DogStatsD protocol over UDP and TCP, aggregates them over the flush
+// interval, and pushes the aggregated series for the configured tenant.
+type dogStatsDServer struct {
+	services.Service
+
+	cfg         DatadogConfig
+	push        PushFunc
+	pushMetrics *PushMetrics
+	logger      log.Logger
+
+	packetConn  net.PacketConn
+	tcpListener net.Listener
+
+	aggregatorMtx sync.Mutex
+	aggregator    *datadogpush.DogStatsDAggregator
+
+	connsMtx sync.Mutex
+	conns    map[net.Conn]struct{}
+	connsWg  sync.WaitGroup
+}
+
+func newDogStatsDServer(cfg DatadogConfig, push PushFunc, pushMetrics *PushMetrics, logger log.Logger) *dogStatsDServer {
+	s := &dogStatsDServer{
+		cfg:         cfg,
+		push:        push,
+		pushMetrics: pushMetrics,
+		logger:      log.With(logger, "component", "dogstatsd-server"),
+		aggregator:  datadogpush.NewDogStatsDAggregator(),
+		conns:       map[net.Conn]struct{}{},
+	}
+	s.Service = services.NewBasicService(s.starting, s.running, s.stopping)
+	return s
+}
+
+func (s *dogStatsDServer) starting(_ context.Context) error {
+	var err error
+	if s.cfg.DogStatsDUDPListenAddress != "" {
+		if s.packetConn, err = net.ListenPacket("udp", s.cfg.DogStatsDUDPListenAddress); err != nil {
+			return fmt.Errorf("failed to listen for DogStatsD metrics over UDP: %w", err)
+		}
+	}
+	if s.cfg.DogStatsDTCPListenAddress != "" {
+		if s.tcpListener, err = net.Listen("tcp", s.cfg.DogStatsDTCPListenAddress); err != nil {
+			if s.packetConn != nil {
+				_ = s.packetConn.Close()
+			}
+			return fmt.Errorf("failed to listen for DogStatsD metrics over TCP: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *dogStatsDServer) running(ctx context.Context) error {
+	if s.packetConn != nil {
+		s.connsWg.Add(1)
+		go func() {
+			defer s.connsWg.Done()
+			s.readPackets()
+		}()
+	}
+	if s.tcpListener != nil {
+		go s.accept()
+	}
+
+	ticker := time.NewTicker(s.cfg.DogStatsDFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *dogStatsDServer) stopping(_ error) error {
+	if s.packetConn != nil {
+		_ = s.packetConn.Close()
+	}
+	if s.tcpListener != nil {
+		_ = s.tcpListener.Close()
+	}
+
+	s.connsMtx.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.connsMtx.Unlock()
+
+	s.connsWg.Wait()
+
+	// Metrics received since the last flush are pushed, so that they aren't lost when the distributor is stopped.
+	s.flush()
+	return nil
+}
+
+func (s *dogStatsDServer) readPackets() {
+	buf := make([]byte, dogStatsDMaxPacketSize)
+	for {
+		n, addr, err := s.packetConn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				level.Error(s.logger).Log("msg", "failed to read DogStatsD UDP packet", "err", err)
+			}
+			return
+		}
+
+		// A packet may hold several newline-delimited metrics.
+		for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+			s.add(line, addr)
+		}
+	}
+}
+
+func (s *dogStatsDServer) accept() {
+	for {
+		conn, err := s.tcpListener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				level.Error(s.logger).Log("msg", "failed to accept DogStatsD TCP connection", "err", err)
+			}
+			return
+		}
+
+		s.connsMtx.Lock()
+		s.conns[conn] = struct{}{}
+		s.connsWg.Add(1)
+		s.connsMtx.Unlock()
+
+		go func() {
+			defer func() {
+				_ = conn.Close()
+
+				s.connsMtx.Lock()
+				delete(s.conns, conn)
+				s.connsMtx.Unlock()
+				s.connsWg.Done()
+			}()
+
+			s.handleConn(conn)
+		}()
+	}
+}
+
+// handleConn reads newline-delimited metrics in the DogStatsD protocol from conn.
+func (s *dogStatsDServer) handleConn(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	var line []byte
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		switch {
+		case err == nil || errors.Is(err, io.EOF):
+			s.add(line, conn.RemoteAddr())
+			line = line[:0]
+			if err != nil {
+				return
+			}
+		case errors.Is(err, bufio.ErrBufferFull):
+			if len(line) > dogStatsDMaxLineLength {
+				level.Warn(s.logger).Log("msg", "closing DogStatsD TCP connection because a line exceeds the maximum length", "remote_addr", conn.RemoteAddr().String(), "max_length", dogStatsDMaxLineLength)
+				return
+			}
+		default:
+			if !errors.Is(err, net.ErrClosed) {
+				level.Warn(s.logger).Log("msg", "failed to read from DogStatsD TCP connection", "remote_addr", conn.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+	}
+}
+
+func (s *dogStatsDServer) add(line []byte, addr net.Addr) {
+	m, ok, err := datadogpush.ParseDogStatsDLine(line)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to parse DogStatsD metric", "remote_addr", addr.String(), "err", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	s.aggregatorMtx.Lock()
+	s.aggregator.Add(m)
+	s.aggregatorMtx.Unlock()
+}
+
+// flush pushes the metrics aggregated since the previous flush for the configured tenant.
+func (s *dogStatsDServer) flush() {
+	resources := []datadogpush.Resource{{Type: dogStatsDServerLabel, Name: s.cfg.DogStatsDServerName}}
+
+	s.aggregatorMtx.Lock()
+	series, sketches := s.aggregator.Flush(time.Now().UnixMilli(), int64(s.cfg.DogStatsDFlushInterval.Seconds()), resources)
+	s.aggregatorMtx.Unlock()
+
+	if len(series) == 0 && len(sketches) == 0 {
+		return
+	}
+
+	userID := s.cfg.DogStatsDTenantID
+	s.pushMetrics.IncDatadogRequest(userID)
+
+	// Metrics are pushed even when the server is stopping, so that they aren't lost when the distributor is stopped.
+	ctx := user.InjectOrgID(context.Background(), userID)
+	req := newRequest(func() (*mimirpb.WriteRequest, func(), error) {
+		req := &mimirpb.PreallocWriteRequest{}
+		req.Timeseries = datadogpush.ToTimeseries(series, sketches)
+		req.Metadata = datadogpush.ToMetadata(series, sketches)
+
+		cleanup := func() {
+			mimirpb.ReuseSlice(req.Timeseries)
+		}
+		return &req.WriteRequest, cleanup, nil
+	})
+	if err := s.push(ctx, req); err != nil {
+		level.Warn(s.logger).Log("msg", "failed to push DogStatsD metrics", "series", len(series)+len(sketches), "err", err)
+	}
+}
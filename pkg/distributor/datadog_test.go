@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestDatadogHandler(t *testing.T) {
+	const seriesV1 = `{"series": [{"metric": "app.requests", "points": [[1700000000, 12]], "type": "count", "interval": 10, "host": "web01", "tags": ["env:prod"]}]}`
+	expectedSeriesV1 := []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+		Labels: []mimirpb.LabelAdapter{
+			{Name: "__name__", Value: "app_requests"},
+			{Name: "__proxy_source__", Value: "datadog"},
+			{Name: "env", Value: "prod"},
+			{Name: "host", Value: "web01"},
+		},
+		Samples: []mimirpb.Sample{{TimestampMs: 1700000000000, Value: 12}},
+	}}}
+
+	// A MetricPayload with a single gauge series, as sent by the Datadog Agent to the v2 series API.
+	var point, series, seriesV2 []byte
+	point = protowire.AppendTag(point, 1, protowire.Fixed64Type)
+	point = protowire.AppendFixed64(point, math.Float64bits(0.5))
+	point = protowire.AppendTag(point, 2, protowire.VarintType)
+	point = protowire.AppendVarint(point, 1700000000)
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendString(series, "system.load.1")
+	series = protowire.AppendTag(series, 4, protowire.BytesType)
+	series = protowire.AppendBytes(series, point)
+	series = protowire.AppendTag(series, 5, protowire.VarintType)
+	series = protowire.AppendVarint(series, 3)
+	seriesV2 = protowire.AppendTag(seriesV2, 1, protowire.BytesType)
+	seriesV2 = protowire.AppendBytes(seriesV2, series)
+	expectedSeriesV2 := []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+		Labels: []mimirpb.LabelAdapter{
+			{Name: "__name__", Value: "system_load_1"},
+			{Name: "__proxy_source__", Value: "datadog"},
+		},
+		Samples: []mimirpb.Sample{{TimestampMs: 1700000000000, Value: 0.5}},
+	}}}
+
+	deflate := func(data []byte) []byte {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+	zstdCompress := func(data []byte) []byte {
+		enc, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		return enc.EncodeAll(data, nil)
+	}
+
+	tests := []struct {
+		name             string
+		path             string
+		data             []byte
+		contentType      string
+		contentEncoding  string
+		maxRequestSize   int
+		expectedCode     int
+		expectedSeries   []mimirpb.PreallocTimeseries
+		expectedMetadata []*mimirpb.MetricMetadata
+		expectedErr      string
+	}{
+		{
+			name:             "v1 series",
+			path:             "/api/v1/push/datadog/api/v1/series",
+			data:             []byte(seriesV1),
+			contentType:      "application/json",
+			expectedCode:     http.StatusAccepted,
+			expectedSeries:   expectedSeriesV1,
+			expectedMetadata: []*mimirpb.MetricMetadata{{Type: mimirpb.GAUGE, MetricFamilyName: "app_requests", Help: "Datadog count: number of occurrences over the interval of each sample."}},
+		},
+		{
+			name:             "deflate compressed v1 series",
+			path:             "/api/v1/push/datadog/api/v1/series",
+			data:             deflate([]byte(seriesV1)),
+			contentType:      "application/json",
+			contentEncoding:  "deflate",
+			expectedCode:     http.StatusAccepted,
+			expectedSeries:   expectedSeriesV1,
+			expectedMetadata: []*mimirpb.MetricMetadata{{Type: mimirpb.GAUGE, MetricFamilyName: "app_requests", Help: "Datadog count: number of occurrences over the interval of each sample."}},
+		},
+		{
+			name:             "zstd compressed v2 series",
+			path:             "/api/v1/push/datadog/api/v2/series",
+			data:             zstdCompress(seriesV2),
+			contentType:      "application/x-protobuf",
+			contentEncoding:  "zstd",
+			expectedCode:     http.StatusAccepted,
+			expectedSeries:   expectedSeriesV2,
+			expectedMetadata: []*mimirpb.MetricMetadata{{Type: mimirpb.GAUGE, MetricFamilyName: "system_load_1"}},
+		},
+		{
+			name:         "invalid v2 series",
+			path:         "/api/v1/push/datadog/api/v2/series",
+			data:         seriesV2[:len(seriesV2)-1],
+			contentType:  "application/x-protobuf",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "invalid series payload",
+		},
+		{
+			name:            "unsupported compression",
+			path:            "/api/v1/push/datadog/api/v1/series",
+			data:            []byte(seriesV1),
+			contentEncoding: "br",
+			expectedCode:    http.StatusUnsupportedMediaType,
+			expectedErr:     "unsupported compression: br",
+		},
+		{
+			name:            "request too big",
+			path:            "/api/v1/push/datadog/api/v1/series",
+			data:            deflate([]byte(seriesV1)),
+			contentEncoding: "deflate",
+			maxRequestSize:  50,
+			expectedCode:    http.StatusRequestEntityTooLarge,
+			expectedErr:     "larger than the allowed limit of 50 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			push := func(_ context.Context, pushReq *Request) error {
+				req, err := pushReq.WriteRequest()
+				if err != nil {
+					assert.ErrorContains(t, err, tt.expectedErr)
+					return err
+				}
+				assert.Equal(t, tt.expectedSeries, copyTimeseries(req.Timeseries))
+				assert.Equal(t, tt.expectedMetadata, req.Metadata)
+				return nil
+			}
+
+			maxRequestSize := tt.maxRequestSize
+			if maxRequestSize == 0 {
+				maxRequestSize = 1 << 20
+			}
+			handler := DatadogHandler(maxRequestSize, nil, RetryConfig{}, push, nil, log.NewNopLogger())
+
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(tt.data))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			req = req.WithContext(user.InjectOrgID(context.Background(), "test"))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedCode == http.StatusAccepted {
+				assert.JSONEq(t, `{"errors":[]}`, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDatadogValidateHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	DatadogValidateHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/push/datadog/api/v1/validate", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"valid":true}`, rec.Body.String())
+}
+
+func TestDogStatsDServer(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		received = map[string]float64{}
+	)
+	push := func(ctx context.Context, pushReq *Request) error {
+		userID, err := tenant.TenantID(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "dogstatsd-tenant", userID)
+
+		req, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		for _, ts := range req.Timeseries {
+			require.Len(t, ts.Samples, 1)
+			received[mimirpb.FromLabelAdaptersToString(ts.Labels)] += ts.Samples[0].Value
+		}
+		pushReq.CleanUp()
+		return nil
+	}
+
+	cfg := DatadogConfig{
+		DogStatsDUDPListenAddress: "localhost:0",
+		DogStatsDTCPListenAddress: "localhost:0",
+		DogStatsDTenantID:         "dogstatsd-tenant",
+		DogStatsDServerName:       "distributor-1",
+		DogStatsDFlushInterval:    100 * time.Millisecond,
+	}
+	s := newDogStatsDServer(cfg, push, nil, log.NewNopLogger())
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), s))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), s))
+	})
+
+	udpConn, err := net.Dial("udp", s.packetConn.LocalAddr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = udpConn.Close() })
+
+	// A packet may hold several metrics, and invalid metrics are skipped.
+	_, err = udpConn.Write([]byte("page.views:1|c|#env:prod\ninvalid\nqueue.size:3|g"))
+	require.NoError(t, err)
+
+	tcpConn, err := net.Dial("tcp", s.tcpListener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = tcpConn.Close() })
+
+	// A line can be split across writes.
+	_, err = tcpConn.Write([]byte("page.views:2|c|#env:dev\nqueue"))
+	require.NoError(t, err)
+	_, err = tcpConn.Write([]byte(".depth:4|g\n"))
+	require.NoError(t, err)
+
+	expected := map[string]float64{
+		`page_views{__proxy_source__="datadog", dogstatsd_server="distributor-1", env="prod"}`: 1,
+		`page_views{__proxy_source__="datadog", dogstatsd_server="distributor-1", env="dev"}`:  2,
+		`queue_size{__proxy_source__="datadog", dogstatsd_server="distributor-1"}`:             3,
+		`queue_depth{__proxy_source__="datadog", dogstatsd_server="distributor-1"}`:            4,
+	}
+	assert.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return assert.ObjectsAreEqual(expected, received)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDatadogConfig_Validate(t *testing.T) {
+	cfg := DatadogConfig{DogStatsDUDPListenAddress: ":8125", DogStatsDFlushInterval: 10 * time.Second}
+	assert.ErrorIs(t, cfg.Validate(), errDogStatsDTenantIDRequired)
+
+	cfg.DogStatsDTenantID = "anonymous"
+	assert.ErrorIs(t, cfg.Validate(), errDogStatsDServerNameRequired)
+
+	cfg.DogStatsDServerName = "distributor-1"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.DogStatsDFlushInterval = 0
+	assert.ErrorIs(t, cfg.Validate(), errInvalidDogStatsDFlushInterval)
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/util/strutil"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// internalLabel is the label added to series ingested through the Datadog endpoints, like the one added
+// to series ingested through the Influx endpoint.
+const internalLabel = "__proxy_source__"
+
+const (
+	countHelp = "Datadog count: number of occurrences over the interval of each sample."
+	rateHelp  = "Datadog rate: number of occurrences per second over the interval of each sample."
+)
+
+// ToTimeseries converts Datadog series and sketches to series. Count, rate and gauge series are all
+// converted to float samples, since the value of count and rate points only relates to their interval.
+// Sketches are converted to gauge native histograms.
+func ToTimeseries(series []Series, sketches []Sketch) []mimirpb.PreallocTimeseries {
+	returnTs := mimirpb.PreallocTimeseriesSliceFromPool()[:0]
+
+	for _, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+
+		ts := mimirpb.TimeseriesFromPool()
+		ts.Labels = appendLabels(ts.Labels, s.Metric, s.Tags, s.Resources)
+		for _, p := range s.Points {
+			ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: p.Timestamp, Value: p.Value})
+		}
+		returnTs = append(returnTs, mimirpb.PreallocTimeseries{TimeSeries: ts})
+	}
+
+	for _, s := range sketches {
+		if len(s.Points) == 0 {
+			continue
+		}
+
+		ts := mimirpb.TimeseriesFromPool()
+		ts.Labels = appendLabels(ts.Labels, s.Metric, s.Tags, s.Resources)
+		for _, p := range s.Points {
+			ts.Histograms = append(ts.Histograms, mimirpb.FromHistogramToHistogramProto(p.Timestamp, p.Histogram()))
+		}
+		returnTs = append(returnTs, mimirpb.PreallocTimeseries{TimeSeries: ts})
+	}
+
+	return returnTs
+}
+
+// ToMetadata returns the metadata of the metrics of the Datadog series and sketches, which keeps track of
+// their Datadog type and unit.
+func ToMetadata(series []Series, sketches []Sketch) []*mimirpb.MetricMetadata {
+	var metadata []*mimirpb.MetricMetadata
+	seen := map[string]struct{}{}
+
+	for _, s := range series {
+		name := metricName(s.Metric)
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		m := &mimirpb.MetricMetadata{Type: mimirpb.GAUGE, MetricFamilyName: name, Unit: s.Unit}
+		switch s.Type {
+		case MetricTypeCount:
+			m.Help = countHelp
+		case MetricTypeRate:
+			m.Help = rateHelp
+		}
+		metadata = append(metadata, m)
+	}
+
+	for _, s := range sketches {
+		name := metricName(s.Metric)
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		metadata = append(metadata, &mimirpb.MetricMetadata{Type: mimirpb.GAUGEHISTOGRAM, MetricFamilyName: name})
+	}
+
+	return metadata
+}
+
+// metricName converts a dotted Datadog metric name to a valid Prometheus metric name.
+func metricName(metric string) string {
+	return model.EscapeName(metric, model.UnderscoreEscaping)
+}
+
+// appendLabels appends the sorted labels of a Datadog metric to lbls. Tags are converted to labels, with the
+// values of tags with the same name joined with commas. Tags without a value are dropped, like labels with
+// an empty value. Resources, such as the host, are converted to labels named after their type.
+func appendLabels(lbls []mimirpb.LabelAdapter, metric string, tags []string, resources []Resource) []mimirpb.LabelAdapter {
+	values := make(map[string]string, len(tags)+len(resources)+2)
+	for _, tag := range tags {
+		name, value, ok := strings.Cut(tag, ":")
+		if !ok || name == "" || value == "" {
+			continue
+		}
+
+		name = strutil.SanitizeFullLabelName(name)
+		if existing, ok := values[name]; ok && existing != value {
+			value = existing + "," + value
+		}
+		values[name] = value
+	}
+	for _, r := range resources {
+		if r.Type != "" {
+			values[strutil.SanitizeFullLabelName(r.Type)] = r.Name
+		}
+	}
+	values[labels.MetricName] = metricName(metric)
+	values[internalLabel] = "datadog"
+
+	start := len(lbls)
+	for name, value := range values {
+		if value == "" {
+			continue
+		}
+		lbls = append(lbls, mimirpb.LabelAdapter{Name: name, Value: value})
+	}
+	added := lbls[start:]
+	sort.Slice(added, func(i, j int) bool {
+		return added[i].Name < added[j].Name
+	})
+	return lbls
+}
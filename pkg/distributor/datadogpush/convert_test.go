@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestToTimeseries(t *testing.T) {
+	series := []Series{
+		{
+			Metric:    "app.requests",
+			Type:      MetricTypeCount,
+			Points:    []Point{{Timestamp: 1700000000000, Value: 3}, {Timestamp: 1700000010000, Value: 4}},
+			Tags:      []string{"env:prod", "role:web", "role:api", "standalone", "invalid-name:x", "url:http://example.com"},
+			Resources: []Resource{{Type: "host", Name: "web01"}},
+		},
+		{
+			Metric: "empty",
+			Type:   MetricTypeGauge,
+		},
+	}
+	sketches := []Sketch{{
+		Metric: "request.duration",
+		Points: []SketchPoint{{Timestamp: 1700000000000, Sum: 3, Keys: []int32{sketchBias}, Counts: []uint32{3}}},
+	}}
+
+	ts := ToTimeseries(series, sketches)
+	require.Len(t, ts, 2)
+
+	assert.Equal(t, []mimirpb.LabelAdapter{
+		{Name: "__name__", Value: "app_requests"},
+		{Name: "__proxy_source__", Value: "datadog"},
+		{Name: "env", Value: "prod"},
+		{Name: "host", Value: "web01"},
+		{Name: "invalid_name", Value: "x"},
+		{Name: "role", Value: "web,api"},
+		{Name: "url", Value: "http://example.com"},
+	}, ts[0].Labels)
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 1700000000000, Value: 3}, {TimestampMs: 1700000010000, Value: 4}}, ts[0].Samples)
+
+	assert.Equal(t, []mimirpb.LabelAdapter{
+		{Name: "__name__", Value: "request_duration"},
+		{Name: "__proxy_source__", Value: "datadog"},
+	}, ts[1].Labels)
+	require.Len(t, ts[1].Histograms, 1)
+	assert.Equal(t, int64(1700000000000), ts[1].Histograms[0].Timestamp)
+	assert.Equal(t, mimirpb.Histogram_GAUGE, ts[1].Histograms[0].ResetHint)
+	assert.Equal(t, uint64(3), ts[1].Histograms[0].GetCountInt())
+
+	mimirpb.ReuseSlice(ts)
+}
+
+func TestToMetadata(t *testing.T) {
+	series := []Series{
+		{Metric: "app.requests", Type: MetricTypeCount, Unit: "request"},
+		{Metric: "app.requests", Type: MetricTypeCount, Unit: "request"},
+		{Metric: "app.request_rate", Type: MetricTypeRate},
+		{Metric: "system.load.1", Type: MetricTypeGauge},
+	}
+	sketches := []Sketch{{Metric: "request.duration"}}
+
+	assert.Equal(t, []*mimirpb.MetricMetadata{
+		{Type: mimirpb.GAUGE, MetricFamilyName: "app_requests", Help: countHelp, Unit: "request"},
+		{Type: mimirpb.GAUGE, MetricFamilyName: "app_request_rate", Help: rateHelp},
+		{Type: mimirpb.GAUGE, MetricFamilyName: "system_load_1"},
+		{Type: mimirpb.GAUGEHISTOGRAM, MetricFamilyName: "request_duration"},
+	}, ToMetadata(series, sketches))
+}
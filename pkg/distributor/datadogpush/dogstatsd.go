@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DogStatsDType is the type of a metric sent in the DogStatsD protocol.
+type DogStatsDType byte
+
+const (
+	DogStatsDCount DogStatsDType = iota
+	DogStatsDGauge
+	DogStatsDHistogram
+	DogStatsDDistribution
+	DogStatsDSet
+)
+
+// DogStatsDMetric is a value of a metric sent in the DogStatsD protocol.
+type DogStatsDMetric struct {
+	Name string
+	Type DogStatsDType
+	// Values are the values of the metric, since the protocol allows sending several values of the same metric
+	// in a single message. They aren't set for sets, whose values are in SetValue.
+	Values     []float64
+	SetValue   string
+	SampleRate float64
+	Tags       []string
+}
+
+// ParseDogStatsDLine parses a line in the DogStatsD protocol, such as "page.views:1|c|@0.5|#env:prod".
+// ok is false if the line is empty, or is an event or a service check, which are ignored.
+func ParseDogStatsDLine(line []byte) (m DogStatsDMetric, ok bool, err error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || bytes.HasPrefix(line, []byte("_e{")) || bytes.HasPrefix(line, []byte("_sc|")) {
+		return DogStatsDMetric{}, false, nil
+	}
+
+	fields := strings.Split(string(line), "|")
+	if len(fields) < 2 {
+		return DogStatsDMetric{}, false, fmt.Errorf("invalid DogStatsD line %q: the metric type is missing", line)
+	}
+
+	name, rawValues, found := strings.Cut(fields[0], ":")
+	if !found || name == "" || rawValues == "" {
+		return DogStatsDMetric{}, false, fmt.Errorf("invalid DogStatsD line %q: expected <name>:<value>", line)
+	}
+	m = DogStatsDMetric{Name: name, SampleRate: 1}
+
+	switch fields[1] {
+	case "c":
+		m.Type = DogStatsDCount
+	case "g":
+		m.Type = DogStatsDGauge
+	case "h", "ms":
+		m.Type = DogStatsDHistogram
+	case "d":
+		m.Type = DogStatsDDistribution
+	case "s":
+		m.Type = DogStatsDSet
+	default:
+		return DogStatsDMetric{}, false, fmt.Errorf("invalid DogStatsD line %q: unsupported metric type %q", line, fields[1])
+	}
+
+	if m.Type == DogStatsDSet {
+		m.SetValue = rawValues
+	} else {
+		for _, raw := range strings.Split(rawValues, ":") {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return DogStatsDMetric{}, false, fmt.Errorf("invalid DogStatsD line %q: invalid value %q", line, raw)
+			}
+			m.Values = append(m.Values, v)
+		}
+	}
+
+	// Other fields, such as the container ID or the timestamp, are ignored: metrics are aggregated over
+	// the flush interval, and timestamped with the time they're flushed.
+	for _, f := range fields[2:] {
+		switch {
+		case strings.HasPrefix(f, "@"):
+			rate, err := strconv.ParseFloat(f[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return DogStatsDMetric{}, false, fmt.Errorf("invalid DogStatsD line %q: invalid sample rate %q", line, f[1:])
+			}
+			m.SampleRate = rate
+		case strings.HasPrefix(f, "#"):
+			m.Tags = append(m.Tags, strings.Split(f[1:], ",")...)
+		}
+	}
+
+	return m, true, nil
+}
+
+// DogStatsDAggregator aggregates the values of DogStatsD metrics over a flush interval, like the DogStatsD
+// server of the Datadog Agent: counts are summed, gauges keep their last value, sets are converted to the
+// number of their unique values, and histograms, timers and distributions are converted to sketches.
+// It's not safe for concurrent use.
+type DogStatsDAggregator struct {
+	contexts map[string]*dogStatsDContext
+}
+
+// dogStatsDContext is the state of a metric, identified by its name, type and tags, over a flush interval.
+type dogStatsDContext struct {
+	name string
+	typ  DogStatsDType
+	tags []string
+
+	value float64
+	set   map[string]struct{}
+	sum   float64
+	bins  map[int32]uint32
+}
+
+func NewDogStatsDAggregator() *DogStatsDAggregator {
+	return &DogStatsDAggregator{contexts: map[string]*dogStatsDContext{}}
+}
+
+// Add adds the values of m to the aggregated metric it belongs to.
+func (a *DogStatsDAggregator) Add(m DogStatsDMetric) {
+	tags := slices.Clone(m.Tags)
+	slices.Sort(tags)
+	key := fmt.Sprintf("%s|%d|%s", m.Name, m.Type, strings.Join(tags, ","))
+
+	c := a.contexts[key]
+	if c == nil {
+		c = &dogStatsDContext{name: m.Name, typ: m.Type, tags: tags}
+		a.contexts[key] = c
+	}
+
+	switch m.Type {
+	case DogStatsDCount:
+		for _, v := range m.Values {
+			c.value += v / m.SampleRate
+		}
+	case DogStatsDGauge:
+		c.value = m.Values[len(m.Values)-1]
+	case DogStatsDSet:
+		if c.set == nil {
+			c.set = map[string]struct{}{}
+		}
+		c.set[m.SetValue] = struct{}{}
+	case DogStatsDHistogram, DogStatsDDistribution:
+		if c.bins == nil {
+			c.bins = map[int32]uint32{}
+		}
+		count := uint32(max(1, math.Round(1/m.SampleRate)))
+		for _, v := range m.Values {
+			c.sum += v * float64(count)
+			c.bins[sketchKey(v)] += count
+		}
+	}
+}
+
+// Flush returns the metrics aggregated since the previous flush as series and sketches timestamped with ts,
+// the Unix timestamp in milliseconds, and resets the aggregator. interval is the flush interval in seconds.
+func (a *DogStatsDAggregator) Flush(ts, interval int64, resources []Resource) ([]Series, []Sketch) {
+	var (
+		series   []Series
+		sketches []Sketch
+	)
+	for _, c := range a.contexts {
+		switch c.typ {
+		case DogStatsDCount:
+			series = append(series, Series{Metric: c.name, Type: MetricTypeCount, Points: []Point{{Timestamp: ts, Value: c.value}}, Tags: c.tags, Resources: resources, Interval: interval})
+		case DogStatsDGauge:
+			series = append(series, Series{Metric: c.name, Type: MetricTypeGauge, Points: []Point{{Timestamp: ts, Value: c.value}}, Tags: c.tags, Resources: resources})
+		case DogStatsDSet:
+			series = append(series, Series{Metric: c.name, Type: MetricTypeGauge, Points: []Point{{Timestamp: ts, Value: float64(len(c.set))}}, Tags: c.tags, Resources: resources})
+		case DogStatsDHistogram, DogStatsDDistribution:
+			p := SketchPoint{Timestamp: ts, Sum: c.sum, Keys: make([]int32, 0, len(c.bins)), Counts: make([]uint32, 0, len(c.bins))}
+			for key := range c.bins {
+				p.Keys = append(p.Keys, key)
+			}
+			slices.Sort(p.Keys)
+			for _, key := range p.Keys {
+				p.Counts = append(p.Counts, c.bins[key])
+			}
+			sketches = append(sketches, Sketch{Metric: c.name, Tags: c.tags, Resources: resources, Points: []SketchPoint{p}})
+		}
+	}
+
+	clear(a.contexts)
+	return series, sketches
+}
+
+// sketchKey returns the key of the sketch bin v is mapped to by the Datadog Agent.
+func sketchKey(v float64) int32 {
+	switch {
+	case math.Abs(v) < sketchMinValue:
+		return 0
+	case v < 0:
+		return -sketchKey(-v)
+	default:
+		return int32(min(math.Ceil(math.Log(v)/sketchGammaLn), math.MaxInt16)) + sketchBias
+	}
+}
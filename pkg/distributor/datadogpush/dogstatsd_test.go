@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDogStatsDLine(t *testing.T) {
+	testCases := map[string]struct {
+		line        string
+		expected    DogStatsDMetric
+		expectedOk  bool
+		expectedErr string
+	}{
+		"count with sample rate and tags": {
+			line:       "page.views:2|c|@0.5|#env:prod,region:eu",
+			expected:   DogStatsDMetric{Name: "page.views", Type: DogStatsDCount, Values: []float64{2}, SampleRate: 0.5, Tags: []string{"env:prod", "region:eu"}},
+			expectedOk: true,
+		},
+		"gauge with container ID and timestamp": {
+			line:       "queue.size:12.5|g|c:abc|T1700000000",
+			expected:   DogStatsDMetric{Name: "queue.size", Type: DogStatsDGauge, Values: []float64{12.5}, SampleRate: 1},
+			expectedOk: true,
+		},
+		"timer with multiple values": {
+			line:       "request.duration:10:20|ms",
+			expected:   DogStatsDMetric{Name: "request.duration", Type: DogStatsDHistogram, Values: []float64{10, 20}, SampleRate: 1},
+			expectedOk: true,
+		},
+		"set": {
+			line:       "users.uniques:user-1|s",
+			expected:   DogStatsDMetric{Name: "users.uniques", Type: DogStatsDSet, SetValue: "user-1", SampleRate: 1},
+			expectedOk: true,
+		},
+		"event": {
+			line: "_e{5,4}:title|text",
+		},
+		"service check": {
+			line: "_sc|service.up|0",
+		},
+		"empty line": {
+			line: " ",
+		},
+		"missing type": {
+			line:        "page.views:1",
+			expectedErr: "the metric type is missing",
+		},
+		"unsupported type": {
+			line:        "page.views:1|x",
+			expectedErr: `unsupported metric type "x"`,
+		},
+		"invalid value": {
+			line:        "page.views:NaN|c",
+			expectedErr: `invalid value "NaN"`,
+		},
+		"invalid sample rate": {
+			line:        "page.views:1|c|@2",
+			expectedErr: `invalid sample rate "2"`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m, ok, err := ParseDogStatsDLine([]byte(testCase.line))
+			if testCase.expectedErr != "" {
+				require.ErrorContains(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedOk, ok)
+			assert.Equal(t, testCase.expected, m)
+		})
+	}
+}
+
+func TestDogStatsDAggregator(t *testing.T) {
+	a := NewDogStatsDAggregator()
+	for _, line := range []string{
+		"page.views:1|c|#env:prod",
+		"page.views:2|c|@0.5|#env:prod",
+		"page.views:1|c|#env:dev",
+		"queue.size:3|g",
+		"queue.size:5|g",
+		"users.uniques:user-1|s",
+		"users.uniques:user-2|s",
+		"users.uniques:user-1|s",
+		"request.duration:1:1|h",
+		"request.duration:-1|d",
+	} {
+		m, ok, err := ParseDogStatsDLine([]byte(line))
+		require.NoError(t, err)
+		require.True(t, ok)
+		a.Add(m)
+	}
+
+	resources := []Resource{{Type: "dogstatsd_server", Name: "distributor-1"}}
+	series, sketches := a.Flush(1700000000000, 10, resources)
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Metric != series[j].Metric {
+			return series[i].Metric < series[j].Metric
+		}
+		return series[i].Tags[0] < series[j].Tags[0]
+	})
+	sort.Slice(sketches, func(i, j int) bool {
+		return sketches[i].Points[0].Sum > sketches[j].Points[0].Sum
+	})
+
+	assert.Equal(t, []Series{
+		{Metric: "page.views", Type: MetricTypeCount, Points: []Point{{Timestamp: 1700000000000, Value: 1}}, Tags: []string{"env:dev"}, Resources: resources, Interval: 10},
+		{Metric: "page.views", Type: MetricTypeCount, Points: []Point{{Timestamp: 1700000000000, Value: 5}}, Tags: []string{"env:prod"}, Resources: resources, Interval: 10},
+		{Metric: "queue.size", Type: MetricTypeGauge, Points: []Point{{Timestamp: 1700000000000, Value: 5}}, Resources: resources},
+		{Metric: "users.uniques", Type: MetricTypeGauge, Points: []Point{{Timestamp: 1700000000000, Value: 2}}, Resources: resources},
+	}, series)
+
+	// Histograms and distributions are mapped to the same sketch keys as the Datadog Agent.
+	assert.Equal(t, []Sketch{
+		{Metric: "request.duration", Resources: resources, Points: []SketchPoint{{Timestamp: 1700000000000, Sum: 2, Keys: []int32{1338}, Counts: []uint32{2}}}},
+		{Metric: "request.duration", Resources: resources, Points: []SketchPoint{{Timestamp: 1700000000000, Sum: -1, Keys: []int32{-1338}, Counts: []uint32{1}}}},
+	}, sketches)
+
+	// The aggregator is reset on flush.
+	series, sketches = a.Flush(1700000010000, 10, resources)
+	assert.Empty(t, series)
+	assert.Empty(t, sketches)
+}
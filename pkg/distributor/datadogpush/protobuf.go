@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The Datadog Agent payloads are decoded from the wire format directly, rather than with generated code,
+// as only a few fields of a few messages are used.
+
+// field is a decoded protobuf field. u64 holds the value of varint and fixed fields, and b the value of
+// length-delimited fields.
+type field struct {
+	num protowire.Number
+	typ protowire.Type
+	u64 uint64
+	b   []byte
+}
+
+func (f field) expect(typ protowire.Type) error {
+	if f.typ != typ {
+		return fmt.Errorf("unexpected wire type %d for field %d", f.typ, f.num)
+	}
+	return nil
+}
+
+// decodeFields calls fn for each field of the protobuf message encoded in b.
+func decodeFields(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.u64, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.u64 = uint64(v)
+		case protowire.Fixed64Type:
+			f.u64, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.b, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeVarints calls fn for each value of a repeated varint field, which may be packed or not.
+func decodeVarints(f field, fn func(v uint64)) error {
+	switch f.typ {
+	case protowire.VarintType:
+		fn(f.u64)
+		return nil
+	case protowire.BytesType:
+		b := f.b
+		for len(b) > 0 {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fn(v)
+			b = b[n:]
+		}
+		return nil
+	default:
+		return f.expect(protowire.VarintType)
+	}
+}
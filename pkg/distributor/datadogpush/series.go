@@ -0,0 +1,280 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MetricType is the type of a Datadog series, with the values of the v2 series intake API.
+type MetricType int32
+
+const (
+	MetricTypeUnspecified MetricType = 0
+	MetricTypeCount       MetricType = 1
+	MetricTypeRate        MetricType = 2
+	MetricTypeGauge       MetricType = 3
+)
+
+func parseMetricTypeV1(typ string) (MetricType, error) {
+	switch typ {
+	case "":
+		return MetricTypeUnspecified, nil
+	case "count":
+		return MetricTypeCount, nil
+	case "rate":
+		return MetricTypeRate, nil
+	case "gauge":
+		return MetricTypeGauge, nil
+	default:
+		return 0, fmt.Errorf("unsupported metric type %q", typ)
+	}
+}
+
+// Resource is a resource a Datadog series is attached to, for example its host.
+type Resource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// Point is a point of a Datadog series.
+type Point struct {
+	// Timestamp is the Unix timestamp of the point in milliseconds.
+	Timestamp int64
+	Value     float64
+}
+
+// Series is a Datadog series, as sent to the v1 or v2 series intake API.
+type Series struct {
+	Metric    string
+	Type      MetricType
+	Points    []Point
+	Tags      []string
+	Resources []Resource
+	// Interval is the interval in seconds count and rate points are measured over.
+	Interval int64
+	Unit     string
+}
+
+type seriesPayloadV1 struct {
+	Series []struct {
+		Metric   string       `json:"metric"`
+		Points   [][2]float64 `json:"points"`
+		Type     string       `json:"type"`
+		Interval int64        `json:"interval"`
+		Host     string       `json:"host"`
+		Device   string       `json:"device"`
+		Tags     []string     `json:"tags"`
+	} `json:"series"`
+}
+
+// ParseSeriesV1 parses a JSON payload of the v1 series intake API, where points are [timestamp, value] pairs
+// with timestamps in seconds, and the host and device of each series are separate fields.
+func ParseSeriesV1(data []byte) ([]Series, error) {
+	var payload seriesPayloadV1
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid series payload: %w", err)
+	}
+
+	series := make([]Series, 0, len(payload.Series))
+	for _, s := range payload.Series {
+		typ, err := parseMetricTypeV1(s.Type)
+		if err != nil {
+			return nil, fmt.Errorf("series %q: %w", s.Metric, err)
+		}
+
+		converted := Series{
+			Metric:   s.Metric,
+			Type:     typ,
+			Points:   make([]Point, 0, len(s.Points)),
+			Tags:     s.Tags,
+			Interval: s.Interval,
+		}
+		for _, p := range s.Points {
+			converted.Points = append(converted.Points, Point{Timestamp: secondsToMillis(p[0]), Value: p[1]})
+		}
+		if s.Host != "" {
+			converted.Resources = append(converted.Resources, Resource{Type: "host", Name: s.Host})
+		}
+		if s.Device != "" {
+			converted.Resources = append(converted.Resources, Resource{Type: "device", Name: s.Device})
+		}
+		series = append(series, converted)
+	}
+	return series, nil
+}
+
+type seriesPayloadV2 struct {
+	Series []struct {
+		Metric string     `json:"metric"`
+		Type   MetricType `json:"type"`
+		Points []struct {
+			Timestamp int64   `json:"timestamp"`
+			Value     float64 `json:"value"`
+		} `json:"points"`
+		Resources []Resource `json:"resources"`
+		Tags      []string   `json:"tags"`
+		Interval  int64      `json:"interval"`
+		Unit      string     `json:"unit"`
+	} `json:"series"`
+}
+
+// ParseSeriesV2JSON parses a JSON payload of the v2 series intake API.
+func ParseSeriesV2JSON(data []byte) ([]Series, error) {
+	var payload seriesPayloadV2
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid series payload: %w", err)
+	}
+
+	series := make([]Series, 0, len(payload.Series))
+	for _, s := range payload.Series {
+		if err := validateMetricType(s.Type); err != nil {
+			return nil, fmt.Errorf("series %q: %w", s.Metric, err)
+		}
+
+		converted := Series{
+			Metric:    s.Metric,
+			Type:      s.Type,
+			Points:    make([]Point, 0, len(s.Points)),
+			Tags:      s.Tags,
+			Resources: s.Resources,
+			Interval:  s.Interval,
+			Unit:      s.Unit,
+		}
+		for _, p := range s.Points {
+			converted.Points = append(converted.Points, Point{Timestamp: p.Timestamp * 1000, Value: p.Value})
+		}
+		series = append(series, converted)
+	}
+	return series, nil
+}
+
+// ParseSeriesV2Protobuf parses a protobuf payload of the v2 series intake API, the MetricPayload message
+// sent by the Datadog Agent.
+func ParseSeriesV2Protobuf(data []byte) ([]Series, error) {
+	var series []Series
+	err := decodeFields(data, func(f field) error {
+		// MetricPayload.series
+		if f.num != 1 {
+			return nil
+		}
+		if err := f.expect(protowire.BytesType); err != nil {
+			return err
+		}
+
+		s, err := decodeSeries(f.b)
+		if err != nil {
+			return err
+		}
+		series = append(series, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid series payload: %w", err)
+	}
+	return series, nil
+}
+
+func decodeSeries(data []byte) (Series, error) {
+	var s Series
+	err := decodeFields(data, func(f field) error {
+		switch f.num {
+		case 1: // resources
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			var r Resource
+			err := decodeFields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					if err := f.expect(protowire.BytesType); err != nil {
+						return err
+					}
+					r.Type = string(f.b)
+				case 2:
+					if err := f.expect(protowire.BytesType); err != nil {
+						return err
+					}
+					r.Name = string(f.b)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			s.Resources = append(s.Resources, r)
+		case 2: // metric
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			s.Metric = string(f.b)
+		case 3: // tags
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			s.Tags = append(s.Tags, string(f.b))
+		case 4: // points
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			var p Point
+			err := decodeFields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					if err := f.expect(protowire.Fixed64Type); err != nil {
+						return err
+					}
+					p.Value = math.Float64frombits(f.u64)
+				case 2:
+					if err := f.expect(protowire.VarintType); err != nil {
+						return err
+					}
+					p.Timestamp = int64(f.u64) * 1000
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			s.Points = append(s.Points, p)
+		case 5: // type
+			if err := f.expect(protowire.VarintType); err != nil {
+				return err
+			}
+			s.Type = MetricType(f.u64)
+		case 6: // unit
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			s.Unit = string(f.b)
+		case 8: // interval
+			if err := f.expect(protowire.VarintType); err != nil {
+				return err
+			}
+			s.Interval = int64(f.u64)
+		}
+		return nil
+	})
+	if err != nil {
+		return Series{}, err
+	}
+	if err := validateMetricType(s.Type); err != nil {
+		return Series{}, fmt.Errorf("series %q: %w", s.Metric, err)
+	}
+	return s, nil
+}
+
+func validateMetricType(typ MetricType) error {
+	if typ < MetricTypeUnspecified || typ > MetricTypeGauge {
+		return fmt.Errorf("unsupported metric type %d", typ)
+	}
+	return nil
+}
+
+func secondsToMillis(seconds float64) int64 {
+	return int64(seconds * 1000)
+}
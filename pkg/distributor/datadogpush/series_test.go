@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseSeriesV1(t *testing.T) {
+	series, err := ParseSeriesV1([]byte(`{"series": [
+		{"metric": "system.load.1", "points": [[1700000000, 0.5], [1700000010.5, 0.7]], "type": "gauge", "host": "web01", "tags": ["env:prod"]},
+		{"metric": "app.requests", "points": [[1700000000, 12]], "type": "count", "interval": 10, "device": "eth0"},
+		{"metric": "app.untyped", "points": [[1700000000, 1]]}
+	]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Series{
+		{
+			Metric:    "system.load.1",
+			Type:      MetricTypeGauge,
+			Points:    []Point{{Timestamp: 1700000000000, Value: 0.5}, {Timestamp: 1700000010500, Value: 0.7}},
+			Tags:      []string{"env:prod"},
+			Resources: []Resource{{Type: "host", Name: "web01"}},
+		},
+		{
+			Metric:    "app.requests",
+			Type:      MetricTypeCount,
+			Points:    []Point{{Timestamp: 1700000000000, Value: 12}},
+			Resources: []Resource{{Type: "device", Name: "eth0"}},
+			Interval:  10,
+		},
+		{
+			Metric: "app.untyped",
+			Type:   MetricTypeUnspecified,
+			Points: []Point{{Timestamp: 1700000000000, Value: 1}},
+		},
+	}, series)
+
+	t.Run("invalid type", func(t *testing.T) {
+		_, err := ParseSeriesV1([]byte(`{"series": [{"metric": "foo", "points": [[1700000000, 1]], "type": "histogram"}]}`))
+		require.ErrorContains(t, err, `series "foo": unsupported metric type "histogram"`)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, err := ParseSeriesV1([]byte(`{"series": [`))
+		require.ErrorContains(t, err, "invalid series payload")
+	})
+}
+
+func TestParseSeriesV2JSON(t *testing.T) {
+	series, err := ParseSeriesV2JSON([]byte(`{"series": [
+		{"metric": "app.requests", "type": 2, "points": [{"timestamp": 1700000000, "value": 1.2}], "resources": [{"type": "host", "name": "web01"}], "tags": ["env:prod"], "interval": 10, "unit": "request"}
+	]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Series{{
+		Metric:    "app.requests",
+		Type:      MetricTypeRate,
+		Points:    []Point{{Timestamp: 1700000000000, Value: 1.2}},
+		Tags:      []string{"env:prod"},
+		Resources: []Resource{{Type: "host", Name: "web01"}},
+		Interval:  10,
+		Unit:      "request",
+	}}, series)
+
+	_, err = ParseSeriesV2JSON([]byte(`{"series": [{"metric": "foo", "type": 4}]}`))
+	require.ErrorContains(t, err, `series "foo": unsupported metric type 4`)
+}
+
+func TestParseSeriesV2Protobuf(t *testing.T) {
+	series, err := ParseSeriesV2Protobuf(encodeMetricPayload(
+		encodeMetricSeries("app.requests", MetricTypeCount, 10, []string{"env:prod", "team:a"}, []Resource{{Type: "host", Name: "web01"}}, []Point{{Timestamp: 1700000000000, Value: 3}, {Timestamp: 1700000010000, Value: 4}}),
+		encodeMetricSeries("system.load.1", MetricTypeGauge, 0, nil, nil, []Point{{Timestamp: 1700000000000, Value: 0.5}}),
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Series{
+		{
+			Metric:    "app.requests",
+			Type:      MetricTypeCount,
+			Points:    []Point{{Timestamp: 1700000000000, Value: 3}, {Timestamp: 1700000010000, Value: 4}},
+			Tags:      []string{"env:prod", "team:a"},
+			Resources: []Resource{{Type: "host", Name: "web01"}},
+			Interval:  10,
+		},
+		{
+			Metric: "system.load.1",
+			Type:   MetricTypeGauge,
+			Points: []Point{{Timestamp: 1700000000000, Value: 0.5}},
+		},
+	}, series)
+
+	t.Run("truncated", func(t *testing.T) {
+		payload := encodeMetricPayload(encodeMetricSeries("foo", MetricTypeGauge, 0, nil, nil, []Point{{Timestamp: 1700000000000, Value: 1}}))
+		_, err := ParseSeriesV2Protobuf(payload[:len(payload)-1])
+		require.ErrorContains(t, err, "invalid series payload")
+	})
+
+	t.Run("unexpected wire type", func(t *testing.T) {
+		payload := protowire.AppendTag(nil, 1, protowire.VarintType)
+		payload = protowire.AppendVarint(payload, 1)
+		_, err := ParseSeriesV2Protobuf(payload)
+		require.ErrorContains(t, err, "unexpected wire type 0 for field 1")
+	})
+}
+
+func encodeMetricPayload(series ...[]byte) []byte {
+	var b []byte
+	for _, s := range series {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, s)
+	}
+	return b
+}
+
+func encodeMetricSeries(metric string, typ MetricType, interval int64, tags []string, resources []Resource, points []Point) []byte {
+	var b []byte
+	for _, r := range resources {
+		var rb []byte
+		rb = protowire.AppendTag(rb, 1, protowire.BytesType)
+		rb = protowire.AppendString(rb, r.Type)
+		rb = protowire.AppendTag(rb, 2, protowire.BytesType)
+		rb = protowire.AppendString(rb, r.Name)
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, rb)
+	}
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, metric)
+	for _, tag := range tags {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, tag)
+	}
+	for _, p := range points {
+		var pb []byte
+		pb = protowire.AppendTag(pb, 1, protowire.Fixed64Type)
+		pb = protowire.AppendFixed64(pb, math.Float64bits(p.Value))
+		pb = protowire.AppendTag(pb, 2, protowire.VarintType)
+		pb = protowire.AppendVarint(pb, uint64(p.Timestamp/1000))
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, pb)
+	}
+	b = protowire.AppendTag(b, 5, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(typ))
+	if interval > 0 {
+		b = protowire.AppendTag(b, 8, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(interval))
+	}
+	return b
+}
@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// The Datadog Agent maps the values added to its sketches to keys with a relative accuracy of 1/128.
+	// Values whose magnitude is lower than sketchMinValue are mapped to key 0.
+	sketchRelativeAccuracy = 1.0 / 128
+	sketchMinValue         = 1e-9
+
+	// sketchSchema is the schema of the native histograms sketches are converted to. Its buckets are the
+	// smallest ones that are wider than the ones of the sketches.
+	sketchSchema = 5
+)
+
+var (
+	sketchGammaLn = math.Log1p(2 * sketchRelativeAccuracy)
+	sketchBias    = 1 - int32(math.Floor(math.Log(sketchMinValue)/sketchGammaLn))
+)
+
+// Sketch is a Datadog distribution metric, as sent to the sketches intake API.
+type Sketch struct {
+	Metric    string
+	Tags      []string
+	Resources []Resource
+	Points    []SketchPoint
+}
+
+// SketchPoint is the distribution of the values of a Datadog distribution metric over an interval.
+type SketchPoint struct {
+	// Timestamp is the Unix timestamp of the point in milliseconds.
+	Timestamp int64
+	Sum       float64
+	// Keys and Counts are the keys of the non-empty bins of the sketch, and the number of values in each one.
+	Keys   []int32
+	Counts []uint32
+}
+
+// ParseSketches parses a protobuf payload of the sketches intake API, the SketchPayload message sent by
+// the Datadog Agent. The deprecated distributions of the sketches are ignored.
+func ParseSketches(data []byte) ([]Sketch, error) {
+	var sketches []Sketch
+	err := decodeFields(data, func(f field) error {
+		// SketchPayload.sketches
+		if f.num != 1 {
+			return nil
+		}
+		if err := f.expect(protowire.BytesType); err != nil {
+			return err
+		}
+
+		s, err := decodeSketch(f.b)
+		if err != nil {
+			return err
+		}
+		sketches = append(sketches, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid sketches payload: %w", err)
+	}
+	return sketches, nil
+}
+
+func decodeSketch(data []byte) (Sketch, error) {
+	var s Sketch
+	err := decodeFields(data, func(f field) error {
+		switch f.num {
+		case 1: // metric
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			s.Metric = string(f.b)
+		case 2: // host
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			if len(f.b) > 0 {
+				s.Resources = append(s.Resources, Resource{Type: "host", Name: string(f.b)})
+			}
+		case 4: // tags
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			s.Tags = append(s.Tags, string(f.b))
+		case 7: // dogsketches
+			if err := f.expect(protowire.BytesType); err != nil {
+				return err
+			}
+			p, err := decodeSketchPoint(f.b)
+			if err != nil {
+				return fmt.Errorf("sketch %q: %w", s.Metric, err)
+			}
+			s.Points = append(s.Points, p)
+		}
+		return nil
+	})
+	return s, err
+}
+
+func decodeSketchPoint(data []byte) (SketchPoint, error) {
+	var p SketchPoint
+	err := decodeFields(data, func(f field) error {
+		switch f.num {
+		case 1: // ts
+			if err := f.expect(protowire.VarintType); err != nil {
+				return err
+			}
+			p.Timestamp = int64(f.u64) * 1000
+		case 6: // sum
+			if err := f.expect(protowire.Fixed64Type); err != nil {
+				return err
+			}
+			p.Sum = math.Float64frombits(f.u64)
+		case 7: // k
+			return decodeVarints(f, func(v uint64) {
+				p.Keys = append(p.Keys, int32(protowire.DecodeZigZag(v)))
+			})
+		case 8: // n
+			return decodeVarints(f, func(v uint64) {
+				p.Counts = append(p.Counts, uint32(v))
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return SketchPoint{}, err
+	}
+	if len(p.Keys) != len(p.Counts) {
+		return SketchPoint{}, fmt.Errorf("the number of keys (%d) and counts (%d) of a sketch don't match", len(p.Keys), len(p.Counts))
+	}
+	return p, nil
+}
+
+// Histogram converts the sketch to a gauge native histogram, since each sketch is the distribution of
+// the values over a single interval. The count of each bin of the sketch is added to the bucket of the
+// native histogram containing the value the bin represents.
+func (p SketchPoint) Histogram() *histogram.Histogram {
+	h := &histogram.Histogram{
+		CounterResetHint: histogram.GaugeType,
+		Schema:           sketchSchema,
+		ZeroThreshold:    sketchMinValue,
+		Sum:              p.Sum,
+	}
+
+	positive := map[int32]uint64{}
+	negative := map[int32]uint64{}
+	for i, key := range p.Keys {
+		count := uint64(p.Counts[i])
+		h.Count += count
+
+		switch {
+		case key == 0:
+			h.ZeroCount += count
+		case key > 0:
+			positive[sketchBucketIndex(key)] += count
+		default:
+			negative[sketchBucketIndex(-key)] += count
+		}
+	}
+
+	h.PositiveSpans, h.PositiveBuckets = spansAndDeltas(positive)
+	h.NegativeSpans, h.NegativeBuckets = spansAndDeltas(negative)
+	return h
+}
+
+// sketchBucketIndex returns the index of the native histogram bucket containing the value represented by
+// the positive sketch key.
+func sketchBucketIndex(key int32) int32 {
+	value := math.Exp(float64(key-sketchBias) * sketchGammaLn)
+	return int32(math.Ceil(math.Log2(value) * (1 << sketchSchema)))
+}
+
+func spansAndDeltas(buckets map[int32]uint64) ([]histogram.Span, []int64) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	indexes := make([]int32, 0, len(buckets))
+	for idx := range buckets {
+		indexes = append(indexes, idx)
+	}
+	slices.Sort(indexes)
+
+	var (
+		spans  []histogram.Span
+		deltas = make([]int64, 0, len(indexes))
+		prev   int64
+	)
+	for i, idx := range indexes {
+		switch {
+		case i == 0:
+			spans = append(spans, histogram.Span{Offset: idx, Length: 1})
+		case idx == indexes[i-1]+1:
+			spans[len(spans)-1].Length++
+		default:
+			spans = append(spans, histogram.Span{Offset: idx - indexes[i-1] - 1, Length: 1})
+		}
+
+		count := int64(buckets[idx])
+		deltas = append(deltas, count-prev)
+		prev = count
+	}
+	return spans, deltas
+}
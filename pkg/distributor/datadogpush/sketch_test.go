@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package datadogpush
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseSketches(t *testing.T) {
+	sketches, err := ParseSketches(encodeSketchPayload(
+		encodeSketch("request.duration", "web01", []string{"env:prod"}, SketchPoint{Timestamp: 1700000000000, Sum: 4.5, Keys: []int32{-1338, 0, 1338}, Counts: []uint32{1, 2, 3}}),
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Sketch{{
+		Metric:    "request.duration",
+		Tags:      []string{"env:prod"},
+		Resources: []Resource{{Type: "host", Name: "web01"}},
+		Points:    []SketchPoint{{Timestamp: 1700000000000, Sum: 4.5, Keys: []int32{-1338, 0, 1338}, Counts: []uint32{1, 2, 3}}},
+	}}, sketches)
+
+	t.Run("mismatching keys and counts", func(t *testing.T) {
+		_, err := ParseSketches(encodeSketchPayload(
+			encodeSketch("request.duration", "", nil, SketchPoint{Timestamp: 1700000000000, Keys: []int32{1, 2}, Counts: []uint32{1}}),
+		))
+		require.ErrorContains(t, err, `sketch "request.duration": the number of keys (2) and counts (1) of a sketch don't match`)
+	})
+}
+
+func TestSketchPoint_Histogram(t *testing.T) {
+	// Key sketchBias represents 1, and each following key is larger by a factor of 1+2/128.
+	p := SketchPoint{
+		Timestamp: 1700000000000,
+		Sum:       10,
+		Keys:      []int32{-sketchBias, 0, sketchBias, sketchBias + 1, sketchBias + 2, sketchBias + 64},
+		Counts:    []uint32{1, 2, 3, 4, 5, 6},
+	}
+
+	h := p.Histogram()
+	require.NoError(t, h.Validate())
+	assert.Equal(t, &histogram.Histogram{
+		CounterResetHint: histogram.GaugeType,
+		Schema:           sketchSchema,
+		ZeroThreshold:    sketchMinValue,
+		ZeroCount:        2,
+		Count:            21,
+		Sum:              10,
+		// 1 is in bucket 0, 1.015625 in bucket 1, 1.0315 in bucket 2 and 2.6945 in bucket 46.
+		PositiveSpans:   []histogram.Span{{Offset: 0, Length: 3}, {Offset: 43, Length: 1}},
+		PositiveBuckets: []int64{3, 1, 1, 1},
+		NegativeSpans:   []histogram.Span{{Offset: 0, Length: 1}},
+		NegativeBuckets: []int64{1},
+	}, h)
+
+	for _, v := range []float64{1e-6, 0.5, 1, 3, 1000, 1e9} {
+		key := int32(math.Round(math.Log(v)/sketchGammaLn)) + sketchBias
+		idx := sketchBucketIndex(key)
+
+		// The bucket contains the value represented by the key, which is within the relative accuracy of v.
+		upper := math.Pow(2, float64(idx)/(1<<sketchSchema))
+		lower := math.Pow(2, float64(idx-1)/(1<<sketchSchema))
+		assert.Greater(t, v*(1+2*sketchRelativeAccuracy), lower, "value %v", v)
+		assert.Less(t, v/(1+2*sketchRelativeAccuracy), upper, "value %v", v)
+	}
+}
+
+func encodeSketchPayload(sketches ...[]byte) []byte {
+	var b []byte
+	for _, s := range sketches {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, s)
+	}
+	return b
+}
+
+func encodeSketch(metric, host string, tags []string, points ...SketchPoint) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, metric)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, host)
+	for _, tag := range tags {
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendString(b, tag)
+	}
+	for _, p := range points {
+		var pb []byte
+		pb = protowire.AppendTag(pb, 1, protowire.VarintType)
+		pb = protowire.AppendVarint(pb, uint64(p.Timestamp/1000))
+		pb = protowire.AppendTag(pb, 6, protowire.Fixed64Type)
+		pb = protowire.AppendFixed64(pb, math.Float64bits(p.Sum))
+
+		// Keys are packed, as encoded by the Datadog Agent, and counts are not, as allowed by the protobuf
+		// encoding of repeated fields.
+		var keys []byte
+		for _, k := range p.Keys {
+			keys = protowire.AppendVarint(keys, protowire.EncodeZigZag(int64(k)))
+		}
+		pb = protowire.AppendTag(pb, 7, protowire.BytesType)
+		pb = protowire.AppendBytes(pb, keys)
+		for _, n := range p.Counts {
+			pb = protowire.AppendTag(pb, 8, protowire.VarintType)
+			pb = protowire.AppendVarint(pb, uint64(n))
+		}
+
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendBytes(b, pb)
+	}
+	return b
+}
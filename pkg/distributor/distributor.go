@@ -168,6 +168,13 @@ type Distributor struct {
 	sampleValidationMetrics   *sampleValidationMetrics
 	exemplarValidationMetrics *exemplarValidationMetrics
 	metadataValidationMetrics *metadataValidationMetrics
+	ingestionPipelineMetrics  *ingestionPipelineMetrics
+
+	// Per-tenant ingestion pipelines.
+	ingestionPipelines *ingestionPipelines
+
+	// Buffers the write requests while the ingesters are unavailable. Nil if disabled.
+	writeBuffer *writeBuffer
 
 	// Metrics to be passed to distributor push handlers
 	PushMetrics *PushMetrics
@@ -262,6 +269,17 @@ type Config struct {
 
 	// Change the implementation of OTel startTime from a real zero to a special NaN value.
 	EnableStartTimeQuietZero bool `yaml:"start_time_quiet_zero" category:"advanced" doc:"hidden"`
+
+	// OTLP gRPC receiver disabled by default
+	EnableOTLPGRPCReceiver bool `yaml:"otlp_grpc_receiver_enabled" category:"experimental"`
+
+	Graphite GraphiteConfig `yaml:"graphite"`
+
+	Datadog DatadogConfig `yaml:"datadog"`
+
+	WriteBuffer WriteBufferConfig `yaml:"write_buffer"`
+
+	AggregatorName string `yaml:"aggregator_name" category:"experimental"`
 }
 
 // PushWrapper wraps around a push. It is similar to middleware.Interface.
@@ -273,6 +291,9 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.HATrackerConfig.RegisterFlags(f)
 	cfg.DistributorRing.RegisterFlags(f, logger)
 	cfg.RetryConfig.RegisterFlags(f)
+	cfg.Graphite.RegisterFlags(f)
+	cfg.Datadog.RegisterFlags(f)
+	cfg.WriteBuffer.RegisterFlags(f)
 
 	f.IntVar(&cfg.MaxRecvMsgSize, "distributor.max-recv-msg-size", 100<<20, "Max message size in bytes that the distributors will accept for incoming push requests to the remote write API. If exceeded, the request will be rejected.")
 	f.IntVar(&cfg.MaxOTLPRequestSize, maxOTLPRequestSizeFlag, 100<<20, "Maximum OTLP request size in bytes that the distributors accept. Requests exceeding this limit are rejected.")
@@ -283,6 +304,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.BoolVar(&cfg.EnableInfluxEndpoint, "distributor.influx-endpoint-enabled", false, "Enable Influx endpoint.")
 	f.IntVar(&cfg.ReusableIngesterPushWorkers, "distributor.reusable-ingester-push-workers", 2000, "Number of pre-allocated workers used to forward push requests to the ingesters. If 0, no workers will be used and a new goroutine will be spawned for each ingester push request. If not enough workers available, new goroutine will be spawned. (Note: this is a performance optimization, not a limiting feature.)")
 	f.BoolVar(&cfg.EnableStartTimeQuietZero, "distributor.otel-start-time-quiet-zero", false, "Change the implementation of OTel startTime from a real zero to a special NaN value.")
+	f.StringVar(&cfg.AggregatorName, "distributor.aggregator-name", "", "Value of the "+aggregatorLabel+" label added to the series output by the aggregation rules. Each distributor aggregates the series it receives, so the name must be different for each distributor, so that the series they aggregate don't collide. It should be stable across restarts, such as the name of the distributor's pod in a StatefulSet, so that restarts don't create new series. Defaults to the instance ID of the distributor in the distributors ring.")
+	f.BoolVar(&cfg.EnableOTLPGRPCReceiver, "distributor.otlp-grpc-receiver-enabled", false, "Enable the OTLP gRPC receiver, which accepts OTLP metrics export requests on the gRPC server, in addition to the OTLP HTTP endpoint. Requests are subject to -"+maxOTLPRequestSizeFlag+" and -server.grpc-max-recv-msg-size-bytes.")
 
 	cfg.DefaultLimits.RegisterFlags(f)
 }
@@ -296,6 +319,15 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 	if err := cfg.HATrackerConfig.Validate(); err != nil {
 		return err
 	}
+	if err := cfg.Graphite.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Datadog.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.WriteBuffer.Validate(); err != nil {
+		return err
+	}
 	return cfg.RetryConfig.Validate()
 }
 
@@ -309,6 +341,10 @@ type PushMetrics struct {
 	// Influx metrics.
 	influxRequestCounter       *prometheus.CounterVec
 	influxUncompressedBodySize *prometheus.HistogramVec
+	// Graphite metrics.
+	graphiteRequestCounter *prometheus.CounterVec
+	// Datadog metrics.
+	datadogRequestCounter *prometheus.CounterVec
 	// OTLP metrics.
 	otlpRequestCounter   *prometheus.CounterVec
 	uncompressedBodySize *prometheus.HistogramVec
@@ -327,6 +363,14 @@ func newPushMetrics(reg prometheus.Registerer) *PushMetrics {
 			NativeHistogramMinResetDuration: 1 * time.Hour,
 			NativeHistogramMaxBucketNumber:  100,
 		}, []string{"user"}),
+		graphiteRequestCounter: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_graphite_requests_total",
+			Help: "The total number of Graphite requests that have come in to the distributor, including batches of data points received over TCP.",
+		}, []string{"user"}),
+		datadogRequestCounter: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_datadog_requests_total",
+			Help: "The total number of Datadog requests that have come in to the distributor.",
+		}, []string{"user"}),
 		otlpRequestCounter: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name: "cortex_distributor_otlp_requests_total",
 			Help: "The total number of OTLP requests that have come in to the distributor.",
@@ -353,6 +397,18 @@ func (m *PushMetrics) ObserveInfluxUncompressedBodySize(user string, size float6
 	}
 }
 
+func (m *PushMetrics) IncGraphiteRequest(user string) {
+	if m != nil {
+		m.graphiteRequestCounter.WithLabelValues(user).Inc()
+	}
+}
+
+func (m *PushMetrics) IncDatadogRequest(user string) {
+	if m != nil {
+		m.datadogRequestCounter.WithLabelValues(user).Inc()
+	}
+}
+
 func (m *PushMetrics) IncOTLPRequest(user string) {
 	if m != nil {
 		m.otlpRequestCounter.WithLabelValues(user).Inc()
@@ -368,6 +424,8 @@ func (m *PushMetrics) ObserveUncompressedBodySize(user string, size float64) {
 func (m *PushMetrics) deleteUserMetrics(user string) {
 	m.influxRequestCounter.DeleteLabelValues(user)
 	m.influxUncompressedBodySize.DeleteLabelValues(user)
+	m.graphiteRequestCounter.DeleteLabelValues(user)
+	m.datadogRequestCounter.DeleteLabelValues(user)
 	m.otlpRequestCounter.DeleteLabelValues(user)
 	m.uncompressedBodySize.DeleteLabelValues(user)
 }
@@ -485,6 +543,7 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		sampleValidationMetrics:   newSampleValidationMetrics(reg),
 		exemplarValidationMetrics: newExemplarValidationMetrics(reg),
 		metadataValidationMetrics: newMetadataValidationMetrics(reg),
+		ingestionPipelineMetrics:  newIngestionPipelineMetrics(reg),
 
 		hashCollisionCount: promauto.With(reg).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_distributor_hash_collisions_total",
@@ -593,9 +652,25 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 	d.activeUsers = util.NewActiveUsersCleanupWithDefaultValues(d.cleanupInactiveUser)
 	d.activeGroups = activeGroupsCleanupService
 
+	aggregatorName := cfg.AggregatorName
+	if aggregatorName == "" {
+		aggregatorName = cfg.DistributorRing.Common.InstanceID
+	}
+	d.ingestionPipelines = newIngestionPipelines(limits, aggregatorName, reg, log)
+
 	d.PushWithMiddlewares = d.wrapPushWithMiddlewares(d.push)
 
-	subservices = append(subservices, d.ingesterPool, d.activeUsers)
+	subservices = append(subservices, d.ingesterPool, d.activeUsers, d.ingestionPipelines)
+
+	if cfg.Graphite.TCPListenAddress != "" || cfg.Graphite.PickleTCPListenAddress != "" {
+		// The Graphite TCP listeners are experimental.
+		subservices = append(subservices, newGraphiteTCPServer(cfg.Graphite, limits, d.PushWithMiddlewares, d.PushMetrics, log))
+	}
+
+	if cfg.Datadog.DogStatsDUDPListenAddress != "" || cfg.Datadog.DogStatsDTCPListenAddress != "" {
+		// The DogStatsD listeners are experimental.
+		subservices = append(subservices, newDogStatsDServer(cfg.Datadog, d.PushWithMiddlewares, d.PushMetrics, log))
+	}
 
 	if cfg.ReusableIngesterPushWorkers > 0 {
 		wp := concurrency.NewReusableGoroutinesPool(cfg.ReusableIngesterPushWorkers)
@@ -611,6 +686,11 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 	if cfg.IngestStorageConfig.Enabled {
 		d.ingestStorageWriter = ingest.NewWriter(d.cfg.IngestStorageConfig.KafkaConfig, log, reg)
 		subservices = append(subservices, d.ingestStorageWriter)
+	} else if canJoinDistributorsRing && cfg.WriteBuffer.Enabled {
+		// The write buffer is experimental.
+		d.writeBuffer = newWriteBuffer(cfg.WriteBuffer, limits, reg, log)
+		d.writeBuffer.replay = d.replayBufferedWriteRequest
+		subservices = append(subservices, d.writeBuffer)
 	}
 
 	// Register each metric only if the corresponding storage is enabled.
@@ -746,6 +826,11 @@ func (d *Distributor) cleanupInactiveUser(userID string) {
 	d.sampleValidationMetrics.deleteUserMetrics(userID)
 	d.exemplarValidationMetrics.deleteUserMetrics(userID)
 	d.metadataValidationMetrics.deleteUserMetrics(userID)
+	d.ingestionPipelineMetrics.deleteUserMetrics(userID)
+	d.ingestionPipelines.deleteUserMetrics(userID)
+	if d.writeBuffer != nil {
+		d.writeBuffer.deleteUserMetrics(userID)
+	}
 }
 
 func (d *Distributor) RemoveGroupMetricsForUser(userID, group string) {
@@ -753,6 +838,7 @@ func (d *Distributor) RemoveGroupMetricsForUser(userID, group string) {
 	d.discardedSamplesTooManyHaClusters.DeleteLabelValues(userID, group)
 	d.discardedSamplesRateLimited.DeleteLabelValues(userID, group)
 	d.sampleValidationMetrics.deleteUserMetricsForGroup(userID, group)
+	d.ingestionPipelineMetrics.deleteUserMetricsForGroup(userID, group)
 }
 
 // Called after distributor is asked to stop via StopAsync.
@@ -766,12 +852,7 @@ func (d *Distributor) stopping(_ error) error {
 func (d *Distributor) checkSample(ctx context.Context, userID, cluster, replica string) (removeReplicaLabel bool, _ error) {
 	// If the sample doesn't have either HA label, accept it.
 	// At the moment we want to accept these samples by default.
-	if cluster == "" || replica == "" {
-		return false, nil
-	}
-
-	// If replica label is too long, don't use it. We accept the sample here, but it will fail validation later anyway.
-	if len(replica) > d.limits.MaxLabelValueLength(userID) {
+	if !d.hasHALabels(userID, cluster, replica) {
 		return false, nil
 	}
 
@@ -786,6 +867,16 @@ func (d *Distributor) checkSample(ctx context.Context, userID, cluster, replica
 	return true, nil
 }
 
+// hasHALabels returns whether samples with the cluster and replica labels come from a Prometheus HA replica.
+func (d *Distributor) hasHALabels(userID, cluster, replica string) bool {
+	if cluster == "" || replica == "" {
+		return false
+	}
+
+	// If replica label is too long, don't use it. We accept the sample here, but it will fail validation later anyway.
+	return len(replica) <= d.limits.MaxLabelValueLength(userID)
+}
+
 // validateSamples validates samples of a single timeseries and removes the ones with duplicated timestamps.
 // Returns an error explaining the first validation finding.
 // May alter timeseries data in-place.
@@ -961,6 +1052,7 @@ func (d *Distributor) wrapPushWithMiddlewares(next PushFunc) PushFunc {
 	middlewares = append(middlewares, d.metricsMiddleware)
 	middlewares = append(middlewares, d.prePushHaDedupeMiddleware)
 	middlewares = append(middlewares, d.prePushRelabelMiddleware)
+	middlewares = append(middlewares, d.prePushIngestionPipelineMiddleware)
 	middlewares = append(middlewares, d.prePushSortAndFilterMiddleware)
 	middlewares = append(middlewares, d.prePushValidationMiddleware)
 	middlewares = append(middlewares, d.cfg.PushWrappers...)
@@ -1011,6 +1103,22 @@ func (d *Distributor) prePushHaDedupeMiddleware(next PushFunc) PushFunc {
 			numSamples += len(ts.Samples) + len(ts.Histograms)
 		}
 
+		if d.limits.HADeduplicationMode(userID) == validation.HADeduplicationModeSample {
+			// The samples of all the replicas are accepted, and merged per series and timestamp by the ingesters,
+			// which only drop the duplicate samples of the series flagged as HA deduplicated.
+			haDeduplicated := d.hasHALabels(userID, cluster, replica)
+			for ix := range req.Timeseries {
+				if haDeduplicated {
+					req.Timeseries[ix].RemoveLabel(haReplicaLabel)
+				}
+				req.Timeseries[ix].HaDeduplicated = haDeduplicated
+			}
+			if !haDeduplicated {
+				d.nonHASamples.WithLabelValues(userID).Add(float64(numSamples))
+			}
+			return next(ctx, pushReq)
+		}
+
 		removeReplica, err := d.checkSample(ctx, userID, cluster, replica)
 		if err != nil {
 			if errors.As(err, &replicasDidNotMatchError{}) {
@@ -1683,12 +1791,36 @@ func (d *Distributor) push(ctx context.Context, pushReq *Request) error {
 		ingestersSubring = d.ingestersRing.ShuffleShard(userID, d.limits.IngestionTenantShardSize(userID))
 	}
 
+	// The request may be cleaned up before sendWriteRequestToBackends() returns, so count what's written beforehand.
+	written := newWrittenStats(req)
+
 	// we must not re-use buffers now until all writes to backends (e.g. ingesters) have completed, which can happen
 	// even after this function returns. For this reason, it's unsafe to cleanup in the defer and we'll do the cleanup
 	// once all backend requests have completed (see cleanup function passed to sendWriteRequestToBackends()).
 	cleanupInDefer = false
 
-	return d.sendWriteRequestToBackends(ctx, userID, req, keys, initialMetadataIndex, ingestersSubring, partitionsSubring, pushReq.CleanUp)
+	send := func(cleanup func()) error {
+		return d.sendWriteRequestToBackends(ctx, userID, req, keys, initialMetadataIndex, ingestersSubring, partitionsSubring, cleanup)
+	}
+	if d.writeBuffer != nil {
+		err = d.writeBuffer.write(ctx, userID, req, send, pushReq.CleanUp)
+	} else {
+		err = send(pushReq.CleanUp)
+	}
+	if err != nil {
+		return err
+	}
+	pushReq.written = written
+	return nil
+}
+
+// replayBufferedWriteRequest sends a write request buffered by the write buffer to the ingesters. The write request
+// already went through the push middlewares when it was buffered.
+func (d *Distributor) replayBufferedWriteRequest(ctx context.Context, userID string, req *mimirpb.WriteRequest) error {
+	ctx = user.InjectOrgID(ctx, userID)
+	keys, initialMetadataIndex := getSeriesAndMetadataTokens(userID, req)
+	ingestersSubring := d.ingestersRing.ShuffleShard(userID, d.limits.IngestionTenantShardSize(userID))
+	return d.sendWriteRequestToBackends(ctx, userID, req, keys, initialMetadataIndex, ingestersSubring, nil, func() {})
 }
 
 // sendWriteRequestToBackends sends the input req data to backends. The backends could be:
@@ -316,6 +316,61 @@ func TestDistributor_PushWithDoBatchWorkers(t *testing.T) {
 	require.GreaterOrEqual(t, counter.Load(), int64(3))
 }
 
+func TestDistributor_PushWrittenStats(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	t.Cleanup(mtime.NowReset)
+
+	for name, tc := range map[string]struct {
+		happyIngesters  int
+		invalidSeries   bool
+		expectedWritten writtenStats
+	}{
+		"written to the ingesters": {
+			happyIngesters:  3,
+			expectedWritten: writtenStats{samples: 4, histograms: 4, exemplars: 4},
+		},
+		"some series are invalid": {
+			happyIngesters:  3,
+			invalidSeries:   true,
+			expectedWritten: writtenStats{samples: 4, histograms: 4, exemplars: 4},
+		},
+		"failed to write to the ingesters": {
+			happyIngesters: 0,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := prepareDefaultLimits()
+			limits.MaxGlobalExemplarsPerUser = 10
+
+			ds, _, _, _ := prepare(t, prepConfig{
+				numIngesters:    3,
+				happyIngesters:  tc.happyIngesters,
+				numDistributors: 1,
+				limits:          limits,
+			})
+
+			req := makeWriteRequest(now.UnixMilli(), 4, 0, true, true, "foo")
+			if tc.invalidSeries {
+				req.Timeseries = append(req.Timeseries, makeTimeseries(
+					[]string{model.MetricNameLabel, "foo", "bar", "baz", "sample", "invalid", "invalid label", "value"},
+					makeSamples(now.UnixMilli(), 1),
+					nil,
+					nil,
+				))
+			}
+			pushReq := NewParsedRequest(req)
+			err := ds[0].PushWithMiddlewares(user.InjectOrgID(context.Background(), "user"), pushReq)
+			if tc.happyIngesters == 0 || tc.invalidSeries {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedWritten, pushReq.written)
+		})
+	}
+}
+
 func TestDistributor_ContextCanceledRequest(t *testing.T) {
 	now := time.Now()
 	mtime.NowForce(now)
@@ -928,6 +983,93 @@ func TestDistributor_PushHAInstances(t *testing.T) {
 	}
 }
 
+func TestDistributor_PushHAInstances_SampleDeduplication(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.AcceptHASamples = true
+	limits.HADeduplicationMode = validation.HADeduplicationModeSample
+
+	ds, ingesters, _, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		replicationFactor: 1,
+		numDistributors:   1,
+		limits:            &limits,
+		enableTracker:     true,
+	})
+
+	// The samples of all the replicas are accepted, even if another replica is elected.
+	require.NoError(t, ds[0].HATracker.checkReplica(ctx, "user", "cluster0", "instance2", time.Now()))
+	for _, replica := range []string{"instance0", "instance1"} {
+		response, err := ds[0].Push(ctx, makeWriteRequestForGenerators(1, labelSetGenWithReplicaAndCluster(replica, "cluster0"), nil, nil))
+		require.NoError(t, err)
+		assert.Equal(t, emptyResponse, response)
+	}
+
+	// The replica label is removed, so that the samples of the replicas are merged in the same series, which
+	// is flagged for the ingesters to drop the duplicate samples.
+	series := ingesters[0].series()
+	require.Len(t, series, 1)
+	for _, ts := range series {
+		assert.Equal(t, labelAdapters("__name__", "foo", "bar", "baz", "cluster", "cluster0", "sample", "0"), ts.Labels)
+		assert.True(t, ts.HaDeduplicated)
+	}
+
+	// Series without the HA labels aren't flagged, even if the client did.
+	req := makeWriteRequestForGenerators(1, labelSetGenWithCluster("cluster1"), nil, nil)
+	req.Timeseries[0].HaDeduplicated = true
+	_, err := ds[0].Push(ctx, req)
+	require.NoError(t, err)
+	for _, ts := range ingesters[0].series() {
+		assert.Equal(t, ts.Labels[2].Value == "cluster0", ts.HaDeduplicated)
+	}
+}
+
+func TestDistributor_Push_WriteBuffer(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	dir := t.TempDir()
+
+	limits := prepareDefaultLimits()
+	limits.WriteBufferMaxSizeBytes = 1 << 20
+	limits.OutOfOrderTimeWindow = limits.WriteBufferMaxAge
+
+	ds, ingesters, _, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		replicationFactor: 1,
+		numDistributors:   1,
+		limits:            limits,
+		configure: func(cfg *Config) {
+			cfg.WriteBuffer = WriteBufferConfig{Enabled: true, Dir: dir, ReplayInterval: time.Hour}
+		},
+	})
+	require.NotNil(t, ds[0].writeBuffer)
+
+	setHappy := func(happy bool) {
+		ingesters[0].Lock()
+		ingesters[0].happy = happy
+		ingesters[0].Unlock()
+	}
+
+	// Write requests are accepted and buffered while the ingesters are unavailable.
+	setHappy(false)
+	response, err := ds[0].Push(ctx, makeWriteRequest(0, 1, 0, false, false, "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, emptyResponse, response)
+	assert.Empty(t, ingesters[0].series())
+
+	// The buffered write requests are replayed once the ingesters are available again.
+	setHappy(true)
+	ds[0].writeBuffer.replayUser(ctx, "user", time.Now())
+	series := ingesters[0].series()
+	require.Len(t, series, 1)
+	for _, ts := range series {
+		assert.Equal(t, labelAdapters("__name__", "foo", "bar", "baz", "sample", "0"), ts.Labels)
+	}
+}
+
 func TestDistributor_PushQuery(t *testing.T) {
 	const metricName = "foo"
 	ctx := user.InjectOrgID(context.Background(), "user")
@@ -7423,6 +7565,145 @@ func TestDistributor_Push_Relabel(t *testing.T) {
 	}
 }
 
+func TestDistributor_Push_IngestionPipeline(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	now := time.Now()
+	maxValue := 10.0
+
+	limits := prepareDefaultLimits()
+	limits.IngestionPipeline = []*validation.IngestionPipelineStep{
+		{Type: validation.IngestionPipelineStepDropByValue, Match: `{__name__="foo"}`, MaxValue: &maxValue},
+		{Type: validation.IngestionPipelineStepRenameMetric, NameRegex: "bar", Name: "baz"},
+	}
+
+	ds, ingesters, regs, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		replicationFactor: 1,
+		numDistributors:   1,
+		limits:            limits,
+	})
+
+	req := makeWriteRequestWith(
+		makeTimeseries([]string{"__name__", "foo", "job", "a"}, makeSamples(now.UnixMilli(), 1), nil, nil),
+		makeTimeseries([]string{"__name__", "foo", "job", "b"}, makeSamples(now.UnixMilli(), 100), nil, nil),
+		makeTimeseries([]string{"__name__", "bar", "pod", "a"}, makeSamples(now.UnixMilli(), 2), nil, nil),
+		makeTimeseries([]string{"__name__", "bar", "pod", "b"}, makeSamples(now.UnixMilli(), 3), nil, nil),
+	)
+	_, err := ds[0].Push(ctx, req)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"baz", "foo"}, ingesters[0].metricNames())
+	require.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="ingestion_pipeline_drop_by_value",user="user"} 1
+	`), "cortex_discarded_samples_total"))
+}
+
+func TestDistributor_Push_AggregationRules(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	now := time.Now()
+
+	limits := prepareDefaultLimits()
+	limits.AggregationRules = []*validation.AggregationRule{
+		{Match: `{__name__="foo"}`, Interval: model.Duration(time.Minute), Without: []string{"pod"}, Outputs: []string{validation.AggregationOutputSum}},
+		{Match: `{__name__="bar"}`, Interval: model.Duration(time.Minute), By: []string{"job"}, Outputs: []string{validation.AggregationOutputMax}, KeepInput: true},
+	}
+
+	ds, ingesters, regs, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		replicationFactor: 1,
+		numDistributors:   1,
+		limits:            limits,
+	})
+
+	req := makeWriteRequestWith(
+		makeTimeseries([]string{"__name__", "foo", "pod", "a"}, makeSamples(now.UnixMilli(), 2), nil, nil),
+		makeTimeseries([]string{"__name__", "foo", "pod", "b"}, makeSamples(now.UnixMilli(), 3), nil, nil),
+		makeTimeseries([]string{"__name__", "bar", "job", "a", "pod", "a"}, makeSamples(now.UnixMilli(), 4), nil, nil),
+		makeTimeseries([]string{"__name__", "bar", "job", "a", "pod", "b"}, makeSamples(now.UnixMilli(), 7), nil, nil),
+	)
+	_, err := ds[0].Push(ctx, req)
+	require.NoError(t, err)
+
+	// The input series of the rule keeping its input are ingested.
+	assert.Equal(t, []string{"bar"}, ingesters[0].metricNames())
+	require.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="aggregation_rules",user="user"} 2
+	`), "cortex_discarded_samples_total"))
+
+	// The output series are pushed once their window is flushed.
+	ds[0].ingestionPipelines.flush(context.Background(), now.Add(3*time.Minute), false)
+
+	windowEnd := now.UnixMilli() - now.UnixMilli()%time.Minute.Milliseconds() + time.Minute.Milliseconds()
+	assert.Equal(t, []string{"bar", "bar:1m_by_job_max", "foo:1m_without_pod_sum"}, ingesters[0].metricNames())
+	for _, ts := range ingesters[0].series() {
+		switch ts.Labels[0].Value {
+		case "bar:1m_by_job_max":
+			assert.Equal(t, labelAdapters("__name__", "bar:1m_by_job_max", "aggregator", "0", "job", "a"), ts.Labels)
+			assert.Equal(t, []mimirpb.Sample{{TimestampMs: windowEnd, Value: 7}}, ts.Samples)
+		case "foo:1m_without_pod_sum":
+			assert.Equal(t, labelAdapters("__name__", "foo:1m_without_pod_sum", "aggregator", "0"), ts.Labels)
+			assert.Equal(t, []mimirpb.Sample{{TimestampMs: windowEnd, Value: 5}}, ts.Samples)
+		}
+	}
+	require.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_distributor_aggregated_series_flushed_total The total number of series output by the aggregations of the ingestion pipelines, each with a single sample.
+		# TYPE cortex_distributor_aggregated_series_flushed_total counter
+		cortex_distributor_aggregated_series_flushed_total{user="user"} 2
+	`), "cortex_distributor_aggregated_series_flushed_total"))
+}
+
+func TestDistributor_Push_AggregationRules_MultipleDistributors(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	now := time.Now()
+
+	limits := prepareDefaultLimits()
+	limits.AggregationRules = []*validation.AggregationRule{
+		{Match: `{__name__="foo"}`, Interval: model.Duration(time.Minute), Without: []string{"pod"}, Outputs: []string{validation.AggregationOutputSum}},
+	}
+
+	ds, ingesters, _, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		replicationFactor: 1,
+		numDistributors:   2,
+		limits:            limits,
+	})
+
+	// Each distributor receives a different part of the series of the same group.
+	_, err := ds[0].Push(ctx, makeWriteRequestWith(
+		makeTimeseries([]string{"__name__", "foo", "pod", "a"}, makeSamples(now.UnixMilli(), 2), nil, nil),
+	))
+	require.NoError(t, err)
+	_, err = ds[1].Push(ctx, makeWriteRequestWith(
+		makeTimeseries([]string{"__name__", "foo", "pod", "b"}, makeSamples(now.UnixMilli(), 3), nil, nil),
+	))
+	require.NoError(t, err)
+
+	for _, d := range ds {
+		d.ingestionPipelines.flush(context.Background(), now.Add(3*time.Minute), false)
+	}
+
+	// The series output by each distributor don't collide.
+	windowEnd := now.UnixMilli() - now.UnixMilli()%time.Minute.Milliseconds() + time.Minute.Milliseconds()
+	series := ingesters[0].series()
+	require.Len(t, series, 2)
+	expected := map[string]float64{"0": 2, "1": 3}
+	for _, ts := range series {
+		require.Len(t, ts.Labels, 2)
+		assert.Equal(t, "foo:1m_without_pod_sum", ts.Labels[0].Value)
+		assert.Equal(t, aggregatorLabel, ts.Labels[1].Name)
+		assert.Equal(t, []mimirpb.Sample{{TimestampMs: windowEnd, Value: expected[ts.Labels[1].Value]}}, ts.Samples)
+		delete(expected, ts.Labels[1].Value)
+	}
+	assert.Empty(t, expected)
+}
+
 func countMockIngestersCalled(ingesters []*mockIngester, name string) int {
 	count := 0
 	for _, i := range ingesters {
@@ -0,0 +1,468 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/grpcutil"
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+
+	"github.com/grafana/mimir/pkg/distributor/graphitepush"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	utillog "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// graphiteContentTypePickle is the content type of requests to the Graphite endpoint with pickled data points.
+	graphiteContentTypePickle = "application/python-pickle"
+
+	// graphiteTCPFlushPeriod is the maximum time data points received over a plaintext TCP connection are
+	// buffered before being pushed.
+	graphiteTCPFlushPeriod = time.Second
+	// graphiteTCPMaxBatchSize is the maximum number of data points received over a plaintext TCP connection
+	// pushed in the same request.
+	graphiteTCPMaxBatchSize = 10000
+	// graphiteTCPMaxLineLength is the maximum length of a line received over a plaintext TCP connection.
+	graphiteTCPMaxLineLength = 64 * 1024
+)
+
+var errGraphiteTCPTenantIDRequired = errors.New("the Graphite TCP tenant ID is required when a Graphite TCP listen address is configured")
+
+// GraphiteConfig configures the ingestion of Graphite metrics.
+type GraphiteConfig struct {
+	EndpointEnabled        bool   `yaml:"endpoint_enabled" category:"experimental"`
+	MaxRequestSize         int    `yaml:"max_request_size" category:"experimental"`
+	TCPListenAddress       string `yaml:"tcp_listen_address" category:"experimental"`
+	PickleTCPListenAddress string `yaml:"pickle_tcp_listen_address" category:"experimental"`
+	TCPTenantID            string `yaml:"tcp_tenant_id" category:"experimental"`
+}
+
+func (cfg *GraphiteConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.EndpointEnabled, "distributor.graphite.endpoint-enabled", false, "Enable the Graphite endpoint, which accepts data points in the Graphite plaintext protocol, or pickled if the request content type is "+graphiteContentTypePickle+".")
+	f.IntVar(&cfg.MaxRequestSize, "distributor.graphite.max-request-size", 100<<20, "Maximum Graphite request size in bytes that the distributors accept, and maximum size of pickled messages received over TCP. Requests exceeding this limit are rejected.")
+	f.StringVar(&cfg.TCPListenAddress, "distributor.graphite.tcp-listen-address", "", "Address to listen on for data points in the Graphite plaintext protocol over TCP, for example :2003. Empty to disable.")
+	f.StringVar(&cfg.PickleTCPListenAddress, "distributor.graphite.pickle-tcp-listen-address", "", "Address to listen on for pickled data points over TCP, for example :2004. Empty to disable.")
+	f.StringVar(&cfg.TCPTenantID, "distributor.graphite.tcp-tenant-id", "anonymous", "Tenant the data points received over the Graphite TCP listeners are ingested for, since TCP connections can't be authenticated.")
+}
+
+func (cfg *GraphiteConfig) Validate() error {
+	if (cfg.TCPListenAddress != "" || cfg.PickleTCPListenAddress != "") && cfg.TCPTenantID == "" {
+		return errGraphiteTCPTenantIDRequired
+	}
+	return nil
+}
+
+type GraphiteHandlerLimits interface {
+	GraphiteMappings(userID string) []*validation.GraphiteMapping
+}
+
+// graphiteMappers caches the mapper of each tenant, so that the mappings of a tenant are only compiled when
+// they change.
+type graphiteMappers struct {
+	limits GraphiteHandlerLimits
+
+	mtx     sync.Mutex
+	mappers map[string]cachedGraphiteMapper
+}
+
+type cachedGraphiteMapper struct {
+	mappings []*validation.GraphiteMapping
+	mapper   *graphitepush.Mapper
+}
+
+func newGraphiteMappers(limits GraphiteHandlerLimits) *graphiteMappers {
+	return &graphiteMappers{
+		limits:  limits,
+		mappers: map[string]cachedGraphiteMapper{},
+	}
+}
+
+func (m *graphiteMappers) mapper(userID string) (*graphitepush.Mapper, error) {
+	mappings := m.limits.GraphiteMappings(userID)
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// Mappings are replaced, rather than modified, when the runtime configuration is reloaded.
+	if cached, ok := m.mappers[userID]; ok && slices.Equal(cached.mappings, mappings) {
+		return cached.mapper, nil
+	}
+
+	mapper, err := graphitepush.NewMapper(mappings)
+	if err != nil {
+		return nil, err
+	}
+	m.mappers[userID] = cachedGraphiteMapper{mappings: slices.Clone(mappings), mapper: mapper}
+	return mapper, nil
+}
+
+// graphitePointsSupplier returns a supplier of the write request with the series of points mapped with the
+// mappings of userID.
+func graphitePointsSupplier(mappers *graphiteMappers, userID string, points func() ([]graphitepush.Point, error)) supplierFunc {
+	return func() (*mimirpb.WriteRequest, func(), error) {
+		mapper, err := mappers.mapper(userID)
+		if err != nil {
+			return nil, nil, httpgrpc.Error(http.StatusInternalServerError, fmt.Sprintf("invalid Graphite mappings: %s", err))
+		}
+
+		pts, err := points()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var req mimirpb.PreallocWriteRequest
+		req.Timeseries, err = graphitepush.PointsToTimeseries(pts, mapper)
+		if err != nil {
+			return nil, nil, httpgrpc.Error(http.StatusBadRequest, err.Error())
+		}
+
+		cleanup := func() {
+			mimirpb.ReuseSlice(req.Timeseries)
+		}
+		return &req.WriteRequest, cleanup, nil
+	}
+}
+
+// GraphiteHandler is a http.Handler which accepts data points in the Graphite plaintext or pickle protocol
+// and converts them to WriteRequests.
+func GraphiteHandler(
+	maxRecvMsgSize int,
+	sourceIPs *middleware.SourceIPExtractor,
+	limits GraphiteHandlerLimits,
+	retryCfg RetryConfig,
+	push PushFunc,
+	pushMetrics *PushMetrics,
+	logger log.Logger,
+) http.Handler {
+	mappers := newGraphiteMappers(limits)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := utillog.WithContext(ctx, logger)
+		if sourceIPs != nil {
+			source := sourceIPs.Get(r)
+			if source != "" {
+				logger = utillog.WithSourceIPs(source, logger)
+			}
+		}
+
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			level.Warn(logger).Log("msg", "unable to obtain tenantID", "err", err)
+			return
+		}
+
+		pushMetrics.IncGraphiteRequest(tenantID)
+
+		req := newRequest(graphitePointsSupplier(mappers, tenantID, func() ([]graphitepush.Point, error) {
+			return parseGraphiteRequest(ctx, r, maxRecvMsgSize, logger)
+		}))
+		if err := push(ctx, req); err != nil {
+			if errors.Is(err, context.Canceled) {
+				level.Warn(logger).Log("msg", "push request canceled", "err", err)
+				w.WriteHeader(statusClientClosedRequest)
+				return
+			}
+
+			var httpCode int
+			var errorMsg string
+			if st, ok := grpcutil.ErrorToStatus(err); ok {
+				// This code is needed for a correct handling of errors returned by the supplier function.
+				// These errors are created by using the httpgrpc package.
+				httpCode = int(st.Code())
+				errorMsg = st.Message()
+			} else {
+				var distributorErr Error
+				errorMsg = err.Error()
+				if errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &distributorErr) {
+					httpCode = http.StatusServiceUnavailable
+				} else {
+					httpCode = errorCauseToHTTPStatusCode(distributorErr.Cause(), false)
+				}
+			}
+			if httpCode != http.StatusAccepted {
+				msgs := []interface{}{"msg", "detected an error while ingesting Graphite metrics request (the request may have been partially ingested)", "httpCode", httpCode, "err", err}
+				if httpCode/100 == 4 {
+					// This tag makes the error message visible for our Grafana Cloud customers.
+					msgs = append(msgs, "insight", true)
+				}
+				level.Error(logger).Log(msgs...)
+			}
+			addHeaders(w, err, r, httpCode, retryCfg)
+			http.Error(w, validUTF8Message(errorMsg), httpCode)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func parseGraphiteRequest(ctx context.Context, r *http.Request, maxSize int, logger log.Logger) ([]graphitepush.Point, error) {
+	spanLogger, _ := spanlogger.NewWithLogger(ctx, logger, "Distributor.GraphiteHandler.decodeAndConvert")
+	defer spanLogger.Span.Finish()
+
+	contentType := r.Header.Get("Content-Type")
+	spanLogger.SetTag("content_type", contentType)
+	spanLogger.SetTag("content_encoding", r.Header.Get("Content-Encoding"))
+	spanLogger.SetTag("content_length", r.ContentLength)
+
+	body := io.Reader(r.Body)
+	switch r.Header.Get("Content-Encoding") {
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, httpgrpc.Error(http.StatusBadRequest, fmt.Sprintf("gzip compression error: %s", err))
+		}
+		defer gzipReader.Close()
+		body = gzipReader
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, int64(maxSize)+1))
+	if err != nil {
+		return nil, httpgrpc.Error(http.StatusBadRequest, fmt.Sprintf("can't read body: %s", err))
+	}
+	if len(data) > maxSize {
+		return nil, httpgrpc.Error(http.StatusRequestEntityTooLarge, fmt.Sprintf("the incoming Graphite request has been rejected because its size is larger than the allowed limit of %d bytes", maxSize))
+	}
+
+	var points []graphitepush.Point
+	if contentType == graphiteContentTypePickle {
+		points, err = graphitepush.ParsePickle(data, time.Now())
+	} else {
+		points, err = graphitepush.ParsePlaintext(data, time.Now())
+	}
+	level.Debug(spanLogger).Log("msg", "decodeAndConvert complete", "bytesRead", len(data), "points", len(points), "err", err)
+	if err != nil {
+		return nil, httpgrpc.Error(http.StatusBadRequest, err.Error())
+	}
+	return points, nil
+}
+
+// graphiteTCPServer accepts data points in the Graphite plaintext and pickle protocols over TCP, and pushes them
+// for the configured tenant.
+type graphiteTCPServer struct {
+	services.Service
+
+	cfg         GraphiteConfig
+	mappers     *graphiteMappers
+	push        PushFunc
+	pushMetrics *PushMetrics
+	logger      log.Logger
+
+	plaintextListener net.Listener
+	pickleListener    net.Listener
+
+	connsMtx sync.Mutex
+	conns    map[net.Conn]struct{}
+	connsWg  sync.WaitGroup
+}
+
+func newGraphiteTCPServer(cfg GraphiteConfig, limits GraphiteHandlerLimits, push PushFunc, pushMetrics *PushMetrics, logger log.Logger) *graphiteTCPServer {
+	s := &graphiteTCPServer{
+		cfg:         cfg,
+		mappers:     newGraphiteMappers(limits),
+		push:        push,
+		pushMetrics: pushMetrics,
+		logger:      log.With(logger, "component", "graphite-tcp-server"),
+		conns:       map[net.Conn]struct{}{},
+	}
+	s.Service = services.NewBasicService(s.starting, s.running, s.stopping)
+	return s
+}
+
+func (s *graphiteTCPServer) starting(_ context.Context) error {
+	var err error
+	if s.cfg.TCPListenAddress != "" {
+		if s.plaintextListener, err = net.Listen("tcp", s.cfg.TCPListenAddress); err != nil {
+			return fmt.Errorf("failed to listen for Graphite plaintext data points: %w", err)
+		}
+	}
+	if s.cfg.PickleTCPListenAddress != "" {
+		if s.pickleListener, err = net.Listen("tcp", s.cfg.PickleTCPListenAddress); err != nil {
+			if s.plaintextListener != nil {
+				_ = s.plaintextListener.Close()
+			}
+			return fmt.Errorf("failed to listen for Graphite pickled data points: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *graphiteTCPServer) running(ctx context.Context) error {
+	if s.plaintextListener != nil {
+		go s.accept(s.plaintextListener, s.handlePlaintextConn)
+	}
+	if s.pickleListener != nil {
+		go s.accept(s.pickleListener, s.handlePickleConn)
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func (s *graphiteTCPServer) stopping(_ error) error {
+	for _, l := range []net.Listener{s.plaintextListener, s.pickleListener} {
+		if l != nil {
+			_ = l.Close()
+		}
+	}
+
+	s.connsMtx.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.connsMtx.Unlock()
+
+	s.connsWg.Wait()
+	return nil
+}
+
+func (s *graphiteTCPServer) accept(l net.Listener, handle func(net.Conn, log.Logger)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				level.Error(s.logger).Log("msg", "failed to accept Graphite TCP connection", "err", err)
+			}
+			return
+		}
+
+		s.connsMtx.Lock()
+		s.conns[conn] = struct{}{}
+		s.connsWg.Add(1)
+		s.connsMtx.Unlock()
+
+		go func() {
+			defer func() {
+				_ = conn.Close()
+
+				s.connsMtx.Lock()
+				delete(s.conns, conn)
+				s.connsMtx.Unlock()
+				s.connsWg.Done()
+			}()
+
+			handle(conn, log.With(s.logger, "remote_addr", conn.RemoteAddr().String()))
+		}()
+	}
+}
+
+// handlePlaintextConn reads data points in the Graphite plaintext protocol from conn, and pushes them in batches
+// at least every graphiteTCPFlushPeriod.
+func (s *graphiteTCPServer) handlePlaintextConn(conn net.Conn, logger log.Logger) {
+	reader := bufio.NewReader(conn)
+	batch := make([]graphitepush.Point, 0, graphiteTCPMaxBatchSize)
+	var line []byte
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(graphiteTCPFlushPeriod))
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if err == nil || errors.Is(err, io.EOF) {
+			p, ok, parseErr := graphitepush.ParsePlaintextLine(line, time.Now())
+			line = line[:0]
+			if parseErr != nil {
+				level.Warn(logger).Log("msg", "failed to parse Graphite data point", "err", parseErr)
+			} else if ok {
+				batch = append(batch, p)
+			}
+		}
+
+		var netErr net.Error
+		switch {
+		case err == nil:
+			if len(batch) >= graphiteTCPMaxBatchSize {
+				batch = s.pushPoints(batch, logger)
+			}
+		case errors.Is(err, bufio.ErrBufferFull):
+			if len(line) > graphiteTCPMaxLineLength {
+				level.Warn(logger).Log("msg", "closing Graphite TCP connection because a line exceeds the maximum length", "max_length", graphiteTCPMaxLineLength)
+				s.pushPoints(batch, logger)
+				return
+			}
+		case errors.As(err, &netErr) && netErr.Timeout():
+			batch = s.pushPoints(batch, logger)
+		default:
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				level.Warn(logger).Log("msg", "failed to read from Graphite TCP connection", "err", err)
+			}
+			s.pushPoints(batch, logger)
+			return
+		}
+	}
+}
+
+// handlePickleConn reads messages of pickled data points from conn, each prefixed with its length as a 4 bytes
+// big endian unsigned integer, and pushes each message.
+func (s *graphiteTCPServer) handlePickleConn(conn net.Conn, logger log.Logger) {
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				level.Warn(logger).Log("msg", "failed to read from Graphite TCP connection", "err", err)
+			}
+			return
+		}
+
+		size := binary.BigEndian.Uint32(header[:])
+		if uint64(size) > uint64(s.cfg.MaxRequestSize) {
+			level.Warn(logger).Log("msg", "closing Graphite TCP connection because a pickled message exceeds the maximum size", "size", size, "max_size", s.cfg.MaxRequestSize)
+			return
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			level.Warn(logger).Log("msg", "failed to read from Graphite TCP connection", "err", err)
+			return
+		}
+
+		points, err := graphitepush.ParsePickle(data, time.Now())
+		if err != nil {
+			level.Warn(logger).Log("msg", "failed to parse pickled Graphite data points", "err", err)
+			continue
+		}
+		s.pushPoints(points, logger)
+	}
+}
+
+// pushPoints pushes points for the configured tenant, and returns points emptied so that it can be reused.
+func (s *graphiteTCPServer) pushPoints(points []graphitepush.Point, logger log.Logger) []graphitepush.Point {
+	if len(points) == 0 {
+		return points
+	}
+
+	userID := s.cfg.TCPTenantID
+	s.pushMetrics.IncGraphiteRequest(userID)
+
+	// Data points are pushed even when the server is stopping, so that they aren't lost when connections are closed.
+	ctx := user.InjectOrgID(context.Background(), userID)
+	req := newRequest(graphitePointsSupplier(s.mappers, userID, func() ([]graphitepush.Point, error) {
+		return points, nil
+	}))
+	if err := s.push(ctx, req); err != nil {
+		level.Warn(logger).Log("msg", "failed to push Graphite data points received over TCP", "points", len(points), "err", err)
+	}
+	return points[:0]
+}
@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+type graphiteLimitsMock struct {
+	mappings []*validation.GraphiteMapping
+}
+
+func (m graphiteLimitsMock) GraphiteMappings(string) []*validation.GraphiteMapping {
+	return m.mappings
+}
+
+var testGraphiteMappings = []*validation.GraphiteMapping{
+	{Match: "servers.*.cpu.*", Name: "cpu_${2}", Labels: map[string]string{"host": "$1"}},
+	{Match: "debug.*", Action: validation.GraphiteMappingActionDrop},
+}
+
+func TestGraphiteHandler(t *testing.T) {
+	cpuSeries := mimirpb.PreallocTimeseries{
+		TimeSeries: &mimirpb.TimeSeries{
+			Labels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "cpu_idle"},
+				{Name: "__proxy_source__", Value: "graphite"},
+				{Name: "host", Value: "web01"},
+			},
+			Samples: []mimirpb.Sample{{Value: 2, TimestampMs: 1465839830000}},
+		},
+	}
+	unmappedSeries := mimirpb.PreallocTimeseries{
+		TimeSeries: &mimirpb.TimeSeries{
+			Labels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "app_requests"},
+				{Name: "__proxy_source__", Value: "graphite"},
+			},
+			Samples: []mimirpb.Sample{{Value: 5, TimestampMs: 1465839831000}},
+		},
+	}
+
+	gzipped := func(data string) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name            string
+		data            []byte
+		contentType     string
+		contentEncoding string
+		maxRequestSize  int
+		pushErr         error
+		expectedCode    int
+		expectedSeries  []mimirpb.PreallocTimeseries
+		expectedErr     string
+	}{
+		{
+			name:           "plaintext",
+			data:           []byte("servers.web01.cpu.idle 2 1465839830\ndebug.foo 1 1465839830\napp.requests 5 1465839831\n"),
+			expectedCode:   http.StatusNoContent,
+			expectedSeries: []mimirpb.PreallocTimeseries{cpuSeries, unmappedSeries},
+		},
+		{
+			name:            "gzipped plaintext",
+			data:            gzipped("servers.web01.cpu.idle 2 1465839830\n"),
+			contentEncoding: "gzip",
+			expectedCode:    http.StatusNoContent,
+			expectedSeries:  []mimirpb.PreallocTimeseries{cpuSeries},
+		},
+		{
+			name:           "pickle",
+			data:           []byte("(lp0\n(S'servers.web01.cpu.idle'\np1\n(I1465839830\nF2.0\ntp2\ntp3\na."),
+			contentType:    graphiteContentTypePickle,
+			expectedCode:   http.StatusNoContent,
+			expectedSeries: []mimirpb.PreallocTimeseries{cpuSeries},
+		},
+		{
+			name:         "invalid plaintext",
+			data:         []byte("servers.web01.cpu.idle 2\n"),
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "line 1: invalid line",
+		},
+		{
+			name:         "invalid pickle",
+			data:         []byte("servers.web01.cpu.idle 2 1465839830\n"),
+			contentType:  graphiteContentTypePickle,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:           "request too big",
+			data:           []byte("servers.web01.cpu.idle 2 1465839830\n"),
+			maxRequestSize: 10,
+			expectedCode:   http.StatusRequestEntityTooLarge,
+			expectedErr:    "larger than the allowed limit of 10 bytes",
+		},
+		{
+			name:           "push error",
+			data:           []byte("servers.web01.cpu.idle 2 1465839830\n"),
+			pushErr:        context.DeadlineExceeded,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedSeries: []mimirpb.PreallocTimeseries{cpuSeries},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			push := func(_ context.Context, pushReq *Request) error {
+				req, err := pushReq.WriteRequest()
+				if err != nil {
+					assert.ErrorContains(t, err, tt.expectedErr)
+					return err
+				}
+				assert.Equal(t, tt.expectedSeries, copyTimeseries(req.Timeseries))
+				return tt.pushErr
+			}
+
+			maxRequestSize := tt.maxRequestSize
+			if maxRequestSize == 0 {
+				maxRequestSize = 1 << 20
+			}
+			handler := GraphiteHandler(maxRequestSize, nil, graphiteLimitsMock{mappings: testGraphiteMappings}, RetryConfig{}, push, nil, log.NewNopLogger())
+
+			req := httptest.NewRequest("POST", "/api/v1/push/graphite", bytes.NewReader(tt.data))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			req = req.WithContext(user.InjectOrgID(context.Background(), "test"))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestGraphiteTCPServer(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		received []mimirpb.PreallocTimeseries
+	)
+	push := func(ctx context.Context, pushReq *Request) error {
+		userID, err := tenant.TenantID(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "graphite-tenant", userID)
+
+		req, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		// Series are returned to the pool once pushed, so they must be copied.
+		received = append(received, copyTimeseries(req.Timeseries)...)
+		pushReq.CleanUp()
+		return nil
+	}
+
+	cfg := GraphiteConfig{
+		MaxRequestSize:         1 << 20,
+		TCPListenAddress:       "localhost:0",
+		PickleTCPListenAddress: "localhost:0",
+		TCPTenantID:            "graphite-tenant",
+	}
+	s := newGraphiteTCPServer(cfg, graphiteLimitsMock{mappings: testGraphiteMappings}, push, nil, log.NewNopLogger())
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), s))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), s))
+	})
+
+	seriesWithHost := func(host string, ts int64) mimirpb.PreallocTimeseries {
+		return mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "cpu_idle"},
+				{Name: "__proxy_source__", Value: "graphite"},
+				{Name: "host", Value: host},
+			},
+			Samples: []mimirpb.Sample{{Value: 2, TimestampMs: ts}},
+		}}
+	}
+
+	t.Run("plaintext", func(t *testing.T) {
+		conn, err := net.Dial("tcp", s.plaintextListener.Addr().String())
+		require.NoError(t, err)
+
+		// Invalid lines are skipped, and a line can be split across writes.
+		_, err = conn.Write([]byte("servers.web01.cpu.idle 2 1465839830\ninvalid\nservers.web02.cpu"))
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(".idle 2 1465839831\n"))
+		require.NoError(t, err)
+
+		// Points are pushed periodically, even if the connection is still open.
+		assert.Eventually(t, func() bool {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return len(received) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, conn.Close())
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.Equal(t, []mimirpb.PreallocTimeseries{seriesWithHost("web01", 1465839830000), seriesWithHost("web02", 1465839831000)}, received)
+		received = nil
+	})
+
+	t.Run("pickle", func(t *testing.T) {
+		conn, err := net.Dial("tcp", s.pickleListener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		payload := []byte("(lp0\n(S'servers.web03.cpu.idle'\np1\n(I1465839832\nF2.0\ntp2\ntp3\na.")
+		msg := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		_, err = conn.Write(append(msg, payload...))
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return len(received) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.Equal(t, []mimirpb.PreallocTimeseries{seriesWithHost("web03", 1465839832000)}, received)
+	})
+}
+
+// copyTimeseries returns a copy of the labels and samples of series.
+func copyTimeseries(series []mimirpb.PreallocTimeseries) []mimirpb.PreallocTimeseries {
+	result := make([]mimirpb.PreallocTimeseries, 0, len(series))
+	for _, ts := range series {
+		result = append(result, mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  append([]mimirpb.LabelAdapter(nil), ts.Labels...),
+			Samples: append([]mimirpb.Sample(nil), ts.Samples...),
+		}})
+	}
+	return result
+}
+
+func TestGraphiteConfig_Validate(t *testing.T) {
+	cfg := GraphiteConfig{TCPListenAddress: ":2003"}
+	assert.ErrorIs(t, cfg.Validate(), errGraphiteTCPTenantIDRequired)
+
+	cfg.TCPTenantID = "anonymous"
+	assert.NoError(t, cfg.Validate())
+}
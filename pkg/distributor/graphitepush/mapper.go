@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package graphitepush
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// internalLabel is the label added to series ingested through the Graphite endpoints, like the one added
+// to series ingested through the Influx endpoint.
+const internalLabel = "__proxy_source__"
+
+type mappingRule struct {
+	match  *regexp.Regexp
+	name   string
+	labels map[string]string
+	drop   bool
+}
+
+// Mapper maps dotted Graphite metric names to Prometheus metric names and labels.
+type Mapper struct {
+	rules []mappingRule
+}
+
+// NewMapper makes a new Mapper applying mappings. The first mapping matching a Graphite metric name applies.
+// Graphite metric names not matching any mapping are converted to valid Prometheus metric names, by replacing
+// dots and any other invalid characters with underscores.
+func NewMapper(mappings []*validation.GraphiteMapping) (*Mapper, error) {
+	m := &Mapper{rules: make([]mappingRule, 0, len(mappings))}
+	for _, mapping := range mappings {
+		if err := mapping.Validate(); err != nil {
+			return nil, err
+		}
+
+		var (
+			match *regexp.Regexp
+			err   error
+		)
+		if mapping.MatchType == validation.GraphiteMappingMatchTypeRegex {
+			match, err = regexp.Compile("^(?:" + mapping.Match + ")$")
+		} else {
+			match, err = regexp.Compile(globToRegexp(mapping.Match))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid graphite mapping %q: %w", mapping.Match, err)
+		}
+
+		m.rules = append(m.rules, mappingRule{
+			match:  match,
+			name:   mapping.Name,
+			labels: mapping.Labels,
+			drop:   mapping.Action == validation.GraphiteMappingActionDrop,
+		})
+	}
+	return m, nil
+}
+
+// globToRegexp converts a graphite_exporter glob, where each * matches any part of a single dot-separated
+// component of the metric name, to an anchored regular expression capturing what each * matched.
+func globToRegexp(glob string) string {
+	parts := strings.Split(glob, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return "^" + strings.Join(parts, "([^.]*)") + "$"
+}
+
+// Map returns the labels of the series of the Graphite metric named path, which may have Graphite tags
+// appended with the name;tag=value syntax. It returns false if the metric must be dropped.
+func (m *Mapper) Map(path string) ([]mimirpb.LabelAdapter, bool, error) {
+	name, tags, err := splitTags(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if name == "" {
+		return nil, false, fmt.Errorf("empty metric name in %q", path)
+	}
+
+	lbls := make(map[string]string, len(tags)+2)
+	for tag, value := range tags {
+		lbls[sanitizeName(tag, false)] = value
+	}
+
+	matched := false
+	for _, rule := range m.rules {
+		submatches := rule.match.FindStringSubmatchIndex(name)
+		if submatches == nil {
+			continue
+		}
+		if rule.drop {
+			return nil, false, nil
+		}
+
+		lbls[labels.MetricName] = string(rule.match.ExpandString(nil, rule.name, name, submatches))
+		for labelName, template := range rule.labels {
+			lbls[labelName] = string(rule.match.ExpandString(nil, template, name, submatches))
+		}
+		matched = true
+		break
+	}
+	if !matched {
+		lbls[labels.MetricName] = sanitizeName(name, true)
+	}
+	lbls[internalLabel] = "graphite"
+
+	result := make([]mimirpb.LabelAdapter, 0, len(lbls))
+	for labelName, value := range lbls {
+		if value == "" {
+			continue
+		}
+		result = append(result, mimirpb.LabelAdapter{Name: labelName, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, true, nil
+}
+
+// splitTags splits the name and the tags of a metric name using the Graphite tags syntax, for example
+// disk.used;datacenter=dc1;server=web01.
+func splitTags(path string) (string, map[string]string, error) {
+	name, rawTags, found := strings.Cut(path, ";")
+	if !found {
+		return name, nil, nil
+	}
+
+	tags := map[string]string{}
+	for _, tag := range strings.Split(rawTags, ";") {
+		tagName, value, ok := strings.Cut(tag, "=")
+		if !ok || tagName == "" || value == "" {
+			return "", nil, fmt.Errorf("invalid tag %q in %q", tag, path)
+		}
+		tags[tagName] = value
+	}
+	return name, tags, nil
+}
+
+// sanitizeName replaces the characters that aren't valid in Prometheus metric names, or label names if isMetricName
+// is false, with underscores, and prefixes names starting with a digit with an underscore.
+func sanitizeName(name string, isMetricName bool) string {
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		if i == 0 && r >= '0' && r <= '9' {
+			b.WriteByte('_')
+		}
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || (isMetricName && r == ':') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package graphitepush
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestMapper_Map(t *testing.T) {
+	mapper, err := NewMapper([]*validation.GraphiteMapping{
+		{
+			Match:  "test.dispatcher.*.*.*",
+			Name:   "dispatcher_events_total",
+			Labels: map[string]string{"processor": "$1", "action": "$2", "outcome": "$3", "job": "test_dispatcher"},
+		},
+		{
+			Match:     `servers\.(.*)\.networking\.subnetworks\.transmissions\.([a-z0-9-]+)\.(.*)`,
+			MatchType: validation.GraphiteMappingMatchTypeRegex,
+			Name:      "servers_networking_transmissions_${3}",
+			Labels:    map[string]string{"hostname": "${1}", "device": "${2}"},
+		},
+		{
+			Match:  "*.signup.*.*",
+			Action: validation.GraphiteMappingActionDrop,
+		},
+		{
+			Match: "*.*",
+			Name:  "two_components",
+		},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		path           string
+		expectedLabels []mimirpb.LabelAdapter
+		expectedDrop   bool
+		expectedErr    bool
+	}{
+		"glob mapping": {
+			path: "test.dispatcher.FooProcessor.send.success",
+			expectedLabels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "dispatcher_events_total"},
+				{Name: "__proxy_source__", Value: "graphite"},
+				{Name: "action", Value: "send"},
+				{Name: "job", Value: "test_dispatcher"},
+				{Name: "outcome", Value: "success"},
+				{Name: "processor", Value: "FooProcessor"},
+			},
+		},
+		"glob doesn't match across dots": {
+			path: "test.dispatcher.FooProcessor.send.success.extra",
+			expectedLabels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "test_dispatcher_FooProcessor_send_success_extra"},
+				{Name: "__proxy_source__", Value: "graphite"},
+			},
+		},
+		"regex mapping": {
+			path: "servers.rack-003-server-c4de.networking.subnetworks.transmissions.eth0.failure.mean_rate",
+			expectedLabels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "servers_networking_transmissions_failure.mean_rate"},
+				{Name: "__proxy_source__", Value: "graphite"},
+				{Name: "device", Value: "eth0"},
+				{Name: "hostname", Value: "rack-003-server-c4de"},
+			},
+		},
+		"dropped metric": {
+			path:         "new.signup.web.count",
+			expectedDrop: true,
+		},
+		"first matching mapping applies": {
+			path: "foo.bar",
+			expectedLabels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "two_components"},
+				{Name: "__proxy_source__", Value: "graphite"},
+			},
+		},
+		"unmapped metric": {
+			path: "1st-metric.with/invalid:chars.x",
+			expectedLabels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "_1st_metric_with_invalid:chars_x"},
+				{Name: "__proxy_source__", Value: "graphite"},
+			},
+		},
+		"tagged metric": {
+			path: "foo.bar;env=prod;data-center=dc1",
+			expectedLabels: []mimirpb.LabelAdapter{
+				{Name: "__name__", Value: "two_components"},
+				{Name: "__proxy_source__", Value: "graphite"},
+				{Name: "data_center", Value: "dc1"},
+				{Name: "env", Value: "prod"},
+			},
+		},
+		"invalid tag": {
+			path:        "foo.bar;env",
+			expectedErr: true,
+		},
+		"empty name": {
+			path:        ";env=prod",
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			lbls, keep, err := mapper.Map(tc.path)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, !tc.expectedDrop, keep)
+			assert.Equal(t, tc.expectedLabels, lbls)
+		})
+	}
+}
+
+func TestNewMapper_InvalidMappings(t *testing.T) {
+	tests := map[string]*validation.GraphiteMapping{
+		"missing match":      {Name: "foo"},
+		"missing name":       {Match: "foo.*"},
+		"invalid match type": {Match: "foo.*", Name: "foo", MatchType: "prefix"},
+		"invalid regex":      {Match: "foo.(", Name: "foo", MatchType: validation.GraphiteMappingMatchTypeRegex},
+		"invalid action":     {Match: "foo.*", Name: "foo", Action: "keep"},
+		"nil mapping":        nil,
+	}
+
+	for name, mapping := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMapper([]*validation.GraphiteMapping{mapping})
+			require.Error(t, err)
+		})
+	}
+}
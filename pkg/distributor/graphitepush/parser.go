@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package graphitepush
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// Point is a Graphite data point.
+type Point struct {
+	Path string
+	// Timestamp is the Unix timestamp of the point in milliseconds.
+	Timestamp int64
+	Value     float64
+}
+
+// ParsePlaintext parses data points in the Graphite plaintext protocol, one "<path> <value> <timestamp>" point
+// per line. Timestamps are in seconds. Points with a timestamp of -1 are assigned now.
+func ParsePlaintext(data []byte, now time.Time) ([]Point, error) {
+	points := make([]Point, 0, bytes.Count(data, []byte{'\n'})+1)
+	for lineNum := 1; len(data) > 0; lineNum++ {
+		var line []byte
+		line, data, _ = bytes.Cut(data, []byte{'\n'})
+
+		p, ok, err := ParsePlaintextLine(line, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if ok {
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
+
+// ParsePlaintextLine parses a data point in the Graphite plaintext protocol. It returns false if the line is empty.
+func ParsePlaintextLine(line []byte, now time.Time) (Point, bool, error) {
+	fields := bytes.Fields(line)
+	switch len(fields) {
+	case 0:
+		return Point{}, false, nil
+	case 3:
+	default:
+		return Point{}, false, fmt.Errorf("invalid line %q: expected \"<path> <value> <timestamp>\"", truncate(line))
+	}
+
+	value, err := strconv.ParseFloat(string(fields[1]), 64)
+	if err != nil {
+		return Point{}, false, fmt.Errorf("invalid value in line %q: %w", truncate(line), err)
+	}
+	timestamp, err := strconv.ParseFloat(string(fields[2]), 64)
+	if err != nil {
+		return Point{}, false, fmt.Errorf("invalid timestamp in line %q: %w", truncate(line), err)
+	}
+
+	return Point{
+		Path:      string(fields[0]),
+		Timestamp: timestampMillis(timestamp, now),
+		Value:     value,
+	}, true, nil
+}
+
+// timestampMillis converts a Graphite timestamp in seconds to milliseconds. Negative timestamps are replaced
+// with now, as carbon does.
+func timestampMillis(seconds float64, now time.Time) int64 {
+	if seconds < 0 || math.IsNaN(seconds) {
+		return now.UnixMilli()
+	}
+	return int64(seconds * 1000)
+}
+
+func truncate(line []byte) []byte {
+	const maxLen = 256
+	if len(line) > maxLen {
+		return line[:maxLen]
+	}
+	return line
+}
+
+// PointsToTimeseries converts data points to series with the labels returned by mapper.
+// Points dropped by mapper are skipped.
+func PointsToTimeseries(points []Point, mapper *Mapper) ([]mimirpb.PreallocTimeseries, error) {
+	// As for the Influx endpoint, each sample is added to its own series: multiple samples for the same series
+	// in the same request are rare.
+	returnTs := mimirpb.PreallocTimeseriesSliceFromPool()[:0]
+	if cap(returnTs) < len(points) {
+		returnTs = make([]mimirpb.PreallocTimeseries, 0, len(points))
+	}
+
+	for _, p := range points {
+		lbls, keep, err := mapper.Map(p.Path)
+		if err != nil {
+			mimirpb.ReuseSlice(returnTs)
+			return nil, err
+		}
+		if !keep {
+			continue
+		}
+
+		ts := mimirpb.TimeseriesFromPool()
+		ts.Labels = append(ts.Labels, lbls...)
+		ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: p.Timestamp, Value: p.Value})
+		returnTs = append(returnTs, mimirpb.PreallocTimeseries{TimeSeries: ts})
+	}
+	return returnTs, nil
+}
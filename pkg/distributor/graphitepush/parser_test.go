@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package graphitepush
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestParsePlaintext(t *testing.T) {
+	now := time.UnixMilli(1700000010000)
+
+	tests := map[string]struct {
+		data           string
+		expectedPoints []Point
+		expectedErr    string
+	}{
+		"single point": {
+			data:           "foo.bar 1.5 1700000000",
+			expectedPoints: []Point{{Path: "foo.bar", Timestamp: 1700000000000, Value: 1.5}},
+		},
+		"multiple points with empty lines and extra whitespace": {
+			data: "foo.bar 1.5 1700000000\n\n  foo.baz\t2 1700000001.25  \r\n",
+			expectedPoints: []Point{
+				{Path: "foo.bar", Timestamp: 1700000000000, Value: 1.5},
+				{Path: "foo.baz", Timestamp: 1700000001250, Value: 2},
+			},
+		},
+		"point with timestamp -1": {
+			data:           "foo.bar 1 -1",
+			expectedPoints: []Point{{Path: "foo.bar", Timestamp: 1700000010000, Value: 1}},
+		},
+		"point with tags": {
+			data:           "foo.bar;env=prod 1 1700000000",
+			expectedPoints: []Point{{Path: "foo.bar;env=prod", Timestamp: 1700000000000, Value: 1}},
+		},
+		"missing timestamp": {
+			data:        "foo.bar 1.5\nfoo.bar 1 1700000000",
+			expectedErr: `line 1: invalid line "foo.bar 1.5": expected "<path> <value> <timestamp>"`,
+		},
+		"invalid value": {
+			data:        "foo.bar 1 1700000000\nfoo.bar one 1700000000",
+			expectedErr: `line 2: invalid value in line "foo.bar one 1700000000"`,
+		},
+		"invalid timestamp": {
+			data:        "foo.bar 1 yesterday",
+			expectedErr: `line 1: invalid timestamp in line "foo.bar 1 yesterday"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			points, err := ParsePlaintext([]byte(tc.data), now)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPoints, points)
+		})
+	}
+}
+
+func TestPointsToTimeseries(t *testing.T) {
+	mapper, err := NewMapper([]*validation.GraphiteMapping{
+		{Match: "dropped.*", Action: validation.GraphiteMappingActionDrop},
+	})
+	require.NoError(t, err)
+
+	points := []Point{
+		{Path: "foo.bar", Timestamp: 1000, Value: 1},
+		{Path: "dropped.bar", Timestamp: 1000, Value: 2},
+		{Path: "foo.bar;env=prod", Timestamp: 2000, Value: 3},
+	}
+
+	ts, err := PointsToTimeseries(points, mapper)
+	require.NoError(t, err)
+	defer mimirpb.ReuseSlice(ts)
+
+	require.Len(t, ts, 2)
+	assert.Equal(t, []mimirpb.LabelAdapter{{Name: "__name__", Value: "foo_bar"}, {Name: "__proxy_source__", Value: "graphite"}}, ts[0].Labels)
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 1000, Value: 1}}, ts[0].Samples)
+	assert.Equal(t, []mimirpb.LabelAdapter{{Name: "__name__", Value: "foo_bar"}, {Name: "__proxy_source__", Value: "graphite"}, {Name: "env", Value: "prod"}}, ts[1].Labels)
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 2000, Value: 3}}, ts[1].Samples)
+
+	_, err = PointsToTimeseries([]Point{{Path: "foo;invalid", Timestamp: 1000, Value: 1}}, mapper)
+	require.Error(t, err)
+}
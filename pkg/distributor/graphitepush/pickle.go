@@ -0,0 +1,606 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package graphitepush
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// Pickle opcodes supported by the decoder. They cover the opcodes used by carbon clients to pickle lists of
+// (path, (timestamp, value)) tuples with protocols 0 to 5. Opcodes building arbitrary Python objects aren't
+// supported.
+const (
+	opMark           = '('
+	opStop           = '.'
+	opPop            = '0'
+	opPopMark        = '1'
+	opDup            = '2'
+	opFloat          = 'F'
+	opInt            = 'I'
+	opBinInt         = 'J'
+	opBinInt1        = 'K'
+	opLong           = 'L'
+	opBinInt2        = 'M'
+	opNone           = 'N'
+	opString         = 'S'
+	opBinString      = 'T'
+	opShortBinString = 'U'
+	opUnicode        = 'V'
+	opBinUnicode     = 'X'
+	opAppend         = 'a'
+	opAppends        = 'e'
+	opGet            = 'g'
+	opBinGet         = 'h'
+	opLongBinGet     = 'j'
+	opList           = 'l'
+	opPut            = 'p'
+	opBinPut         = 'q'
+	opLongBinPut     = 'r'
+	opTuple          = 't'
+	opEmptyList      = ']'
+	opEmptyTuple     = ')'
+	opBinFloat       = 'G'
+	opBinBytes       = 'B'
+	opShortBinBytes  = 'C'
+
+	opProto           = 0x80
+	opTuple1          = 0x85
+	opTuple2          = 0x86
+	opTuple3          = 0x87
+	opNewTrue         = 0x88
+	opNewFalse        = 0x89
+	opLong1           = 0x8a
+	opLong4           = 0x8b
+	opShortBinUnicode = 0x8c
+	opBinUnicode8     = 0x8d
+	opBinBytes8       = 0x8e
+	opMemoize         = 0x94
+	opFrame           = 0x95
+)
+
+var errPickleTruncated = errors.New("truncated pickle")
+
+type pickleMark struct{}
+
+type pickleList struct {
+	items []any
+}
+
+type pickleDecoder struct {
+	data  []byte
+	pos   int
+	stack []any
+	memo  map[int]any
+}
+
+// ParsePickle parses data points pickled by carbon clients, as a list of (path, (timestamp, value)) tuples.
+// Timestamps are in seconds. Points with a negative timestamp are assigned now.
+func ParsePickle(data []byte, now time.Time) ([]Point, error) {
+	d := &pickleDecoder{data: data, memo: map[int]any{}}
+	v, err := d.decode()
+	if err != nil {
+		return nil, fmt.Errorf("invalid pickle: %w", err)
+	}
+
+	items, ok := pickleSequence(v)
+	if !ok {
+		return nil, fmt.Errorf("invalid pickle: expected a list of data points, got %T", v)
+	}
+
+	points := make([]Point, 0, len(items))
+	for _, item := range items {
+		p, err := picklePoint(item, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pickled data point: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func picklePoint(item any, now time.Time) (Point, error) {
+	tuple, ok := pickleSequence(item)
+	if !ok || len(tuple) != 2 {
+		return Point{}, errors.New("expected a (path, (timestamp, value)) tuple")
+	}
+	path, ok := pickleString(tuple[0])
+	if !ok {
+		return Point{}, fmt.Errorf("expected a string path, got %T", tuple[0])
+	}
+	datapoint, ok := pickleSequence(tuple[1])
+	if !ok || len(datapoint) != 2 {
+		return Point{}, fmt.Errorf("expected a (timestamp, value) tuple for %q", path)
+	}
+	timestamp, ok := pickleNumber(datapoint[0])
+	if !ok {
+		return Point{}, fmt.Errorf("invalid timestamp for %q", path)
+	}
+	value, ok := pickleNumber(datapoint[1])
+	if !ok {
+		return Point{}, fmt.Errorf("invalid value for %q", path)
+	}
+
+	return Point{
+		Path:      path,
+		Timestamp: timestampMillis(timestamp, now),
+		Value:     value,
+	}, nil
+}
+
+func pickleSequence(v any) ([]any, bool) {
+	switch v := v.(type) {
+	case *pickleList:
+		return v.items, true
+	case []any:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+func pickleString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
+
+// pickleNumber returns v as a float64. Like carbon, it accepts numbers formatted as strings.
+func pickleNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		s, ok := pickleString(v)
+		if !ok {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+}
+
+func (d *pickleDecoder) decode() (any, error) {
+	for {
+		op, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opStop:
+			if len(d.stack) != 1 {
+				return nil, errors.New("unexpected stack size at STOP")
+			}
+			return d.stack[0], nil
+
+		case opProto:
+			if _, err := d.read(1); err != nil {
+				return nil, err
+			}
+		case opFrame:
+			if _, err := d.read(8); err != nil {
+				return nil, err
+			}
+
+		case opMark:
+			d.push(pickleMark{})
+		case opPop:
+			if _, err := d.pop(); err != nil {
+				return nil, err
+			}
+		case opPopMark:
+			if _, err := d.popMark(); err != nil {
+				return nil, err
+			}
+		case opDup:
+			v, err := d.top()
+			if err != nil {
+				return nil, err
+			}
+			d.push(v)
+
+		case opNone:
+			d.push(nil)
+		case opNewTrue:
+			d.push(true)
+		case opNewFalse:
+			d.push(false)
+
+		case opInt:
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			switch string(line) {
+			case "00":
+				d.push(false)
+			case "01":
+				d.push(true)
+			default:
+				v, err := strconv.ParseInt(string(line), 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				d.push(v)
+			}
+		case opLong:
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			v, ok := new(big.Int).SetString(string(bytes.TrimSuffix(line, []byte{'L'})), 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid long %q", line)
+			}
+			d.push(normalizeBigInt(v))
+		case opBinInt:
+			b, err := d.read(4)
+			if err != nil {
+				return nil, err
+			}
+			d.push(int64(int32(binary.LittleEndian.Uint32(b))))
+		case opBinInt1:
+			b, err := d.read(1)
+			if err != nil {
+				return nil, err
+			}
+			d.push(int64(b[0]))
+		case opBinInt2:
+			b, err := d.read(2)
+			if err != nil {
+				return nil, err
+			}
+			d.push(int64(binary.LittleEndian.Uint16(b)))
+		case opLong1, opLong4:
+			n, err := d.readLength(op == opLong4)
+			if err != nil {
+				return nil, err
+			}
+			b, err := d.read(n)
+			if err != nil {
+				return nil, err
+			}
+			d.push(decodeLong(b))
+
+		case opFloat:
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			v, err := strconv.ParseFloat(string(line), 64)
+			if err != nil {
+				return nil, err
+			}
+			d.push(v)
+		case opBinFloat:
+			b, err := d.read(8)
+			if err != nil {
+				return nil, err
+			}
+			d.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+
+		case opString:
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			v, err := strconv.Unquote(pythonQuotedToGo(line))
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %q: %w", line, err)
+			}
+			d.push(v)
+		case opUnicode:
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			d.push(string(line))
+		case opShortBinString, opShortBinUnicode, opShortBinBytes:
+			n, err := d.readLength(false)
+			if err != nil {
+				return nil, err
+			}
+			b, err := d.read(n)
+			if err != nil {
+				return nil, err
+			}
+			d.push(string(b))
+		case opBinString, opBinUnicode, opBinBytes:
+			n, err := d.readLength(true)
+			if err != nil {
+				return nil, err
+			}
+			b, err := d.read(n)
+			if err != nil {
+				return nil, err
+			}
+			d.push(string(b))
+		case opBinUnicode8, opBinBytes8:
+			b, err := d.read(8)
+			if err != nil {
+				return nil, err
+			}
+			n := binary.LittleEndian.Uint64(b)
+			if n > uint64(len(d.data)) {
+				return nil, errPickleTruncated
+			}
+			b, err = d.read(int(n))
+			if err != nil {
+				return nil, err
+			}
+			d.push(string(b))
+
+		case opEmptyList:
+			d.push(&pickleList{})
+		case opList:
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			d.push(&pickleList{items: items})
+		case opAppend:
+			v, err := d.pop()
+			if err != nil {
+				return nil, err
+			}
+			l, err := d.topList()
+			if err != nil {
+				return nil, err
+			}
+			l.items = append(l.items, v)
+		case opAppends:
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			l, err := d.topList()
+			if err != nil {
+				return nil, err
+			}
+			l.items = append(l.items, items...)
+
+		case opEmptyTuple:
+			d.push([]any{})
+		case opTuple:
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			d.push(items)
+		case opTuple1, opTuple2, opTuple3:
+			n := int(op-opTuple1) + 1
+			if len(d.stack) < n {
+				return nil, errors.New("stack underflow")
+			}
+			items := append([]any{}, d.stack[len(d.stack)-n:]...)
+			d.stack = d.stack[:len(d.stack)-n]
+			d.push(items)
+
+		case opPut:
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			idx, err := strconv.Atoi(string(line))
+			if err != nil {
+				return nil, err
+			}
+			if err := d.memoize(idx); err != nil {
+				return nil, err
+			}
+		case opBinPut, opLongBinPut:
+			idx, err := d.readLength(op == opLongBinPut)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.memoize(idx); err != nil {
+				return nil, err
+			}
+		case opMemoize:
+			if err := d.memoize(len(d.memo)); err != nil {
+				return nil, err
+			}
+		case opGet:
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			idx, err := strconv.Atoi(string(line))
+			if err != nil {
+				return nil, err
+			}
+			if err := d.get(idx); err != nil {
+				return nil, err
+			}
+		case opBinGet, opLongBinGet:
+			idx, err := d.readLength(op == opLongBinGet)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.get(idx); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported opcode 0x%02x at offset %d", op, d.pos-1)
+		}
+	}
+}
+
+func (d *pickleDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errPickleTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *pickleDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errPickleTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readLength reads a 4 bytes little endian length if long is true, or a 1 byte length otherwise.
+func (d *pickleDecoder) readLength(long bool) (int, error) {
+	if !long {
+		b, err := d.read(1)
+		if err != nil {
+			return 0, err
+		}
+		return int(b[0]), nil
+	}
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.LittleEndian.Uint32(b)), nil
+}
+
+func (d *pickleDecoder) readLine() ([]byte, error) {
+	idx := bytes.IndexByte(d.data[d.pos:], '\n')
+	if idx < 0 {
+		return nil, errPickleTruncated
+	}
+	line := d.data[d.pos : d.pos+idx]
+	d.pos += idx + 1
+	return line, nil
+}
+
+func (d *pickleDecoder) push(v any) {
+	d.stack = append(d.stack, v)
+}
+
+func (d *pickleDecoder) pop() (any, error) {
+	v, err := d.top()
+	if err != nil {
+		return nil, err
+	}
+	d.stack = d.stack[:len(d.stack)-1]
+	return v, nil
+}
+
+func (d *pickleDecoder) top() (any, error) {
+	if len(d.stack) == 0 {
+		return nil, errors.New("stack underflow")
+	}
+	return d.stack[len(d.stack)-1], nil
+}
+
+func (d *pickleDecoder) topList() (*pickleList, error) {
+	v, err := d.top()
+	if err != nil {
+		return nil, err
+	}
+	l, ok := v.(*pickleList)
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %T", v)
+	}
+	return l, nil
+}
+
+// popMark pops the items pushed since the last mark, and the mark.
+func (d *pickleDecoder) popMark() ([]any, error) {
+	for i := len(d.stack) - 1; i >= 0; i-- {
+		if _, ok := d.stack[i].(pickleMark); ok {
+			items := append([]any{}, d.stack[i+1:]...)
+			d.stack = d.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("mark not found")
+}
+
+func (d *pickleDecoder) memoize(idx int) error {
+	v, err := d.top()
+	if err != nil {
+		return err
+	}
+	d.memo[idx] = v
+	return nil
+}
+
+func (d *pickleDecoder) get(idx int) error {
+	v, ok := d.memo[idx]
+	if !ok {
+		return fmt.Errorf("memo key %d not found", idx)
+	}
+	d.push(v)
+	return nil
+}
+
+// decodeLong decodes a little endian two's complement integer.
+func decodeLong(b []byte) any {
+	if len(b) == 0 {
+		return int64(0)
+	}
+
+	// Convert to big endian.
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	v := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return normalizeBigInt(v)
+}
+
+func normalizeBigInt(v *big.Int) any {
+	if v.IsInt64() {
+		return v.Int64()
+	}
+	return v
+}
+
+// pythonQuotedToGo converts a string quoted with single quotes by Python's repr() to a string quoted with double
+// quotes, which can be unquoted by strconv.Unquote.
+func pythonQuotedToGo(s []byte) string {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return string(s)
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('"')
+	inner := s[1 : len(s)-1]
+	for i := 0; i < len(inner); i++ {
+		switch c := inner[i]; {
+		case c == '\\' && i+1 < len(inner) && inner[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
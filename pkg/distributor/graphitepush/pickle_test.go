@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package graphitepush
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePickle(t *testing.T) {
+	now := time.UnixMilli(1700000010000)
+
+	// Generated with pickle.dumps([('foo.bar', (1700000000, 1.5)), ('baz;env=prod', (1700000001.5, 2)),
+	// ('x.y', (1700000002, 10**20)), ('neg', (-1, '3.5'))], protocol=N) in Python 3.
+	pickles := map[string]string{
+		"protocol 0": "286c70300a2856666f6f2e6261720a70310a2849313730303030303030300a46312e350a7470320a7470330a61285662617a3b656e763d70726f640a70340a2846313730303030303030312e350a49320a7470350a7470360a612856782e790a70370a2849313730303030303030320a4c3130303030303030303030303030303030303030304c0a7470380a7470390a6128566e65670a7031300a28492d310a56332e350a7031310a747031320a747031330a612e",
+		"protocol 1": "5d710028285807000000666f6f2e6261727101284a00f15365473ff800000000000074710274710328580c00000062617a3b656e763d70726f647104284741d954fc406000004b02747105747106285803000000782e797107284a02f153654c3130303030303030303030303030303030303030304c0a7471087471092858030000006e6567710a284affffffff5803000000332e35710b74710c74710d652e",
+		"protocol 2": "80025d7100285807000000666f6f2e62617271014a00f15365473ff8000000000000867102867103580c00000062617a3b656e763d70726f6471044741d954fc406000004b028671058671065803000000782e7971074a02f153658a09000010632d5ec76b0586710886710958030000006e6567710a4affffffff5803000000332e35710b86710c86710d652e",
+		"protocol 4": "8004956e000000000000005d94288c07666f6f2e626172944a00f15365473ff8000000000000869486948c0c62617a3b656e763d70726f64944741d954fc406000004b02869486948c03782e79944a02f153658a09000010632d5ec76b05869486948c036e6567944affffffff8c03332e359486948694652e",
+		"protocol 5": "8005956e000000000000005d94288c07666f6f2e626172944a00f15365473ff8000000000000869486948c0c62617a3b656e763d70726f64944741d954fc406000004b02869486948c03782e79944a02f153658a09000010632d5ec76b05869486948c036e6567944affffffff8c03332e359486948694652e",
+	}
+
+	expected := []Point{
+		{Path: "foo.bar", Timestamp: 1700000000000, Value: 1.5},
+		{Path: "baz;env=prod", Timestamp: 1700000001500, Value: 2},
+		{Path: "x.y", Timestamp: 1700000002000, Value: 1e20},
+		{Path: "neg", Timestamp: 1700000010000, Value: 3.5},
+	}
+
+	for name, data := range pickles {
+		t.Run(name, func(t *testing.T) {
+			b, err := hex.DecodeString(data)
+			require.NoError(t, err)
+
+			points, err := ParsePickle(b, now)
+			require.NoError(t, err)
+			assert.Equal(t, expected, points)
+		})
+	}
+
+	t.Run("python 2 protocol 0", func(t *testing.T) {
+		points, err := ParsePickle([]byte("(lp0\n(S'foo.bar'\np1\n(I1700000000\nF1.5\ntp2\ntp3\na."), now)
+		require.NoError(t, err)
+		assert.Equal(t, []Point{{Path: "foo.bar", Timestamp: 1700000000000, Value: 1.5}}, points)
+	})
+}
+
+func TestParsePickle_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"empty":              "",
+		"truncated":          "80025d7100285807000000666f6f",
+		"not a list":         "80024b012e",
+		"invalid data point": "80025d71004b0161" + "2e",
+		// pickle.dumps(datetime.date(2020, 1, 1), protocol=2) uses GLOBAL and REDUCE.
+		"unsupported opcode": "8002636461746574696d650a646174650a7100635f636f646563730a656e636f64650a7101580500000007c3a40101710258060000006c6174696e3171038671045271058571065271072e",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			b, err := hex.DecodeString(data)
+			require.NoError(t, err)
+
+			_, err = ParsePickle(b, time.Now())
+			require.Error(t, err)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -34,12 +35,16 @@ import (
 var (
 	errNegativeUpdateTimeoutJitterMax = errors.New("HA tracker max update timeout jitter shouldn't be negative")
 	errInvalidFailoverTimeout         = "HA Tracker failover timeout (%v) must be at least 1s greater than update timeout - max jitter (%v)"
+	errHAClusterNotFound              = errors.New("HA cluster not found")
 )
 
 type haTrackerLimits interface {
 	// MaxHAClusters returns the max number of clusters that the HA tracker should track for a user.
 	// Samples from additional clusters are rejected.
 	MaxHAClusters(user string) int
+
+	// HAReplicaPriorities returns the replicas of each cluster of a user, from the most to the least preferred.
+	HAReplicaPriorities(user string) map[string][]string
 }
 
 type haTracker interface {
@@ -48,6 +53,11 @@ type haTracker interface {
 
 	checkReplica(ctx context.Context, userID, cluster, replica string, now time.Time) error
 	cleanupHATrackerMetricsForUser(userID string)
+
+	// FailoverHandler, PinHandler and ClearHandler are the handlers of the HA tracker admin API.
+	FailoverHandler(w http.ResponseWriter, req *http.Request)
+	PinHandler(w http.ResponseWriter, req *http.Request)
+	ClearHandler(w http.ResponseWriter, req *http.Request)
 }
 
 // ProtoReplicaDescFactory makes new InstanceDescs
@@ -224,6 +234,9 @@ type haClusterInfo struct {
 	electedLastSeenTimestamp    int64       // timestamp in milliseconds
 	nonElectedLastSeenReplica   string
 	nonElectedLastSeenTimestamp int64 // timestamp in milliseconds
+	// The most preferred non-elected replica with a higher priority than the elected replica, recently seen.
+	preferredLastSeenReplica   string
+	preferredLastSeenTimestamp int64 // timestamp in milliseconds
 }
 
 // newHaTracker returns a new HA cluster tracker using either Consul,
@@ -450,7 +463,12 @@ func (h *defaultHaTracker) updateKVStoreAll(ctx context.Context, now time.Time)
 			}
 			var replica string
 			var receivedAt int64
-			if h.withinUpdateTimeout(now, entry.electedLastSeenTimestamp) {
+			if !entry.elected.Pinned && h.withinUpdateTimeout(now, entry.preferredLastSeenTimestamp) &&
+				h.prefers(userID, cluster, entry.preferredLastSeenReplica, entry.elected.Replica) {
+				// Have seen a replica with a higher priority than the elected one: attempt to fail over to it.
+				replica = entry.preferredLastSeenReplica
+				receivedAt = entry.preferredLastSeenTimestamp
+			} else if h.withinUpdateTimeout(now, entry.electedLastSeenTimestamp) {
 				// We have seen the elected replica recently; carry on with that choice.
 				replica = entry.elected.Replica
 				receivedAt = entry.electedLastSeenTimestamp
@@ -564,6 +582,11 @@ func (h *defaultHaTracker) checkReplica(ctx context.Context, userID, cluster, re
 			// Sample received is from non-elected replica: record details and reject.
 			entry.nonElectedLastSeenReplica = replica
 			entry.nonElectedLastSeenTimestamp = timestamp.FromTime(now)
+			if h.prefers(userID, cluster, replica, entry.elected.Replica) &&
+				(!h.withinUpdateTimeout(now, entry.preferredLastSeenTimestamp) || !h.prefers(userID, cluster, entry.preferredLastSeenReplica, replica)) {
+				entry.preferredLastSeenReplica = replica
+				entry.preferredLastSeenTimestamp = timestamp.FromTime(now)
+			}
 			err = newReplicasDidNotMatchError(replica, entry.elected.Replica)
 		}
 		h.electedLock.Unlock()
@@ -591,6 +614,21 @@ func (h *defaultHaTracker) withinUpdateTimeout(now time.Time, receivedAt int64)
 	return now.Sub(timestamp.Time(receivedAt)) < h.cfg.UpdateTimeout+h.updateTimeoutJitter
 }
 
+// prefers returns whether replica has a higher priority than other in the cluster of userID.
+func (h *defaultHaTracker) prefers(userID, cluster, replica, other string) bool {
+	priorities := h.limits.HAReplicaPriorities(userID)[cluster]
+	if len(priorities) == 0 || replica == other {
+		return false
+	}
+	rank := func(r string) int {
+		if i := slices.Index(priorities, r); i >= 0 {
+			return i
+		}
+		return len(priorities)
+	}
+	return rank(replica) < rank(other)
+}
+
 // Must be called with electedLock held.
 func (h *defaultHaTracker) updateCache(userID, cluster string, desc *ReplicaDesc) {
 	if h.clusters[userID] == nil {
@@ -610,7 +648,9 @@ func (h *defaultHaTracker) updateCache(userID, cluster string, desc *ReplicaDesc
 		h.lastElectionTimestamp.WithLabelValues(userID, cluster).Set(float64(desc.ElectedAt / 1000))
 		h.totalReelections.WithLabelValues(userID, cluster).Set(float64(desc.ElectedChanges))
 		level.Info(h.logger).Log("msg", "updating replica in cache", "user", userID, "cluster", cluster, "old_replica", entry.elected.Replica, "new_replica", desc.Replica, "received_at", timestamp.Time(desc.ReceivedAt))
-		if entry.nonElectedLastSeenReplica == desc.Replica {
+		if entry.preferredLastSeenReplica == desc.Replica {
+			entry.electedLastSeenTimestamp = entry.preferredLastSeenTimestamp
+		} else if entry.nonElectedLastSeenReplica == desc.Replica {
 			entry.electedLastSeenTimestamp = entry.nonElectedLastSeenTimestamp
 		} else {
 			// clear electedLastSeenTimestamp since we don't know when we have seen this replica.
@@ -636,9 +676,14 @@ func (h *defaultHaTracker) updateKVStore(ctx context.Context, userID, cluster, r
 			}
 			electedAtTime = desc.ElectedAt
 			electedChanges = desc.ElectedChanges
-			// If our replica is different, wait until the failover time
+			// If our replica is different, wait until the failover time, unless our replica has a higher priority.
+			// Pinned replicas are never failed over.
 			if desc.Replica != replica {
-				if now.Sub(timestamp.Time(desc.ReceivedAt)) < h.cfg.FailoverTimeout {
+				if desc.Pinned {
+					level.Info(h.logger).Log("msg", "replica differs, but the elected replica is pinned", "user", userID, "cluster", cluster, "replica", replica, "elected", desc.Replica)
+					return nil, false, nil
+				}
+				if now.Sub(timestamp.Time(desc.ReceivedAt)) < h.cfg.FailoverTimeout && !h.prefers(userID, cluster, replica, desc.Replica) {
 					level.Info(h.logger).Log("msg", "replica differs, but it's too early to failover", "user", userID, "cluster", cluster, "replica", replica, "elected", desc.Replica, "received_at", timestamp.Time(desc.ReceivedAt))
 					return nil, false, nil
 				}
@@ -661,6 +706,8 @@ func (h *defaultHaTracker) updateKVStore(ctx context.Context, userID, cluster, r
 			DeletedAt:      0,
 			ElectedAt:      electedAtTime,
 			ElectedChanges: electedChanges,
+			// The pin is kept as long as the pinned replica is elected.
+			Pinned: ok && desc.DeletedAt == 0 && desc.Replica == replica && desc.Pinned,
 		}
 		return desc, true, nil
 	})
@@ -674,6 +721,108 @@ func (h *defaultHaTracker) updateKVStore(ctx context.Context, userID, cluster, r
 	return err
 }
 
+// forceFailover elects replica in the cluster of userID, even if the elected replica is still sending samples or
+// is pinned. The pin, if any, is removed: the replica fails over as usual if it doesn't send samples within the
+// failover timeout.
+func (h *defaultHaTracker) forceFailover(ctx context.Context, userID, cluster, replica string, now time.Time) error {
+	return h.updateKVStoreAdmin(ctx, userID, cluster, func(desc *ReplicaDesc) *ReplicaDesc {
+		return electReplicaDesc(desc, replica, false, now)
+	})
+}
+
+// pin elects and pins replica in the cluster of userID, or pins the elected replica if replica is empty.
+func (h *defaultHaTracker) pin(ctx context.Context, userID, cluster, replica string, now time.Time) error {
+	return h.updateKVStoreAdmin(ctx, userID, cluster, func(desc *ReplicaDesc) *ReplicaDesc {
+		if replica == "" {
+			if desc == nil {
+				return nil
+			}
+			return electReplicaDesc(desc, desc.Replica, true, now)
+		}
+		return electReplicaDesc(desc, replica, true, now)
+	})
+}
+
+// unpin unpins the elected replica of the cluster of userID.
+func (h *defaultHaTracker) unpin(ctx context.Context, userID, cluster string, now time.Time) error {
+	return h.updateKVStoreAdmin(ctx, userID, cluster, func(desc *ReplicaDesc) *ReplicaDesc {
+		if desc == nil {
+			return nil
+		}
+		return electReplicaDesc(desc, desc.Replica, false, now)
+	})
+}
+
+// clear marks the cluster of userID for deletion, so that a new replica is elected from the next samples of the
+// cluster, like after the cleanup of clusters which stopped sending samples.
+func (h *defaultHaTracker) clear(ctx context.Context, userID, cluster string, now time.Time) error {
+	return h.updateKVStoreAdmin(ctx, userID, cluster, func(desc *ReplicaDesc) *ReplicaDesc {
+		if desc == nil {
+			return nil
+		}
+		out := *desc
+		out.DeletedAt = timestamp.FromTime(now)
+		return &out
+	})
+}
+
+// updateKVStoreAdmin updates the replica of the cluster of userID in the KV store with update, called with the
+// current replica, or nil if the cluster isn't tracked. It returns errHAClusterNotFound if update returns nil.
+func (h *defaultHaTracker) updateKVStoreAdmin(ctx context.Context, userID, cluster string, update func(desc *ReplicaDesc) *ReplicaDesc) error {
+	key := fmt.Sprintf("%s/%s", userID, cluster)
+	var desc *ReplicaDesc
+	err := h.client.CAS(ctx, key, func(in interface{}) (out interface{}, retry bool, err error) {
+		current, ok := in.(*ReplicaDesc)
+		if !ok || current.DeletedAt > 0 {
+			current = nil
+		}
+		desc = update(current)
+		if desc == nil {
+			return nil, false, nil
+		}
+		return desc, true, nil
+	})
+	h.kvCASCalls.WithLabelValues(userID, cluster).Inc()
+	if err != nil {
+		return err
+	}
+	if desc == nil {
+		return errHAClusterNotFound
+	}
+
+	if desc.DeletedAt > 0 {
+		h.cleanupDeletedReplica(userID, cluster)
+		return nil
+	}
+	h.electedLock.Lock()
+	h.updateCache(userID, cluster, desc)
+	h.electedLock.Unlock()
+	return nil
+}
+
+// electReplicaDesc returns the replica descriptor electing replica in place of desc, which can be nil. The
+// timestamps are moved forward so that the descriptor wins over desc when merged.
+func electReplicaDesc(desc *ReplicaDesc, replica string, pinned bool, now time.Time) *ReplicaDesc {
+	out := &ReplicaDesc{
+		Replica:    replica,
+		ReceivedAt: timestamp.FromTime(now),
+		ElectedAt:  timestamp.FromTime(now),
+		Pinned:     pinned,
+	}
+	if desc == nil {
+		return out
+	}
+	out.ElectedChanges = desc.ElectedChanges
+	if desc.Replica == replica {
+		out.ElectedAt = desc.ElectedAt
+		out.ReceivedAt = max(out.ReceivedAt, desc.ReceivedAt+1)
+	} else {
+		out.ElectedAt = max(out.ElectedAt, desc.ElectedAt+1)
+		out.ElectedChanges++
+	}
+	return out
+}
+
 func findHALabels(replicaLabel, clusterLabel string, labels []mimirpb.LabelAdapter) (string, string) {
 	var cluster, replica string
 	var pair mimirpb.LabelAdapter
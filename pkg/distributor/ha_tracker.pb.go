@@ -39,6 +39,9 @@ type ReplicaDesc struct {
 	ElectedAt int64 `protobuf:"varint,4,opt,name=elected_at,json=electedAt,proto3" json:"elected_at,omitempty"`
 	// This is incremented every time a new replica is elected as the leader.
 	ElectedChanges int64 `protobuf:"varint,5,opt,name=elected_changes,json=electedChanges,proto3" json:"elected_changes,omitempty"`
+	// Whether the replica was pinned through the HA tracker admin API. A pinned replica stays elected even if
+	// it stops sending samples or a replica with a higher priority sends samples, until it's unpinned.
+	Pinned bool `protobuf:"varint,6,opt,name=pinned,proto3" json:"pinned,omitempty"`
 }
 
 func (m *ReplicaDesc) Reset()      { *m = ReplicaDesc{} }
@@ -108,6 +111,13 @@ func (m *ReplicaDesc) GetElectedChanges() int64 {
 	return 0
 }
 
+func (m *ReplicaDesc) GetPinned() bool {
+	if m != nil {
+		return m.Pinned
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*ReplicaDesc)(nil), "distributor.ReplicaDesc")
 }
@@ -115,23 +125,24 @@ func init() {
 func init() { proto.RegisterFile("ha_tracker.proto", fileDescriptor_86f0e7bcf71d860b) }
 
 var fileDescriptor_86f0e7bcf71d860b = []byte{
-	// 253 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x34, 0x8f, 0x3f, 0x4e, 0xc3, 0x30,
-	0x14, 0x87, 0xfd, 0x28, 0x7f, 0x54, 0x57, 0x02, 0xe4, 0x29, 0x42, 0xe2, 0x51, 0xb1, 0xd0, 0x85,
-	0x76, 0x80, 0x0b, 0x14, 0x38, 0x41, 0x2e, 0x50, 0x39, 0xce, 0x23, 0xb1, 0x08, 0xb8, 0x72, 0x1c,
-	0x66, 0x8e, 0xc0, 0x31, 0xd8, 0xb8, 0x06, 0x63, 0xc6, 0x8e, 0xc4, 0x59, 0x18, 0x7b, 0x04, 0x24,
-	0x3b, 0xd9, 0xfc, 0x7d, 0xdf, 0xcf, 0xc3, 0xe3, 0xe7, 0xa5, 0xdc, 0x38, 0x2b, 0xd5, 0x0b, 0xd9,
-	0xe5, 0xd6, 0x1a, 0x67, 0xc4, 0x2c, 0xd7, 0xb5, 0xb3, 0x3a, 0x6b, 0x9c, 0xb1, 0x17, 0xb7, 0x85,
-	0x76, 0x65, 0x93, 0x2d, 0x95, 0x79, 0x5d, 0x15, 0xa6, 0x30, 0xab, 0xb0, 0xc9, 0x9a, 0xe7, 0x40,
-	0x01, 0xc2, 0x2b, 0xfe, 0xbd, 0xfe, 0x06, 0x3e, 0x4b, 0x69, 0x5b, 0x69, 0x25, 0x9f, 0xa8, 0x56,
-	0x22, 0xe1, 0x27, 0x36, 0x62, 0x02, 0x73, 0x58, 0x4c, 0xd3, 0x11, 0xc5, 0x15, 0x9f, 0x59, 0x52,
-	0xa4, 0xdf, 0x29, 0xdf, 0x48, 0x97, 0x1c, 0xcc, 0x61, 0x31, 0x49, 0xf9, 0xa8, 0xd6, 0x4e, 0x5c,
-	0x72, 0x9e, 0x53, 0x45, 0x2e, 0xf6, 0x49, 0xe8, 0xd3, 0xc1, 0xc4, 0x4c, 0x15, 0xa9, 0x21, 0x1f,
-	0xc6, 0x3c, 0x98, 0xb5, 0x13, 0x37, 0xfc, 0x6c, 0xcc, 0xaa, 0x94, 0x6f, 0x05, 0xd5, 0xc9, 0x51,
-	0xd8, 0x9c, 0x0e, 0xfa, 0x31, 0xda, 0x87, 0xfb, 0xb6, 0x43, 0xb6, 0xeb, 0x90, 0xed, 0x3b, 0x84,
-	0x0f, 0x8f, 0xf0, 0xe5, 0x11, 0x7e, 0x3c, 0x42, 0xeb, 0x11, 0x7e, 0x3d, 0xc2, 0x9f, 0x47, 0xb6,
-	0xf7, 0x08, 0x9f, 0x3d, 0xb2, 0xb6, 0x47, 0xb6, 0xeb, 0x91, 0x65, 0xc7, 0xe1, 0xdc, 0xbb, 0xff,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0x78, 0x84, 0x4a, 0xc8, 0x3e, 0x01, 0x00, 0x00,
+	// 264 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x34, 0x90, 0x31, 0x4e, 0xc3, 0x30,
+	0x14, 0x86, 0xfd, 0x28, 0x14, 0xea, 0x4a, 0x80, 0x3c, 0xa0, 0x08, 0x89, 0x47, 0xc5, 0x42, 0x17,
+	0xda, 0x01, 0x2e, 0x50, 0xe0, 0x04, 0xb9, 0x40, 0x95, 0x38, 0x8f, 0xd4, 0xa2, 0xc4, 0x91, 0xe3,
+	0x30, 0x73, 0x04, 0x8e, 0xc1, 0x4d, 0x60, 0xcc, 0xd8, 0x91, 0x38, 0x0b, 0x63, 0x8f, 0x80, 0x64,
+	0x27, 0x9b, 0xbf, 0xef, 0xfb, 0xbd, 0x3c, 0x7e, 0xbe, 0x49, 0xd6, 0xd6, 0x24, 0xf2, 0x95, 0xcc,
+	0xa2, 0x34, 0xda, 0x6a, 0x31, 0xcd, 0x54, 0x65, 0x8d, 0x4a, 0x6b, 0xab, 0xcd, 0xe5, 0x5d, 0xae,
+	0xec, 0xa6, 0x4e, 0x17, 0x52, 0xbf, 0x2d, 0x73, 0x9d, 0xeb, 0xa5, 0xdf, 0xa4, 0xf5, 0x8b, 0x27,
+	0x0f, 0xfe, 0x15, 0xfe, 0xde, 0x7c, 0x03, 0x9f, 0xc6, 0x54, 0x6e, 0x95, 0x4c, 0x9e, 0xa9, 0x92,
+	0x22, 0xe2, 0xc7, 0x26, 0x60, 0x04, 0x33, 0x98, 0x4f, 0xe2, 0x01, 0xc5, 0x35, 0x9f, 0x1a, 0x92,
+	0xa4, 0xde, 0x29, 0x5b, 0x27, 0x36, 0x3a, 0x98, 0xc1, 0x7c, 0x14, 0xf3, 0x41, 0xad, 0xac, 0xb8,
+	0xe2, 0x3c, 0xa3, 0x2d, 0xd9, 0xd0, 0x47, 0xbe, 0x4f, 0x7a, 0x13, 0x32, 0x6d, 0x49, 0xf6, 0xf9,
+	0x30, 0xe4, 0xde, 0xac, 0xac, 0xb8, 0xe5, 0x67, 0x43, 0x96, 0x9b, 0xa4, 0xc8, 0xa9, 0x8a, 0x8e,
+	0xfc, 0xe6, 0xb4, 0xd7, 0x4f, 0xc1, 0x8a, 0x0b, 0x3e, 0x2e, 0x55, 0x51, 0x50, 0x16, 0x8d, 0x67,
+	0x30, 0x3f, 0x89, 0x7b, 0x7a, 0x7c, 0x68, 0x5a, 0x64, 0xbb, 0x16, 0xd9, 0xbe, 0x45, 0xf8, 0x70,
+	0x08, 0x5f, 0x0e, 0xe1, 0xc7, 0x21, 0x34, 0x0e, 0xe1, 0xd7, 0x21, 0xfc, 0x39, 0x64, 0x7b, 0x87,
+	0xf0, 0xd9, 0x21, 0x6b, 0x3a, 0x64, 0xbb, 0x0e, 0x59, 0x3a, 0xf6, 0x67, 0xb8, 0xff, 0x1f, 0x00,
+	0x9a, 0xcc, 0xbe, 0x36, 0x56, 0x01, 0x00, 0x00,
 }
 
 func (this *ReplicaDesc) Equal(that interface{}) bool {
@@ -168,19 +179,23 @@ func (this *ReplicaDesc) Equal(that interface{}) bool {
 	if this.ElectedChanges != that1.ElectedChanges {
 		return false
 	}
+	if this.Pinned != that1.Pinned {
+		return false
+	}
 	return true
 }
 func (this *ReplicaDesc) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 9)
+	s := make([]string, 0, 10)
 	s = append(s, "&distributor.ReplicaDesc{")
 	s = append(s, "Replica: "+fmt.Sprintf("%#v", this.Replica)+",\n")
 	s = append(s, "ReceivedAt: "+fmt.Sprintf("%#v", this.ReceivedAt)+",\n")
 	s = append(s, "DeletedAt: "+fmt.Sprintf("%#v", this.DeletedAt)+",\n")
 	s = append(s, "ElectedAt: "+fmt.Sprintf("%#v", this.ElectedAt)+",\n")
 	s = append(s, "ElectedChanges: "+fmt.Sprintf("%#v", this.ElectedChanges)+",\n")
+	s = append(s, "Pinned: "+fmt.Sprintf("%#v", this.Pinned)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -212,6 +227,16 @@ func (m *ReplicaDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Pinned {
+		i--
+		if m.Pinned {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x30
+	}
 	if m.ElectedChanges != 0 {
 		i = encodeVarintHaTracker(dAtA, i, uint64(m.ElectedChanges))
 		i--
@@ -275,6 +300,9 @@ func (m *ReplicaDesc) Size() (n int) {
 	if m.ElectedChanges != 0 {
 		n += 1 + sovHaTracker(uint64(m.ElectedChanges))
 	}
+	if m.Pinned {
+		n += 2
+	}
 	return n
 }
 
@@ -294,6 +322,7 @@ func (this *ReplicaDesc) String() string {
 		`DeletedAt:` + fmt.Sprintf("%v", this.DeletedAt) + `,`,
 		`ElectedAt:` + fmt.Sprintf("%v", this.ElectedAt) + `,`,
 		`ElectedChanges:` + fmt.Sprintf("%v", this.ElectedChanges) + `,`,
+		`Pinned:` + fmt.Sprintf("%v", this.Pinned) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -443,6 +472,26 @@ func (m *ReplicaDesc) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pinned", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHaTracker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Pinned = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipHaTracker(dAtA[iNdEx:])
@@ -7,11 +7,13 @@ package distributor
 
 import (
 	_ "embed" // Used to embed html template
+	"errors"
 	"html/template"
 	"net/http"
 	"sort"
 	"time"
 
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/prometheus/model/timestamp"
 
 	"github.com/grafana/mimir/pkg/util"
@@ -34,6 +36,7 @@ type haTrackerReplica struct {
 	ElectedLastSeenTime time.Time     `json:"electedLastSeenTime"`
 	UpdateTime          time.Duration `json:"updateDuration"`
 	FailoverTime        time.Duration `json:"failoverDuration"`
+	Pinned              bool          `json:"pinned"`
 }
 
 func (h *defaultHaTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -51,6 +54,7 @@ func (h *defaultHaTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				ElectedLastSeenTime: timestamp.Time(desc.ReceivedAt),
 				UpdateTime:          time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.UpdateTimeout)),
 				FailoverTime:        time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.FailoverTimeout)),
+				Pinned:              desc.Pinned,
 			})
 		}
 	}
@@ -71,3 +75,76 @@ func (h *defaultHaTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		Now:     time.Now(),
 	}, haTrackerStatusPageTemplate, req)
 }
+
+// FailoverHandler forces the failover of the cluster given by the user and cluster query parameters to the
+// replica given by the replica query parameter.
+func (h *defaultHaTracker) FailoverHandler(w http.ResponseWriter, req *http.Request) {
+	userID, cluster, ok := haTrackerAdminParams(w, req)
+	if !ok {
+		return
+	}
+	replica := req.FormValue("replica")
+	if replica == "" {
+		http.Error(w, "replica parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.forceFailover(req.Context(), userID, cluster, replica, time.Now())
+	h.writeAdminResponse(w, "forced failover", userID, cluster, err)
+}
+
+// PinHandler pins (POST) or unpins (DELETE) the replica of the cluster given by the user and cluster query
+// parameters. The replica query parameter optionally selects the replica to elect and pin, instead of the
+// elected replica.
+func (h *defaultHaTracker) PinHandler(w http.ResponseWriter, req *http.Request) {
+	userID, cluster, ok := haTrackerAdminParams(w, req)
+	if !ok {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		err := h.pin(req.Context(), userID, cluster, req.FormValue("replica"), time.Now())
+		h.writeAdminResponse(w, "pinned replica", userID, cluster, err)
+	case http.MethodDelete:
+		err := h.unpin(req.Context(), userID, cluster, time.Now())
+		h.writeAdminResponse(w, "unpinned replica", userID, cluster, err)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ClearHandler clears the cluster given by the user and cluster query parameters, so that a new replica is
+// elected from the next samples of the cluster.
+func (h *defaultHaTracker) ClearHandler(w http.ResponseWriter, req *http.Request) {
+	userID, cluster, ok := haTrackerAdminParams(w, req)
+	if !ok {
+		return
+	}
+
+	err := h.clear(req.Context(), userID, cluster, time.Now())
+	h.writeAdminResponse(w, "cleared cluster", userID, cluster, err)
+}
+
+func haTrackerAdminParams(w http.ResponseWriter, req *http.Request) (userID, cluster string, ok bool) {
+	userID = req.FormValue("user")
+	cluster = req.FormValue("cluster")
+	if userID == "" || cluster == "" {
+		http.Error(w, "user and cluster parameters are required", http.StatusBadRequest)
+		return "", "", false
+	}
+	return userID, cluster, true
+}
+
+func (h *defaultHaTracker) writeAdminResponse(w http.ResponseWriter, msg, userID, cluster string, err error) {
+	switch {
+	case errors.Is(err, errHAClusterNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		level.Error(h.logger).Log("msg", "failed to update HA tracker KV store", "user", userID, "cluster", cluster, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		level.Info(h.logger).Log("msg", msg, "user", userID, "cluster", cluster)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
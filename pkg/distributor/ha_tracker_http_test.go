@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHATracker_AdminHandlers(t *testing.T) {
+	c, err := newHaTracker(HATrackerConfig{
+		EnableHATracker:        true,
+		KVStore:                kv.Config{Store: "inmemory"},
+		UpdateTimeout:          100 * time.Millisecond,
+		UpdateTimeoutJitterMax: 0,
+		FailoverTimeout:        time.Second,
+	}, trackerLimits{maxClusters: 100}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	defer services.StopAndAwaitTerminated(context.Background(), c) //nolint:errcheck
+
+	serve := func(handler http.HandlerFunc, method, url string) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, url, nil))
+		return rec.Code
+	}
+
+	// Unknown clusters and missing parameters are rejected.
+	assert.Equal(t, http.StatusNotFound, serve(c.PinHandler, http.MethodPost, "/distributor/ha_tracker/pin?user=user-admin&cluster=test"))
+	assert.Equal(t, http.StatusNotFound, serve(c.ClearHandler, http.MethodPost, "/distributor/ha_tracker/clear?user=user-admin&cluster=test"))
+	assert.Equal(t, http.StatusBadRequest, serve(c.FailoverHandler, http.MethodPost, "/distributor/ha_tracker/failover?user=user-admin&cluster=test"))
+	assert.Equal(t, http.StatusBadRequest, serve(c.PinHandler, http.MethodPost, "/distributor/ha_tracker/pin?cluster=test"))
+
+	require.NoError(t, c.checkReplica(context.Background(), "user-admin", "test", "replica1", time.Now()))
+
+	// Forced failovers elect the replica immediately.
+	assert.Equal(t, http.StatusNoContent, serve(c.FailoverHandler, http.MethodPost, "/distributor/ha_tracker/failover?user=user-admin&cluster=test&replica=replica2"))
+	require.NoError(t, c.checkReplica(context.Background(), "user-admin", "test", "replica2", time.Now()))
+	assert.Error(t, c.checkReplica(context.Background(), "user-admin", "test", "replica1", time.Now()))
+
+	// Pinned replicas aren't failed over, even after the failover timeout.
+	assert.Equal(t, http.StatusNoContent, serve(c.PinHandler, http.MethodPost, "/distributor/ha_tracker/pin?user=user-admin&cluster=test"))
+	now := time.Now().Add(2 * time.Second)
+	assert.Error(t, c.checkReplica(context.Background(), "user-admin", "test", "replica1", now))
+	c.updateKVStoreAll(context.Background(), now)
+	require.NoError(t, c.checkReplica(context.Background(), "user-admin", "test", "replica2", now))
+
+	// Pinning another replica elects it.
+	assert.Equal(t, http.StatusNoContent, serve(c.PinHandler, http.MethodPost, "/distributor/ha_tracker/pin?user=user-admin&cluster=test&replica=replica3"))
+	assert.Error(t, c.checkReplica(context.Background(), "user-admin", "test", "replica2", now))
+	c.electedLock.RLock()
+	assert.Equal(t, "replica3", c.clusters["user-admin"]["test"].elected.Replica)
+	assert.True(t, c.clusters["user-admin"]["test"].elected.Pinned)
+	c.electedLock.RUnlock()
+
+	// Once unpinned, the replica is failed over after the failover timeout.
+	assert.Equal(t, http.StatusNoContent, serve(c.PinHandler, http.MethodDelete, "/distributor/ha_tracker/pin?user=user-admin&cluster=test"))
+	now = time.Now().Add(2 * time.Second)
+	assert.Error(t, c.checkReplica(context.Background(), "user-admin", "test", "replica1", now))
+	c.updateKVStoreAll(context.Background(), now)
+	require.NoError(t, c.checkReplica(context.Background(), "user-admin", "test", "replica1", now))
+
+	// Cleared clusters elect the replica writing next.
+	assert.Equal(t, http.StatusNoContent, serve(c.ClearHandler, http.MethodPost, "/distributor/ha_tracker/clear?user=user-admin&cluster=test"))
+	require.NoError(t, c.checkReplica(context.Background(), "user-admin", "test", "replica2", now))
+	assert.Error(t, c.checkReplica(context.Background(), "user-admin", "test", "replica1", now))
+}
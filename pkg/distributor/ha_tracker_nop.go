@@ -29,3 +29,15 @@ func (n nopHaTracker) checkReplica(context.Context, string, string, string, time
 func (n nopHaTracker) cleanupHATrackerMetricsForUser(string) {
 	// no-op
 }
+
+func (n nopHaTracker) FailoverHandler(w http.ResponseWriter, req *http.Request) {
+	http.NotFound(w, req)
+}
+
+func (n nopHaTracker) PinHandler(w http.ResponseWriter, req *http.Request) {
+	http.NotFound(w, req)
+}
+
+func (n nopHaTracker) ClearHandler(w http.ResponseWriter, req *http.Request) {
+	http.NotFound(w, req)
+}
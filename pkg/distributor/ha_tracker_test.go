@@ -616,6 +616,55 @@ func TestHATrackerCheckReplicaOverwriteTimeout(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestHATrackerCheckReplicaPriorities(t *testing.T) {
+	c, err := newHaTracker(HATrackerConfig{
+		EnableHATracker:        true,
+		KVStore:                kv.Config{Store: "inmemory"},
+		UpdateTimeout:          100 * time.Millisecond,
+		UpdateTimeoutJitterMax: 0,
+		FailoverTimeout:        time.Second,
+	}, trackerLimits{maxClusters: 100, replicaPriorities: map[string][]string{"test": {"replica1", "replica2"}}}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	defer services.StopAndAwaitTerminated(context.Background(), c) //nolint:errcheck
+
+	now := time.Now()
+
+	// The replica with the lowest priority writes first and is elected.
+	require.NoError(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica3", now))
+
+	// Samples from the replicas with higher priorities are rejected until the next update.
+	now = now.Add(200 * time.Millisecond)
+	require.NoError(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica3", now))
+	assert.Error(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica2", now))
+	assert.Error(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica1", now))
+	// A replica with a lower priority than the preferred one doesn't replace it.
+	assert.Error(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica2", now))
+
+	// The replica with the highest priority is elected, without waiting for the failover timeout.
+	c.updateKVStoreAll(context.Background(), now)
+	checkReplicaTimestamp(t, 100*time.Millisecond, c, "user-priorities", "test", "replica1", now, now)
+	require.NoError(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica1", now))
+
+	// Replicas with lower priorities don't replace it before the failover timeout.
+	now = now.Add(200 * time.Millisecond)
+	assert.Error(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica2", now))
+	c.updateKVStoreAll(context.Background(), now)
+	checkReplicaTimestamp(t, 100*time.Millisecond, c, "user-priorities", "test", "replica1", now.Add(-200*time.Millisecond), now)
+
+	// Once the failover timeout expired, the replica with the lower priority is elected.
+	now = now.Add(time.Second)
+	assert.Error(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica2", now))
+	c.updateKVStoreAll(context.Background(), now)
+	checkReplicaTimestamp(t, 100*time.Millisecond, c, "user-priorities", "test", "replica2", now, now)
+
+	// And the replica with the highest priority is re-elected once it's back.
+	now = now.Add(200 * time.Millisecond)
+	assert.Error(t, c.checkReplica(context.Background(), "user-priorities", "test", "replica1", now))
+	c.updateKVStoreAll(context.Background(), now)
+	checkReplicaTimestamp(t, 100*time.Millisecond, c, "user-priorities", "test", "replica1", now, now)
+}
+
 func TestHATrackerCheckReplicaMultiCluster(t *testing.T) {
 	replica1 := "replica1"
 	replica2 := "replica2"
@@ -1062,13 +1111,18 @@ func waitForClustersUpdate(t *testing.T, expected int, tr *defaultHaTracker, use
 }
 
 type trackerLimits struct {
-	maxClusters int
+	maxClusters       int
+	replicaPriorities map[string][]string
 }
 
 func (l trackerLimits) MaxHAClusters(_ string) int {
 	return l.maxClusters
 }
 
+func (l trackerLimits) HAReplicaPriorities(_ string) map[string][]string {
+	return l.replicaPriorities
+}
+
 func TestHATracker_MetricsCleanup(t *testing.T) {
 	reg := prometheus.NewPedanticRegistry()
 	logger := utiltest.NewTestingLogger(t)
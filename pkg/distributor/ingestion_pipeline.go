@@ -0,0 +1,316 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/distributor/ingestionpipeline"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// ingestionPipelineFlushInterval is how often the output series of the aggregation rules of the ingestion
+// pipelines are flushed.
+const ingestionPipelineFlushInterval = 5 * time.Second
+
+// aggregatorLabel is the label added to the output series of the aggregations of the ingestion pipelines, set
+// to the aggregator name of the distributor that aggregated them, because each distributor only aggregates the
+// series it receives.
+const aggregatorLabel = "aggregator"
+
+type pushSourceContextKey int
+
+const pushSourceKey pushSourceContextKey = 0
+
+// PushSourceHandler is a http.Handler adding the source of push requests, their client IP and token, to their
+// context, for the ingestion pipeline steps selecting series on it.
+func PushSourceHandler(sourceIPs *middleware.SourceIPExtractor, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var src ingestionpipeline.Source
+
+		remoteAddr := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+			remoteAddr = host
+		}
+		if sourceIPs != nil {
+			// The first source IP is the client, the others the proxies the request went through.
+			remoteAddr, _, _ = strings.Cut(sourceIPs.Get(r), ",")
+		}
+		if ip, err := netip.ParseAddr(strings.TrimSpace(remoteAddr)); err == nil {
+			src.IP = ip
+		}
+
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			src.Token = token
+		} else if _, password, ok := r.BasicAuth(); ok {
+			src.Token = password
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pushSourceKey, src)))
+	})
+}
+
+func pushSourceFromContext(ctx context.Context) ingestionpipeline.Source {
+	src, _ := ctx.Value(pushSourceKey).(ingestionpipeline.Source)
+	return src
+}
+
+// ingestionPipelineLimits are the limits the ingestion pipelines are configured with.
+type ingestionPipelineLimits interface {
+	IngestionPipeline(userID string) []*validation.IngestionPipelineStep
+	AggregationRules(userID string) []*validation.AggregationRule
+}
+
+// ingestionPipelines caches the pipeline of each tenant, so that the pipeline of a tenant is only compiled
+// when its configuration changes, and flushes the output series of the aggregation rules of the pipelines.
+type ingestionPipelines struct {
+	services.Service
+
+	limits         ingestionPipelineLimits
+	aggregatorName string
+	logger         log.Logger
+
+	// push pushes the output series of the aggregations through the middlewares following the pipeline.
+	push PushFunc
+
+	flushedSeries       *prometheus.CounterVec
+	failedFlushedSeries *prometheus.CounterVec
+
+	mtx       sync.Mutex
+	pipelines map[string]cachedIngestionPipeline
+	// replaced are the pipelines with aggregations which were replaced since the last flush, and must be
+	// entirely flushed.
+	replaced map[string][]*ingestionpipeline.Pipeline
+}
+
+type cachedIngestionPipeline struct {
+	steps    []*validation.IngestionPipelineStep
+	rules    []*validation.AggregationRule
+	pipeline *ingestionpipeline.Pipeline
+}
+
+func newIngestionPipelines(limits ingestionPipelineLimits, aggregatorName string, reg prometheus.Registerer, logger log.Logger) *ingestionPipelines {
+	p := &ingestionPipelines{
+		limits:         limits,
+		aggregatorName: aggregatorName,
+		logger:         logger,
+		flushedSeries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_aggregated_series_flushed_total",
+			Help: "The total number of series output by the aggregations of the ingestion pipelines, each with a single sample.",
+		}, []string{"user"}),
+		failedFlushedSeries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_aggregated_series_flush_failures_total",
+			Help: "The total number of series output by the aggregations of the ingestion pipelines which failed to be pushed.",
+		}, []string{"user"}),
+		pipelines: map[string]cachedIngestionPipeline{},
+		replaced:  map[string][]*ingestionpipeline.Pipeline{},
+	}
+	p.Service = services.NewTimerService(ingestionPipelineFlushInterval, nil, p.iteration, p.stopping)
+	return p
+}
+
+// pipeline returns the pipeline of userID, or nil if the tenant has no pipeline.
+func (p *ingestionPipelines) pipeline(userID string) (*ingestionpipeline.Pipeline, error) {
+	steps := p.limits.IngestionPipeline(userID)
+	rules := p.limits.AggregationRules(userID)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	// Steps and rules are replaced, rather than modified, when the runtime configuration is reloaded.
+	cached, ok := p.pipelines[userID]
+	if ok && slices.Equal(cached.steps, steps) && slices.Equal(cached.rules, rules) {
+		return cached.pipeline, nil
+	}
+
+	var pipeline *ingestionpipeline.Pipeline
+	if len(steps) > 0 || len(rules) > 0 {
+		var err error
+		pipeline, err = ingestionpipeline.New(steps, rules)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ok && cached.pipeline != nil && cached.pipeline.HasAggregation() {
+		p.replaced[userID] = append(p.replaced[userID], cached.pipeline)
+	}
+	p.pipelines[userID] = cachedIngestionPipeline{steps: slices.Clone(steps), rules: slices.Clone(rules), pipeline: pipeline}
+	return pipeline, nil
+}
+
+func (p *ingestionPipelines) deleteUserMetrics(userID string) {
+	p.flushedSeries.DeleteLabelValues(userID)
+	p.failedFlushedSeries.DeleteLabelValues(userID)
+}
+
+func (p *ingestionPipelines) iteration(ctx context.Context) error {
+	p.flush(ctx, time.Now(), false)
+	return nil
+}
+
+func (p *ingestionPipelines) stopping(_ error) error {
+	p.flush(context.Background(), time.Now(), true)
+	return nil
+}
+
+// flush pushes the output series of the aggregations of the pipelines for the windows which ended, or for all
+// the windows if all is true.
+func (p *ingestionPipelines) flush(ctx context.Context, now time.Time, all bool) {
+	p.mtx.Lock()
+	pipelines := make(map[string][]*ingestionpipeline.Pipeline, len(p.pipelines))
+	for userID, cached := range p.pipelines {
+		if cached.pipeline != nil && cached.pipeline.HasAggregation() {
+			pipelines[userID] = append(pipelines[userID], cached.pipeline)
+		}
+	}
+	replaced := p.replaced
+	p.replaced = map[string][]*ingestionpipeline.Pipeline{}
+	p.mtx.Unlock()
+
+	for userID, replacedPipelines := range replaced {
+		for _, pipeline := range replacedPipelines {
+			p.flushPipeline(ctx, userID, pipeline, now, true)
+		}
+	}
+	for userID, userPipelines := range pipelines {
+		for _, pipeline := range userPipelines {
+			p.flushPipeline(ctx, userID, pipeline, now, all)
+		}
+	}
+}
+
+func (p *ingestionPipelines) flushPipeline(ctx context.Context, userID string, pipeline *ingestionpipeline.Pipeline, now time.Time, all bool) {
+	series := pipeline.Flush(now, all)
+	if len(series) == 0 {
+		return
+	}
+	for _, ts := range series {
+		setLabel(ts.TimeSeries, aggregatorLabel, p.aggregatorName)
+	}
+	p.flushedSeries.WithLabelValues(userID).Add(float64(len(series)))
+
+	req := &mimirpb.WriteRequest{Timeseries: series, Source: mimirpb.API}
+	pushReq := NewParsedRequest(req)
+	pushReq.AddCleanup(func() {
+		mimirpb.ReuseSlice(req.Timeseries)
+	})
+	if err := p.push(user.InjectOrgID(ctx, userID), pushReq); err != nil {
+		p.failedFlushedSeries.WithLabelValues(userID).Add(float64(len(series)))
+		level.Warn(p.logger).Log("msg", "failed to push the aggregated series of the ingestion pipeline", "user", userID, "series", len(series), "err", err)
+	}
+}
+
+// setLabel sets the label name to value on the series, replacing its existing value if any. Labels are sorted
+// by the distributor after the pipeline.
+func setLabel(ts *mimirpb.TimeSeries, name, value string) {
+	for i, l := range ts.Labels {
+		if l.Name == name {
+			ts.Labels[i].Value = value
+			return
+		}
+	}
+	ts.Labels = append(ts.Labels, mimirpb.LabelAdapter{Name: name, Value: value})
+}
+
+// ingestionPipelineDiscardReasons are the reasons the samples discarded by each type of step are discarded with.
+var ingestionPipelineDiscardReasons = map[string]string{
+	validation.IngestionPipelineStepRelabel:     reasonIngestionPipelineRelabel,
+	validation.IngestionPipelineStepDropByValue: reasonIngestionPipelineDropByValue,
+	validation.IngestionPipelineStepDropByAge:   reasonIngestionPipelineDropByAge,
+	ingestionpipeline.StepTypeAggregationRules:  reasonAggregationRules,
+}
+
+// ingestionPipelineMetrics are the metrics of the samples discarded by the steps of the ingestion pipelines.
+type ingestionPipelineMetrics struct {
+	discardedSamples map[string]*prometheus.CounterVec
+}
+
+func newIngestionPipelineMetrics(r prometheus.Registerer) *ingestionPipelineMetrics {
+	return &ingestionPipelineMetrics{
+		discardedSamples: map[string]*prometheus.CounterVec{
+			validation.IngestionPipelineStepRelabel:     validation.DiscardedSamplesCounter(r, reasonIngestionPipelineRelabel),
+			validation.IngestionPipelineStepDropByValue: validation.DiscardedSamplesCounter(r, reasonIngestionPipelineDropByValue),
+			validation.IngestionPipelineStepDropByAge:   validation.DiscardedSamplesCounter(r, reasonIngestionPipelineDropByAge),
+			ingestionpipeline.StepTypeAggregationRules:  validation.DiscardedSamplesCounter(r, reasonAggregationRules),
+		},
+	}
+}
+
+func (m *ingestionPipelineMetrics) deleteUserMetrics(userID string) {
+	filter := prometheus.Labels{"user": userID}
+	for _, c := range m.discardedSamples {
+		c.DeletePartialMatch(filter)
+	}
+}
+
+func (m *ingestionPipelineMetrics) deleteUserMetricsForGroup(userID, group string) {
+	for _, c := range m.discardedSamples {
+		c.DeleteLabelValues(userID, group)
+	}
+}
+
+// prePushIngestionPipelineMiddleware runs the series through the ingestion pipeline of the tenant.
+func (d *Distributor) prePushIngestionPipelineMiddleware(next PushFunc) PushFunc {
+	// The output series of the aggregation rules skip the middlewares before the pipeline.
+	d.ingestionPipelines.push = next
+
+	return func(ctx context.Context, pushReq *Request) error {
+		next, maybeCleanup := NextOrCleanup(next, pushReq)
+		defer maybeCleanup()
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return err
+		}
+
+		pipeline, err := d.ingestionPipelines.pipeline(userID)
+		if err != nil {
+			level.Warn(d.log).Log("msg", "invalid ingestion pipeline, series are ingested without it", "user", userID, "err", err)
+		}
+		if pipeline == nil {
+			return next(ctx, pushReq)
+		}
+
+		req, err := pushReq.WriteRequest()
+		if err != nil {
+			return err
+		}
+		if len(req.Timeseries) == 0 {
+			return next(ctx, pushReq)
+		}
+
+		now := time.Now()
+		group := d.activeGroups.UpdateActiveGroupTimestamp(userID, validation.GroupLabel(d.limits, userID, req.Timeseries), now)
+		req.Timeseries = pipeline.Process(req.Timeseries, pushSourceFromContext(ctx), now, func(stepType string, lbls []mimirpb.LabelAdapter, samples int) {
+			d.discardIngestionPipelineSamples(userID, group, stepType, lbls, samples, now)
+		})
+
+		return next(ctx, pushReq)
+	}
+}
+
+func (d *Distributor) discardIngestionPipelineSamples(userID, group, stepType string, lbls []mimirpb.LabelAdapter, samples int, now time.Time) {
+	c, ok := d.ingestionPipelineMetrics.discardedSamples[stepType]
+	if !ok {
+		return
+	}
+	c.WithLabelValues(userID, group).Add(float64(samples))
+	d.costAttributionMgr.SampleTracker(userID).IncrementDiscardedSamples(lbls, float64(samples), ingestionPipelineDiscardReasons[stepType], now)
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/grafana/dskit/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/distributor/ingestionpipeline"
+)
+
+func TestPushSourceHandler(t *testing.T) {
+	sourceIPs, err := middleware.NewSourceIPs("", "", false)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		sourceIPs      *middleware.SourceIPExtractor
+		setup          func(r *http.Request)
+		expectedSource ingestionpipeline.Source
+	}{
+		"remote address": {
+			setup:          func(r *http.Request) { r.RemoteAddr = "10.0.0.1:1234" },
+			expectedSource: ingestionpipeline.Source{IP: netip.MustParseAddr("10.0.0.1")},
+		},
+		"forwarded address": {
+			sourceIPs: sourceIPs,
+			setup: func(r *http.Request) {
+				r.RemoteAddr = "10.0.0.1:1234"
+				r.Header.Set("X-Forwarded-For", "192.168.0.1")
+			},
+			expectedSource: ingestionpipeline.Source{IP: netip.MustParseAddr("192.168.0.1")},
+		},
+		"bearer token": {
+			setup: func(r *http.Request) {
+				r.RemoteAddr = "10.0.0.1:1234"
+				r.Header.Set("Authorization", "Bearer secret")
+			},
+			expectedSource: ingestionpipeline.Source{IP: netip.MustParseAddr("10.0.0.1"), Token: "secret"},
+		},
+		"basic authentication password": {
+			setup: func(r *http.Request) {
+				r.RemoteAddr = "10.0.0.1:1234"
+				r.SetBasicAuth("user", "password")
+			},
+			expectedSource: ingestionpipeline.Source{IP: netip.MustParseAddr("10.0.0.1"), Token: "password"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var actual ingestionpipeline.Source
+			handler := PushSourceHandler(tc.sourceIPs, http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				actual = pushSourceFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest("POST", "/api/v1/push", nil)
+			tc.setup(req)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.expectedSource, actual)
+		})
+	}
+}
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingestionpipeline
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// aggregator aggregates the float samples of the series grouped by or without the configured labels over
+// fixed windows. Once a window ended, an output series with a sample at the end of the window is flushed for
+// each group of input series and output.
+//
+// Windows are only flushed an interval after they ended, to give late samples the time to arrive. Samples
+// for windows which were already flushed are ignored. Series with native histograms aren't aggregated.
+//
+// Each distributor only aggregates the series it receives. The total output accumulates the increases of each
+// input series across windows, so that it doesn't drop when an input series moves to another distributor.
+type aggregator struct {
+	interval int64
+	by       []string
+	without  []string
+	outputs  []string
+	// metricNameSuffix is appended to the metric name of the output series, followed by the name of the output.
+	metricNameSuffix string
+	keepInput        bool
+
+	// staleness is how long the inputs and running totals of the total output are kept without being updated.
+	staleness int64
+
+	mtx     sync.Mutex
+	windows map[int64]*aggregationWindow
+	// flushedUntil is the end of the last flushed window.
+	flushedUntil int64
+
+	// totalInputs is the last sample of each input series, by hash of their labels, if the total output is
+	// configured.
+	totalInputs map[uint64]*totalInput
+	// totals is the running total of each group, by key of their labels.
+	totals map[string]*groupTotal
+}
+
+type totalInput struct {
+	sample mimirpb.Sample
+	// updatedAt is when the input was last received, in milliseconds.
+	updatedAt int64
+}
+
+type groupTotal struct {
+	value float64
+	// updatedAt is when the total was last flushed, in milliseconds.
+	updatedAt int64
+}
+
+type aggregationWindow struct {
+	groups map[string]*aggregationGroup
+}
+
+type aggregationGroup struct {
+	labels []mimirpb.LabelAdapter
+	// inputs is the last sample of each input series, by hash of their labels.
+	inputs map[uint64]mimirpb.Sample
+
+	samples  int
+	sum      float64
+	min, max float64
+	// increase is the sum of the increases of the inputs in the window, for the total output.
+	increase float64
+	// total is the running total of the group once the window is flushed.
+	total float64
+}
+
+// newRuleAggregator returns the aggregator of an aggregation rule.
+func newRuleAggregator(rule *validation.AggregationRule) *aggregator {
+	interval := time.Duration(rule.Interval).Milliseconds()
+	a := &aggregator{
+		interval:         interval,
+		by:               rule.By,
+		without:          rule.Without,
+		outputs:          rule.Outputs,
+		metricNameSuffix: rule.OutputMetricNameSuffix(),
+		keepInput:        rule.KeepInput,
+		// Inputs sampled less often than the interval are kept between their samples.
+		staleness: max(2*interval, totalMinStaleness.Milliseconds()),
+		windows:   map[int64]*aggregationWindow{},
+	}
+	if slices.Contains(rule.Outputs, validation.AggregationOutputTotal) {
+		a.totalInputs = map[uint64]*totalInput{}
+		a.totals = map[string]*groupTotal{}
+	}
+	return a
+}
+
+// totalMinStaleness is the minimum time the inputs and running totals of the total output are kept without
+// being updated.
+const totalMinStaleness = 5 * time.Minute
+
+// add adds the float samples of the series to the windows they belong to.
+func (a *aggregator) add(ts *mimirpb.PreallocTimeseries, now time.Time) {
+	// The labels of the series are sorted to hash them.
+	ts.SortLabelsIfNeeded()
+	groupLabels := a.groupLabels(ts.Labels)
+	key := mimirpb.FromLabelAdaptersToKeyString(groupLabels)
+	inputHash := mimirpb.FromLabelAdaptersToLabels(ts.Labels).Hash()
+	maxT := now.UnixMilli() + a.interval
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for _, s := range ts.Samples {
+		start := s.TimestampMs - s.TimestampMs%a.interval
+		if value.IsStaleNaN(s.Value) || start+a.interval <= a.flushedUntil || s.TimestampMs > maxT {
+			continue
+		}
+
+		w, ok := a.windows[start]
+		if !ok {
+			w = &aggregationWindow{groups: map[string]*aggregationGroup{}}
+			a.windows[start] = w
+		}
+		g, ok := w.groups[key]
+		if !ok {
+			// The labels of the request are only valid until the request is done.
+			g = &aggregationGroup{
+				labels: cloneLabels(groupLabels),
+				inputs: map[uint64]mimirpb.Sample{},
+				min:    math.Inf(1),
+				max:    math.Inf(-1),
+			}
+			w.groups[key] = g
+		}
+		if last, ok := g.inputs[inputHash]; !ok || s.TimestampMs >= last.TimestampMs {
+			g.inputs[inputHash] = s
+		}
+		if a.totalInputs != nil {
+			g.increase += a.inputIncrease(inputHash, s, now.UnixMilli())
+		}
+		g.samples++
+		g.sum += s.Value
+		g.min = min(g.min, s.Value)
+		g.max = max(g.max, s.Value)
+	}
+}
+
+// inputIncrease returns the increase of the input series since its previous sample, handling counter resets.
+// The first sample of an input isn't an increase, because the input may have been aggregated before by
+// another distributor. Samples older than the previous one are ignored.
+func (a *aggregator) inputIncrease(inputHash uint64, s mimirpb.Sample, now int64) float64 {
+	in, ok := a.totalInputs[inputHash]
+	if !ok {
+		a.totalInputs[inputHash] = &totalInput{sample: s, updatedAt: now}
+		return 0
+	}
+	in.updatedAt = now
+	if s.TimestampMs <= in.sample.TimestampMs {
+		return 0
+	}
+
+	increase := s.Value - in.sample.Value
+	if s.Value < in.sample.Value {
+		// The counter was reset.
+		increase = s.Value
+	}
+	in.sample = s
+	return increase
+}
+
+// groupLabels returns the labels of the group of an input series with sorted labels.
+func (a *aggregator) groupLabels(lbls []mimirpb.LabelAdapter) []mimirpb.LabelAdapter {
+	out := make([]mimirpb.LabelAdapter, 0, len(lbls))
+	for _, l := range lbls {
+		switch {
+		case len(a.by) > 0 && l.Name != labels.MetricName && !slices.Contains(a.by, l.Name):
+		case slices.Contains(a.without, l.Name):
+		default:
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// flush returns the output series of the windows which ended an interval before now, or of all the windows
+// if all is true.
+func (a *aggregator) flush(now time.Time, all bool) []mimirpb.PreallocTimeseries {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	defer a.purgeTotals(now.UnixMilli())
+
+	var starts []int64
+	for start := range a.windows {
+		if all || start+2*a.interval <= now.UnixMilli() {
+			starts = append(starts, start)
+		}
+	}
+	if len(starts) == 0 {
+		return nil
+	}
+	slices.Sort(starts)
+
+	series := mimirpb.PreallocTimeseriesSliceFromPool()
+	for _, start := range starts {
+		end := start + a.interval
+		for key, g := range a.windows[start].groups {
+			if a.totals != nil {
+				g.total = a.addTotal(key, g.increase, now.UnixMilli())
+			}
+			for _, output := range a.outputs {
+				ts := mimirpb.TimeseriesFromPool()
+				ts.Labels = a.outputLabels(ts.Labels, g.labels, output)
+				ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: end, Value: g.value(output)})
+				series = append(series, mimirpb.PreallocTimeseries{TimeSeries: ts})
+			}
+		}
+		delete(a.windows, start)
+		a.flushedUntil = max(a.flushedUntil, end)
+	}
+	return series
+}
+
+// addTotal adds the increase to the running total of the group with the key, and returns the running total.
+func (a *aggregator) addTotal(key string, increase float64, now int64) float64 {
+	t, ok := a.totals[key]
+	if !ok {
+		t = &groupTotal{}
+		a.totals[key] = t
+	}
+	t.value += increase
+	t.updatedAt = now
+	return t.value
+}
+
+// purgeTotals removes the inputs and running totals of the total output which weren't updated for longer than
+// the staleness. The running total of a group which is updated again restarts from zero, which is a counter reset.
+func (a *aggregator) purgeTotals(now int64) {
+	for hash, in := range a.totalInputs {
+		if now-in.updatedAt > a.staleness {
+			delete(a.totalInputs, hash)
+		}
+	}
+	for key, t := range a.totals {
+		if now-t.updatedAt > a.staleness {
+			delete(a.totals, key)
+		}
+	}
+}
+
+func (a *aggregator) outputLabels(dst, lbls []mimirpb.LabelAdapter, output string) []mimirpb.LabelAdapter {
+	dst = append(dst, lbls...)
+	for i, l := range dst {
+		if l.Name == labels.MetricName {
+			dst[i].Value = l.Value + a.metricNameSuffix + "_" + output
+		}
+	}
+	return dst
+}
+
+func (g *aggregationGroup) value(output string) float64 {
+	switch output {
+	case validation.AggregationOutputCountSeries:
+		return float64(len(g.inputs))
+	case validation.AggregationOutputCountSamples:
+		return float64(g.samples)
+	case validation.AggregationOutputMin:
+		return g.min
+	case validation.AggregationOutputMax:
+		return g.max
+	case validation.AggregationOutputAvg:
+		return g.sum / float64(g.samples)
+	case validation.AggregationOutputTotal:
+		return g.total
+	default:
+		// The sum is the sum of the last sample of each input series.
+		var sum float64
+		for _, s := range g.inputs {
+			sum += s.Value
+		}
+		return sum
+	}
+}
+
+func cloneLabels(lbls []mimirpb.LabelAdapter) []mimirpb.LabelAdapter {
+	out := make([]mimirpb.LabelAdapter, 0, len(lbls))
+	for _, l := range lbls {
+		out = append(out, mimirpb.LabelAdapter{Name: strings.Clone(l.Name), Value: strings.Clone(l.Value)})
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingestionpipeline
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// Source is the source of a push request, which add_labels steps select series on.
+type Source struct {
+	// IP is the address of the client, or the zero address if unknown.
+	IP netip.Addr
+	// Token is the bearer token or basic authentication password of the request, if any.
+	Token string
+}
+
+// StepTypeAggregationRules is the step type the samples of the input series dropped by aggregation rules are
+// discarded with.
+const StepTypeAggregationRules = "aggregation_rules"
+
+// DiscardFunc is called with the number of samples and histograms of a series discarded by a step of type
+// stepType, and the labels of the series before the step.
+type DiscardFunc func(stepType string, lbls []mimirpb.LabelAdapter, samples int)
+
+// Pipeline transforms the series ingested by a tenant with a list of steps, applied in order, and then
+// aggregates them with aggregation rules.
+type Pipeline struct {
+	steps []step
+	rules []rule
+}
+
+type step struct {
+	typ      string
+	matchers []*labels.Matcher
+	transform
+}
+
+type rule struct {
+	matchers   []*labels.Matcher
+	aggregator *aggregator
+}
+
+type transform interface {
+	// apply transforms the series in place, and returns the number of samples and histograms it dropped from
+	// the series, and whether the series must be kept. The samples and histograms of series which must not be
+	// kept are discarded.
+	apply(ts *mimirpb.PreallocTimeseries, src Source, now time.Time) (dropped int, keep bool)
+}
+
+// New compiles the steps and aggregation rules of a pipeline.
+func New(cfgs []*validation.IngestionPipelineStep, rules []*validation.AggregationRule) (*Pipeline, error) {
+	p := &Pipeline{steps: make([]step, 0, len(cfgs)), rules: make([]rule, 0, len(rules))}
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		matchers, err := cfg.Matchers()
+		if err != nil {
+			return nil, err
+		}
+
+		var t transform
+		switch cfg.Type {
+		case validation.IngestionPipelineStepRelabel:
+			t = newRelabelTransform(cfg)
+		case validation.IngestionPipelineStepDropByValue:
+			t = newDropByValueTransform(cfg)
+		case validation.IngestionPipelineStepDropByAge:
+			t = newDropByAgeTransform(cfg)
+		case validation.IngestionPipelineStepRenameMetric:
+			t, err = newRenameMetricTransform(cfg)
+		case validation.IngestionPipelineStepAddLabels:
+			t, err = newAddLabelsTransform(cfg)
+		default:
+			err = fmt.Errorf("unsupported ingestion pipeline step type %q", cfg.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.steps = append(p.steps, step{typ: cfg.Type, matchers: matchers, transform: t})
+	}
+
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		matchers, err := r.Matchers()
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, rule{matchers: matchers, aggregator: newRuleAggregator(r)})
+	}
+	return p, nil
+}
+
+// Process runs the series through the steps and aggregation rules of the pipeline, and returns the remaining
+// series. The series removed from the slice are returned to the pool.
+func (p *Pipeline) Process(series []mimirpb.PreallocTimeseries, src Source, now time.Time, discard DiscardFunc) []mimirpb.PreallocTimeseries {
+	var removeIndexes []int
+	for i := range series {
+		if !processSeries(&series[i], p.steps, src, now, discard) || !aggregateSeries(&series[i], p.rules, now, discard) {
+			removeIndexes = append(removeIndexes, i)
+		}
+	}
+
+	if len(removeIndexes) > 0 {
+		for _, i := range removeIndexes {
+			mimirpb.ReusePreallocTimeseries(&series[i])
+		}
+		series = util.RemoveSliceIndexes(series, removeIndexes)
+	}
+	return series
+}
+
+// processSeries runs the series through the steps, and returns whether it must be kept.
+func processSeries(ts *mimirpb.PreallocTimeseries, steps []step, src Source, now time.Time, discard DiscardFunc) bool {
+	for _, s := range steps {
+		if !matches(ts.Labels, s.matchers) {
+			continue
+		}
+
+		lbls := ts.Labels
+		hadSamples := len(ts.Samples)+len(ts.Histograms) > 0
+		dropped, keep := s.apply(ts, src, now)
+		if !keep {
+			dropped += len(ts.Samples) + len(ts.Histograms)
+		}
+		if dropped > 0 {
+			discard(s.typ, lbls, dropped)
+		}
+		// Series are dropped when all their samples and histograms were dropped, but series only sent with
+		// exemplars are kept.
+		if !keep || (hadSamples && len(ts.Samples)+len(ts.Histograms) == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// aggregateSeries adds the series to the aggregators of the matching rules, and returns whether it must be
+// kept. Series are dropped if any of the matching rules doesn't keep its input.
+func aggregateSeries(ts *mimirpb.PreallocTimeseries, rules []rule, now time.Time, discard DiscardFunc) bool {
+	keep := true
+	for _, r := range rules {
+		if len(ts.Histograms) > 0 || !matches(ts.Labels, r.matchers) {
+			continue
+		}
+		r.aggregator.add(ts, now)
+		keep = keep && r.aggregator.keepInput
+	}
+	if !keep {
+		discard(StepTypeAggregationRules, ts.Labels, len(ts.Samples))
+	}
+	return keep
+}
+
+// Flush returns the output series of the aggregation rules for the windows which ended, or for all the windows
+// if all is true.
+func (p *Pipeline) Flush(now time.Time, all bool) []mimirpb.PreallocTimeseries {
+	var series []mimirpb.PreallocTimeseries
+	for _, r := range p.rules {
+		series = append(series, r.aggregator.flush(now, all)...)
+	}
+	return series
+}
+
+// HasAggregation returns whether the pipeline has aggregation rules, whose output series must be flushed.
+func (p *Pipeline) HasAggregation() bool {
+	return len(p.rules) > 0
+}
+
+func matches(lbls []mimirpb.LabelAdapter, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(labelValue(lbls, m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func labelValue(lbls []mimirpb.LabelAdapter, name string) string {
+	for _, l := range lbls {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,299 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingestionpipeline
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestPipeline_Process(t *testing.T) {
+	now := time.UnixMilli(3_600_000)
+	minValue, maxValue := 0.0, 100.0
+
+	tests := map[string]struct {
+		steps             []*validation.IngestionPipelineStep
+		src               Source
+		input             []mimirpb.PreallocTimeseries
+		expected          []mimirpb.PreallocTimeseries
+		expectedDiscarded map[string]int
+	}{
+		"relabel": {
+			steps: []*validation.IngestionPipelineStep{{
+				Type: validation.IngestionPipelineStepRelabel,
+				RelabelConfigs: []*relabel.Config{
+					{SourceLabels: []model.LabelName{"env"}, Regex: relabel.MustNewRegexp("dev"), Action: relabel.Drop},
+					{Regex: relabel.MustNewRegexp("pod"), Action: relabel.LabelDrop},
+				},
+			}},
+			input: []mimirpb.PreallocTimeseries{
+				series(0, 1, "__name__", "up", "env", "dev"),
+				series(0, 1, "__name__", "up", "env", "prod", "pod", "p1"),
+			},
+			expected:          []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up", "env", "prod")},
+			expectedDiscarded: map[string]int{validation.IngestionPipelineStepRelabel: 1},
+		},
+		"drop by value of the matching series": {
+			steps: []*validation.IngestionPipelineStep{{
+				Type:     validation.IngestionPipelineStepDropByValue,
+				Match:    `{__name__="temperature"}`,
+				MinValue: &minValue,
+				MaxValue: &maxValue,
+			}},
+			input: []mimirpb.PreallocTimeseries{
+				series(0, -1, "__name__", "temperature", "room", "a"),
+				{TimeSeries: &mimirpb.TimeSeries{
+					Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "temperature"}, {Name: "room", Value: "b"}},
+					Samples: []mimirpb.Sample{{TimestampMs: 0, Value: 20}, {TimestampMs: 1, Value: 200}},
+				}},
+				series(0, -1, "__name__", "other"),
+			},
+			expected: []mimirpb.PreallocTimeseries{
+				series(0, 20, "__name__", "temperature", "room", "b"),
+				series(0, -1, "__name__", "other"),
+			},
+			expectedDiscarded: map[string]int{validation.IngestionPipelineStepDropByValue: 2},
+		},
+		"drop by age": {
+			steps: []*validation.IngestionPipelineStep{{
+				Type:   validation.IngestionPipelineStepDropByAge,
+				MaxAge: model.Duration(time.Hour),
+			}},
+			input: []mimirpb.PreallocTimeseries{
+				series(now.Add(-2*time.Hour).UnixMilli(), 1, "__name__", "old"),
+				series(now.UnixMilli(), 1, "__name__", "new"),
+			},
+			expected:          []mimirpb.PreallocTimeseries{series(now.UnixMilli(), 1, "__name__", "new")},
+			expectedDiscarded: map[string]int{validation.IngestionPipelineStepDropByAge: 1},
+		},
+		"rename metric": {
+			steps: []*validation.IngestionPipelineStep{{
+				Type:      validation.IngestionPipelineStepRenameMetric,
+				NameRegex: "legacy_(.+)",
+				Name:      "app_$1",
+			}},
+			input: []mimirpb.PreallocTimeseries{
+				series(0, 1, "__name__", "legacy_requests_total", "job", "app"),
+				series(0, 1, "__name__", "not_legacy_requests_total"),
+			},
+			expected: []mimirpb.PreallocTimeseries{
+				series(0, 1, "__name__", "app_requests_total", "job", "app"),
+				series(0, 1, "__name__", "not_legacy_requests_total"),
+			},
+		},
+		"add labels for source IP": {
+			steps: []*validation.IngestionPipelineStep{
+				{Type: validation.IngestionPipelineStepAddLabels, SourceIPs: []string{"10.0.0.0/8"}, Labels: map[string]string{"network": "internal"}},
+				{Type: validation.IngestionPipelineStepAddLabels, SourceIPs: []string{"192.168.0.1"}, Labels: map[string]string{"network": "office"}},
+			},
+			src:      Source{IP: netip.MustParseAddr("10.1.2.3")},
+			input:    []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up", "network", "unknown")},
+			expected: []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up", "network", "internal")},
+		},
+		"add labels for token": {
+			steps: []*validation.IngestionPipelineStep{
+				{Type: validation.IngestionPipelineStepAddLabels, Tokens: []flagext.Secret{flagext.SecretWithValue("secret")}, Labels: map[string]string{"team": "a"}},
+			},
+			src:      Source{IP: netip.MustParseAddr("10.1.2.3"), Token: "secret"},
+			input:    []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up")},
+			expected: []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up", "team", "a")},
+		},
+		"add labels for other source": {
+			steps: []*validation.IngestionPipelineStep{
+				{Type: validation.IngestionPipelineStepAddLabels, SourceIPs: []string{"10.0.0.0/8"}, Tokens: []flagext.Secret{flagext.SecretWithValue("secret")}, Labels: map[string]string{"team": "a"}},
+			},
+			src:      Source{IP: netip.MustParseAddr("192.168.0.1"), Token: "other"},
+			input:    []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up")},
+			expected: []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up")},
+		},
+		"steps are applied in order": {
+			steps: []*validation.IngestionPipelineStep{
+				{Type: validation.IngestionPipelineStepRenameMetric, NameRegex: "up", Name: "target_up"},
+				{Type: validation.IngestionPipelineStepDropByValue, Match: `{__name__="up"}`, MaxValue: &minValue},
+			},
+			input:    []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "up")},
+			expected: []mimirpb.PreallocTimeseries{series(0, 1, "__name__", "target_up")},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(tc.steps, nil)
+			require.NoError(t, err)
+
+			discarded := map[string]int{}
+			out := p.Process(tc.input, tc.src, now, func(stepType string, _ []mimirpb.LabelAdapter, samples int) {
+				discarded[stepType] += samples
+			})
+
+			require.Len(t, out, len(tc.expected))
+			for i := range out {
+				assert.ElementsMatch(t, tc.expected[i].Labels, out[i].Labels)
+				assert.Equal(t, tc.expected[i].Samples, out[i].Samples)
+			}
+			if tc.expectedDiscarded == nil {
+				tc.expectedDiscarded = map[string]int{}
+			}
+			assert.Equal(t, tc.expectedDiscarded, discarded)
+		})
+	}
+}
+
+func TestPipeline_AggregationWindows(t *testing.T) {
+	p, err := New(nil, []*validation.AggregationRule{
+		{Match: `{__name__="requests_total"}`, Interval: model.Duration(time.Minute), Without: []string{"pod"}, Outputs: []string{validation.AggregationOutputSum}},
+	})
+	require.NoError(t, err)
+
+	discard := func(string, []mimirpb.LabelAdapter, int) {}
+
+	now := time.UnixMilli(130_000)
+	p.Process([]mimirpb.PreallocTimeseries{
+		{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "requests_total"}, {Name: "pod", Value: "a"}, {Name: "service", Value: "s"}},
+			Samples: []mimirpb.Sample{{TimestampMs: 70_000, Value: 1}, {TimestampMs: 80_000, Value: 2}, {TimestampMs: 130_000, Value: 10}},
+		}},
+		series(75_000, 3, "__name__", "requests_total", "pod", "b", "service", "s"),
+		series(75_000, 100, "__name__", "requests_total", "pod", "c", "service", "other"),
+	}, Source{}, now, discard)
+
+	// The window ending at 120s is only flushed an interval after it ended.
+	require.Empty(t, p.Flush(time.UnixMilli(150_000), false))
+
+	flushed := p.Flush(time.UnixMilli(180_000), false)
+	actual := map[string]mimirpb.Sample{}
+	for _, ts := range flushed {
+		require.Len(t, ts.Samples, 1)
+		actual[mimirpb.FromLabelAdaptersToString(ts.Labels)] = ts.Samples[0]
+	}
+	assert.Equal(t, map[string]mimirpb.Sample{
+		`requests_total:1m_without_pod_sum{service="s"}`:     {TimestampMs: 120_000, Value: 5},
+		`requests_total:1m_without_pod_sum{service="other"}`: {TimestampMs: 120_000, Value: 100},
+	}, actual)
+
+	// Samples of flushed windows are ignored.
+	p.Process([]mimirpb.PreallocTimeseries{series(90_000, 1, "__name__", "requests_total", "pod", "a", "service", "s")}, Source{}, now, discard)
+
+	// All the windows are flushed when required.
+	flushed = p.Flush(time.UnixMilli(180_000), true)
+	require.Len(t, flushed, 1)
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 180_000, Value: 10}}, flushed[0].Samples)
+	require.Empty(t, p.Flush(time.UnixMilli(1_000_000), true))
+}
+
+func TestPipeline_AggregationRules(t *testing.T) {
+	p, err := New(nil, []*validation.AggregationRule{
+		{
+			Match:    `{__name__="requests_total"}`,
+			Interval: model.Duration(time.Minute),
+			By:       []string{"service"},
+			Outputs:  []string{validation.AggregationOutputSum, validation.AggregationOutputCountSeries, validation.AggregationOutputCountSamples},
+		},
+		{
+			Match:     `{__name__="temperature"}`,
+			Interval:  model.Duration(time.Minute),
+			Without:   []string{"sensor"},
+			Outputs:   []string{validation.AggregationOutputMin, validation.AggregationOutputMax, validation.AggregationOutputAvg},
+			KeepInput: true,
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, p.HasAggregation())
+
+	discarded := map[string]int{}
+	discard := func(stepType string, _ []mimirpb.LabelAdapter, samples int) {
+		discarded[stepType] += samples
+	}
+
+	now := time.UnixMilli(90_000)
+	out := p.Process([]mimirpb.PreallocTimeseries{
+		{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "requests_total"}, {Name: "pod", Value: "a"}, {Name: "service", Value: "s"}},
+			Samples: []mimirpb.Sample{{TimestampMs: 70_000, Value: 1}, {TimestampMs: 80_000, Value: 2}},
+		}},
+		series(75_000, 3, "__name__", "requests_total", "pod", "b", "service", "s"),
+		series(75_000, 10, "__name__", "temperature", "room", "a", "sensor", "1"),
+		series(75_000, 20, "__name__", "temperature", "room", "a", "sensor", "2"),
+	}, Source{}, now, discard)
+
+	// The input series of the rules are dropped, unless the rule keeps them.
+	require.Len(t, out, 2)
+	assert.Equal(t, "temperature", out[0].Labels[0].Value)
+	assert.Equal(t, "temperature", out[1].Labels[0].Value)
+	assert.Equal(t, map[string]int{StepTypeAggregationRules: 3}, discarded)
+
+	flushed := p.Flush(time.UnixMilli(180_000), false)
+	actual := map[string]float64{}
+	for _, ts := range flushed {
+		require.Len(t, ts.Samples, 1)
+		assert.Equal(t, int64(120_000), ts.Samples[0].TimestampMs)
+		actual[mimirpb.FromLabelAdaptersToString(ts.Labels)] = ts.Samples[0].Value
+	}
+	assert.Equal(t, map[string]float64{
+		`requests_total:1m_by_service_sum{service="s"}`:           5,
+		`requests_total:1m_by_service_count_series{service="s"}`:  2,
+		`requests_total:1m_by_service_count_samples{service="s"}`: 3,
+		`temperature:1m_without_sensor_min{room="a"}`:             10,
+		`temperature:1m_without_sensor_max{room="a"}`:             20,
+		`temperature:1m_without_sensor_avg{room="a"}`:             15,
+	}, actual)
+}
+
+func TestPipeline_AggregationRules_Total(t *testing.T) {
+	p, err := New(nil, []*validation.AggregationRule{
+		{Match: `{__name__="requests_total"}`, Interval: model.Duration(time.Minute), Without: []string{"pod"}, Outputs: []string{validation.AggregationOutputSum, validation.AggregationOutputTotal}},
+	})
+	require.NoError(t, err)
+
+	discard := func(string, []mimirpb.LabelAdapter, int) {}
+	// process adds the samples of the pods at ts, and flushes the window they belong to.
+	process := func(ts int64, values map[string]float64) map[string]float64 {
+		var in []mimirpb.PreallocTimeseries
+		for pod, v := range values {
+			in = append(in, series(ts, v, "__name__", "requests_total", "pod", pod))
+		}
+		p.Process(in, Source{}, time.UnixMilli(ts), discard)
+
+		out := map[string]float64{}
+		for _, s := range p.Flush(time.UnixMilli(ts+2*time.Minute.Milliseconds()), false) {
+			out[s.Labels[0].Value] = s.Samples[0].Value
+		}
+		return out
+	}
+
+	// The first sample of each input isn't an increase.
+	assert.Equal(t, map[string]float64{"requests_total:1m_without_pod_sum": 15, "requests_total:1m_without_pod_total": 0}, process(30_000, map[string]float64{"a": 5, "b": 10}))
+	assert.Equal(t, map[string]float64{"requests_total:1m_without_pod_sum": 20, "requests_total:1m_without_pod_total": 5}, process(90_000, map[string]float64{"a": 7, "b": 13}))
+
+	// The series b moved to another distributor: the sum drops, but not the total.
+	assert.Equal(t, map[string]float64{"requests_total:1m_without_pod_sum": 9, "requests_total:1m_without_pod_total": 7}, process(150_000, map[string]float64{"a": 9}))
+
+	// The series a was reset.
+	assert.Equal(t, map[string]float64{"requests_total:1m_without_pod_sum": 4, "requests_total:1m_without_pod_total": 11}, process(210_000, map[string]float64{"a": 4}))
+
+	// Samples older than the previous sample of an input aren't increases.
+	p.Process([]mimirpb.PreallocTimeseries{series(275_000, 6, "__name__", "requests_total", "pod", "a")}, Source{}, time.UnixMilli(275_000), discard)
+	assert.Equal(t, map[string]float64{"requests_total:1m_without_pod_sum": 6, "requests_total:1m_without_pod_total": 13}, process(270_000, map[string]float64{"a": 5}))
+
+	// The inputs and running totals which weren't updated for longer than the staleness are purged.
+	require.Empty(t, p.Flush(time.UnixMilli(390_000+(5*time.Minute).Milliseconds()+1), false))
+	assert.Equal(t, map[string]float64{"requests_total:1m_without_pod_sum": 8, "requests_total:1m_without_pod_total": 0}, process(800_000, map[string]float64{"a": 8}))
+}
+
+func series(ts int64, value float64, lbls ...string) mimirpb.PreallocTimeseries {
+	s := &mimirpb.TimeSeries{Samples: []mimirpb.Sample{{TimestampMs: ts, Value: value}}}
+	for i := 0; i < len(lbls); i += 2 {
+		s.Labels = append(s.Labels, mimirpb.LabelAdapter{Name: lbls[i], Value: lbls[i+1]})
+	}
+	return mimirpb.PreallocTimeseries{TimeSeries: s}
+}
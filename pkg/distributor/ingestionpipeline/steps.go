@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingestionpipeline
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/netip"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// relabelTransform relabels series, with the same semantics as metric_relabel_configs.
+type relabelTransform struct {
+	configs  []*relabel.Config
+	builders sync.Pool
+}
+
+func newRelabelTransform(cfg *validation.IngestionPipelineStep) *relabelTransform {
+	return &relabelTransform{
+		configs: cfg.RelabelConfigs,
+		builders: sync.Pool{New: func() any {
+			return labels.NewBuilder(labels.EmptyLabels())
+		}},
+	}
+}
+
+func (t *relabelTransform) apply(ts *mimirpb.PreallocTimeseries, _ Source, _ time.Time) (int, bool) {
+	lb := t.builders.Get().(*labels.Builder)
+	defer t.builders.Put(lb)
+
+	mimirpb.FromLabelAdaptersToBuilder(ts.Labels, lb)
+	if !relabel.ProcessBuilder(lb, t.configs...) {
+		return 0, false
+	}
+	ts.SetLabels(mimirpb.FromBuilderToLabelAdapters(lb, ts.Labels))
+	return 0, len(ts.Labels) > 0
+}
+
+// dropByValueTransform drops the float samples whose value is out of the configured range. NaN values, like
+// staleness markers, are never out of the range.
+type dropByValueTransform struct {
+	min, max float64
+}
+
+func newDropByValueTransform(cfg *validation.IngestionPipelineStep) *dropByValueTransform {
+	t := &dropByValueTransform{min: math.Inf(-1), max: math.Inf(1)}
+	if cfg.MinValue != nil {
+		t.min = *cfg.MinValue
+	}
+	if cfg.MaxValue != nil {
+		t.max = *cfg.MaxValue
+	}
+	return t
+}
+
+func (t *dropByValueTransform) apply(ts *mimirpb.PreallocTimeseries, _ Source, _ time.Time) (int, bool) {
+	n := len(ts.Samples)
+	ts.Samples = slices.DeleteFunc(ts.Samples, func(s mimirpb.Sample) bool {
+		return s.Value < t.min || s.Value > t.max
+	})
+	if dropped := n - len(ts.Samples); dropped > 0 {
+		ts.SamplesUpdated()
+		return dropped, true
+	}
+	return 0, true
+}
+
+// dropByAgeTransform drops the samples and histograms older than the configured age.
+type dropByAgeTransform struct {
+	maxAge time.Duration
+}
+
+func newDropByAgeTransform(cfg *validation.IngestionPipelineStep) *dropByAgeTransform {
+	return &dropByAgeTransform{maxAge: time.Duration(cfg.MaxAge)}
+}
+
+func (t *dropByAgeTransform) apply(ts *mimirpb.PreallocTimeseries, _ Source, now time.Time) (int, bool) {
+	minT := now.Add(-t.maxAge).UnixMilli()
+	dropped := 0
+
+	n := len(ts.Samples)
+	ts.Samples = slices.DeleteFunc(ts.Samples, func(s mimirpb.Sample) bool { return s.TimestampMs < minT })
+	if len(ts.Samples) < n {
+		dropped += n - len(ts.Samples)
+		ts.SamplesUpdated()
+	}
+
+	n = len(ts.Histograms)
+	ts.Histograms = slices.DeleteFunc(ts.Histograms, func(h mimirpb.Histogram) bool { return h.Timestamp < minT })
+	if len(ts.Histograms) < n {
+		dropped += n - len(ts.Histograms)
+		ts.HistogramsUpdated()
+	}
+
+	return dropped, true
+}
+
+// renameMetricTransform renames the metrics whose name matches a regular expression. The new name can
+// reference the capture groups of the regular expression.
+type renameMetricTransform struct {
+	regex *regexp.Regexp
+	name  string
+}
+
+func newRenameMetricTransform(cfg *validation.IngestionPipelineStep) (*renameMetricTransform, error) {
+	// The regular expression is anchored, like the regular expressions of relabel configs.
+	regex, err := regexp.Compile("^(?:" + cfg.NameRegex + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return &renameMetricTransform{regex: regex, name: cfg.Name}, nil
+}
+
+func (t *renameMetricTransform) apply(ts *mimirpb.PreallocTimeseries, _ Source, _ time.Time) (int, bool) {
+	for i, l := range ts.Labels {
+		if l.Name != labels.MetricName {
+			continue
+		}
+		match := t.regex.FindStringSubmatchIndex(l.Value)
+		if match == nil {
+			break
+		}
+		ts.Labels[i].Value = string(t.regex.ExpandString(nil, t.name, l.Value, match))
+		ts.SetLabels(ts.Labels)
+		break
+	}
+	return 0, true
+}
+
+// addLabelsTransform adds labels to the series of the requests from the configured source IPs or with the
+// configured tokens, or to all series if neither are configured. Existing labels with the same names are
+// replaced.
+type addLabelsTransform struct {
+	labels    []mimirpb.LabelAdapter
+	sourceIPs []netip.Prefix
+	tokens    []string
+}
+
+func newAddLabelsTransform(cfg *validation.IngestionPipelineStep) (*addLabelsTransform, error) {
+	sourceIPs, err := cfg.SourceIPPrefixes()
+	if err != nil {
+		return nil, err
+	}
+	t := &addLabelsTransform{sourceIPs: sourceIPs}
+	for _, token := range cfg.Tokens {
+		t.tokens = append(t.tokens, token.String())
+	}
+	for name, value := range cfg.Labels {
+		t.labels = append(t.labels, mimirpb.LabelAdapter{Name: name, Value: value})
+	}
+	slices.SortFunc(t.labels, func(a, b mimirpb.LabelAdapter) int { return a.Compare(b) })
+	return t, nil
+}
+
+func (t *addLabelsTransform) apply(ts *mimirpb.PreallocTimeseries, src Source, _ time.Time) (int, bool) {
+	if !t.matchesSource(src) {
+		return 0, true
+	}
+
+	lbls := ts.Labels
+	for _, add := range t.labels {
+		if i := slices.IndexFunc(lbls, func(l mimirpb.LabelAdapter) bool { return l.Name == add.Name }); i >= 0 {
+			lbls[i].Value = add.Value
+		} else {
+			lbls = append(lbls, add)
+		}
+	}
+	// Labels are sorted by the distributor after the pipeline.
+	ts.SetLabels(lbls)
+	return 0, true
+}
+
+func (t *addLabelsTransform) matchesSource(src Source) bool {
+	if len(t.sourceIPs) == 0 && len(t.tokens) == 0 {
+		return true
+	}
+	if src.IP.IsValid() {
+		ip := src.IP.Unmap()
+		for _, prefix := range t.sourceIPs {
+			if prefix.Contains(ip) {
+				return true
+			}
+		}
+	}
+	if src.Token != "" {
+		for _, token := range t.tokens {
+			if subtle.ConstantTimeCompare([]byte(src.Token), []byte(token)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
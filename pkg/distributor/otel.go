@@ -57,6 +57,7 @@ type OTLPHandlerLimits interface {
 	OTelCreatedTimestampZeroIngestionEnabled(id string) bool
 	PromoteOTelResourceAttributes(id string) []string
 	OTelKeepIdentifyingResourceAttributes(id string) bool
+	OTelDeltaToCumulativeEnabled(id string) bool
 }
 
 // OTLPHandler is an http.Handler accepting OTLP write requests.
@@ -262,55 +263,81 @@ func newOTLPParser(
 
 		level.Debug(spanLogger).Log("msg", "decoding complete, starting conversion")
 
-		tenantID, err := tenant.TenantID(ctx)
-		if err != nil {
-			return err
-		}
-		addSuffixes := limits.OTelMetricSuffixesEnabled(tenantID)
-		enableCTZeroIngestion := limits.OTelCreatedTimestampZeroIngestionEnabled(tenantID)
-		if resourceAttributePromotionConfig == nil {
-			resourceAttributePromotionConfig = limits
-		}
-		promoteResourceAttributes := resourceAttributePromotionConfig.PromoteOTelResourceAttributes(tenantID)
-		keepIdentifyingResourceAttributes := limits.OTelKeepIdentifyingResourceAttributes(tenantID)
-
-		pushMetrics.IncOTLPRequest(tenantID)
-		pushMetrics.ObserveUncompressedBodySize(tenantID, float64(uncompressedBodySize))
+		return convertOTLPRequest(ctx, otlpReq, uncompressedBodySize, limits, resourceAttributePromotionConfig, otlpConverter, enableStartTimeQuietZero, pushMetrics, discardedDueToOtelParseError, req, spanLogger)
+	}
+}
 
-		metrics, metricsDropped, err := otelMetricsToTimeseries(ctx, otlpConverter, addSuffixes, enableCTZeroIngestion, enableStartTimeQuietZero, promoteResourceAttributes, keepIdentifyingResourceAttributes, otlpReq.Metrics(), spanLogger)
-		if metricsDropped > 0 {
-			discardedDueToOtelParseError.WithLabelValues(tenantID, "").Add(float64(metricsDropped)) // "group" label is empty here as metrics couldn't be parsed
-		}
-		if err != nil {
-			return err
-		}
+// convertOTLPRequest converts the decoded OTLP request otlpReq to Mimir timeseries and metadata, and stores them in req.
+func convertOTLPRequest(
+	ctx context.Context,
+	otlpReq pmetricotlp.ExportRequest,
+	uncompressedBodySize int,
+	limits OTLPHandlerLimits,
+	resourceAttributePromotionConfig OTelResourceAttributePromotionConfig,
+	otlpConverter *otlpMimirConverter,
+	enableStartTimeQuietZero bool,
+	pushMetrics *PushMetrics,
+	discardedDueToOtelParseError *prometheus.CounterVec,
+	req *mimirpb.PreallocWriteRequest,
+	spanLogger *spanlogger.SpanLogger,
+) error {
+	tenantID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return err
+	}
+	addSuffixes := limits.OTelMetricSuffixesEnabled(tenantID)
+	enableCTZeroIngestion := limits.OTelCreatedTimestampZeroIngestionEnabled(tenantID)
+	if resourceAttributePromotionConfig == nil {
+		resourceAttributePromotionConfig = limits
+	}
+	promoteResourceAttributes := resourceAttributePromotionConfig.PromoteOTelResourceAttributes(tenantID)
+	keepIdentifyingResourceAttributes := limits.OTelKeepIdentifyingResourceAttributes(tenantID)
 
-		metricCount := len(metrics)
-		sampleCount := 0
-		histogramCount := 0
-		exemplarCount := 0
+	pushMetrics.IncOTLPRequest(tenantID)
+	pushMetrics.ObserveUncompressedBodySize(tenantID, float64(uncompressedBodySize))
 
-		for _, m := range metrics {
-			sampleCount += len(m.Samples)
-			histogramCount += len(m.Histograms)
-			exemplarCount += len(m.Exemplars)
-		}
+	// Delta sums and histograms are dropped by the converter, unless they're accumulated by the ingesters.
+	hasDeltaMetrics := false
+	if limits.OTelDeltaToCumulativeEnabled(tenantID) {
+		hasDeltaMetrics = markDeltaMetrics(otlpReq.Metrics())
+	}
 
-		level.Debug(spanLogger).Log(
-			"msg", "OTLP to Prometheus conversion complete",
-			"metric_count", metricCount,
-			"metrics_dropped", metricsDropped,
-			"sample_count", sampleCount,
-			"histogram_count", histogramCount,
-			"exemplar_count", exemplarCount,
-			"promoted_resource_attributes", promoteResourceAttributes,
-		)
+	metrics, metricsDropped, err := otelMetricsToTimeseries(ctx, otlpConverter, addSuffixes, enableCTZeroIngestion, enableStartTimeQuietZero, promoteResourceAttributes, keepIdentifyingResourceAttributes, otlpReq.Metrics(), spanLogger)
+	if metricsDropped > 0 {
+		discardedDueToOtelParseError.WithLabelValues(tenantID, "").Add(float64(metricsDropped)) // "group" label is empty here as metrics couldn't be parsed
+	}
+	if err != nil {
+		return err
+	}
+	if hasDeltaMetrics {
+		unmarkDeltaTimeseries(metrics)
+	}
 
-		req.Timeseries = metrics
-		req.Metadata = otelMetricsToMetadata(addSuffixes, otlpReq.Metrics())
+	metricCount := len(metrics)
+	sampleCount := 0
+	histogramCount := 0
+	exemplarCount := 0
 
-		return nil
+	for _, m := range metrics {
+		sampleCount += len(m.Samples)
+		histogramCount += len(m.Histograms)
+		exemplarCount += len(m.Exemplars)
 	}
+
+	level.Debug(spanLogger).Log(
+		"msg", "OTLP to Prometheus conversion complete",
+		"metric_count", metricCount,
+		"metrics_dropped", metricsDropped,
+		"sample_count", sampleCount,
+		"histogram_count", histogramCount,
+		"exemplar_count", exemplarCount,
+		"promoted_resource_attributes", promoteResourceAttributes,
+	)
+
+	req.Timeseries = metrics
+	req.Metadata = otelMetricsToMetadata(addSuffixes, otlpReq.Metrics())
+
+	return nil
 }
 
 // toOtlpGRPCHTTPStatus is utilized by the OTLP endpoint.
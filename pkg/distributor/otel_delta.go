@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// otlpDeltaMarkerLabel is the data point attribute added to the data points of OTel metrics with delta temporality,
+// so that the series they're converted to can be told apart once converted. It's removed before the series are pushed.
+const otlpDeltaMarkerLabel = "__mimir_otlp_delta__"
+
+// markDeltaMetrics changes the aggregation temporality of OTel sums and histograms with delta temporality to
+// cumulative, so that the OTLP converter doesn't drop them, and marks their data points with otlpDeltaMarkerLabel.
+// It returns whether any metric was marked.
+func markDeltaMetrics(md pmetric.Metrics) bool {
+	marked := false
+
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+
+				//exhaustive:enforce
+				switch metric.Type() {
+				case pmetric.MetricTypeSum:
+					sum := metric.Sum()
+					if sum.AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+						continue
+					}
+					sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+					for x := 0; x < sum.DataPoints().Len(); x++ {
+						markDeltaDataPoint(sum.DataPoints().At(x))
+					}
+					marked = true
+
+				case pmetric.MetricTypeHistogram:
+					hist := metric.Histogram()
+					if hist.AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+						continue
+					}
+					hist.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+					for x := 0; x < hist.DataPoints().Len(); x++ {
+						markDeltaDataPoint(hist.DataPoints().At(x))
+					}
+					marked = true
+
+				case pmetric.MetricTypeExponentialHistogram:
+					hist := metric.ExponentialHistogram()
+					if hist.AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+						continue
+					}
+					hist.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+					for x := 0; x < hist.DataPoints().Len(); x++ {
+						markDeltaDataPoint(hist.DataPoints().At(x))
+					}
+					marked = true
+
+				case pmetric.MetricTypeGauge, pmetric.MetricTypeSummary, pmetric.MetricTypeEmpty:
+				}
+			}
+		}
+	}
+
+	return marked
+}
+
+type deltaDataPoint interface {
+	Attributes() pcommon.Map
+	SetStartTimestamp(pcommon.Timestamp)
+}
+
+func markDeltaDataPoint(dp deltaDataPoint) {
+	dp.Attributes().PutStr(otlpDeltaMarkerLabel, "true")
+	// The start timestamp of a delta data point is the start of the interval the delta refers to, not the time
+	// the cumulative series was created, so it must not be converted to a zero sample.
+	dp.SetStartTimestamp(0)
+}
+
+// unmarkDeltaTimeseries removes otlpDeltaMarkerLabel from the series converted from metrics marked by
+// markDeltaMetrics and flags them as having delta temporality, so that ingesters accumulate their samples.
+func unmarkDeltaTimeseries(timeseries []mimirpb.PreallocTimeseries) {
+	for _, ts := range timeseries {
+		for i, l := range ts.Labels {
+			if l.Name != otlpDeltaMarkerLabel {
+				continue
+			}
+
+			ts.Labels = append(ts.Labels[:i], ts.Labels[i+1:]...)
+			ts.DeltaTemporality = true
+			break
+		}
+	}
+}
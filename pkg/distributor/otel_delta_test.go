@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestConvertOTLPRequest_DeltaTemporality(t *testing.T) {
+	ts := pcommon.NewTimestampFromTime(time.Now())
+
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+	deltaSum := metrics.AppendEmpty()
+	deltaSum.SetName("delta_sum")
+	deltaSum.SetEmptySum().SetIsMonotonic(true)
+	deltaSum.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	dp := deltaSum.Sum().DataPoints().AppendEmpty()
+	dp.SetTimestamp(ts)
+	dp.SetStartTimestamp(ts - 1000)
+	dp.SetDoubleValue(2)
+
+	cumulativeSum := metrics.AppendEmpty()
+	cumulativeSum.SetName("cumulative_sum")
+	cumulativeSum.SetEmptySum().SetIsMonotonic(true)
+	cumulativeSum.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	dp = cumulativeSum.Sum().DataPoints().AppendEmpty()
+	dp.SetTimestamp(ts)
+	dp.SetDoubleValue(5)
+
+	deltaHistogram := metrics.AppendEmpty()
+	deltaHistogram.SetName("delta_histogram")
+	deltaHistogram.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	hdp := deltaHistogram.ExponentialHistogram().DataPoints().AppendEmpty()
+	hdp.SetTimestamp(ts)
+	hdp.SetCount(1)
+	hdp.SetSum(3)
+	hdp.Positive().BucketCounts().FromRaw([]uint64{1})
+
+	tests := map[string]struct {
+		enabled        bool
+		expectedSeries map[string]bool
+	}{
+		"delta to cumulative disabled": {
+			enabled:        false,
+			expectedSeries: map[string]bool{"cumulative_sum": false},
+		},
+		"delta to cumulative enabled": {
+			enabled:        true,
+			expectedSeries: map[string]bool{"delta_sum": true, "cumulative_sum": false, "delta_histogram": true},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			otlpReq := pmetricotlp.NewExportRequest()
+			md.CopyTo(otlpReq.Metrics())
+
+			ctx := user.InjectOrgID(context.Background(), "test")
+			spanLogger, ctx := spanlogger.NewWithLogger(ctx, log.NewNopLogger(), "test")
+			defer spanLogger.Finish()
+
+			var req mimirpb.PreallocWriteRequest
+			err := convertOTLPRequest(ctx, otlpReq, 0, otlpDeltaLimitsMock{enabled: tc.enabled}, nil, newOTLPMimirConverter(), false, newPushMetrics(nil), validation.DiscardedSamplesCounter(nil, otelParseError), &req, spanLogger)
+			require.NoError(t, err)
+
+			actualSeries := map[string]bool{}
+			for _, series := range req.Timeseries {
+				for _, l := range series.Labels {
+					assert.NotEqual(t, otlpDeltaMarkerLabel, l.Name)
+				}
+				actualSeries[mimirpb.FromLabelAdaptersToLabels(series.Labels).Get("__name__")] = series.DeltaTemporality
+			}
+			assert.Equal(t, tc.expectedSeries, actualSeries)
+
+			// Delta metrics are ingested as counters.
+			for _, m := range req.Metadata {
+				if _, ok := tc.expectedSeries[m.MetricFamilyName]; ok && m.MetricFamilyName != "delta_histogram" {
+					assert.Equal(t, mimirpb.COUNTER, m.Type)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkDeltaMetrics(t *testing.T) {
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+	gauge := metrics.AppendEmpty()
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+	require.False(t, markDeltaMetrics(md))
+
+	histogram := metrics.AppendEmpty()
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	dp := histogram.Histogram().DataPoints().AppendEmpty()
+	dp.SetStartTimestamp(1000)
+	require.True(t, markDeltaMetrics(md))
+
+	assert.Equal(t, pmetric.AggregationTemporalityCumulative, histogram.Histogram().AggregationTemporality())
+	assert.Equal(t, pcommon.Timestamp(0), dp.StartTimestamp())
+	marker, ok := dp.Attributes().Get(otlpDeltaMarkerLabel)
+	require.True(t, ok)
+	assert.Equal(t, "true", marker.Str())
+	_, ok = gauge.Gauge().DataPoints().At(0).Attributes().Get(otlpDeltaMarkerLabel)
+	assert.False(t, ok)
+}
+
+type otlpDeltaLimitsMock struct {
+	otlpLimitsMock
+	enabled bool
+}
+
+func (o otlpDeltaLimitsMock) OTelDeltaToCumulativeEnabled(string) bool {
+	return o.enabled
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/grpcutil"
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	utillog "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// OTLPGRPCServer is a pmetricotlp.GRPCServer accepting OTLP write requests over gRPC.
+// It converts and pushes requests the same way OTLPHandler does.
+type OTLPGRPCServer struct {
+	pmetricotlp.UnimplementedGRPCServer
+
+	maxRecvMsgSize                   int
+	limits                           OTLPHandlerLimits
+	resourceAttributePromotionConfig OTelResourceAttributePromotionConfig
+	retryCfg                         RetryConfig
+	enableStartTimeQuietZero         bool
+	push                             PushFunc
+	pushMetrics                      *PushMetrics
+	discardedDueToOtelParseError     *prometheus.CounterVec
+	logger                           log.Logger
+}
+
+// NewOTLPGRPCServer makes a new OTLPGRPCServer.
+func NewOTLPGRPCServer(
+	maxRecvMsgSize int,
+	limits OTLPHandlerLimits,
+	resourceAttributePromotionConfig OTelResourceAttributePromotionConfig,
+	retryCfg RetryConfig,
+	enableStartTimeQuietZero bool,
+	push PushFunc,
+	pushMetrics *PushMetrics,
+	reg prometheus.Registerer,
+	logger log.Logger,
+) *OTLPGRPCServer {
+	return &OTLPGRPCServer{
+		maxRecvMsgSize:                   maxRecvMsgSize,
+		limits:                           limits,
+		resourceAttributePromotionConfig: resourceAttributePromotionConfig,
+		retryCfg:                         retryCfg,
+		enableStartTimeQuietZero:         enableStartTimeQuietZero,
+		push:                             push,
+		pushMetrics:                      pushMetrics,
+		discardedDueToOtelParseError:     sharedDiscardedSamplesCounter(reg, otelParseError),
+		logger:                           logger,
+	}
+}
+
+// sharedDiscardedSamplesCounter returns the counter of samples discarded for reason registered to reg,
+// registering it if it isn't registered yet. The counter of samples discarded because of OTLP parse
+// errors is also registered by OTLPHandler.
+func sharedDiscardedSamplesCounter(reg prometheus.Registerer, reason string) *prometheus.CounterVec {
+	counter := validation.DiscardedSamplesCounter(nil, reason)
+	if reg == nil {
+		return counter
+	}
+
+	if err := reg.Register(counter); err != nil {
+		var alreadyRegistered prometheus.AlreadyRegisteredError
+		if errors.As(err, &alreadyRegistered) {
+			if existing, ok := alreadyRegistered.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return counter
+}
+
+// Export implements pmetricotlp.GRPCServer.
+func (s *OTLPGRPCServer) Export(ctx context.Context, otlpReq pmetricotlp.ExportRequest) (pmetricotlp.ExportResponse, error) {
+	logger := utillog.WithContext(ctx, s.logger)
+	otlpConverter := newOTLPMimirConverter()
+
+	supplier := func() (*mimirpb.WriteRequest, func(), error) {
+		var req mimirpb.PreallocWriteRequest
+		if err := s.convert(ctx, otlpReq, otlpConverter, &req, logger); err != nil {
+			return nil, nil, err
+		}
+
+		cleanup := func() {
+			mimirpb.ReuseSlice(req.Timeseries)
+		}
+		return &req.WriteRequest, cleanup, nil
+	}
+	req := newRequest(supplier)
+
+	pushErr := s.push(ctx, req)
+	if pushErr == nil {
+		if otlpErr := otlpConverter.Err(); otlpErr != nil {
+			// Push was successful, but OTLP converter left out some samples. We let the client know about it by replying with an error.
+			level.Error(logger).Log("msg", "detected an error while ingesting OTLP metrics request (the request may have been partially ingested)", "grpcCode", codes.InvalidArgument, "err", otlpErr, "insight", true)
+			return pmetricotlp.ExportResponse{}, grpcstatus.Error(codes.InvalidArgument, validUTF8Message(otlpErr.Error()))
+		}
+		return pmetricotlp.NewExportResponse(), nil
+	}
+
+	if errors.Is(pushErr, context.Canceled) {
+		level.Warn(logger).Log("msg", "push request canceled", "err", pushErr)
+		return pmetricotlp.ExportResponse{}, grpcstatus.Error(codes.Canceled, "push request context canceled")
+	}
+
+	grpcCode, httpCode, errorMsg := toOtlpGRPCStatus(pushErr)
+	if httpCode/100 == 2 {
+		// The OTLP/HTTP endpoint responds with a successful status code to this error, for example when samples
+		// are deduplicated by the HA tracker, so the request isn't retried.
+		return pmetricotlp.NewExportResponse(), nil
+	}
+
+	msgs := []interface{}{"msg", "detected an error while ingesting OTLP metrics request (the request may have been partially ingested)", "grpcCode", grpcCode, "err", pushErr}
+	if httpCode/100 == 4 {
+		msgs = append(msgs, "insight", true)
+	}
+	level.Error(logger).Log(msgs...)
+
+	st := grpcstatus.New(grpcCode, validUTF8Message(errorMsg))
+	if grpcCode == codes.ResourceExhausted && httpCode == http.StatusTooManyRequests {
+		// OTLP clients only retry requests failed with codes.ResourceExhausted if the status has RetryInfo details.
+		retryInfo := &errdetails.RetryInfo{}
+		if s.retryCfg.Enabled {
+			delaySeconds, _ := strconv.Atoi(calculateRetryAfter("", s.retryCfg.MinBackoff, s.retryCfg.MaxBackoff))
+			retryInfo.RetryDelay = durationpb.New(time.Duration(delaySeconds) * time.Second)
+		}
+		if withDetails, err := st.WithDetails(retryInfo); err == nil {
+			st = withDetails
+		}
+	}
+	return pmetricotlp.ExportResponse{}, st.Err()
+}
+
+func (s *OTLPGRPCServer) convert(ctx context.Context, otlpReq pmetricotlp.ExportRequest, otlpConverter *otlpMimirConverter, req *mimirpb.PreallocWriteRequest, logger log.Logger) error {
+	spanLogger, ctx := spanlogger.NewWithLogger(ctx, logger, "Distributor.OTLPGRPCServer.convert")
+	defer spanLogger.Span.Finish()
+
+	// The request was already decoded by the gRPC server, so its size is the size of the uncompressed message.
+	uncompressedBodySize := (&pmetric.ProtoMarshaler{}).MetricsSize(otlpReq.Metrics())
+	spanLogger.SetTag("uncompressed_body_size", uncompressedBodySize)
+	if uncompressedBodySize > s.maxRecvMsgSize {
+		return httpgrpc.Error(http.StatusRequestEntityTooLarge, distributorMaxOTLPRequestSizeErr{
+			actual: uncompressedBodySize,
+			limit:  s.maxRecvMsgSize,
+		}.Error())
+	}
+
+	err := convertOTLPRequest(ctx, otlpReq, uncompressedBodySize, s.limits, s.resourceAttributePromotionConfig, otlpConverter, s.enableStartTimeQuietZero, s.pushMetrics, s.discardedDueToOtelParseError, req, spanLogger)
+	if err != nil {
+		// Check for httpgrpc error, default to client error if conversion failed.
+		if _, ok := httpgrpc.HTTPResponseFromError(err); !ok {
+			err = httpgrpc.Error(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// toOtlpGRPCStatus is utilized by the OTLP gRPC receiver. It returns the gRPC status code and the error message
+// to respond with, and the HTTP status code the OTLP HTTP endpoint would respond with to the same error.
+// The gRPC status code is chosen so that OTLP clients retry the same errors over gRPC as over HTTP.
+func toOtlpGRPCStatus(pushErr error) (codes.Code, int, string) {
+	var (
+		grpcCode codes.Code
+		httpCode int
+		errorMsg string
+	)
+
+	if st, ok := grpcutil.ErrorToStatus(pushErr); ok {
+		// This code is needed for a correct handling of errors returned by the supplier function.
+		// These errors are created by using the httpgrpc package, and their code is an HTTP status code.
+		httpCode = httpRetryableToOTLPRetryable(int(st.Code()))
+		grpcCode = httpToOtlpGRPCCode(httpCode)
+		errorMsg = st.Message()
+	} else {
+		grpcCode, httpCode = toOtlpGRPCHTTPStatus(pushErr)
+		errorMsg = pushErr.Error()
+	}
+
+	// OTLP clients don't retry requests failed with codes.Internal, while they retry 5xx HTTP status codes
+	// returned by httpRetryableToOTLPRetryable.
+	if httpCode/100 == 5 {
+		grpcCode = codes.Unavailable
+	}
+	return grpcCode, httpCode, errorMsg
+}
+
+// httpToOtlpGRPCCode maps an HTTP status code returned by the OTLP HTTP endpoint to a gRPC status code.
+func httpToOtlpGRPCCode(httpCode int) codes.Code {
+	switch {
+	case httpCode/100 == 2:
+		return codes.OK
+	case httpCode == http.StatusTooManyRequests, httpCode == http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	case httpCode == statusClientClosedRequest:
+		return codes.Canceled
+	case httpCode/100 == 4:
+		return codes.InvalidArgument
+	default:
+		return codes.Unavailable
+	}
+}
@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/user"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestOTLPGRPCServer_Export(t *testing.T) {
+	series := []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "foo"}, {Name: "job", Value: "test"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: time.Now().UnixMilli()}},
+	}}
+	metadata := []mimirpb.MetricMetadata{{Type: mimirpb.GAUGE, MetricFamilyName: "foo", Help: "Foo."}}
+
+	var (
+		pushedTenant string
+		pushedReq    *mimirpb.WriteRequest
+	)
+	push := func(ctx context.Context, pushReq *Request) error {
+		var err error
+		pushedTenant, err = user.ExtractOrgID(ctx)
+		require.NoError(t, err)
+
+		req, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+		pushedReq = req
+		pushReq.CleanUp()
+		return nil
+	}
+
+	client := newOTLPGRPCTestClient(t, NewOTLPGRPCServer(100000, otlpLimitsMock{}, nil, RetryConfig{}, false, push, newPushMetrics(nil), prometheus.NewPedanticRegistry(), log.NewNopLogger()))
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	ctx, err := user.InjectIntoGRPCRequest(ctx)
+	require.NoError(t, err)
+
+	_, err = client.Export(ctx, TimeseriesToOTLPRequest(series, metadata))
+	require.NoError(t, err)
+
+	require.Equal(t, "test", pushedTenant)
+	require.NotNil(t, pushedReq)
+	require.Len(t, pushedReq.Timeseries, 1)
+	require.Len(t, pushedReq.Metadata, 1)
+	assert.Equal(t, "foo", pushedReq.Metadata[0].MetricFamilyName)
+}
+
+func TestOTLPGRPCServer_ExportErrors(t *testing.T) {
+	series := []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "foo"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: time.Now().UnixMilli()}},
+	}}
+
+	tests := map[string]struct {
+		maxRecvMsgSize    int
+		retryCfg          RetryConfig
+		pushErr           error
+		expectedCode      codes.Code
+		expectedRetryInfo bool
+	}{
+		"request exceeding the maximum OTLP request size": {
+			maxRecvMsgSize: 10,
+			expectedCode:   codes.ResourceExhausted,
+		},
+		"validation error": {
+			pushErr:      newValidationError(errors.New("invalid")),
+			expectedCode: codes.InvalidArgument,
+		},
+		"ingestion rate limited error": {
+			pushErr:           newIngestionRateLimitedError(10, 10),
+			retryCfg:          RetryConfig{Enabled: true, MinBackoff: 5 * time.Second, MaxBackoff: 5 * time.Second},
+			expectedCode:      codes.ResourceExhausted,
+			expectedRetryInfo: true,
+		},
+		"replicas did not match error": {
+			pushErr:      newReplicasDidNotMatchError("a", "b"),
+			expectedCode: codes.OK,
+		},
+		"generic error": {
+			pushErr:      errors.New("something went wrong"),
+			expectedCode: codes.Unavailable,
+		},
+		"httpgrpc error": {
+			pushErr:      httpgrpc.Error(http.StatusBadRequest, "bad request"),
+			expectedCode: codes.InvalidArgument,
+		},
+		"context canceled": {
+			pushErr:      context.Canceled,
+			expectedCode: codes.Canceled,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			push := func(_ context.Context, pushReq *Request) error {
+				defer pushReq.CleanUp()
+				if _, err := pushReq.WriteRequest(); err != nil {
+					return err
+				}
+				return tc.pushErr
+			}
+
+			maxRecvMsgSize := tc.maxRecvMsgSize
+			if maxRecvMsgSize == 0 {
+				maxRecvMsgSize = 100000
+			}
+			srv := NewOTLPGRPCServer(maxRecvMsgSize, otlpLimitsMock{}, nil, tc.retryCfg, false, push, newPushMetrics(nil), nil, log.NewNopLogger())
+
+			_, err := srv.Export(user.InjectOrgID(context.Background(), "test"), TimeseriesToOTLPRequest(series, nil))
+			if tc.expectedCode == codes.OK {
+				require.NoError(t, err)
+				return
+			}
+
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			assert.Equal(t, tc.expectedCode, st.Code())
+
+			var retryInfo *errdetails.RetryInfo
+			for _, detail := range st.Details() {
+				if ri, ok := detail.(*errdetails.RetryInfo); ok {
+					retryInfo = ri
+				}
+			}
+			if tc.expectedRetryInfo {
+				require.NotNil(t, retryInfo)
+				assert.Equal(t, 5*time.Second, retryInfo.GetRetryDelay().AsDuration())
+			} else {
+				assert.Nil(t, retryInfo)
+			}
+		})
+	}
+}
+
+func TestOTLPGRPCServer_SharesDiscardedSamplesCounterWithHTTPHandler(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	_ = OTLPHandler(100000, nil, nil, otlpLimitsMock{}, nil, RetryConfig{}, false, nil, nil, reg, log.NewNopLogger())
+
+	require.NotPanics(t, func() {
+		NewOTLPGRPCServer(100000, otlpLimitsMock{}, nil, RetryConfig{}, false, nil, nil, reg, log.NewNopLogger())
+	})
+}
+
+func newOTLPGRPCTestClient(t *testing.T, srv pmetricotlp.GRPCServer) pmetricotlp.GRPCClient {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(middleware.ServerUserHeaderInterceptor))
+	pmetricotlp.RegisterGRPCServer(server, srv)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough://bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pmetricotlp.NewGRPCClient(conn)
+}
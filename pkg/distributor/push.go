@@ -25,7 +25,9 @@ import (
 	"github.com/grafana/dskit/user"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	promRW2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
 
+	"github.com/grafana/mimir/pkg/distributor/rw2"
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/globalerror"
@@ -48,6 +50,11 @@ const (
 	SkipLabelNameValidationHeader  = "X-Mimir-SkipLabelNameValidation"
 	SkipLabelCountValidationHeader = "X-Mimir-SkipLabelCountValidation"
 
+	// Headers of the Remote Write 2.0 responses with the number of samples, histograms and exemplars written.
+	rw2WrittenSamplesHeader    = "X-Prometheus-Remote-Write-Samples-Written"
+	rw2WrittenHistogramsHeader = "X-Prometheus-Remote-Write-Histograms-Written"
+	rw2WrittenExemplarsHeader  = "X-Prometheus-Remote-Write-Exemplars-Written"
+
 	statusClientClosedRequest = 499
 )
 
@@ -141,6 +148,8 @@ func handler(
 	logger log.Logger,
 	parser parserFunc,
 ) http.Handler {
+	createdTimestamps := rw2.NewCreatedTimestamps()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		logger := utillog.WithContext(ctx, logger)
@@ -151,16 +160,36 @@ func handler(
 			}
 		}
 
-		var supplier supplierFunc
+		var (
+			supplier supplierFunc
+			// received are the numbers of samples, histograms and exemplars of a remote-write 2.0 request.
+			received writtenStats
+		)
 		isRW2, err := isRemoteWrite2(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 		if isRW2 {
 			supplier = func() (*mimirpb.WriteRequest, func(), error) {
-				// Return 415 Unsupported Media Type for remote-write v2 requests for now. This is not retryable
-				// unless the client switches to remote-write v1.
-				return nil, nil, httpgrpc.Error(http.StatusUnsupportedMediaType, "remote-write v2 is not supported")
+				rb := util.NewRequestBuffers(requestBufferPool)
+				var req mimirpb.PreallocWriteRequest
+
+				if err := parseRemoteWrite2Request(ctx, r, maxRecvMsgSize, rb, limits, createdTimestamps, &req, &received); err != nil {
+					if _, ok := httpgrpc.HTTPResponseFromError(err); !ok {
+						err = httpgrpc.Error(http.StatusBadRequest, err.Error())
+					}
+
+					mimirpb.ReuseSlice(req.Timeseries)
+					rb.CleanUp()
+					return nil, nil, err
+				}
+
+				cleanup := func() {
+					mimirpb.ReuseSlice(req.Timeseries)
+					rb.CleanUp()
+				}
+				return &req.WriteRequest, cleanup, nil
 			}
 		} else {
 			supplier = func() (*mimirpb.WriteRequest, func(), error) {
@@ -235,12 +264,61 @@ func handler(
 				}
 				level.Error(logger).Log(msgs...)
 			}
+			if isRW2 {
+				addRemoteWrite2WrittenHeaders(w, req.written, received)
+			}
 			addHeaders(w, err, r, code, retryCfg)
 			http.Error(w, validUTF8Message(msg), code)
+			return
+		}
+		if isRW2 {
+			addRemoteWrite2WrittenHeaders(w, req.written, received)
 		}
 	})
 }
 
+// parseRemoteWrite2Request parses the snappy compressed Remote Write 2.0 request and converts it to req.
+// The numbers of samples, histograms and exemplars of the request are set in received.
+func parseRemoteWrite2Request(ctx context.Context, r *http.Request, maxRecvMsgSize int, buffers *util.RequestBuffers, limits *validation.Overrides, createdTimestamps *rw2.CreatedTimestamps, req *mimirpb.PreallocWriteRequest, received *writtenStats) error {
+	var rw2Req promRW2.Request
+	_, err := util.ParseProtoReader(ctx, r.Body, int(r.ContentLength), maxRecvMsgSize, buffers, &rw2Req, util.RawSnappy)
+	if errors.Is(err, util.MsgSizeTooLargeErr{}) {
+		err = distributorMaxWriteMessageSizeErr{actual: int(r.ContentLength), limit: maxRecvMsgSize}
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, ts := range rw2Req.Timeseries {
+		received.samples += len(ts.Samples)
+		received.histograms += len(ts.Histograms)
+		received.exemplars += len(ts.Exemplars)
+	}
+
+	userID, err := tenant.TenantID(ctx)
+	if err != nil && !errors.Is(err, user.ErrNoOrgID) { // ignore user.ErrNoOrgID
+		return errors.Wrap(err, "failed to get tenant ID")
+	}
+	if !limits.RemoteWrite2CreatedTimestampZeroIngestionEnabled(userID) {
+		createdTimestamps = nil
+	}
+	return rw2.ToWriteRequest(&rw2Req, userID, createdTimestamps, &req.WriteRequest)
+}
+
+// addRemoteWrite2WrittenHeaders adds the headers of the Remote Write 2.0 specification with the number of samples,
+// histograms and exemplars that were written, which clients rely on to know what was ingested, even on errors.
+//
+// Only what was actually written is reported: requests accepted without writing anything, like the ones
+// deduplicated by the HA tracker or whose series were all dropped, report nothing written, and requests with
+// series rejected by the distributor validation report only their valid part. Requests failing to be written to
+// the backends report nothing written, even if part of them was, because which part isn't known.
+// The zero samples added for created timestamps aren't reported.
+func addRemoteWrite2WrittenHeaders(w http.ResponseWriter, written, received writtenStats) {
+	w.Header().Set(rw2WrittenSamplesHeader, strconv.Itoa(min(written.samples, received.samples)))
+	w.Header().Set(rw2WrittenHistogramsHeader, strconv.Itoa(min(written.histograms, received.histograms)))
+	w.Header().Set(rw2WrittenExemplarsHeader, strconv.Itoa(min(written.exemplars, received.exemplars)))
+}
+
 func isRemoteWrite2(r *http.Request) (bool, error) {
 	const appProtoContentType = "application/x-protobuf"
 
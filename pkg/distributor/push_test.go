@@ -34,7 +34,9 @@ import (
 	"github.com/grafana/dskit/user"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/prompb"
+	promRW2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pmetric"
@@ -43,6 +45,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/grafana/mimir/pkg/distributor/rw2"
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/test"
@@ -124,6 +127,107 @@ func TestOTelMetricsToMetadata(t *testing.T) {
 	}
 }
 
+func TestHandler_remoteWrite2(t *testing.T) {
+	rw2Req := rw2.AddFloatSeries(nil, labels.FromStrings("__name__", "foo"),
+		[]promRW2.Sample{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2}},
+		promRW2.Metadata_METRIC_TYPE_COUNTER, "Some help.", "", 0, nil,
+	)
+	rw2Req = rw2.AddHistogramSeries(rw2Req, labels.FromStrings("__name__", "bar"),
+		[]promRW2.Histogram{promRW2.FromIntHistogram(1000, test.GenerateTestHistogram(1))}, "", "", 0, nil,
+	)
+	data, err := rw2Req.Marshal()
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		data            []byte
+		written         writtenStats
+		pushErr         error
+		expectedCode    int
+		expectedWritten []string
+	}{
+		"success": {
+			data:            data,
+			written:         writtenStats{samples: 2, histograms: 1},
+			expectedCode:    http.StatusOK,
+			expectedWritten: []string{"2", "1", "0"},
+		},
+		"partially written": {
+			data:            data,
+			written:         writtenStats{samples: 2},
+			pushErr:         newValidationError(errors.New("some series are invalid")),
+			expectedCode:    http.StatusBadRequest,
+			expectedWritten: []string{"2", "0", "0"},
+		},
+		"created timestamp zero samples aren't reported": {
+			data:            data,
+			written:         writtenStats{samples: 3, histograms: 2},
+			expectedCode:    http.StatusOK,
+			expectedWritten: []string{"2", "1", "0"},
+		},
+		"deduplicated by the HA tracker": {
+			data:            data,
+			pushErr:         newReplicasDidNotMatchError("replica-2", "replica-1"),
+			expectedCode:    http.StatusAccepted,
+			expectedWritten: []string{"0", "0", "0"},
+		},
+		"all series dropped": {
+			data:            data,
+			expectedCode:    http.StatusOK,
+			expectedWritten: []string{"0", "0", "0"},
+		},
+		"failed to write to the backends": {
+			data:            data,
+			pushErr:         errors.New("failed pushing to ingester"),
+			expectedCode:    http.StatusInternalServerError,
+			expectedWritten: []string{"0", "0", "0"},
+		},
+		"malformed request": {
+			data:            []byte("malformed"),
+			expectedCode:    http.StatusBadRequest,
+			expectedWritten: []string{"0", "0", "0"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := createRequest(t, tc.data)
+			req.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+			req.Header.Set("X-Prometheus-Remote-Write-Version", "2.0.0")
+
+			push := func(_ context.Context, pushReq *Request) error {
+				defer pushReq.CleanUp()
+				request, err := pushReq.WriteRequest()
+				if err != nil {
+					return err
+				}
+
+				require.Len(t, request.Timeseries, 2)
+				assert.Equal(t, []mimirpb.LabelAdapter{{Name: "__name__", Value: "foo"}}, request.Timeseries[0].Labels)
+				assert.Equal(t, []mimirpb.Sample{{TimestampMs: 1000, Value: 1}, {TimestampMs: 2000, Value: 2}}, request.Timeseries[0].Samples)
+				assert.Len(t, request.Timeseries[1].Histograms, 1)
+				assert.Equal(t, []*mimirpb.MetricMetadata{
+					{Type: mimirpb.COUNTER, MetricFamilyName: "foo", Help: "Some help."},
+					{Type: mimirpb.HISTOGRAM, MetricFamilyName: "bar"},
+				}, request.Metadata)
+
+				// Emulate the distributor, which sets the written stats once the request has been written.
+				pushReq.written = tc.written
+				return tc.pushErr
+			}
+
+			resp := httptest.NewRecorder()
+			handler := Handler(100000, nil, nil, false, false, validation.MockDefaultOverrides(), RetryConfig{}, push, nil, log.NewNopLogger())
+			handler.ServeHTTP(resp, req)
+			assert.Equal(t, tc.expectedCode, resp.Code)
+			assert.Equal(t, tc.expectedWritten, []string{
+				resp.Header().Get("X-Prometheus-Remote-Write-Samples-Written"),
+				resp.Header().Get("X-Prometheus-Remote-Write-Histograms-Written"),
+				resp.Header().Get("X-Prometheus-Remote-Write-Exemplars-Written"),
+			})
+		})
+	}
+}
+
 func TestHandler_mimirWriteRequest(t *testing.T) {
 	req := createRequest(t, createMimirWriteRequestProtobuf(t, false, false))
 	resp := httptest.NewRecorder()
@@ -1461,6 +1565,10 @@ func (o otlpLimitsMock) OTelKeepIdentifyingResourceAttributes(string) bool {
 	return false
 }
 
+func (o otlpLimitsMock) OTelDeltaToCumulativeEnabled(string) bool {
+	return false
+}
+
 func promToMimirHistogram(h *prompb.Histogram) mimirpb.Histogram {
 	pSpans := make([]mimirpb.BucketSpan, 0, len(h.PositiveSpans))
 	for _, span := range h.PositiveSpans {
@@ -22,6 +22,25 @@ type Request struct {
 
 	request *mimirpb.WriteRequest
 	err     error
+
+	// written is set once the request has been successfully written to the backends. It's left empty
+	// when writing to the backends fails, even if part of the request was written.
+	written writtenStats
+}
+
+// writtenStats are the number of samples, histograms and exemplars of a request that were written.
+type writtenStats struct {
+	samples, histograms, exemplars int
+}
+
+func newWrittenStats(req *mimirpb.WriteRequest) writtenStats {
+	var stats writtenStats
+	for _, ts := range req.Timeseries {
+		stats.samples += len(ts.Samples)
+		stats.histograms += len(ts.Histograms)
+		stats.exemplars += len(ts.Exemplars)
+	}
+	return stats
 }
 
 func newRequest(p supplierFunc) *Request {
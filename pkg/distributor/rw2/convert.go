@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package rw2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	promRW2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// createdTimestampZeroIngestionInterval is the maximum distance between the created timestamp of a series and its
+// first sample for a zero sample to be added at the created timestamp. Created timestamps are sent with every
+// sample, so zero samples are only added for series that started recently, to avoid adding samples far in the
+// past of series that already have newer ones. It's the same interval as for OTel start timestamps.
+const createdTimestampZeroIngestionInterval = int64(300_000)
+
+// ToWriteRequest converts the Remote Write 2.0 request to dst. Labels and metadata are resolved from the
+// symbols of the request. The metadata of each metric family is only added once.
+//
+// When createdTimestamps isn't nil, a zero sample is added at the created timestamp of each series whose first
+// sample is within 5 minutes of it, so that counters and histograms start from zero. The zero sample is only added
+// to the first request of the series with that created timestamp, which is tracked in createdTimestamps.
+func ToWriteRequest(req *promRW2.Request, userID string, createdTimestamps *CreatedTimestamps, dst *mimirpb.WriteRequest) error {
+	if len(req.Timeseries) > 0 {
+		dst.Timeseries = mimirpb.PreallocTimeseriesSliceFromPool()
+	}
+	seenMetadata := map[string]struct{}{}
+
+	for i := range req.Timeseries {
+		rw2Series := &req.Timeseries[i]
+
+		ts := mimirpb.TimeseriesFromPool()
+		dst.Timeseries = append(dst.Timeseries, mimirpb.PreallocTimeseries{TimeSeries: ts})
+
+		var err error
+		ts.Labels, err = desymbolizeLabels(ts.Labels, rw2Series.LabelsRefs, req.Symbols)
+		if err != nil {
+			return fmt.Errorf("series %d: %w", i, err)
+		}
+
+		ct := rw2Series.CreatedTimestamp
+		for j, s := range rw2Series.Samples {
+			if j == 0 && needsCreatedTimestampZero(ct, s.Timestamp) && createdTimestamps.addSeries(userID, ts.Labels, ct) {
+				ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: ct})
+			}
+			ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: s.Timestamp, Value: s.Value})
+		}
+
+		for j, h := range rw2Series.Histograms {
+			if histogram.IsCustomBucketsSchema(h.Schema) {
+				return fmt.Errorf("series %s: native histograms with custom buckets are not supported", mimirpb.FromLabelAdaptersToString(ts.Labels))
+			}
+			if j == 0 && needsCreatedTimestampZero(ct, h.Timestamp) && createdTimestamps.addSeries(userID, ts.Labels, ct) {
+				// The zero histogram has the same type as the histogram, so that the series doesn't switch between
+				// integer and float histograms.
+				if h.IsFloatHistogram() {
+					ts.Histograms = append(ts.Histograms, mimirpb.FromFloatHistogramToHistogramProto(ct, &histogram.FloatHistogram{Schema: h.Schema, ZeroThreshold: h.ZeroThreshold}))
+				} else {
+					ts.Histograms = append(ts.Histograms, mimirpb.FromHistogramToHistogramProto(ct, &histogram.Histogram{Schema: h.Schema, ZeroThreshold: h.ZeroThreshold}))
+				}
+			}
+			if h.IsFloatHistogram() {
+				ts.Histograms = append(ts.Histograms, mimirpb.FromFloatHistogramToHistogramProto(h.Timestamp, h.ToFloatHistogram()))
+			} else {
+				ts.Histograms = append(ts.Histograms, mimirpb.FromHistogramToHistogramProto(h.Timestamp, h.ToIntHistogram()))
+			}
+		}
+
+		for _, e := range rw2Series.Exemplars {
+			exemplarLabels, err := desymbolizeLabels(nil, e.LabelsRefs, req.Symbols)
+			if err != nil {
+				return fmt.Errorf("exemplar of series %s: %w", mimirpb.FromLabelAdaptersToString(ts.Labels), err)
+			}
+			ts.Exemplars = append(ts.Exemplars, mimirpb.Exemplar{Labels: exemplarLabels, Value: e.Value, TimestampMs: e.Timestamp})
+		}
+
+		metadata, err := toMetadata(rw2Series.Metadata, ts.Labels, req.Symbols)
+		if err != nil {
+			return fmt.Errorf("metadata of series %s: %w", mimirpb.FromLabelAdaptersToString(ts.Labels), err)
+		}
+		if metadata == nil {
+			continue
+		}
+		if _, ok := seenMetadata[metadata.MetricFamilyName]; ok {
+			continue
+		}
+		seenMetadata[metadata.MetricFamilyName] = struct{}{}
+		dst.Metadata = append(dst.Metadata, metadata)
+	}
+
+	return nil
+}
+
+// needsCreatedTimestampZero returns whether a zero sample should be added at the created timestamp ct of a
+// series whose first sample is at ts.
+func needsCreatedTimestampZero(ct, ts int64) bool {
+	return ct > 0 && ct < ts && ts-ct <= createdTimestampZeroIngestionInterval
+}
+
+func desymbolizeLabels(dst []mimirpb.LabelAdapter, refs []uint32, symbols []string) ([]mimirpb.LabelAdapter, error) {
+	if len(refs)%2 != 0 {
+		return nil, fmt.Errorf("odd number of label references: %d", len(refs))
+	}
+	for i := 0; i < len(refs); i += 2 {
+		name, err := symbol(refs[i], symbols)
+		if err != nil {
+			return nil, err
+		}
+		value, err := symbol(refs[i+1], symbols)
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, mimirpb.LabelAdapter{Name: name, Value: value})
+	}
+	return dst, nil
+}
+
+func symbol(ref uint32, symbols []string) (string, error) {
+	if int(ref) >= len(symbols) {
+		return "", fmt.Errorf("symbol reference %d is out of range of the %d symbols", ref, len(symbols))
+	}
+	return symbols[ref], nil
+}
+
+// toMetadata returns the metadata of the metric family of the series, or nil if the series has no metadata.
+func toMetadata(m promRW2.Metadata, lbls []mimirpb.LabelAdapter, symbols []string) (*mimirpb.MetricMetadata, error) {
+	help, err := symbol(m.HelpRef, symbols)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := symbol(m.UnitRef, symbols)
+	if err != nil {
+		return nil, err
+	}
+	if m.Type == promRW2.Metadata_METRIC_TYPE_UNSPECIFIED && help == "" && unit == "" {
+		return nil, nil
+	}
+
+	var name string
+	for _, l := range lbls {
+		if l.Name == labels.MetricName {
+			name = l.Value
+			break
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	// The series of classic histograms and summaries have the name of their metric family with a suffix.
+	switch m.Type {
+	case promRW2.Metadata_METRIC_TYPE_HISTOGRAM, promRW2.Metadata_METRIC_TYPE_GAUGEHISTOGRAM:
+		name = trimSuffixes(name, "_bucket", "_sum", "_count")
+	case promRW2.Metadata_METRIC_TYPE_SUMMARY:
+		name = trimSuffixes(name, "_sum", "_count")
+	}
+
+	// The metric types of Remote Write 2.0 have the same values as the ones of Mimir metadata.
+	return &mimirpb.MetricMetadata{
+		Type:             mimirpb.MetricMetadata_MetricType(m.Type),
+		MetricFamilyName: name,
+		Help:             help,
+		Unit:             unit,
+	}, nil
+}
+
+func trimSuffixes(name string, suffixes ...string) string {
+	for _, suffix := range suffixes {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok {
+			return trimmed
+		}
+	}
+	return name
+}
@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package rw2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	promRW2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestToWriteRequest(t *testing.T) {
+	h := test.GenerateTestHistogram(1)
+	fh := test.GenerateTestFloatHistogram(2)
+
+	req := AddFloatSeries(nil, labels.FromStrings("__name__", "requests_total", "job", "app"),
+		[]promRW2.Sample{{Timestamp: 2000, Value: 1}, {Timestamp: 3000, Value: 2}},
+		promRW2.Metadata_METRIC_TYPE_COUNTER, "Total requests.", "", 0,
+		[]promRW2.Exemplar{{LabelsRefs: []uint32{}, Value: 1, Timestamp: 2000}},
+	)
+	req = AddFloatSeries(req, labels.FromStrings("__name__", "requests_total", "job", "other"),
+		[]promRW2.Sample{{Timestamp: 2000, Value: 5}},
+		promRW2.Metadata_METRIC_TYPE_COUNTER, "Total requests.", "", 0, nil,
+	)
+	req = AddFloatSeries(req, labels.FromStrings("__name__", "duration_seconds_bucket", "le", "+Inf"),
+		[]promRW2.Sample{{Timestamp: 2000, Value: 3}},
+		promRW2.Metadata_METRIC_TYPE_HISTOGRAM, "", "seconds", 0, nil,
+	)
+	req = AddFloatSeries(req, labels.FromStrings("__name__", "no_metadata"),
+		[]promRW2.Sample{{Timestamp: 2000, Value: 4}},
+		promRW2.Metadata_METRIC_TYPE_UNSPECIFIED, "", "", 0, nil,
+	)
+	req = AddHistogramSeries(req, labels.FromStrings("__name__", "native"),
+		[]promRW2.Histogram{promRW2.FromIntHistogram(2000, h), promRW2.FromFloatHistogram(3000, fh)},
+		"", "", 0, nil,
+	)
+	// The exemplar labels refer to the symbols added for the series.
+	req.Timeseries[0].Exemplars[0].LabelsRefs = []uint32{req.Timeseries[0].LabelsRefs[2], req.Timeseries[0].LabelsRefs[3]}
+
+	var dst mimirpb.WriteRequest
+	require.NoError(t, ToWriteRequest(req, "user", nil, &dst))
+	defer mimirpb.ReuseSlice(dst.Timeseries)
+
+	require.Len(t, dst.Timeseries, 5)
+	assert.Equal(t, []mimirpb.LabelAdapter{{Name: "__name__", Value: "requests_total"}, {Name: "job", Value: "app"}}, dst.Timeseries[0].Labels)
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 2000, Value: 1}, {TimestampMs: 3000, Value: 2}}, dst.Timeseries[0].Samples)
+	assert.Equal(t, []mimirpb.Exemplar{{Labels: []mimirpb.LabelAdapter{{Name: "job", Value: "app"}}, Value: 1, TimestampMs: 2000}}, dst.Timeseries[0].Exemplars)
+	assert.Equal(t, []mimirpb.Histogram{
+		mimirpb.FromHistogramToHistogramProto(2000, h),
+		mimirpb.FromFloatHistogramToHistogramProto(3000, fh),
+	}, dst.Timeseries[4].Histograms)
+
+	assert.Equal(t, []*mimirpb.MetricMetadata{
+		{Type: mimirpb.COUNTER, MetricFamilyName: "requests_total", Help: "Total requests."},
+		{Type: mimirpb.HISTOGRAM, MetricFamilyName: "duration_seconds", Unit: "seconds"},
+		{Type: mimirpb.HISTOGRAM, MetricFamilyName: "native"},
+	}, dst.Metadata)
+}
+
+func TestToWriteRequest_CreatedTimestamp(t *testing.T) {
+	h := test.GenerateTestHistogram(1)
+	fh := test.GenerateTestFloatHistogram(2)
+	intZero := mimirpb.FromHistogramToHistogramProto(1000, &histogram.Histogram{Schema: h.Schema, ZeroThreshold: h.ZeroThreshold})
+	floatZero := mimirpb.FromFloatHistogramToHistogramProto(1000, &histogram.FloatHistogram{Schema: fh.Schema, ZeroThreshold: fh.ZeroThreshold})
+
+	tests := map[string]struct {
+		createdTimestamp              int64
+		createdTimestampZeroIngestion bool
+		expectedSamples               []mimirpb.Sample
+		expectedHistograms            []mimirpb.Histogram
+		expectedFloatHistograms       []mimirpb.Histogram
+	}{
+		"zero ingestion disabled": {
+			createdTimestamp:        1000,
+			expectedSamples:         []mimirpb.Sample{{TimestampMs: 2000, Value: 1}},
+			expectedHistograms:      []mimirpb.Histogram{mimirpb.FromHistogramToHistogramProto(2000, h)},
+			expectedFloatHistograms: []mimirpb.Histogram{mimirpb.FromFloatHistogramToHistogramProto(2000, fh)},
+		},
+		"created timestamp within the interval": {
+			createdTimestamp:              1000,
+			createdTimestampZeroIngestion: true,
+			expectedSamples:               []mimirpb.Sample{{TimestampMs: 1000}, {TimestampMs: 2000, Value: 1}},
+			expectedHistograms:            []mimirpb.Histogram{intZero, mimirpb.FromHistogramToHistogramProto(2000, h)},
+			expectedFloatHistograms:       []mimirpb.Histogram{floatZero, mimirpb.FromFloatHistogramToHistogramProto(2000, fh)},
+		},
+		"created timestamp too old": {
+			createdTimestamp:              2000 - createdTimestampZeroIngestionInterval - 1,
+			createdTimestampZeroIngestion: true,
+			expectedSamples:               []mimirpb.Sample{{TimestampMs: 2000, Value: 1}},
+			expectedHistograms:            []mimirpb.Histogram{mimirpb.FromHistogramToHistogramProto(2000, h)},
+			expectedFloatHistograms:       []mimirpb.Histogram{mimirpb.FromFloatHistogramToHistogramProto(2000, fh)},
+		},
+		"created timestamp equal to the sample timestamp": {
+			createdTimestamp:              2000,
+			createdTimestampZeroIngestion: true,
+			expectedSamples:               []mimirpb.Sample{{TimestampMs: 2000, Value: 1}},
+			expectedHistograms:            []mimirpb.Histogram{mimirpb.FromHistogramToHistogramProto(2000, h)},
+			expectedFloatHistograms:       []mimirpb.Histogram{mimirpb.FromFloatHistogramToHistogramProto(2000, fh)},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var createdTimestamps *CreatedTimestamps
+			if tc.createdTimestampZeroIngestion {
+				createdTimestamps = NewCreatedTimestamps()
+			}
+
+			dst := toWriteRequestWithCreatedTimestamp(t, tc.createdTimestamp, 2000, createdTimestamps)
+			defer mimirpb.ReuseSlice(dst.Timeseries)
+
+			require.Len(t, dst.Timeseries, 3)
+			assert.Equal(t, tc.expectedSamples, dst.Timeseries[0].Samples)
+			assert.Equal(t, tc.expectedHistograms, dst.Timeseries[1].Histograms)
+			assert.Equal(t, tc.expectedFloatHistograms, dst.Timeseries[2].Histograms)
+		})
+	}
+}
+
+func TestToWriteRequest_CreatedTimestampZeroAddedOnce(t *testing.T) {
+	createdTimestamps := NewCreatedTimestamps()
+
+	dst := toWriteRequestWithCreatedTimestamp(t, 1000, 2000, createdTimestamps)
+	for _, ts := range dst.Timeseries {
+		assert.Equal(t, int64(1000), firstTimestamp(ts.TimeSeries))
+	}
+	mimirpb.ReuseSlice(dst.Timeseries)
+
+	// The following requests of the series have the same created timestamp, and don't get a zero sample again.
+	dst = toWriteRequestWithCreatedTimestamp(t, 1000, 3000, createdTimestamps)
+	for _, ts := range dst.Timeseries {
+		assert.Equal(t, int64(3000), firstTimestamp(ts.TimeSeries))
+	}
+	mimirpb.ReuseSlice(dst.Timeseries)
+
+	// A series restarting has a new created timestamp, and gets a zero sample again.
+	dst = toWriteRequestWithCreatedTimestamp(t, 3500, 4000, createdTimestamps)
+	for _, ts := range dst.Timeseries {
+		assert.Equal(t, int64(3500), firstTimestamp(ts.TimeSeries))
+	}
+	mimirpb.ReuseSlice(dst.Timeseries)
+
+	// Other tenants' series are tracked separately.
+	req := AddFloatSeries(nil, labels.FromStrings("__name__", "requests_total"),
+		[]promRW2.Sample{{Timestamp: 2000, Value: 1}},
+		promRW2.Metadata_METRIC_TYPE_COUNTER, "", "", 1000, nil,
+	)
+	var other mimirpb.WriteRequest
+	require.NoError(t, ToWriteRequest(req, "other", createdTimestamps, &other))
+	defer mimirpb.ReuseSlice(other.Timeseries)
+	assert.Equal(t, int64(1000), firstTimestamp(other.Timeseries[0].TimeSeries))
+}
+
+func TestCreatedTimestamps_Purge(t *testing.T) {
+	now := time.UnixMilli(10_000_000)
+	createdTimestamps := NewCreatedTimestamps()
+	createdTimestamps.now = func() time.Time { return now }
+
+	require.True(t, createdTimestamps.add("user", 1, 1000))
+	require.True(t, createdTimestamps.add("user", 2, 1000))
+
+	now = now.Add(time.Duration(createdTimestampZeroIngestionInterval) * time.Millisecond)
+	require.False(t, createdTimestamps.add("user", 2, 1000))
+
+	// Series that weren't seen for longer than the interval aren't tracked anymore.
+	now = now.Add(time.Millisecond)
+	require.False(t, createdTimestamps.add("user", 2, 1000))
+	require.Len(t, createdTimestamps.series, 1)
+	require.True(t, createdTimestamps.add("user", 1, 1000))
+}
+
+// toWriteRequestWithCreatedTimestamp converts a request with a float, an integer histogram and a float histogram
+// series with the created timestamp ct and a sample at ts.
+func toWriteRequestWithCreatedTimestamp(t *testing.T, ct, ts int64, createdTimestamps *CreatedTimestamps) mimirpb.WriteRequest {
+	req := AddFloatSeries(nil, labels.FromStrings("__name__", "requests_total"),
+		[]promRW2.Sample{{Timestamp: ts, Value: 1}},
+		promRW2.Metadata_METRIC_TYPE_COUNTER, "", "", ct, nil,
+	)
+	req = AddHistogramSeries(req, labels.FromStrings("__name__", "native"),
+		[]promRW2.Histogram{promRW2.FromIntHistogram(ts, test.GenerateTestHistogram(1))},
+		"", "", ct, nil,
+	)
+	req = AddHistogramSeries(req, labels.FromStrings("__name__", "native_float"),
+		[]promRW2.Histogram{promRW2.FromFloatHistogram(ts, test.GenerateTestFloatHistogram(2))},
+		"", "", ct, nil,
+	)
+
+	var dst mimirpb.WriteRequest
+	require.NoError(t, ToWriteRequest(req, "user", createdTimestamps, &dst))
+	return dst
+}
+
+func firstTimestamp(ts *mimirpb.TimeSeries) int64 {
+	if len(ts.Samples) > 0 {
+		return ts.Samples[0].TimestampMs
+	}
+	return ts.Histograms[0].Timestamp
+}
+
+func TestToWriteRequest_Errors(t *testing.T) {
+	t.Run("label reference out of range", func(t *testing.T) {
+		req := AddFloatSeries(nil, labels.FromStrings("__name__", "foo"), nil, promRW2.Metadata_METRIC_TYPE_GAUGE, "", "", 0, nil)
+		req.Timeseries[0].LabelsRefs[1] = 100
+
+		var dst mimirpb.WriteRequest
+		err := ToWriteRequest(req, "user", nil, &dst)
+		mimirpb.ReuseSlice(dst.Timeseries)
+		require.EqualError(t, err, "series 0: symbol reference 100 is out of range of the 3 symbols")
+	})
+
+	t.Run("odd number of label references", func(t *testing.T) {
+		req := AddFloatSeries(nil, labels.FromStrings("__name__", "foo"), nil, promRW2.Metadata_METRIC_TYPE_GAUGE, "", "", 0, nil)
+		req.Timeseries[0].LabelsRefs = req.Timeseries[0].LabelsRefs[:1]
+
+		var dst mimirpb.WriteRequest
+		err := ToWriteRequest(req, "user", nil, &dst)
+		mimirpb.ReuseSlice(dst.Timeseries)
+		require.EqualError(t, err, "series 0: odd number of label references: 1")
+	})
+
+	t.Run("native histogram with custom buckets", func(t *testing.T) {
+		h := &histogram.Histogram{Schema: histogram.CustomBucketsSchema, Count: 1, PositiveSpans: []histogram.Span{{Length: 1}}, PositiveBuckets: []int64{1}, CustomValues: []float64{1}}
+		req := AddHistogramSeries(nil, labels.FromStrings("__name__", "foo"), []promRW2.Histogram{promRW2.FromIntHistogram(1000, h)}, "", "", 0, nil)
+
+		var dst mimirpb.WriteRequest
+		err := ToWriteRequest(req, "user", nil, &dst)
+		mimirpb.ReuseSlice(dst.Timeseries)
+		require.EqualError(t, err, "series foo: native histograms with custom buckets are not supported")
+	})
+}
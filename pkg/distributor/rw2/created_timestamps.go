@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package rw2
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// CreatedTimestamps tracks the series zero samples were added for at their created timestamp. Created timestamps
+// are sent with every sample, so without it a zero sample would be added to every request of a series in the
+// interval after its created timestamp, and all but the first one would be out-of-order or duplicated.
+//
+// Series are tracked per distributor, so a series whose requests are load balanced across distributors gets a zero
+// sample from each of them, and series are only tracked while their samples are in the interval.
+type CreatedTimestamps struct {
+	mtx       sync.Mutex
+	series    map[createdTimestampKey]int64 // The last time a sample of the series was seen, in milliseconds.
+	lastPurge int64
+	now       func() time.Time
+}
+
+type createdTimestampKey struct {
+	userID           string
+	seriesHash       uint64
+	createdTimestamp int64
+}
+
+// NewCreatedTimestamps returns a new CreatedTimestamps.
+func NewCreatedTimestamps() *CreatedTimestamps {
+	return &CreatedTimestamps{
+		series: map[createdTimestampKey]int64{},
+		now:    time.Now,
+	}
+}
+
+// addSeries tracks the series with the labels lbls and the created timestamp ct, and returns whether it wasn't
+// tracked yet. It returns false if c is nil, when created timestamps zero ingestion is disabled.
+func (c *CreatedTimestamps) addSeries(userID string, lbls []mimirpb.LabelAdapter, ct int64) bool {
+	if c == nil {
+		return false
+	}
+	return c.add(userID, mimirpb.FromLabelAdaptersToLabels(lbls).Hash(), ct)
+}
+
+// add tracks the series with the created timestamp ct, and returns whether it wasn't tracked yet.
+func (c *CreatedTimestamps) add(userID string, seriesHash uint64, ct int64) bool {
+	now := c.now().UnixMilli()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if now-c.lastPurge > createdTimestampZeroIngestionInterval {
+		c.purge(now - createdTimestampZeroIngestionInterval)
+		c.lastPurge = now
+	}
+
+	key := createdTimestampKey{userID: userID, seriesHash: seriesHash, createdTimestamp: ct}
+	_, ok := c.series[key]
+	c.series[key] = now
+	return !ok
+}
+
+// purge stops tracking the series that weren't seen since before, which can't get a zero sample anymore.
+func (c *CreatedTimestamps) purge(before int64) {
+	for key, lastSeen := range c.series {
+		if lastSeen < before {
+			delete(c.series, key)
+		}
+	}
+}
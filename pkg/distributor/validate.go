@@ -66,6 +66,15 @@ var (
 	// reasonTooManyHAClusters is one of the reasons for discarding samples.
 	reasonTooManyHAClusters = "too_many_ha_clusters"
 
+	// Discarded samples reasons of the ingestion pipeline steps, each prefixed with the type of the step.
+	reasonIngestionPipelinePrefix      = "ingestion_pipeline_"
+	reasonIngestionPipelineRelabel     = reasonIngestionPipelinePrefix + validation.IngestionPipelineStepRelabel
+	reasonIngestionPipelineDropByValue = reasonIngestionPipelinePrefix + validation.IngestionPipelineStepDropByValue
+	reasonIngestionPipelineDropByAge   = reasonIngestionPipelinePrefix + validation.IngestionPipelineStepDropByAge
+
+	// reasonAggregationRules is the reason for discarding the samples of the input series of aggregation rules.
+	reasonAggregationRules = "aggregation_rules"
+
 	labelNameTooLongMsgFormat = globalerror.SeriesLabelNameTooLong.MessageWithPerTenantLimitConfig(
 		"received a series whose label name length exceeds the limit, label: '%.200s' series: '%.200s'",
 		validation.MaxLabelNameLengthFlag,
@@ -0,0 +1,455 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/atomicfs"
+)
+
+const (
+	writeBufferDroppedReasonFull     = "full"
+	writeBufferDroppedReasonTooOld   = "too_old"
+	writeBufferDroppedReasonRejected = "rejected"
+	writeBufferDroppedReasonCorrupt  = "corrupt"
+)
+
+var (
+	errInvalidWriteBufferReplayInterval = errors.New("the write buffer replay interval must be greater than 0")
+	errWriteBufferFull                  = errors.New("the write buffer of the tenant is full")
+)
+
+// WriteBufferConfig configures the buffering of write requests on the local disk of the distributors while the
+// ingesters are unavailable.
+type WriteBufferConfig struct {
+	Enabled        bool          `yaml:"enabled" category:"experimental"`
+	Dir            string        `yaml:"dir" category:"experimental"`
+	ReplayInterval time.Duration `yaml:"replay_interval" category:"experimental"`
+}
+
+func (cfg *WriteBufferConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "distributor.write-buffer.enabled", false, "Enable the buffering of write requests on the local disk while the ingesters are unavailable. Write requests failing because of unavailable ingesters are accepted and buffered, up to the tenant's write buffer limits, and replayed in order once the ingesters are available again. Not supported with the ingest storage.")
+	f.StringVar(&cfg.Dir, "distributor.write-buffer.dir", "./distributor-write-buffer/", "Directory to store the buffered write requests in. It should be on a persistent volume, so that the buffered write requests are replayed after a restart.")
+	f.DurationVar(&cfg.ReplayInterval, "distributor.write-buffer.replay-interval", 10*time.Second, "How often to replay the buffered write requests to the ingesters.")
+}
+
+func (cfg *WriteBufferConfig) Validate() error {
+	if cfg.Enabled && cfg.ReplayInterval <= 0 {
+		return errInvalidWriteBufferReplayInterval
+	}
+	return nil
+}
+
+// writeBufferLimits are the limits the write buffer is configured with.
+type writeBufferLimits interface {
+	WriteBufferMaxSizeBytes(userID string) int
+	WriteBufferMaxAge(userID string) time.Duration
+}
+
+// isWriteBufferableError returns whether err is returned when sending a write request to unavailable ingesters,
+// and the write request should be buffered to be replayed later.
+func isWriteBufferableError(err error) bool {
+	var distributorErr Error
+	if errors.As(err, &distributorErr) {
+		switch distributorErr.Cause() {
+		case mimirpb.UNKNOWN_CAUSE, mimirpb.INSTANCE_LIMIT, mimirpb.SERVICE_UNAVAILABLE, mimirpb.TSDB_UNAVAILABLE, mimirpb.TOO_BUSY, mimirpb.CIRCUIT_BREAKER_OPEN:
+			return true
+		}
+		return false
+	}
+	return !isIngestionClientError(err)
+}
+
+// writeBuffer buffers the write requests of each tenant on local disk while the ingesters are unavailable, and
+// replays them in order once the ingesters are available again. Each buffered write request is stored in its own
+// file, in the directory of its tenant, named after its sequence number and the time it was buffered at.
+//
+// Each distributor replays its buffered write requests independently, so they're usually older than the samples
+// ingested through the other distributors in the meantime. The limits of the tenants buffering write requests
+// require an out-of-order time window covering the maximum age of the buffered write requests, so that their
+// samples aren't rejected when replayed.
+type writeBuffer struct {
+	services.Service
+
+	cfg    WriteBufferConfig
+	limits writeBufferLimits
+	logger log.Logger
+
+	// replay sends a buffered write request to the ingesters.
+	replay func(ctx context.Context, userID string, req *mimirpb.WriteRequest) error
+
+	bufferedRequests *prometheus.CounterVec
+	replayedRequests *prometheus.CounterVec
+	droppedRequests  *prometheus.CounterVec
+	requests         *prometheus.GaugeVec
+	sizeBytes        *prometheus.GaugeVec
+
+	mtx     sync.Mutex
+	tenants map[string]*tenantWriteBuffer
+}
+
+type tenantWriteBuffer struct {
+	mtx sync.Mutex
+	// entries are the buffered write requests, from the oldest to the newest.
+	entries []writeBufferEntry
+	size    int64
+	nextSeq uint64
+	// drained is closed once the buffered write requests were all replayed or dropped. It's nil while the
+	// tenant has no buffered write requests.
+	drained chan struct{}
+}
+
+type writeBufferEntry struct {
+	seq  uint64
+	time time.Time
+	size int64
+}
+
+func (e writeBufferEntry) name() string {
+	return fmt.Sprintf("%020d-%d", e.seq, e.time.UnixMilli())
+}
+
+func parseWriteBufferEntryName(name string) (writeBufferEntry, bool) {
+	seq, millis, ok := strings.Cut(name, "-")
+	if !ok {
+		return writeBufferEntry{}, false
+	}
+	s, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return writeBufferEntry{}, false
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return writeBufferEntry{}, false
+	}
+	return writeBufferEntry{seq: s, time: time.UnixMilli(ms)}, true
+}
+
+func newWriteBuffer(cfg WriteBufferConfig, limits writeBufferLimits, reg prometheus.Registerer, logger log.Logger) *writeBuffer {
+	b := &writeBuffer{
+		cfg:    cfg,
+		limits: limits,
+		logger: logger,
+		bufferedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_write_buffer_buffered_requests_total",
+			Help: "The total number of write requests buffered while the ingesters were unavailable.",
+		}, []string{"user"}),
+		replayedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_write_buffer_replayed_requests_total",
+			Help: "The total number of buffered write requests successfully replayed to the ingesters.",
+		}, []string{"user"}),
+		droppedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_write_buffer_dropped_requests_total",
+			Help: "The total number of write requests which couldn't be buffered because the write buffer was full, or which were dropped from the write buffer without being replayed.",
+		}, []string{"user", "reason"}),
+		requests: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_distributor_write_buffer_requests",
+			Help: "The number of write requests in the write buffer.",
+		}, []string{"user"}),
+		sizeBytes: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_distributor_write_buffer_size_bytes",
+			Help: "The size in bytes of the write requests in the write buffer.",
+		}, []string{"user"}),
+		tenants: map[string]*tenantWriteBuffer{},
+	}
+	b.Service = services.NewTimerService(cfg.ReplayInterval, b.starting, b.iteration, nil)
+	return b
+}
+
+// starting loads the write requests buffered before the distributor restarted.
+func (b *writeBuffer) starting(_ context.Context) error {
+	if err := os.MkdirAll(b.cfg.Dir, 0o750); err != nil {
+		return errors.Wrap(err, "create write buffer directory")
+	}
+	userDirs, err := os.ReadDir(b.cfg.Dir)
+	if err != nil {
+		return errors.Wrap(err, "read write buffer directory")
+	}
+
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+		userID := userDir.Name()
+		files, err := os.ReadDir(filepath.Join(b.cfg.Dir, userID))
+		if err != nil {
+			return errors.Wrapf(err, "read write buffer directory of tenant %s", userID)
+		}
+
+		tb := &tenantWriteBuffer{}
+		for _, file := range files {
+			entry, ok := parseWriteBufferEntryName(file.Name())
+			if !ok {
+				// Temporary files of write requests which failed to be buffered.
+				level.Warn(b.logger).Log("msg", "removing unexpected file from the write buffer", "user", userID, "file", file.Name())
+				_ = os.RemoveAll(filepath.Join(b.cfg.Dir, userID, file.Name()))
+				continue
+			}
+			info, err := file.Info()
+			if err != nil {
+				return errors.Wrapf(err, "read buffered write request of tenant %s", userID)
+			}
+			entry.size = info.Size()
+			tb.entries = append(tb.entries, entry)
+			tb.size += entry.size
+		}
+		if len(tb.entries) == 0 {
+			continue
+		}
+
+		slices.SortFunc(tb.entries, func(a, b writeBufferEntry) int {
+			return cmp.Compare(a.seq, b.seq)
+		})
+		tb.nextSeq = tb.entries[len(tb.entries)-1].seq + 1
+		tb.drained = make(chan struct{})
+		b.tenants[userID] = tb
+		b.requests.WithLabelValues(userID).Set(float64(len(tb.entries)))
+		b.sizeBytes.WithLabelValues(userID).Set(float64(tb.size))
+		level.Info(b.logger).Log("msg", "loaded buffered write requests", "user", userID, "requests", len(tb.entries), "bytes", tb.size)
+	}
+	return nil
+}
+
+func (b *writeBuffer) iteration(ctx context.Context) error {
+	b.mtx.Lock()
+	userIDs := make([]string, 0, len(b.tenants))
+	for userID := range b.tenants {
+		userIDs = append(userIDs, userID)
+	}
+	b.mtx.Unlock()
+
+	for _, userID := range userIDs {
+		b.replayUser(ctx, userID, time.Now())
+	}
+	return nil
+}
+
+func (b *writeBuffer) tenant(userID string) *tenantWriteBuffer {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	tb, ok := b.tenants[userID]
+	if !ok {
+		tb = &tenantWriteBuffer{}
+		b.tenants[userID] = tb
+	}
+	return tb
+}
+
+// write sends req to the ingesters with send, or buffers it if send fails because the ingesters are unavailable.
+// While the tenant has buffered write requests still to be replayed, write requests are buffered too, or held until
+// the buffered ones are replayed if the write buffer of the tenant is full, so that they're never sent before them.
+// send is passed the cleanup function to call once the request isn't needed anymore, and cleanup is called if send
+// isn't. The request is only marshalled if it's buffered.
+func (b *writeBuffer) write(ctx context.Context, userID string, req *mimirpb.WriteRequest, send func(cleanup func()) error, cleanup func()) error {
+	maxSize := int64(b.limits.WriteBufferMaxSizeBytes(userID))
+	if maxSize <= 0 {
+		return send(cleanup)
+	}
+
+	tb := b.tenant(userID)
+	for {
+		tb.mtx.Lock()
+		drained := tb.drained
+		tb.mtx.Unlock()
+		if drained == nil {
+			break
+		}
+
+		err := b.append(userID, tb, req, maxSize)
+		if err == nil {
+			cleanup()
+			return nil
+		}
+		if !errors.Is(err, errWriteBufferFull) {
+			cleanup()
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			cleanup()
+			return ctx.Err()
+		case <-drained:
+		}
+	}
+
+	// The request may be cleaned up by send before it returns, so the cleanup is delayed until the request is
+	// buffered or not.
+	refs := atomic.NewInt32(2)
+	release := func() {
+		if refs.Dec() == 0 {
+			cleanup()
+		}
+	}
+	defer release()
+
+	sendErr := send(release)
+	if sendErr == nil || ctx.Err() != nil || !isWriteBufferableError(sendErr) {
+		return sendErr
+	}
+	if err := b.append(userID, tb, req, maxSize); err != nil {
+		if errors.Is(err, errWriteBufferFull) {
+			b.droppedRequests.WithLabelValues(userID, writeBufferDroppedReasonFull).Inc()
+		}
+		return sendErr
+	}
+	return nil
+}
+
+// append stores the write request in the buffer of the tenant.
+func (b *writeBuffer) append(userID string, tb *tenantWriteBuffer, req *mimirpb.WriteRequest, maxSize int64) error {
+	data, err := req.Marshal()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to marshal write request to buffer", "user", userID, "err", err)
+		return err
+	}
+	data = snappy.Encode(nil, data)
+
+	tb.mtx.Lock()
+	defer tb.mtx.Unlock()
+
+	if tb.size+int64(len(data)) > maxSize {
+		return errWriteBufferFull
+	}
+
+	entry := writeBufferEntry{seq: tb.nextSeq, time: time.Now(), size: int64(len(data))}
+	dir := filepath.Join(b.cfg.Dir, userID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to create the write buffer directory of the tenant", "user", userID, "err", err)
+		return err
+	}
+	if err := atomicfs.CreateFile(filepath.Join(dir, entry.name()), bytes.NewReader(data)); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to buffer write request", "user", userID, "err", err)
+		return err
+	}
+
+	if len(tb.entries) == 0 {
+		tb.drained = make(chan struct{})
+	}
+	tb.entries = append(tb.entries, entry)
+	tb.size += entry.size
+	tb.nextSeq++
+	b.bufferedRequests.WithLabelValues(userID).Inc()
+	b.requests.WithLabelValues(userID).Set(float64(len(tb.entries)))
+	b.sizeBytes.WithLabelValues(userID).Set(float64(tb.size))
+	return nil
+}
+
+// replayUser replays the buffered write requests of a tenant in order, until the ingesters fail to accept one.
+func (b *writeBuffer) replayUser(ctx context.Context, userID string, now time.Time) {
+	tb := b.tenant(userID)
+	maxAge := b.limits.WriteBufferMaxAge(userID)
+
+	for ctx.Err() == nil {
+		tb.mtx.Lock()
+		if len(tb.entries) == 0 {
+			tb.mtx.Unlock()
+			return
+		}
+		entry := tb.entries[0]
+		tb.mtx.Unlock()
+
+		if maxAge > 0 && now.Sub(entry.time) > maxAge {
+			b.remove(userID, tb, entry, writeBufferDroppedReasonTooOld)
+			continue
+		}
+
+		req, err := b.read(userID, entry)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "dropping buffered write request which can't be read", "user", userID, "file", entry.name(), "err", err)
+			b.remove(userID, tb, entry, writeBufferDroppedReasonCorrupt)
+			continue
+		}
+
+		if err := b.replay(ctx, userID, req); err != nil {
+			if isWriteBufferableError(err) {
+				// The ingesters are still unavailable: the write request is replayed at the next iteration.
+				level.Debug(b.logger).Log("msg", "failed to replay buffered write request", "user", userID, "err", err)
+				return
+			}
+			level.Warn(b.logger).Log("msg", "dropping buffered write request rejected by the ingesters", "user", userID, "err", err)
+			b.remove(userID, tb, entry, writeBufferDroppedReasonRejected)
+			continue
+		}
+		b.remove(userID, tb, entry, "")
+	}
+}
+
+func (b *writeBuffer) read(userID string, entry writeBufferEntry) (*mimirpb.WriteRequest, error) {
+	data, err := os.ReadFile(filepath.Join(b.cfg.Dir, userID, entry.name()))
+	if err != nil {
+		return nil, err
+	}
+	data, err = snappy.Decode(nil, data)
+	if err != nil {
+		return nil, err
+	}
+	req := &mimirpb.WriteRequest{}
+	if err := req.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// remove removes the oldest buffered write request of a tenant once replayed, or dropped for the given reason.
+func (b *writeBuffer) remove(userID string, tb *tenantWriteBuffer, entry writeBufferEntry, droppedReason string) {
+	if err := os.Remove(filepath.Join(b.cfg.Dir, userID, entry.name())); err != nil && !os.IsNotExist(err) {
+		level.Warn(b.logger).Log("msg", "failed to remove buffered write request", "user", userID, "file", entry.name(), "err", err)
+	}
+
+	if droppedReason == "" {
+		b.replayedRequests.WithLabelValues(userID).Inc()
+	} else {
+		b.droppedRequests.WithLabelValues(userID, droppedReason).Inc()
+	}
+
+	tb.mtx.Lock()
+	defer tb.mtx.Unlock()
+
+	tb.entries = tb.entries[1:]
+	tb.size -= entry.size
+	if len(tb.entries) == 0 {
+		close(tb.drained)
+		tb.drained = nil
+	}
+	b.requests.WithLabelValues(userID).Set(float64(len(tb.entries)))
+	b.sizeBytes.WithLabelValues(userID).Set(float64(tb.size))
+}
+
+func (b *writeBuffer) deleteUserMetrics(userID string) {
+	tb := b.tenant(userID)
+	tb.mtx.Lock()
+	defer tb.mtx.Unlock()
+
+	// The metrics are still updated while the buffered write requests of the tenant are replayed.
+	if len(tb.entries) > 0 {
+		return
+	}
+	b.bufferedRequests.DeleteLabelValues(userID)
+	b.replayedRequests.DeleteLabelValues(userID)
+	b.droppedRequests.DeletePartialMatch(prometheus.Labels{"user": userID})
+	b.requests.DeleteLabelValues(userID)
+	b.sizeBytes.DeleteLabelValues(userID)
+}
@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+type writeBufferTestLimits struct {
+	maxSize int
+	maxAge  time.Duration
+}
+
+func (l writeBufferTestLimits) WriteBufferMaxSizeBytes(string) int {
+	return l.maxSize
+}
+
+func (l writeBufferTestLimits) WriteBufferMaxAge(string) time.Duration {
+	return l.maxAge
+}
+
+func newWriteBufferForTest(t *testing.T, dir string, limits writeBufferTestLimits, reg prometheus.Registerer) (*writeBuffer, *[]int64, *error) {
+	var (
+		replayed  []int64
+		replayErr error
+	)
+	b := newWriteBuffer(WriteBufferConfig{Enabled: true, Dir: dir, ReplayInterval: time.Hour}, limits, reg, log.NewNopLogger())
+	b.replay = func(_ context.Context, _ string, req *mimirpb.WriteRequest) error {
+		if replayErr != nil {
+			return replayErr
+		}
+		replayed = append(replayed, req.Timeseries[0].Samples[0].TimestampMs)
+		return nil
+	}
+	require.NoError(t, b.starting(context.Background()))
+	return b, &replayed, &replayErr
+}
+
+func writeBufferTestRequest(ts int64) *mimirpb.WriteRequest {
+	return mimirpb.ToWriteRequest(
+		[][]mimirpb.LabelAdapter{{{Name: labels.MetricName, Value: "test"}}},
+		[]mimirpb.Sample{{Value: 1, TimestampMs: ts}},
+		nil,
+		nil,
+		mimirpb.API,
+	)
+}
+
+func TestWriteBuffer_BufferAndReplay(t *testing.T) {
+	var (
+		ctx            = context.Background()
+		dir            = t.TempDir()
+		limits         = writeBufferTestLimits{maxSize: 1 << 20}
+		unavailableErr = errors.New("at least 2 live replicas required, could only find 1")
+		clientErr      = httpgrpc.Errorf(http.StatusBadRequest, "bad data")
+	)
+
+	b, _, _ := newWriteBufferForTest(t, dir, limits, prometheus.NewPedanticRegistry())
+
+	write := func(ts int64, sendErr error) (sent, cleanedUp bool, err error) {
+		err = b.write(ctx, "user", writeBufferTestRequest(ts), func(cleanup func()) error {
+			sent = true
+			// The request is cleaned up by send, before being buffered.
+			cleanup()
+			return sendErr
+		}, func() {
+			require.False(t, cleanedUp, "the request must be cleaned up once")
+			cleanedUp = true
+		})
+		return
+	}
+
+	// Client errors are returned.
+	sent, cleanedUp, err := write(1, clientErr)
+	assert.True(t, sent)
+	assert.True(t, cleanedUp)
+	assert.Equal(t, clientErr, err)
+
+	// Write requests failing because of unavailable ingesters are buffered.
+	sent, cleanedUp, err = write(2, unavailableErr)
+	assert.True(t, sent)
+	assert.True(t, cleanedUp)
+	assert.NoError(t, err)
+
+	// Write requests are buffered while there are buffered write requests still to be replayed.
+	sent, cleanedUp, err = write(3, nil)
+	assert.False(t, sent)
+	assert.True(t, cleanedUp)
+	assert.NoError(t, err)
+
+	// The buffered write requests are loaded after a restart, and replayed in order once the ingesters are available.
+	reg := prometheus.NewPedanticRegistry()
+	b, replayed, replayErr := newWriteBufferForTest(t, dir, limits, reg)
+	*replayErr = unavailableErr
+	b.replayUser(ctx, "user", time.Now())
+	assert.Empty(t, *replayed)
+
+	*replayErr = nil
+	b.replayUser(ctx, "user", time.Now())
+	assert.Equal(t, []int64{2, 3}, *replayed)
+
+	// Write requests are sent once the buffered write requests were replayed.
+	sent, _, err = write(4, nil)
+	assert.True(t, sent)
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_write_buffer_replayed_requests_total The total number of buffered write requests successfully replayed to the ingesters.
+		# TYPE cortex_distributor_write_buffer_replayed_requests_total counter
+		cortex_distributor_write_buffer_replayed_requests_total{user="user"} 2
+		# HELP cortex_distributor_write_buffer_requests The number of write requests in the write buffer.
+		# TYPE cortex_distributor_write_buffer_requests gauge
+		cortex_distributor_write_buffer_requests{user="user"} 0
+		# HELP cortex_distributor_write_buffer_size_bytes The size in bytes of the write requests in the write buffer.
+		# TYPE cortex_distributor_write_buffer_size_bytes gauge
+		cortex_distributor_write_buffer_size_bytes{user="user"} 0
+	`),
+		"cortex_distributor_write_buffer_replayed_requests_total",
+		"cortex_distributor_write_buffer_requests",
+		"cortex_distributor_write_buffer_size_bytes",
+	))
+}
+
+func TestWriteBuffer_HoldWhileFull(t *testing.T) {
+	var (
+		ctx            = context.Background()
+		unavailableErr = errors.New("at least 2 live replicas required, could only find 1")
+	)
+
+	b, replayed, _ := newWriteBufferForTest(t, t.TempDir(), writeBufferTestLimits{maxSize: 1 << 20}, prometheus.NewPedanticRegistry())
+	require.NoError(t, b.write(ctx, "user", writeBufferTestRequest(1), func(func()) error { return unavailableErr }, func() {}))
+
+	// Once the write buffer of the tenant is full, write requests are held until the buffered ones are replayed.
+	b.limits = writeBufferTestLimits{maxSize: int(b.tenant("user").size)}
+	sent := make(chan int64, 1)
+	done := make(chan error)
+	go func() {
+		done <- b.write(ctx, "user", writeBufferTestRequest(2), func(func()) error {
+			sent <- 2
+			return nil
+		}, func() {})
+	}()
+
+	select {
+	case <-sent:
+		require.Fail(t, "the write request was sent before the buffered write requests were replayed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	b.replayUser(ctx, "user", time.Now())
+	require.NoError(t, <-done)
+	assert.Equal(t, []int64{1}, *replayed)
+	assert.Equal(t, int64(2), <-sent)
+
+	// Held write requests are released when their context is canceled.
+	require.NoError(t, b.write(ctx, "user", writeBufferTestRequest(3), func(func()) error { return unavailableErr }, func() {}))
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	cleanedUp := false
+	err := b.write(canceledCtx, "user", writeBufferTestRequest(4), func(func()) error {
+		require.Fail(t, "the write request must not be sent")
+		return nil
+	}, func() { cleanedUp = true })
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, cleanedUp)
+}
+
+func TestWriteBuffer_Drop(t *testing.T) {
+	var (
+		ctx            = context.Background()
+		unavailableErr = errors.New("at least 2 live replicas required, could only find 1")
+		reg            = prometheus.NewPedanticRegistry()
+	)
+
+	b, replayed, replayErr := newWriteBufferForTest(t, t.TempDir(), writeBufferTestLimits{maxSize: 1 << 20, maxAge: time.Hour}, reg)
+	buffer := func(userID string, ts int64) error {
+		return b.write(ctx, userID, writeBufferTestRequest(ts), func(func()) error { return unavailableErr }, func() {})
+	}
+
+	// Write requests are rejected when the write buffer is full.
+	b.limits = writeBufferTestLimits{maxSize: 1, maxAge: time.Hour}
+	assert.Equal(t, unavailableErr, buffer("user-1", 1))
+	b.limits = writeBufferTestLimits{maxSize: 1 << 20, maxAge: time.Hour}
+
+	// Write requests older than the max age are dropped.
+	require.NoError(t, buffer("user-2", 2))
+	b.replayUser(ctx, "user-2", time.Now().Add(2*time.Hour))
+
+	// Write requests rejected by the ingesters are dropped.
+	require.NoError(t, buffer("user-3", 3))
+	*replayErr = httpgrpc.Errorf(http.StatusBadRequest, "bad data")
+	b.replayUser(ctx, "user-3", time.Now())
+
+	assert.Empty(t, *replayed)
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_write_buffer_buffered_requests_total The total number of write requests buffered while the ingesters were unavailable.
+		# TYPE cortex_distributor_write_buffer_buffered_requests_total counter
+		cortex_distributor_write_buffer_buffered_requests_total{user="user-2"} 1
+		cortex_distributor_write_buffer_buffered_requests_total{user="user-3"} 1
+		# HELP cortex_distributor_write_buffer_dropped_requests_total The total number of write requests which couldn't be buffered because the write buffer was full, or which were dropped from the write buffer without being replayed.
+		# TYPE cortex_distributor_write_buffer_dropped_requests_total counter
+		cortex_distributor_write_buffer_dropped_requests_total{reason="full",user="user-1"} 1
+		cortex_distributor_write_buffer_dropped_requests_total{reason="rejected",user="user-3"} 1
+		cortex_distributor_write_buffer_dropped_requests_total{reason="too_old",user="user-2"} 1
+	`),
+		"cortex_distributor_write_buffer_buffered_requests_total",
+		"cortex_distributor_write_buffer_dropped_requests_total",
+	))
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package astmapper
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// NewQueryRewrite creates a new ASTMapper which replaces every expression that is
+// equal to pattern with replacement. Expressions are compared using their formatted
+// representation, so differences in formatting between pattern and the query are ignored.
+// The number of replaced expressions is added to stats.
+func NewQueryRewrite(ctx context.Context, pattern, replacement parser.Expr, stats *MapperStats) ASTMapper {
+	rewrite := &queryRewrite{
+		ctx:         ctx,
+		pattern:     pattern.String(),
+		replacement: replacement,
+		stats:       stats,
+	}
+	return NewASTExprMapper(rewrite)
+}
+
+type queryRewrite struct {
+	ctx         context.Context
+	pattern     string
+	replacement parser.Expr
+	stats       *MapperStats
+}
+
+func (r *queryRewrite) MapExpr(expr parser.Expr) (mapped parser.Expr, finished bool, err error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if expr.String() == r.pattern {
+		replacement, err := cloneExpr(r.replacement)
+		if err != nil {
+			return nil, false, err
+		}
+		r.stats.AddRewrittenExpressions(1)
+		return replacement, true, nil
+	}
+
+	// The selector of a range vector can only be replaced by another selector,
+	// so don't descend into it if the replacement is anything else.
+	if e, ok := expr.(*parser.MatrixSelector); ok {
+		if _, ok := r.replacement.(*parser.VectorSelector); !ok {
+			return e, true, nil
+		}
+	}
+
+	return expr, false, nil
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package astmapper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRewrite_Cancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	query, _ := parser.ParseExpr(`up{foo="bar"}`)
+	pattern, _ := parser.ParseExpr(`up`)
+	mapper := NewQueryRewrite(ctx, pattern, pattern, NewMapperStats())
+	_, err := mapper.Map(query)
+
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestQueryRewrite_Queries(t *testing.T) {
+	type testCase struct {
+		query         string
+		pattern       string
+		replacement   string
+		expectedQuery string
+	}
+
+	testCases := []testCase{
+		{
+			query:         `sum(rate(some_series{job="foo"}[1m]))`,
+			pattern:       `other_series`,
+			replacement:   `replaced_series`,
+			expectedQuery: `sum(rate(some_series{job="foo"}[1m]))`,
+		},
+		{
+			query:         `count({job="foo"})`,
+			pattern:       `{job="foo"}`,
+			replacement:   `{__name__=~"up|some_series",job="foo"}`,
+			expectedQuery: `count({__name__=~"up|some_series",job="foo"})`,
+		},
+		{
+			query:         `sum(rate(some_series{job="foo"}[1m]))`,
+			pattern:       `some_series{job="foo"}`,
+			replacement:   `some_series{job="foo",namespace="bar"}`,
+			expectedQuery: `sum(rate(some_series{job="foo",namespace="bar"}[1m]))`,
+		},
+		{
+			query:         `sum by (job) (rate(some_series[1m])) / sum by (job) (rate(other_series[1m]))`,
+			pattern:       `sum by (job) (rate(some_series[1m]))`,
+			replacement:   `job:some_series:rate1m`,
+			expectedQuery: `job:some_series:rate1m / sum by (job) (rate(other_series[1m]))`,
+		},
+		{
+			query:         `sum  (  rate(some_series[1m])  )`,
+			pattern:       `sum(rate(some_series[1m]))`,
+			replacement:   `some_series:rate1m`,
+			expectedQuery: `some_series:rate1m`,
+		},
+		{
+			query:         `some_series + sum(some_series)`,
+			pattern:       `some_series`,
+			replacement:   `other_series`,
+			expectedQuery: `other_series + sum(other_series)`,
+		},
+		{
+			query:         `rate(some_series[1m])`,
+			pattern:       `some_series`,
+			replacement:   `sum(other_series)`,
+			expectedQuery: `rate(some_series[1m])`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			query, err := parser.ParseExpr(tc.query)
+			require.NoError(t, err)
+			pattern, err := parser.ParseExpr(tc.pattern)
+			require.NoError(t, err)
+			replacement, err := parser.ParseExpr(tc.replacement)
+			require.NoError(t, err)
+
+			stats := NewMapperStats()
+			mapper := NewQueryRewrite(context.Background(), pattern, replacement, stats)
+			mapped, err := mapper.Map(query)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedQuery, mapped.String())
+			require.Equal(t, tc.expectedQuery != tc.query, stats.GetRewrittenExpressions() > 0)
+		})
+	}
+}
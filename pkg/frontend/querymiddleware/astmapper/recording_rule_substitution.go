@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package astmapper
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// NewRecordingRuleSubstitution creates a new ASTMapper which replaces expressions with the
+// selector of the recording rule that records them. substitutions maps the formatted
+// representation of the expressions to replace to their replacement.
+//
+// Expressions within subqueries are not replaced, as they're evaluated at the step of the
+// subquery, which may be shorter than the evaluation interval of the recording rule.
+// The number of replaced expressions is added to stats.
+func NewRecordingRuleSubstitution(ctx context.Context, substitutions map[string]parser.Expr, stats *MapperStats) ASTMapper {
+	substitution := &recordingRuleSubstitution{
+		ctx:           ctx,
+		substitutions: substitutions,
+		stats:         stats,
+	}
+	return NewASTExprMapper(substitution)
+}
+
+type recordingRuleSubstitution struct {
+	ctx           context.Context
+	substitutions map[string]parser.Expr
+	stats         *MapperStats
+}
+
+func (s *recordingRuleSubstitution) MapExpr(expr parser.Expr) (mapped parser.Expr, finished bool, err error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	switch e := expr.(type) {
+	case *parser.SubqueryExpr:
+		return e, true, nil
+	case *parser.AggregateExpr:
+		replacement, ok := s.substitutions[e.String()]
+		if !ok {
+			return e, false, nil
+		}
+
+		replacement, err := cloneExpr(replacement)
+		if err != nil {
+			return nil, false, err
+		}
+		s.stats.AddRewrittenExpressions(1)
+		return replacement, true, nil
+	default:
+		return expr, false, nil
+	}
+}
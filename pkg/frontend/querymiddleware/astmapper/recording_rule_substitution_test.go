@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package astmapper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingRuleSubstitution_Cancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	query, _ := parser.ParseExpr(`sum(up)`)
+	mapper := NewRecordingRuleSubstitution(ctx, map[string]parser.Expr{}, NewMapperStats())
+	_, err := mapper.Map(query)
+
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRecordingRuleSubstitution_Queries(t *testing.T) {
+	substitutions := map[string]string{
+		`sum by (job) (rate(some_series[5m]))`: `sum without () (job:some_series:rate5m)`,
+		`sum(rate(other_series[5m]))`:          `sum without (env) (other_series:rate5m{env="prod"})`,
+	}
+
+	testCases := map[string]string{
+		`sum(rate(some_series[5m]))`:                                         `sum(rate(some_series[5m]))`,
+		`sum by (job) (rate(some_series[5m]))`:                               `sum without () (job:some_series:rate5m)`,
+		`sum(rate(some_series[5m])) by (job)`:                                `sum without () (job:some_series:rate5m)`,
+		`sum by (job) (rate(some_series[5m])) / sum(rate(other_series[5m]))`: `sum without () (job:some_series:rate5m) / sum without (env) (other_series:rate5m{env="prod"})`,
+		`max_over_time(sum by (job) (rate(some_series[5m]))[1h:1m])`:         `max_over_time(sum by (job) (rate(some_series[5m]))[1h:1m])`,
+	}
+
+	replacements := map[string]parser.Expr{}
+	for pattern, replacement := range substitutions {
+		expr, err := parser.ParseExpr(replacement)
+		require.NoError(t, err)
+		replacements[pattern] = expr
+	}
+
+	for query, expectedQuery := range testCases {
+		t.Run(query, func(t *testing.T) {
+			expr, err := parser.ParseExpr(query)
+			require.NoError(t, err)
+
+			stats := NewMapperStats()
+			mapper := NewRecordingRuleSubstitution(context.Background(), replacements, stats)
+			mapped, err := mapper.Map(expr)
+			require.NoError(t, err)
+			require.Equal(t, expectedQuery, mapped.String())
+			require.Equal(t, expectedQuery != query, stats.GetRewrittenExpressions() > 0)
+		})
+	}
+}
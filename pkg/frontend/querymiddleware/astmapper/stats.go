@@ -3,7 +3,8 @@
 package astmapper
 
 type MapperStats struct {
-	shardedQueries int
+	shardedQueries       int
+	rewrittenExpressions int
 }
 
 func NewMapperStats() *MapperStats {
@@ -19,3 +20,13 @@ func (s *MapperStats) AddShardedQueries(num int) {
 func (s *MapperStats) GetShardedQueries() int {
 	return s.shardedQueries
 }
+
+// AddRewrittenExpressions add num rewritten expressions to the counter.
+func (s *MapperStats) AddRewrittenExpressions(num int) {
+	s.rewrittenExpressions += num
+}
+
+// GetRewrittenExpressions returns the number of rewritten expressions.
+func (s *MapperStats) GetRewrittenExpressions() int {
+	return s.rewrittenExpressions
+}
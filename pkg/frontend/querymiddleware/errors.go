@@ -25,6 +25,13 @@ func newMaxQueryExpressionSizeBytesError(actualSizeBytes, maxQuerySizeBytes int)
 	))
 }
 
+func newMaxEstimatedQueryCostError(estimatedCost, maxEstimatedCost int) error {
+	return apierror.New(apierror.TypeBadData, globalerror.MaxEstimatedQueryCost.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the estimated cost of the query exceeds the limit (estimated cost: %d, limit: %d)", estimatedCost, maxEstimatedCost),
+		validation.MaxEstimatedQueryCostFlag,
+	))
+}
+
 func newQueryBlockedError() error {
 	return apierror.New(apierror.TypeBadData, globalerror.QueryBlocked.Message("the request has been blocked by the cluster administrator"))
 }
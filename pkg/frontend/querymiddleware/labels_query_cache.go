@@ -32,6 +32,9 @@ const (
 func newLabelsQueryCacheRoundTripper(
 	cache cache.Cache,
 	generator CacheKeyGenerator,
+	generations LabelsQueryCacheGenerations,
+	codec Codec,
+	deletions SeriesDeletions,
 	limits Limits,
 	next http.RoundTripper,
 	logger log.Logger,
@@ -41,7 +44,15 @@ func newLabelsQueryCacheRoundTripper(
 		limits: limits,
 	}
 
-	return newGenericQueryCacheRoundTripper(cache, generator.LabelValues, ttl, next, logger, newResultsCacheMetrics(queryTypeLabels, reg))
+	cacheKey := generator.LabelValues
+	if generations != nil {
+		cacheKey = labelsQueryCacheKeyWithGenerations(cacheKey, generations)
+	}
+	if deletions != nil {
+		cacheKey = labelsQueryCacheKeyWithoutSeriesDeletions(cacheKey, codec, deletions, logger)
+	}
+
+	return newGenericQueryCacheRoundTripper(cache, cacheKey, ttl, next, logger, newResultsCacheMetrics(queryTypeLabels, reg))
 }
 
 type labelsQueryTTL struct {
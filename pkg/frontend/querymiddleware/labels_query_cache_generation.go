@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/ring"
+	ring_client "github.com/grafana/dskit/ring/client"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+	"go.uber.org/atomic"
+
+	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+// ingestersHeadGenerationsOp is the ring operation used to select the ingesters whose head generation is read.
+// It selects the same ingesters queried by queriers.
+var ingestersHeadGenerationsOp = ring.NewOp([]ring.InstanceState{ring.ACTIVE, ring.PENDING}, nil)
+
+// LabelsQueryCacheGenerations returns the generation of the data that the label names and values queries of a
+// tenant are evaluated against. The generation changes whenever the result of these queries may change, so
+// cached results can be invalidated as soon as the data changes.
+type LabelsQueryCacheGenerations interface {
+	Generation(ctx context.Context, userID string) (string, error)
+}
+
+// BucketIndexLoader loads the bucket index of a tenant.
+type BucketIndexLoader interface {
+	GetIndex(ctx context.Context, userID string) (*bucketindex.Index, error)
+}
+
+// HeadGenerationsReader reads the generation of the TSDB head of a tenant from ingesters.
+type HeadGenerationsReader interface {
+	// HeadGenerations returns the head generation of userID keyed by ingester ID.
+	HeadGenerations(ctx context.Context, userID string) (map[string]uint64, error)
+}
+
+// errGenerationNotLoaded is returned for tenants whose generation hasn't been loaded in background yet.
+var errGenerationNotLoaded = errors.New("the generation of the data of the tenant hasn't been loaded yet")
+
+// LabelsQueryCacheGenerationsLoader is the LabelsQueryCacheGenerations based on the time the bucket index of the
+// tenant was last updated, which changes when blocks are uploaded or deleted, and on the generation of the TSDB
+// head of the tenant in every ingester, which changes when series are created or deleted.
+//
+// Generations are loaded in background for the tenants that recently ran label names and values queries, so that
+// queries, including the ones hitting the cache, never read the bucket index or send requests to ingesters.
+// Tenants whose generation wasn't requested for longer than the idle timeout are offloaded.
+type LabelsQueryCacheGenerationsLoader struct {
+	services.Service
+
+	bucketIndexes BucketIndexLoader
+	ingesters     HeadGenerationsReader
+	idleTimeout   time.Duration
+	logger        log.Logger
+
+	generationsMx sync.RWMutex
+	generations   map[string]*cachedGeneration
+}
+
+type cachedGeneration struct {
+	// We cache either the generation or the error occurred while loading it.
+	generation string
+	err        error
+
+	// Unix timestamp (seconds) of when the generation has been requested the last time.
+	requestedAt atomic.Int64
+}
+
+// NewLabelsQueryCacheGenerationsLoader makes a new LabelsQueryCacheGenerationsLoader, which reloads the
+// generation of the data of tenants every refreshInterval.
+func NewLabelsQueryCacheGenerationsLoader(bucketIndexes BucketIndexLoader, ingesters HeadGenerationsReader, refreshInterval, idleTimeout time.Duration, logger log.Logger) *LabelsQueryCacheGenerationsLoader {
+	l := &LabelsQueryCacheGenerationsLoader{
+		bucketIndexes: bucketIndexes,
+		ingesters:     ingesters,
+		idleTimeout:   idleTimeout,
+		logger:        logger,
+		generations:   map[string]*cachedGeneration{},
+	}
+
+	l.Service = services.NewTimerService(refreshInterval, nil, l.refreshGenerations, nil)
+	return l
+}
+
+// Generation returns the last generation loaded for the tenant. The first time the generation of a tenant is
+// requested, it returns an error and the generation is loaded at the next refresh.
+func (l *LabelsQueryCacheGenerationsLoader) Generation(_ context.Context, userID string) (string, error) {
+	l.generationsMx.RLock()
+	if entry := l.generations[userID]; entry != nil {
+		generation, err := entry.generation, entry.err
+		l.generationsMx.RUnlock()
+
+		entry.requestedAt.Store(time.Now().Unix())
+		return generation, err
+	}
+	l.generationsMx.RUnlock()
+
+	l.generationsMx.Lock()
+	defer l.generationsMx.Unlock()
+
+	// Another request may have added the tenant in the meantime.
+	entry := l.generations[userID]
+	if entry == nil {
+		entry = &cachedGeneration{err: errGenerationNotLoaded}
+		l.generations[userID] = entry
+	}
+	entry.requestedAt.Store(time.Now().Unix())
+	return entry.generation, entry.err
+}
+
+// refreshGenerations offloads the generations that weren't requested for longer than the idle timeout,
+// and reloads all other ones.
+func (l *LabelsQueryCacheGenerationsLoader) refreshGenerations(ctx context.Context) error {
+	now := time.Now()
+
+	var toUpdate []string
+	l.generationsMx.Lock()
+	for userID, entry := range l.generations {
+		if now.Sub(time.Unix(entry.requestedAt.Load(), 0)) >= l.idleTimeout {
+			delete(l.generations, userID)
+			continue
+		}
+		toUpdate = append(toUpdate, userID)
+	}
+	l.generationsMx.Unlock()
+
+	for _, userID := range toUpdate {
+		generation, err := l.loadGeneration(ctx, userID)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				// The service is stopping.
+				return nil
+			}
+			level.Warn(l.logger).Log("msg", "unable to load the generation of the data of the tenant for the labels query cache", "user", userID, "err", err)
+		}
+
+		l.generationsMx.Lock()
+		if entry := l.generations[userID]; entry != nil {
+			entry.generation = generation
+			entry.err = err
+		}
+		l.generationsMx.Unlock()
+	}
+
+	// Never return error, otherwise the service terminates.
+	return nil
+}
+
+func (l *LabelsQueryCacheGenerationsLoader) loadGeneration(ctx context.Context, userID string) (string, error) {
+	var updatedAt int64
+	idx, err := l.bucketIndexes.GetIndex(ctx, userID)
+	switch {
+	case err == nil:
+		updatedAt = idx.UpdatedAt
+	case errors.Is(err, bucketindex.ErrIndexNotFound):
+		// The tenant has no blocks in the storage yet.
+	default:
+		return "", fmt.Errorf("failed to load bucket index: %w", err)
+	}
+
+	heads, err := l.ingesters.HeadGenerations(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read head generations from ingesters: %w", err)
+	}
+
+	ids := make([]string, 0, len(heads))
+	for id := range heads {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	// Hash the head generations to keep the cache key short regardless of the number of ingesters.
+	digest := xxhash.New()
+	for _, id := range ids {
+		_, _ = digest.WriteString(id)
+		_, _ = digest.WriteString(":")
+		_, _ = digest.WriteString(strconv.FormatUint(heads[id], 10))
+		_, _ = digest.WriteString(",")
+	}
+
+	return fmt.Sprintf("%d:%x", updatedAt, digest.Sum64()), nil
+}
+
+// labelsQueryCacheKeyWithGenerations returns a keyingFunc that adds the generation of the data of the tenants
+// to the cache key returned by next. If the generation can't be read, the request is not cached.
+func labelsQueryCacheKeyWithGenerations(next keyingFunc, generations LabelsQueryCacheGenerations) keyingFunc {
+	return func(r *http.Request) (*GenericQueryCacheKey, error) {
+		key, err := next(r)
+		if err != nil {
+			return nil, err
+		}
+
+		tenantIDs, err := tenant.TenantIDs(r.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		b := strings.Builder{}
+		b.WriteString(key.CacheKey)
+
+		for _, userID := range tenantIDs {
+			generation, err := generations.Generation(r.Context(), userID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the generation of the data of tenant %s: %w", userID, err)
+			}
+
+			b.WriteRune(stringParamSeparator)
+			b.WriteString(generation)
+		}
+
+		return &GenericQueryCacheKey{
+			CacheKey:       b.String(),
+			CacheKeyPrefix: key.CacheKeyPrefix,
+		}, nil
+	}
+}
+
+type ingestersShardSizeLimits interface {
+	IngestionTenantShardSize(userID string) int
+}
+
+// IngestersHeadGenerations reads the generation of the TSDB head of tenants from the ingesters in the tenant's shard.
+type IngestersHeadGenerations struct {
+	services.Service
+
+	ring           ring.ReadRing
+	pool           *ring_client.Pool
+	limits         ingestersShardSizeLimits
+	lookbackPeriod time.Duration
+}
+
+// NewIngestersHeadGenerations creates a new IngestersHeadGenerations. lookbackPeriod is the period during which
+// ingesters that were part of the shard of a tenant are still queried, and should match the one used by queriers.
+func NewIngestersHeadGenerations(ingestersRing ring.ReadRing, clientCfg ingester_client.Config, poolCfg ring_client.PoolConfig, limits ingestersShardSizeLimits, lookbackPeriod time.Duration, logger log.Logger) *IngestersHeadGenerations {
+	// Client metrics aren't registered, because they're already registered by distributors and queriers
+	// when running in monolithic mode.
+	clientMetrics := ingester_client.NewMetrics(nil)
+	factory := ring_client.PoolInstFunc(func(inst ring.InstanceDesc) (ring_client.PoolClient, error) {
+		return ingester_client.MakeIngesterClient(inst, clientCfg, clientMetrics, logger)
+	})
+	pool := ring_client.NewPool("ingester", poolCfg, ring_client.NewRingServiceDiscovery(ingestersRing), factory, nil, logger)
+
+	return &IngestersHeadGenerations{
+		Service:        pool,
+		ring:           ingestersRing,
+		pool:           pool,
+		limits:         limits,
+		lookbackPeriod: lookbackPeriod,
+	}
+}
+
+func (h *IngestersHeadGenerations) HeadGenerations(ctx context.Context, userID string) (map[string]uint64, error) {
+	r := h.ring.ShuffleShardWithLookback(userID, h.limits.IngestionTenantShardSize(userID), h.lookbackPeriod, time.Now())
+
+	replicationSet, err := r.GetReplicationSetForOperation(ingestersHeadGenerationsOp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Federated queries read the generation of each tenant separately.
+	ctx = user.InjectOrgID(ctx, userID)
+	req := &ingester_client.UserStatsRequest{}
+	generations := make([]uint64, len(replicationSet.Instances))
+
+	// The head generation of every ingester is required: one that didn't respond may hold series that were
+	// created after the cached result was stored.
+	err = concurrency.ForEachJob(ctx, len(replicationSet.Instances), 0, func(ctx context.Context, idx int) error {
+		poolClient, err := h.pool.GetClientForInstance(replicationSet.Instances[idx])
+		if err != nil {
+			return err
+		}
+
+		resp, err := poolClient.(ingester_client.IngesterClient).UserStats(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		generations[idx] = resp.HeadGeneration
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]uint64, len(replicationSet.Instances))
+	for idx, instance := range replicationSet.Instances {
+		result[instance.Id] = generations[idx]
+	}
+	return result, nil
+}
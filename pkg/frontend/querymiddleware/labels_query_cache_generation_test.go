@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestLabelsQueryCacheGenerationsLoader_Generation(t *testing.T) {
+	ctx := context.Background()
+
+	// generation requests the generation of user-1 and reloads it as the background job does.
+	generation := func(t *testing.T, l *LabelsQueryCacheGenerationsLoader) (string, error) {
+		_, _ = l.Generation(ctx, "user-1")
+		require.NoError(t, l.refreshGenerations(ctx))
+		return l.Generation(ctx, "user-1")
+	}
+
+	t.Run("should not be available until loaded in background", func(t *testing.T) {
+		indexes := &mockBucketIndexLoader{indexes: map[string]*bucketindex.Index{"user-1": {UpdatedAt: 100}}}
+		heads := &mockHeadGenerationsReader{generations: map[string]map[string]uint64{"user-1": {"ingester-1": 1}}}
+		l := NewLabelsQueryCacheGenerationsLoader(indexes, heads, time.Minute, time.Hour, log.NewNopLogger())
+
+		_, err := l.Generation(ctx, "user-1")
+		require.ErrorIs(t, err, errGenerationNotLoaded)
+		assert.Equal(t, 0, indexes.calls)
+		assert.Equal(t, 0, heads.calls)
+
+		require.NoError(t, l.refreshGenerations(ctx))
+		assert.Equal(t, 1, indexes.calls)
+		assert.Equal(t, 1, heads.calls)
+
+		// Requesting the generation doesn't read the bucket index nor the ingesters.
+		for i := 0; i < 3; i++ {
+			_, err = l.Generation(ctx, "user-1")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, indexes.calls)
+		assert.Equal(t, 1, heads.calls)
+	})
+
+	t.Run("should change when the bucket index is updated", func(t *testing.T) {
+		indexes := &mockBucketIndexLoader{indexes: map[string]*bucketindex.Index{"user-1": {UpdatedAt: 100}}}
+		heads := &mockHeadGenerationsReader{generations: map[string]map[string]uint64{"user-1": {"ingester-1": 1}}}
+		l := NewLabelsQueryCacheGenerationsLoader(indexes, heads, time.Minute, time.Hour, log.NewNopLogger())
+
+		first, err := generation(t, l)
+		require.NoError(t, err)
+
+		indexes.indexes["user-1"] = &bucketindex.Index{UpdatedAt: 200}
+		second, err := generation(t, l)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("should change when the head generation of any ingester changes", func(t *testing.T) {
+		indexes := &mockBucketIndexLoader{indexes: map[string]*bucketindex.Index{"user-1": {UpdatedAt: 100}}}
+		heads := &mockHeadGenerationsReader{generations: map[string]map[string]uint64{"user-1": {"ingester-1": 1, "ingester-2": 2}}}
+		l := NewLabelsQueryCacheGenerationsLoader(indexes, heads, time.Minute, time.Hour, log.NewNopLogger())
+
+		first, err := generation(t, l)
+		require.NoError(t, err)
+
+		// The generation is stable as long as the data doesn't change.
+		again, err := generation(t, l)
+		require.NoError(t, err)
+		assert.Equal(t, first, again)
+
+		heads.generations["user-1"]["ingester-2"] = 3
+		second, err := generation(t, l)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("should succeed if the tenant has no bucket index", func(t *testing.T) {
+		indexes := &mockBucketIndexLoader{}
+		heads := &mockHeadGenerationsReader{generations: map[string]map[string]uint64{"user-1": {"ingester-1": 1}}}
+		l := NewLabelsQueryCacheGenerationsLoader(indexes, heads, time.Minute, time.Hour, log.NewNopLogger())
+
+		_, err := generation(t, l)
+		require.NoError(t, err)
+	})
+
+	t.Run("should fail if the bucket index can't be loaded", func(t *testing.T) {
+		indexes := &mockBucketIndexLoader{err: errors.New("bucket unavailable")}
+		heads := &mockHeadGenerationsReader{}
+		l := NewLabelsQueryCacheGenerationsLoader(indexes, heads, time.Minute, time.Hour, log.NewNopLogger())
+
+		_, err := generation(t, l)
+		require.ErrorContains(t, err, "bucket unavailable")
+	})
+
+	t.Run("should fail if the head generations can't be read", func(t *testing.T) {
+		indexes := &mockBucketIndexLoader{}
+		heads := &mockHeadGenerationsReader{err: errors.New("ingester unavailable")}
+		l := NewLabelsQueryCacheGenerationsLoader(indexes, heads, time.Minute, time.Hour, log.NewNopLogger())
+
+		_, err := generation(t, l)
+		require.ErrorContains(t, err, "ingester unavailable")
+	})
+
+	t.Run("should offload tenants not requested for longer than the idle timeout", func(t *testing.T) {
+		indexes := &mockBucketIndexLoader{}
+		heads := &mockHeadGenerationsReader{generations: map[string]map[string]uint64{"user-1": {"ingester-1": 1}}}
+		l := NewLabelsQueryCacheGenerationsLoader(indexes, heads, time.Minute, 0, log.NewNopLogger())
+
+		_, err := generation(t, l)
+		require.ErrorIs(t, err, errGenerationNotLoaded)
+		assert.Equal(t, 0, heads.calls)
+	})
+}
+
+func TestLabelsQueryCache_RoundTripWithGenerations(t *testing.T) {
+	const userID = "user-1"
+
+	generations := &mockLabelsQueryCacheGenerations{generation: "1"}
+	cacheBackend := cache.NewInstrumentedMockCache()
+	limits := mockLimits{resultsCacheTTLForLabelsQuery: time.Hour}
+	reg := prometheus.NewPedanticRegistry()
+
+	downstreamCalls := 0
+	downstream := RoundTripFunc(func(*http.Request) (*http.Response, error) {
+		downstreamCalls++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"status":"success","data":["foo"]}`)),
+		}, nil
+	})
+
+	rt := newLabelsQueryCacheRoundTripper(cacheBackend, DefaultCacheKeyGenerator{codec: NewPrometheusCodec(reg, 0, formatJSON, nil)}, generations, nil, nil, limits, downstream, log.NewNopLogger(), reg)
+
+	roundTrip := func() {
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/labels?match[]=up", nil)
+		require.NoError(t, err)
+
+		res, err := rt.RoundTrip(req.WithContext(user.InjectOrgID(context.Background(), userID)))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	roundTrip()
+	require.Equal(t, 1, downstreamCalls)
+	require.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	// The cached response is returned while the generation doesn't change.
+	roundTrip()
+	require.Equal(t, 1, downstreamCalls)
+
+	// The cached response is invalidated when the generation changes.
+	generations.generation = "2"
+	roundTrip()
+	require.Equal(t, 2, downstreamCalls)
+	require.Equal(t, 2, cacheBackend.CountStoreCalls())
+
+	// The cache is bypassed when the generation can't be read.
+	generations.err = errors.New("ingester unavailable")
+	roundTrip()
+	require.Equal(t, 3, downstreamCalls)
+	require.Equal(t, 2, cacheBackend.CountStoreCalls())
+}
+
+type mockBucketIndexLoader struct {
+	indexes map[string]*bucketindex.Index
+	err     error
+	calls   int
+}
+
+func (m *mockBucketIndexLoader) GetIndex(_ context.Context, userID string) (*bucketindex.Index, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	if idx, ok := m.indexes[userID]; ok {
+		return idx, nil
+	}
+	return nil, bucketindex.ErrIndexNotFound
+}
+
+type mockHeadGenerationsReader struct {
+	generations map[string]map[string]uint64
+	err         error
+	calls       int
+}
+
+func (m *mockHeadGenerationsReader) HeadGenerations(_ context.Context, userID string) (map[string]uint64, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.generations[userID], nil
+}
+
+type mockLabelsQueryCacheGenerations struct {
+	generation string
+	err        error
+}
+
+func (m *mockLabelsQueryCacheGenerations) Generation(context.Context, string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	return m.generation, nil
+}
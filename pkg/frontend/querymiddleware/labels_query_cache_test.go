@@ -10,6 +10,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/labels"
 	v1 "github.com/prometheus/prometheus/web/api/v1"
@@ -18,7 +20,11 @@ import (
 )
 
 func TestLabelsQueryCache_RoundTrip(t *testing.T) {
-	testGenericQueryCacheRoundTrip(t, newLabelsQueryCacheRoundTripper, "label_names_and_values", map[string]testGenericQueryCacheRequestType{
+	newRoundTripper := func(cache cache.Cache, generator CacheKeyGenerator, limits Limits, next http.RoundTripper, logger log.Logger, reg prometheus.Registerer) http.RoundTripper {
+		return newLabelsQueryCacheRoundTripper(cache, generator, nil, nil, nil, limits, next, logger, reg)
+	}
+
+	testGenericQueryCacheRoundTrip(t, newRoundTripper, "label_names_and_values", map[string]testGenericQueryCacheRequestType{
 		"label names request": {
 			reqPath:        "/prometheus/api/v1/labels",
 			reqData:        url.Values{"start": []string{"2023-07-05T01:00:00Z"}, "end": []string{"2023-07-05T08:00:00Z"}, "match[]": []string{`{job="test_1"}`, `{job!="test_2"}`}},
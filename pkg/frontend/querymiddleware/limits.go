@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -46,6 +47,13 @@ type Limits interface {
 	// query may be. 0 means "unlimited".
 	MaxQueryExpressionSizeBytes(userID string) int
 
+	// MaxEstimatedQueryCost returns the maximum estimated cost of a query. 0 means "unlimited".
+	MaxEstimatedQueryCost(userID string) int
+
+	// LowPriorityEstimatedQueryCost returns the estimated cost above which queries are executed
+	// with low priority. 0 means queries are never executed with low priority.
+	LowPriorityEstimatedQueryCost(userID string) int
+
 	// MaxCacheFreshness returns the period after which results are cacheable,
 	// to prevent caching of very recent results.
 	MaxCacheFreshness(userID string) time.Duration
@@ -109,6 +117,12 @@ type Limits interface {
 	// BlockedRequests returns the blocked http requests.
 	BlockedRequests(userID string) []*validation.BlockedRequest
 
+	// QueryRewrites returns the rules to rewrite queries with.
+	QueryRewrites(userID string) []*validation.QueryRewrite
+
+	// RulerRecordingRulesEvaluationEnabled returns whether the recording rules of the tenant are evaluated.
+	RulerRecordingRulesEvaluationEnabled(userID string) bool
+
 	// AlignQueriesWithStep returns if queries should be adjusted to be step-aligned
 	AlignQueriesWithStep(userID string) bool
 
@@ -207,20 +221,28 @@ type limitedParallelismRoundTripper struct {
 	downstream MetricsQueryHandler
 	limits     Limits
 
-	codec      Codec
-	middleware MetricsQueryMiddleware
+	codec             Codec
+	middleware        MetricsQueryMiddleware
+	lowPriorityBudget *lowPriorityQueryBudget
 }
 
 // NewLimitedParallelismRoundTripper creates a new roundtripper that enforces MaxQueryParallelism to the `next` roundtripper across `middlewares`.
 func NewLimitedParallelismRoundTripper(next http.RoundTripper, codec Codec, limits Limits, middlewares ...MetricsQueryMiddleware) http.RoundTripper {
+	return newLimitedParallelismRoundTripper(next, codec, limits, newLowPriorityQueryBudget(), middlewares...)
+}
+
+// newLimitedParallelismRoundTripper is like NewLimitedParallelismRoundTripper, but low priority queries share
+// lowPriorityBudget, which may be shared with other roundtrippers.
+func newLimitedParallelismRoundTripper(next http.RoundTripper, codec Codec, limits Limits, lowPriorityBudget *lowPriorityQueryBudget, middlewares ...MetricsQueryMiddleware) http.RoundTripper {
 	return limitedParallelismRoundTripper{
 		downstream: roundTripperHandler{
 			next:  next,
 			codec: codec,
 		},
-		codec:      codec,
-		limits:     limits,
-		middleware: MergeMetricsQueryMiddlewares(middlewares...),
+		codec:             codec,
+		limits:            limits,
+		middleware:        MergeMetricsQueryMiddlewares(middlewares...),
+		lowPriorityBudget: lowPriorityBudget,
 	}
 }
 
@@ -250,14 +272,21 @@ func (rt limitedParallelismRoundTripper) RoundTrip(r *http.Request) (*http.Respo
 	// sub-requests run in parallel.
 	response, err := rt.middleware.Wrap(
 		HandlerFunc(func(ctx context.Context, r MetricsQueryRequest) (Response, error) {
-			if err := sem.Acquire(ctx, 1); err != nil {
-				// Without this change, using WithTimeoutCause has no effect when calling Do on
-				// limitedParallelismRoundTripper, since that would need to return the cause as error,
-				// which is the normal behaviour except that semaphore does not do that.
-				if errors.Is(err, ctx.Err()) {
-					err = context.Cause(ctx)
+			// Low priority queries additionally share a single query's parallelism with all other low priority
+			// queries of the same tenants, so they can't crowd out the tenants' other queries.
+			if isLowPriorityQuery(ctx) {
+				key := tenant.JoinTenantIDs(tenantIDs)
+				lowPrioritySem := rt.lowPriorityBudget.acquire(key, parallelism)
+				defer rt.lowPriorityBudget.release(key)
+
+				if err := acquireWork(ctx, lowPrioritySem); err != nil {
+					return nil, err
 				}
-				return nil, fmt.Errorf("could not acquire work: %w", err)
+				defer lowPrioritySem.Release(1)
+			}
+
+			if err := acquireWork(ctx, sem); err != nil {
+				return nil, err
 			}
 			defer sem.Release(1)
 
@@ -270,6 +299,63 @@ func (rt limitedParallelismRoundTripper) RoundTrip(r *http.Request) (*http.Respo
 	return rt.codec.EncodeMetricsQueryResponse(ctx, r, response)
 }
 
+func acquireWork(ctx context.Context, sem *semaphore.Weighted) error {
+	if err := sem.Acquire(ctx, 1); err != nil {
+		// Without this change, using WithTimeoutCause has no effect when calling Do on
+		// limitedParallelismRoundTripper, since that would need to return the cause as error,
+		// which is the normal behaviour except that semaphore does not do that.
+		if errors.Is(err, ctx.Err()) {
+			err = context.Cause(ctx)
+		}
+		return fmt.Errorf("could not acquire work: %w", err)
+	}
+
+	return nil
+}
+
+// lowPriorityQueryBudget holds the semaphores shared by the sub-requests of all low priority queries,
+// one per set of tenants.
+type lowPriorityQueryBudget struct {
+	mtx     sync.Mutex
+	budgets map[string]*lowPriorityTenantBudget
+}
+
+type lowPriorityTenantBudget struct {
+	sem  *semaphore.Weighted
+	refs int
+}
+
+func newLowPriorityQueryBudget() *lowPriorityQueryBudget {
+	return &lowPriorityQueryBudget{budgets: map[string]*lowPriorityTenantBudget{}}
+}
+
+// acquire returns the semaphore shared by low priority queries for key, creating it with parallelism if no other
+// sub-request of a low priority query for key is in flight. Each call to acquire must be followed by a call to release.
+func (b *lowPriorityQueryBudget) acquire(key string, parallelism int) *semaphore.Weighted {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	budget, ok := b.budgets[key]
+	if !ok {
+		budget = &lowPriorityTenantBudget{sem: semaphore.NewWeighted(int64(parallelism))}
+		b.budgets[key] = budget
+	}
+
+	budget.refs++
+	return budget.sem
+}
+
+func (b *lowPriorityQueryBudget) release(key string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	budget := b.budgets[key]
+	budget.refs--
+	if budget.refs == 0 {
+		delete(b.budgets, key)
+	}
+}
+
 // roundTripperHandler is an adapter that implements the MetricsQueryHandler interface using a http.RoundTripper to perform
 // the requests and a Codec to translate between http Request/Response model and this package's Request/Response model.
 // It basically encodes a MetricsQueryRequest from MetricsQueryHandler.Do and decodes response from next roundtripper.
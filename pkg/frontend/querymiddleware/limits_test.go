@@ -513,6 +513,14 @@ func (m multiTenantMockLimits) MaxQueryParallelism(userID string) int {
 	return m.byTenant[userID].maxQueryParallelism
 }
 
+func (m multiTenantMockLimits) MaxEstimatedQueryCost(userID string) int {
+	return m.byTenant[userID].maxEstimatedQueryCost
+}
+
+func (m multiTenantMockLimits) LowPriorityEstimatedQueryCost(userID string) int {
+	return m.byTenant[userID].lowPriorityEstimatedQueryCost
+}
+
 func (m multiTenantMockLimits) MaxCacheFreshness(userID string) time.Duration {
 	return m.byTenant[userID].maxCacheFreshness
 }
@@ -605,6 +613,14 @@ func (m multiTenantMockLimits) BlockedRequests(userID string) []*validation.Bloc
 	return m.byTenant[userID].blockedRequests
 }
 
+func (m multiTenantMockLimits) QueryRewrites(userID string) []*validation.QueryRewrite {
+	return m.byTenant[userID].queryRewrites
+}
+
+func (m multiTenantMockLimits) RulerRecordingRulesEvaluationEnabled(userID string) bool {
+	return m.byTenant[userID].rulerRecordingRulesEvaluationEnabled
+}
+
 func (m multiTenantMockLimits) InstantQueriesWithSubquerySpinOff(userID string) []string {
 	return m.byTenant[userID].instantQueriesWithSubquerySpinOff
 }
@@ -614,6 +630,8 @@ type mockLimits struct {
 	maxQueryLength                       time.Duration
 	maxTotalQueryLength                  time.Duration
 	maxQueryExpressionSizeBytes          int
+	maxEstimatedQueryCost                int
+	lowPriorityEstimatedQueryCost        int
 	maxCacheFreshness                    time.Duration
 	maxQueryParallelism                  int
 	maxShardedQueries                    int
@@ -635,6 +653,8 @@ type mockLimits struct {
 	prom2RangeCompat                     bool
 	blockedQueries                       []*validation.BlockedQuery
 	blockedRequests                      []*validation.BlockedRequest
+	queryRewrites                        []*validation.QueryRewrite
+	rulerRecordingRulesEvaluationEnabled bool
 	alignQueriesWithStep                 bool
 	queryIngestersWithin                 time.Duration
 	ingestStorageReadConsistency         string
@@ -663,6 +683,14 @@ func (m mockLimits) MaxQueryParallelism(string) int {
 	return m.maxQueryParallelism
 }
 
+func (m mockLimits) MaxEstimatedQueryCost(string) int {
+	return m.maxEstimatedQueryCost
+}
+
+func (m mockLimits) LowPriorityEstimatedQueryCost(string) int {
+	return m.lowPriorityEstimatedQueryCost
+}
+
 func (m mockLimits) MaxCacheFreshness(string) time.Duration {
 	return m.maxCacheFreshness
 }
@@ -755,6 +783,14 @@ func (m mockLimits) BlockedRequests(string) []*validation.BlockedRequest {
 	return m.blockedRequests
 }
 
+func (m mockLimits) QueryRewrites(string) []*validation.QueryRewrite {
+	return m.queryRewrites
+}
+
+func (m mockLimits) RulerRecordingRulesEvaluationEnabled(string) bool {
+	return m.rulerRecordingRulesEvaluationEnabled
+}
+
 func (m mockLimits) InstantQueriesWithSubquerySpinOff(string) []string {
 	return m.instantQueriesWithSubquerySpinOff
 }
@@ -819,6 +855,83 @@ func TestLimitedRoundTripper_MaxQueryParallelism(t *testing.T) {
 	require.LessOrEqual(t, maxFound, maxQueryParallelism, "max query parallelism: ", maxFound, " went over the configured one:", maxQueryParallelism)
 }
 
+func TestLimitedRoundTripper_LowPriorityQueriesShareParallelism(t *testing.T) {
+	const (
+		maxQueryParallelism = 2
+		concurrentQueries   = 3
+	)
+
+	for name, lowPriority := range map[string]bool{"low priority queries": true, "other queries": false} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				count      atomic.Int32
+				max        atomic.Int32
+				downstream = RoundTripFunc(func(_ *http.Request) (*http.Response, error) {
+					cur := count.Inc()
+					if cur > max.Load() {
+						max.Store(cur)
+					}
+					defer count.Dec()
+					// simulate some work
+					time.Sleep(20 * time.Millisecond)
+					return &http.Response{
+						Body: http.NoBody,
+					}, nil
+				})
+				ctx = user.InjectOrgID(context.Background(), "foo")
+			)
+
+			codec := newTestPrometheusCodec()
+			r, err := codec.EncodeMetricsQueryRequest(ctx, &PrometheusRangeQueryRequest{
+				path:      "/api/v1/query_range",
+				start:     time.Now().Add(time.Hour).Unix(),
+				end:       util.TimeToMillis(time.Now()),
+				step:      int64(1 * time.Second * time.Millisecond),
+				queryExpr: parseQuery(t, `foo`),
+			})
+			require.Nil(t, err)
+
+			tripper := NewLimitedParallelismRoundTripper(downstream, codec, mockLimits{maxQueryParallelism: maxQueryParallelism},
+				MetricsQueryMiddlewareFunc(func(next MetricsQueryHandler) MetricsQueryHandler {
+					return HandlerFunc(func(c context.Context, _ MetricsQueryRequest) (Response, error) {
+						if lowPriority {
+							c = context.WithValue(c, lowPriorityQueryCtxKey, true)
+						}
+
+						var wg sync.WaitGroup
+						for i := 0; i < maxQueryParallelism*5; i++ {
+							wg.Add(1)
+							go func() {
+								defer wg.Done()
+								_, _ = next.Do(c, &PrometheusRangeQueryRequest{})
+							}()
+						}
+						wg.Wait()
+						return newEmptyPrometheusResponse(), nil
+					})
+				}),
+			)
+
+			var wg sync.WaitGroup
+			for i := 0; i < concurrentQueries; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := tripper.RoundTrip(r)
+					assert.NoError(t, err)
+				}()
+			}
+			wg.Wait()
+
+			if lowPriority {
+				require.LessOrEqual(t, int(max.Load()), maxQueryParallelism)
+			} else {
+				require.Greater(t, int(max.Load()), maxQueryParallelism)
+			}
+		})
+	}
+}
+
 func TestLimitedRoundTripper_MaxQueryParallelismLateScheduling(t *testing.T) {
 	var (
 		maxQueryParallelism = 2
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/tenant"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// queryCostSampleInterval is the interval between samples assumed when estimating the number of samples a
+	// query reads, as the actual interval of the series a query selects isn't known before it runs.
+	queryCostSampleInterval = time.Minute
+)
+
+var lowPriorityQueryCtxKey = contextKey(1)
+
+// queryCostMiddleware estimates the cost of queries before they run, and rejects or deprioritises queries that
+// are estimated to be more expensive than the tenant's limits allow.
+//
+// The cost of a query is the number of series it is estimated to select, multiplied by the number of samples it
+// reads or points it evaluates for each series, whichever is greater. Series estimates are the number of series
+// fetched by previous executions of the same query over a similar time range that were not served from the results
+// cache, stored in the results cache in the same way as estimates for cardinality-based query sharding.
+type queryCostMiddleware struct {
+	next        MetricsQueryHandler
+	limits      Limits
+	logger      log.Logger
+	cardinality *cardinalityEstimation
+
+	expensiveQueries *prometheus.CounterVec
+}
+
+func newQueryCostMiddleware(cache cache.Cache, limits Limits, logger log.Logger, registerer prometheus.Registerer) MetricsQueryMiddleware {
+	expensiveQueries := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_query_frontend_expensive_queries_total",
+		Help: "Number of queries that were rejected or executed with low priority because of their estimated cost.",
+	}, []string{"user", "action"})
+
+	return MetricsQueryMiddlewareFunc(func(next MetricsQueryHandler) MetricsQueryHandler {
+		return &queryCostMiddleware{
+			next:        next,
+			limits:      limits,
+			logger:      logger,
+			cardinality: &cardinalityEstimation{cache: cache, logger: logger},
+
+			expensiveQueries: expensiveQueries,
+		}
+	})
+}
+
+func (q *queryCostMiddleware) Do(ctx context.Context, req MetricsQueryRequest) (Response, error) {
+	spanLog := spanlogger.FromContext(ctx, q.logger)
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return q.next.Do(ctx, req)
+	}
+
+	userID := tenant.JoinTenantIDs(tenantIDs)
+	key := generateCardinalityEstimationCacheKey(userID, req, cardinalityEstimateBucketSize)
+
+	estimatedSeries, estimateAvailable := q.cardinality.lookupCardinalityForKey(ctx, key)
+	if estimateAvailable {
+		cost := estimateQueryCost(req, estimatedSeries)
+		spanLog.LogFields(otlog.Int("estimated query cost", cost))
+
+		if details := QueryDetailsFromContext(ctx); details != nil {
+			details.EstimatedQueryCost = cost
+		}
+
+		if maxCost := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, q.limits.MaxEstimatedQueryCost); maxCost > 0 && cost > maxCost {
+			q.expensiveQueries.WithLabelValues(userID, "rejected").Inc()
+			return nil, newMaxEstimatedQueryCostError(cost, maxCost)
+		}
+
+		if lowPriorityCost := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, q.limits.LowPriorityEstimatedQueryCost); lowPriorityCost > 0 && cost > lowPriorityCost {
+			q.expensiveQueries.WithLabelValues(userID, "low_priority").Inc()
+			spanLog.LogFields(otlog.Bool("low priority", true))
+			ctx = context.WithValue(ctx, lowPriorityQueryCtxKey, true)
+		}
+	}
+
+	res, err := q.next.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queries served entirely or partly from the results cache fetch fewer series than they select, so they
+	// don't update the estimate.
+	if details := QueryDetailsFromContext(ctx); details != nil && details.ResultsCacheHitBytes > 0 {
+		return res, nil
+	}
+
+	// Streamed responses are returned before the querier has sent the stats of the query, so the number of
+	// fetched series isn't known yet and they don't update the estimate.
+	if _, streamed := res.(*streamedResponse); streamed {
+		return res, nil
+	}
+
+	// Queries that run with stats disabled don't report how many series they fetched.
+	if statistics := stats.FromContext(ctx); statistics != nil {
+		actualSeries := statistics.GetFetchedSeriesCount()
+		if !estimateAvailable || !isCardinalitySimilar(actualSeries, estimatedSeries) {
+			q.cardinality.storeCardinalityForKey(key, actualSeries)
+		}
+	}
+
+	return res, nil
+}
+
+// estimateQueryCost returns the estimated cost of req, given the estimated number of series it selects.
+func estimateQueryCost(req MetricsQueryRequest, estimatedSeries uint64) int {
+	// The number of samples in the time range the query reads, including any range selectors and offsets.
+	samplesPerSeries := int64(1)
+	if req.GetMaxT() > req.GetMinT() {
+		samplesPerSeries += (req.GetMaxT() - req.GetMinT()) / queryCostSampleInterval.Milliseconds()
+	}
+
+	// The number of points the query evaluates, which may be more than the number of samples read if the step is
+	// smaller than the sample interval.
+	pointsPerSeries := int64(1)
+	if req.GetStep() > 0 {
+		pointsPerSeries += (req.GetEnd() - req.GetStart()) / req.GetStep()
+	}
+
+	cost := float64(estimatedSeries) * float64(max(samplesPerSeries, pointsPerSeries))
+	if cost > math.MaxInt {
+		return math.MaxInt
+	}
+
+	return int(cost)
+}
+
+// isLowPriorityQuery returns true if the query with ctx should be executed with low priority.
+func isLowPriorityQuery(ctx context.Context) bool {
+	lowPriority, _ := ctx.Value(lowPriorityQueryCtxKey).(bool)
+	return lowPriority
+}
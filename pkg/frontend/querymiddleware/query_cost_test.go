@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/querier/stats"
+)
+
+func TestEstimateQueryCost(t *testing.T) {
+	start := parseTimeRFC3339(t, "2023-01-31T09:00:00Z").UnixMilli()
+	end := start + time.Hour.Milliseconds()
+
+	tests := map[string]struct {
+		req             MetricsQueryRequest
+		estimatedSeries uint64
+		expected        int
+	}{
+		"instant query": {
+			req: &PrometheusInstantQueryRequest{
+				time: end,
+				minT: end - 5*time.Minute.Milliseconds(),
+				maxT: end,
+			},
+			estimatedSeries: 10,
+			expected:        10 * 6,
+		},
+		"range query with step smaller than the sample interval": {
+			req: &PrometheusRangeQueryRequest{
+				start: start,
+				end:   end,
+				step:  15 * time.Second.Milliseconds(),
+				minT:  start,
+				maxT:  end,
+			},
+			estimatedSeries: 10,
+			expected:        10 * 241,
+		},
+		"range query with step larger than the sample interval": {
+			req: &PrometheusRangeQueryRequest{
+				start: start,
+				end:   end,
+				step:  5 * time.Minute.Milliseconds(),
+				minT:  start,
+				maxT:  end,
+			},
+			estimatedSeries: 10,
+			expected:        10 * 61,
+		},
+		"no series": {
+			req: &PrometheusRangeQueryRequest{
+				start: start,
+				end:   end,
+				step:  time.Minute.Milliseconds(),
+				minT:  start,
+				maxT:  end,
+			},
+			estimatedSeries: 0,
+			expected:        0,
+		},
+		"cost that overflows": {
+			req: &PrometheusRangeQueryRequest{
+				start: start,
+				end:   end,
+				step:  time.Minute.Milliseconds(),
+				minT:  start,
+				maxT:  end,
+			},
+			estimatedSeries: math.MaxUint64,
+			expected:        math.MaxInt,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testData.expected, estimateQueryCost(testData.req, testData.estimatedSeries))
+		})
+	}
+}
+
+func TestQueryCostMiddleware(t *testing.T) {
+	const numSeries = uint64(100)
+
+	start := parseTimeRFC3339(t, "2023-01-31T09:00:00Z").UnixMilli()
+	end := start + time.Hour.Milliseconds()
+	request := &PrometheusRangeQueryRequest{
+		start:     start,
+		end:       end,
+		step:      time.Minute.Milliseconds(),
+		minT:      start,
+		maxT:      end,
+		queryExpr: parseQuery(t, "up"),
+	}
+	expectedCost := int(numSeries) * 61
+
+	marshaledEstimate, err := proto.Marshal(&QueryStatistics{EstimatedSeriesCount: numSeries})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		limits               mockLimits
+		cachedEstimate       bool
+		expectedErr          bool
+		expectedLowPriority  bool
+		expectedEstimate     int
+		expectedExpensiveMsg string
+	}{
+		"no estimate available": {
+			limits: mockLimits{maxEstimatedQueryCost: 1, lowPriorityEstimatedQueryCost: 1},
+		},
+		"estimate available, no limits": {
+			cachedEstimate:   true,
+			expectedEstimate: expectedCost,
+		},
+		"estimate available, below limits": {
+			limits:           mockLimits{maxEstimatedQueryCost: expectedCost, lowPriorityEstimatedQueryCost: expectedCost},
+			cachedEstimate:   true,
+			expectedEstimate: expectedCost,
+		},
+		"estimate available, above low priority limit": {
+			limits:              mockLimits{lowPriorityEstimatedQueryCost: expectedCost - 1},
+			cachedEstimate:      true,
+			expectedLowPriority: true,
+			expectedEstimate:    expectedCost,
+			expectedExpensiveMsg: `
+				# HELP cortex_query_frontend_expensive_queries_total Number of queries that were rejected or executed with low priority because of their estimated cost.
+				# TYPE cortex_query_frontend_expensive_queries_total counter
+				cortex_query_frontend_expensive_queries_total{action="low_priority",user="test"} 1
+			`,
+		},
+		"estimate available, above max limit": {
+			limits:           mockLimits{maxEstimatedQueryCost: expectedCost - 1, lowPriorityEstimatedQueryCost: expectedCost - 1},
+			cachedEstimate:   true,
+			expectedErr:      true,
+			expectedEstimate: expectedCost,
+			expectedExpensiveMsg: `
+				# HELP cortex_query_frontend_expensive_queries_total Number of queries that were rejected or executed with low priority because of their estimated cost.
+				# TYPE cortex_query_frontend_expensive_queries_total counter
+				cortex_query_frontend_expensive_queries_total{action="rejected",user="test"} 1
+			`,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := cache.NewInstrumentedMockCache()
+			if testData.cachedEstimate {
+				c.SetMultiAsync(map[string][]byte{generateCardinalityEstimationCacheKey("test", request, cardinalityEstimateBucketSize): marshaledEstimate}, time.Minute)
+			}
+
+			reg := prometheus.NewPedanticRegistry()
+			downstreamCalled := false
+			downstream := HandlerFunc(func(ctx context.Context, _ MetricsQueryRequest) (Response, error) {
+				downstreamCalled = true
+				assert.Equal(t, testData.expectedLowPriority, isLowPriorityQuery(ctx))
+				stats.FromContext(ctx).AddFetchedSeries(numSeries)
+				return &PrometheusResponse{}, nil
+			})
+
+			_, ctx := stats.ContextWithEmptyStats(context.Background())
+			details, ctx := ContextWithEmptyDetails(ctx)
+			ctx = user.InjectOrgID(ctx, "test")
+
+			mw := newQueryCostMiddleware(c, testData.limits, log.NewNopLogger(), reg)
+			_, err := mw.Wrap(downstream).Do(ctx, request)
+
+			if testData.expectedErr {
+				require.Error(t, err)
+				assert.True(t, apierror.IsAPIError(err))
+				assert.False(t, downstreamCalled)
+			} else {
+				require.NoError(t, err)
+				assert.True(t, downstreamCalled)
+			}
+
+			assert.Equal(t, testData.expectedEstimate, details.EstimatedQueryCost)
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(testData.expectedExpensiveMsg), "cortex_query_frontend_expensive_queries_total"))
+		})
+	}
+}
+
+func TestQueryCostMiddleware_ResultsCacheHit(t *testing.T) {
+	start := parseTimeRFC3339(t, "2023-01-31T09:00:00Z").UnixMilli()
+	end := start + time.Hour.Milliseconds()
+	request := &PrometheusRangeQueryRequest{
+		start:     start,
+		end:       end,
+		step:      time.Minute.Milliseconds(),
+		minT:      start,
+		maxT:      end,
+		queryExpr: parseQuery(t, "up"),
+	}
+	key := generateCardinalityEstimationCacheKey("test", request, cardinalityEstimateBucketSize)
+
+	c := cache.NewInstrumentedMockCache()
+	mw := newQueryCostMiddleware(c, mockLimits{}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	run := func(fetchedSeries uint64, cacheHitBytes int) *QueryDetails {
+		downstream := HandlerFunc(func(ctx context.Context, _ MetricsQueryRequest) (Response, error) {
+			stats.FromContext(ctx).AddFetchedSeries(fetchedSeries)
+			QueryDetailsFromContext(ctx).ResultsCacheHitBytes = cacheHitBytes
+			return &PrometheusResponse{}, nil
+		})
+
+		_, ctx := stats.ContextWithEmptyStats(context.Background())
+		details, ctx := ContextWithEmptyDetails(ctx)
+		ctx = user.InjectOrgID(ctx, "test")
+
+		_, err := mw.Wrap(downstream).Do(ctx, request)
+		require.NoError(t, err)
+		return details
+	}
+
+	// A query that isn't served from the results cache stores its estimate.
+	run(100, 0)
+	require.Contains(t, c.GetMulti(context.Background(), []string{key}), key)
+
+	// A query served from the results cache fetches no series, but uses and keeps the existing estimate.
+	details := run(0, 1024)
+	assert.Equal(t, 100*61, details.EstimatedQueryCost)
+	details = run(0, 1024)
+	assert.Equal(t, 100*61, details.EstimatedQueryCost)
+}
+
+func TestQueryCostMiddleware_StreamedResponse(t *testing.T) {
+	start := parseTimeRFC3339(t, "2023-01-31T09:00:00Z").UnixMilli()
+	end := start + time.Hour.Milliseconds()
+	request := &PrometheusRangeQueryRequest{
+		start:     start,
+		end:       end,
+		step:      time.Minute.Milliseconds(),
+		minT:      start,
+		maxT:      end,
+		queryExpr: parseQuery(t, "up"),
+	}
+	key := generateCardinalityEstimationCacheKey("test", request, cardinalityEstimateBucketSize)
+
+	c := cache.NewInstrumentedMockCache()
+	mw := newQueryCostMiddleware(c, mockLimits{}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	run := func(fetchedSeries uint64, res Response) *QueryDetails {
+		downstream := HandlerFunc(func(ctx context.Context, _ MetricsQueryRequest) (Response, error) {
+			stats.FromContext(ctx).AddFetchedSeries(fetchedSeries)
+			return res, nil
+		})
+
+		_, ctx := stats.ContextWithEmptyStats(context.Background())
+		details, ctx := ContextWithEmptyDetails(ctx)
+		ctx = user.InjectOrgID(ctx, "test")
+
+		_, err := mw.Wrap(downstream).Do(ctx, request)
+		require.NoError(t, err)
+		return details
+	}
+
+	// A streamed response doesn't store an estimate, since the querier hasn't sent the stats of the query yet.
+	run(0, &streamedResponse{response: &http.Response{}})
+	require.NotContains(t, c.GetMulti(context.Background(), []string{key}), key)
+
+	// Nor does it overwrite an existing estimate.
+	run(100, &PrometheusResponse{})
+	require.Contains(t, c.GetMulti(context.Background(), []string{key}), key)
+	run(0, &streamedResponse{response: &http.Response{}})
+	details := run(0, &streamedResponse{response: &http.Response{}})
+	assert.Equal(t, 100*61, details.EstimatedQueryCost)
+}
@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/mimir/pkg/frontend/querymiddleware/astmapper"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// errQueryRewriteNotValidated is returned for rules that weren't validated when the limits were loaded,
+// which should never happen.
+var errQueryRewriteNotValidated = errors.New("query rewrite rule has not been validated")
+
+// queryRewriteMiddleware rewrites queries according to the rules configured for each tenant.
+type queryRewriteMiddleware struct {
+	next             MetricsQueryHandler
+	limits           Limits
+	logger           log.Logger
+	rewrittenQueries *prometheus.CounterVec
+}
+
+func newQueryRewriteMiddleware(
+	limits Limits,
+	logger log.Logger,
+	registerer prometheus.Registerer,
+) MetricsQueryMiddleware {
+	rewrittenQueries := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_query_frontend_rewritten_queries_total",
+		Help: "Number of queries that were rewritten by rules configured by the cluster administrator.",
+	}, []string{"user"})
+	return MetricsQueryMiddlewareFunc(func(next MetricsQueryHandler) MetricsQueryHandler {
+		return &queryRewriteMiddleware{
+			next:             next,
+			limits:           limits,
+			logger:           logger,
+			rewrittenQueries: rewrittenQueries,
+		}
+	})
+}
+
+func (qr *queryRewriteMiddleware) Do(ctx context.Context, req MetricsQueryRequest) (Response, error) {
+	tenants, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return qr.next.Do(ctx, req)
+	}
+
+	origQuery := req.GetQuery()
+	expr, err := parser.ParseExpr(origQuery)
+	if err != nil {
+		return qr.next.Do(ctx, req)
+	}
+
+	matched := false
+	for _, tenant := range tenants {
+		var tenantMatched bool
+		expr, tenantMatched = qr.rewrite(ctx, tenant, expr)
+		matched = matched || tenantMatched
+	}
+
+	if !matched {
+		return qr.next.Do(ctx, req)
+	}
+
+	rewrittenQuery := expr.String()
+
+	rewritten, err := req.WithExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	spanLog := spanlogger.FromContext(ctx, qr.logger)
+	spanLog.DebugLog("msg", "query rewritten", "original", origQuery, "rewritten", rewrittenQuery)
+	qr.rewrittenQueries.WithLabelValues(tenant.JoinTenantIDs(tenants)).Inc()
+
+	if details := QueryDetailsFromContext(ctx); details != nil {
+		details.RewrittenQuery = rewrittenQuery
+	}
+
+	return qr.next.Do(ctx, rewritten)
+}
+
+// rewrite applies the rules of tenant to expr in order, and returns the rewritten expression and
+// whether any rule matched. Rules that fail to apply are logged and ignored.
+func (qr *queryRewriteMiddleware) rewrite(ctx context.Context, tenant string, expr parser.Expr) (parser.Expr, bool) {
+	rules := qr.limits.QueryRewrites(tenant)
+	if len(rules) == 0 {
+		return expr, false
+	}
+	logger := log.With(qr.logger, "user", tenant)
+
+	matched := false
+	for ruleIndex, rule := range rules {
+		rewritten, ruleMatched, err := applyQueryRewrite(ctx, rule, expr)
+		if err != nil {
+			level.Error(logger).Log("msg", "query rewrite rule could not be applied, ignoring rule", "pattern", rule.Pattern, "replacement", rule.Replacement, "err", err, "index", ruleIndex)
+			continue
+		}
+
+		if ruleMatched {
+			level.Info(logger).Log("msg", "query rewrite rule matched", "pattern", rule.Pattern, "query", expr.String(), "rewritten", rewritten.String(), "index", ruleIndex)
+			expr = rewritten
+			matched = true
+		}
+	}
+
+	return expr, matched
+}
+
+// applyQueryRewrite applies a single rule to expr, and returns the rewritten expression and whether
+// the rule matched. Regex rules must match the whole formatted query, and their replacement may
+// refer to capture groups. Other rules replace every subexpression of the query equal to the pattern.
+func applyQueryRewrite(ctx context.Context, rule *validation.QueryRewrite, expr parser.Expr) (parser.Expr, bool, error) {
+	if rule.Regex {
+		r := rule.Regexp()
+		if r == nil {
+			return nil, false, errQueryRewriteNotValidated
+		}
+
+		query := expr.String()
+		if !r.MatchString(query) {
+			return expr, false, nil
+		}
+
+		rewritten, err := parser.ParseExpr(r.ReplaceAllString(query, rule.Replacement))
+		if err != nil {
+			return nil, false, err
+		}
+		return rewritten, true, nil
+	}
+
+	pattern, replacement := rule.Expressions()
+	if pattern == nil || replacement == nil {
+		return nil, false, errQueryRewriteNotValidated
+	}
+
+	// The mapper modifies the expression it is given, so map a copy to leave expr unchanged if the rule fails.
+	cloned, err := parser.ParseExpr(expr.String())
+	if err != nil {
+		return nil, false, err
+	}
+
+	stats := astmapper.NewMapperStats()
+	rewritten, err := astmapper.NewQueryRewrite(ctx, pattern, replacement, stats).Map(cloned)
+	if err != nil {
+		return nil, false, err
+	}
+	if stats.GetRewrittenExpressions() == 0 {
+		return expr, false, nil
+	}
+
+	// The replacement may not be valid in every position the pattern appears in,
+	// for example if it has a different type, so check the result is still valid.
+	rewritten, err = parser.ParseExpr(rewritten.String())
+	if err != nil {
+		return nil, false, err
+	}
+	return rewritten, true, nil
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestQueryRewriteMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		limits        mockLimits
+		expectedQuery string
+	}{
+		{
+			name:          "doesn't rewrite queries due to empty limits",
+			limits:        mockLimits{},
+			query:         "rate(metric_counter[5m])",
+			expectedQuery: "rate(metric_counter[5m])",
+		},
+		{
+			name: "rewrites subexpression matching non regex pattern",
+			limits: mockLimits{
+				queryRewrites: []*validation.QueryRewrite{
+					{Pattern: "sum by (job) (rate(metric_counter[5m]))", Replacement: "job:metric_counter:rate5m"},
+				},
+			},
+			query: `
+				sum(rate(metric_counter[5m])) by (job)
+				/
+				sum(rate(other_counter[5m])) by (job)
+			`,
+			expectedQuery: "job:metric_counter:rate5m / sum by (job) (rate(other_counter[5m]))",
+		},
+		{
+			name: "adds matchers to selector matching non regex pattern",
+			limits: mockLimits{
+				queryRewrites: []*validation.QueryRewrite{
+					{Pattern: `{job="app"}`, Replacement: `{__name__=~"metric_.*",job="app"}`},
+				},
+			},
+			query:         `count({job="app"})`,
+			expectedQuery: `count({__name__=~"metric_.*",job="app"})`,
+		},
+		{
+			name: "doesn't rewrite query not matching non regex pattern",
+			limits: mockLimits{
+				queryRewrites: []*validation.QueryRewrite{
+					{Pattern: "rate(metric_counter[5m])", Replacement: "metric_counter:rate5m"},
+				},
+			},
+			query:         "rate(metric_counter[15m])",
+			expectedQuery: "rate(metric_counter[15m])",
+		},
+		{
+			name: "rewrites query matching regex pattern",
+			limits: mockLimits{
+				queryRewrites: []*validation.QueryRewrite{
+					{Pattern: `rate\((\w+)\[5m\]\)`, Regex: true, Replacement: "${1}:rate5m"},
+				},
+			},
+			query:         "rate(metric_counter[5m])",
+			expectedQuery: "metric_counter:rate5m",
+		},
+		{
+			name: "regex pattern must match the whole query",
+			limits: mockLimits{
+				queryRewrites: []*validation.QueryRewrite{
+					{Pattern: `rate\((\w+)\[5m\]\)`, Regex: true, Replacement: "${1}:rate5m"},
+				},
+			},
+			query:         "sum(rate(metric_counter[5m]))",
+			expectedQuery: "sum(rate(metric_counter[5m]))",
+		},
+		{
+			name: "applies rules in order",
+			limits: mockLimits{
+				queryRewrites: []*validation.QueryRewrite{
+					{Pattern: "metric_counter", Replacement: "other_counter"},
+					{Pattern: "rate(other_counter[5m])", Replacement: "other_counter:rate5m"},
+				},
+			},
+			query:         "rate(metric_counter[5m])",
+			expectedQuery: "other_counter:rate5m",
+		},
+		{
+			name: "ignores rules producing invalid queries",
+			limits: mockLimits{
+				queryRewrites: []*validation.QueryRewrite{
+					{Pattern: `sum\(metric_counter\)`, Regex: true, Replacement: "sum("},
+					{Pattern: "metric_counter", Replacement: `"foo"`},
+				},
+			},
+			query:         "sum(metric_counter)",
+			expectedQuery: "sum(metric_counter)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, rule := range tt.limits.queryRewrites {
+				require.NoError(t, rule.Validate())
+			}
+
+			reqs := map[string]MetricsQueryRequest{
+				"range query": &PrometheusRangeQueryRequest{
+					queryExpr: parseQuery(t, tt.query),
+				},
+				"instant query": &PrometheusInstantQueryRequest{
+					queryExpr: parseQuery(t, tt.query),
+				},
+			}
+
+			for reqType, req := range reqs {
+				t.Run(reqType, func(t *testing.T) {
+					reg := prometheus.NewPedanticRegistry()
+					logger := log.NewNopLogger()
+					details, ctx := ContextWithEmptyDetails(user.InjectOrgID(context.Background(), "test"))
+
+					var actualQuery string
+					mw := newQueryRewriteMiddleware(tt.limits, logger, reg)
+					_, err := mw.Wrap(HandlerFunc(func(_ context.Context, req MetricsQueryRequest) (Response, error) {
+						actualQuery = req.GetQuery()
+						return &PrometheusResponse{}, nil
+					})).Do(ctx, req)
+
+					require.NoError(t, err)
+					require.Equal(t, tt.expectedQuery, actualQuery)
+
+					if tt.expectedQuery != req.GetQuery() {
+						require.Equal(t, tt.expectedQuery, details.RewrittenQuery)
+						require.NoError(t, promtest.GatherAndCompare(reg, strings.NewReader(`
+							# HELP cortex_query_frontend_rewritten_queries_total Number of queries that were rewritten by rules configured by the cluster administrator.
+							# TYPE cortex_query_frontend_rewritten_queries_total counter
+							cortex_query_frontend_rewritten_queries_total{user="test"} 1
+						`)))
+					} else {
+						require.Empty(t, details.RewrittenQuery)
+						require.NoError(t, promtest.GatherAndCompare(reg, strings.NewReader(``)))
+					}
+				})
+			}
+		})
+	}
+}
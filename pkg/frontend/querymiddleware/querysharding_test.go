@@ -1796,6 +1796,7 @@ func TestQuerySharding_Annotations(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
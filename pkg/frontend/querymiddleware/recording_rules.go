@@ -0,0 +1,417 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/frontend/querymiddleware/astmapper"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/rulestore"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+// recordingRulesIdleTimeout is how long the recording rules of a tenant are kept loaded after its last range query.
+const recordingRulesIdleTimeout = time.Hour
+
+// RecordingRulesStore is the subset of rulestore.RuleStore used to load tenants' recording rules.
+type RecordingRulesStore interface {
+	ListRuleGroupsForUserAndNamespace(ctx context.Context, userID string, namespace string, opts ...rulestore.Option) (rulespb.RuleGroupList, error)
+	LoadRuleGroups(ctx context.Context, groupsToLoad map[string]rulespb.RuleGroupList) (missing rulespb.RuleGroupList, err error)
+}
+
+// recordingRule is a recording rule the query-frontend may substitute for its expression.
+type recordingRule struct {
+	record      string
+	labels      labels.Labels
+	interval    time.Duration
+	queryOffset time.Duration
+
+	// loadedAt is when the query-frontend first loaded the rule. The rule may have been created earlier,
+	// but its series are only known to be recorded from some time after this.
+	loadedAt time.Time
+}
+
+// selector returns an expression that selects the series recorded by the rule, with the same labels
+// as the series its expression returns.
+func (r recordingRule) selector() parser.Expr {
+	matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, r.record)}
+	names := make([]string, 0, r.labels.Len())
+	r.labels.Range(func(l labels.Label) {
+		matchers = append(matchers, labels.MustNewMatcher(labels.MatchEqual, l.Name, l.Value))
+		names = append(names, l.Name)
+	})
+
+	// Every group has a single series, so this only removes the metric name and the labels added by the rule.
+	return &parser.AggregateExpr{
+		Op:       parser.SUM,
+		Without:  true,
+		Grouping: names,
+		Expr: &parser.VectorSelector{
+			Name:          r.record,
+			LabelMatchers: matchers,
+		},
+	}
+}
+
+// canSubstitute returns true if the series recorded by the rule can be used in place of its expression
+// in a range query starting at start with step, evaluated with lookbackDelta.
+//
+// Rules are only substituted in queries starting at least minAge after the rule was loaded, so that rulers
+// have loaded the rule and recorded its series over the whole time range of the query.
+func (r recordingRule) canSubstitute(start, step int64, lookbackDelta, minAge time.Duration) bool {
+	if start < r.loadedAt.Add(minAge).UnixMilli() {
+		return false
+	}
+
+	// A shorter step would return the same recorded sample for several consecutive steps.
+	if step < r.interval.Milliseconds() {
+		return false
+	}
+
+	// The most recent sample must be within the lookback delta for every step to have a value.
+	return r.interval+r.queryOffset <= lookbackDelta
+}
+
+// recordingRuleSubstitutionMiddleware replaces aggregations in range queries that match the expression of
+// one of the tenant's recording rules with the series recorded by that rule.
+type recordingRuleSubstitutionMiddleware struct {
+	next          MetricsQueryHandler
+	limits        Limits
+	rules         *RecordingRulesLoader
+	lookbackDelta time.Duration
+	minRuleAge    time.Duration
+	logger        log.Logger
+
+	substitutedQueries *prometheus.CounterVec
+}
+
+func newRecordingRuleSubstitutionMiddleware(
+	rules *RecordingRulesLoader,
+	minRuleAge time.Duration,
+	lookbackDelta time.Duration,
+	limits Limits,
+	logger log.Logger,
+	registerer prometheus.Registerer,
+) MetricsQueryMiddleware {
+	substitutedQueries := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_query_frontend_recording_rule_substituted_queries_total",
+		Help: "Number of queries in which at least one expression was substituted with the series recorded by a recording rule.",
+	}, []string{"user"})
+
+	if lookbackDelta == 0 {
+		// This should be the same value as github.com/prometheus/prometheus/promql.defaultLookbackDelta.
+		lookbackDelta = 5 * time.Minute
+	}
+
+	return MetricsQueryMiddlewareFunc(func(next MetricsQueryHandler) MetricsQueryHandler {
+		return &recordingRuleSubstitutionMiddleware{
+			next:               next,
+			limits:             limits,
+			rules:              rules,
+			lookbackDelta:      lookbackDelta,
+			minRuleAge:         minRuleAge,
+			logger:             logger,
+			substitutedQueries: substitutedQueries,
+		}
+	})
+}
+
+func (s *recordingRuleSubstitutionMiddleware) Do(ctx context.Context, req MetricsQueryRequest) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return s.next.Do(ctx, req)
+	}
+
+	// Rules are evaluated against the data of a single tenant, so they can't be used for federated queries.
+	if len(tenantIDs) != 1 || req.GetStep() <= 0 || !s.limits.RulerRecordingRulesEvaluationEnabled(tenantIDs[0]) {
+		return s.next.Do(ctx, req)
+	}
+
+	rules := s.rules.rulesForUser(tenantIDs[0])
+	if len(rules) == 0 {
+		return s.next.Do(ctx, req)
+	}
+
+	substitutions := make(map[string]parser.Expr, len(rules))
+	for expr, candidates := range rules {
+		for _, rule := range candidates {
+			if rule.canSubstitute(req.GetStart(), req.GetStep(), s.lookbackDelta, s.minRuleAge) {
+				substitutions[expr] = rule.selector()
+				break
+			}
+		}
+	}
+	if len(substitutions) == 0 {
+		return s.next.Do(ctx, req)
+	}
+
+	origQuery := req.GetQuery()
+	expr, err := parser.ParseExpr(origQuery)
+	if err != nil {
+		return s.next.Do(ctx, req)
+	}
+
+	mapped, substituted, err := substituteRecordingRules(ctx, substitutions, expr)
+	if err != nil || !substituted {
+		return s.next.Do(ctx, req)
+	}
+
+	substitutedReq, err := req.WithExpr(mapped)
+	if err != nil {
+		return nil, err
+	}
+
+	substitutedQuery := mapped.String()
+
+	spanLog := spanlogger.FromContext(ctx, s.logger)
+	spanLog.DebugLog("msg", "substituted recording rules in query", "original", origQuery, "rewritten", substitutedQuery)
+	s.substitutedQueries.WithLabelValues(tenantIDs[0]).Inc()
+
+	if details := QueryDetailsFromContext(ctx); details != nil {
+		details.SubstitutedQuery = substitutedQuery
+	}
+
+	return s.next.Do(ctx, substitutedReq)
+}
+
+// substituteRecordingRules replaces the expressions in expr that are keys of substitutions, and returns the
+// resulting expression and whether any expression was substituted.
+func substituteRecordingRules(ctx context.Context, substitutions map[string]parser.Expr, expr parser.Expr) (parser.Expr, bool, error) {
+	stats := astmapper.NewMapperStats()
+	mapped, err := astmapper.NewRecordingRuleSubstitution(ctx, substitutions, stats).Map(expr)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return mapped, stats.GetRewrittenExpressions() > 0, nil
+}
+
+// RecordingRulesLoader loads the recording rules of tenants from the ruler storage, keyed by the formatted
+// representation of their expression.
+//
+// Rules are loaded in background for the tenants that recently ran range queries, so that queries never wait for
+// the ruler storage. Tenants whose rules weren't requested for longer than recordingRulesIdleTimeout are offloaded,
+// and their rules are treated as newly loaded if they're requested again.
+type RecordingRulesLoader struct {
+	services.Service
+
+	store                     RecordingRulesStore
+	defaultEvaluationInterval time.Duration
+	idleTimeout               time.Duration
+	logger                    log.Logger
+
+	mtx    sync.RWMutex
+	byUser map[string]*userRecordingRules
+}
+
+type userRecordingRules struct {
+	// rules are the most recently loaded rules, which are kept if the rules fail to load,
+	// so that the time each rule was first loaded isn't lost.
+	rules map[string][]recordingRule
+
+	// Unix timestamp (seconds) of when the rules have been requested the last time.
+	requestedAt atomic.Int64
+}
+
+// NewRecordingRulesLoader makes a new RecordingRulesLoader, which reloads the recording rules of tenants every
+// refreshInterval. defaultEvaluationInterval is the evaluation interval of rule groups that don't set one.
+func NewRecordingRulesLoader(store RecordingRulesStore, refreshInterval, defaultEvaluationInterval time.Duration, logger log.Logger) *RecordingRulesLoader {
+	l := &RecordingRulesLoader{
+		store:                     store,
+		defaultEvaluationInterval: defaultEvaluationInterval,
+		idleTimeout:               recordingRulesIdleTimeout,
+		logger:                    logger,
+		byUser:                    map[string]*userRecordingRules{},
+	}
+
+	l.Service = services.NewTimerService(refreshInterval, nil, l.refreshRules, nil)
+	return l
+}
+
+// rulesForUser returns the last recording rules loaded for userID. The first time the rules of a tenant are
+// requested, it returns no rules and the rules are loaded at the next refresh.
+func (l *RecordingRulesLoader) rulesForUser(userID string) map[string][]recordingRule {
+	l.mtx.RLock()
+	if entry := l.byUser[userID]; entry != nil {
+		rules := entry.rules
+		l.mtx.RUnlock()
+
+		entry.requestedAt.Store(time.Now().Unix())
+		return rules
+	}
+	l.mtx.RUnlock()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	// Another request may have added the tenant in the meantime.
+	entry := l.byUser[userID]
+	if entry == nil {
+		entry = &userRecordingRules{}
+		l.byUser[userID] = entry
+	}
+	entry.requestedAt.Store(time.Now().Unix())
+	return entry.rules
+}
+
+// refreshRules offloads the rules that weren't requested for longer than the idle timeout,
+// and reloads all other ones.
+func (l *RecordingRulesLoader) refreshRules(ctx context.Context) error {
+	now := time.Now()
+
+	toUpdate := map[string]map[string][]recordingRule{}
+	l.mtx.Lock()
+	for userID, entry := range l.byUser {
+		if now.Sub(time.Unix(entry.requestedAt.Load(), 0)) >= l.idleTimeout {
+			delete(l.byUser, userID)
+			continue
+		}
+		toUpdate[userID] = entry.rules
+	}
+	l.mtx.Unlock()
+
+	for userID, previous := range toUpdate {
+		rules, err := l.loadRules(ctx, userID, previous, time.Now())
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				// The service is stopping.
+				return nil
+			}
+			// Keep the previous rules, so that a temporary failure doesn't reset the time they were first loaded.
+			level.Warn(l.logger).Log("msg", "failed to load recording rules, previously loaded rules will be substituted in queries", "user", userID, "err", err)
+			continue
+		}
+
+		l.mtx.Lock()
+		if entry := l.byUser[userID]; entry != nil {
+			entry.rules = rules
+		}
+		l.mtx.Unlock()
+	}
+
+	// Never return error, otherwise the service terminates.
+	return nil
+}
+
+// loadRules loads the recording rules of userID. Rules that are in previous keep the time they were first loaded,
+// and other rules are marked as loaded at now.
+func (l *RecordingRulesLoader) loadRules(ctx context.Context, userID string, previous map[string][]recordingRule, now time.Time) (map[string][]recordingRule, error) {
+	groups, err := l.store.ListRuleGroupsForUserAndNamespace(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	if _, err := l.store.LoadRuleGroups(ctx, map[string]rulespb.RuleGroupList{userID: groups}); err != nil {
+		return nil, err
+	}
+
+	rules := map[string][]recordingRule{}
+	for _, group := range groups {
+		// Federated rule groups record series queried from other tenants.
+		if len(group.SourceTenants) > 0 {
+			continue
+		}
+
+		interval := group.Interval
+		if interval == 0 {
+			interval = l.defaultEvaluationInterval
+		}
+		queryOffset := max(group.QueryOffset, group.EvaluationDelay)
+
+		for _, rule := range group.Rules {
+			if rule.Record == "" {
+				continue
+			}
+
+			expr, ok := substitutableExpr(rule)
+			if !ok {
+				continue
+			}
+
+			key := expr.String()
+			loaded := recordingRule{
+				record:      rule.Record,
+				labels:      mimirpb.FromLabelAdaptersToLabels(rule.Labels),
+				interval:    interval,
+				queryOffset: queryOffset,
+				loadedAt:    now,
+			}
+			for _, p := range previous[key] {
+				if p.record == loaded.record && labels.Equal(p.labels, loaded.labels) {
+					loaded.loadedAt = p.loadedAt
+					break
+				}
+			}
+			rules[key] = append(rules[key], loaded)
+		}
+	}
+
+	return rules, nil
+}
+
+// substitutableExpr returns the expression of rule if it can be replaced by the series the rule records.
+//
+// The expression must be an aggregation that removes the metric name from its result, so that the recorded
+// series only differ from the aggregation's result by their metric name and the labels added by the rule.
+func substitutableExpr(rule *rulespb.RuleDesc) (*parser.AggregateExpr, bool) {
+	parsed, err := parser.ParseExpr(rule.Expr)
+	if err != nil {
+		return nil, false
+	}
+
+	for {
+		paren, ok := parsed.(*parser.ParenExpr)
+		if !ok {
+			break
+		}
+		parsed = paren.Expr
+	}
+
+	expr, ok := parsed.(*parser.AggregateExpr)
+	if !ok {
+		return nil, false
+	}
+
+	switch expr.Op {
+	case parser.SUM, parser.AVG, parser.MIN, parser.MAX, parser.COUNT, parser.GROUP, parser.STDDEV, parser.STDVAR, parser.QUANTILE:
+	default:
+		// Other aggregations return the original series, or add labels to them.
+		return nil, false
+	}
+
+	if len(rule.Labels) == 0 {
+		return expr, true
+	}
+
+	// The labels the rule adds to its series must not overwrite labels returned by the aggregation,
+	// otherwise they can't be removed to get the aggregation's result.
+	if expr.Without {
+		return nil, false
+	}
+	for _, l := range rule.Labels {
+		if slices.Contains(expr.Grouping, l.Name) {
+			return nil, false
+		}
+	}
+
+	return expr, true
+}
@@ -0,0 +1,337 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/rulestore"
+)
+
+func TestRecordingRuleSubstitutionMiddleware(t *testing.T) {
+	groups := rulespb.RuleGroupList{
+		{
+			Name:      "1m",
+			Namespace: "test",
+			User:      "test",
+			Interval:  time.Minute,
+			Rules: []*rulespb.RuleDesc{
+				{Record: "job:some_series:rate5m", Expr: "sum by (job) (rate(some_series[5m]))"},
+				{Record: "some_series:rate5m", Expr: "sum(rate(some_series[5m]))", Labels: []mimirpb.LabelAdapter{{Name: "env", Value: "prod"}}},
+				{Record: "job:some_series:topk", Expr: "topk by (job) (1, some_series)"},
+				{Alert: "SomeSeriesHigh", Expr: "sum(some_series) > 10"},
+			},
+		},
+		{
+			Name:      "default interval",
+			Namespace: "test",
+			User:      "test",
+			Rules: []*rulespb.RuleDesc{
+				{Record: "job:other_series:sum", Expr: "(sum by (job) (other_series))"},
+			},
+		},
+		{
+			Name:      "10m",
+			Namespace: "test",
+			User:      "test",
+			Interval:  10 * time.Minute,
+			Rules: []*rulespb.RuleDesc{
+				{Record: "job:slow_series:sum", Expr: "sum by (job) (slow_series)"},
+			},
+		},
+		{
+			Name:          "federated",
+			Namespace:     "test",
+			User:          "test",
+			Interval:      time.Minute,
+			SourceTenants: []string{"other"},
+			Rules: []*rulespb.RuleDesc{
+				{Record: "job:federated_series:sum", Expr: "sum by (job) (federated_series)"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		query         string
+		step          time.Duration
+		disabled      bool
+		expectedQuery string
+	}{
+		"query without recording rule": {
+			query:         "sum by (job) (rate(other_series[5m]))",
+			step:          time.Minute,
+			expectedQuery: "sum by (job) (rate(other_series[5m]))",
+		},
+		"query matching recording rule": {
+			query:         "sum(rate(some_series[5m])) by (job)",
+			step:          time.Minute,
+			expectedQuery: "sum without () (job:some_series:rate5m)",
+		},
+		"query with subexpressions matching recording rules": {
+			query:         "sum by (job) (rate(some_series[5m])) / ignoring (job) group_left () sum(rate(some_series[5m]))",
+			step:          time.Minute,
+			expectedQuery: `sum without () (job:some_series:rate5m) / ignoring (job) group_left () sum without (env) (some_series:rate5m{env="prod"})`,
+		},
+		"query matching recording rule with default evaluation interval": {
+			query:         "sum by (job) (other_series)",
+			step:          2 * time.Minute,
+			expectedQuery: "sum without () (job:other_series:sum)",
+		},
+		"query with step shorter than evaluation interval": {
+			query:         "sum by (job) (rate(some_series[5m]))",
+			step:          30 * time.Second,
+			expectedQuery: "sum by (job) (rate(some_series[5m]))",
+		},
+		"query matching recording rule with evaluation interval longer than lookback delta": {
+			query:         "sum by (job) (slow_series)",
+			step:          10 * time.Minute,
+			expectedQuery: "sum by (job) (slow_series)",
+		},
+		"query matching recording rule that can't be substituted": {
+			query:         "topk by (job) (1, some_series)",
+			step:          time.Minute,
+			expectedQuery: "topk by (job) (1, some_series)",
+		},
+		"query matching federated recording rule": {
+			query:         "sum by (job) (federated_series)",
+			step:          time.Minute,
+			expectedQuery: "sum by (job) (federated_series)",
+		},
+		"recording rules evaluation disabled for tenant": {
+			query:         "sum by (job) (rate(some_series[5m]))",
+			step:          time.Minute,
+			disabled:      true,
+			expectedQuery: "sum by (job) (rate(some_series[5m]))",
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := &mockRecordingRulesStore{groups: map[string]rulespb.RuleGroupList{"test": groups}}
+			limits := mockLimits{rulerRecordingRulesEvaluationEnabled: !testData.disabled}
+			reg := prometheus.NewPedanticRegistry()
+			rules := newLoadedRecordingRules(t, store, 2*time.Minute, "test")
+			mw := newRecordingRuleSubstitutionMiddleware(rules, 0, 0, limits, log.NewNopLogger(), reg)
+
+			// Rules are only substituted in queries starting after they were loaded.
+			start := time.Now().Add(time.Hour)
+			req := &PrometheusRangeQueryRequest{
+				start:     start.UnixMilli(),
+				end:       start.Add(time.Hour).UnixMilli(),
+				step:      testData.step.Milliseconds(),
+				queryExpr: parseQuery(t, testData.query),
+			}
+
+			details, ctx := ContextWithEmptyDetails(user.InjectOrgID(context.Background(), "test"))
+
+			var actualQuery string
+			_, err := mw.Wrap(HandlerFunc(func(_ context.Context, req MetricsQueryRequest) (Response, error) {
+				actualQuery = req.GetQuery()
+				return &PrometheusResponse{}, nil
+			})).Do(ctx, req)
+
+			require.NoError(t, err)
+			require.Equal(t, testData.expectedQuery, actualQuery)
+
+			if testData.expectedQuery != req.GetQuery() {
+				require.Equal(t, testData.expectedQuery, details.SubstitutedQuery)
+				require.NoError(t, promtest.GatherAndCompare(reg, strings.NewReader(`
+					# HELP cortex_query_frontend_recording_rule_substituted_queries_total Number of queries in which at least one expression was substituted with the series recorded by a recording rule.
+					# TYPE cortex_query_frontend_recording_rule_substituted_queries_total counter
+					cortex_query_frontend_recording_rule_substituted_queries_total{user="test"} 1
+				`)))
+			} else {
+				require.Empty(t, details.SubstitutedQuery)
+				require.NoError(t, promtest.GatherAndCompare(reg, strings.NewReader(``)))
+			}
+		})
+	}
+}
+
+func TestRecordingRuleSubstitutionMiddleware_FederatedQuery(t *testing.T) {
+	store := &mockRecordingRulesStore{groups: map[string]rulespb.RuleGroupList{
+		"a": {{Name: "group", Namespace: "test", User: "a", Interval: time.Minute, Rules: []*rulespb.RuleDesc{
+			{Record: "job:some_series:sum", Expr: "sum by (job) (some_series)"},
+		}}},
+	}}
+	limits := mockLimits{rulerRecordingRulesEvaluationEnabled: true}
+	rules := NewRecordingRulesLoader(store, time.Minute, time.Minute, log.NewNopLogger())
+	mw := newRecordingRuleSubstitutionMiddleware(rules, 0, 0, limits, log.NewNopLogger(), nil)
+
+	req := &PrometheusRangeQueryRequest{
+		end:       time.Hour.Milliseconds(),
+		step:      time.Minute.Milliseconds(),
+		queryExpr: parseQuery(t, "sum by (job) (some_series)"),
+	}
+
+	_, err := mw.Wrap(HandlerFunc(func(_ context.Context, actual MetricsQueryRequest) (Response, error) {
+		assert.Equal(t, req.GetQuery(), actual.GetQuery())
+		return &PrometheusResponse{}, nil
+	})).Do(user.InjectOrgID(context.Background(), "a|b"), req)
+
+	require.NoError(t, err)
+
+	// The rules of the tenants of federated queries aren't loaded.
+	require.NoError(t, rules.refreshRules(context.Background()))
+	require.Zero(t, store.listCalls.Load())
+}
+
+func TestRecordingRuleSubstitutionMiddleware_MinRuleAge(t *testing.T) {
+	const minRuleAge = 10 * time.Minute
+
+	store := &mockRecordingRulesStore{groups: map[string]rulespb.RuleGroupList{
+		"test": {{Name: "group", Namespace: "test", User: "test", Interval: time.Minute, Rules: []*rulespb.RuleDesc{
+			{Record: "job:some_series:sum", Expr: "sum by (job) (some_series)"},
+		}}},
+	}}
+	limits := mockLimits{rulerRecordingRulesEvaluationEnabled: true}
+	now := time.Now()
+	rules := newLoadedRecordingRules(t, store, time.Minute, "test")
+	mw := newRecordingRuleSubstitutionMiddleware(rules, minRuleAge, 0, limits, log.NewNopLogger(), nil)
+
+	tests := map[string]struct {
+		start         time.Time
+		expectedQuery string
+	}{
+		"query starting before the rule was loaded": {
+			start:         now.Add(-time.Hour),
+			expectedQuery: "sum by (job) (some_series)",
+		},
+		"query starting less than the minimum rule age after the rule was loaded": {
+			start:         now.Add(minRuleAge / 2),
+			expectedQuery: "sum by (job) (some_series)",
+		},
+		"query starting more than the minimum rule age after the rule was loaded": {
+			start:         now.Add(2 * minRuleAge),
+			expectedQuery: "sum without () (job:some_series:sum)",
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := &PrometheusRangeQueryRequest{
+				start:     testData.start.UnixMilli(),
+				end:       testData.start.Add(time.Hour).UnixMilli(),
+				step:      time.Minute.Milliseconds(),
+				queryExpr: parseQuery(t, "sum by (job) (some_series)"),
+			}
+
+			_, err := mw.Wrap(HandlerFunc(func(_ context.Context, actual MetricsQueryRequest) (Response, error) {
+				assert.Equal(t, testData.expectedQuery, actual.GetQuery())
+				return &PrometheusResponse{}, nil
+			})).Do(user.InjectOrgID(context.Background(), "test"), req)
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRecordingRulesLoader_KeepsLoadTimeOfRules(t *testing.T) {
+	ctx := context.Background()
+	store := &mockRecordingRulesStore{groups: map[string]rulespb.RuleGroupList{
+		"test": {{Name: "group", Namespace: "test", User: "test", Interval: time.Minute, Rules: []*rulespb.RuleDesc{
+			{Record: "job:some_series:sum", Expr: "sum by (job) (some_series)"},
+		}}},
+	}}
+	loader := newLoadedRecordingRules(t, store, time.Minute, "test")
+
+	rules := loader.rulesForUser("test")
+	require.Len(t, rules["sum by (job) (some_series)"], 1)
+	loadedAt := rules["sum by (job) (some_series)"][0].loadedAt
+
+	// Rules that fail to load keep the previously loaded rules.
+	store.err = errors.New("store unavailable")
+	require.NoError(t, loader.refreshRules(ctx))
+	require.Equal(t, rules, loader.rulesForUser("test"))
+	store.err = nil
+
+	// Rules that were loaded before keep their load time.
+	store.groups["test"][0].Rules = append(store.groups["test"][0].Rules, &rulespb.RuleDesc{Record: "job:other_series:sum", Expr: "sum by (job) (other_series)"})
+	time.Sleep(time.Millisecond)
+	require.NoError(t, loader.refreshRules(ctx))
+	rules = loader.rulesForUser("test")
+	require.Len(t, rules, 2)
+	require.Equal(t, loadedAt, rules["sum by (job) (some_series)"][0].loadedAt)
+	require.True(t, rules["sum by (job) (other_series)"][0].loadedAt.After(loadedAt))
+	require.Equal(t, int64(3), store.listCalls.Load())
+}
+
+func TestRecordingRulesLoader(t *testing.T) {
+	ctx := context.Background()
+	store := &mockRecordingRulesStore{groups: map[string]rulespb.RuleGroupList{
+		"test": {{Name: "group", Namespace: "test", User: "test", Interval: time.Minute, Rules: []*rulespb.RuleDesc{
+			{Record: "job:some_series:sum", Expr: "sum by (job) (some_series)"},
+		}}},
+	}}
+	loader := NewRecordingRulesLoader(store, time.Minute, time.Minute, log.NewNopLogger())
+
+	// Rules aren't available until loaded in background.
+	require.Empty(t, loader.rulesForUser("test"))
+	require.Zero(t, store.listCalls.Load())
+
+	require.NoError(t, loader.refreshRules(ctx))
+	require.Equal(t, int64(1), store.listCalls.Load())
+
+	// Requesting the rules doesn't read the store.
+	for i := 0; i < 3; i++ {
+		rules := loader.rulesForUser("test")
+		require.Len(t, rules, 1)
+		require.Contains(t, rules, "sum by (job) (some_series)")
+	}
+	require.Equal(t, int64(1), store.listCalls.Load())
+
+	// Tenants without rules don't have any rules to substitute.
+	require.Empty(t, loader.rulesForUser("other"))
+	require.NoError(t, loader.refreshRules(ctx))
+	require.Empty(t, loader.rulesForUser("other"))
+	require.Equal(t, int64(3), store.listCalls.Load())
+
+	// Tenants whose rules weren't requested for longer than the idle timeout are offloaded.
+	loader.idleTimeout = 0
+	require.NoError(t, loader.refreshRules(ctx))
+	require.Equal(t, int64(3), store.listCalls.Load())
+	require.Empty(t, loader.rulesForUser("test"))
+}
+
+// newLoadedRecordingRules returns a RecordingRulesLoader with the rules of userIDs already loaded.
+func newLoadedRecordingRules(t *testing.T, store RecordingRulesStore, defaultEvaluationInterval time.Duration, userIDs ...string) *RecordingRulesLoader {
+	loader := NewRecordingRulesLoader(store, time.Minute, defaultEvaluationInterval, log.NewNopLogger())
+	for _, userID := range userIDs {
+		loader.rulesForUser(userID)
+	}
+	require.NoError(t, loader.refreshRules(context.Background()))
+	return loader
+}
+
+type mockRecordingRulesStore struct {
+	groups    map[string]rulespb.RuleGroupList
+	err       error
+	listCalls atomic.Int64
+}
+
+func (m *mockRecordingRulesStore) ListRuleGroupsForUserAndNamespace(_ context.Context, userID string, _ string, _ ...rulestore.Option) (rulespb.RuleGroupList, error) {
+	m.listCalls.Inc()
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.groups[userID], nil
+}
+
+func (m *mockRecordingRulesStore) LoadRuleGroups(context.Context, map[string]rulespb.RuleGroupList) (rulespb.RuleGroupList, error) {
+	// The groups returned by ListRuleGroupsForUserAndNamespace already have their rules.
+	return nil, nil
+}
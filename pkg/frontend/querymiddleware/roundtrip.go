@@ -67,6 +67,14 @@ type Config struct {
 	TargetSeriesPerShard     uint64        `yaml:"query_sharding_target_series_per_shard" category:"advanced"`
 	ShardActiveSeriesQueries bool          `yaml:"shard_active_series_queries" category:"experimental"`
 	UseActiveSeriesDecoder   bool          `yaml:"use_active_series_decoder" category:"experimental"`
+	StreamRangeQueryResults  bool          `yaml:"stream_range_query_results" category:"experimental"`
+
+	SubstituteRecordingRules      bool          `yaml:"substitute_recording_rules" category:"experimental"`
+	RecordingRulesRefreshInterval time.Duration `yaml:"recording_rules_refresh_interval" category:"experimental"`
+	RecordingRulesMinAge          time.Duration `yaml:"recording_rules_min_age" category:"experimental"`
+
+	LabelsQueryCacheGenerationEnabled         bool          `yaml:"labels_query_cache_generation_enabled" category:"experimental"`
+	LabelsQueryCacheGenerationRefreshInterval time.Duration `yaml:"labels_query_cache_generation_refresh_interval" category:"experimental"`
 
 	// CacheKeyGenerator allows to inject a CacheKeyGenerator to use for generating cache keys.
 	// If nil, the querymiddleware package uses a DefaultCacheKeyGenerator with SplitQueriesByInterval.
@@ -82,6 +90,16 @@ type Config struct {
 
 	ExtraPropagateHeaders []string `yaml:"-"`
 
+	// RecordingRules is used to load the recording rules of tenants when SubstituteRecordingRules is enabled.
+	RecordingRules *RecordingRulesLoader `yaml:"-"`
+
+	// LabelsQueryCacheGenerations is used to invalidate cached label names and values query results
+	// when LabelsQueryCacheGenerationEnabled is enabled.
+	LabelsQueryCacheGenerations LabelsQueryCacheGenerations `yaml:"-"`
+
+	// SeriesDeletions is used to not cache the results of queries reading deleted samples.
+	SeriesDeletions SeriesDeletions `yaml:"-"`
+
 	QueryResultResponseFormat string `yaml:"query_result_response_format"`
 }
 
@@ -98,6 +116,12 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.QueryResultResponseFormat, "query-frontend.query-result-response-format", formatProtobuf, fmt.Sprintf("Format to use when retrieving query results from queriers. Supported values: %s", strings.Join(allFormats, ", ")))
 	f.BoolVar(&cfg.ShardActiveSeriesQueries, "query-frontend.shard-active-series-queries", false, "True to enable sharding of active series queries.")
 	f.BoolVar(&cfg.UseActiveSeriesDecoder, "query-frontend.use-active-series-decoder", false, "Set to true to use the zero-allocation response decoder for active series queries.")
+	f.BoolVar(&cfg.StreamRangeQueryResults, "query-frontend.stream-range-query-results", false, "Set to true to return range query results that are encoded as JSON to the client as queriers produce them, rather than waiting for the whole result. Range queries with streamed results are not split by interval, cached or sharded, and the series in their results are not sorted. Queriers stream results only if they run the Mimir query engine and -querier.response-streaming-enabled is true.")
+	f.BoolVar(&cfg.SubstituteRecordingRules, "query-frontend.substitute-recording-rules", false, "Set to true to replace aggregations in range queries that are identical to the expression of one of the tenant's recording rules with the series recorded by the rule. Aggregations are only replaced if the query step is at least the evaluation interval of the rule, and the query starts at least -query-frontend.recording-rules-min-age after the rule was first loaded. Requires the ruler storage to be configured for the query-frontend.")
+	f.DurationVar(&cfg.RecordingRulesRefreshInterval, "query-frontend.recording-rules-refresh-interval", time.Minute, "How frequently to reload the recording rules of tenants that recently ran range queries from the ruler storage, when -query-frontend.substitute-recording-rules is enabled. Rules are loaded in background, so the rules of a tenant aren't substituted in queries until they've been loaded for the first time.")
+	f.DurationVar(&cfg.RecordingRulesMinAge, "query-frontend.recording-rules-min-age", 15*time.Minute, "Minimum time between the query-frontend first loading a recording rule and the start of a range query for the rule to be substituted in the query, when -query-frontend.substitute-recording-rules is enabled. Rulers only record series for a rule once they've loaded it, so this should be greater than -ruler.poll-interval. The time rules were loaded is kept in memory, so it's reset when the query-frontend restarts.")
+	f.BoolVar(&cfg.LabelsQueryCacheGenerationEnabled, "query-frontend.labels-query-cache-generation-enabled", false, "Set to true to add the time the bucket index of the tenant was last updated and the generation of the TSDB head of the tenant in each ingester to the cache key of label names and values queries. The generation of the data of each tenant is reloaded in background every -query-frontend.labels-query-cache-generation-refresh-interval, so cached results may be returned for up to one refresh interval after series are created or deleted in ingesters, and for up to -blocks-storage.bucket-store.sync-interval plus one refresh interval after the bucket index is updated. Results can therefore be cached for longer with -query-frontend.results-cache-ttl-for-labels-query. The first label names and values queries of a tenant, until its generation has been loaded for the first time, aren't cached. Requires the blocks storage and the ingesters ring to be configured for the query-frontend, and isn't supported with the ingest storage.")
+	f.DurationVar(&cfg.LabelsQueryCacheGenerationRefreshInterval, "query-frontend.labels-query-cache-generation-refresh-interval", 15*time.Second, "How frequently to reload the generation of the data of tenants that recently ran label names and values queries, when -query-frontend.labels-query-cache-generation-enabled is enabled.")
 	cfg.ResultsCacheConfig.RegisterFlags(f)
 }
 
@@ -115,6 +139,18 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.LabelsQueryCacheGenerationEnabled && cfg.LabelsQueryCacheGenerationRefreshInterval <= 0 {
+		return errors.New("-query-frontend.labels-query-cache-generation-refresh-interval must be greater than 0 when -query-frontend.labels-query-cache-generation-enabled is enabled")
+	}
+
+	if cfg.SubstituteRecordingRules && cfg.RecordingRulesRefreshInterval <= 0 {
+		return errors.New("-query-frontend.recording-rules-refresh-interval must be greater than 0 when -query-frontend.substitute-recording-rules is enabled")
+	}
+
+	if cfg.SubstituteRecordingRules && cfg.RecordingRulesMinAge < 0 {
+		return errors.New("-query-frontend.recording-rules-min-age must not be negative")
+	}
+
 	if !slices.Contains(allFormats, cfg.QueryResultResponseFormat) {
 		return fmt.Errorf("unknown query result response format '%s'. Supported values: %s", cfg.QueryResultResponseFormat, strings.Join(allFormats, ", "))
 	}
@@ -253,7 +289,7 @@ func newQueryTripperware(
 		cacheKeyGenerator = NewDefaultCacheKeyGenerator(codec, cfg.SplitQueriesByInterval)
 	}
 
-	queryRangeMiddleware, queryInstantMiddleware, remoteReadMiddleware := newQueryMiddlewares(cfg, log, limits, codec, c, cacheKeyGenerator, cacheExtractor, engine, engineOpts.NoStepSubqueryIntervalFn, registerer)
+	queryRangeMiddleware, queryInstantMiddleware, remoteReadMiddleware, streamingQueryRangeMiddleware := newQueryMiddlewares(cfg, log, limits, codec, c, cacheKeyGenerator, cacheExtractor, engine, engineOpts.NoStepSubqueryIntervalFn, engineOpts.LookbackDelta, registerer)
 	requestBlocker := newRequestBlocker(limits, log, registerer)
 
 	return func(next http.RoundTripper) http.RoundTripper {
@@ -261,9 +297,12 @@ func newQueryTripperware(
 		// It means that the first roundtrippers defined in this function will be the last to be
 		// executed.
 
-		queryrange := NewLimitedParallelismRoundTripper(next, codec, limits, queryRangeMiddleware...)
-		instant := NewLimitedParallelismRoundTripper(next, codec, limits, queryInstantMiddleware...)
+		// Low priority range and instant queries share the same budget.
+		lowPriorityBudget := newLowPriorityQueryBudget()
+		queryrange := newLimitedParallelismRoundTripper(next, codec, limits, lowPriorityBudget, queryRangeMiddleware...)
+		instant := newLimitedParallelismRoundTripper(next, codec, limits, lowPriorityBudget, queryInstantMiddleware...)
 		remoteRead := NewRemoteReadRoundTripper(next, remoteReadMiddleware...)
+		streamingQueryRange := newStreamingRangeQueryRoundTripper(next, codec, log, streamingQueryRangeMiddleware...)
 
 		// Wrap next for cardinality, labels queries and all other queries.
 		// That attempts to parse "start" and "end" from the HTTP request and set them in the request's QueryDetails.
@@ -285,6 +324,7 @@ func newQueryTripperware(
 			metrics := newReadConsistencyMetrics(registerer, ingestStorageTopicOffsetsReaders)
 
 			queryrange = newReadConsistencyRoundTripper(queryrange, ingestStorageTopicOffsetsReaders, limits, log, metrics)
+			streamingQueryRange = newReadConsistencyRoundTripper(streamingQueryRange, ingestStorageTopicOffsetsReaders, limits, log, metrics)
 			instant = newReadConsistencyRoundTripper(instant, ingestStorageTopicOffsetsReaders, limits, log, metrics)
 			cardinality = newReadConsistencyRoundTripper(cardinality, ingestStorageTopicOffsetsReaders, limits, log, metrics)
 			activeSeries = newReadConsistencyRoundTripper(activeSeries, ingestStorageTopicOffsetsReaders, limits, log, metrics)
@@ -298,11 +338,12 @@ func newQueryTripperware(
 		// Look up cache as first thing after validation.
 		if cfg.CacheResults {
 			cardinality = newCardinalityQueryCacheRoundTripper(c, cacheKeyGenerator, limits, cardinality, log, registerer)
-			labels = newLabelsQueryCacheRoundTripper(c, cacheKeyGenerator, limits, labels, log, registerer)
+			labels = newLabelsQueryCacheRoundTripper(c, cacheKeyGenerator, cfg.LabelsQueryCacheGenerations, codec, cfg.SeriesDeletions, limits, labels, log, registerer)
 		}
 
 		// Validate the request before any processing.
 		queryrange = NewMetricsQueryRequestValidationRoundTripper(codec, queryrange)
+		streamingQueryRange = NewMetricsQueryRequestValidationRoundTripper(codec, streamingQueryRange)
 		instant = NewMetricsQueryRequestValidationRoundTripper(codec, instant)
 		labels = NewLabelsQueryRequestValidationRoundTripper(codec, labels)
 		series = NewLabelsQueryRequestValidationRoundTripper(codec, series)
@@ -314,6 +355,8 @@ func newQueryTripperware(
 			}
 
 			switch {
+			case IsRangeQuery(r.URL.Path) && cfg.StreamRangeQueryResults && acceptsJSONQueryResult(r):
+				return streamingQueryRange.RoundTrip(r)
 			case IsRangeQuery(r.URL.Path):
 				return queryrange.RoundTrip(r)
 			case IsInstantQuery(r.URL.Path):
@@ -349,14 +392,17 @@ func newQueryMiddlewares(
 	cacheExtractor Extractor,
 	engine *promql.Engine,
 	defaultStepFunc func(rangeMillis int64) int64,
+	lookbackDelta time.Duration,
 	registerer prometheus.Registerer,
-) (queryRangeMiddleware, queryInstantMiddleware, remoteReadMiddleware []MetricsQueryMiddleware) {
+) (queryRangeMiddleware, queryInstantMiddleware, remoteReadMiddleware, streamingQueryRangeMiddleware []MetricsQueryMiddleware) {
 	// Metric used to keep track of each middleware execution duration.
 	metrics := newInstrumentMiddlewareMetrics(registerer)
 	queryBlockerMiddleware := newQueryBlockerMiddleware(limits, log, registerer)
 	queryStatsMiddleware := newQueryStatsMiddleware(registerer, engine)
 	prom2CompatMiddleware := newProm2RangeCompatMiddleware(limits, log, registerer)
 	retryMiddlewareMetrics := newRetryMiddlewareMetrics(registerer)
+	queryCostMiddleware := newQueryCostMiddleware(cacheClient, limits, log, registerer)
+	queryRewriteMiddleware := newQueryRewriteMiddleware(limits, log, registerer)
 
 	remoteReadMiddleware = append(remoteReadMiddleware,
 		// Track query range statistics. Added first before any subsequent middleware modifies the request.
@@ -369,13 +415,38 @@ func newQueryMiddlewares(
 		// Track query range statistics. Added first before any subsequent middleware modifies the request.
 		queryStatsMiddleware,
 		newLimitsMiddleware(limits, log),
+		// Check whether the query is blocked before it's rewritten, so blocked queries can't bypass the blocker.
 		queryBlockerMiddleware,
+		newInstrumentMiddleware("query_rewrite", metrics),
+		queryRewriteMiddleware,
 		newInstrumentMiddleware("prom2_compat", metrics),
 		prom2CompatMiddleware,
 		newInstrumentMiddleware("step_align", metrics),
 		newStepAlignMiddleware(limits, log, registerer),
 	)
 
+	if cfg.SubstituteRecordingRules && cfg.RecordingRules != nil {
+		// Substitute recording rules after the step is aligned, and before the cost of the query is estimated.
+		queryRangeMiddleware = append(
+			queryRangeMiddleware,
+			newInstrumentMiddleware("recording_rule_substitution", metrics),
+			newRecordingRuleSubstitutionMiddleware(cfg.RecordingRules, cfg.RecordingRulesMinAge, lookbackDelta, limits, log, registerer),
+		)
+	}
+
+	queryRangeMiddleware = append(
+		queryRangeMiddleware,
+		// Estimate the cost of the whole query, before it's split.
+		newInstrumentMiddleware("query_cost", metrics),
+		queryCostMiddleware,
+	)
+
+	if cfg.StreamRangeQueryResults {
+		// Range queries with streamed results skip all middlewares that need to decode or merge query results,
+		// such as splitting by interval, results caching and query sharding.
+		streamingQueryRangeMiddleware = slices.Clone(queryRangeMiddleware)
+	}
+
 	if cfg.CacheResults && cfg.CacheErrors {
 		queryRangeMiddleware = append(
 			queryRangeMiddleware,
@@ -397,6 +468,7 @@ func newQueryMiddlewares(
 			cacheKeyGenerator,
 			cacheExtractor,
 			resultsCacheEnabledByOption,
+			cfg.SeriesDeletions,
 			log,
 			registerer,
 		)
@@ -407,8 +479,14 @@ func newQueryMiddlewares(
 	queryInstantMiddleware = append(queryInstantMiddleware,
 		queryStatsMiddleware,
 		newLimitsMiddleware(limits, log),
+		// Estimate the cost of the whole query, before it's split.
+		newInstrumentMiddleware("query_cost", metrics),
+		queryCostMiddleware,
 		newSplitInstantQueryByIntervalMiddleware(limits, log, engine, registerer),
+		// Check whether the query is blocked before it's rewritten, so blocked queries can't bypass the blocker.
 		queryBlockerMiddleware,
+		newInstrumentMiddleware("query_rewrite", metrics),
+		queryRewriteMiddleware,
 		newInstrumentMiddleware("prom2_compat", metrics),
 		prom2CompatMiddleware,
 	)
@@ -483,6 +561,11 @@ func newQueryMiddlewares(
 	if cfg.MaxRetries > 0 {
 		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("retry", metrics), newRetryMiddleware(log, cfg.MaxRetries, retryMiddlewareMetrics))
 		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("retry", metrics), newRetryMiddleware(log, cfg.MaxRetries, retryMiddlewareMetrics))
+
+		if cfg.StreamRangeQueryResults {
+			// Queries are only retried if they fail before the querier starts streaming the result.
+			streamingQueryRangeMiddleware = append(streamingQueryRangeMiddleware, newInstrumentMiddleware("retry", metrics), newRetryMiddleware(log, cfg.MaxRetries, retryMiddlewareMetrics))
+		}
 	}
 
 	// Does not apply to remote read as those are executed remotely and the enabling of PromQL experimental
@@ -499,6 +582,14 @@ func newQueryMiddlewares(
 		experimentalFunctionsMiddleware,
 	)
 
+	if cfg.StreamRangeQueryResults {
+		streamingQueryRangeMiddleware = append(
+			streamingQueryRangeMiddleware,
+			newInstrumentMiddleware("experimental_functions", metrics),
+			experimentalFunctionsMiddleware,
+		)
+	}
+
 	return
 }
 
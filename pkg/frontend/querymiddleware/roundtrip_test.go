@@ -597,7 +597,7 @@ func TestMiddlewaresConsistency(t *testing.T) {
 	require.NotZero(t, cfg.SplitQueriesByInterval)
 	require.NotZero(t, cfg.MaxRetries)
 
-	queryRangeMiddlewares, queryInstantMiddlewares, remoteReadMiddlewares := newQueryMiddlewares(
+	queryRangeMiddlewares, queryInstantMiddlewares, remoteReadMiddlewares, _ := newQueryMiddlewares(
 		cfg,
 		log.NewNopLogger(),
 		mockLimits{
@@ -609,6 +609,7 @@ func TestMiddlewaresConsistency(t *testing.T) {
 		nil,
 		promql.NewEngine(promql.EngineOpts{}),
 		defaultStepFunc,
+		0,
 		nil,
 	)
 
@@ -640,6 +641,8 @@ func TestMiddlewaresConsistency(t *testing.T) {
 				"experimentalFunctionsMiddleware",       // No blocking for PromQL experimental functions as it is executed remotely.
 				"prom2RangeCompatHandler",               // No rewriting Prometheus 2 subqueries to Prometheus 3
 				"spinOffSubqueriesMiddleware",           // This middleware is only for instant queries.
+				"queryCostMiddleware",                   // No cost estimation, as remote read requests don't report the series they fetched.
+				"queryRewriteMiddleware",                // No rewriting, as remote read requests don't have a PromQL query.
 			},
 		},
 	}
@@ -704,12 +707,20 @@ func TestConfig_Validate(t *testing.T) {
 			config:        Config{QueryResultResponseFormat: "something-else"},
 			expectedError: errors.New("unknown query result response format 'something-else'. Supported values: json, protobuf"),
 		},
+		"recording rules substitution without refresh interval": {
+			config:        Config{QueryResultResponseFormat: formatJSON, SubstituteRecordingRules: true},
+			expectedError: errors.New("-query-frontend.recording-rules-refresh-interval must be greater than 0 when -query-frontend.substitute-recording-rules is enabled"),
+		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
 			err := test.config.Validate()
-			require.Equal(t, test.expectedError, err)
+			if test.expectedError == nil {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expectedError.Error())
+			}
 		})
 	}
 }
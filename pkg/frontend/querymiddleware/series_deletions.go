@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+// SeriesDeletions tells whether samples of a tenant are deleted by its series deletion requests or series
+// retention rules. The results of queries reading deleted samples aren't cached, so that they never include
+// samples deleted after they've been cached. Samples deleted less than the series deletion requests refresh
+// interval ago may still be returned from the cache.
+type SeriesDeletions interface {
+	// Overlap returns whether any sample of the tenant between minT and maxT may be deleted.
+	Overlap(ctx context.Context, userID string, minT, maxT int64) (bool, error)
+}
+
+// seriesDeletionsOverlap returns whether any sample of the tenants between minT and maxT may be deleted,
+// or whether it can't be checked.
+func seriesDeletionsOverlap(ctx context.Context, deletions SeriesDeletions, tenantIDs []string, minT, maxT int64, logger log.Logger) bool {
+	for _, userID := range tenantIDs {
+		overlap, err := deletions.Overlap(ctx, userID, minT, maxT)
+		if err != nil {
+			level.Warn(spanlogger.FromContext(ctx, logger)).Log("msg", "unable to check whether the queried samples are deleted, the results cache is skipped", "user", userID, "err", err)
+			return true
+		}
+		if overlap {
+			return true
+		}
+	}
+	return false
+}
+
+// labelsQueryCacheKeyWithoutSeriesDeletions returns a keyingFunc that doesn't cache the label names and values
+// queries reading deleted samples, and otherwise returns the cache key returned by next.
+func labelsQueryCacheKeyWithoutSeriesDeletions(next keyingFunc, codec Codec, deletions SeriesDeletions, logger log.Logger) keyingFunc {
+	return func(r *http.Request) (*GenericQueryCacheKey, error) {
+		key, err := next(r)
+		if err != nil {
+			return nil, err
+		}
+
+		tenantIDs, err := tenant.TenantIDs(r.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := codec.DecodeLabelsSeriesQueryRequest(r.Context(), r)
+		if err != nil {
+			return nil, err
+		}
+
+		if seriesDeletionsOverlap(r.Context(), deletions, tenantIDs, req.GetStartOrDefault(), req.GetEndOrDefault(), logger) {
+			return nil, ErrUnsupportedRequest
+		}
+		return key, nil
+	}
+}
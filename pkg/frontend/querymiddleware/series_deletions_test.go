@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAndCacheMiddleware_ResultsCache_SeriesDeletions(t *testing.T) {
+	deletions := &mockSeriesDeletions{}
+	cacheBackend := cache.NewInstrumentedMockCache()
+	reg := prometheus.NewPedanticRegistry()
+
+	mw := newSplitAndCacheMiddleware(
+		true,
+		true,
+		24*time.Hour,
+		mockLimits{maxCacheFreshness: 10 * time.Minute, resultsCacheTTL: resultsCacheTTL, resultsCacheOutOfOrderWindowTTL: resultsCacheLowerTTL},
+		newTestPrometheusCodec(),
+		cacheBackend,
+		DefaultCacheKeyGenerator{interval: day},
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		deletions,
+		log.NewNopLogger(),
+		reg,
+	)
+
+	downstreamReqs := 0
+	rc := mw.Wrap(HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+		downstreamReqs++
+		return &PrometheusResponse{
+			Status: "success",
+			Data:   &PrometheusData{ResultType: model.ValMatrix.String(), Result: []SampleStream{}},
+		}, nil
+	}))
+
+	// The query is split into 3 days, and only the second one reads deleted samples.
+	req := NewPrometheusRangeQueryRequest(
+		"/api/v1/query_range",
+		nil,
+		parseTimeRFC3339(t, "2021-10-13T00:00:00Z").UnixMilli(),
+		parseTimeRFC3339(t, "2021-10-15T12:00:00Z").UnixMilli(),
+		(2 * time.Minute).Milliseconds(),
+		5*time.Minute,
+		parseQuery(t, "up"),
+		Options{},
+		nil,
+	)
+	deletions.minT = parseTimeRFC3339(t, "2021-10-14T10:00:00Z").UnixMilli()
+	deletions.maxT = parseTimeRFC3339(t, "2021-10-14T11:00:00Z").UnixMilli()
+
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	_, err := rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 3, downstreamReqs)
+
+	// The day reading deleted samples is never looked up from the cache.
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 4, downstreamReqs)
+
+	// The cache is skipped when the deletions can't be checked.
+	deletions.err = errors.New("bucket unavailable")
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 7, downstreamReqs)
+
+	// The skipped requests are only counted when looked up from the cache.
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
+		# TYPE cortex_frontend_query_result_cache_skipped_total counter
+		cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="series-deletions"} 5
+		cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 0
+	`), "cortex_frontend_query_result_cache_skipped_total"))
+}
+
+func TestLabelsQueryCache_RoundTripWithSeriesDeletions(t *testing.T) {
+	const userID = "user-1"
+
+	deletions := &mockSeriesDeletions{minT: 100_000, maxT: 200_000}
+	cacheBackend := cache.NewInstrumentedMockCache()
+	limits := mockLimits{resultsCacheTTLForLabelsQuery: time.Hour}
+	reg := prometheus.NewPedanticRegistry()
+	codec := NewPrometheusCodec(reg, 0, formatJSON, nil)
+
+	downstreamCalls := 0
+	downstream := RoundTripFunc(func(*http.Request) (*http.Response, error) {
+		downstreamCalls++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"status":"success","data":["foo"]}`)),
+		}, nil
+	})
+
+	rt := newLabelsQueryCacheRoundTripper(cacheBackend, DefaultCacheKeyGenerator{codec: codec}, nil, codec, deletions, limits, downstream, log.NewNopLogger(), reg)
+
+	roundTrip := func(start, end string) {
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/labels?match[]=up&start="+start+"&end="+end, nil)
+		require.NoError(t, err)
+
+		res, err := rt.RoundTrip(req.WithContext(user.InjectOrgID(context.Background(), userID)))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	// Queries not reading deleted samples are cached.
+	roundTrip("300", "400")
+	roundTrip("300", "400")
+	require.Equal(t, 1, downstreamCalls)
+	require.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	// Queries reading deleted samples are not cached.
+	roundTrip("150", "400")
+	roundTrip("150", "400")
+	require.Equal(t, 3, downstreamCalls)
+	require.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	// The cache is skipped when the deletions can't be checked.
+	deletions.err = errors.New("bucket unavailable")
+	roundTrip("300", "400")
+	require.Equal(t, 4, downstreamCalls)
+}
+
+// mockSeriesDeletions deletes the samples of all tenants between minT and maxT.
+type mockSeriesDeletions struct {
+	minT, maxT int64
+	err        error
+}
+
+func (m *mockSeriesDeletions) Overlap(_ context.Context, _ string, minT, maxT int64) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	return m.minT <= maxT && m.maxT >= minT, nil
+}
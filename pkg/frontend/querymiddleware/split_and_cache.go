@@ -34,6 +34,7 @@ const (
 	notCachableReasonUnalignedTimeRange   = "unaligned-time-range"
 	notCachableReasonTooNew               = "too-new"
 	notCachableReasonModifiersNotCachable = "has-modifiers"
+	notCachableReasonSeriesDeletions      = "series-deletions"
 )
 
 var (
@@ -69,7 +70,7 @@ func newSplitAndCacheMiddlewareMetrics(reg prometheus.Registerer) *splitAndCache
 
 	// Initialize known label values.
 	for _, reason := range []string{notCachableReasonUnalignedTimeRange, notCachableReasonTooNew,
-		notCachableReasonModifiersNotCachable} {
+		notCachableReasonModifiersNotCachable, notCachableReasonSeriesDeletions} {
 		m.queryResultCacheSkippedCount.WithLabelValues(reason)
 	}
 
@@ -95,6 +96,7 @@ type splitAndCacheMiddleware struct {
 	splitter       CacheKeyGenerator
 	extractor      Extractor
 	shouldCacheReq shouldCacheFn
+	deletions      SeriesDeletions
 
 	// Can be set from tests
 	currentTime func() time.Time
@@ -111,6 +113,7 @@ func newSplitAndCacheMiddleware(
 	splitter CacheKeyGenerator,
 	extractor Extractor,
 	shouldCacheReq shouldCacheFn,
+	deletions SeriesDeletions,
 	logger log.Logger,
 	reg prometheus.Registerer) MetricsQueryMiddleware {
 	metrics := newSplitAndCacheMiddlewareMetrics(reg)
@@ -128,6 +131,7 @@ func newSplitAndCacheMiddleware(
 			splitter:       splitter,
 			extractor:      extractor,
 			shouldCacheReq: shouldCacheReq,
+			deletions:      deletions,
 			logger:         logger,
 			currentTime:    time.Now,
 		}
@@ -163,7 +167,7 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 
 		for _, splitReq := range splitReqs {
 			// Do not try to pick response from cache at all if the request is not cachable.
-			if cachable, reason := isRequestCachable(splitReq.orig, maxCacheTime, cacheUnalignedRequests, s.logger); !cachable {
+			if cachable, reason := s.isRequestCachable(ctx, tenantIDs, splitReq.orig, maxCacheTime, cacheUnalignedRequests); !cachable {
 				level.Debug(spanLog).Log("msg", "skipping response cache as query is not cacheable", "query", splitReq.orig.GetQuery(), "reason", reason, "tenants", tenant.JoinTenantIDs(tenantIDs))
 				splitReq.downstreamRequests = []MetricsQueryRequest{splitReq.orig}
 				s.metrics.queryResultCacheSkippedCount.WithLabelValues(reason).Inc()
@@ -253,7 +257,7 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 			}
 
 			// Skip caching if the request is not cachable.
-			if cachable, _ := isRequestCachable(splitReq.orig, maxCacheTime, cacheUnalignedRequests, s.logger); !cachable {
+			if cachable, _ := s.isRequestCachable(ctx, tenantIDs, splitReq.orig, maxCacheTime, cacheUnalignedRequests); !cachable {
 				continue
 			}
 
@@ -307,6 +311,20 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 	return s.merger.MergeResponse(responses...)
 }
 
+// isRequestCachable says whether the request is eligible for caching. Requests querying samples deleted by
+// series deletion requests or series retention rules are not eligible, because their cached results could
+// include the samples deleted after they've been cached.
+func (s *splitAndCacheMiddleware) isRequestCachable(ctx context.Context, tenantIDs []string, req MetricsQueryRequest, maxCacheTime int64, cacheUnalignedRequests bool) (cachable bool, reason string) {
+	if cachable, reason := isRequestCachable(req, maxCacheTime, cacheUnalignedRequests, s.logger); !cachable {
+		return false, reason
+	}
+
+	if s.deletions != nil && seriesDeletionsOverlap(ctx, s.deletions, tenantIDs, req.GetMinT(), req.GetMaxT(), s.logger) {
+		return false, notCachableReasonSeriesDeletions
+	}
+	return true, ""
+}
+
 // splitRequestByInterval splits the given MetricsQueryRequest by configured interval. Returns the input request if splitting is disabled.
 func (s *splitAndCacheMiddleware) splitRequestByInterval(req MetricsQueryRequest) (splitRequests, error) {
 	if !s.splitEnabled {
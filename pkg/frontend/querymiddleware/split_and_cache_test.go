@@ -176,6 +176,7 @@ func TestSplitAndCacheMiddleware_SplitByInterval(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
@@ -215,6 +216,7 @@ func TestSplitAndCacheMiddleware_SplitByInterval(t *testing.T) {
 		# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 		# TYPE cortex_frontend_query_result_cache_skipped_total counter
 		cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="series-deletions"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 0
 		# HELP cortex_frontend_split_queries_total Total number of underlying query requests after the split by interval is applied.
@@ -247,6 +249,7 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 		DefaultCacheKeyGenerator{interval: day},
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
@@ -353,6 +356,7 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 		# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 		# TYPE cortex_frontend_query_result_cache_skipped_total counter
 		cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="series-deletions"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 0
 
@@ -384,6 +388,7 @@ func TestSplitAndCacheMiddleware_ResultsCacheNoStore(t *testing.T) {
 		DefaultCacheKeyGenerator{interval: day},
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysDisabled,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
@@ -479,6 +484,7 @@ func TestSplitAndCacheMiddleware_ResultsCacheNoStore(t *testing.T) {
 		# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 		# TYPE cortex_frontend_query_result_cache_skipped_total counter
 		cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="series-deletions"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 0
 
@@ -510,6 +516,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAli
 		DefaultCacheKeyGenerator{interval: day},
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
@@ -592,6 +599,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAli
 		# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 		# TYPE cortex_frontend_query_result_cache_skipped_total counter
 		cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="series-deletions"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 1
 		# HELP cortex_frontend_split_queries_total Total number of underlying query requests after the split by interval is applied.
@@ -626,6 +634,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_EnabledCachingOfStepUnalignedReque
 		DefaultCacheKeyGenerator{interval: day},
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		prometheus.NewPedanticRegistry(),
 	)
@@ -742,6 +751,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 				# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 				# TYPE cortex_frontend_query_result_cache_skipped_total counter
 				cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+				cortex_frontend_query_result_cache_skipped_total{reason="series-deletions"} 0
 				cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 2
 				cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 0
 				# HELP cortex_frontend_split_queries_total Total number of underlying query requests after the split by interval is applied.
@@ -789,6 +799,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 				keyGenerator,
 				PrometheusResponseExtractor{},
 				resultsCacheAlwaysEnabled,
+				nil,
 				log.NewNopLogger(),
 				reg,
 			)
@@ -996,6 +1007,7 @@ func TestSplitAndCacheMiddleware_ResultsCacheFuzzy(t *testing.T) {
 					DefaultCacheKeyGenerator{interval: day},
 					PrometheusResponseExtractor{},
 					resultsCacheAlwaysEnabled,
+					nil,
 					log.NewNopLogger(),
 					prometheus.NewPedanticRegistry(),
 				).Wrap(downstream)
@@ -1288,6 +1300,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ExtentsEdgeCases(t *testing.T) {
 				keyGenerator,
 				PrometheusResponseExtractor{},
 				resultsCacheAlwaysEnabled,
+				nil,
 				log.NewNopLogger(),
 				prometheus.NewPedanticRegistry(),
 			).Wrap(HandlerFunc(func(_ context.Context, req MetricsQueryRequest) (Response, error) {
@@ -1337,6 +1350,7 @@ func TestSplitAndCacheMiddleware_StoreAndFetchCacheExtents(t *testing.T) {
 		DefaultCacheKeyGenerator{interval: day},
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		prometheus.NewPedanticRegistry(),
 	).Wrap(nil).(*splitAndCacheMiddleware)
@@ -1418,6 +1432,7 @@ func TestSplitAndCacheMiddleware_WrapMultipleTimes(t *testing.T) {
 		DefaultCacheKeyGenerator{interval: day},
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		prometheus.NewPedanticRegistry(),
 	)
@@ -155,6 +155,16 @@ type QueryDetails struct {
 
 	ResultsCacheMissBytes int
 	ResultsCacheHitBytes  int
+
+	// EstimatedQueryCost is the estimated cost of the query, or 0 if no estimate is available.
+	EstimatedQueryCost int
+
+	// RewrittenQuery is the query after rewrite rules were applied, or empty if no rule matched the query.
+	RewrittenQuery string
+
+	// SubstitutedQuery is the query after recording rules were substituted in it, or empty if no recording rule
+	// was substituted. Recording rules are substituted after rewrite rules are applied.
+	SubstitutedQuery string
 }
 
 type contextKey int
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/opentracing/opentracing-go"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/querier/api"
+)
+
+// streamingRangeQueryRoundTripper sends range queries to queriers and returns the response body from the querier
+// to the client as it is received, rather than decoding the whole result and encoding it again. This allows queriers
+// to stream the result of a query as they produce it, so that neither queriers nor query-frontends need to hold the
+// whole result in memory.
+type streamingRangeQueryRoundTripper struct {
+	next       http.RoundTripper
+	codec      Codec
+	logger     log.Logger
+	middleware MetricsQueryMiddleware
+}
+
+// newStreamingRangeQueryRoundTripper creates a new roundtripper that streams range query results from queriers
+// to clients. middlewares must not read or modify the response: it's returned to the client as-is.
+func newStreamingRangeQueryRoundTripper(next http.RoundTripper, codec Codec, logger log.Logger, middlewares ...MetricsQueryMiddleware) http.RoundTripper {
+	return streamingRangeQueryRoundTripper{
+		next:       next,
+		codec:      codec,
+		logger:     logger,
+		middleware: MergeMetricsQueryMiddlewares(middlewares...),
+	}
+}
+
+func (rt streamingRangeQueryRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+
+	request, err := rt.codec.DecodeMetricsQueryRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		request.AddSpanTags(span)
+	}
+
+	response, err := rt.middleware.Wrap(HandlerFunc(rt.forward)).Do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if streamed, ok := response.(*streamedResponse); ok {
+		return streamed.response, nil
+	}
+
+	// A middleware answered the request itself, so there's nothing to stream.
+	return rt.codec.EncodeMetricsQueryResponse(ctx, r, response)
+}
+
+func (rt streamingRangeQueryRoundTripper) forward(ctx context.Context, r MetricsQueryRequest) (Response, error) {
+	request, err := rt.codec.EncodeMetricsQueryRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The querier's response is returned to the client as-is, so it must be in the format the client expects,
+	// regardless of the format configured for results we decode.
+	request.Header.Set("Accept", jsonMimeType)
+	request.Header.Set(api.StreamQueryResultHeader, "true")
+
+	if err := user.InjectOrgIDIntoHTTPRequest(ctx, request); err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	response, err := rt.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		// Decode error responses, so they're handled like errors for any other query (eg. retried if appropriate).
+		defer func() { _ = response.Body.Close() }()
+		return rt.codec.DecodeMetricsQueryResponse(ctx, response, r, rt.logger)
+	}
+
+	return &streamedResponse{response: response}, nil
+}
+
+// acceptsJSONQueryResult returns true if the client expects the result of the query in r to be encoded as JSON.
+func acceptsJSONQueryResult(r *http.Request) bool {
+	contentType, _ := prometheusCodec{}.negotiateContentType(r.Header.Get("Accept"))
+	return contentType == jsonMimeType
+}
+
+// streamedResponse is a Response holding a response from a querier that is returned to the client without
+// decoding it.
+type streamedResponse struct {
+	response *http.Response
+}
+
+func (r *streamedResponse) Reset()         {}
+func (r *streamedResponse) String() string { return "streamedResponse" }
+func (r *streamedResponse) ProtoMessage()  {}
+
+func (r *streamedResponse) GetHeaders() []*PrometheusHeader {
+	return httpHeadersToProm(r.response.Header)
+}
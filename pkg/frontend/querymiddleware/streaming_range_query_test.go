@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/require"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/querier/api"
+)
+
+func TestStreamingRangeQueryRoundTripper(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+
+	testCases := map[string]struct {
+		downstreamStatusCode int
+		downstreamBody       string
+		middleware           MetricsQueryMiddleware
+
+		expectDownstreamCalled bool
+		expectStatusCode       int
+		expectBody             string
+		expectErr              error
+	}{
+		"should return response from querier as-is": {
+			downstreamStatusCode:   http.StatusOK,
+			downstreamBody:         body,
+			expectDownstreamCalled: true,
+			expectStatusCode:       http.StatusOK,
+			expectBody:             body,
+		},
+		"should return error response from querier as an error": {
+			downstreamStatusCode:   http.StatusUnprocessableEntity,
+			downstreamBody:         `{"status":"error","errorType":"execution","error":"something went wrong"}`,
+			expectDownstreamCalled: true,
+			expectErr:              apierror.New(apierror.TypeExec, "something went wrong"),
+		},
+		"should encode response from middleware that answers the request itself": {
+			middleware: MetricsQueryMiddlewareFunc(func(MetricsQueryHandler) MetricsQueryHandler {
+				return HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+					return newEmptyPrometheusResponse(), nil
+				})
+			}),
+			expectStatusCode: http.StatusOK,
+			expectBody:       body,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			downstreamCalled := false
+			downstream := RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				downstreamCalled = true
+
+				require.Equal(t, "true", req.Header.Get(api.StreamQueryResultHeader))
+				require.Equal(t, jsonMimeType, req.Header.Get("Accept"))
+				require.Equal(t, "user-1", req.Header.Get(user.OrgIDHeaderName))
+
+				return &http.Response{
+					StatusCode: testCase.downstreamStatusCode,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(testCase.downstreamBody)),
+				}, nil
+			})
+
+			var middlewares []MetricsQueryMiddleware
+			if testCase.middleware != nil {
+				middlewares = append(middlewares, testCase.middleware)
+			}
+
+			rt := newStreamingRangeQueryRoundTripper(downstream, newTestPrometheusCodec(), log.NewNopLogger(), middlewares...)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=up&start=0&end=60&step=15", nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+
+			resp, err := rt.RoundTrip(req)
+			require.Equal(t, testCase.expectDownstreamCalled, downstreamCalled)
+
+			if testCase.expectErr != nil {
+				require.Equal(t, testCase.expectErr, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectStatusCode, resp.StatusCode)
+
+			actualBody, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, testCase.expectBody, string(actualBody))
+		})
+	}
+}
+
+func TestAcceptsJSONQueryResult(t *testing.T) {
+	for accept, expected := range map[string]bool{
+		"":                 true,
+		"application/json": true,
+		"*/*":              true,
+		"application/vnd.mimir.queryresponse+protobuf":                         false,
+		"application/vnd.mimir.queryresponse+protobuf, application/json;q=0.9": false,
+		"application/json, application/vnd.mimir.queryresponse+protobuf;q=0.9": true,
+	} {
+		t.Run(accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range", nil)
+			req.Header.Set("Accept", accept)
+			require.Equal(t, expected, acceptsJSONQueryResult(req))
+		})
+	}
+}
@@ -44,6 +44,7 @@ const (
 	cacheControlHeader           = "Cache-Control"
 	cacheControlLogField         = "header_cache_control"
 	responseQueryStatsHeaderName = "X-Mimir-Response-Query-Stats"
+	estimatedQueryCostHeaderName = "X-Mimir-Estimated-Query-Cost"
 	encodeTimeSeconds            = "encode_time_seconds"
 	estimatedSeriesCount         = "estimated_series_count"
 	fetchedChunkBytes            = "fetched_chunk_bytes"
@@ -239,6 +240,11 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	resp, err := f.roundTripper.RoundTrip(r)
 	queryResponseTime := time.Since(startTime)
 
+	// Return the estimated cost of the query even if it failed, as it may have been rejected because of it.
+	if queryDetails != nil && queryDetails.EstimatedQueryCost > 0 {
+		w.Header().Set(estimatedQueryCostHeaderName, strconv.Itoa(queryDetails.EstimatedQueryCost))
+	}
+
 	if err != nil {
 		statusCode := writeError(w, err)
 		f.reportQueryStats(r, params, startTime, queryResponseTime, 0, queryDetails, statusCode, err)
@@ -274,16 +280,46 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(resp.StatusCode)
-	// we don't check for copy error as there is no much we can do at this point
-	queryResponseSize, _ := io.Copy(w, resp.Body)
+	body := &bodyReader{r: resp.Body}
+	queryResponseSize, copyErr := io.Copy(w, body)
+	if copyErr != nil {
+		if body.err != nil {
+			level.Warn(util_log.WithContext(r.Context(), f.log)).Log("msg", "failed to read streamed response body, aborting response", "err", copyErr)
+		} else {
+			// The client has most likely gone away or cancelled the request, so there's no one left to tell.
+			level.Debug(util_log.WithContext(r.Context(), f.log)).Log("msg", "failed to write response body", "err", copyErr)
+		}
+	}
 
 	if f.cfg.LogQueriesLongerThan > 0 && queryResponseTime > f.cfg.LogQueriesLongerThan {
 		f.reportSlowQuery(r, params, queryResponseTime, queryDetails)
 	}
 	if f.cfg.QueryStatsEnabled {
-		f.reportQueryStats(r, params, startTime, queryResponseTime, queryResponseSize, queryDetails, resp.StatusCode, nil)
+		f.reportQueryStats(r, params, startTime, queryResponseTime, queryResponseSize, queryDetails, resp.StatusCode, copyErr)
 	}
 
+	if body.err != nil {
+		// The status code and part of the body may have already been sent, so the only way to signal to the client
+		// that the response is incomplete is to abort it. Only streamed response bodies can fail part way, as all
+		// other response bodies are already held in memory.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// bodyReader records the error returned by r, if any, so that failures reading a response body can be told apart
+// from failures writing it to the client.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+
+	return n, err
 }
 
 // reportSlowQuery reports slow queries.
@@ -360,6 +396,7 @@ func (f *Handler) reportQueryStats(
 		queueTimeSeconds, stats.LoadQueueTime().Seconds(),
 		encodeTimeSeconds, stats.LoadEncodeTime().Seconds(),
 		"samples_processed", stats.LoadSamplesProcessed(),
+		"spilled_bytes", stats.LoadSpilledBytes(),
 	}, formatQueryString(details, queryString)...)
 
 	if details != nil {
@@ -377,7 +414,14 @@ func (f *Handler) reportQueryStats(
 		logMessage = append(logMessage,
 			resultsCacheHitBytes, details.ResultsCacheHitBytes,
 			resultsCacheMissBytes, details.ResultsCacheMissBytes,
+			"estimated_query_cost", details.EstimatedQueryCost,
 		)
+		if details.RewrittenQuery != "" {
+			logMessage = append(logMessage, "rewritten_query", details.RewrittenQuery)
+		}
+		if details.SubstitutedQuery != "" {
+			logMessage = append(logMessage, "recording_rules_substituted_query", details.SubstitutedQuery)
+		}
 	}
 
 	// Log the read consistency only when explicitly defined.
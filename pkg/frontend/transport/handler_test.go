@@ -18,6 +18,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/go-kit/log"
@@ -666,6 +667,56 @@ func TestHandler_Stop(t *testing.T) {
 	})
 }
 
+func TestHandler_ResponseBodyCopyErrors(t *testing.T) {
+	testCases := map[string]struct {
+		body          io.Reader
+		writer        http.ResponseWriter
+		expectAborted bool
+	}{
+		"streamed response body fails part way": {
+			body:          io.MultiReader(strings.NewReader(`{"status":"success"`), iotest.ErrReader(errors.New("querier went away"))),
+			writer:        httptest.NewRecorder(),
+			expectAborted: true,
+		},
+		"client goes away while the response body is written": {
+			body:          strings.NewReader(`{"status":"success","data":{}}`),
+			writer:        failingResponseWriter{httptest.NewRecorder()},
+			expectAborted: false,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			roundTripper := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(testCase.body),
+				}, nil
+			})
+
+			handler := NewHandler(HandlerConfig{MaxBodySize: 1024}, roundTripper, log.NewNopLogger(), prometheus.NewPedanticRegistry(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=some_metric&start=0&end=60&step=15", nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), "12345"))
+
+			serve := func() { handler.ServeHTTP(testCase.writer, req) }
+			if testCase.expectAborted {
+				require.PanicsWithValue(t, http.ErrAbortHandler, serve)
+			} else {
+				require.NotPanics(t, serve)
+			}
+		})
+	}
+}
+
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
 func TestHandler_LogsFormattedQueryDetails(t *testing.T) {
 	t1 := time.UnixMilli(1698421429219)
 	t2 := t1.Add(time.Hour)
@@ -742,6 +793,23 @@ func TestHandler_LogsFormattedQueryDetails(t *testing.T) {
 				"header_cache_control":     "",
 			},
 		},
+		{
+			name:              "rewritten query",
+			requestFormFields: []string{},
+			setQueryDetails: func(d *querymiddleware.QueryDetails) {
+				d.RewrittenQuery = "job:up:sum"
+			},
+			expectedLoggedFields: map[string]string{
+				"rewritten_query": "job:up:sum",
+			},
+		},
+		{
+			name:                  "query not rewritten",
+			requestFormFields:     []string{},
+			setQueryDetails:       func(*querymiddleware.QueryDetails) {},
+			expectedLoggedFields:  map[string]string{},
+			expectedMissingFields: []string{"rewritten_query"},
+		},
 		{
 			name:              "results cache turned off on request",
 			requestFormFields: []string{},
@@ -403,6 +403,7 @@ func (f *Frontend) QueryResultStream(stream frontendv2pb.FrontendForQuerier_Quer
 	}(writer)
 
 	metadataReceived := false
+	var req *frontendRequest
 
 	for {
 		var resp *frontendv2pb.QueryResultStreamRequest
@@ -423,7 +424,7 @@ func (f *Frontend) QueryResultStream(stream frontendv2pb.FrontendForQuerier_Quer
 			if metadataReceived {
 				return fmt.Errorf("metadata for query ID %d received more than once", resp.QueryID)
 			}
-			req := f.requests.get(resp.QueryID)
+			req = f.requests.get(resp.QueryID)
 			if req == nil {
 				return fmt.Errorf("query %d not found", resp.QueryID)
 			}
@@ -456,6 +457,17 @@ func (f *Frontend) QueryResultStream(stream frontendv2pb.FrontendForQuerier_Quer
 			if err != nil {
 				return fmt.Errorf("failed to write query result body chunk: %w", err)
 			}
+		case *frontendv2pb.QueryResultStreamRequest_Trailer:
+			if !metadataReceived {
+				return fmt.Errorf("result trailer for query ID %d received before metadata", resp.QueryID)
+			}
+
+			// The body stream is only closed once we return, so the stats are merged before the body has been read completely.
+			stats.FromContext(req.ctx).Merge(d.Trailer.Stats) // Safe if stats is nil.
+
+			if d.Trailer.Error != "" {
+				return fmt.Errorf("querier failed to produce the complete query result: %s", d.Trailer.Error)
+			}
 		default:
 			return fmt.Errorf("unknown query result stream message type: %T", resp.Data)
 		}
@@ -488,6 +488,35 @@ func TestFrontendStreamingResponse(t *testing.T) {
 			expectBody:          "part 1/2",
 			expectContentLength: 16,
 		},
+		{
+			name: "metadata, body chunk and trailer",
+			sendResultStream: func(f *Frontend, msg *schedulerpb.FrontendToScheduler) error {
+				s := &mockQueryResultStreamServer{ctx: user.InjectOrgID(context.Background(), userID), queryID: msg.QueryID}
+				s.msgs = append(s.msgs,
+					metadataRequest(msg, http.StatusOK, []*httpgrpc.Header{{Key: "Content-Length", Values: []string{"8"}}}),
+					bodyChunkRequest(msg, []byte("complete")),
+					trailerRequest(msg, ""),
+				)
+				return f.QueryResultStream(s)
+			},
+			expectBody:          "complete",
+			expectContentLength: 8,
+		},
+		{
+			name: "trailer with error",
+			sendResultStream: func(f *Frontend, msg *schedulerpb.FrontendToScheduler) error {
+				s := &mockQueryResultStreamServer{ctx: user.InjectOrgID(context.Background(), userID), queryID: msg.QueryID}
+				s.msgs = append(s.msgs,
+					metadataRequest(msg, http.StatusOK, []*httpgrpc.Header{{Key: "Content-Length", Values: []string{"16"}}}),
+					bodyChunkRequest(msg, []byte("part 1/2")),
+					trailerRequest(msg, "query evaluation failed"),
+				)
+				return f.QueryResultStream(s)
+			},
+			expectStreamError:   true,
+			expectBody:          "part 1/2",
+			expectContentLength: 16,
+		},
 		{
 			name: "context cancelled while streaming response",
 			sendResultStream: func(f *Frontend, msg *schedulerpb.FrontendToScheduler) error {
@@ -577,6 +606,13 @@ func bodyChunkRequest(msg *schedulerpb.FrontendToScheduler, content []byte) *fro
 	}
 }
 
+func trailerRequest(msg *schedulerpb.FrontendToScheduler, errMsg string) *frontendv2pb.QueryResultStreamRequest {
+	return &frontendv2pb.QueryResultStreamRequest{
+		QueryID: msg.QueryID,
+		Data:    &frontendv2pb.QueryResultStreamRequest_Trailer{Trailer: &frontendv2pb.QueryResultTrailer{Stats: &stats.Stats{}, Error: errMsg}},
+	}
+}
+
 type mockQueryResultStreamServer struct {
 	ctx        context.Context
 	queryID    uint64
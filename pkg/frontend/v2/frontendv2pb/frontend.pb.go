@@ -96,6 +96,7 @@ type QueryResultStreamRequest struct {
 	// Types that are valid to be assigned to Data:
 	//	*QueryResultStreamRequest_Metadata
 	//	*QueryResultStreamRequest_Body
+	//	*QueryResultStreamRequest_Trailer
 	Data isQueryResultStreamRequest_Data `protobuf_oneof:"data"`
 }
 
@@ -144,9 +145,13 @@ type QueryResultStreamRequest_Metadata struct {
 type QueryResultStreamRequest_Body struct {
 	Body *QueryResultBody `protobuf:"bytes,3,opt,name=body,proto3,oneof" json:"body,omitempty"`
 }
+type QueryResultStreamRequest_Trailer struct {
+	Trailer *QueryResultTrailer `protobuf:"bytes,4,opt,name=trailer,proto3,oneof" json:"trailer,omitempty"`
+}
 
 func (*QueryResultStreamRequest_Metadata) isQueryResultStreamRequest_Data() {}
 func (*QueryResultStreamRequest_Body) isQueryResultStreamRequest_Data()     {}
+func (*QueryResultStreamRequest_Trailer) isQueryResultStreamRequest_Data()  {}
 
 func (m *QueryResultStreamRequest) GetData() isQueryResultStreamRequest_Data {
 	if m != nil {
@@ -176,11 +181,19 @@ func (m *QueryResultStreamRequest) GetBody() *QueryResultBody {
 	return nil
 }
 
+func (m *QueryResultStreamRequest) GetTrailer() *QueryResultTrailer {
+	if x, ok := m.GetData().(*QueryResultStreamRequest_Trailer); ok {
+		return x.Trailer
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*QueryResultStreamRequest) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*QueryResultStreamRequest_Metadata)(nil),
 		(*QueryResultStreamRequest_Body)(nil),
+		(*QueryResultStreamRequest_Trailer)(nil),
 	}
 }
 
@@ -286,13 +299,67 @@ func (m *QueryResultBody) GetChunk() []byte {
 	return nil
 }
 
+// QueryResultTrailer is sent after the last body chunk by queriers that stream the body as it's produced.
+type QueryResultTrailer struct {
+	// Statistics for the query. Queriers that send a trailer send statistics here rather than in the metadata.
+	Stats *stats.Stats `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	// If set, the querier failed to produce the rest of the body, and the response must be treated as incomplete.
+	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (m *QueryResultTrailer) Reset()      { *m = QueryResultTrailer{} }
+func (*QueryResultTrailer) ProtoMessage() {}
+func (*QueryResultTrailer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eca3873955a29cfe, []int{4}
+}
+func (m *QueryResultTrailer) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *QueryResultTrailer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_QueryResultTrailer.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *QueryResultTrailer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryResultTrailer.Merge(m, src)
+}
+func (m *QueryResultTrailer) XXX_Size() int {
+	return m.Size()
+}
+func (m *QueryResultTrailer) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryResultTrailer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryResultTrailer proto.InternalMessageInfo
+
+func (m *QueryResultTrailer) GetStats() *stats.Stats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *QueryResultTrailer) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 type QueryResultResponse struct {
 }
 
 func (m *QueryResultResponse) Reset()      { *m = QueryResultResponse{} }
 func (*QueryResultResponse) ProtoMessage() {}
 func (*QueryResultResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_eca3873955a29cfe, []int{4}
+	return fileDescriptor_eca3873955a29cfe, []int{5}
 }
 func (m *QueryResultResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -326,43 +393,47 @@ func init() {
 	proto.RegisterType((*QueryResultStreamRequest)(nil), "frontendv2pb.QueryResultStreamRequest")
 	proto.RegisterType((*QueryResultMetadata)(nil), "frontendv2pb.QueryResultMetadata")
 	proto.RegisterType((*QueryResultBody)(nil), "frontendv2pb.QueryResultBody")
+	proto.RegisterType((*QueryResultTrailer)(nil), "frontendv2pb.QueryResultTrailer")
 	proto.RegisterType((*QueryResultResponse)(nil), "frontendv2pb.QueryResultResponse")
 }
 
 func init() { proto.RegisterFile("frontend.proto", fileDescriptor_eca3873955a29cfe) }
 
 var fileDescriptor_eca3873955a29cfe = []byte{
-	// 480 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x93, 0xcf, 0x6e, 0xd3, 0x40,
-	0x10, 0xc6, 0xbd, 0x6d, 0xd2, 0xa2, 0x49, 0xc4, 0x9f, 0xa5, 0x20, 0x2b, 0x12, 0xab, 0xd4, 0x07,
-	0x88, 0x38, 0xd8, 0x28, 0x95, 0x38, 0x70, 0x41, 0x8a, 0x50, 0x15, 0x0e, 0x48, 0x74, 0x9b, 0x13,
-	0xb7, 0x75, 0xbc, 0x75, 0xac, 0x60, 0xaf, 0xbb, 0x5e, 0x23, 0xf9, 0xc6, 0x13, 0x20, 0x1e, 0x83,
-	0x33, 0x4f, 0xc1, 0x09, 0xe5, 0xd8, 0x23, 0x71, 0x2e, 0x1c, 0xfb, 0x08, 0xc8, 0x6b, 0x3b, 0x72,
-	0xa0, 0xa1, 0xb9, 0xac, 0x66, 0x34, 0xdf, 0xb7, 0xf3, 0xf3, 0x8c, 0x17, 0xee, 0x5e, 0x48, 0x11,
-	0x29, 0x1e, 0x79, 0x76, 0x2c, 0x85, 0x12, 0xb8, 0x5b, 0xe7, 0x9f, 0x86, 0xb1, 0xdb, 0x3b, 0xf2,
-	0x85, 0x2f, 0x74, 0xc1, 0x29, 0xa2, 0x52, 0xd3, 0x7b, 0xe1, 0x07, 0x6a, 0x96, 0xba, 0xf6, 0x54,
-	0x84, 0x8e, 0x2f, 0xd9, 0x05, 0x8b, 0x98, 0xe3, 0x25, 0xf3, 0x40, 0x39, 0x33, 0xa5, 0x62, 0x5f,
-	0xc6, 0xd3, 0x75, 0x50, 0x39, 0x5e, 0xde, 0xe0, 0x08, 0x83, 0x30, 0x90, 0x4e, 0x3c, 0xf7, 0x9d,
-	0xcb, 0x94, 0xcb, 0x80, 0x4b, 0x27, 0x51, 0x4c, 0x25, 0xe5, 0x59, 0xfa, 0xac, 0x2f, 0x08, 0xf0,
-	0x59, 0xca, 0x65, 0x46, 0x79, 0x92, 0x7e, 0x54, 0x94, 0x5f, 0xa6, 0x3c, 0x51, 0xd8, 0x84, 0xc3,
-	0xc2, 0x93, 0xbd, 0x7d, 0x63, 0xa2, 0x3e, 0x1a, 0xb4, 0x68, 0x9d, 0xe2, 0x57, 0xd0, 0x2d, 0x5a,
-	0x53, 0x9e, 0xc4, 0x22, 0x4a, 0xb8, 0xb9, 0xd7, 0x47, 0x83, 0xce, 0xf0, 0xb1, 0xbd, 0xe6, 0x19,
-	0x4f, 0x26, 0xef, 0xeb, 0x2a, 0xdd, 0xd0, 0x62, 0x0b, 0xda, 0xba, 0xb7, 0xb9, 0xaf, 0x4d, 0x5d,
-	0xbb, 0x24, 0x39, 0x2f, 0x4e, 0x5a, 0x96, 0xac, 0xef, 0x08, 0xcc, 0x06, 0xd0, 0xb9, 0x92, 0x9c,
-	0x85, 0xb7, 0x63, 0xbd, 0x86, 0x3b, 0x21, 0x57, 0xcc, 0x63, 0x8a, 0x55, 0x48, 0xc7, 0x76, 0x73,
-	0xd0, 0x76, 0xe3, 0xce, 0x77, 0x95, 0x70, 0x6c, 0xd0, 0xb5, 0x09, 0x9f, 0x40, 0xcb, 0x15, 0x5e,
-	0x56, 0xa1, 0x3d, 0xd9, 0x6a, 0x1e, 0x09, 0x2f, 0x1b, 0x1b, 0x54, 0x8b, 0x47, 0x07, 0xd0, 0x2a,
-	0xcc, 0x56, 0x06, 0x0f, 0x6f, 0xb8, 0x1f, 0x63, 0x68, 0x4d, 0x85, 0xc7, 0x35, 0x6b, 0x9b, 0xea,
-	0x18, 0x3f, 0x87, 0xc3, 0x19, 0x67, 0x1e, 0x97, 0x89, 0xb9, 0xd7, 0xdf, 0x1f, 0x74, 0x86, 0xf7,
-	0x1b, 0xa3, 0xd3, 0x05, 0x5a, 0x0b, 0x76, 0x9a, 0xd7, 0x33, 0xb8, 0xf7, 0x17, 0x1d, 0x3e, 0x82,
-	0xf6, 0x74, 0x96, 0x46, 0x73, 0xdd, 0xb7, 0x4b, 0xcb, 0xc4, 0x7a, 0xb4, 0xc1, 0x58, 0xef, 0x64,
-	0xf8, 0x13, 0x01, 0x3e, 0xad, 0xbe, 0xf5, 0x54, 0xc8, 0xb3, 0xf2, 0x4f, 0xc1, 0x13, 0xe8, 0x34,
-	0xd4, 0xb8, 0xbf, 0x75, 0x1e, 0xd5, 0x6a, 0x7a, 0xc7, 0xff, 0x51, 0x94, 0xad, 0x2c, 0x03, 0xbb,
-	0xf0, 0xe0, 0x9f, 0xdd, 0xe2, 0xa7, 0x5b, 0x9d, 0x1b, 0xcb, 0xdf, 0xa9, 0xc3, 0x00, 0x8d, 0x46,
-	0x8b, 0x25, 0x31, 0xae, 0x96, 0xc4, 0xb8, 0x5e, 0x12, 0xf4, 0x39, 0x27, 0xe8, 0x5b, 0x4e, 0xd0,
-	0x8f, 0x9c, 0xa0, 0x45, 0x4e, 0xd0, 0xaf, 0x9c, 0xa0, 0xdf, 0x39, 0x31, 0xae, 0x73, 0x82, 0xbe,
-	0xae, 0x88, 0xb1, 0x58, 0x11, 0xe3, 0x6a, 0x45, 0x8c, 0x0f, 0x1b, 0xaf, 0xd2, 0x3d, 0xd0, 0x8f,
-	0xe3, 0xe4, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xca, 0x18, 0x1f, 0x97, 0xbc, 0x03, 0x00, 0x00,
+	// 516 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x4d, 0x6f, 0xd3, 0x40,
+	0x10, 0xf5, 0xb6, 0x49, 0x03, 0x93, 0x88, 0x8f, 0xa5, 0x20, 0x2b, 0x12, 0xab, 0xd4, 0x07, 0x88,
+	0x38, 0xd8, 0x28, 0x95, 0x38, 0x20, 0x24, 0xa4, 0x08, 0x55, 0xe1, 0x00, 0xa2, 0xdb, 0x9c, 0x38,
+	0xb1, 0x89, 0xb7, 0x8e, 0x95, 0x26, 0xeb, 0xae, 0xd7, 0x48, 0xbe, 0xf1, 0x0b, 0x10, 0x3f, 0x83,
+	0x9f, 0x82, 0x38, 0xe5, 0xd8, 0x23, 0x71, 0x2e, 0x1c, 0xf3, 0x13, 0x90, 0xd7, 0x76, 0x70, 0x68,
+	0x4d, 0x7b, 0x59, 0xed, 0x68, 0xde, 0xdb, 0xf7, 0x66, 0xf2, 0x62, 0xb8, 0x73, 0x2a, 0xc5, 0x5c,
+	0xf1, 0xb9, 0x6b, 0x07, 0x52, 0x28, 0x81, 0x5b, 0x45, 0xfd, 0xb9, 0x17, 0x8c, 0xda, 0xfb, 0x9e,
+	0xf0, 0x84, 0x6e, 0x38, 0xe9, 0x2d, 0xc3, 0xb4, 0x9f, 0x7b, 0xbe, 0x9a, 0x44, 0x23, 0x7b, 0x2c,
+	0x66, 0x8e, 0x27, 0xd9, 0x29, 0x9b, 0x33, 0xc7, 0x0d, 0xa7, 0xbe, 0x72, 0x26, 0x4a, 0x05, 0x9e,
+	0x0c, 0xc6, 0x9b, 0x4b, 0xce, 0x78, 0x71, 0x05, 0x63, 0xe6, 0xcf, 0x7c, 0xe9, 0x04, 0x53, 0xcf,
+	0x39, 0x8f, 0xb8, 0xf4, 0xb9, 0x74, 0x42, 0xc5, 0x54, 0x98, 0x9d, 0x19, 0xcf, 0xfa, 0x8a, 0x00,
+	0x1f, 0x47, 0x5c, 0xc6, 0x94, 0x87, 0xd1, 0x99, 0xa2, 0xfc, 0x3c, 0xe2, 0xa1, 0xc2, 0x26, 0x34,
+	0x52, 0x4e, 0xfc, 0xf6, 0x8d, 0x89, 0x3a, 0xa8, 0x5b, 0xa3, 0x45, 0x89, 0x5f, 0x42, 0x2b, 0x95,
+	0xa6, 0x3c, 0x0c, 0xc4, 0x3c, 0xe4, 0xe6, 0x4e, 0x07, 0x75, 0x9b, 0xbd, 0x47, 0xf6, 0xc6, 0xcf,
+	0x60, 0x38, 0xfc, 0x50, 0x74, 0xe9, 0x16, 0x16, 0x5b, 0x50, 0xd7, 0xda, 0xe6, 0xae, 0x26, 0xb5,
+	0xec, 0xcc, 0xc9, 0x49, 0x7a, 0xd2, 0xac, 0x65, 0xad, 0x11, 0x98, 0x25, 0x43, 0x27, 0x4a, 0x72,
+	0x36, 0xbb, 0xde, 0xd6, 0x6b, 0xb8, 0x35, 0xe3, 0x8a, 0xb9, 0x4c, 0xb1, 0xdc, 0xd2, 0x81, 0x5d,
+	0x5e, 0xb4, 0x5d, 0x7a, 0xf3, 0x5d, 0x0e, 0x1c, 0x18, 0x74, 0x43, 0xc2, 0x87, 0x50, 0x1b, 0x09,
+	0x37, 0xce, 0xad, 0x3d, 0xae, 0x24, 0xf7, 0x85, 0x1b, 0x0f, 0x0c, 0xaa, 0xc1, 0xf8, 0x15, 0x34,
+	0x94, 0x64, 0xfe, 0x19, 0x97, 0x66, 0x4d, 0xf3, 0x3a, 0x95, 0xbc, 0x61, 0x86, 0x1b, 0x18, 0xb4,
+	0xa0, 0xf4, 0xf7, 0xa0, 0x96, 0x4a, 0x5b, 0x31, 0x3c, 0xb8, 0xc2, 0x1d, 0xc6, 0x50, 0x1b, 0x0b,
+	0x97, 0xeb, 0x49, 0xeb, 0x54, 0xdf, 0xf1, 0x33, 0x68, 0x4c, 0x38, 0x73, 0xb9, 0x0c, 0xcd, 0x9d,
+	0xce, 0x6e, 0xb7, 0xd9, 0xbb, 0x57, 0x5a, 0xbc, 0x6e, 0xd0, 0x02, 0x70, 0xa3, 0x6d, 0x3f, 0x85,
+	0xbb, 0xff, 0xcc, 0x86, 0xf7, 0xa1, 0x3e, 0x9e, 0x44, 0xf3, 0xa9, 0xd6, 0x6d, 0xd1, 0xac, 0xb0,
+	0xde, 0x03, 0xbe, 0x3c, 0xcc, 0x5f, 0x09, 0x54, 0x29, 0x91, 0xbe, 0xc7, 0xa5, 0x14, 0x52, 0xff,
+	0x2c, 0xb7, 0x69, 0x56, 0x58, 0x0f, 0xb7, 0x66, 0x2e, 0x12, 0xd2, 0xfb, 0x89, 0x00, 0x1f, 0xe5,
+	0x1b, 0x3c, 0x12, 0xf2, 0x38, 0xcb, 0x2d, 0xa6, 0xd0, 0x2c, 0xa1, 0x71, 0xf5, 0x96, 0xf3, 0xa0,
+	0xb4, 0x0f, 0xfe, 0x83, 0xc8, 0xc3, 0xf8, 0x09, 0xee, 0x5f, 0xca, 0x19, 0x7e, 0x52, 0xc9, 0xdb,
+	0x0a, 0xe2, 0x0d, 0xde, 0xef, 0xa2, 0x7e, 0x7f, 0xb1, 0x24, 0xc6, 0xc5, 0x92, 0x18, 0xeb, 0x25,
+	0x41, 0x5f, 0x12, 0x82, 0xbe, 0x27, 0x04, 0xfd, 0x48, 0x08, 0x5a, 0x24, 0x04, 0xfd, 0x4a, 0x08,
+	0xfa, 0x9d, 0x10, 0x63, 0x9d, 0x10, 0xf4, 0x6d, 0x45, 0x8c, 0xc5, 0x8a, 0x18, 0x17, 0x2b, 0x62,
+	0x7c, 0xdc, 0xfa, 0x3e, 0x8c, 0xf6, 0xf4, 0xdf, 0xf4, 0xf0, 0xcf, 0x00, 0x84, 0x1e, 0x5b, 0x12,
+	0x46, 0x04, 0x00, 0x00,
 }
 
 func (this *QueryResultRequest) Equal(that interface{}) bool {
@@ -476,6 +547,30 @@ func (this *QueryResultStreamRequest_Body) Equal(that interface{}) bool {
 	}
 	return true
 }
+func (this *QueryResultStreamRequest_Trailer) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*QueryResultStreamRequest_Trailer)
+	if !ok {
+		that2, ok := that.(QueryResultStreamRequest_Trailer)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.Trailer.Equal(that1.Trailer) {
+		return false
+	}
+	return true
+}
 func (this *QueryResultMetadata) Equal(that interface{}) bool {
 	if that == nil {
 		return this == nil
@@ -535,6 +630,33 @@ func (this *QueryResultBody) Equal(that interface{}) bool {
 	}
 	return true
 }
+func (this *QueryResultTrailer) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*QueryResultTrailer)
+	if !ok {
+		that2, ok := that.(QueryResultTrailer)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.Stats.Equal(that1.Stats) {
+		return false
+	}
+	if this.Error != that1.Error {
+		return false
+	}
+	return true
+}
 func (this *QueryResultResponse) Equal(that interface{}) bool {
 	if that == nil {
 		return this == nil
@@ -576,7 +698,7 @@ func (this *QueryResultStreamRequest) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 7)
+	s := make([]string, 0, 8)
 	s = append(s, "&frontendv2pb.QueryResultStreamRequest{")
 	s = append(s, "QueryID: "+fmt.Sprintf("%#v", this.QueryID)+",\n")
 	if this.Data != nil {
@@ -601,6 +723,14 @@ func (this *QueryResultStreamRequest_Body) GoString() string {
 		`Body:` + fmt.Sprintf("%#v", this.Body) + `}`}, ", ")
 	return s
 }
+func (this *QueryResultStreamRequest_Trailer) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&frontendv2pb.QueryResultStreamRequest_Trailer{` +
+		`Trailer:` + fmt.Sprintf("%#v", this.Trailer) + `}`}, ", ")
+	return s
+}
 func (this *QueryResultMetadata) GoString() string {
 	if this == nil {
 		return "nil"
@@ -627,6 +757,19 @@ func (this *QueryResultBody) GoString() string {
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
+func (this *QueryResultTrailer) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&frontendv2pb.QueryResultTrailer{")
+	if this.Stats != nil {
+		s = append(s, "Stats: "+fmt.Sprintf("%#v", this.Stats)+",\n")
+	}
+	s = append(s, "Error: "+fmt.Sprintf("%#v", this.Error)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
 func (this *QueryResultResponse) GoString() string {
 	if this == nil {
 		return "nil"
@@ -927,6 +1070,27 @@ func (m *QueryResultStreamRequest_Body) MarshalToSizedBuffer(dAtA []byte) (int,
 	}
 	return len(dAtA) - i, nil
 }
+func (m *QueryResultStreamRequest_Trailer) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *QueryResultStreamRequest_Trailer) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Trailer != nil {
+		{
+			size, err := m.Trailer.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintFrontend(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	return len(dAtA) - i, nil
+}
 func (m *QueryResultMetadata) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -1011,6 +1175,48 @@ func (m *QueryResultBody) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *QueryResultTrailer) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *QueryResultTrailer) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *QueryResultTrailer) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Error) > 0 {
+		i -= len(m.Error)
+		copy(dAtA[i:], m.Error)
+		i = encodeVarintFrontend(dAtA, i, uint64(len(m.Error)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Stats != nil {
+		{
+			size, err := m.Stats.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintFrontend(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *QueryResultResponse) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -1104,6 +1310,18 @@ func (m *QueryResultStreamRequest_Body) Size() (n int) {
 	}
 	return n
 }
+func (m *QueryResultStreamRequest_Trailer) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Trailer != nil {
+		l = m.Trailer.Size()
+		n += 1 + l + sovFrontend(uint64(l))
+	}
+	return n
+}
 func (m *QueryResultMetadata) Size() (n int) {
 	if m == nil {
 		return 0
@@ -1139,6 +1357,23 @@ func (m *QueryResultBody) Size() (n int) {
 	return n
 }
 
+func (m *QueryResultTrailer) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Stats != nil {
+		l = m.Stats.Size()
+		n += 1 + l + sovFrontend(uint64(l))
+	}
+	l = len(m.Error)
+	if l > 0 {
+		n += 1 + l + sovFrontend(uint64(l))
+	}
+	return n
+}
+
 func (m *QueryResultResponse) Size() (n int) {
 	if m == nil {
 		return 0
@@ -1197,6 +1432,16 @@ func (this *QueryResultStreamRequest_Body) String() string {
 	}, "")
 	return s
 }
+func (this *QueryResultStreamRequest_Trailer) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&QueryResultStreamRequest_Trailer{`,
+		`Trailer:` + strings.Replace(fmt.Sprintf("%v", this.Trailer), "QueryResultTrailer", "QueryResultTrailer", 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
 func (this *QueryResultMetadata) String() string {
 	if this == nil {
 		return "nil"
@@ -1224,6 +1469,17 @@ func (this *QueryResultBody) String() string {
 	}, "")
 	return s
 }
+func (this *QueryResultTrailer) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&QueryResultTrailer{`,
+		`Stats:` + strings.Replace(fmt.Sprintf("%v", this.Stats), "Stats", "stats.Stats", 1) + `,`,
+		`Error:` + fmt.Sprintf("%v", this.Error) + `,`,
+		`}`,
+	}, "")
+	return s
+}
 func (this *QueryResultResponse) String() string {
 	if this == nil {
 		return "nil"
@@ -1500,6 +1756,41 @@ func (m *QueryResultStreamRequest) Unmarshal(dAtA []byte) error {
 			}
 			m.Data = &QueryResultStreamRequest_Body{v}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Trailer", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFrontend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthFrontend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthFrontend
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &QueryResultTrailer{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Data = &QueryResultStreamRequest_Trailer{v}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFrontend(dAtA[iNdEx:])
@@ -1744,6 +2035,124 @@ func (m *QueryResultBody) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *QueryResultTrailer) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowFrontend
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: QueryResultTrailer: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: QueryResultTrailer: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Stats", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFrontend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthFrontend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthFrontend
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Stats == nil {
+				m.Stats = &stats.Stats{}
+			}
+			if err := m.Stats.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Error", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFrontend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFrontend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthFrontend
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Error = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipFrontend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthFrontend
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func (m *QueryResultResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
@@ -1121,6 +1121,7 @@ type UserStatsResponse struct {
 	NumSeries         uint64  `protobuf:"varint,2,opt,name=num_series,json=numSeries,proto3" json:"num_series,omitempty"`
 	ApiIngestionRate  float64 `protobuf:"fixed64,3,opt,name=api_ingestion_rate,json=apiIngestionRate,proto3" json:"api_ingestion_rate,omitempty"`
 	RuleIngestionRate float64 `protobuf:"fixed64,4,opt,name=rule_ingestion_rate,json=ruleIngestionRate,proto3" json:"rule_ingestion_rate,omitempty"`
+	HeadGeneration    uint64  `protobuf:"varint,5,opt,name=head_generation,json=headGeneration,proto3" json:"head_generation,omitempty"`
 }
 
 func (m *UserStatsResponse) Reset()      { *m = UserStatsResponse{} }
@@ -1183,6 +1184,13 @@ func (m *UserStatsResponse) GetRuleIngestionRate() float64 {
 	return 0
 }
 
+func (m *UserStatsResponse) GetHeadGeneration() uint64 {
+	if m != nil {
+		return m.HeadGeneration
+	}
+	return 0
+}
+
 type UserIDStatsResponse struct {
 	UserId string             `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Data   *UserStatsResponse `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
@@ -1805,118 +1813,117 @@ func init() {
 func init() { proto.RegisterFile("ingester.proto", fileDescriptor_60f6df4f3586b478) }
 
 var fileDescriptor_60f6df4f3586b478 = []byte{
-	// 1762 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0xcd, 0x6f, 0x1b, 0xc7,
-	0x15, 0xe7, 0xf0, 0x2b, 0xe2, 0x23, 0x25, 0x51, 0x43, 0x49, 0x64, 0x56, 0xd1, 0x4a, 0xd9, 0xc2,
-	0x09, 0x9b, 0x26, 0x92, 0xbf, 0x1a, 0x38, 0x69, 0x8a, 0x82, 0x92, 0x19, 0x8b, 0x4e, 0x28, 0x29,
-	0x4b, 0xc9, 0xfd, 0x00, 0x8c, 0xc5, 0x92, 0x1c, 0x49, 0x0b, 0x71, 0x97, 0xec, 0xee, 0xd0, 0xb0,
-	0x7c, 0xea, 0xa9, 0xe7, 0xfe, 0x01, 0xbd, 0xf4, 0x56, 0xf4, 0x5c, 0xa0, 0x97, 0xfe, 0x01, 0xbe,
-	0x14, 0xf0, 0xa1, 0x40, 0x8d, 0x02, 0x35, 0x6a, 0xf9, 0xd2, 0xde, 0x0c, 0xf4, 0x1f, 0x28, 0x76,
-	0x66, 0xf6, 0x93, 0xa4, 0x28, 0x19, 0xb1, 0x4f, 0xe4, 0xbc, 0xaf, 0xf9, 0xbd, 0x37, 0x6f, 0xde,
-	0x7b, 0x3b, 0x30, 0x67, 0x58, 0xc7, 0xc4, 0xa1, 0xc4, 0xde, 0x18, 0xd8, 0x7d, 0xda, 0xc7, 0xd9,
-	0x4e, 0xdf, 0xa6, 0xe4, 0xb1, 0xf4, 0xd9, 0xb1, 0x41, 0x4f, 0x86, 0xed, 0x8d, 0x4e, 0xdf, 0xdc,
-	0x3c, 0xee, 0x1f, 0xf7, 0x37, 0x19, 0xbb, 0x3d, 0x3c, 0x62, 0x2b, 0xb6, 0x60, 0xff, 0xb8, 0x9a,
-	0x74, 0x3d, 0x2c, 0x6e, 0xeb, 0x47, 0xba, 0xa5, 0x6f, 0x9a, 0x86, 0x69, 0xd8, 0x9b, 0x83, 0xd3,
-	0x63, 0xfe, 0x6f, 0xd0, 0xe6, 0xbf, 0x5c, 0x43, 0xf9, 0x2d, 0x02, 0xe9, 0x5b, 0xbd, 0x4d, 0x7a,
-	0xbb, 0xba, 0x49, 0x9c, 0x9a, 0xd5, 0x7d, 0xa0, 0xf7, 0x86, 0xc4, 0x51, 0xc9, 0xaf, 0x87, 0xc4,
-	0xa1, 0xf8, 0x3a, 0xcc, 0x98, 0x3a, 0xed, 0x9c, 0x10, 0xdb, 0xa9, 0xa0, 0xf5, 0x54, 0x35, 0x7f,
-	0x73, 0x71, 0x83, 0x43, 0xdb, 0x60, 0x5a, 0x4d, 0xce, 0x54, 0x7d, 0x29, 0xfc, 0x39, 0x14, 0x3a,
-	0xfd, 0xa1, 0x45, 0x35, 0x93, 0xd0, 0x93, 0x7e, 0xb7, 0x92, 0x5c, 0x47, 0xd5, 0xb9, 0x9b, 0x25,
-	0x4f, 0x6b, 0xdb, 0xe5, 0x35, 0x19, 0x4b, 0xcd, 0x77, 0x82, 0x85, 0xb2, 0x03, 0x2b, 0x63, 0x71,
-	0x38, 0x83, 0xbe, 0xe5, 0x10, 0xfc, 0x43, 0xc8, 0x18, 0x94, 0x98, 0x1e, 0x8a, 0x52, 0x04, 0x85,
-	0x90, 0xe5, 0x12, 0xca, 0x5d, 0xc8, 0x87, 0xa8, 0x78, 0x15, 0xa0, 0xe7, 0x2e, 0x35, 0x4b, 0x37,
-	0x49, 0x05, 0xad, 0xa3, 0x6a, 0x4e, 0xcd, 0xf5, 0xbc, 0xad, 0xf0, 0x32, 0x64, 0x1f, 0x31, 0xc1,
-	0x4a, 0x72, 0x3d, 0x55, 0xcd, 0xa9, 0x62, 0xa5, 0xfc, 0x09, 0xc1, 0x6a, 0xc8, 0xcc, 0xb6, 0x6e,
-	0x77, 0x0d, 0x4b, 0xef, 0x19, 0xf4, 0xcc, 0x8b, 0xcd, 0x1a, 0xe4, 0x03, 0xc3, 0x1c, 0x58, 0x4e,
-	0x05, 0xdf, 0xb2, 0x13, 0x09, 0x5e, 0xf2, 0x8d, 0x82, 0x97, 0xba, 0x64, 0xf0, 0x0e, 0x41, 0x9e,
-	0x84, 0x55, 0xc4, 0xef, 0x56, 0x34, 0x7e, 0xab, 0xa3, 0xf1, 0x6b, 0x11, 0xdb, 0x20, 0x0e, 0xdb,
-	0xc2, 0x8b, 0xe4, 0x0b, 0x04, 0x4b, 0x63, 0x05, 0xa6, 0x05, 0x55, 0x07, 0xcc, 0xd9, 0x2c, 0x98,
-	0x9a, 0xc3, 0x34, 0x45, 0x0c, 0x6e, 0x5d, 0xb8, 0xf5, 0x08, 0xb5, 0x6e, 0x51, 0xfb, 0x4c, 0x2d,
-	0xf6, 0x62, 0x64, 0x69, 0x7b, 0x14, 0x1a, 0x13, 0xc5, 0x45, 0x48, 0x9d, 0x92, 0x33, 0x81, 0xc9,
-	0xfd, 0x8b, 0x17, 0x21, 0xc3, 0x70, 0xb0, 0x5c, 0x4c, 0xab, 0x7c, 0xf1, 0x65, 0xf2, 0x0e, 0x52,
-	0xfe, 0x81, 0xa0, 0xf0, 0xdd, 0x90, 0xd8, 0xfe, 0x99, 0x7e, 0x0a, 0xd8, 0xa1, 0xba, 0x4d, 0x35,
-	0x6a, 0x98, 0xc4, 0xa1, 0xba, 0x39, 0xd0, 0x58, 0xcc, 0x50, 0x35, 0xa5, 0x16, 0x19, 0xe7, 0xc0,
-	0x63, 0x34, 0x1d, 0x5c, 0x85, 0x22, 0xb1, 0xba, 0x51, 0xd9, 0x24, 0x93, 0x9d, 0x23, 0x56, 0x37,
-	0x2c, 0x19, 0x4e, 0x85, 0xd4, 0xa5, 0x52, 0xe1, 0xa7, 0xb0, 0xe2, 0x50, 0x9b, 0xe8, 0xa6, 0x61,
-	0x1d, 0x6b, 0x9d, 0x93, 0xa1, 0x75, 0xea, 0x68, 0x6d, 0x97, 0xa9, 0x39, 0xc6, 0x13, 0x52, 0xe9,
-	0x32, 0x57, 0x2a, 0xbe, 0xc8, 0x36, 0x93, 0xd8, 0x72, 0x05, 0x5a, 0xc6, 0x13, 0xa2, 0xfc, 0x01,
-	0xc1, 0x62, 0xfd, 0x31, 0x31, 0x07, 0x3d, 0xdd, 0x7e, 0x27, 0x1e, 0xde, 0x18, 0xf1, 0x70, 0x69,
-	0x9c, 0x87, 0x4e, 0xe0, 0xa2, 0xf2, 0x57, 0x04, 0xa5, 0x5a, 0x87, 0x1a, 0x8f, 0xc4, 0xf9, 0xbd,
-	0x79, 0xd1, 0xf9, 0x09, 0xa4, 0xe9, 0xd9, 0x80, 0x88, 0x62, 0xf3, 0xb1, 0x27, 0x3d, 0xc6, 0xf8,
-	0x86, 0xf8, 0x3d, 0x38, 0x1b, 0x10, 0x95, 0x29, 0x29, 0x9f, 0x43, 0x3e, 0x44, 0xc4, 0x00, 0xd9,
-	0x56, 0x5d, 0x6d, 0xd4, 0x5b, 0xc5, 0x04, 0x5e, 0x81, 0xf2, 0x6e, 0xed, 0xa0, 0xf1, 0xa0, 0xae,
-	0xed, 0x34, 0x5a, 0x07, 0x7b, 0xf7, 0xd4, 0x5a, 0x53, 0x13, 0x4c, 0xa4, 0x7c, 0x03, 0xb3, 0x22,
-	0xb2, 0xe2, 0x8e, 0x7d, 0x09, 0xc0, 0x02, 0xc5, 0xb3, 0x3d, 0x8a, 0x7c, 0xd0, 0xde, 0x70, 0xa3,
-	0xc5, 0xb1, 0x6c, 0xa5, 0x9f, 0xbe, 0x58, 0x4b, 0xa8, 0x21, 0x69, 0xe5, 0x7f, 0x49, 0x28, 0x31,
-	0x6b, 0x2d, 0x76, 0xa2, 0xbe, 0xcd, 0x9f, 0x41, 0x9e, 0x1f, 0x7e, 0xd8, 0x68, 0xd9, 0x73, 0x30,
-	0x30, 0xc9, 0xce, 0x5f, 0xd8, 0x0d, 0x6b, 0xc4, 0x40, 0x25, 0xaf, 0x02, 0x0a, 0xdf, 0x87, 0x62,
-	0x90, 0x83, 0xc2, 0x02, 0x3f, 0xdb, 0xf7, 0x3d, 0x04, 0x21, 0xcc, 0x11, 0x33, 0xf3, 0xbe, 0x22,
-	0x27, 0xe3, 0xdb, 0x50, 0x36, 0x1c, 0xcd, 0x4d, 0xa6, 0xfe, 0x91, 0xb0, 0xa5, 0x71, 0x99, 0x4a,
-	0x7a, 0x1d, 0x55, 0x67, 0xd4, 0x92, 0xe1, 0xd4, 0xad, 0xee, 0xde, 0x11, 0x97, 0xe7, 0x26, 0xf1,
-	0x43, 0x28, 0xc7, 0x11, 0x88, 0xcb, 0x50, 0xc9, 0x30, 0x20, 0x6b, 0x13, 0x81, 0x88, 0x1b, 0xc1,
-	0xe1, 0x2c, 0xc5, 0xe0, 0x70, 0xa6, 0xf2, 0x7b, 0x04, 0x0b, 0x23, 0x8a, 0xf8, 0x08, 0xb2, 0xac,
-	0xdc, 0xc4, 0x9b, 0xcd, 0xa0, 0xcd, 0xf3, 0x6f, 0x5f, 0x37, 0xec, 0xad, 0x2f, 0x5c, 0xbb, 0xff,
-	0x7c, 0xb1, 0x76, 0xe3, 0x32, 0x2d, 0x97, 0xeb, 0xd5, 0xba, 0xfa, 0x80, 0x12, 0x5b, 0x15, 0xd6,
-	0xdd, 0x06, 0xc2, 0x7c, 0xd1, 0x58, 0x29, 0x17, 0xf7, 0x0a, 0x18, 0x89, 0xd5, 0x42, 0xc5, 0x80,
-	0xf2, 0x04, 0xb7, 0xf0, 0x87, 0x50, 0x10, 0xe1, 0x30, 0xac, 0x2e, 0x79, 0xcc, 0x2e, 0x70, 0x5a,
-	0xcd, 0x73, 0x5a, 0xc3, 0x25, 0xe1, 0x1f, 0x41, 0x56, 0x84, 0x8a, 0x9f, 0xfa, 0xac, 0xdf, 0x46,
-	0x42, 0xb9, 0x22, 0x44, 0x94, 0x16, 0x2c, 0xc5, 0xca, 0xc5, 0xf7, 0x90, 0xd4, 0x7f, 0x47, 0x80,
-	0xc3, 0x0d, 0x5a, 0xdc, 0xef, 0x29, 0xcd, 0x63, 0x7c, 0x85, 0x4a, 0x5e, 0xa1, 0x42, 0xa5, 0xa6,
-	0x56, 0x28, 0x37, 0xe5, 0xa6, 0x57, 0x28, 0xb7, 0x73, 0xf4, 0x0c, 0xd3, 0xa0, 0x95, 0x0c, 0xb3,
-	0xc8, 0x17, 0xca, 0x1d, 0x28, 0x45, 0xbc, 0x12, 0x91, 0xfa, 0x10, 0x0a, 0xa1, 0xa6, 0xe7, 0x0d,
-	0x04, 0xf9, 0xa0, 0x73, 0x39, 0xca, 0x9f, 0x11, 0x2c, 0x04, 0x53, 0xce, 0xbb, 0x2d, 0xc9, 0x57,
-	0x73, 0x38, 0x1d, 0x76, 0xf8, 0xc7, 0xe2, 0x18, 0x05, 0x6a, 0xe1, 0xef, 0xb4, 0xf9, 0x47, 0xb9,
-	0x0f, 0xc5, 0x43, 0x87, 0xd8, 0x2d, 0xaa, 0x53, 0xdf, 0xd7, 0xf8, 0x84, 0x83, 0x2e, 0x39, 0xe1,
-	0xfc, 0x05, 0xc1, 0x42, 0xc8, 0x98, 0x80, 0x70, 0xcd, 0x1b, 0x9c, 0x8d, 0xbe, 0xa5, 0xd9, 0x3a,
-	0xe5, 0xd9, 0x84, 0xd4, 0x59, 0x9f, 0xaa, 0xea, 0x94, 0xb8, 0x09, 0x67, 0x0d, 0xcd, 0x60, 0x0c,
-	0x71, 0xaf, 0x4a, 0xce, 0x1a, 0x7a, 0xf7, 0xfd, 0x53, 0xc0, 0xfa, 0xc0, 0xd0, 0x62, 0x96, 0x52,
-	0xcc, 0x52, 0x51, 0x1f, 0x18, 0x8d, 0x88, 0xb1, 0x0d, 0x28, 0xd9, 0xc3, 0x1e, 0x89, 0x8b, 0xa7,
-	0x99, 0xf8, 0x82, 0xcb, 0x8a, 0xc8, 0x2b, 0x0f, 0xa1, 0xe4, 0x02, 0x6f, 0xdc, 0x8d, 0x42, 0x2f,
-	0xc3, 0x7b, 0x43, 0x87, 0xd8, 0x9a, 0xd1, 0x15, 0x37, 0x20, 0xeb, 0x2e, 0x1b, 0x5d, 0xfc, 0x19,
-	0xa4, 0xbb, 0x3a, 0xd5, 0x19, 0xcc, 0x50, 0xa1, 0x1d, 0x71, 0x5e, 0x65, 0x62, 0xca, 0x3d, 0xc0,
-	0x2e, 0xcb, 0x89, 0x5a, 0xbf, 0x01, 0x19, 0xc7, 0x25, 0x88, 0x0b, 0xbb, 0x12, 0xb6, 0x12, 0x43,
-	0xa2, 0x72, 0x49, 0xe5, 0x29, 0x02, 0xb9, 0x49, 0xa8, 0x6d, 0x74, 0x9c, 0xaf, 0xfb, 0x76, 0x34,
-	0x41, 0xde, 0x72, 0xa2, 0xde, 0x81, 0x82, 0x97, 0x81, 0x9a, 0x43, 0xe8, 0xc5, 0xf3, 0x43, 0xde,
-	0x13, 0x6d, 0x11, 0x3a, 0x21, 0x5f, 0xbf, 0x81, 0xb5, 0x89, 0x9e, 0x88, 0x00, 0x55, 0x21, 0x6b,
-	0x32, 0x11, 0x11, 0xa1, 0x62, 0x50, 0xd2, 0xb8, 0xaa, 0x2a, 0xf8, 0xca, 0x00, 0x96, 0x85, 0xb1,
-	0x26, 0xa1, 0xba, 0x1b, 0x73, 0x2f, 0x1c, 0xfe, 0xe6, 0x6e, 0x04, 0x16, 0xc4, 0xe6, 0xae, 0xdb,
-	0xec, 0x8f, 0x36, 0x20, 0xb6, 0x26, 0xf6, 0x48, 0x32, 0x81, 0x39, 0x46, 0xdf, 0x27, 0x36, 0xb7,
-	0xe7, 0x7e, 0x7a, 0x08, 0x7e, 0x8a, 0x67, 0x80, 0xd8, 0x71, 0x0f, 0xca, 0x23, 0x3b, 0x0a, 0xd8,
-	0xb7, 0x61, 0xc6, 0x14, 0x34, 0x01, 0xbc, 0x12, 0x07, 0xee, 0xeb, 0xf8, 0x92, 0x4a, 0x07, 0x16,
-	0xa3, 0xa3, 0xd0, 0x55, 0x83, 0xe0, 0xd6, 0xb6, 0xf6, 0xb0, 0x73, 0x4a, 0xa8, 0xdf, 0xab, 0x52,
-	0x6e, 0xbb, 0xe1, 0x34, 0xde, 0xac, 0xfe, 0x8b, 0x60, 0x3e, 0x36, 0x8f, 0xb8, 0xb1, 0x38, 0xb2,
-	0xfb, 0xa6, 0xe6, 0x7d, 0xdd, 0x06, 0xd9, 0x3e, 0xe7, 0xd2, 0x1b, 0x82, 0xdc, 0xe8, 0x86, 0xaf,
-	0x43, 0x32, 0x72, 0x1d, 0x82, 0x66, 0x9c, 0x7a, 0xab, 0xcd, 0x38, 0xe8, 0x96, 0xe9, 0xe9, 0xdd,
-	0xf2, 0x6f, 0x08, 0x32, 0xdc, 0xc3, 0xb7, 0x75, 0x25, 0x24, 0x98, 0x21, 0x56, 0xa7, 0xdf, 0x35,
-	0xac, 0x63, 0x96, 0x1d, 0x19, 0xd5, 0x5f, 0xe3, 0x7d, 0x51, 0x21, 0xdc, 0x9c, 0x2f, 0x6c, 0x7d,
-	0x25, 0x7c, 0xbf, 0x7d, 0x29, 0xdf, 0x0f, 0x2d, 0x47, 0x3f, 0x22, 0x5b, 0x67, 0x94, 0xb4, 0x7a,
-	0x46, 0xc7, 0x2b, 0x22, 0x35, 0x98, 0x8d, 0x5c, 0x93, 0xab, 0x8f, 0xe0, 0x8a, 0x06, 0x85, 0x30,
-	0x07, 0x5f, 0x13, 0x23, 0x39, 0x2f, 0xf0, 0x0b, 0x9e, 0x36, 0x63, 0x07, 0xc3, 0x37, 0xc6, 0x90,
-	0x66, 0x53, 0x00, 0x3f, 0x74, 0xf6, 0x3f, 0xf8, 0x5e, 0xe3, 0xd7, 0x82, 0x2f, 0x3e, 0xa9, 0x42,
-	0x3e, 0xd4, 0x1d, 0xf0, 0x2c, 0xe4, 0x1a, 0xbb, 0x5a, 0xb3, 0xde, 0xdc, 0x53, 0x7f, 0x59, 0x4c,
-	0xb8, 0x53, 0x7b, 0x6d, 0xdb, 0x9d, 0xd4, 0x8b, 0xe8, 0x93, 0xfb, 0x90, 0xf3, 0xb7, 0xc1, 0x39,
-	0xc8, 0xd4, 0xbf, 0x3b, 0xac, 0x7d, 0x5b, 0x4c, 0xb8, 0x2a, 0xbb, 0x7b, 0x07, 0x1a, 0x5f, 0x22,
-	0x3c, 0x0f, 0x79, 0xb5, 0x7e, 0xaf, 0xfe, 0x0b, 0xad, 0x59, 0x3b, 0xd8, 0xde, 0x29, 0x26, 0x31,
-	0x86, 0x39, 0x4e, 0xd8, 0xdd, 0x13, 0xb4, 0xd4, 0xcd, 0x7f, 0xbd, 0x07, 0x33, 0x5e, 0x9a, 0xe2,
-	0x2f, 0x20, 0xbd, 0x3f, 0x74, 0x4e, 0xf0, 0x72, 0x90, 0x83, 0x3f, 0xb7, 0x0d, 0x4a, 0x44, 0x41,
-	0x90, 0xca, 0x23, 0x74, 0x7e, 0xd1, 0x94, 0x04, 0xbe, 0x0b, 0xf9, 0xd0, 0x28, 0x87, 0x17, 0x23,
-	0x63, 0xab, 0xa7, 0xbf, 0x32, 0x66, 0x98, 0x0d, 0x6c, 0x5c, 0x47, 0x78, 0x0f, 0xe6, 0x18, 0xcb,
-	0x1b, 0xd5, 0x1c, 0xfc, 0x81, 0xa7, 0x32, 0xee, 0x63, 0x4f, 0x5a, 0x9d, 0xc0, 0xf5, 0x61, 0xed,
-	0x44, 0xdf, 0x4a, 0xa4, 0x71, 0xcf, 0x2a, 0x71, 0x70, 0x63, 0x66, 0x1f, 0x25, 0x81, 0xeb, 0x00,
-	0xc1, 0x8c, 0x80, 0xdf, 0x8f, 0x08, 0x87, 0xa7, 0x1d, 0x49, 0x1a, 0xc7, 0xf2, 0xcd, 0x6c, 0x41,
-	0xce, 0xef, 0x74, 0xb8, 0x32, 0xa6, 0xf9, 0x71, 0x23, 0x93, 0xdb, 0xa2, 0x92, 0xc0, 0x5f, 0x43,
-	0xa1, 0xd6, 0xeb, 0x5d, 0xc6, 0x8c, 0x14, 0xe6, 0x38, 0x71, 0x3b, 0x3d, 0xbf, 0x0e, 0xc7, 0xdb,
-	0x08, 0xfe, 0xc8, 0xcf, 0xe7, 0x0b, 0x3b, 0xa6, 0xf4, 0xf1, 0x54, 0x39, 0x7f, 0xb7, 0x03, 0x98,
-	0x8f, 0x55, 0x7d, 0x2c, 0xc7, 0xb4, 0x63, 0x0d, 0x48, 0x5a, 0x9b, 0xc8, 0xf7, 0xad, 0xb6, 0xc5,
-	0xac, 0x1a, 0x7d, 0x56, 0xc3, 0xca, 0xe8, 0x21, 0xc4, 0xdf, 0xfe, 0xa4, 0x1f, 0x5c, 0x28, 0x13,
-	0xca, 0xca, 0x53, 0x58, 0x1e, 0xff, 0xfa, 0x84, 0xaf, 0x8d, 0xc9, 0x99, 0xd1, 0x97, 0x34, 0xe9,
-	0xa3, 0x69, 0x62, 0xa1, 0xcd, 0x9a, 0x50, 0x08, 0xf7, 0x32, 0xbc, 0x72, 0xc1, 0xc7, 0xbe, 0xf4,
-	0xc1, 0x78, 0x66, 0x60, 0x6e, 0xeb, 0xab, 0x67, 0x2f, 0xe5, 0xc4, 0xf3, 0x97, 0x72, 0xe2, 0xf5,
-	0x4b, 0x19, 0xfd, 0xe6, 0x5c, 0x46, 0x7f, 0x3c, 0x97, 0xd1, 0xd3, 0x73, 0x19, 0x3d, 0x3b, 0x97,
-	0xd1, 0xbf, 0xcf, 0x65, 0xf4, 0x9f, 0x73, 0x39, 0xf1, 0xfa, 0x5c, 0x46, 0xbf, 0x7b, 0x25, 0x27,
-	0x9e, 0xbd, 0x92, 0x13, 0xcf, 0x5f, 0xc9, 0x89, 0x5f, 0x65, 0x3b, 0x3d, 0x83, 0x58, 0xb4, 0x9d,
-	0x65, 0x8f, 0xa8, 0xb7, 0xfe, 0x1f, 0x00, 0x00, 0xff, 0xff, 0xda, 0x42, 0x4a, 0x92, 0xbf, 0x15,
-	0x00, 0x00,
+	// 1758 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbd, 0x18, 0xcb, 0x6e, 0x13, 0x57,
+	0x34, 0x63, 0x3b, 0x26, 0x3e, 0x4e, 0x1c, 0xe7, 0x3a, 0x89, 0x8d, 0x03, 0x09, 0x9d, 0x0a, 0x48,
+	0x69, 0x49, 0x78, 0x15, 0x01, 0xa5, 0xaa, 0x9c, 0x60, 0x12, 0x03, 0x4e, 0xc2, 0x38, 0xa1, 0x0f,
+	0x09, 0x8d, 0xc6, 0xf6, 0x4d, 0x32, 0x8a, 0x3d, 0x76, 0x67, 0xc6, 0x88, 0x74, 0xd5, 0x55, 0xd7,
+	0xfd, 0x80, 0x6e, 0xba, 0xab, 0xba, 0xee, 0xb2, 0x1f, 0xc0, 0xa6, 0x12, 0x8b, 0x4a, 0x45, 0x95,
+	0x8a, 0x80, 0x6e, 0xda, 0x5d, 0xa5, 0xfe, 0x40, 0xef, 0x6b, 0x9e, 0xb6, 0xe3, 0x04, 0x15, 0x16,
+	0x63, 0xcf, 0x3d, 0xaf, 0x7b, 0xce, 0xb9, 0xe7, 0x35, 0x17, 0x52, 0xba, 0xb1, 0x83, 0x2d, 0x1b,
+	0x9b, 0x0b, 0x6d, 0xb3, 0x65, 0xb7, 0x50, 0xbc, 0xd6, 0x32, 0x6d, 0xfc, 0x38, 0x7f, 0x7e, 0x47,
+	0xb7, 0x77, 0x3b, 0xd5, 0x85, 0x5a, 0xab, 0xb9, 0xb8, 0xd3, 0xda, 0x69, 0x2d, 0x32, 0x74, 0xb5,
+	0xb3, 0xcd, 0x56, 0x6c, 0xc1, 0xde, 0x38, 0x5b, 0xfe, 0x82, 0x9f, 0xdc, 0xd4, 0xb6, 0x35, 0x43,
+	0x5b, 0x6c, 0xea, 0x4d, 0xdd, 0x5c, 0x6c, 0xef, 0xed, 0xf0, 0xb7, 0x76, 0x95, 0xff, 0x73, 0x0e,
+	0xf9, 0x1b, 0x09, 0xf2, 0xf7, 0xb4, 0x2a, 0x6e, 0xac, 0x69, 0x4d, 0x6c, 0x15, 0x8c, 0xfa, 0x03,
+	0xad, 0xd1, 0xc1, 0x96, 0x82, 0xbf, 0x24, 0xbf, 0x36, 0xba, 0x00, 0x23, 0x4d, 0xcd, 0xae, 0xed,
+	0x62, 0xd3, 0xca, 0x49, 0xa7, 0xa2, 0xf3, 0xc9, 0x4b, 0x93, 0x0b, 0x5c, 0xb5, 0x05, 0xc6, 0x55,
+	0xe6, 0x48, 0xc5, 0xa5, 0x42, 0x57, 0x61, 0xb4, 0xd6, 0xea, 0x18, 0xb6, 0xda, 0xc4, 0xf6, 0x6e,
+	0xab, 0x9e, 0x8b, 0x9c, 0x92, 0xe6, 0x53, 0x97, 0x32, 0x0e, 0xd7, 0x32, 0xc5, 0x95, 0x19, 0x4a,
+	0x49, 0xd6, 0xbc, 0x85, 0xbc, 0x0a, 0x33, 0x3d, 0xf5, 0xb0, 0xda, 0x2d, 0xc3, 0xc2, 0xe8, 0x3d,
+	0x18, 0xd6, 0x6d, 0xdc, 0x74, 0xb4, 0xc8, 0x04, 0xb4, 0x10, 0xb4, 0x9c, 0x42, 0xbe, 0x05, 0x49,
+	0x1f, 0x14, 0x9d, 0x04, 0x68, 0xd0, 0xa5, 0x6a, 0x10, 0xc9, 0x84, 0x5d, 0x9a, 0x4f, 0x28, 0x89,
+	0x86, 0xb3, 0x15, 0x9a, 0x86, 0xf8, 0x23, 0x46, 0x48, 0x34, 0x8d, 0x12, 0x94, 0x58, 0xc9, 0x3f,
+	0x4a, 0x70, 0xd2, 0x27, 0x66, 0x59, 0x33, 0xeb, 0xba, 0xa1, 0x35, 0x74, 0x7b, 0xdf, 0xf1, 0xcd,
+	0x1c, 0x24, 0x3d, 0xc1, 0x5c, 0xb1, 0x84, 0x02, 0xae, 0x64, 0x2b, 0xe0, 0xbc, 0xc8, 0x6b, 0x39,
+	0x2f, 0x7a, 0x48, 0xe7, 0x6d, 0xc1, 0x6c, 0x3f, 0x5d, 0x85, 0xff, 0x2e, 0x07, 0xfd, 0x77, 0xb2,
+	0xdb, 0x7f, 0x15, 0x6c, 0xea, 0x84, 0x97, 0x4a, 0x75, 0x3c, 0xf9, 0x5c, 0x82, 0xa9, 0x9e, 0x04,
+	0x83, 0x9c, 0xaa, 0x01, 0xe2, 0x68, 0xe6, 0x4c, 0xd5, 0x62, 0x9c, 0xc2, 0x07, 0x97, 0x0f, 0xdc,
+	0xba, 0x0b, 0x5a, 0x34, 0x6c, 0x73, 0x5f, 0x49, 0x37, 0x42, 0xe0, 0xfc, 0x72, 0xb7, 0x6a, 0x8c,
+	0x14, 0xa5, 0x21, 0xba, 0x87, 0xf7, 0x85, 0x4e, 0xf4, 0x15, 0x4d, 0xc2, 0x30, 0xd3, 0x83, 0xc5,
+	0x62, 0x4c, 0xe1, 0x8b, 0x1b, 0x91, 0x6b, 0x92, 0xfc, 0x9b, 0x04, 0xa3, 0xf7, 0x3b, 0xd8, 0x74,
+	0xcf, 0xf4, 0x03, 0x40, 0x96, 0xad, 0x99, 0xb6, 0x6a, 0xeb, 0xe4, 0x04, 0x6d, 0xad, 0xd9, 0x56,
+	0x99, 0xcf, 0xa4, 0xf9, 0xa8, 0x92, 0x66, 0x98, 0x4d, 0x07, 0x51, 0xb6, 0xd0, 0x3c, 0xa4, 0xb1,
+	0x51, 0x0f, 0xd2, 0x46, 0x18, 0x6d, 0x8a, 0xc0, 0xfd, 0x94, 0xfe, 0x50, 0x88, 0x1e, 0x2a, 0x14,
+	0x3e, 0x86, 0x19, 0xcb, 0x36, 0xb1, 0xd6, 0x24, 0x95, 0x41, 0xad, 0xed, 0x76, 0x8c, 0x3d, 0x4b,
+	0xad, 0x52, 0xa4, 0x6a, 0xe9, 0x5f, 0xe1, 0x5c, 0x9d, 0x99, 0x92, 0x73, 0x49, 0x96, 0x19, 0xc5,
+	0x12, 0x25, 0xa8, 0x10, 0xbc, 0xfc, 0xbd, 0x04, 0x93, 0xc5, 0xc7, 0xb8, 0xd9, 0x6e, 0x68, 0xe6,
+	0x5b, 0xb1, 0xf0, 0x62, 0x97, 0x85, 0x53, 0xbd, 0x2c, 0xb4, 0x3c, 0x13, 0xe5, 0x9f, 0x25, 0xc8,
+	0x14, 0x6a, 0xb6, 0xfe, 0x48, 0x9c, 0xdf, 0xeb, 0x17, 0x9d, 0x8f, 0x20, 0x66, 0xef, 0xb7, 0xb1,
+	0x28, 0x36, 0x67, 0x1d, 0xea, 0x1e, 0xc2, 0x17, 0xc4, 0xff, 0x26, 0x21, 0x57, 0x18, 0x93, 0x7c,
+	0x15, 0x92, 0x3e, 0x20, 0x02, 0x88, 0x57, 0x8a, 0x4a, 0xa9, 0x58, 0x49, 0x0f, 0xa1, 0x19, 0xc8,
+	0xae, 0x15, 0x36, 0x4b, 0x0f, 0x8a, 0xea, 0x6a, 0xa9, 0xb2, 0xb9, 0xbe, 0xa2, 0x14, 0xca, 0xaa,
+	0x40, 0x4a, 0xf2, 0x5d, 0x18, 0x13, 0x9e, 0x15, 0x39, 0x76, 0x03, 0x80, 0x39, 0x8a, 0x47, 0x7b,
+	0x50, 0xf3, 0x76, 0x75, 0x81, 0x7a, 0x8b, 0xeb, 0xb2, 0x14, 0x7b, 0xf2, 0x7c, 0x6e, 0x48, 0xf1,
+	0x51, 0xcb, 0xff, 0x46, 0x20, 0xc3, 0xa4, 0x55, 0xd8, 0x89, 0xba, 0x32, 0x3f, 0x81, 0x24, 0x3f,
+	0x7c, 0xbf, 0xd0, 0xac, 0x63, 0xa0, 0x27, 0x92, 0x9d, 0xbf, 0x90, 0xeb, 0xe7, 0x08, 0x29, 0x15,
+	0x39, 0x8a, 0x52, 0xe8, 0x0e, 0xa4, 0xbd, 0x18, 0x14, 0x12, 0xf8, 0xd9, 0x1e, 0x77, 0x34, 0xf0,
+	0xe9, 0x1c, 0x10, 0x33, 0xee, 0x32, 0x72, 0x30, 0xba, 0x02, 0x59, 0xdd, 0x52, 0x69, 0x30, 0xb5,
+	0xb6, 0x85, 0x2c, 0x95, 0xd3, 0xe4, 0x62, 0xe4, 0xd4, 0x46, 0x94, 0x8c, 0x4e, 0x12, 0xb8, 0xbe,
+	0xbe, 0xcd, 0xe9, 0xb9, 0x48, 0xf4, 0x10, 0xb2, 0x61, 0x0d, 0x44, 0x32, 0xe4, 0x86, 0x99, 0x22,
+	0x73, 0x7d, 0x15, 0x11, 0x19, 0xc1, 0xd5, 0x99, 0x0a, 0xa9, 0xc3, 0x91, 0xf2, 0x77, 0x12, 0x4c,
+	0x74, 0x31, 0xa2, 0x6d, 0x88, 0xb3, 0x72, 0x13, 0x6e, 0x36, 0xc4, 0x5d, 0x2c, 0xfe, 0x36, 0x34,
+	0xdd, 0x5c, 0xba, 0x4e, 0xe5, 0xfe, 0xfe, 0x7c, 0xee, 0xe2, 0x61, 0x5a, 0x2e, 0xe7, 0x2b, 0xd4,
+	0xb5, 0x36, 0x69, 0xf1, 0x8a, 0x90, 0x4e, 0x1b, 0x08, 0xb3, 0x45, 0x65, 0xa5, 0x5c, 0xe4, 0x15,
+	0x30, 0x10, 0xab, 0x85, 0xb2, 0x0e, 0xd9, 0x3e, 0x66, 0xa1, 0x77, 0x60, 0x54, 0xb8, 0x43, 0x37,
+	0xea, 0xf8, 0x31, 0x4b, 0xe0, 0x98, 0x92, 0xe4, 0xb0, 0x12, 0x05, 0xa1, 0xf7, 0x21, 0x2e, 0x5c,
+	0xc5, 0x4f, 0x7d, 0xcc, 0x6d, 0x23, 0xbe, 0x58, 0x11, 0x24, 0x72, 0x05, 0xa6, 0x42, 0xe5, 0xe2,
+	0x7f, 0x08, 0xea, 0x5f, 0x25, 0x40, 0xfe, 0x06, 0x2d, 0xf2, 0x7b, 0x40, 0xf3, 0xe8, 0x5d, 0xa1,
+	0x22, 0x47, 0xa8, 0x50, 0xd1, 0x81, 0x15, 0x8a, 0x86, 0xdc, 0xe0, 0x0a, 0x45, 0x3b, 0x47, 0x83,
+	0x9c, 0xa0, 0x4d, 0x82, 0x8d, 0x4a, 0xe4, 0x0b, 0xf9, 0x1a, 0x64, 0x02, 0x56, 0x09, 0x4f, 0x91,
+	0x23, 0xf1, 0x35, 0x3d, 0x67, 0x20, 0x48, 0x7a, 0x9d, 0xcb, 0x92, 0x7f, 0x22, 0xf1, 0xe6, 0x4d,
+	0x39, 0x6f, 0xb7, 0x24, 0x1f, 0xcd, 0xe0, 0x98, 0xdf, 0xe0, 0x0f, 0xc5, 0x31, 0x0a, 0xad, 0x85,
+	0xbd, 0x83, 0xe6, 0x1f, 0x99, 0x94, 0x8f, 0x2d, 0x12, 0x09, 0x15, 0x5b, 0xb3, 0x5d, 0x5b, 0xc3,
+	0x13, 0x8e, 0x74, 0xc8, 0x09, 0xe7, 0x05, 0xf1, 0x9c, 0x4f, 0x98, 0x50, 0xe1, 0x34, 0x88, 0xc1,
+	0x59, 0x6f, 0x19, 0xaa, 0xa9, 0xd9, 0x3c, 0x9a, 0x24, 0x65, 0xcc, 0x85, 0x2a, 0x04, 0x48, 0x03,
+	0xce, 0xe8, 0x34, 0xbd, 0x31, 0x84, 0xa6, 0x4a, 0x82, 0x40, 0x44, 0xbe, 0x13, 0xff, 0x6b, 0x6d,
+	0x5d, 0x0d, 0x49, 0x8a, 0x32, 0x49, 0x69, 0x82, 0x29, 0x05, 0x84, 0x2d, 0x40, 0xc6, 0xec, 0x34,
+	0x70, 0x98, 0x3c, 0xc6, 0xc8, 0x27, 0x28, 0x2a, 0x48, 0x7f, 0x16, 0xc6, 0x77, 0xb1, 0x56, 0x57,
+	0x77, 0xb0, 0x81, 0x09, 0x25, 0x01, 0xb3, 0x68, 0x8a, 0x29, 0x29, 0x0a, 0x5e, 0x71, 0xa1, 0xf2,
+	0x43, 0xc8, 0x50, 0x0b, 0x4b, 0xb7, 0x82, 0x36, 0x66, 0xe1, 0x58, 0x87, 0x80, 0x55, 0xbd, 0x2e,
+	0x52, 0x25, 0x4e, 0x97, 0xa5, 0x3a, 0x3a, 0x0f, 0xb1, 0xba, 0x66, 0x6b, 0xcc, 0x1e, 0x5f, 0x45,
+	0xee, 0xf2, 0x92, 0xc2, 0xc8, 0xe4, 0x15, 0x40, 0x14, 0x65, 0x05, 0xa5, 0x5f, 0x84, 0x61, 0x8b,
+	0x02, 0x44, 0x66, 0xcf, 0xf8, 0xa5, 0x84, 0x34, 0x51, 0x38, 0xa5, 0xfc, 0x44, 0x82, 0x59, 0x72,
+	0x2a, 0xa6, 0x5e, 0xb3, 0x6e, 0xb7, 0xcc, 0x60, 0x24, 0xbd, 0xe1, 0x88, 0xbe, 0x06, 0xa3, 0x4e,
+	0xa8, 0x92, 0xd3, 0xb4, 0x0f, 0x1e, 0x34, 0x92, 0x0e, 0x69, 0x05, 0xdb, 0x7d, 0x02, 0xfb, 0x2e,
+	0xcc, 0xf5, 0xb5, 0x44, 0x38, 0x68, 0x1e, 0xe2, 0x4d, 0x46, 0x22, 0x3c, 0x94, 0xf6, 0x6a, 0x1f,
+	0x67, 0x55, 0x04, 0x5e, 0x6e, 0xc3, 0xb4, 0x10, 0x46, 0xfe, 0x34, 0xea, 0x73, 0xc7, 0x1d, 0xee,
+	0xe6, 0xd4, 0x03, 0x13, 0x62, 0x73, 0x6a, 0x36, 0x7b, 0x51, 0xdb, 0xe4, 0x74, 0xc5, 0x1e, 0x11,
+	0x46, 0x90, 0x62, 0xf0, 0x0d, 0x6c, 0x72, 0x79, 0xf4, 0x1b, 0x45, 0xe0, 0xa3, 0x3c, 0x02, 0xc4,
+	0x8e, 0xeb, 0x90, 0xed, 0xda, 0x51, 0xa8, 0x7d, 0x85, 0xe4, 0xbe, 0x80, 0x09, 0xc5, 0x73, 0x61,
+	0xc5, 0x5d, 0x1e, 0x97, 0x52, 0xae, 0xc1, 0x64, 0x70, 0x66, 0x3a, 0xaa, 0x13, 0x68, 0x11, 0xac,
+	0x76, 0x6a, 0x7b, 0xd8, 0x76, 0x9b, 0x5a, 0x94, 0xf6, 0x25, 0x0e, 0xe3, 0x5d, 0xed, 0x6f, 0x09,
+	0xc6, 0x43, 0x83, 0x0b, 0xf5, 0xc5, 0xb6, 0xd9, 0x6a, 0xaa, 0xce, 0x67, 0xb0, 0x17, 0xed, 0x29,
+	0x0a, 0x2f, 0x09, 0x30, 0x89, 0x7a, 0x5f, 0x3a, 0x44, 0x02, 0xe9, 0xe0, 0x75, 0xed, 0xe8, 0x1b,
+	0xed, 0xda, 0x5e, 0x5b, 0x8d, 0x0d, 0x6e, 0xab, 0xbf, 0x48, 0x30, 0xcc, 0x2d, 0x7c, 0x53, 0x29,
+	0x91, 0x87, 0x11, 0x6c, 0xd4, 0x5a, 0xe4, 0x83, 0x6f, 0x87, 0x45, 0xc7, 0xb0, 0xe2, 0xae, 0xd1,
+	0x86, 0xa8, 0x10, 0x34, 0xe6, 0x47, 0x97, 0x6e, 0x0a, 0xdb, 0xaf, 0x1c, 0xca, 0xf6, 0x2d, 0xc3,
+	0xd2, 0xb6, 0xf1, 0xd2, 0xbe, 0x8d, 0x2b, 0x0d, 0xbd, 0xe6, 0x14, 0x91, 0x02, 0x8c, 0x05, 0xd2,
+	0xe4, 0xe8, 0xb3, 0xba, 0xac, 0xc2, 0xa8, 0x1f, 0x43, 0x6a, 0x38, 0x9f, 0xdd, 0x79, 0x27, 0x98,
+	0x70, 0xb8, 0x19, 0xda, 0x9b, 0xd2, 0x11, 0x82, 0x18, 0x1b, 0x17, 0xf8, 0xa1, 0xb3, 0x77, 0xef,
+	0xc3, 0x8e, 0xa7, 0x05, 0x5f, 0x9c, 0x9b, 0x87, 0xa4, 0xaf, 0x8d, 0xa0, 0x31, 0x48, 0x94, 0xd6,
+	0xd4, 0x72, 0xb1, 0xbc, 0xae, 0x7c, 0x4e, 0x46, 0x7a, 0x32, 0xde, 0x17, 0x96, 0xe9, 0x48, 0x9f,
+	0x96, 0xce, 0xdd, 0x81, 0x84, 0xbb, 0x0d, 0x4a, 0xc0, 0x70, 0xf1, 0xfe, 0x56, 0xe1, 0x1e, 0xa1,
+	0x21, 0x2c, 0x6b, 0xeb, 0x9b, 0x2a, 0x5f, 0x4a, 0x68, 0x9c, 0x7c, 0x20, 0x14, 0x57, 0x8a, 0x9f,
+	0xa9, 0xe5, 0xc2, 0xe6, 0xf2, 0x6a, 0x3a, 0x42, 0x74, 0x49, 0x71, 0xc0, 0xda, 0xba, 0x80, 0x45,
+	0x2f, 0xfd, 0x71, 0x0c, 0x46, 0x9c, 0x30, 0x45, 0xd7, 0x21, 0xb6, 0xd1, 0xb1, 0x76, 0xd1, 0xb4,
+	0x17, 0x83, 0x9f, 0x9a, 0xe4, 0x9b, 0x5a, 0x14, 0x84, 0x7c, 0xb6, 0x0b, 0xce, 0x13, 0x4d, 0x1e,
+	0x42, 0xb7, 0x20, 0xe9, 0x9b, 0xf9, 0xd0, 0x64, 0x60, 0xbe, 0x75, 0xf8, 0x67, 0x7a, 0x4c, 0xbd,
+	0x9e, 0x8c, 0x0b, 0x12, 0x5a, 0x87, 0x14, 0x43, 0x39, 0x33, 0x9d, 0x85, 0x4e, 0x38, 0x2c, 0xbd,
+	0xbe, 0x0a, 0xf3, 0x27, 0xfb, 0x60, 0x5d, 0xb5, 0x56, 0x83, 0x97, 0x2a, 0xf9, 0x5e, 0xf7, 0x2f,
+	0x61, 0xe5, 0x7a, 0x0c, 0x49, 0x44, 0x52, 0x11, 0xc0, 0x1b, 0x26, 0xd0, 0xf1, 0x00, 0xb1, 0x7f,
+	0x2c, 0xca, 0xe7, 0x7b, 0xa1, 0x5c, 0x31, 0x4b, 0x90, 0x70, 0x3b, 0x1d, 0xca, 0xf5, 0x68, 0x7e,
+	0x5c, 0x48, 0xff, 0xb6, 0x48, 0x64, 0xdc, 0x86, 0xd1, 0x42, 0xa3, 0x71, 0x18, 0x31, 0x79, 0x3f,
+	0xc6, 0x0a, 0xcb, 0x69, 0xb8, 0x75, 0x38, 0xdc, 0x46, 0xd0, 0x19, 0x37, 0x9e, 0x0f, 0xec, 0x98,
+	0xf9, 0xb3, 0x03, 0xe9, 0xdc, 0xdd, 0x36, 0x61, 0x3c, 0x54, 0xf5, 0xd1, 0x6c, 0x88, 0x3b, 0xd4,
+	0x80, 0xf2, 0x73, 0x7d, 0xf1, 0xae, 0xd4, 0xaa, 0x18, 0x6a, 0x83, 0xf7, 0x6f, 0x48, 0xee, 0x3e,
+	0x84, 0xf0, 0x25, 0x61, 0xfe, 0xdd, 0x03, 0x69, 0x7c, 0x51, 0xb9, 0x07, 0xd3, 0xbd, 0xaf, 0xa9,
+	0xd0, 0xe9, 0x1e, 0x31, 0xd3, 0x7d, 0xe5, 0x96, 0x3f, 0x33, 0x88, 0xcc, 0xb7, 0x59, 0x99, 0x1c,
+	0xae, 0xaf, 0x97, 0xa1, 0x99, 0x03, 0x6e, 0x05, 0xf2, 0x27, 0x7a, 0x23, 0x3d, 0x71, 0x4b, 0x37,
+	0x9f, 0xbe, 0x9c, 0x1d, 0x7a, 0x46, 0x9e, 0x7f, 0x5e, 0xce, 0x4a, 0x5f, 0xbf, 0x9a, 0x95, 0x7e,
+	0x20, 0xcf, 0x13, 0xf2, 0x3c, 0x25, 0xcf, 0x0b, 0xf2, 0xfc, 0xf5, 0x8a, 0xe0, 0xc8, 0xff, 0xb7,
+	0x7f, 0xce, 0x0e, 0x3d, 0x25, 0xcf, 0x33, 0xf2, 0x7c, 0x11, 0xaf, 0x35, 0x74, 0x6c, 0xd8, 0xd5,
+	0x38, 0xbb, 0x6d, 0xbd, 0xfc, 0x1f, 0x4b, 0x13, 0xac, 0xe4, 0xe8, 0x15, 0x00, 0x00,
 }
 
 func (x CountMethod) String() string {
@@ -2599,6 +2606,9 @@ func (this *UserStatsResponse) Equal(that interface{}) bool {
 	if this.RuleIngestionRate != that1.RuleIngestionRate {
 		return false
 	}
+	if this.HeadGeneration != that1.HeadGeneration {
+		return false
+	}
 	return true
 }
 func (this *UserIDStatsResponse) Equal(that interface{}) bool {
@@ -3245,12 +3255,13 @@ func (this *UserStatsResponse) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 8)
+	s := make([]string, 0, 9)
 	s = append(s, "&client.UserStatsResponse{")
 	s = append(s, "IngestionRate: "+fmt.Sprintf("%#v", this.IngestionRate)+",\n")
 	s = append(s, "NumSeries: "+fmt.Sprintf("%#v", this.NumSeries)+",\n")
 	s = append(s, "ApiIngestionRate: "+fmt.Sprintf("%#v", this.ApiIngestionRate)+",\n")
 	s = append(s, "RuleIngestionRate: "+fmt.Sprintf("%#v", this.RuleIngestionRate)+",\n")
+	s = append(s, "HeadGeneration: "+fmt.Sprintf("%#v", this.HeadGeneration)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -4865,6 +4876,11 @@ func (m *UserStatsResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.HeadGeneration != 0 {
+		i = encodeVarintIngester(dAtA, i, uint64(m.HeadGeneration))
+		i--
+		dAtA[i] = 0x28
+	}
 	if m.RuleIngestionRate != 0 {
 		i -= 8
 		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.RuleIngestionRate))))
@@ -5779,6 +5795,9 @@ func (m *UserStatsResponse) Size() (n int) {
 	if m.RuleIngestionRate != 0 {
 		n += 9
 	}
+	if m.HeadGeneration != 0 {
+		n += 1 + sovIngester(uint64(m.HeadGeneration))
+	}
 	return n
 }
 
@@ -6302,6 +6321,7 @@ func (this *UserStatsResponse) String() string {
 		`NumSeries:` + fmt.Sprintf("%v", this.NumSeries) + `,`,
 		`ApiIngestionRate:` + fmt.Sprintf("%v", this.ApiIngestionRate) + `,`,
 		`RuleIngestionRate:` + fmt.Sprintf("%v", this.RuleIngestionRate) + `,`,
+		`HeadGeneration:` + fmt.Sprintf("%v", this.HeadGeneration) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -8767,6 +8787,25 @@ func (m *UserStatsResponse) Unmarshal(dAtA []byte) error {
 			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
 			iNdEx += 8
 			m.RuleIngestionRate = float64(math.Float64frombits(v))
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HeadGeneration", wireType)
+			}
+			m.HeadGeneration = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.HeadGeneration |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipIngester(dAtA[iNdEx:])
@@ -0,0 +1,291 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	mimir_storage "github.com/grafana/mimir/pkg/storage"
+)
+
+// cumulativeSample is the last cumulative sample appended to a series with delta temporality.
+type cumulativeSample struct {
+	t int64
+	f float64
+	h *histogram.FloatHistogram
+}
+
+// deltaAccumulatorLockStripes is the number of locks used to serialise the requests appending to the same series
+// with delta temporality.
+const deltaAccumulatorLockStripes = 512
+
+// deltaAccumulator holds the last cumulative sample of the series of a tenant with delta temporality, so that
+// incoming deltas can be accumulated without reading the head. Series missing from the accumulator, for example
+// after a restart, are initialized from the last sample of the series in the head.
+//
+// Each ingester accumulates the deltas it receives independently of the other replicas of the series, so the
+// cumulative samples of an ingester that missed a request permanently diverge from the ones of the other replicas.
+type deltaAccumulator struct {
+	mtx    sync.Mutex
+	series map[storage.SeriesRef]cumulativeSample
+
+	// locks serialise the requests appending to the same series, from reading the last cumulative sample of
+	// the series until the request's samples are committed or rolled back. Series are mapped to a lock by the
+	// hash of their labels, because new series don't have a reference until they're appended.
+	locks [deltaAccumulatorLockStripes]sync.Mutex
+}
+
+func newDeltaAccumulator() *deltaAccumulator {
+	return &deltaAccumulator{
+		series: map[storage.SeriesRef]cumulativeSample{},
+	}
+}
+
+// begin locks the series with delta temporality in timeseries, and returns the deltaAppend the cumulative samples
+// of the request must be read from and written to. The returned deltaAppend must be committed or rolled back.
+func (a *deltaAccumulator) begin(timeseries []mimirpb.PreallocTimeseries) *deltaAppend {
+	var stripes []int
+	for _, ts := range timeseries {
+		if !ts.DeltaTemporality {
+			continue
+		}
+		stripe := int(mimirpb.FromLabelAdaptersToLabels(ts.Labels).Hash() % deltaAccumulatorLockStripes)
+		if !slices.Contains(stripes, stripe) {
+			stripes = append(stripes, stripe)
+		}
+	}
+	if len(stripes) == 0 {
+		return nil
+	}
+
+	// Locks are always taken in the same order, so that concurrent requests can't deadlock.
+	slices.Sort(stripes)
+	for _, stripe := range stripes {
+		a.locks[stripe].Lock()
+	}
+
+	return &deltaAppend{
+		accumulator: a,
+		stripes:     stripes,
+		pending:     map[storage.SeriesRef]cumulativeSample{},
+	}
+}
+
+func (a *deltaAccumulator) get(ref storage.SeriesRef) (cumulativeSample, bool) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	s, ok := a.series[ref]
+	return s, ok
+}
+
+// delete removes the series deleted from the head.
+func (a *deltaAccumulator) delete(refs map[chunks.HeadSeriesRef]labels.Labels) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for ref := range refs {
+		delete(a.series, storage.SeriesRef(ref))
+	}
+}
+
+// reset removes all series, so that they're initialized from the head again.
+func (a *deltaAccumulator) reset() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	clear(a.series)
+}
+
+// deltaAppend holds the cumulative samples of the series with delta temporality of a single request, until
+// they're committed to the accumulator along with the samples appended to the head.
+type deltaAppend struct {
+	accumulator *deltaAccumulator
+	stripes     []int
+	pending     map[storage.SeriesRef]cumulativeSample
+}
+
+// set records s as the last cumulative sample of the series identified by ref, once the request is committed.
+func (d *deltaAppend) set(ref storage.SeriesRef, s cumulativeSample) {
+	d.pending[ref] = s
+}
+
+// commit stores the cumulative samples of the request in the accumulator and unlocks the request's series.
+// It must be called once the request's samples are committed to the head. It's a no-op on a nil deltaAppend.
+func (d *deltaAppend) commit() {
+	if d == nil {
+		return
+	}
+
+	d.accumulator.mtx.Lock()
+	maps.Copy(d.accumulator.series, d.pending)
+	d.accumulator.mtx.Unlock()
+
+	d.unlock()
+}
+
+// rollback discards the cumulative samples of the request and unlocks the request's series. It must be called
+// when the request's samples are rolled back or fail to be committed. It's a no-op on a nil deltaAppend.
+func (d *deltaAppend) rollback() {
+	if d == nil {
+		return
+	}
+
+	d.unlock()
+}
+
+func (d *deltaAppend) unlock() {
+	for _, stripe := range d.stripes {
+		d.accumulator.locks[stripe].Unlock()
+	}
+}
+
+// lastCumulativeSample returns the last sample of the series identified by ref and lbls. The sample is read
+// from the request's pending samples, then from the accumulator, and from the head if the series isn't in either.
+func (d *deltaAppend) lastCumulativeSample(db *userTSDB, ref storage.SeriesRef, lbls labels.Labels) (cumulativeSample, error) {
+	if ref == 0 {
+		// The series doesn't exist in the head yet.
+		return cumulativeSample{t: math.MinInt64}, nil
+	}
+	if s, ok := d.pending[ref]; ok {
+		return s, nil
+	}
+	if s, ok := d.accumulator.get(ref); ok {
+		return s, nil
+	}
+
+	head := db.db.Head()
+	mint, maxt := head.MinTime(), head.MaxTime()
+	q, err := tsdb.NewBlockQuerier(tsdb.NewRangeHead(head, mint, maxt), mint, maxt)
+	if err != nil {
+		return cumulativeSample{}, err
+	}
+	defer q.Close()
+
+	matchers := make([]*labels.Matcher, 0, lbls.Len())
+	lbls.Range(func(l labels.Label) {
+		matchers = append(matchers, labels.MustNewMatcher(labels.MatchEqual, l.Name, l.Value))
+	})
+
+	last := cumulativeSample{t: math.MinInt64}
+	ss := q.Select(context.Background(), false, nil, matchers...)
+	for ss.Next() {
+		series := ss.At()
+		if !labels.Equal(series.Labels(), lbls) {
+			continue
+		}
+
+		it := series.Iterator(nil)
+		for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+			switch vt {
+			case chunkenc.ValFloat:
+				t, f := it.At()
+				// A stale marker ends the series, so accumulation starts from zero again.
+				if value.IsStaleNaN(f) {
+					last = cumulativeSample{t: t}
+					continue
+				}
+				last = cumulativeSample{t: t, f: f}
+			case chunkenc.ValHistogram, chunkenc.ValFloatHistogram:
+				t, h := it.AtFloatHistogram(nil)
+				if value.IsStaleNaN(h.Sum) {
+					last = cumulativeSample{t: t}
+					continue
+				}
+				last = cumulativeSample{t: t, h: h}
+			}
+		}
+		if err := it.Err(); err != nil {
+			return cumulativeSample{}, err
+		}
+	}
+	if err := ss.Err(); err != nil {
+		return cumulativeSample{}, err
+	}
+
+	return last, nil
+}
+
+// accumulateDeltas replaces the delta samples and histograms of ts, in place, with the cumulative samples
+// obtained by adding each delta to the previous cumulative sample of the series. Samples with the same timestamp
+// as the last accumulated sample are dropped, because they've already been accumulated by a previous request.
+// Samples older than the last accumulated sample can't be accumulated and are reported as out-of-order.
+// It returns the last cumulative sample, which must be stored in the accumulator once the samples are appended.
+func accumulateDeltas(ts mimirpb.PreallocTimeseries, last cumulativeSample, accumulateHistograms bool, errProcessor *mimir_storage.SoftAppendErrorProcessor) cumulativeSample {
+	samples := ts.Samples[:0]
+	for _, s := range ts.Samples {
+		if s.TimestampMs < last.t {
+			errProcessor.ProcessErr(storage.ErrOutOfOrderSample, s.TimestampMs, ts.Labels)
+			continue
+		}
+		if s.TimestampMs == last.t {
+			continue
+		}
+
+		if value.IsStaleNaN(s.Value) {
+			last = cumulativeSample{t: s.TimestampMs}
+		} else {
+			last = cumulativeSample{t: s.TimestampMs, f: last.f + s.Value}
+			s.Value = last.f
+		}
+		samples = append(samples, s)
+	}
+	ts.Samples = samples
+
+	if !accumulateHistograms {
+		return last
+	}
+
+	histograms := ts.Histograms[:0]
+	for _, h := range ts.Histograms {
+		if h.Timestamp < last.t {
+			errProcessor.ProcessErr(storage.ErrOutOfOrderSample, h.Timestamp, ts.Labels)
+			continue
+		}
+		if h.Timestamp == last.t {
+			continue
+		}
+
+		var delta *histogram.FloatHistogram
+		if h.IsFloatHistogram() {
+			delta = mimirpb.FromFloatHistogramProtoToFloatHistogram(&h)
+		} else {
+			delta = mimirpb.FromHistogramProtoToHistogram(&h).ToFloat(nil)
+		}
+
+		if value.IsStaleNaN(delta.Sum) {
+			last = cumulativeSample{t: h.Timestamp}
+			histograms = append(histograms, h)
+			continue
+		}
+
+		cumulative := delta
+		cumulative.CounterResetHint = histogram.UnknownCounterReset
+		if last.h != nil {
+			// Histograms with incompatible bucket layouts can't be added, so accumulation starts again from the delta.
+			if sum, err := last.h.Copy().Add(delta); err == nil {
+				cumulative = sum
+				cumulative.CounterResetHint = histogram.NotCounterReset
+			}
+		}
+
+		last = cumulativeSample{t: h.Timestamp, h: cumulative}
+		histograms = append(histograms, mimirpb.FromFloatHistogramToHistogramProto(h.Timestamp, cumulative.Copy()))
+	}
+	ts.Histograms = histograms
+
+	return last
+}
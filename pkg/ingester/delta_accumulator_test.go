@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	util_test "github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestIngester_PushDeltaTemporality(t *testing.T) {
+	const userID = "test"
+	i, push := prepareIngesterForDeltaTemporality(t, true)
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	require.NoError(t, push(mimirpb.Sample{TimestampMs: 1000, Value: 1}, mimirpb.Sample{TimestampMs: 2000, Value: 2}))
+
+	// Samples that were already accumulated, for example because the request is retried, are skipped.
+	require.NoError(t, push(mimirpb.Sample{TimestampMs: 2000, Value: 2}, mimirpb.Sample{TimestampMs: 3000, Value: 4}))
+
+	// Samples older than the last accumulated sample can't be accumulated.
+	require.Error(t, push(mimirpb.Sample{TimestampMs: 1500, Value: 1}))
+
+	// Series missing from the accumulator are initialized from the head.
+	i.getTSDB(userID).deltaAccumulator.reset()
+	require.NoError(t, push(mimirpb.Sample{TimestampMs: 4000, Value: 1}))
+
+	res, _, err := runTestQuery(ctx, t, i, labels.MatchEqual, labels.MetricName, "delta_total")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []model.SamplePair{
+		{Timestamp: 1000, Value: 1},
+		{Timestamp: 2000, Value: 3},
+		{Timestamp: 3000, Value: 7},
+		{Timestamp: 4000, Value: 8},
+	}, res[0].Values)
+}
+
+func TestIngester_PushDeltaTemporality_DisabledForTenant(t *testing.T) {
+	i, push := prepareIngesterForDeltaTemporality(t, false)
+	ctx := user.InjectOrgID(context.Background(), "test")
+
+	// Series with delta temporality are ingested like any other series.
+	require.NoError(t, push(mimirpb.Sample{TimestampMs: 1000, Value: 1}, mimirpb.Sample{TimestampMs: 2000, Value: 2}))
+	require.NoError(t, push(mimirpb.Sample{TimestampMs: 3000, Value: 4}))
+
+	res, _, err := runTestQuery(ctx, t, i, labels.MatchEqual, labels.MetricName, "delta_total")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []model.SamplePair{
+		{Timestamp: 1000, Value: 1},
+		{Timestamp: 2000, Value: 2},
+		{Timestamp: 3000, Value: 4},
+	}, res[0].Values)
+}
+
+// prepareIngesterForDeltaTemporality starts an ingester with the accumulation of series with delta temporality
+// enabled or disabled for all tenants, and returns a function to push samples with delta temporality to the
+// series delta_total of the tenant "test".
+func prepareIngesterForDeltaTemporality(t *testing.T, enabled bool) (*Ingester, func(samples ...mimirpb.Sample) error) {
+	cfg := defaultIngesterTestConfig(t)
+	limits := defaultLimitsTestConfig()
+	limits.NativeHistogramsIngestionEnabled = true
+	limits.OTelDeltaToCumulativeEnabled = enabled
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, nil, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+	})
+
+	// Wait until the ingester is healthy
+	test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	push := func(samples ...mimirpb.Sample) error {
+		req := &mimirpb.WriteRequest{
+			Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+				Labels:           []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "delta_total"}},
+				Samples:          samples,
+				DeltaTemporality: true,
+			}}},
+			Source: mimirpb.API,
+		}
+		_, err := i.Push(ctx, req)
+		return err
+	}
+
+	return i, push
+}
+
+func TestDeltaAccumulator_RollbackOnlyDiscardsTheRequestSeries(t *testing.T) {
+	a := newDeltaAccumulator()
+	a.series[1] = cumulativeSample{t: 1000, f: 1}
+	a.series[2] = cumulativeSample{t: 1000, f: 2}
+
+	d := a.begin(deltaTimeseries("series_1"))
+	d.set(1, cumulativeSample{t: 2000, f: 10})
+	d.rollback()
+
+	s, ok := a.get(1)
+	require.True(t, ok)
+	assert.Equal(t, cumulativeSample{t: 1000, f: 1}, s)
+	s, ok = a.get(2)
+	require.True(t, ok)
+	assert.Equal(t, cumulativeSample{t: 1000, f: 2}, s)
+
+	d = a.begin(deltaTimeseries("series_1"))
+	d.set(1, cumulativeSample{t: 2000, f: 10})
+	d.commit()
+
+	s, ok = a.get(1)
+	require.True(t, ok)
+	assert.Equal(t, cumulativeSample{t: 2000, f: 10}, s)
+}
+
+func TestDeltaAccumulator_SerialisesRequestsForTheSameSeries(t *testing.T) {
+	a := newDeltaAccumulator()
+
+	// Requests without series with delta temporality don't lock anything.
+	require.Nil(t, a.begin([]mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+		Labels: []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "series_1"}},
+	}}}))
+
+	first := a.begin(deltaTimeseries("series_1", "series_2"))
+
+	// Requests for other series aren't blocked.
+	a.begin(deltaTimeseries("series_3")).commit()
+
+	secondStarted := make(chan struct{})
+	go func() {
+		second := a.begin(deltaTimeseries("series_2", "series_1"))
+		close(secondStarted)
+		second.commit()
+	}()
+
+	select {
+	case <-secondStarted:
+		require.FailNow(t, "the second request shouldn't start until the first one is committed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.commit()
+	select {
+	case <-secondStarted:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the second request should start once the first one is committed")
+	}
+}
+
+// deltaTimeseries returns series with delta temporality with the given metric names.
+func deltaTimeseries(names ...string) []mimirpb.PreallocTimeseries {
+	timeseries := make([]mimirpb.PreallocTimeseries, 0, len(names))
+	for _, name := range names {
+		timeseries = append(timeseries, mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels:           []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: name}},
+			DeltaTemporality: true,
+		}})
+	}
+	return timeseries
+}
+
+func TestAccumulateDeltas_Histograms(t *testing.T) {
+	delta := util_test.GenerateTestHistogram(1)
+	ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+		Histograms: []mimirpb.Histogram{
+			mimirpb.FromHistogramToHistogramProto(1000, delta),
+			mimirpb.FromHistogramToHistogramProto(2000, delta),
+		},
+	}}
+
+	last := accumulateDeltas(ts, cumulativeSample{t: 500}, true, nil)
+	require.Len(t, ts.Histograms, 2)
+
+	expected := delta.ToFloat(nil)
+	expected.CounterResetHint = histogram.UnknownCounterReset
+	require.Equal(t, expected, mimirpb.FromFloatHistogramProtoToFloatHistogram(&ts.Histograms[0]))
+
+	expected, err := expected.Copy().Add(delta.ToFloat(nil))
+	require.NoError(t, err)
+	expected.CounterResetHint = histogram.NotCounterReset
+	require.Equal(t, expected, mimirpb.FromFloatHistogramProtoToFloatHistogram(&ts.Histograms[1]))
+
+	assert.Equal(t, int64(2000), last.t)
+	assert.Equal(t, expected, last.h)
+}
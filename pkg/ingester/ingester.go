@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -986,6 +987,7 @@ type pushStats struct {
 	sampleTooOldCount           int
 	sampleTooFarInFutureCount   int
 	newValueForTimestampCount   int
+	haDeduplicatedSamplesCount  int
 	perUserSeriesLimitCount     int
 	perMetricSeriesLimitCount   int
 	invalidNativeHistogramCount int
@@ -1348,10 +1350,18 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 
 	minAppendTime, minAppendTimeAvailable := db.Head().AppendableMinValidTime()
 
-	if pushSamplesToAppenderErr := i.pushSamplesToAppender(userID, req.Timeseries, app, startAppend, &stats, &errProcessor, updateFirstPartial, activeSeries, i.limits.OutOfOrderTimeWindow(userID), minAppendTimeAvailable, minAppendTime); pushSamplesToAppenderErr != nil {
+	// Series with delta temporality are only accumulated if enabled for the tenant, and are otherwise ingested
+	// like any other series.
+	var deltas *deltaAppend
+	if i.limits.OTelDeltaToCumulativeEnabled(userID) {
+		deltas = db.deltaAccumulator.begin(req.Timeseries)
+	}
+
+	if pushSamplesToAppenderErr := i.pushSamplesToAppender(userID, req.Timeseries, app, startAppend, &stats, &errProcessor, updateFirstPartial, activeSeries, i.limits.OutOfOrderTimeWindow(userID), minAppendTimeAvailable, minAppendTime, deltas); pushSamplesToAppenderErr != nil {
 		if err := app.Rollback(); err != nil {
 			level.Warn(i.logger).Log("msg", "failed to rollback appender on error", "user", userID, "err", err)
 		}
+		deltas.rollback()
 
 		return wrapOrAnnotateWithUser(pushSamplesToAppenderErr, userID)
 	}
@@ -1369,8 +1379,10 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 
 	startCommit := time.Now()
 	if err := app.Commit(); err != nil {
+		deltas.rollback()
 		return wrapOrAnnotateWithUser(err, userID)
 	}
+	deltas.commit()
 
 	commitDuration := time.Since(startCommit)
 	i.metrics.appenderCommitDuration.Observe(commitDuration.Seconds())
@@ -1386,6 +1398,9 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 	// which will be converted into an HTTP 5xx and the client should/will retry.
 	i.metrics.ingestedSamples.WithLabelValues(userID).Add(float64(stats.succeededSamplesCount))
 	i.metrics.ingestedSamplesFail.WithLabelValues(userID).Add(float64(stats.failedSamplesCount))
+	if stats.haDeduplicatedSamplesCount > 0 {
+		i.metrics.haDeduplicatedSamples.WithLabelValues(userID).Add(float64(stats.haDeduplicatedSamplesCount))
+	}
 	i.metrics.ingestedExemplars.Add(float64(stats.succeededExemplarsCount))
 	i.metrics.ingestedExemplarsFail.Add(float64(stats.failedExemplarsCount))
 	appendedSamplesStats.Inc(int64(stats.succeededSamplesCount))
@@ -1438,17 +1453,28 @@ func (i *Ingester) updateMetricsFromPushStats(userID string, group string, stats
 	}
 }
 
+// isHADuplicateSampleErr returns whether err is returned when appending a sample of a Prometheus HA replica which
+// was already ingested from another replica: a sample at the same timestamp, or older than the latest sample of
+// the series. With sample-level HA deduplication, such samples of the series flagged as HA deduplicated by the
+// distributor are dropped without error, so that the first sample ingested for each series and timestamp is kept.
+// Sample-level HA deduplication requires the out-of-order time window to be disabled, otherwise the samples of
+// the replicas, scraped at different offsets, would be interleaved in their series.
+func isHADuplicateSampleErr(err error) bool {
+	return errors.Is(err, storage.ErrDuplicateSampleForTimestamp) || errors.Is(err, storage.ErrOutOfOrderSample)
+}
+
 // pushSamplesToAppender appends samples and exemplars to the appender. Most errors are handled via updateFirstPartial function,
 // but in case of unhandled errors, appender is rolled back and such error is returned. Errors handled by updateFirstPartial
 // must be of type softError.
 func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.PreallocTimeseries, app extendedAppender, startAppend time.Time,
 	stats *pushStats, errProcessor *mimir_storage.SoftAppendErrorProcessor, updateFirstPartial func(sampler *util_log.Sampler, errFn softErrorFunction), activeSeries *activeseries.ActiveSeries,
-	outOfOrderWindow time.Duration, minAppendTimeAvailable bool, minAppendTime int64) error {
+	outOfOrderWindow time.Duration, minAppendTimeAvailable bool, minAppendTime int64, deltas *deltaAppend) error {
 	// Fetch limits once per push request both to avoid processing half the request differently.
 	var (
 		nativeHistogramsIngestionEnabled = i.limits.NativeHistogramsIngestionEnabled(userID)
 		maxTimestampMs                   = startAppend.Add(i.limits.CreationGracePeriod(userID)).UnixMilli()
 		minTimestampMs                   = int64(math.MinInt64)
+		haSampleDeduplication            = i.limits.AcceptHASamples(userID) && i.limits.HADeduplicationMode(userID) == validation.HADeduplicationModeSample
 	)
 	if i.limits.PastGracePeriod(userID) > 0 {
 		minTimestampMs = startAppend.Add(-i.limits.PastGracePeriod(userID)).Add(-i.limits.OutOfOrderTimeWindow(userID)).UnixMilli()
@@ -1457,8 +1483,10 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 	var builder labels.ScratchBuilder
 	var nonCopiedLabels labels.Labels
 
+	db := i.getTSDB(userID)
+
 	// idx is used to decrease active series count in case of error for cost attribution.
-	idx := i.getTSDB(userID).Head().MustIndex()
+	idx := db.Head().MustIndex()
 	defer idx.Close()
 
 	for _, ts := range timeseries {
@@ -1514,6 +1542,20 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 		// and NOT the stable hashing because we use the stable hashing in ingesters only for query sharding.
 		ref, copiedLabels := app.GetRef(nonCopiedLabels, hash)
 
+		// Samples of series with delta temporality are replaced with cumulative samples before being appended.
+		accumulate := ts.DeltaTemporality && deltas != nil
+		// Samples of series flagged by the distributor as coming from an HA replica already ingested from
+		// another replica are dropped without error.
+		haDeduplicated := ts.HaDeduplicated && haSampleDeduplication
+		var lastCumulative cumulativeSample
+		if accumulate {
+			var err error
+			if lastCumulative, err = deltas.lastCumulativeSample(db, ref, nonCopiedLabels); err != nil {
+				return err
+			}
+			lastCumulative = accumulateDeltas(ts, lastCumulative, nativeHistogramsIngestionEnabled, errProcessor)
+		}
+
 		// To find out if any sample was added to this series, we keep old value.
 		oldSucceededSamplesCount := stats.succeededSamplesCount
 
@@ -1546,6 +1588,11 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 				}
 			}
 
+			if haDeduplicated && isHADuplicateSampleErr(err) {
+				stats.haDeduplicatedSamplesCount++
+				continue
+			}
+
 			// If it's a soft error it will be returned back to the distributor later as a 400.
 			if errProcessor.ProcessErr(err, s.TimestampMs, ts.Labels) {
 				continue
@@ -1595,6 +1642,11 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 					}
 				}
 
+				if haDeduplicated && isHADuplicateSampleErr(err) {
+					stats.haDeduplicatedSamplesCount++
+					continue
+				}
+
 				if errProcessor.ProcessErr(err, h.Timestamp, ts.Labels) {
 					continue
 				}
@@ -1617,6 +1669,10 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 			}
 		}
 
+		if accumulate && ref != 0 {
+			deltas.set(ref, lastCumulative)
+		}
+
 		if activeSeries != nil && stats.succeededSamplesCount > oldSucceededSamplesCount {
 			activeSeries.UpdateSeries(nonCopiedLabels, ref, startAppend, numNativeHistogramBuckets, idx)
 		}
@@ -2009,7 +2065,12 @@ func (i *Ingester) UserStats(ctx context.Context, req *client.UserStatsRequest)
 		return &client.UserStatsResponse{}, nil
 	}
 
-	return createUserStats(db, req)
+	stats, err := createUserStats(db, req)
+	if err != nil {
+		return nil, err
+	}
+	stats.HeadGeneration = db.headGeneration.Load()
+	return stats, nil
 }
 
 // AllUserStats returns some per-tenant statistics about the data ingested in this ingester.
@@ -2755,12 +2816,14 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 		instanceErrors:          i.metrics.rejected,
 		blockMinRetention:       i.cfg.BlocksStorageConfig.TSDB.Retention,
 		useOwnedSeriesForLimits: i.cfg.UseIngesterOwnedSeriesForLimits,
+		deltaAccumulator:        newDeltaAccumulator(),
 
 		ownedState: ownedSeriesState{
 			shardSize:        ownedSeriedStateShardSize, // initialize series shard size so that it's correct even before we update ownedSeries for the first time
 			localSeriesLimit: initialLocalLimit,
 		},
 	}
+	userDB.headGeneration.Store(rand.Uint64())
 	userDB.triggerRecomputeOwnedSeries(recomputeOwnedSeriesReasonNewUser)
 
 	userDBHasDB := atomic.NewBool(false)
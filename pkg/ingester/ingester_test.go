@@ -3647,6 +3647,69 @@ func TestIngester_Push_DecreaseInactiveSeries(t *testing.T) {
 	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedMetrics), metricNames...))
 }
 
+func TestIngester_Push_HASampleDeduplication(t *testing.T) {
+	metricLabelAdapters := [][]mimirpb.LabelAdapter{{{Name: labels.MetricName, Value: "test"}}}
+	metricNames := []string{
+		"cortex_ingester_ingested_samples_total",
+		"cortex_ingester_ingested_samples_failures_total",
+		"cortex_ingester_ha_deduplicated_samples_total",
+	}
+
+	registry := prometheus.NewRegistry()
+
+	limits := defaultLimitsTestConfig()
+	limits.AcceptHASamples = true
+	limits.HADeduplicationMode = validation.HADeduplicationModeSample
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, nil, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until the ingester is healthy
+	test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	// Samples of both replicas are pushed: only the first sample of each timestamp is kept,
+	// and samples older than the latest one of the series are dropped without error.
+	ctx := user.InjectOrgID(context.Background(), "test")
+	for _, sample := range []mimirpb.Sample{
+		{Value: 1, TimestampMs: 10}, {Value: 2, TimestampMs: 20},
+		{Value: 1.5, TimestampMs: 10}, {Value: 2.5, TimestampMs: 20}, {Value: 3.5, TimestampMs: 30},
+		{Value: 0.5, TimestampMs: 5},
+	} {
+		req := mimirpb.ToWriteRequest(metricLabelAdapters, []mimirpb.Sample{sample}, nil, nil, mimirpb.API)
+		req.Timeseries[0].HaDeduplicated = true
+		_, err := i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	// Duplicate samples of series which aren't flagged as HA deduplicated by the distributor are rejected.
+	_, err = i.Push(ctx, mimirpb.ToWriteRequest(metricLabelAdapters, []mimirpb.Sample{{Value: 4, TimestampMs: 30}}, nil, nil, mimirpb.API))
+	require.Error(t, err)
+
+	res, _, err := runTestQuery(ctx, t, i, labels.MatchEqual, labels.MetricName, "test")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{{
+		Metric: model.Metric{labels.MetricName: "test"},
+		Values: []model.SamplePair{{Timestamp: 10, Value: 1}, {Timestamp: 20, Value: 2}, {Timestamp: 30, Value: 3.5}},
+	}}, res)
+
+	expectedMetrics := `
+		# HELP cortex_ingester_ingested_samples_total The total number of samples ingested per user.
+		# TYPE cortex_ingester_ingested_samples_total counter
+		cortex_ingester_ingested_samples_total{user="test"} 3
+		# HELP cortex_ingester_ingested_samples_failures_total The total number of samples that errored on ingestion per user.
+		# TYPE cortex_ingester_ingested_samples_failures_total counter
+		cortex_ingester_ingested_samples_failures_total{user="test"} 1
+		# HELP cortex_ingester_ha_deduplicated_samples_total The total number of samples from Prometheus HA replicas dropped by the sample-level HA deduplication per user, because a sample of another replica was already ingested for their series at their timestamp or later.
+		# TYPE cortex_ingester_ha_deduplicated_samples_total counter
+		cortex_ingester_ha_deduplicated_samples_total{user="test"} 3
+	`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedMetrics), metricNames...))
+}
+
 func BenchmarkIngesterPush(b *testing.B) {
 	costAttributionCases := []struct {
 		state          string
@@ -7404,6 +7467,25 @@ func Test_Ingester_UserStats(t *testing.T) {
 	// Active series are considered according to the wall time during the push, not the sample timestamp.
 	// Therefore all three series are still active at this point.
 	assert.Equal(t, uint64(3), res.NumSeries)
+
+	// The head generation only changes when new series are created.
+	headGeneration := res.HeadGeneration
+
+	req, _, _, _ := mockWriteRequest(t, series[0].lbls, series[0].value, series[0].timestamp+1000)
+	_, err = i.Push(ctx, req)
+	require.NoError(t, err)
+
+	res, err = i.UserStats(ctx, &client.UserStatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, headGeneration, res.HeadGeneration)
+
+	req, _, _, _ = mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test_3"), 3, 300000)
+	_, err = i.Push(ctx, req)
+	require.NoError(t, err)
+
+	res, err = i.UserStats(ctx, &client.UserStatsRequest{})
+	require.NoError(t, err)
+	assert.NotEqual(t, headGeneration, res.HeadGeneration)
 }
 
 func Test_Ingester_AllUserStats(t *testing.T) {
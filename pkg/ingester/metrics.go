@@ -27,6 +27,8 @@ type ingesterMetrics struct {
 	ingestedExemplarsFail prometheus.Counter
 	ingestedMetadataFail  prometheus.Counter
 
+	haDeduplicatedSamples *prometheus.CounterVec
+
 	queries          prometheus.Counter
 	queriedSamples   prometheus.Histogram
 	queriedExemplars prometheus.Histogram
@@ -149,6 +151,10 @@ func newIngesterMetrics(
 			Name: "cortex_ingester_ingested_metadata_failures_total",
 			Help: "The total number of metadata that errored on ingestion.",
 		}),
+		haDeduplicatedSamples: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_ha_deduplicated_samples_total",
+			Help: "The total number of samples from Prometheus HA replicas dropped by the sample-level HA deduplication per user, because a sample of another replica was already ingested for their series at their timestamp or later.",
+		}, []string{"user"}),
 		queries: promauto.With(r).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_ingester_queries_total",
 			Help: "The total number of queries the ingester has handled.",
@@ -397,6 +403,7 @@ func newIngesterMetrics(
 func (m *ingesterMetrics) deletePerUserMetrics(userID string) {
 	m.ingestedSamples.DeleteLabelValues(userID)
 	m.ingestedSamplesFail.DeleteLabelValues(userID)
+	m.haDeduplicatedSamples.DeleteLabelValues(userID)
 	m.memMetadataCreatedTotal.DeleteLabelValues(userID)
 	m.memMetadataRemovedTotal.DeleteLabelValues(userID)
 
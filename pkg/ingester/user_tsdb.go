@@ -142,6 +142,13 @@ type userTSDB struct {
 	ownedTokenRanges ring.TokenRanges
 
 	requiresOwnedSeriesUpdate atomic.String // Non-empty string means that we need to recompute "owned series" for the user. Value will be used in the log message.
+
+	// headGeneration changes every time series are created in or deleted from the head. It's initialized with a
+	// random value, so that it changes when the TSDB is reopened too.
+	headGeneration atomic.Uint64
+
+	// deltaAccumulator holds the last cumulative sample of series ingested with delta temporality.
+	deltaAccumulator *deltaAccumulator
 }
 
 func (u *userTSDB) Appender(ctx context.Context) storage.Appender {
@@ -326,6 +333,7 @@ func (u *userTSDB) getSeriesCountAndMinLocalLimit() (int, int) {
 
 func (u *userTSDB) PostCreation(metric labels.Labels) {
 	u.instanceSeriesCount.Inc()
+	u.headGeneration.Inc()
 
 	// If series was just created, it must belong to this ingester. (Unless it was created while replaying WAL,
 	// but we will recompute owned series when ingester joins the ring.)
@@ -343,6 +351,7 @@ func (u *userTSDB) PostCreation(metric labels.Labels) {
 
 func (u *userTSDB) PostDeletion(metrics map[chunks.HeadSeriesRef]labels.Labels) {
 	u.instanceSeriesCount.Sub(int64(len(metrics)))
+	u.headGeneration.Inc()
 
 	for _, lbls := range metrics {
 		metricName, err := extract.MetricNameFromLabels(lbls)
@@ -357,6 +366,7 @@ func (u *userTSDB) PostDeletion(metrics map[chunks.HeadSeriesRef]labels.Labels)
 	// Instead, we recompute owned series after each compaction.
 
 	u.activeSeries.PostDeletion(metrics)
+	u.deltaAccumulator.delete(metrics)
 }
 
 // blocksToDelete filters the input blocks and returns the blocks which are safe to be deleted from the ingester.
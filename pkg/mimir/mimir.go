@@ -277,6 +277,9 @@ func (c *Config) Validate(log log.Logger) error {
 			return errors.New("cannot disable Push gRPC method in ingester, while ingest storage (-ingest-storage.enabled) is not enabled")
 		}
 	}
+	if c.IngestStorage.Enabled && c.Distributor.WriteBuffer.Enabled {
+		return errors.New("the distributor write buffer (-distributor.write-buffer.enabled) is not supported with the ingest storage (-ingest-storage.enabled)")
+	}
 	if err := c.BlocksStorage.Validate(c.Ingester.ActiveSeriesMetrics); err != nil {
 		return errors.Wrap(err, "invalid TSDB config")
 	}
@@ -448,6 +451,15 @@ func (c *Config) validateFilesystemPaths(logger log.Logger) error {
 		})
 	}
 
+	// Distributor.
+	if c.isAnyModuleEnabled(All, Distributor, Write) && c.Distributor.WriteBuffer.Enabled {
+		paths = append(paths, pathConfig{
+			name:       "distributor write buffer directory",
+			cfgValue:   c.Distributor.WriteBuffer.Dir,
+			checkValue: c.Distributor.WriteBuffer.Dir,
+		})
+	}
+
 	// Store-gateway.
 	if c.isAnyModuleEnabled(All, StoreGateway, Backend) {
 		paths = append(paths, pathConfig{
@@ -758,6 +770,7 @@ type Mimir struct {
 	QuerierQueryable                 prom_storage.SampleAndChunkQueryable
 	ExemplarQueryable                prom_storage.ExemplarQueryable
 	AdditionalStorageQueryables      []querier.TimeRangeQueryable
+	SeriesDeletions                  *querier.SeriesDeletions
 	MetadataSupplier                 querier.MetadataSupplier
 	QuerierEngine                    promql.QueryEngine
 	QueryFrontendTripperware         querymiddleware.Tripperware
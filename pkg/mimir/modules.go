@@ -10,17 +10,18 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/dns"
-	httpgrpc_server "github.com/grafana/dskit/httpgrpc/server"
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/kv/memberlist"
 	"github.com/grafana/dskit/modules"
 	"github.com/grafana/dskit/ring"
+	ring_client "github.com/grafana/dskit/ring/client"
 	"github.com/grafana/dskit/server"
 	"github.com/grafana/dskit/services"
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
@@ -59,7 +60,9 @@ import (
 	"github.com/grafana/mimir/pkg/scheduler"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/ingest"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storegateway"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
@@ -94,6 +97,7 @@ const (
 	OverridesExporter                string = "overrides-exporter"
 	Querier                          string = "querier"
 	QueryFrontend                    string = "query-frontend"
+	QueryFrontendCacheGenerations    string = "query-frontend-cache-generations"
 	QueryFrontendCodec               string = "query-frontend-codec"
 	QueryFrontendTopicOffsetsReaders string = "query-frontend-topic-offsets-reader"
 	QueryFrontendTripperware         string = "query-frontend-tripperware"
@@ -103,6 +107,7 @@ const (
 	RulerStorage                     string = "ruler-storage"
 	RuntimeConfig                    string = "runtime-config"
 	SanityCheck                      string = "sanity-check"
+	SeriesDeletions                  string = "series-deletions"
 	Server                           string = "server"
 	StoreGateway                     string = "store-gateway"
 	StoreQueryable                   string = "store-queryable"
@@ -502,21 +507,39 @@ func (t *Mimir) initQueryable() (serv services.Service, err error) {
 		t.Server.HTTP.Use(querier.FilterQueryablesMiddleware().Wrap)
 	}
 
+	var remoteExecutionClient remoteexec.ClosableRemoteExecutionClient
+	if t.Cfg.Querier.RemoteExecutionEnabled() {
+		remoteExecutionClient, err = remoteexec.NewClient(t.Cfg.Querier.MimirQueryEngineRemoteExecution, registerer, util_log.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("could not create Mimir query engine remote execution client: %w", err)
+		}
+
+		// Close the client's connection when the querier shuts down.
+		serv = services.NewIdleService(nil, func(error) error {
+			return remoteExecutionClient.Close()
+		})
+	}
+
 	// Create a querier queryable and PromQL engine
 	t.QuerierQueryable, t.ExemplarQueryable, t.QuerierEngine, err = querier.New(
-		t.Cfg.Querier, t.Overrides, t.Distributor, t.AdditionalStorageQueryables, registerer, util_log.Logger, t.ActivityTracker,
+		t.Cfg.Querier, t.Overrides, t.Distributor, t.AdditionalStorageQueryables, remoteExecutionClient, registerer, util_log.Logger, t.ActivityTracker,
 	)
 	if err != nil {
+		if remoteExecutionClient != nil {
+			_ = remoteExecutionClient.Close()
+		}
 		return nil, fmt.Errorf("could not create queryable: %w", err)
 	}
 
+	t.QuerierQueryable = querier.NewSampleAndChunkQueryable(querier.NewSeriesDeletionQueryable(t.QuerierQueryable, t.SeriesDeletions))
+
 	// Use the distributor to return metric metadata by default
 	t.MetadataSupplier = t.Distributor
 
 	// Register the default endpoints that are always enabled for the querier module
 	t.API.RegisterQueryable(t.Distributor)
 
-	return nil, nil
+	return serv, nil
 }
 
 // Enable merge querier if multi tenant query federation is enabled
@@ -606,6 +629,11 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 		t.Overrides,
 	)
 
+	// Allow other queriers to send expressions to this querier if it's running the Mimir query engine.
+	if remoteExecutionServer := querier.NewRemoteExecutionServer(t.QuerierEngine, t.QuerierQueryable); remoteExecutionServer != nil {
+		t.API.RegisterRemoteExecution(remoteExecutionServer)
+	}
+
 	// If the querier is running standalone without the query-frontend or query-scheduler, we must register it's internal
 	// HTTP handler externally and provide the external Mimir Server HTTP handler to the frontend worker
 	// to ensure requests it processes use the default middleware instrumentation.
@@ -642,7 +670,7 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 		return nil, nil
 	}
 
-	return querier_worker.NewQuerierWorker(t.Cfg.Worker, httpgrpc_server.NewServer(internalQuerierRouter, httpgrpc_server.WithReturn4XXErrors), util_log.Logger, t.Registerer)
+	return querier_worker.NewQuerierWorker(t.Cfg.Worker, querier_worker.NewStreamingRequestHandler(internalQuerierRouter), util_log.Logger, t.Registerer)
 }
 
 func (t *Mimir) initStoreQueryable() (services.Service, error) {
@@ -656,6 +684,29 @@ func (t *Mimir) initStoreQueryable() (services.Service, error) {
 	return q, nil
 }
 
+// initSeriesDeletions instantiates the series deletions, whose samples are filtered out of query results by
+// queriers and rulers, and whose query results aren't cached by the query-frontend.
+func (t *Mimir) initSeriesDeletions() (services.Service, error) {
+	loader, err := querier.NewSeriesDeletionRequestsLoaderFromConfig(t.Cfg.Querier, t.Cfg.BlocksStorage, t.Overrides, util_log.Logger, t.Registerer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize series deletion requests loader: %v", err)
+	}
+
+	// The compactor purges the samples expired by the series retention rules from the blocks whose time range ends
+	// before the retention boundary, at the next compaction, and the store-gateways stop querying the original blocks
+	// once they ignore their deletion marks.
+	retentionPurgeDelay := t.Cfg.Compactor.CompactionInterval + t.Cfg.BlocksStorage.BucketStore.IgnoreDeletionMarksInStoreGatewayDelay + t.Cfg.BlocksStorage.BucketStore.SyncInterval
+	if len(t.Cfg.Compactor.BlockRanges) > 0 {
+		retentionPurgeDelay += slices.Max(t.Cfg.Compactor.BlockRanges)
+	}
+
+	t.SeriesDeletions = querier.NewSeriesDeletions(loader, t.Overrides, retentionPurgeDelay)
+	if loader == nil {
+		return nil, nil
+	}
+	return loader, nil
+}
+
 func (t *Mimir) initActiveGroupsCleanupService() (services.Service, error) {
 	t.ActiveGroupsCleanup = util.NewActiveGroupsCleanupService(3*time.Minute, t.Cfg.Ingester.ActiveSeriesMetrics.IdleTimeout, t.Cfg.MaxSeparateMetricsGroupsPerUser)
 	return t.ActiveGroupsCleanup, nil
@@ -766,13 +817,72 @@ func (t *Mimir) initQueryFrontendTopicOffsetsReaders() (services.Service, error)
 	return ingestTopicOffsetsReader, nil
 }
 
+// initQueryFrontendCacheGenerations instantiates the generations used by the query-frontend to invalidate
+// cached label names and values query results.
+func (t *Mimir) initQueryFrontendCacheGenerations() (services.Service, error) {
+	if !t.Cfg.Frontend.QueryMiddleware.LabelsQueryCacheGenerationEnabled {
+		return nil, nil
+	}
+	if t.Cfg.IngestStorage.Enabled {
+		return nil, errors.New("-query-frontend.labels-query-cache-generation-enabled is not supported when the ingest storage is enabled")
+	}
+
+	registerer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "query-frontend"}, t.Registerer)
+
+	bucketClient, err := bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "query-frontend", util_log.Logger, registerer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create bucket client")
+	}
+
+	bucketIndexes := bucketindex.NewLoader(bucketindex.LoaderConfig{
+		CheckInterval:         time.Minute,
+		UpdateOnStaleInterval: t.Cfg.BlocksStorage.BucketStore.SyncInterval,
+		UpdateOnErrorInterval: t.Cfg.BlocksStorage.BucketStore.BucketIndex.UpdateOnErrorInterval,
+		IdleTimeout:           t.Cfg.BlocksStorage.BucketStore.BucketIndex.IdleTimeout,
+	}, bucketClient, t.Overrides, util_log.Logger, registerer)
+
+	poolCfg := ring_client.PoolConfig{
+		CheckInterval:      t.Cfg.Distributor.PoolConfig.ClientCleanupPeriod,
+		HealthCheckEnabled: t.Cfg.Distributor.PoolConfig.HealthCheckIngesters,
+		HealthCheckTimeout: t.Cfg.Distributor.RemoteTimeout,
+	}
+	// Ingesters are queried by queriers for as long as they may hold data of the tenant, like in distributors.
+	ingesters := querymiddleware.NewIngestersHeadGenerations(t.IngesterRing, t.Cfg.IngesterClient, poolCfg, t.Overrides, t.Cfg.BlocksStorage.TSDB.Retention, util_log.Logger)
+
+	generations := querymiddleware.NewLabelsQueryCacheGenerationsLoader(bucketIndexes, ingesters, t.Cfg.Frontend.QueryMiddleware.LabelsQueryCacheGenerationRefreshInterval, t.Cfg.BlocksStorage.BucketStore.BucketIndex.IdleTimeout, util_log.Logger)
+	t.Cfg.Frontend.QueryMiddleware.LabelsQueryCacheGenerations = generations
+
+	subservices, err := services.NewManager(bucketIndexes, ingesters, generations)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.NewIdleService(func(ctx context.Context) error {
+		return services.StartManagerAndAwaitHealthy(ctx, subservices)
+	}, func(error) error {
+		return services.StopManagerAndAwaitStopped(context.Background(), subservices)
+	}), nil
+}
+
 // initQueryFrontendTripperware instantiates the tripperware used by the query frontend
 // to optimize Prometheus query requests.
 func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error) {
+	if t.Cfg.Frontend.QueryMiddleware.SubstituteRecordingRules {
+		if t.RulerStorage == nil {
+			level.Warn(util_log.Logger).Log("msg", "The ruler storage has not been configured. Recording rules won't be substituted in queries.")
+		} else {
+			recordingRules := querymiddleware.NewRecordingRulesLoader(t.RulerStorage, t.Cfg.Frontend.QueryMiddleware.RecordingRulesRefreshInterval, t.Cfg.Ruler.EvaluationInterval, util_log.Logger)
+			t.Cfg.Frontend.QueryMiddleware.RecordingRules = recordingRules
+			serv = recordingRules
+		}
+	}
+
 	promqlEngineRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "query-frontend"}, t.Registerer)
 
 	engineOpts, _ := engine.NewPromQLEngineOptions(t.Cfg.Querier.EngineConfig, t.ActivityTracker, util_log.Logger, promqlEngineRegisterer)
 
+	t.Cfg.Frontend.QueryMiddleware.SeriesDeletions = t.SeriesDeletions
+
 	tripperware, err := querymiddleware.NewTripperware(
 		t.Cfg.Frontend.QueryMiddleware,
 		util_log.Logger,
@@ -788,7 +898,7 @@ func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error
 	}
 
 	t.QueryFrontendTripperware = tripperware
-	return nil, nil
+	return serv, nil
 }
 
 func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
@@ -903,11 +1013,13 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 		// TODO: Consider wrapping logger to differentiate from querier module logger
 		rulerRegisterer := prometheus.WrapRegistererWith(rulerEngine, t.Registerer)
 
-		queryable, _, eng, err := querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.AdditionalStorageQueryables, rulerRegisterer, util_log.Logger, t.ActivityTracker)
+		queryable, _, eng, err := querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.AdditionalStorageQueryables, nil, rulerRegisterer, util_log.Logger, t.ActivityTracker)
 		if err != nil {
 			return nil, fmt.Errorf("could not create queryable for ruler: %w", err)
 		}
 
+		queryable = querier.NewSeriesDeletionQueryable(queryable, t.SeriesDeletions)
+
 		queryable = querier.NewErrorTranslateQueryableWithFn(queryable, ruler.WrapQueryableErrors)
 
 		if t.Cfg.Ruler.TenantFederation.Enabled {
@@ -1188,6 +1300,7 @@ func (t *Mimir) setupModuleManager() error {
 	mm.RegisterModule(OverridesExporter, t.initOverridesExporter)
 	mm.RegisterModule(Querier, t.initQuerier)
 	mm.RegisterModule(QueryFrontend, t.initQueryFrontend)
+	mm.RegisterModule(QueryFrontendCacheGenerations, t.initQueryFrontendCacheGenerations, modules.UserInvisibleModule)
 	mm.RegisterModule(QueryFrontendCodec, t.initQueryFrontendCodec, modules.UserInvisibleModule)
 	mm.RegisterModule(QueryFrontendTopicOffsetsReaders, t.initQueryFrontendTopicOffsetsReaders, modules.UserInvisibleModule)
 	mm.RegisterModule(QueryFrontendTripperware, t.initQueryFrontendTripperware, modules.UserInvisibleModule)
@@ -1197,6 +1310,7 @@ func (t *Mimir) setupModuleManager() error {
 	mm.RegisterModule(RulerStorage, t.initRulerStorage, modules.UserInvisibleModule)
 	mm.RegisterModule(RuntimeConfig, t.initRuntimeConfig, modules.UserInvisibleModule)
 	mm.RegisterModule(SanityCheck, t.initSanityCheck, modules.UserInvisibleModule)
+	mm.RegisterModule(SeriesDeletions, t.initSeriesDeletions, modules.UserInvisibleModule)
 	mm.RegisterModule(Server, t.initServer, modules.UserInvisibleModule)
 	mm.RegisterModule(StoreGateway, t.initStoreGateway)
 	mm.RegisterModule(StoreQueryable, t.initStoreQueryable, modules.UserInvisibleModule)
@@ -1232,13 +1346,15 @@ func (t *Mimir) setupModuleManager() error {
 		OverridesExporter:                {Overrides, MemberlistKV, Vault},
 		Querier:                          {TenantFederation, Vault},
 		QueryFrontend:                    {QueryFrontendTripperware, MemberlistKV, Vault},
+		QueryFrontendCacheGenerations:    {Overrides},
 		QueryFrontendTopicOffsetsReaders: {IngesterPartitionRing},
-		QueryFrontendTripperware:         {API, Overrides, QueryFrontendCodec, QueryFrontendTopicOffsetsReaders},
+		QueryFrontendTripperware:         {API, Overrides, QueryFrontendCodec, QueryFrontendTopicOffsetsReaders, QueryFrontendCacheGenerations, SeriesDeletions},
 		QueryScheduler:                   {API, Overrides, MemberlistKV, Vault},
-		Queryable:                        {Overrides, DistributorService, IngesterRing, IngesterPartitionRing, API, StoreQueryable, MemberlistKV},
-		Ruler:                            {DistributorService, StoreQueryable, RulerStorage, Vault},
+		Queryable:                        {Overrides, DistributorService, IngesterRing, IngesterPartitionRing, API, StoreQueryable, MemberlistKV, SeriesDeletions},
+		Ruler:                            {DistributorService, StoreQueryable, RulerStorage, Vault, SeriesDeletions},
 		RulerStorage:                     {Overrides},
 		RuntimeConfig:                    {API},
+		SeriesDeletions:                  {Overrides},
 		Server:                           {ActivityTracker, SanityCheck, UsageStats},
 		StoreGateway:                     {API, Overrides, MemberlistKV, Vault},
 		StoreQueryable:                   {Overrides, MemberlistKV},
@@ -1250,6 +1366,15 @@ func (t *Mimir) setupModuleManager() error {
 
 		All: {QueryFrontend, Querier, Ingester, Distributor, StoreGateway, Ruler, Compactor},
 	}
+	if t.Cfg.Frontend.QueryMiddleware.SubstituteRecordingRules {
+		// The query-frontend loads recording rules from the ruler storage to substitute them in queries.
+		deps[QueryFrontendTripperware] = append(deps[QueryFrontendTripperware], RulerStorage)
+	}
+	if t.Cfg.Frontend.QueryMiddleware.LabelsQueryCacheGenerationEnabled {
+		// The query-frontend reads the generation of the TSDB head of tenants from ingesters.
+		deps[QueryFrontendCacheGenerations] = append(deps[QueryFrontendCacheGenerations], IngesterRing)
+	}
+
 	for mod, targets := range deps {
 		if err := mm.AddDependency(mod, targets...); err != nil {
 			return err
@@ -392,6 +392,12 @@ type TimeSeries struct {
 	Samples    []Sample    `protobuf:"bytes,2,rep,name=samples,proto3" json:"samples"`
 	Exemplars  []Exemplar  `protobuf:"bytes,3,rep,name=exemplars,proto3" json:"exemplars"`
 	Histograms []Histogram `protobuf:"bytes,4,rep,name=histograms,proto3" json:"histograms"`
+	// Samples and histograms are deltas from the previous sample of the series, and are accumulated
+	// into cumulative values by the ingester.
+	DeltaTemporality bool `protobuf:"varint,1000,opt,name=delta_temporality,json=deltaTemporality,proto3" json:"delta_temporality,omitempty"`
+	// Samples and histograms come from a Prometheus HA replica deduplicated per sample, and the ingester
+	// drops the ones already ingested from another replica.
+	HaDeduplicated bool `protobuf:"varint,1001,opt,name=ha_deduplicated,json=haDeduplicated,proto3" json:"ha_deduplicated,omitempty"`
 
 	// Skip unmarshaling of exemplars.
 	SkipUnmarshalingExemplars bool
@@ -450,6 +456,20 @@ func (m *TimeSeries) GetHistograms() []Histogram {
 	return nil
 }
 
+func (m *TimeSeries) GetDeltaTemporality() bool {
+	if m != nil {
+		return m.DeltaTemporality
+	}
+	return false
+}
+
+func (m *TimeSeries) GetHaDeduplicated() bool {
+	if m != nil {
+		return m.HaDeduplicated
+	}
+	return false
+}
+
 type LabelPair struct {
 	Name  []byte `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -1925,134 +1945,137 @@ func init() {
 func init() { proto.RegisterFile("mimir.proto", fileDescriptor_86d4d7485f544059) }
 
 var fileDescriptor_86d4d7485f544059 = []byte{
-	// 2029 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xcf, 0x93, 0xdb, 0x48,
-	0xf5, 0xb7, 0x6c, 0xf9, 0x87, 0xde, 0xd8, 0x33, 0x9d, 0x4e, 0x36, 0x5f, 0x6f, 0xbe, 0x1b, 0x27,
-	0xd1, 0x16, 0xcb, 0x90, 0x82, 0x09, 0xb5, 0x81, 0x6c, 0x6d, 0x2a, 0xfc, 0x90, 0x6d, 0x25, 0xe3,
-	0xc4, 0x96, 0x67, 0x5b, 0xf2, 0x84, 0x70, 0x51, 0x69, 0x3c, 0x3d, 0x33, 0xaa, 0xb5, 0x2c, 0x23,
-	0xc9, 0xd9, 0x0c, 0x27, 0x2e, 0x50, 0x14, 0x27, 0x2e, 0x5c, 0x28, 0x6e, 0x5c, 0xa8, 0xe2, 0x1f,
-	0x49, 0x15, 0x97, 0x1c, 0x17, 0x0e, 0x29, 0x32, 0xb9, 0x2c, 0x07, 0xaa, 0xb6, 0x28, 0x4e, 0x9c,
-	0xa8, 0xee, 0xd6, 0x4f, 0xcf, 0x0c, 0x04, 0xc8, 0x4d, 0xef, 0xbd, 0xcf, 0x7b, 0xfd, 0xba, 0xdf,
-	0x8f, 0x7e, 0x2d, 0x58, 0xf3, 0x5c, 0xcf, 0x0d, 0xb6, 0x16, 0x81, 0x1f, 0xf9, 0xb8, 0x31, 0xf5,
-	0x83, 0x88, 0x3e, 0x5b, 0xec, 0x5d, 0xf9, 0xc6, 0xa1, 0x1b, 0x1d, 0x2d, 0xf7, 0xb6, 0xa6, 0xbe,
-	0x77, 0xeb, 0xd0, 0x3f, 0xf4, 0x6f, 0x71, 0xc0, 0xde, 0xf2, 0x80, 0x53, 0x9c, 0xe0, 0x5f, 0x42,
-	0x51, 0xfd, 0x6b, 0x19, 0x9a, 0x8f, 0x03, 0x37, 0xa2, 0x84, 0xfe, 0x68, 0x49, 0xc3, 0x08, 0xef,
-	0x00, 0x44, 0xae, 0x47, 0x43, 0x1a, 0xb8, 0x34, 0x6c, 0x4b, 0xd7, 0x2b, 0x9b, 0x6b, 0x1f, 0x5e,
-	0xda, 0x4a, 0xcc, 0x6f, 0x59, 0xae, 0x47, 0x4d, 0x2e, 0xeb, 0x5e, 0x79, 0xfe, 0xf2, 0x5a, 0xe9,
-	0x4f, 0x2f, 0xaf, 0xe1, 0x9d, 0x80, 0x3a, 0xb3, 0x99, 0x3f, 0xb5, 0x52, 0x3d, 0x92, 0xb3, 0x81,
-	0x3f, 0x86, 0x9a, 0xe9, 0x2f, 0x83, 0x29, 0x6d, 0x97, 0xaf, 0x4b, 0x9b, 0xeb, 0x1f, 0xde, 0xc8,
-	0xac, 0xe5, 0x57, 0xde, 0x12, 0x20, 0x7d, 0xbe, 0xf4, 0x48, 0xac, 0x80, 0xef, 0x42, 0xc3, 0xa3,
-	0x91, 0xb3, 0xef, 0x44, 0x4e, 0xbb, 0xc2, 0x5d, 0x69, 0x67, 0xca, 0x23, 0x1a, 0x05, 0xee, 0x74,
-	0x14, 0xcb, 0xbb, 0xf2, 0xf3, 0x97, 0xd7, 0x24, 0x92, 0xe2, 0xf1, 0x6d, 0x78, 0x27, 0xfc, 0xd4,
-	0x5d, 0xd8, 0x33, 0x67, 0x8f, 0xce, 0xec, 0xa7, 0xce, 0xcc, 0xdd, 0x77, 0x22, 0xd7, 0x9f, 0xb7,
-	0xbf, 0xa8, 0x5f, 0x97, 0x36, 0x1b, 0xe4, 0x22, 0x93, 0x0e, 0x99, 0x70, 0x37, 0x95, 0xe1, 0xef,
-	0xc2, 0xff, 0xe7, 0x94, 0xa6, 0xfe, 0x72, 0x1e, 0xe5, 0x55, 0xff, 0x22, 0x54, 0xdb, 0xa9, 0x6a,
-	0x8f, 0x21, 0x32, 0x7d, 0xf5, 0x1a, 0x40, 0xb6, 0x0d, 0x5c, 0x87, 0x8a, 0xb6, 0x33, 0x40, 0x25,
-	0xdc, 0x00, 0x99, 0x4c, 0x86, 0x3a, 0x92, 0xd4, 0x0d, 0x68, 0xc5, 0x9b, 0x0e, 0x17, 0xfe, 0x3c,
-	0xa4, 0xea, 0x5d, 0x68, 0xea, 0x41, 0xe0, 0x07, 0x7d, 0x1a, 0x39, 0xee, 0x2c, 0xc4, 0x37, 0xa1,
-	0xda, 0x73, 0x96, 0x21, 0x6d, 0x4b, 0xfc, 0xb0, 0x72, 0x47, 0xcf, 0x61, 0x5c, 0x46, 0x04, 0x44,
-	0xfd, 0xbb, 0x04, 0x90, 0x05, 0x04, 0x6b, 0x50, 0xe3, 0x7e, 0x27, 0x61, 0xbb, 0x98, 0xe9, 0x72,
-	0x67, 0x77, 0x1c, 0x37, 0xe8, 0x5e, 0x8a, 0xa3, 0xd6, 0xe4, 0x2c, 0x6d, 0xdf, 0x59, 0x44, 0x34,
-	0x20, 0xb1, 0x22, 0xfe, 0x26, 0xd4, 0x43, 0xc7, 0x5b, 0xcc, 0x68, 0xd8, 0x2e, 0x73, 0x1b, 0x28,
-	0xb3, 0x61, 0x72, 0x01, 0x3f, 0xe7, 0x12, 0x49, 0x60, 0xf8, 0x0e, 0x28, 0xf4, 0x19, 0xf5, 0x16,
-	0x33, 0x27, 0x08, 0xe3, 0x18, 0xe1, 0x9c, 0xcf, 0xb1, 0x28, 0xd6, 0xca, 0xa0, 0xf8, 0x63, 0x80,
-	0x23, 0x37, 0x8c, 0xfc, 0xc3, 0xc0, 0xf1, 0xc2, 0xb6, 0xbc, 0xea, 0xf0, 0x76, 0x22, 0x8b, 0x35,
-	0x73, 0x60, 0xf5, 0xdb, 0xa0, 0xa4, 0xfb, 0xc1, 0x18, 0xe4, 0xb9, 0xe3, 0x89, 0xe3, 0x6a, 0x12,
-	0xfe, 0x8d, 0x2f, 0x41, 0xf5, 0xa9, 0x33, 0x5b, 0x8a, 0x84, 0x6b, 0x12, 0x41, 0xa8, 0x1a, 0xd4,
-	0xc4, 0x16, 0xf0, 0x0d, 0x68, 0xf2, 0xfc, 0x8c, 0x1c, 0x6f, 0x61, 0x7b, 0x21, 0x87, 0x55, 0xc8,
-	0x5a, 0xca, 0x1b, 0x85, 0x99, 0x09, 0x66, 0x57, 0x4a, 0x4c, 0xfc, 0xba, 0x0c, 0xeb, 0xc5, 0xb4,
-	0xc3, 0x1f, 0x81, 0x1c, 0x1d, 0x2f, 0x92, 0x70, 0xbd, 0x7f, 0x5e, 0x7a, 0xc6, 0xa4, 0x75, 0xbc,
-	0xa0, 0x84, 0x2b, 0xe0, 0xaf, 0x03, 0xf6, 0x38, 0xcf, 0x3e, 0x70, 0x3c, 0x77, 0x76, 0x6c, 0xf3,
-	0x6d, 0x30, 0x57, 0x14, 0x82, 0x84, 0xe4, 0x3e, 0x17, 0x18, 0x6c, 0x4b, 0x18, 0xe4, 0x23, 0x3a,
-	0x5b, 0xb4, 0x65, 0x2e, 0xe7, 0xdf, 0x8c, 0xb7, 0x9c, 0xbb, 0x51, 0xbb, 0x2a, 0x78, 0xec, 0x5b,
-	0x3d, 0x06, 0xc8, 0x56, 0xc2, 0x6b, 0x50, 0x9f, 0x18, 0x8f, 0x8c, 0xf1, 0x63, 0x03, 0x95, 0x18,
-	0xd1, 0x1b, 0x4f, 0x0c, 0x4b, 0x27, 0x48, 0xc2, 0x0a, 0x54, 0x1f, 0x68, 0x93, 0x07, 0x3a, 0x2a,
-	0xe3, 0x16, 0x28, 0xdb, 0x03, 0xd3, 0x1a, 0x3f, 0x20, 0xda, 0x08, 0x55, 0x30, 0x86, 0x75, 0x2e,
-	0xc9, 0x78, 0x32, 0x53, 0x35, 0x27, 0xa3, 0x91, 0x46, 0x9e, 0xa0, 0x2a, 0x4b, 0xe6, 0x81, 0x71,
-	0x7f, 0x8c, 0x6a, 0xb8, 0x09, 0x0d, 0xd3, 0xd2, 0x2c, 0xdd, 0xd4, 0x2d, 0x54, 0x57, 0x1f, 0x41,
-	0x4d, 0x2c, 0xfd, 0x16, 0x12, 0x51, 0xfd, 0x99, 0x04, 0x8d, 0x24, 0x79, 0xde, 0x46, 0x62, 0x17,
-	0x52, 0x22, 0x89, 0xe7, 0xa9, 0x44, 0xa8, 0x9c, 0x4a, 0x04, 0xf5, 0x0f, 0x55, 0x50, 0xd2, 0x64,
-	0xc4, 0x57, 0x41, 0x11, 0x4d, 0xc1, 0x9d, 0x47, 0x3c, 0xe4, 0xf2, 0x76, 0x89, 0x34, 0x38, 0x6b,
-	0x30, 0x8f, 0xf0, 0x0d, 0x58, 0x13, 0xe2, 0x83, 0x99, 0xef, 0x44, 0x62, 0xad, 0xed, 0x12, 0x01,
-	0xce, 0xbc, 0xcf, 0x78, 0x18, 0x41, 0x25, 0x5c, 0x7a, 0x7c, 0x25, 0x89, 0xb0, 0x4f, 0x7c, 0x19,
-	0x6a, 0xe1, 0xf4, 0x88, 0x7a, 0x0e, 0x0f, 0xee, 0x05, 0x12, 0x53, 0xf8, 0x2b, 0xb0, 0xfe, 0x63,
-	0x1a, 0xf8, 0x76, 0x74, 0x14, 0xd0, 0xf0, 0xc8, 0x9f, 0xed, 0xf3, 0x40, 0x4b, 0xa4, 0xc5, 0xb8,
-	0x56, 0xc2, 0xc4, 0x1f, 0xc4, 0xb0, 0xcc, 0xaf, 0x1a, 0xf7, 0x4b, 0x22, 0x4d, 0xc6, 0xef, 0x25,
-	0xbe, 0xdd, 0x04, 0x94, 0xc3, 0x09, 0x07, 0xeb, 0xdc, 0x41, 0x89, 0xac, 0xa7, 0x48, 0xe1, 0xa4,
-	0x06, 0xeb, 0x73, 0x7a, 0xe8, 0x44, 0xee, 0x53, 0x6a, 0x87, 0x0b, 0x67, 0x1e, 0xb6, 0x1b, 0xab,
-	0x17, 0x41, 0x77, 0x39, 0xfd, 0x94, 0x46, 0xe6, 0xc2, 0x99, 0xc7, 0x15, 0xda, 0x4a, 0x34, 0x18,
-	0x2f, 0xc4, 0x5f, 0x85, 0x8d, 0xd4, 0xc4, 0x3e, 0x9d, 0x45, 0x4e, 0xd8, 0x56, 0xae, 0x57, 0x36,
-	0x31, 0x49, 0x2d, 0xf7, 0x39, 0xb7, 0x00, 0xe4, 0xbe, 0x85, 0x6d, 0xb8, 0x5e, 0xd9, 0x94, 0x32,
-	0x20, 0x77, 0x8c, 0xb5, 0xb7, 0xf5, 0x85, 0x1f, 0xba, 0x39, 0xa7, 0xd6, 0xfe, 0xbd, 0x53, 0x89,
-	0x46, 0xea, 0x54, 0x6a, 0x22, 0x76, 0xaa, 0x29, 0x9c, 0x4a, 0xd8, 0x99, 0x53, 0x29, 0x30, 0x76,
-	0xaa, 0x25, 0x9c, 0x4a, 0xd8, 0xb1, 0x53, 0xf7, 0x00, 0x02, 0x1a, 0xd2, 0xc8, 0x3e, 0x62, 0x27,
-	0xbf, 0xce, 0x9b, 0xc0, 0xd5, 0x33, 0xda, 0xd8, 0x16, 0x61, 0xa8, 0x6d, 0x77, 0x1e, 0x11, 0x25,
-	0x48, 0x3e, 0xf1, 0x7b, 0xa0, 0xa4, 0xb9, 0xd6, 0xde, 0xe0, 0xc9, 0x97, 0x31, 0xd4, 0xbb, 0xa0,
-	0xa4, 0x5a, 0xc5, 0x52, 0xae, 0x43, 0xe5, 0x89, 0x6e, 0x22, 0x09, 0xd7, 0xa0, 0x6c, 0x8c, 0x51,
-	0x39, 0x2b, 0xe7, 0xca, 0x15, 0xf9, 0xe7, 0xbf, 0xed, 0x48, 0xdd, 0x3a, 0x54, 0xb9, 0xdf, 0xdd,
-	0x26, 0x40, 0x16, 0x76, 0xf5, 0x6f, 0x32, 0xac, 0xf3, 0x10, 0x67, 0x29, 0x1d, 0x02, 0xe6, 0x32,
-	0x1a, 0xd8, 0x2b, 0x3b, 0x69, 0x75, 0xf5, 0x7f, 0xbc, 0xbc, 0xa6, 0xe5, 0x06, 0x8a, 0x45, 0xe0,
-	0x7b, 0x34, 0x3a, 0xa2, 0xcb, 0x30, 0xff, 0xe9, 0xf9, 0xfb, 0x74, 0x76, 0x2b, 0x6d, 0xd0, 0x5b,
-	0x3d, 0x61, 0x2e, 0xdb, 0x31, 0x9a, 0xae, 0x70, 0xfe, 0xd7, 0x9c, 0xbf, 0x9a, 0xdf, 0x94, 0xc8,
-	0x62, 0xa2, 0xa4, 0x39, 0xcc, 0x8a, 0x5d, 0x48, 0xe2, 0x62, 0xe7, 0xc4, 0x19, 0x95, 0xf7, 0x16,
-	0x32, 0xea, 0x2d, 0x54, 0xca, 0xd7, 0x00, 0xa5, 0x5e, 0xec, 0x71, 0x6c, 0x92, 0x6c, 0x69, 0x0e,
-	0x0a, 0x13, 0x1c, 0x9a, 0xae, 0x96, 0x40, 0x45, 0xb1, 0xa4, 0x35, 0x94, 0x40, 0xdf, 0x87, 0xd6,
-	0x74, 0x19, 0x46, 0xbe, 0x67, 0xf3, 0x56, 0x17, 0xb6, 0x11, 0xc7, 0x35, 0x05, 0x73, 0x97, 0xf3,
-	0x1e, 0xca, 0x0d, 0x09, 0x95, 0x1f, 0xca, 0x8d, 0x1a, 0xaa, 0x3f, 0x94, 0x1b, 0x0a, 0x82, 0x87,
-	0x72, 0xa3, 0x89, 0x5a, 0x0f, 0xe5, 0xc6, 0x06, 0x42, 0x24, 0x6b, 0x75, 0x64, 0xa5, 0xc5, 0x90,
-	0xd5, 0xda, 0x26, 0xab, 0x75, 0x95, 0xcf, 0xe3, 0x7b, 0x00, 0xd9, 0x19, 0xb0, 0xd0, 0xfb, 0x07,
-	0x07, 0x21, 0x15, 0xfd, 0xf3, 0x02, 0x89, 0x29, 0xc6, 0x9f, 0xd1, 0xf9, 0x61, 0x74, 0xc4, 0xa3,
-	0xd6, 0x22, 0x31, 0xa5, 0x2e, 0x01, 0x17, 0x33, 0x96, 0x5f, 0xfb, 0x6f, 0x70, 0x85, 0xdf, 0x03,
-	0x25, 0xcd, 0x49, 0xbe, 0x56, 0x61, 0x7a, 0x2c, 0xda, 0x8c, 0xa7, 0xc7, 0x4c, 0x41, 0x9d, 0xc3,
-	0x86, 0x98, 0x16, 0xb2, 0x4a, 0x49, 0xd3, 0x4a, 0x3a, 0x23, 0xad, 0xca, 0x59, 0x5a, 0xdd, 0x86,
-	0x7a, 0x12, 0x1c, 0x31, 0x10, 0xbd, 0x7b, 0xd6, 0x5c, 0xc3, 0x11, 0x24, 0x41, 0xaa, 0x21, 0x6c,
-	0xac, 0xc8, 0x70, 0x07, 0x60, 0xcf, 0x5f, 0xce, 0xf7, 0x9d, 0x78, 0x14, 0x97, 0x36, 0xab, 0x24,
-	0xc7, 0x61, 0xfe, 0xcc, 0xfc, 0xcf, 0x68, 0x90, 0xa4, 0x39, 0x27, 0x18, 0x77, 0xb9, 0x58, 0xd0,
-	0x20, 0x4e, 0x74, 0x41, 0x64, 0xbe, 0xcb, 0x39, 0xdf, 0xd5, 0x19, 0x5c, 0x5c, 0xd9, 0x24, 0x3f,
-	0xdc, 0x42, 0x5b, 0x2a, 0xaf, 0xb4, 0x25, 0xfc, 0xd1, 0xe9, 0x73, 0x7d, 0x77, 0x75, 0x4a, 0x4c,
-	0xed, 0xe5, 0x8f, 0xf4, 0x8f, 0x32, 0xb4, 0x3e, 0x59, 0xd2, 0xe0, 0x38, 0x19, 0x7e, 0xf1, 0x1d,
-	0xa8, 0x85, 0x91, 0x13, 0x2d, 0xc3, 0x78, 0x7c, 0xea, 0x64, 0x76, 0x0a, 0xc0, 0x2d, 0x93, 0xa3,
-	0x48, 0x8c, 0xc6, 0xdf, 0x07, 0xa0, 0x6c, 0x1a, 0xb6, 0xf9, 0xe8, 0x75, 0xea, 0x59, 0x51, 0xd4,
-	0xe5, 0x73, 0x33, 0x1f, 0xbc, 0x14, 0x9a, 0x7c, 0xb2, 0xf3, 0xe0, 0x04, 0x3f, 0x25, 0x85, 0x08,
-	0x02, 0x6f, 0x31, 0x7f, 0x02, 0x77, 0x7e, 0xc8, 0x8f, 0xa9, 0x50, 0xc5, 0x26, 0xe7, 0xf7, 0x9d,
-	0xc8, 0xd9, 0x2e, 0x91, 0x18, 0xc5, 0xf0, 0x4f, 0xe9, 0x34, 0xf2, 0x03, 0xde, 0xa6, 0x0a, 0xf8,
-	0x5d, 0xce, 0x4f, 0xf0, 0x02, 0xc5, 0xed, 0x4f, 0x9d, 0x99, 0x13, 0xf0, 0x3b, 0xba, 0x68, 0x9f,
-	0xf3, 0x53, 0xfb, 0x9c, 0x62, 0x78, 0xcf, 0x89, 0x02, 0xf7, 0x19, 0xef, 0x71, 0x05, 0xfc, 0x88,
-	0xf3, 0x13, 0xbc, 0x40, 0xe1, 0x2b, 0xd0, 0xf8, 0xcc, 0x09, 0xe6, 0xee, 0xfc, 0x50, 0xf4, 0x21,
-	0x85, 0xa4, 0x34, 0xdb, 0xb1, 0x3b, 0x3f, 0xf0, 0xc5, 0x35, 0xac, 0x10, 0x41, 0xa8, 0x1f, 0x40,
-	0x4d, 0x9c, 0x2d, 0xbb, 0x42, 0x74, 0x42, 0xc6, 0x44, 0x4c, 0x8a, 0xe6, 0xa4, 0xd7, 0xd3, 0x4d,
-	0x13, 0x49, 0xe2, 0x3e, 0x51, 0x7f, 0x25, 0x81, 0x92, 0x1e, 0x24, 0x1b, 0x01, 0x8d, 0xb1, 0xa1,
-	0x0b, 0xa8, 0x35, 0x18, 0xe9, 0xe3, 0x89, 0x85, 0x24, 0x36, 0x0f, 0xf6, 0x34, 0xa3, 0xa7, 0x0f,
-	0xf5, 0xbe, 0x98, 0x2b, 0xf5, 0x1f, 0xe8, 0xbd, 0x89, 0x35, 0x18, 0x1b, 0xa8, 0xc2, 0x84, 0x5d,
-	0xad, 0x6f, 0xf7, 0x35, 0x4b, 0x43, 0x32, 0xa3, 0x06, 0x6c, 0x14, 0x35, 0xb4, 0x21, 0xaa, 0xe2,
-	0x0d, 0x58, 0x9b, 0x18, 0xda, 0xae, 0x36, 0x18, 0x6a, 0xdd, 0xa1, 0x8e, 0x6a, 0x4c, 0xd7, 0x18,
-	0x5b, 0xf6, 0xfd, 0xf1, 0xc4, 0xe8, 0xa3, 0x3a, 0x9b, 0x49, 0x19, 0xa9, 0xf5, 0x7a, 0xfa, 0x8e,
-	0xc5, 0x21, 0x8d, 0xf8, 0x9e, 0xab, 0x81, 0xcc, 0xc6, 0x6b, 0x55, 0x07, 0xc8, 0x22, 0x54, 0x9c,
-	0xde, 0x95, 0xf3, 0xa6, 0xbd, 0xd3, 0x3d, 0x43, 0xfd, 0xa9, 0x04, 0x90, 0x45, 0x0e, 0xdf, 0xc9,
-	0x9e, 0x43, 0x62, 0xf2, 0xbc, 0xbc, 0x1a, 0xe0, 0xb3, 0x1f, 0x45, 0xdf, 0x2b, 0x3c, 0x6e, 0xca,
-	0xab, 0x4d, 0x40, 0xa8, 0xfe, 0xab, 0x27, 0x8e, 0x0d, 0xcd, 0xbc, 0x7d, 0xd6, 0x1c, 0xc5, 0x93,
-	0x80, 0xfb, 0xa1, 0x90, 0x98, 0xfa, 0xef, 0xc7, 0xda, 0x5f, 0x48, 0xb0, 0xb1, 0xe2, 0xc6, 0xb9,
-	0x8b, 0x14, 0x1a, 0x69, 0xf9, 0x0d, 0x1a, 0x69, 0x29, 0x57, 0xf5, 0x6f, 0xe2, 0x0c, 0x0b, 0x5e,
-	0x9a, 0xfe, 0x67, 0x3f, 0xbd, 0xde, 0x24, 0x78, 0x5d, 0x80, 0xac, 0x2a, 0xf0, 0xb7, 0xa0, 0x56,
-	0xf8, 0x89, 0x71, 0x79, 0xb5, 0x76, 0xe2, 0xdf, 0x18, 0xc2, 0xe1, 0x18, 0xab, 0xfe, 0x46, 0x82,
-	0x66, 0x5e, 0x7c, 0xee, 0xa1, 0xfc, 0xe7, 0x2f, 0xe5, 0x6e, 0x21, 0x29, 0xc4, 0xcd, 0xf0, 0xde,
-	0x79, 0xe7, 0xc8, 0x9f, 0x34, 0xa7, 0xf2, 0xe2, 0xe6, 0xef, 0xcb, 0x00, 0xd9, 0x7f, 0x00, 0x7c,
-	0x01, 0x5a, 0xf1, 0x50, 0x68, 0xf7, 0xb4, 0x89, 0xc9, 0x0a, 0xf2, 0x0a, 0x5c, 0x26, 0xfa, 0xce,
-	0x70, 0xd0, 0xd3, 0x4c, 0xbb, 0x3f, 0xe8, 0xdb, 0xac, 0x6e, 0x46, 0x9a, 0xd5, 0xdb, 0x46, 0x12,
-	0x7e, 0x07, 0x2e, 0x58, 0xe3, 0xb1, 0x3d, 0xd2, 0x8c, 0x27, 0x76, 0x6f, 0x38, 0x31, 0x2d, 0x9d,
-	0x98, 0xa8, 0x5c, 0xa8, 0xcc, 0x0a, 0x33, 0x30, 0x30, 0x1e, 0xe8, 0x26, 0x2b, 0x5b, 0x9b, 0x68,
-	0x96, 0x6e, 0x0f, 0x07, 0xa3, 0x81, 0xa5, 0xf7, 0x91, 0x8c, 0xdb, 0x70, 0x89, 0xe8, 0x9f, 0x4c,
-	0x74, 0xd3, 0x2a, 0x4a, 0xaa, 0xac, 0x42, 0x07, 0x86, 0x69, 0xb1, 0xea, 0x17, 0x5c, 0x54, 0xc3,
-	0xff, 0x07, 0x17, 0x4d, 0x9d, 0xec, 0x0e, 0x7a, 0xba, 0x9d, 0xaf, 0xee, 0x3a, 0xbe, 0x04, 0xc8,
-	0x32, 0xfb, 0xdd, 0x02, 0xb7, 0xc1, 0xdc, 0x60, 0xde, 0x75, 0x27, 0xe6, 0x13, 0xa4, 0xb0, 0xa5,
-	0x7a, 0x03, 0xd2, 0x9b, 0x0c, 0x2c, 0xbb, 0x4b, 0x74, 0xed, 0x91, 0x4e, 0xec, 0xf1, 0x8e, 0x6e,
-	0x20, 0xc0, 0x97, 0x01, 0x8f, 0x74, 0x6b, 0x7b, 0x2c, 0xf6, 0xa6, 0x0d, 0x87, 0xe3, 0xc7, 0x7a,
-	0x1f, 0xad, 0x61, 0x04, 0x4d, 0x4b, 0x37, 0x34, 0xc3, 0x8a, 0x1d, 0x68, 0x76, 0xbf, 0xf3, 0xe2,
-	0x55, 0xa7, 0xf4, 0xf9, 0xab, 0x4e, 0xe9, 0xcb, 0x57, 0x1d, 0xe9, 0x27, 0x27, 0x1d, 0xe9, 0x77,
-	0x27, 0x1d, 0xe9, 0xf9, 0x49, 0x47, 0x7a, 0x71, 0xd2, 0x91, 0xfe, 0x7c, 0xd2, 0x91, 0xbe, 0x38,
-	0xe9, 0x94, 0xbe, 0x3c, 0xe9, 0x48, 0xbf, 0x7c, 0xdd, 0x29, 0xbd, 0x78, 0xdd, 0x29, 0x7d, 0xfe,
-	0xba, 0x53, 0xfa, 0x61, 0x9d, 0xff, 0x58, 0x5b, 0xec, 0xed, 0xd5, 0xf8, 0x2f, 0xb2, 0xdb, 0xff,
-	0x0c, 0x00, 0x00, 0xff, 0xff, 0xc3, 0xd5, 0x81, 0xca, 0x6a, 0x13, 0x00, 0x00,
+	// 2069 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xcd, 0x93, 0xdb, 0x48,
+	0x15, 0xb7, 0x6c, 0xf9, 0x43, 0x6f, 0xec, 0x99, 0x4e, 0x27, 0x1b, 0xbc, 0x61, 0xd7, 0x49, 0xb4,
+	0xc5, 0x32, 0xa4, 0x96, 0x09, 0xb5, 0x81, 0x6c, 0x6d, 0x2a, 0x7c, 0xc8, 0xb6, 0x92, 0x71, 0x62,
+	0xcb, 0xb3, 0x2d, 0x39, 0x21, 0x5c, 0x54, 0x1a, 0x4f, 0xcf, 0x58, 0xb5, 0x92, 0x65, 0x24, 0x39,
+	0x9b, 0xe1, 0xc4, 0x05, 0x8a, 0xe2, 0xc4, 0x85, 0x0b, 0xc5, 0x8d, 0x0b, 0x55, 0xfc, 0x23, 0xa9,
+	0x82, 0x43, 0x8e, 0x0b, 0x87, 0x14, 0x99, 0x5c, 0x96, 0x03, 0x55, 0x5b, 0x1c, 0x39, 0x51, 0xdd,
+	0xad, 0x2f, 0x7b, 0x26, 0x10, 0x20, 0x37, 0xbd, 0xf7, 0x7e, 0xef, 0xe9, 0xd7, 0xfd, 0xde, 0x6b,
+	0xbd, 0x16, 0x6c, 0xf8, 0xae, 0xef, 0x86, 0x3b, 0x8b, 0x30, 0x88, 0x03, 0xdc, 0x98, 0x06, 0x61,
+	0x4c, 0x9f, 0x2c, 0xf6, 0x2f, 0x7d, 0xf3, 0xc8, 0x8d, 0x67, 0xcb, 0xfd, 0x9d, 0x69, 0xe0, 0x5f,
+	0x3f, 0x0a, 0x8e, 0x82, 0xeb, 0x1c, 0xb0, 0xbf, 0x3c, 0xe4, 0x12, 0x17, 0xf8, 0x93, 0x70, 0x54,
+	0xff, 0x5e, 0x86, 0xe6, 0xc3, 0xd0, 0x8d, 0x29, 0xa1, 0x3f, 0x5e, 0xd2, 0x28, 0xc6, 0x7b, 0x00,
+	0xb1, 0xeb, 0xd3, 0x88, 0x86, 0x2e, 0x8d, 0xda, 0xd2, 0x95, 0xca, 0xf6, 0xc6, 0x87, 0x17, 0x76,
+	0xd2, 0xf0, 0x3b, 0x96, 0xeb, 0x53, 0x93, 0xdb, 0xba, 0x97, 0x9e, 0x3e, 0xbf, 0x5c, 0xfa, 0xcb,
+	0xf3, 0xcb, 0x78, 0x2f, 0xa4, 0x8e, 0xe7, 0x05, 0x53, 0x2b, 0xf3, 0x23, 0x85, 0x18, 0xf8, 0x63,
+	0xa8, 0x99, 0xc1, 0x32, 0x9c, 0xd2, 0x76, 0xf9, 0x8a, 0xb4, 0xbd, 0xf9, 0xe1, 0xd5, 0x3c, 0x5a,
+	0xf1, 0xcd, 0x3b, 0x02, 0xa4, 0xcf, 0x97, 0x3e, 0x49, 0x1c, 0xf0, 0x2d, 0x68, 0xf8, 0x34, 0x76,
+	0x0e, 0x9c, 0xd8, 0x69, 0x57, 0x38, 0x95, 0x76, 0xee, 0x3c, 0xa2, 0x71, 0xe8, 0x4e, 0x47, 0x89,
+	0xbd, 0x2b, 0x3f, 0x7d, 0x7e, 0x59, 0x22, 0x19, 0x1e, 0xdf, 0x80, 0xb7, 0xa2, 0x4f, 0xdd, 0x85,
+	0xed, 0x39, 0xfb, 0xd4, 0xb3, 0x1f, 0x3b, 0x9e, 0x7b, 0xe0, 0xc4, 0x6e, 0x30, 0x6f, 0x7f, 0x51,
+	0xbf, 0x22, 0x6d, 0x37, 0xc8, 0x79, 0x66, 0x1d, 0x32, 0xe3, 0x83, 0xcc, 0x86, 0xbf, 0x07, 0x5f,
+	0x2d, 0x38, 0x4d, 0x83, 0xe5, 0x3c, 0x2e, 0xba, 0xfe, 0x4d, 0xb8, 0xb6, 0x33, 0xd7, 0x1e, 0x43,
+	0xe4, 0xfe, 0xea, 0x65, 0x80, 0x7c, 0x19, 0xb8, 0x0e, 0x15, 0x6d, 0x6f, 0x80, 0x4a, 0xb8, 0x01,
+	0x32, 0x99, 0x0c, 0x75, 0x24, 0xa9, 0x5b, 0xd0, 0x4a, 0x16, 0x1d, 0x2d, 0x82, 0x79, 0x44, 0xd5,
+	0x5b, 0xd0, 0xd4, 0xc3, 0x30, 0x08, 0xfb, 0x34, 0x76, 0x5c, 0x2f, 0xc2, 0xd7, 0xa0, 0xda, 0x73,
+	0x96, 0x11, 0x6d, 0x4b, 0x7c, 0xb3, 0x0a, 0x5b, 0xcf, 0x61, 0xdc, 0x46, 0x04, 0x44, 0xfd, 0x53,
+	0x19, 0x20, 0x4f, 0x08, 0xd6, 0xa0, 0xc6, 0x79, 0xa7, 0x69, 0x3b, 0x9f, 0xfb, 0x72, 0xb2, 0x7b,
+	0x8e, 0x1b, 0x76, 0x2f, 0x24, 0x59, 0x6b, 0x72, 0x95, 0x76, 0xe0, 0x2c, 0x62, 0x1a, 0x92, 0xc4,
+	0x11, 0x7f, 0x0b, 0xea, 0x91, 0xe3, 0x2f, 0x3c, 0x1a, 0xb5, 0xcb, 0x3c, 0x06, 0xca, 0x63, 0x98,
+	0xdc, 0xc0, 0xf7, 0xb9, 0x44, 0x52, 0x18, 0xbe, 0x09, 0x0a, 0x7d, 0x42, 0xfd, 0x85, 0xe7, 0x84,
+	0x51, 0x92, 0x23, 0x5c, 0xe0, 0x9c, 0x98, 0x12, 0xaf, 0x1c, 0x8a, 0x3f, 0x06, 0x98, 0xb9, 0x51,
+	0x1c, 0x1c, 0x85, 0x8e, 0x1f, 0xb5, 0xe5, 0x75, 0xc2, 0xbb, 0xa9, 0x2d, 0xf1, 0x2c, 0x80, 0xf1,
+	0x07, 0x70, 0xee, 0x80, 0x7a, 0xb1, 0x63, 0xc7, 0xd4, 0x5f, 0x04, 0xa1, 0xe3, 0xb9, 0xf1, 0x71,
+	0x9a, 0x55, 0xc4, 0x2d, 0x56, 0x6e, 0xc0, 0xdb, 0xb0, 0x35, 0x73, 0xec, 0x03, 0x7a, 0xb0, 0x5c,
+	0x78, 0xee, 0xd4, 0x89, 0xe9, 0x41, 0x9a, 0xc6, 0xcd, 0x99, 0xd3, 0x2f, 0xa8, 0xd5, 0xef, 0x80,
+	0x92, 0xed, 0x13, 0xc6, 0x20, 0xcf, 0x1d, 0x5f, 0xa4, 0xa1, 0x49, 0xf8, 0x33, 0xbe, 0x00, 0xd5,
+	0xc7, 0x8e, 0xb7, 0x14, 0x85, 0xdc, 0x24, 0x42, 0x50, 0x35, 0xa8, 0x89, 0xad, 0xc1, 0x57, 0xa1,
+	0xc9, 0xeb, 0x3e, 0x76, 0xfc, 0x85, 0xed, 0x47, 0x1c, 0x56, 0x21, 0x1b, 0x99, 0x6e, 0x14, 0xe5,
+	0x21, 0x58, 0x5c, 0x29, 0x0d, 0xf1, 0x9b, 0x32, 0x6c, 0xae, 0x96, 0x33, 0xfe, 0x08, 0xe4, 0xf8,
+	0x78, 0x91, 0x96, 0xc1, 0x7b, 0xaf, 0x2a, 0xfb, 0x44, 0xb4, 0x8e, 0x17, 0x94, 0x70, 0x07, 0xfc,
+	0x01, 0x60, 0x9f, 0xeb, 0xec, 0x43, 0xc7, 0x77, 0xbd, 0x63, 0x9b, 0x2f, 0x83, 0x51, 0x51, 0x08,
+	0x12, 0x96, 0x3b, 0xdc, 0x60, 0xb0, 0x25, 0x61, 0x90, 0x67, 0xd4, 0x5b, 0xb4, 0x65, 0x6e, 0xe7,
+	0xcf, 0x4c, 0xb7, 0x9c, 0xbb, 0x71, 0xbb, 0x2a, 0x74, 0xec, 0x59, 0x3d, 0x06, 0xc8, 0xdf, 0x84,
+	0x37, 0xa0, 0x3e, 0x31, 0xee, 0x1b, 0xe3, 0x87, 0x06, 0x2a, 0x31, 0xa1, 0x37, 0x9e, 0x18, 0x96,
+	0x4e, 0x90, 0x84, 0x15, 0xa8, 0xde, 0xd5, 0x26, 0x77, 0x75, 0x54, 0xc6, 0x2d, 0x50, 0x76, 0x07,
+	0xa6, 0x35, 0xbe, 0x4b, 0xb4, 0x11, 0xaa, 0x60, 0x0c, 0x9b, 0xdc, 0x92, 0xeb, 0x64, 0xe6, 0x6a,
+	0x4e, 0x46, 0x23, 0x8d, 0x3c, 0x42, 0x55, 0xd6, 0x24, 0x03, 0xe3, 0xce, 0x18, 0xd5, 0x70, 0x13,
+	0x1a, 0xa6, 0xa5, 0x59, 0xba, 0xa9, 0x5b, 0xa8, 0xae, 0xde, 0x87, 0x9a, 0x78, 0xf5, 0x1b, 0x28,
+	0x70, 0xf5, 0xe7, 0x12, 0x34, 0xd2, 0xa2, 0x7c, 0x13, 0x0d, 0xb3, 0x52, 0x12, 0x69, 0x3e, 0x4f,
+	0x15, 0x42, 0xe5, 0x54, 0x21, 0xa8, 0x7f, 0xac, 0x82, 0x92, 0x15, 0x39, 0x7e, 0x17, 0x14, 0x71,
+	0xd8, 0xb8, 0xf3, 0x98, 0xa7, 0x5c, 0xde, 0x2d, 0x91, 0x06, 0x57, 0x0d, 0xe6, 0x31, 0xbe, 0x0a,
+	0x1b, 0xc2, 0x7c, 0xe8, 0x05, 0x4e, 0x2c, 0xde, 0xb5, 0x5b, 0x22, 0xc0, 0x95, 0x77, 0x98, 0x0e,
+	0x23, 0xa8, 0x44, 0x4b, 0x9f, 0xbf, 0x49, 0x22, 0xec, 0x11, 0x5f, 0x84, 0x5a, 0x34, 0x9d, 0x51,
+	0xdf, 0xe1, 0xc9, 0x3d, 0x47, 0x12, 0x09, 0x7f, 0x0d, 0x36, 0x7f, 0x42, 0xc3, 0xc0, 0x8e, 0x67,
+	0x21, 0x8d, 0x66, 0x81, 0x77, 0xc0, 0x13, 0x2d, 0x91, 0x16, 0xd3, 0x5a, 0xa9, 0x12, 0xbf, 0x9f,
+	0xc0, 0x72, 0x5e, 0x35, 0xce, 0x4b, 0x22, 0x4d, 0xa6, 0xef, 0xa5, 0xdc, 0xae, 0x01, 0x2a, 0xe0,
+	0x04, 0xc1, 0x3a, 0x27, 0x28, 0x91, 0xcd, 0x0c, 0x29, 0x48, 0x6a, 0xb0, 0x39, 0xa7, 0x47, 0x4e,
+	0xec, 0x3e, 0xa6, 0x76, 0xb4, 0x70, 0xe6, 0x51, 0xbb, 0xb1, 0xfe, 0x81, 0xe9, 0x2e, 0xa7, 0x9f,
+	0xd2, 0xd8, 0x5c, 0x38, 0xf3, 0xa4, 0xf3, 0x5b, 0xa9, 0x07, 0xd3, 0x45, 0xf8, 0xeb, 0xb0, 0x95,
+	0x85, 0xe0, 0xbd, 0x1e, 0xb5, 0x95, 0x2b, 0x95, 0x6d, 0x4c, 0xb2, 0xc8, 0x7d, 0xae, 0x5d, 0x01,
+	0x72, 0x6e, 0x51, 0x1b, 0xae, 0x54, 0xb6, 0xa5, 0x1c, 0xc8, 0x89, 0xb1, 0x63, 0x73, 0x73, 0x11,
+	0x44, 0x6e, 0x81, 0xd4, 0xc6, 0x7f, 0x26, 0x95, 0x7a, 0x64, 0xa4, 0xb2, 0x10, 0x09, 0xa9, 0xa6,
+	0x20, 0x95, 0xaa, 0x73, 0x52, 0x19, 0x30, 0x21, 0xd5, 0x12, 0xa4, 0x52, 0x75, 0x42, 0xea, 0x36,
+	0x40, 0x48, 0x23, 0x1a, 0xdb, 0x33, 0xb6, 0xf3, 0x9b, 0xfc, 0x10, 0x78, 0xf7, 0x8c, 0xe3, 0x71,
+	0x87, 0x30, 0xd4, 0xae, 0x3b, 0x8f, 0x89, 0x12, 0xa6, 0x8f, 0xf8, 0x1d, 0x50, 0xb2, 0x5a, 0x6b,
+	0x6f, 0xf1, 0xe2, 0xcb, 0x15, 0xea, 0x2d, 0x50, 0x32, 0xaf, 0xd5, 0x56, 0xae, 0x43, 0xe5, 0x91,
+	0x6e, 0x22, 0x09, 0xd7, 0xa0, 0x6c, 0x8c, 0x51, 0x39, 0x6f, 0xe7, 0xca, 0x25, 0xf9, 0x17, 0xbf,
+	0xeb, 0x48, 0xdd, 0x3a, 0x54, 0x39, 0xef, 0x6e, 0x13, 0x20, 0x4f, 0xbb, 0xfa, 0x0f, 0x19, 0x36,
+	0x79, 0x8a, 0xf3, 0x92, 0x8e, 0x00, 0x73, 0x1b, 0x0d, 0xed, 0xb5, 0x95, 0xb4, 0xba, 0xfa, 0x3f,
+	0x9f, 0x5f, 0xd6, 0x0a, 0x83, 0xca, 0x22, 0x0c, 0x7c, 0x1a, 0xcf, 0xe8, 0x32, 0x2a, 0x3e, 0xfa,
+	0xc1, 0x01, 0xf5, 0xae, 0x67, 0x07, 0xff, 0x4e, 0x4f, 0x84, 0xcb, 0x57, 0x8c, 0xa6, 0x6b, 0x9a,
+	0xff, 0xb7, 0xe6, 0xdf, 0x2d, 0x2e, 0x4a, 0x54, 0x31, 0x51, 0xb2, 0x1a, 0x66, 0xcd, 0x2e, 0x2c,
+	0x49, 0xb3, 0x73, 0xe1, 0x8c, 0xce, 0x7b, 0x03, 0x15, 0xf5, 0x06, 0x3a, 0xe5, 0x1b, 0x80, 0x32,
+	0x16, 0xfb, 0x1c, 0x9b, 0x16, 0x5b, 0x56, 0x83, 0x22, 0x04, 0x87, 0x66, 0x6f, 0x4b, 0xa1, 0xa2,
+	0x59, 0xb2, 0x1e, 0x4a, 0xa1, 0xef, 0x41, 0x6b, 0xba, 0x8c, 0xe2, 0xc0, 0xb7, 0xf9, 0x51, 0x17,
+	0xb5, 0x11, 0xc7, 0x35, 0x85, 0xf2, 0x01, 0xd7, 0xdd, 0x93, 0x1b, 0x12, 0x2a, 0xdf, 0x93, 0x1b,
+	0x35, 0x54, 0xbf, 0x27, 0x37, 0x14, 0x04, 0xf7, 0xe4, 0x46, 0x13, 0xb5, 0xee, 0xc9, 0x8d, 0x2d,
+	0x84, 0x48, 0x7e, 0xd4, 0x91, 0xb5, 0x23, 0x86, 0xac, 0xf7, 0x36, 0x59, 0xef, 0xab, 0x62, 0x1d,
+	0xdf, 0x06, 0xc8, 0xf7, 0x80, 0xa5, 0x3e, 0x38, 0x3c, 0x8c, 0xa8, 0x38, 0x3f, 0xcf, 0x91, 0x44,
+	0x62, 0x7a, 0x8f, 0xce, 0x8f, 0xe2, 0x19, 0xcf, 0x5a, 0x8b, 0x24, 0x92, 0xba, 0x04, 0xbc, 0x5a,
+	0xb1, 0xfc, 0xb3, 0xff, 0x1a, 0x9f, 0xf0, 0xdb, 0xa0, 0x64, 0x35, 0xc9, 0xdf, 0xb5, 0x32, 0x95,
+	0xae, 0xc6, 0x4c, 0xa6, 0xd2, 0xdc, 0x41, 0x9d, 0xc3, 0x96, 0x98, 0x16, 0xf2, 0x4e, 0xc9, 0xca,
+	0x4a, 0x3a, 0xa3, 0xac, 0xca, 0x79, 0x59, 0xdd, 0x80, 0x7a, 0x9a, 0x1c, 0x31, 0x68, 0xbd, 0x7d,
+	0xd6, 0xbc, 0xc4, 0x11, 0x24, 0x45, 0xaa, 0x11, 0x6c, 0xad, 0xd9, 0x70, 0x07, 0x60, 0x3f, 0x58,
+	0xce, 0x0f, 0x9c, 0x64, 0xc4, 0x97, 0xb6, 0xab, 0xa4, 0xa0, 0x61, 0x7c, 0xbc, 0xe0, 0x33, 0x1a,
+	0xa6, 0x65, 0xce, 0x05, 0xa6, 0x5d, 0x2e, 0x16, 0x34, 0x4c, 0x0a, 0x5d, 0x08, 0x39, 0x77, 0xb9,
+	0xc0, 0x5d, 0xf5, 0xe0, 0xfc, 0xda, 0x22, 0xf9, 0xe6, 0xae, 0x1c, 0x4b, 0xe5, 0xb5, 0x63, 0x09,
+	0x7f, 0x74, 0x7a, 0x5f, 0xdf, 0x5e, 0x9f, 0x3e, 0xb3, 0x78, 0xc5, 0x2d, 0xfd, 0xb3, 0x0c, 0xad,
+	0x4f, 0x96, 0x34, 0x3c, 0x4e, 0x87, 0x6a, 0x7c, 0x13, 0x6a, 0x51, 0xec, 0xc4, 0xcb, 0x28, 0x19,
+	0x9f, 0x3a, 0x79, 0x9c, 0x15, 0xe0, 0x8e, 0xc9, 0x51, 0x24, 0x41, 0xe3, 0x1f, 0x00, 0x50, 0x36,
+	0x65, 0xdb, 0x7c, 0xf4, 0x3a, 0x75, 0x5d, 0x59, 0xf5, 0xe5, 0xf3, 0x38, 0x1f, 0xbc, 0x14, 0x9a,
+	0x3e, 0xb2, 0xfd, 0xe0, 0x02, 0xdf, 0x25, 0x85, 0x08, 0x01, 0xef, 0x30, 0x3e, 0xa1, 0x3b, 0x3f,
+	0xe2, 0xdb, 0xb4, 0xd2, 0xc5, 0x26, 0xd7, 0xf7, 0x9d, 0xd8, 0xd9, 0x2d, 0x91, 0x04, 0xc5, 0xf0,
+	0x8f, 0xe9, 0x34, 0x0e, 0xc2, 0x76, 0x75, 0x1d, 0xff, 0x80, 0xeb, 0x53, 0xbc, 0x40, 0xf1, 0xf8,
+	0x53, 0xc7, 0x73, 0xc2, 0x76, 0x6d, 0x1d, 0x6f, 0x72, 0x7d, 0x16, 0x9f, 0x4b, 0x0c, 0xef, 0x3b,
+	0x71, 0xe8, 0x3e, 0x69, 0xd7, 0xd7, 0xf1, 0x23, 0xae, 0x4f, 0xf1, 0x02, 0x85, 0x2f, 0x41, 0xe3,
+	0x33, 0x27, 0x9c, 0xbb, 0xf3, 0x23, 0x71, 0x0e, 0x29, 0x24, 0x93, 0xd9, 0x8a, 0xdd, 0xf9, 0x61,
+	0x20, 0x3e, 0xc3, 0x0a, 0x11, 0x82, 0xfa, 0x3e, 0xd4, 0xc4, 0xde, 0xb2, 0x4f, 0x88, 0x4e, 0xc8,
+	0x98, 0x88, 0x49, 0xd1, 0x9c, 0xf4, 0x7a, 0xba, 0x69, 0x22, 0x49, 0x7c, 0x4f, 0xd4, 0x5f, 0x4b,
+	0xa0, 0x64, 0x1b, 0xc9, 0x46, 0x40, 0x63, 0x6c, 0xe8, 0x02, 0x6a, 0x0d, 0x46, 0xfa, 0x78, 0x62,
+	0x21, 0x89, 0xcd, 0x83, 0x3d, 0xcd, 0xe8, 0xe9, 0x43, 0xbd, 0x2f, 0xe6, 0x4a, 0xfd, 0x87, 0x7a,
+	0x6f, 0x62, 0x0d, 0xc6, 0x06, 0xaa, 0x30, 0x63, 0x57, 0xeb, 0xdb, 0x7d, 0xcd, 0xd2, 0x90, 0xcc,
+	0xa4, 0x01, 0x1b, 0x45, 0x0d, 0x6d, 0x88, 0xaa, 0x78, 0x0b, 0x36, 0x26, 0x86, 0xf6, 0x40, 0x1b,
+	0x0c, 0xb5, 0xee, 0x50, 0x47, 0x35, 0xe6, 0x6b, 0x8c, 0x2d, 0xfb, 0xce, 0x78, 0x62, 0xf4, 0x51,
+	0x9d, 0xcd, 0xa4, 0x4c, 0xd4, 0x7a, 0x3d, 0x7d, 0xcf, 0xe2, 0x90, 0x46, 0xf2, 0x9d, 0xab, 0x81,
+	0xcc, 0xc6, 0x6b, 0x55, 0x07, 0xc8, 0x33, 0xb4, 0x3a, 0xbd, 0x2b, 0xaf, 0x9a, 0xf6, 0x4e, 0x9f,
+	0x19, 0xea, 0xcf, 0x24, 0x80, 0x3c, 0x73, 0xf8, 0x66, 0x7e, 0xcd, 0x12, 0x93, 0xe7, 0xc5, 0xf5,
+	0x04, 0x9f, 0x7d, 0xd9, 0xfa, 0xfe, 0xca, 0xa5, 0xa9, 0xbc, 0x7e, 0x08, 0x08, 0xd7, 0x7f, 0x73,
+	0x75, 0x52, 0x6d, 0x68, 0x16, 0xe3, 0xb3, 0xc3, 0x51, 0x5c, 0x09, 0x38, 0x0f, 0x85, 0x24, 0xd2,
+	0xff, 0x3e, 0xd6, 0xfe, 0x52, 0x82, 0xad, 0x35, 0x1a, 0xaf, 0x7c, 0xc9, 0xca, 0x41, 0x5a, 0x7e,
+	0x8d, 0x83, 0xb4, 0x54, 0xe8, 0xfa, 0xd7, 0x21, 0xc3, 0x92, 0x97, 0x95, 0xff, 0xd9, 0x57, 0xaf,
+	0xd7, 0x49, 0x5e, 0x17, 0x20, 0xef, 0x0a, 0xfc, 0x6d, 0xa8, 0xad, 0xfc, 0x1c, 0xb9, 0xb8, 0xde,
+	0x3b, 0xc9, 0xef, 0x11, 0x41, 0x38, 0xc1, 0xaa, 0xbf, 0x95, 0xa0, 0x59, 0x34, 0xbf, 0x72, 0x53,
+	0xfe, 0xfb, 0x1b, 0x78, 0x77, 0xa5, 0x28, 0xc4, 0x97, 0xe1, 0x9d, 0x57, 0xed, 0x23, 0xbf, 0xd2,
+	0x9c, 0xaa, 0x8b, 0x6b, 0x7f, 0x28, 0x03, 0xe4, 0xff, 0x17, 0xf0, 0x39, 0x68, 0x25, 0x43, 0xa1,
+	0xdd, 0xd3, 0x26, 0x26, 0x6b, 0xc8, 0x4b, 0x70, 0x91, 0xe8, 0x7b, 0xc3, 0x41, 0x4f, 0x33, 0xed,
+	0xfe, 0xa0, 0x6f, 0xb3, 0xbe, 0x19, 0x69, 0x56, 0x6f, 0x17, 0x49, 0xf8, 0x2d, 0x38, 0x67, 0x8d,
+	0xc7, 0xf6, 0x48, 0x33, 0x1e, 0xd9, 0xbd, 0xe1, 0xc4, 0xb4, 0x74, 0x62, 0xa2, 0xf2, 0x4a, 0x67,
+	0x56, 0x58, 0x80, 0x81, 0x71, 0x57, 0x37, 0x59, 0xdb, 0xda, 0x44, 0xb3, 0x74, 0x7b, 0x38, 0x18,
+	0x0d, 0x2c, 0xbd, 0x8f, 0x64, 0xdc, 0x86, 0x0b, 0x44, 0xff, 0x64, 0xa2, 0x9b, 0xd6, 0xaa, 0xa5,
+	0xca, 0x3a, 0x74, 0x60, 0x98, 0x16, 0xeb, 0x7e, 0xa1, 0x45, 0x35, 0xfc, 0x15, 0x38, 0x6f, 0xea,
+	0xe4, 0xc1, 0xa0, 0xa7, 0xdb, 0xc5, 0xee, 0xae, 0xe3, 0x0b, 0x80, 0x2c, 0xb3, 0xdf, 0x5d, 0xd1,
+	0x36, 0x18, 0x0d, 0xc6, 0xae, 0x3b, 0x31, 0x1f, 0x21, 0x85, 0xbd, 0xaa, 0x37, 0x20, 0xbd, 0xc9,
+	0xc0, 0xb2, 0xbb, 0x44, 0xd7, 0xee, 0xeb, 0xc4, 0x1e, 0xef, 0xe9, 0x06, 0x02, 0x7c, 0x11, 0xf0,
+	0x48, 0xb7, 0x76, 0xc7, 0x62, 0x6d, 0xda, 0x70, 0x38, 0x7e, 0xa8, 0xf7, 0xd1, 0x06, 0x46, 0xd0,
+	0xb4, 0x74, 0x43, 0x33, 0xac, 0x84, 0x40, 0xb3, 0xfb, 0xdd, 0x67, 0x2f, 0x3a, 0xa5, 0xcf, 0x5f,
+	0x74, 0x4a, 0x5f, 0xbe, 0xe8, 0x48, 0x3f, 0x3d, 0xe9, 0x48, 0xbf, 0x3f, 0xe9, 0x48, 0x4f, 0x4f,
+	0x3a, 0xd2, 0xb3, 0x93, 0x8e, 0xf4, 0xd7, 0x93, 0x8e, 0xf4, 0xc5, 0x49, 0xa7, 0xf4, 0xe5, 0x49,
+	0x47, 0xfa, 0xd5, 0xcb, 0x4e, 0xe9, 0xd9, 0xcb, 0x4e, 0xe9, 0xf3, 0x97, 0x9d, 0xd2, 0x8f, 0xea,
+	0xfc, 0x87, 0xdd, 0x62, 0x7f, 0xbf, 0xc6, 0x7f, 0xbd, 0xdd, 0xf8, 0xd7, 0x00, 0xd8, 0xb5, 0xdf,
+	0x55, 0xc2, 0x13, 0x00, 0x00,
 }
 
 func (x ErrorCause) String() string {
@@ -2239,6 +2262,12 @@ func (this *TimeSeries) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if this.DeltaTemporality != that1.DeltaTemporality {
+		return false
+	}
+	if this.HaDeduplicated != that1.HaDeduplicated {
+		return false
+	}
 	return true
 }
 func (this *LabelPair) Equal(that interface{}) bool {
@@ -3243,7 +3272,7 @@ func (this *TimeSeries) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 8)
+	s := make([]string, 0, 9)
 	s = append(s, "&mimirpb.TimeSeries{")
 	s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
 	if this.Samples != nil {
@@ -3267,6 +3296,8 @@ func (this *TimeSeries) GoString() string {
 		}
 		s = append(s, "Histograms: "+fmt.Sprintf("%#v", vs)+",\n")
 	}
+	s = append(s, "DeltaTemporality: "+fmt.Sprintf("%#v", this.DeltaTemporality)+",\n")
+	s = append(s, "HaDeduplicated: "+fmt.Sprintf("%#v", this.HaDeduplicated)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -3810,6 +3841,30 @@ func (m *TimeSeries) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.HaDeduplicated {
+		i--
+		if m.HaDeduplicated {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x3e
+		i--
+		dAtA[i] = 0xc8
+	}
+	if m.DeltaTemporality {
+		i--
+		if m.DeltaTemporality {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x3e
+		i--
+		dAtA[i] = 0xc0
+	}
 	if len(m.Histograms) > 0 {
 		for iNdEx := len(m.Histograms) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -5153,6 +5208,12 @@ func (m *TimeSeries) Size() (n int) {
 			n += 1 + l + sovMimir(uint64(l))
 		}
 	}
+	if m.DeltaTemporality {
+		n += 3
+	}
+	if m.HaDeduplicated {
+		n += 3
+	}
 	return n
 }
 
@@ -5768,6 +5829,8 @@ func (this *TimeSeries) String() string {
 		`Samples:` + repeatedStringForSamples + `,`,
 		`Exemplars:` + repeatedStringForExemplars + `,`,
 		`Histograms:` + repeatedStringForHistograms + `,`,
+		`DeltaTemporality:` + fmt.Sprintf("%v", this.DeltaTemporality) + `,`,
+		`HaDeduplicated:` + fmt.Sprintf("%v", this.HaDeduplicated) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -6625,6 +6688,46 @@ func (m *TimeSeries) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 1000:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DeltaTemporality", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMimir
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DeltaTemporality = bool(v != 0)
+		case 1001:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HaDeduplicated", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMimir
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.HaDeduplicated = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMimir(dAtA[iNdEx:])
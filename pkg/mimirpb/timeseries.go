@@ -506,6 +506,9 @@ func ReuseTimeseries(ts *TimeSeries) {
 		ts.Histograms = ts.Histograms[:0]
 	}
 
+	ts.DeltaTemporality = false
+	ts.HaDeduplicated = false
+
 	ClearExemplars(ts)
 	timeSeriesPool.Put(ts)
 }
@@ -617,6 +620,9 @@ func DeepCopyTimeseries(dst, src PreallocTimeseries, keepHistograms, keepExempla
 		dstTs.Exemplars = dstTs.Exemplars[:0]
 	}
 
+	dstTs.DeltaTemporality = srcTs.DeltaTemporality
+	dstTs.HaDeduplicated = srcTs.HaDeduplicated
+
 	return dst
 }
 
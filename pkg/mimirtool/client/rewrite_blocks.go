@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+const (
+	rewriteBlocksAPIPath       = "/compactor/rewrite_blocks"
+	rewriteBlocksStatusAPIPath = "/compactor/rewrite_blocks_status"
+)
+
+type rewriteBlocksStatusResponse struct {
+	Requests []*mimir_tsdb.BlockRewriteRequest `json:"requests"`
+}
+
+// RewriteBlocks submits a block rewrite request to the compactor. The config is the YAML request body with the
+// relabel configs, and start and end are the optional time range of the rewritten blocks.
+func (c *MimirClient) RewriteBlocks(ctx context.Context, config []byte, start, end string) (*mimir_tsdb.BlockRewriteRequest, error) {
+	query := url.Values{}
+	if start != "" {
+		query.Set("start", start)
+	}
+	if end != "" {
+		query.Set("end", end)
+	}
+
+	p := rewriteBlocksAPIPath
+	if len(query) > 0 {
+		p += "?" + query.Encode()
+	}
+
+	res, err := c.doRequest(ctx, p, "POST", bytes.NewReader(config), int64(len(config)))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	req := &mimir_tsdb.BlockRewriteRequest{}
+	if err := json.NewDecoder(res.Body).Decode(req); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal response")
+	}
+	return req, nil
+}
+
+// RewriteBlocksStatus returns the block rewrite requests of the tenant.
+func (c *MimirClient) RewriteBlocksStatus(ctx context.Context) ([]*mimir_tsdb.BlockRewriteRequest, error) {
+	res, err := c.doRequest(ctx, rewriteBlocksStatusAPIPath, "GET", nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	status := rewriteBlocksStatusResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal response")
+	}
+	return status.Requests, nil
+}
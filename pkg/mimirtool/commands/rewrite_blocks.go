@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+// RewriteBlocksCommand submits block rewrite requests to the Grafana Mimir compactor, and shows their status.
+type RewriteBlocksCommand struct {
+	clientConfig client.Config
+	cli          *client.MimirClient
+
+	configFile string
+	start, end string
+}
+
+func (c *RewriteBlocksCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
+	cmd := app.Command("rewrite-blocks", "Rewrite the series of the blocks stored in Grafana Mimir with relabel configs, using the compactor.").PreAction(c.setup)
+
+	cmd.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").
+		Envar(envVars.Address).
+		Required().
+		StringVar(&c.clientConfig.Address)
+
+	cmd.Flag("user",
+		fmt.Sprintf("Basic auth username to use when contacting Grafana Mimir; alternatively, set %s. If empty, %s is used instead.", envVars.APIUser, envVars.TenantID)).
+		Default("").
+		Envar(envVars.APIUser).
+		StringVar(&c.clientConfig.User)
+
+	cmd.Flag("id", "Grafana Mimir tenant ID. Used for X-Scope-OrgID HTTP header. Also used for basic auth if --user is not provided. Alternatively, set "+envVars.TenantID+".").
+		Envar(envVars.TenantID).
+		Required().
+		StringVar(&c.clientConfig.ID)
+
+	cmd.Flag("key", "Basic auth password to use when contacting Grafana Mimir; alternatively, set "+envVars.APIKey+".").
+		Default("").
+		Envar(envVars.APIKey).
+		StringVar(&c.clientConfig.Key)
+
+	c.clientConfig.ExtraHeaders = map[string]string{}
+	cmd.Flag("extra-headers", "Extra headers to add to the requests in header=value format, alternatively set newline separated "+envVars.ExtraHeaders+".").
+		Envar(envVars.ExtraHeaders).
+		StringMapVar(&c.clientConfig.ExtraHeaders)
+
+	cmd.Flag("tls-ca-path", "TLS CA certificate to verify Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSCAPath+".").
+		Default("").
+		Envar(envVars.TLSCAPath).
+		StringVar(&c.clientConfig.TLS.CAPath)
+
+	cmd.Flag("tls-cert-path", "TLS client certificate to authenticate with the Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSCertPath+".").
+		Default("").
+		Envar(envVars.TLSCertPath).
+		StringVar(&c.clientConfig.TLS.CertPath)
+
+	cmd.Flag("tls-key-path", "TLS client certificate private key to authenticate with the Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSKeyPath+".").
+		Default("").
+		Envar(envVars.TLSKeyPath).
+		StringVar(&c.clientConfig.TLS.KeyPath)
+
+	cmd.Flag("tls-insecure-skip-verify", "Skip TLS certificate verification; alternatively, set "+envVars.TLSInsecureSkipVerify+".").
+		Default("false").
+		Envar(envVars.TLSInsecureSkipVerify).
+		BoolVar(&c.clientConfig.TLS.InsecureSkipVerify)
+
+	submitCmd := cmd.Command("submit", "Submit a block rewrite request, with the relabel configs of a YAML file.").Action(c.submit)
+	submitCmd.Arg("config-file", "YAML file with the relabel configs, under the relabel_configs key, and the optional aggregation of the series given the same labels, under the aggregation key.").Required().ExistingFileVar(&c.configFile)
+	submitCmd.Flag("start", "Start of the time range of the rewritten blocks, as a RFC3339 timestamp or a Unix timestamp in seconds. Defaults to the Unix epoch.").Default("").StringVar(&c.start)
+	submitCmd.Flag("end", "End of the time range of the rewritten blocks, as a RFC3339 timestamp or a Unix timestamp in seconds. Defaults to the current time.").Default("").StringVar(&c.end)
+
+	cmd.Command("status", "Show the block rewrite requests of the tenant, and whether they've been applied to all blocks.").Action(c.status)
+}
+
+func (c *RewriteBlocksCommand) setup(_ *kingpin.ParseContext) error {
+	cli, err := client.New(c.clientConfig)
+	if err != nil {
+		return err
+	}
+	c.cli = cli
+	return nil
+}
+
+func (c *RewriteBlocksCommand) submit(_ *kingpin.ParseContext) error {
+	config, err := os.ReadFile(c.configFile)
+	if err != nil {
+		return errors.Wrap(err, "read config file")
+	}
+
+	// The relabel configs and aggregation are validated before being submitted.
+	var body struct {
+		RelabelConfigs []*relabel.Config                  `yaml:"relabel_configs"`
+		Aggregation    mimir_tsdb.BlockRewriteAggregation `yaml:"aggregation"`
+	}
+	if err := yaml.Unmarshal(config, &body); err != nil {
+		return errors.Wrapf(err, "invalid config file %s", c.configFile)
+	}
+	req := &mimir_tsdb.BlockRewriteRequest{RelabelConfigs: body.RelabelConfigs, Aggregation: body.Aggregation}
+	if err := req.Validate(); err != nil {
+		return errors.Wrapf(err, "invalid config file %s", c.configFile)
+	}
+
+	req, err = c.cli.RewriteBlocks(context.Background(), config, c.start, c.end)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"request_id": req.RequestID,
+		"user":       c.clientConfig.ID,
+	}).Info("block rewrite request submitted")
+	return nil
+}
+
+func (c *RewriteBlocksCommand) status(_ *kingpin.ParseContext) error {
+	requests, err := c.cli.RewriteBlocksStatus(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REQUEST ID\tSTATUS\tSTART\tEND\tCREATED\tPROCESSED\tERROR")
+	for _, req := range requests {
+		processed := ""
+		if req.Status != mimir_tsdb.BlockRewriteRequestPending {
+			processed = req.ProcessedTime.Time().UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			req.RequestID,
+			req.Status,
+			time.UnixMilli(req.StartTime).UTC().Format(time.RFC3339),
+			time.UnixMilli(req.EndTime).UTC().Format(time.RFC3339),
+			req.CreatedTime.Time().UTC().Format(time.RFC3339),
+			processed,
+			req.Error,
+		)
+	}
+	return w.Flush()
+}
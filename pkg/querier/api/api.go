@@ -39,3 +39,14 @@ type LabelNamesCardinalityItem struct {
 type ActiveSeriesResponse struct {
 	Data []labels.Labels `json:"data"`
 }
+
+const (
+	// StreamQueryResultHeader is set by the query-frontend on query requests if it returns the response from the
+	// querier to the client without decoding it. Queriers may then write the result as it is produced, rather than
+	// once the whole result is available.
+	StreamQueryResultHeader = "X-Mimir-Stream-Query-Result"
+
+	// StreamQueryResultErrorTrailer is the HTTP trailer set by queriers if evaluating a query fails after they have
+	// started writing a streamed result. Its value is the JSON-encoded error response.
+	StreamQueryResultErrorTrailer = "X-Mimir-Stream-Query-Result-Error"
+)
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+// downsamplingResolutionFactor is the minimum number of downsampled samples each query step
+// and range must span to query blocks downsampled to a resolution.
+const downsamplingResolutionFactor = 5
+
+// downsamplingSelector selects the resolution of the blocks to query. A nil downsamplingSelector
+// only selects raw blocks.
+type downsamplingSelector struct {
+	// maxResolution is the coarsest resolution whose blocks are preferably queried.
+	maxResolution int64
+
+	// aggregate is the downsampled aggregate to query. If empty, only raw blocks are queried.
+	aggregate string
+
+	// queriesDownsampledBlocks is set once downsampled blocks have been selected.
+	queriesDownsampledBlocks bool
+}
+
+// newDownsamplingSelector returns a downsamplingSelector choosing the resolution from the query
+// step and range, and the aggregate from the function the selected series are passed to.
+func newDownsamplingSelector(sp *storage.SelectHints) *downsamplingSelector {
+	s := &downsamplingSelector{
+		maxResolution: block.ResolutionRaw,
+		aggregate:     downsamplingAggregate(sp.Func),
+	}
+	if s.aggregate == "" || (sp.Step <= 0 && sp.Range <= 0) {
+		return s
+	}
+
+	for _, resolution := range []int64{block.Resolution1h, block.Resolution5m} {
+		if sp.Step > 0 && resolution > sp.Step/downsamplingResolutionFactor {
+			continue
+		}
+		if sp.Range > 0 && resolution > sp.Range/downsamplingResolutionFactor {
+			continue
+		}
+		s.maxResolution = resolution
+		break
+	}
+	return s
+}
+
+// downsamplingAggregate returns the downsampled aggregate to query for the function the selected
+// series are passed to, or an empty string if the function requires raw samples. Only the functions
+// whose result is approximated by the average of each downsampled interval query the avg aggregate:
+// series selected without a function, and by the aggregations which don't depend on the distribution
+// of the samples within a step.
+func downsamplingAggregate(fn string) string {
+	switch fn {
+	case "rate", "increase", "irate":
+		return block.AggregateCounter
+	case "min_over_time":
+		return block.AggregateMin
+	case "max_over_time":
+		return block.AggregateMax
+	case "sum_over_time":
+		return block.AggregateSum
+	case "", "avg_over_time", "sum", "avg", "min", "max", "count", "group":
+		return block.AggregateAvg
+	default:
+		return ""
+	}
+}
+
+// filterBlocks returns the blocks to query. Blocks of the max resolution are preferred, then blocks of finer
+// resolutions and finally blocks of coarser resolutions, which are only queried for the time ranges not
+// covered by the blocks of the preferred resolutions (e.g. because of a shorter retention period).
+//
+// This function modifies input slice.
+func (s *downsamplingSelector) filterBlocks(blocks bucketindex.Blocks) bucketindex.Blocks {
+	if s == nil || s.aggregate == "" {
+		return slices.DeleteFunc(blocks, func(b *bucketindex.Block) bool {
+			return b.Resolution != block.ResolutionRaw
+		})
+	}
+
+	var resolutions []int64
+	for _, resolution := range []int64{block.Resolution1h, block.Resolution5m, block.ResolutionRaw} {
+		if resolution <= s.maxResolution {
+			resolutions = append(resolutions, resolution)
+		}
+	}
+	for _, resolution := range []int64{block.Resolution5m, block.Resolution1h} {
+		if resolution > s.maxResolution {
+			resolutions = append(resolutions, resolution)
+		}
+	}
+
+	var (
+		selected bucketindex.Blocks
+		covered  [][2]int64
+	)
+	for _, resolution := range resolutions {
+		var blocksOfResolution bucketindex.Blocks
+		for _, b := range blocks {
+			if b.Resolution != resolution || timeRangeCovered(covered, b.MinTime, b.MaxTime) {
+				continue
+			}
+			blocksOfResolution = append(blocksOfResolution, b)
+		}
+
+		for _, b := range blocksOfResolution {
+			covered = append(covered, [2]int64{b.MinTime, b.MaxTime})
+			if resolution != block.ResolutionRaw {
+				s.queriesDownsampledBlocks = true
+			}
+		}
+		selected = append(selected, blocksOfResolution...)
+	}
+
+	return append(blocks[:0], selected...)
+}
+
+// aggregateMatcher returns the matcher selecting the queried aggregate of the series of downsampled
+// blocks, and the series of raw blocks, which don't have the aggregate label.
+func (s *downsamplingSelector) aggregateMatcher() *labels.Matcher {
+	return labels.MustNewMatcher(labels.MatchRegexp, block.AggregateLabel, s.aggregate+"|")
+}
+
+// timeRangeCovered returns whether the time ranges cover [minT, maxT).
+func timeRangeCovered(ranges [][2]int64, minT, maxT int64) bool {
+	ranges = slices.Clone(ranges)
+	slices.SortFunc(ranges, func(a, b [2]int64) int {
+		return cmp.Compare(a[0], b[0])
+	})
+
+	for _, r := range ranges {
+		if r[0] > minT {
+			return false
+		}
+		if r[1] > minT {
+			minT = r[1]
+		}
+		if minT >= maxT {
+			return true
+		}
+	}
+	return false
+}
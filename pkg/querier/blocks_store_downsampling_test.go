@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestNewDownsamplingSelector(t *testing.T) {
+	minute := time.Minute.Milliseconds()
+
+	tests := map[string]struct {
+		hints                 storage.SelectHints
+		expectedMaxResolution int64
+		expectedAggregate     string
+	}{
+		"instant query": {
+			hints:                 storage.SelectHints{},
+			expectedMaxResolution: block.ResolutionRaw,
+			expectedAggregate:     block.AggregateAvg,
+		},
+		"small step": {
+			hints:                 storage.SelectHints{Step: minute},
+			expectedMaxResolution: block.ResolutionRaw,
+			expectedAggregate:     block.AggregateAvg,
+		},
+		"step large enough for 5m resolution": {
+			hints:                 storage.SelectHints{Step: 30 * minute, Func: "max_over_time", Range: 30 * minute},
+			expectedMaxResolution: block.Resolution5m,
+			expectedAggregate:     block.AggregateMax,
+		},
+		"step large enough for 1h resolution": {
+			hints:                 storage.SelectHints{Step: 6 * 60 * minute, Func: "rate", Range: 6 * 60 * minute},
+			expectedMaxResolution: block.Resolution1h,
+			expectedAggregate:     block.AggregateCounter,
+		},
+		"range too small for the step": {
+			hints:                 storage.SelectHints{Step: 6 * 60 * minute, Func: "rate", Range: 5 * minute},
+			expectedMaxResolution: block.ResolutionRaw,
+			expectedAggregate:     block.AggregateCounter,
+		},
+		"instant query with a large range": {
+			hints:                 storage.SelectHints{Func: "sum_over_time", Range: 30 * minute},
+			expectedMaxResolution: block.Resolution5m,
+			expectedAggregate:     block.AggregateSum,
+		},
+		"function requiring raw samples": {
+			hints:                 storage.SelectHints{Step: 6 * 60 * minute, Func: "count_over_time", Range: 6 * 60 * minute},
+			expectedMaxResolution: block.ResolutionRaw,
+			expectedAggregate:     "",
+		},
+		"function safe on averages": {
+			hints:                 storage.SelectHints{Step: 6 * 60 * minute, Func: "avg_over_time", Range: 6 * 60 * minute},
+			expectedMaxResolution: block.Resolution1h,
+			expectedAggregate:     block.AggregateAvg,
+		},
+		"function not known to be safe on averages": {
+			hints:                 storage.SelectHints{Step: 6 * 60 * minute, Func: "quantile_over_time", Range: 6 * 60 * minute},
+			expectedMaxResolution: block.ResolutionRaw,
+			expectedAggregate:     "",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newDownsamplingSelector(&tc.hints)
+			assert.Equal(t, tc.expectedMaxResolution, s.maxResolution)
+			assert.Equal(t, tc.expectedAggregate, s.aggregate)
+		})
+	}
+}
+
+func TestDownsamplingSelector_FilterBlocks(t *testing.T) {
+	const day = int64(24 * time.Hour / time.Millisecond)
+
+	var (
+		raw1        = &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: 2 * day, MaxTime: 3 * day}
+		raw2        = &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: 2 * day, MaxTime: 3 * day}
+		fiveM1      = &bucketindex.Block{ID: ulid.MustNew(3, nil), MinTime: 1 * day, MaxTime: 2 * day, Resolution: block.Resolution5m}
+		fiveM2      = &bucketindex.Block{ID: ulid.MustNew(4, nil), MinTime: 2 * day, MaxTime: 3 * day, Resolution: block.Resolution5m}
+		oneH1       = &bucketindex.Block{ID: ulid.MustNew(5, nil), MinTime: 0, MaxTime: 1 * day, Resolution: block.Resolution1h}
+		oneH2       = &bucketindex.Block{ID: ulid.MustNew(6, nil), MinTime: 1 * day, MaxTime: 2 * day, Resolution: block.Resolution1h}
+		oneH3       = &bucketindex.Block{ID: ulid.MustNew(7, nil), MinTime: 2 * day, MaxTime: 3 * day, Resolution: block.Resolution1h}
+		blocks      = bucketindex.Blocks{raw1, raw2, fiveM1, fiveM2, oneH1, oneH2, oneH3}
+		cloneBlocks = func() bucketindex.Blocks { return append(bucketindex.Blocks(nil), blocks...) }
+	)
+
+	tests := map[string]struct {
+		selector                         *downsamplingSelector
+		expectedBlocks                   bucketindex.Blocks
+		expectedQueriesDownsampledBlocks bool
+	}{
+		"nil selector": {
+			selector:       nil,
+			expectedBlocks: bucketindex.Blocks{raw1, raw2},
+		},
+		"raw samples required": {
+			selector:       &downsamplingSelector{maxResolution: block.ResolutionRaw},
+			expectedBlocks: bucketindex.Blocks{raw1, raw2},
+		},
+		"raw resolution falls back to coarser resolutions": {
+			selector:                         &downsamplingSelector{maxResolution: block.ResolutionRaw, aggregate: block.AggregateAvg},
+			expectedBlocks:                   bucketindex.Blocks{raw1, raw2, fiveM1, oneH1},
+			expectedQueriesDownsampledBlocks: true,
+		},
+		"5m resolution": {
+			selector:                         &downsamplingSelector{maxResolution: block.Resolution5m, aggregate: block.AggregateAvg},
+			expectedBlocks:                   bucketindex.Blocks{fiveM1, fiveM2, oneH1},
+			expectedQueriesDownsampledBlocks: true,
+		},
+		"1h resolution": {
+			selector:                         &downsamplingSelector{maxResolution: block.Resolution1h, aggregate: block.AggregateCounter},
+			expectedBlocks:                   bucketindex.Blocks{oneH1, oneH2, oneH3},
+			expectedQueriesDownsampledBlocks: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedBlocks, tc.selector.filterBlocks(cloneBlocks()))
+			if tc.selector != nil {
+				assert.Equal(t, tc.expectedQueriesDownsampledBlocks, tc.selector.queriesDownsampledBlocks)
+			}
+		})
+	}
+}
@@ -380,7 +380,7 @@ func (q *blocksStoreQuerier) LabelNames(ctx context.Context, hints *storage.Labe
 		return queriedBlocks, nil
 	}
 
-	if err := q.queryWithConsistencyCheck(ctx, spanLog, minT, maxT, tenantID, nil, queryF); err != nil {
+	if err := q.queryWithConsistencyCheck(ctx, spanLog, minT, maxT, tenantID, nil, nil, queryF); err != nil {
 		return nil, nil, err
 	}
 
@@ -424,7 +424,7 @@ func (q *blocksStoreQuerier) LabelValues(ctx context.Context, name string, hints
 		return queriedBlocks, nil
 	}
 
-	if err := q.queryWithConsistencyCheck(ctx, spanLog, minT, maxT, tenantID, nil, queryF); err != nil {
+	if err := q.queryWithConsistencyCheck(ctx, spanLog, minT, maxT, tenantID, nil, nil, queryF); err != nil {
 		return nil, nil, err
 	}
 
@@ -455,7 +455,15 @@ func (q *blocksStoreQuerier) selectSorted(ctx context.Context, sp *storage.Selec
 		return storage.ErrSeriesSet(err)
 	}
 
+	downsampling := newDownsamplingSelector(sp)
+
 	queryF := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+		convertedMatchers := convertedMatchers
+		if downsampling.queriesDownsampledBlocks {
+			// Downsampled blocks only return the series of the queried aggregate.
+			convertedMatchers = append(slices.Clip(convertedMatchers), convertMatchersToLabelMatcher([]*labels.Matcher{downsampling.aggregateMatcher()})...)
+		}
+
 		seriesSets, queriedBlocks, warnings, startStreamingChunks, chunkEstimator, err := q.fetchSeriesFromStores(ctx, sp, clients, minT, maxT, tenantID, convertedMatchers)
 		if err != nil {
 			return nil, err
@@ -469,7 +477,7 @@ func (q *blocksStoreQuerier) selectSorted(ctx context.Context, sp *storage.Selec
 		return queriedBlocks, nil
 	}
 
-	err = q.queryWithConsistencyCheck(ctx, spanLog, minT, maxT, tenantID, shard, queryF)
+	err = q.queryWithConsistencyCheck(ctx, spanLog, minT, maxT, tenantID, shard, downsampling, queryF)
 	if err != nil {
 		return storage.ErrSeriesSet(err)
 	}
@@ -504,7 +512,7 @@ func (q *blocksStoreQuerier) selectSorted(ctx context.Context, sp *storage.Selec
 type queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)
 
 func (q *blocksStoreQuerier) queryWithConsistencyCheck(
-	ctx context.Context, spanLog *spanlogger.SpanLogger, minT, maxT int64, tenantID string, shard *sharding.ShardSelector, queryF queryFunc,
+	ctx context.Context, spanLog *spanlogger.SpanLogger, minT, maxT int64, tenantID string, shard *sharding.ShardSelector, downsampling *downsamplingSelector, queryF queryFunc,
 ) (returnErr error) {
 	now := time.Now()
 
@@ -530,6 +538,11 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(
 
 	q.metrics.blocksFound.Add(float64(len(knownBlocks)))
 
+	knownBlocks = downsampling.filterBlocks(knownBlocks)
+	if downsampling != nil {
+		spanLog.DebugLog("msg", "filtered blocks by resolution", "maxResolution", downsampling.maxResolution, "aggregate", downsampling.aggregate, "queriesDownsampledBlocks", downsampling.queriesDownsampledBlocks)
+	}
+
 	if shard != nil && shard.ShardCount > 0 {
 		spanLog.DebugLog("msg", "filtering blocks due to sharding", "blocksBeforeFiltering", knownBlocks.String(), "shardID", shard.LabelValue())
 
@@ -34,6 +34,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/streamingpromql"
 	"github.com/grafana/mimir/pkg/streamingpromql/compat"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
 	"github.com/grafana/mimir/pkg/util/limiter"
@@ -61,6 +62,10 @@ type Config struct {
 
 	FilterQueryablesEnabled bool `yaml:"filter_queryables_enabled" category:"advanced"`
 
+	MimirQueryEngineRemoteExecution remoteexec.Config `yaml:"mimir_query_engine_remote_execution" category:"experimental"`
+
+	SeriesDeletionRequestsRefreshInterval time.Duration `yaml:"series_deletion_requests_refresh_interval" category:"experimental"`
+
 	// PromQL engine config.
 	EngineConfig engine.Config `yaml:",inline"`
 }
@@ -93,6 +98,10 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 
 	f.BoolVar(&cfg.FilterQueryablesEnabled, "querier.filter-queryables-enabled", false, "If set to true, the header 'X-Filter-Queryables' can be used to filter down the list of queryables that shall be used. This is useful to test and monitor single queryables in isolation.")
 
+	f.DurationVar(&cfg.SeriesDeletionRequestsRefreshInterval, "querier.series-deletion-requests-refresh-interval", time.Minute, "How frequently the series deletion requests of a tenant are reloaded from the storage by queriers, rulers and query-frontends. Samples deleted by series deletion requests are filtered out of query results until the compactor has purged them from the blocks, and the results of queries reading them are not cached. 0 disables filtering deleted samples.")
+
+	cfg.MimirQueryEngineRemoteExecution.RegisterFlags(f)
+
 	cfg.EngineConfig.RegisterFlags(f)
 }
 
@@ -101,9 +110,18 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("unknown PromQL engine '%s'", cfg.QueryEngine)
 	}
 
+	if err := cfg.MimirQueryEngineRemoteExecution.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// RemoteExecutionEnabled returns true if the Mimir query engine is in use and remote execution is enabled.
+func (cfg *Config) RemoteExecutionEnabled() bool {
+	return cfg.QueryEngine == mimirEngine && cfg.MimirQueryEngineRemoteExecution.Enabled
+}
+
 func (cfg *Config) ValidateLimits(limits validation.Limits) error {
 	// Ensure the config wont create a situation where no queriers are returned.
 	if limits.QueryIngestersWithin != 0 && cfg.QueryStoreAfter != 0 {
@@ -138,7 +156,9 @@ func ShouldQueryBlockStore(queryStoreAfter time.Duration, now time.Time, queryMi
 }
 
 // New builds a queryable and promql engine.
-func New(cfg Config, limits *validation.Overrides, distributor Distributor, queryables []TimeRangeQueryable, reg prometheus.Registerer, logger log.Logger, tracker *activitytracker.ActivityTracker) (storage.SampleAndChunkQueryable, storage.ExemplarQueryable, promql.QueryEngine, error) {
+// remoteExecutionClient is used by the Mimir query engine to evaluate expressions on other queriers, and must be nil
+// if remote execution is disabled. The caller owns the client and is responsible for closing it.
+func New(cfg Config, limits *validation.Overrides, distributor Distributor, queryables []TimeRangeQueryable, remoteExecutionClient remoteexec.RemoteExecutionClient, reg prometheus.Registerer, logger log.Logger, tracker *activitytracker.ActivityTracker) (storage.SampleAndChunkQueryable, storage.ExemplarQueryable, promql.QueryEngine, error) {
 	queryMetrics := stats.NewQueryMetrics(reg)
 
 	queryables = append(queryables, TimeRangeQueryable{
@@ -173,6 +193,12 @@ func New(cfg Config, limits *validation.Overrides, distributor Distributor, quer
 		eng = promql.NewEngine(opts)
 	case mimirEngine:
 		limitsProvider := &tenantQueryLimitsProvider{limits: limits}
+
+		if remoteExecutionClient != nil {
+			mqeOpts.RemoteExecutionClient = remoteExecutionClient
+			mqeOpts.RemoteExecutionShardCount = cfg.MimirQueryEngineRemoteExecution.ShardCount
+		}
+
 		streamingEngine, err := streamingpromql.NewEngine(mqeOpts, limitsProvider, queryMetrics, logger)
 		if err != nil {
 			return nil, nil, nil, err
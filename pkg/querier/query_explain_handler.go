@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/streamingpromql"
+	"github.com/grafana/mimir/pkg/streamingpromql/compat"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type queryExplainData struct {
+	Supported bool                       `json:"supported"`
+	Reason    string                     `json:"reason,omitempty"`
+	Plan      *streamingpromql.QueryPlan `json:"plan,omitempty"`
+}
+
+type queryExplainSuccessResult struct {
+	Status string           `json:"status"`
+	Data   queryExplainData `json:"data"`
+}
+
+type queryExplainErrorResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// NewQueryExplainHandler creates a http.Handler that returns the tree of operators the Mimir query engine
+// uses to evaluate a query, or the reason the query is not supported by the Mimir query engine.
+//
+// The query is evaluated as an instant query if the time parameter is provided, or as a range query if
+// the start, end and step parameters are provided. If the execute parameter is true, the query is also
+// evaluated and the time spent and memory consumed by each operator is included in the response.
+func NewQueryExplainHandler(engine promql.QueryEngine, queryable storage.Queryable) http.Handler {
+	mqe := mimirQueryEngine(engine)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mqe == nil {
+			writeQueryExplainError(w, http.StatusBadRequest, errors.New("query explain is only supported by the Mimir query engine"))
+			return
+		}
+
+		qs := r.FormValue("query")
+		if qs == "" {
+			writeQueryExplainError(w, http.StatusBadRequest, errors.New("query parameter is required"))
+			return
+		}
+
+		start, end, interval, err := parseQueryExplainTimeRange(r)
+		if err != nil {
+			writeQueryExplainError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		execute := false
+		if s := r.FormValue("execute"); s != "" {
+			execute, err = strconv.ParseBool(s)
+			if err != nil {
+				writeQueryExplainError(w, http.StatusBadRequest, errors.New("execute must be a boolean"))
+				return
+			}
+		}
+
+		plan, err := mqe.Explain(r.Context(), queryable, qs, start, end, interval, execute)
+		if err != nil {
+			if errors.Is(err, compat.NotSupportedError{}) {
+				util.WriteJSONResponse(w, queryExplainSuccessResult{Status: statusSuccess, Data: queryExplainData{Supported: false, Reason: err.Error()}})
+				return
+			}
+
+			writeQueryExplainError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		util.WriteJSONResponse(w, queryExplainSuccessResult{Status: statusSuccess, Data: queryExplainData{Supported: true, Plan: plan}})
+	})
+}
+
+func parseQueryExplainTimeRange(r *http.Request) (start, end time.Time, interval time.Duration, err error) {
+	if r.FormValue("start") == "" && r.FormValue("end") == "" && r.FormValue("step") == "" {
+		ts := time.Now()
+
+		if s := r.FormValue("time"); s != "" {
+			ms, err := util.ParseTime(s)
+			if err != nil {
+				return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid time parameter: %w", err)
+			}
+
+			ts = util.TimeFromMillis(ms)
+		}
+
+		return ts, ts, 0, nil
+	}
+
+	startMs, err := util.ParseTime(r.FormValue("start"))
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid start parameter: %w", err)
+	}
+
+	endMs, err := util.ParseTime(r.FormValue("end"))
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid end parameter: %w", err)
+	}
+
+	stepMs, err := util.ParseDurationMS(r.FormValue("step"))
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid step parameter: %w", err)
+	}
+
+	return util.TimeFromMillis(startMs), util.TimeFromMillis(endMs), time.Duration(stepMs) * time.Millisecond, nil
+}
+
+// mimirQueryEngine returns the Mimir query engine used by engine, or nil if engine does not use the Mimir query engine.
+func mimirQueryEngine(engine promql.QueryEngine) *streamingpromql.Engine {
+	switch e := engine.(type) {
+	case *streamingpromql.Engine:
+		return e
+	case *compat.EngineWithFallback:
+		return mimirQueryEngine(e.Preferred())
+	default:
+		return nil
+	}
+}
+
+func writeQueryExplainError(w http.ResponseWriter, statusCode int, err error) {
+	w.WriteHeader(statusCode)
+	util.WriteJSONResponse(w, queryExplainErrorResult{Status: statusError, Error: err.Error()})
+}
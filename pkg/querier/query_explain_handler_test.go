@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/promqltest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/streamingpromql"
+	"github.com/grafana/mimir/pkg/streamingpromql/compat"
+)
+
+func TestQueryExplainHandler(t *testing.T) {
+	storage := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{idx="1"} 0+1x5
+			some_metric{idx="2"} 0+1x5
+	`)
+	t.Cleanup(func() { require.NoError(t, storage.Close()) })
+
+	opts := streamingpromql.NewTestEngineOpts()
+	mqe, err := streamingpromql.NewEngine(opts, streamingpromql.NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	opts.Features.EnableSubqueries = false
+	mqeWithoutSubqueries, err := streamingpromql.NewEngine(opts, streamingpromql.NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	prometheusEngine := promql.NewEngine(opts.CommonOpts)
+	engineWithFallback := compat.NewEngineWithFallback(mqeWithoutSubqueries, prometheusEngine, nil, log.NewNopLogger())
+
+	testCases := map[string]struct {
+		engine             promql.QueryEngine
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+		verify             func(t *testing.T, data queryExplainData)
+	}{
+		"instant query": {
+			engine:             mqe,
+			url:                "/api/v1/query_explain?query=sum(some_metric)&time=240",
+			expectedStatusCode: http.StatusOK,
+			verify: func(t *testing.T, data queryExplainData) {
+				require.True(t, data.Supported)
+				require.False(t, data.Plan.Executed)
+				require.Equal(t, "aggregations.Aggregation", data.Plan.Root.Operator)
+				require.Equal(t, 1, data.Plan.Root.Steps)
+				require.Len(t, data.Plan.Root.Children, 1)
+				require.Equal(t, "selectors.InstantVectorSelector", data.Plan.Root.Children[0].Operator)
+			},
+		},
+		"executed range query": {
+			engine:             mqe,
+			url:                "/api/v1/query_explain?query=sum(some_metric)&start=0&end=240&step=60&execute=true",
+			expectedStatusCode: http.StatusOK,
+			verify: func(t *testing.T, data queryExplainData) {
+				require.True(t, data.Supported)
+				require.True(t, data.Plan.Executed)
+				require.Equal(t, 5, data.Plan.Root.Steps)
+				require.Equal(t, 1, *data.Plan.Root.Series)
+				require.Equal(t, 2, *data.Plan.Root.Children[0].Series)
+			},
+		},
+		"query supported by engine with fallback": {
+			engine:             engineWithFallback,
+			url:                "/api/v1/query_explain?query=some_metric&time=240",
+			expectedStatusCode: http.StatusOK,
+			verify: func(t *testing.T, data queryExplainData) {
+				require.True(t, data.Supported)
+				require.Equal(t, "selectors.InstantVectorSelector", data.Plan.Root.Operator)
+			},
+		},
+		"query not supported by engine with fallback": {
+			engine:             engineWithFallback,
+			url:                "/api/v1/query_explain?query=max_over_time(some_metric[5m:1m])&time=240",
+			expectedStatusCode: http.StatusOK,
+			verify: func(t *testing.T, data queryExplainData) {
+				require.False(t, data.Supported)
+				require.Equal(t, "not supported by streaming engine: subquery", data.Reason)
+				require.Nil(t, data.Plan)
+			},
+		},
+		"Prometheus' engine": {
+			engine:             prometheusEngine,
+			url:                "/api/v1/query_explain?query=some_metric&time=240",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status":"error","error":"query explain is only supported by the Mimir query engine"}`,
+		},
+		"missing query": {
+			engine:             mqe,
+			url:                "/api/v1/query_explain?time=240",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status":"error","error":"query parameter is required"}`,
+		},
+		"invalid query": {
+			engine:             mqe,
+			url:                "/api/v1/query_explain?query=sum(&time=240",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"invalid step": {
+			engine:             mqe,
+			url:                "/api/v1/query_explain?query=some_metric&start=0&end=240&step=foo",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status":"error","error":"invalid step parameter: cannot parse \"foo\" to a valid duration"}`,
+		},
+		"invalid execute": {
+			engine:             mqe,
+			url:                "/api/v1/query_explain?query=some_metric&time=240&execute=foo",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status":"error","error":"execute must be a boolean"}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			handler := NewQueryExplainHandler(testCase.engine, storage)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.url, nil))
+
+			require.Equal(t, testCase.expectedStatusCode, recorder.Code)
+
+			if testCase.expectedBody != "" {
+				require.JSONEq(t, testCase.expectedBody, recorder.Body.String())
+			}
+
+			if testCase.verify != nil {
+				var result struct {
+					Status string           `json:"status"`
+					Data   queryExplainData `json:"data"`
+				}
+
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+				require.Equal(t, statusSuccess, result.Status)
+				testCase.verify(t, result.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/streamingpromql"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
+)
+
+// NewRemoteExecutionServer returns a server that evaluates expressions sent by other queriers with Mimir query engine
+// remote execution enabled, or nil if engine is not the Mimir query engine.
+func NewRemoteExecutionServer(engine promql.QueryEngine, queryable storage.SampleAndChunkQueryable) remoteexec.RemoteExecutionServer {
+	mqe := mimirQueryEngine(engine)
+	if mqe == nil {
+		return nil
+	}
+
+	return streamingpromql.NewRemoteExecutionServer(mqe, NewErrorTranslateSampleAndChunkQueryable(queryable))
+}
@@ -0,0 +1,483 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+	"github.com/prometheus/prometheus/util/annotations"
+	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// seriesDeletion is a series deletion request, with its selectors parsed.
+type seriesDeletion struct {
+	matchers           [][]*labels.Matcher
+	startTime, endTime int64
+}
+
+// deletedIntervals returns the time intervals of the series deleted by any of the deletions.
+func deletedIntervals(deletions []seriesDeletion, lbls labels.Labels) tombstones.Intervals {
+	var intervals tombstones.Intervals
+	for _, d := range deletions {
+		for _, ms := range d.matchers {
+			if matchesAll(ms, lbls) {
+				intervals = intervals.Add(tombstones.Interval{Mint: d.startTime, Maxt: d.endTime})
+				break
+			}
+		}
+	}
+	return intervals
+}
+
+func matchesAll(matchers []*labels.Matcher, lbls labels.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+type cachedSeriesDeletions struct {
+	// We cache either the deletions or the error occurred while loading them.
+	deletions []seriesDeletion
+	err       error
+
+	// Unix timestamp (seconds) of when the deletions have been requested the last time.
+	requestedAt atomic.Int64
+}
+
+// SeriesDeletionRequestsLoader lazy loads the series deletion requests of tenants from the bucket and, once
+// loaded for the first time, keeps them updated in background, so that only the first query of a tenant reads
+// them from the bucket. Tenants whose requests weren't requested for longer than the idle timeout are offloaded.
+type SeriesDeletionRequestsLoader struct {
+	services.Service
+
+	bkt         objstore.Bucket
+	cfgProvider bucket.TenantConfigProvider
+	idleTimeout time.Duration
+	// processedDelay is how long the requests are still loaded once processed, until the blocks they've been
+	// applied to aren't queried anymore.
+	processedDelay time.Duration
+	logger         log.Logger
+
+	tenantsMx sync.RWMutex
+	tenants   map[string]*cachedSeriesDeletions
+}
+
+// NewSeriesDeletionRequestsLoader makes a new SeriesDeletionRequestsLoader, which reloads the series deletion
+// requests of tenants every refreshInterval. Requests processed for longer than processedDelay aren't loaded.
+func NewSeriesDeletionRequestsLoader(bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider, refreshInterval, idleTimeout, processedDelay time.Duration, logger log.Logger) *SeriesDeletionRequestsLoader {
+	l := &SeriesDeletionRequestsLoader{
+		bkt:            bkt,
+		cfgProvider:    cfgProvider,
+		idleTimeout:    idleTimeout,
+		processedDelay: processedDelay,
+		logger:         logger,
+		tenants:        map[string]*cachedSeriesDeletions{},
+	}
+
+	l.Service = services.NewTimerService(refreshInterval, nil, l.refreshDeletions, nil)
+	return l
+}
+
+// NewSeriesDeletionRequestsLoaderFromConfig makes a new SeriesDeletionRequestsLoader, or returns nil if
+// filtering deleted series is disabled.
+func NewSeriesDeletionRequestsLoaderFromConfig(querierCfg Config, storageCfg mimir_tsdb.BlocksStorageConfig, cfgProvider bucket.TenantConfigProvider, logger log.Logger, reg prometheus.Registerer) (*SeriesDeletionRequestsLoader, error) {
+	if querierCfg.SeriesDeletionRequestsRefreshInterval <= 0 {
+		return nil, nil
+	}
+
+	bucketClient, err := bucket.NewClient(context.Background(), storageCfg.Bucket, "series-deletion-requests", logger, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create bucket client")
+	}
+
+	// Once a request is processed, the blocks it was applied to are marked for deletion, and are no longer queried
+	// once the store-gateways ignore them.
+	processedDelay := storageCfg.BucketStore.IgnoreDeletionMarksInStoreGatewayDelay + storageCfg.BucketStore.SyncInterval
+
+	return NewSeriesDeletionRequestsLoader(bucketClient, cfgProvider, querierCfg.SeriesDeletionRequestsRefreshInterval, storageCfg.BucketStore.BucketIndex.IdleTimeout, processedDelay, logger), nil
+}
+
+// deletions returns the last series deletions loaded for the tenant. The first time the deletions of a tenant
+// are requested, they're loaded from the bucket.
+func (l *SeriesDeletionRequestsLoader) deletions(ctx context.Context, userID string) ([]seriesDeletion, error) {
+	l.tenantsMx.RLock()
+	if entry := l.tenants[userID]; entry != nil {
+		deletions, err := entry.deletions, entry.err
+		l.tenantsMx.RUnlock()
+
+		// We don't reload the deletions if they're stale, because it's the responsibility of the background job.
+		entry.requestedAt.Store(time.Now().Unix())
+		return deletions, err
+	}
+	l.tenantsMx.RUnlock()
+
+	deletions, err := l.load(ctx, userID)
+	if err != nil {
+		// Don't cache context.Canceled errors, as they are caused by the individual query.
+		if !errors.Is(err, context.Canceled) {
+			l.cacheDeletions(userID, nil, err)
+		}
+		return nil, err
+	}
+
+	l.cacheDeletions(userID, deletions, nil)
+	return deletions, nil
+}
+
+func (l *SeriesDeletionRequestsLoader) cacheDeletions(userID string, deletions []seriesDeletion, err error) {
+	l.tenantsMx.Lock()
+	defer l.tenantsMx.Unlock()
+
+	// Another query may have loaded the deletions in the meantime.
+	entry := l.tenants[userID]
+	if entry == nil {
+		entry = &cachedSeriesDeletions{}
+		l.tenants[userID] = entry
+	}
+	entry.deletions, entry.err = deletions, err
+	entry.requestedAt.Store(time.Now().Unix())
+}
+
+// refreshDeletions offloads the deletions that weren't requested for longer than the idle timeout,
+// and reloads all other ones.
+func (l *SeriesDeletionRequestsLoader) refreshDeletions(ctx context.Context) error {
+	now := time.Now()
+
+	var toUpdate []string
+	l.tenantsMx.Lock()
+	for userID, entry := range l.tenants {
+		if now.Sub(time.Unix(entry.requestedAt.Load(), 0)) >= l.idleTimeout {
+			delete(l.tenants, userID)
+			continue
+		}
+		toUpdate = append(toUpdate, userID)
+	}
+	l.tenantsMx.Unlock()
+
+	for _, userID := range toUpdate {
+		deletions, err := l.load(ctx, userID)
+		if errors.Is(err, context.Canceled) {
+			// The service is stopping.
+			return nil
+		}
+
+		l.tenantsMx.Lock()
+		if entry := l.tenants[userID]; entry != nil {
+			switch {
+			case err == nil:
+				entry.deletions, entry.err = deletions, nil
+			case entry.err == nil:
+				level.Warn(l.logger).Log("msg", "failed to reload series deletion requests, using the previously loaded ones", "user", userID, "err", err)
+			default:
+				entry.err = err
+			}
+		}
+		l.tenantsMx.Unlock()
+	}
+
+	// Never return error, otherwise the service terminates.
+	return nil
+}
+
+func (l *SeriesDeletionRequestsLoader) load(ctx context.Context, userID string) ([]seriesDeletion, error) {
+	requests, err := mimir_tsdb.ReadSeriesDeletionRequests(ctx, l.bkt, userID, l.cfgProvider, l.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	deletions := make([]seriesDeletion, 0, len(requests))
+	for _, req := range requests {
+		// The samples deleted by requests processed long enough ago aren't queried anymore.
+		if req.Status == mimir_tsdb.SeriesDeletionRequestProcessed && now.Sub(req.ProcessedTime.Time()) > l.processedDelay {
+			continue
+		}
+		matchers, err := req.Matchers()
+		if err != nil {
+			return nil, errors.Wrapf(err, "series deletion request %s", req.RequestID)
+		}
+		deletions = append(deletions, seriesDeletion{matchers: matchers, startTime: req.StartTime, endTime: req.EndTime})
+	}
+	return deletions, nil
+}
+
+// SeriesDeletions are the samples of tenants deleted by their series deletion requests, and by the series
+// retention rules, which may still be queried because they haven't been purged from the storage yet.
+type SeriesDeletions struct {
+	// The loader is nil if series deletion requests aren't filtered.
+	loader *SeriesDeletionRequestsLoader
+	limits *validation.Overrides
+	// retentionPurgeDelay is how long after their retention boundary the samples expired by the series retention
+	// rules are purged by the compactor and not queried anymore.
+	retentionPurgeDelay time.Duration
+}
+
+// NewSeriesDeletions makes a new SeriesDeletions. The loader is nil if series deletion requests aren't filtered.
+// The samples expired by the series retention rules are only filtered up to retentionPurgeDelay before their
+// retention boundary, because older ones have been purged by the compactor.
+func NewSeriesDeletions(loader *SeriesDeletionRequestsLoader, limits *validation.Overrides, retentionPurgeDelay time.Duration) *SeriesDeletions {
+	return &SeriesDeletions{loader: loader, limits: limits, retentionPurgeDelay: retentionPurgeDelay}
+}
+
+// Overlap returns whether any sample of the tenant between minT and maxT may be deleted. It's used by the
+// query-frontend to not cache the results of queries whose result changes as their samples are deleted.
+func (d *SeriesDeletions) Overlap(ctx context.Context, userID string, minT, maxT int64) (bool, error) {
+	deletions, err := d.overlapping(ctx, userID, minT, maxT)
+	return len(deletions) > 0, err
+}
+
+// overlapping returns the series deletions of the tenant overlapping the time range between minT and maxT.
+func (d *SeriesDeletions) overlapping(ctx context.Context, userID string, minT, maxT int64) ([]seriesDeletion, error) {
+	var deletions []seriesDeletion
+	if d.loader != nil {
+		var err error
+		if deletions, err = d.loader.deletions(ctx, userID); err != nil {
+			return nil, errors.Wrap(err, "failed to load series deletion requests")
+		}
+	}
+
+	// The samples of the series matching a series retention rule are deleted up to the retention boundary. The
+	// samples older than the purge delay before the boundary have already been purged by the compactor.
+	rules := d.limits.CompactorSeriesRetentionRules(userID)
+	if len(rules) > 0 {
+		now := time.Now()
+		deletions = slices.Clip(deletions)
+		for _, rule := range rules {
+			ms, err := rule.Matchers()
+			if err != nil {
+				return nil, errors.Wrapf(err, "series retention rule %q", rule.Match)
+			}
+			boundary := now.Add(-time.Duration(rule.RetentionPeriod))
+			deletions = append(deletions, seriesDeletion{matchers: [][]*labels.Matcher{ms}, startTime: boundary.Add(-d.retentionPurgeDelay).UnixMilli(), endTime: boundary.UnixMilli()})
+		}
+	}
+
+	var overlapping []seriesDeletion
+	for _, d := range deletions {
+		if d.startTime <= maxT && d.endTime >= minT {
+			overlapping = append(overlapping, d)
+		}
+	}
+	return overlapping, nil
+}
+
+// NewSeriesDeletionQueryable returns a queryable which doesn't return the deleted samples, nor the series and
+// labels left without samples.
+func NewSeriesDeletionQueryable(q storage.Queryable, deletions *SeriesDeletions) storage.Queryable {
+	return storage.QueryableFunc(func(minT, maxT int64) (storage.Querier, error) {
+		querier, err := q.Querier(minT, maxT)
+		if err != nil {
+			return nil, err
+		}
+
+		return &seriesDeletionQuerier{Querier: querier, deletions: deletions, minT: minT, maxT: maxT}, nil
+	})
+}
+
+type seriesDeletionQuerier struct {
+	storage.Querier
+
+	deletions  *SeriesDeletions
+	minT, maxT int64
+}
+
+func (q *seriesDeletionQuerier) Select(ctx context.Context, sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	minT, maxT := q.minT, q.maxT
+	if hints != nil {
+		minT, maxT = hints.Start, hints.End
+	}
+
+	deletions, err := q.overlapping(ctx, minT, maxT)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+	if len(deletions) == 0 {
+		return q.Querier.Select(ctx, sortSeries, hints, matchers...)
+	}
+
+	limit := 0
+	if hints != nil {
+		// Series-only selects don't fetch the samples, which are needed to know whether the series has any sample
+		// left. The limit is applied once the series without samples have been filtered out.
+		limit = hints.Limit
+		selectHints := *hints
+		if selectHints.Func == "series" {
+			selectHints.Func = ""
+		}
+		selectHints.Limit = 0
+		hints = &selectHints
+	}
+
+	set := q.Querier.Select(ctx, sortSeries, hints, matchers...)
+	return &seriesDeletionSeriesSet{SeriesSet: set, deletions: deletions, minT: minT, maxT: maxT, limit: limit}
+}
+
+// LabelNames returns the label names of the series with samples left between minT and maxT. If any sample in
+// the time range is deleted, the label names are read from the series selected by the matchers, which is slower.
+func (q *seriesDeletionQuerier) LabelNames(ctx context.Context, hints *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	deletions, err := q.overlapping(ctx, q.minT, q.maxT)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(deletions) == 0 {
+		return q.Querier.LabelNames(ctx, hints, matchers...)
+	}
+
+	if len(matchers) == 0 {
+		matchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")}
+	}
+	return q.selectLabels(ctx, hints, matchers, func(lbls labels.Labels, add func(string)) {
+		lbls.Range(func(l labels.Label) { add(l.Name) })
+	})
+}
+
+// LabelValues returns the label values of the series with samples left between minT and maxT. If any sample in
+// the time range is deleted, the label values are read from the series selected by the matchers, which is slower.
+func (q *seriesDeletionQuerier) LabelValues(ctx context.Context, name string, hints *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	deletions, err := q.overlapping(ctx, q.minT, q.maxT)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(deletions) == 0 {
+		return q.Querier.LabelValues(ctx, name, hints, matchers...)
+	}
+
+	matchers = append(slices.Clip(matchers), labels.MustNewMatcher(labels.MatchNotEqual, name, ""))
+	return q.selectLabels(ctx, hints, matchers, func(lbls labels.Labels, add func(string)) {
+		add(lbls.Get(name))
+	})
+}
+
+// overlapping returns the series deletions of the tenant overlapping the time range between minT and maxT.
+func (q *seriesDeletionQuerier) overlapping(ctx context.Context, minT, maxT int64) ([]seriesDeletion, error) {
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return q.deletions.overlapping(ctx, userID, minT, maxT)
+}
+
+// selectLabels returns the sorted strings added by collect for each series selected by the matchers.
+func (q *seriesDeletionQuerier) selectLabels(ctx context.Context, hints *storage.LabelHints, matchers []*labels.Matcher, collect func(labels.Labels, func(string))) ([]string, annotations.Annotations, error) {
+	unique := map[string]struct{}{}
+	add := func(s string) { unique[s] = struct{}{} }
+
+	set := q.Select(ctx, false, &storage.SelectHints{Start: q.minT, End: q.maxT, Func: "series"}, matchers...)
+	for set.Next() {
+		collect(set.At().Labels(), add)
+	}
+	if err := set.Err(); err != nil {
+		return nil, set.Warnings(), err
+	}
+
+	result := make([]string, 0, len(unique))
+	for s := range unique {
+		result = append(result, s)
+	}
+	slices.Sort(result)
+
+	if hints != nil && hints.Limit > 0 && len(result) > hints.Limit {
+		result = result[:hints.Limit]
+	}
+	return result, set.Warnings(), nil
+}
+
+// seriesDeletionSeriesSet removes the deleted samples from the series, and the series left without samples
+// between minT and maxT.
+type seriesDeletionSeriesSet struct {
+	storage.SeriesSet
+
+	deletions  []seriesDeletion
+	minT, maxT int64
+
+	// Maximum number of series returned, or 0 for no limit.
+	limit    int
+	returned int
+
+	curr storage.Series
+	it   chunkenc.Iterator
+	err  error
+}
+
+func (s *seriesDeletionSeriesSet) Next() bool {
+	if s.limit > 0 && s.returned >= s.limit {
+		return false
+	}
+
+	for s.SeriesSet.Next() {
+		series := s.SeriesSet.At()
+
+		intervals := deletedIntervals(s.deletions, series.Labels())
+		if len(intervals) > 0 {
+			if (tombstones.Interval{Mint: s.minT, Maxt: s.maxT}).IsSubrange(intervals) {
+				continue
+			}
+
+			series = &seriesDeletionSeries{Series: series, intervals: intervals}
+
+			// Skip the series if all its samples in the time range are deleted.
+			s.it = series.Iterator(s.it)
+			if s.it.Seek(s.minT) == chunkenc.ValNone || s.it.AtT() > s.maxT {
+				if err := s.it.Err(); err != nil {
+					s.err = err
+					return false
+				}
+				continue
+			}
+		}
+
+		s.curr = series
+		s.returned++
+		return true
+	}
+	return false
+}
+
+func (s *seriesDeletionSeriesSet) At() storage.Series {
+	return s.curr
+}
+
+func (s *seriesDeletionSeriesSet) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.SeriesSet.Err()
+}
+
+type seriesDeletionSeries struct {
+	storage.Series
+
+	intervals tombstones.Intervals
+}
+
+func (s *seriesDeletionSeries) Iterator(it chunkenc.Iterator) chunkenc.Iterator {
+	if deleted, ok := it.(*tsdb.DeletedIterator); ok {
+		deleted.Iter = s.Series.Iterator(deleted.Iter)
+		deleted.Intervals = s.intervals
+		return deleted
+	}
+
+	return &tsdb.DeletedIterator{Iter: s.Series.Iterator(it), Intervals: s.intervals}
+}
@@ -0,0 +1,269 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/util/annotations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/series"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestSeriesDeletionQueryable(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	bkt := objstore.NewInMemBucket()
+
+	var samples []model.SamplePair
+	for ts := model.Time(0); ts < 10; ts++ {
+		samples = append(samples, model.SamplePair{Timestamp: ts, Value: model.SampleValue(ts)})
+	}
+
+	upstream := storage.QueryableFunc(func(_, _ int64) (storage.Querier, error) {
+		return &mockSeriesDeletionQuerier{series: []storage.Series{
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "a", "job", "test"), samples, nil),
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "b", "job", "test"), samples, nil),
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "c", "job", "other"), samples, nil),
+		}}, nil
+	})
+
+	overrides, err := validation.NewOverrides(defaultLimitsConfig(), nil)
+	require.NoError(t, err)
+
+	loader := NewSeriesDeletionRequestsLoader(bkt, nil, time.Hour, time.Hour, time.Hour, log.NewNopLogger())
+	queryable := NewSeriesDeletionQueryable(upstream, NewSeriesDeletions(loader, overrides, 0))
+
+	query := func(ctx context.Context, minT, maxT int64) map[string][]int64 {
+		q, err := queryable.Querier(minT, maxT)
+		require.NoError(t, err)
+
+		result := map[string][]int64{}
+		set := q.Select(ctx, false, nil, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+"))
+		for set.Next() {
+			s := set.At()
+			var timestamps []int64
+			it := s.Iterator(nil)
+			for it.Next() != chunkenc.ValNone {
+				timestamps = append(timestamps, it.AtT())
+			}
+			require.NoError(t, it.Err())
+			result[s.Labels().Get(labels.MetricName)] = timestamps
+		}
+		require.NoError(t, set.Err())
+		return result
+	}
+
+	all := []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	assert.Equal(t, map[string][]int64{"a": all, "b": all, "c": all}, query(ctx, 0, 9))
+
+	for _, req := range []*mimir_tsdb.SeriesDeletionRequest{
+		{RequestID: "1", Selectors: []string{`{__name__="a"}`}, StartTime: 2, EndTime: 4},
+		{RequestID: "2", Selectors: []string{`{__name__="a"}`, `{__name__="b", job="test"}`}, StartTime: 4, EndTime: 6},
+		{RequestID: "3", Selectors: []string{`{__name__="c", job="test"}`}, StartTime: 0, EndTime: 9},
+	} {
+		require.NoError(t, mimir_tsdb.WriteSeriesDeletionRequest(ctx, bkt, "user-1", nil, req))
+	}
+
+	// The requests are only reloaded in background.
+	assert.Equal(t, map[string][]int64{"a": all, "b": all, "c": all}, query(ctx, 0, 9))
+
+	require.NoError(t, loader.refreshDeletions(context.Background()))
+	assert.Equal(t, map[string][]int64{
+		"a": {0, 1, 7, 8, 9},
+		"b": {0, 1, 2, 3, 7, 8, 9},
+		"c": all,
+	}, query(ctx, 0, 9))
+
+	// The series whose samples in the time range are all deleted aren't returned.
+	assert.Equal(t, map[string][]int64{"c": all}, query(ctx, 4, 6))
+
+	// Requests of other tenants don't apply.
+	assert.Equal(t, map[string][]int64{"a": all, "b": all, "c": all}, query(user.InjectOrgID(context.Background(), "user-2"), 0, 9))
+
+	// Processed requests still apply until the blocks they've been applied to aren't queried anymore.
+	for _, req := range []*mimir_tsdb.SeriesDeletionRequest{
+		{RequestID: "1", Selectors: []string{`{__name__="a"}`}, StartTime: 2, EndTime: 4, Status: mimir_tsdb.SeriesDeletionRequestProcessed, ProcessedTime: util.UnixSecondsFromTime(time.Now().Add(-time.Minute))},
+		{RequestID: "2", Selectors: []string{`{__name__="a"}`, `{__name__="b", job="test"}`}, StartTime: 4, EndTime: 6, Status: mimir_tsdb.SeriesDeletionRequestProcessed, ProcessedTime: util.UnixSecondsFromTime(time.Now().Add(-2 * time.Hour))},
+	} {
+		require.NoError(t, mimir_tsdb.WriteSeriesDeletionRequest(ctx, bkt, "user-1", nil, req))
+	}
+	require.NoError(t, loader.refreshDeletions(context.Background()))
+	assert.Equal(t, map[string][]int64{
+		"a": {0, 1, 5, 6, 7, 8, 9},
+		"b": all,
+		"c": all,
+	}, query(ctx, 0, 9))
+}
+
+func TestSeriesDeletionQueryable_SeriesRetentionRules(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	now := time.Now()
+
+	var samples []model.SamplePair
+	for ts := now.Add(-10 * time.Hour); !ts.After(now); ts = ts.Add(time.Hour) {
+		samples = append(samples, model.SamplePair{Timestamp: model.TimeFromUnixNano(ts.UnixNano()), Value: 1})
+	}
+
+	upstream := storage.QueryableFunc(func(_, _ int64) (storage.Querier, error) {
+		return &mockSeriesDeletionQuerier{series: []storage.Series{
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "container_cpu"), samples, nil),
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "slo_errors"), samples, nil),
+		}}, nil
+	})
+
+	tenantLimits := defaultLimitsConfig()
+	tenantLimits.CompactorSeriesRetentionRules = []*validation.SeriesRetentionRule{
+		{Match: `{__name__=~"container_.*"}`, RetentionPeriod: model.Duration(150 * time.Minute)},
+	}
+	overrides, err := validation.NewOverrides(defaultLimitsConfig(), validation.NewMockTenantLimits(map[string]*validation.Limits{"user-1": &tenantLimits}))
+	require.NoError(t, err)
+
+	// Series deletion requests aren't loaded when the loader is nil. The samples older than the purge delay before
+	// the retention boundary have already been purged by the compactor, so they aren't filtered.
+	q, err := NewSeriesDeletionQueryable(upstream, NewSeriesDeletions(nil, overrides, 4*time.Hour)).Querier(now.Add(-10*time.Hour).UnixMilli(), now.UnixMilli())
+	require.NoError(t, err)
+
+	result := map[string]int{}
+	set := q.Select(ctx, false, nil, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+"))
+	for set.Next() {
+		s := set.At()
+		it := s.Iterator(nil)
+		for it.Next() != chunkenc.ValNone {
+			result[s.Labels().Get(labels.MetricName)]++
+		}
+		require.NoError(t, it.Err())
+	}
+	require.NoError(t, set.Err())
+
+	// The samples of the matching series between 150 minutes and 6 hours and 30 minutes ago aren't returned.
+	assert.Equal(t, map[string]int{"container_cpu": 7, "slo_errors": 11}, result)
+}
+
+func TestSeriesDeletionQueryable_Labels(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	bkt := objstore.NewInMemBucket()
+
+	samples := []model.SamplePair{{Timestamp: 10, Value: 1}, {Timestamp: 20, Value: 1}}
+	upstream := storage.QueryableFunc(func(_, _ int64) (storage.Querier, error) {
+		return &mockSeriesDeletionQuerier{series: []storage.Series{
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "a", "job", "test"), samples, nil),
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "b", "job", "test", "pod", "b-1"), samples, nil),
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "b", "job", "other"), samples, nil),
+		}}, nil
+	})
+
+	require.NoError(t, mimir_tsdb.WriteSeriesDeletionRequest(ctx, bkt, "user-1", nil, &mimir_tsdb.SeriesDeletionRequest{
+		RequestID: "1", Selectors: []string{`{__name__="b", job="test"}`}, StartTime: 0, EndTime: 15,
+	}))
+
+	overrides, err := validation.NewOverrides(defaultLimitsConfig(), nil)
+	require.NoError(t, err)
+
+	loader := NewSeriesDeletionRequestsLoader(bkt, nil, time.Hour, time.Hour, time.Hour, log.NewNopLogger())
+	queryable := NewSeriesDeletionQueryable(upstream, NewSeriesDeletions(loader, overrides, 0))
+
+	querySeries := func(q storage.Querier, hints *storage.SelectHints) []string {
+		var result []string
+		set := q.Select(ctx, true, hints, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+"))
+		for set.Next() {
+			result = append(result, set.At().Labels().String())
+		}
+		require.NoError(t, set.Err())
+		return result
+	}
+
+	t.Run("time range with deleted samples", func(t *testing.T) {
+		q, err := queryable.Querier(0, 15)
+		require.NoError(t, err)
+
+		names, _, err := q.LabelNames(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{labels.MetricName, "job"}, names)
+
+		values, _, err := q.LabelValues(ctx, "job", nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "b"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"other"}, values)
+
+		values, _, err = q.LabelValues(ctx, "pod", nil)
+		require.NoError(t, err)
+		assert.Empty(t, values)
+
+		values, _, err = q.LabelValues(ctx, labels.MetricName, &storage.LabelHints{Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, values)
+
+		hints := &storage.SelectHints{Start: 0, End: 15, Func: "series", Limit: 2}
+		assert.Equal(t, []string{`{__name__="a", job="test"}`, `{__name__="b", job="other"}`}, querySeries(q, hints))
+	})
+
+	t.Run("time range without deleted samples", func(t *testing.T) {
+		q, err := queryable.Querier(16, 20)
+		require.NoError(t, err)
+
+		names, _, err := q.LabelNames(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{labels.MetricName, "job", "pod"}, names)
+
+		values, _, err := q.LabelValues(ctx, "pod", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b-1"}, values)
+
+		hints := &storage.SelectHints{Start: 16, End: 20, Func: "series"}
+		assert.Len(t, querySeries(q, hints), 3)
+	})
+}
+
+type mockSeriesDeletionQuerier struct {
+	storage.Querier
+
+	series []storage.Series
+}
+
+func (m *mockSeriesDeletionQuerier) Select(_ context.Context, _ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	var result []storage.Series
+	for _, s := range m.series {
+		if matchesAll(matchers, s.Labels()) {
+			result = append(result, s)
+		}
+	}
+	return series.NewConcreteSeriesSetFromUnsortedSeries(result)
+}
+
+func (m *mockSeriesDeletionQuerier) LabelNames(context.Context, *storage.LabelHints, ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	var names []string
+	for _, s := range m.series {
+		s.Labels().Range(func(l labels.Label) {
+			if !slices.Contains(names, l.Name) {
+				names = append(names, l.Name)
+			}
+		})
+	}
+	slices.Sort(names)
+	return names, nil, nil
+}
+
+func (m *mockSeriesDeletionQuerier) LabelValues(_ context.Context, name string, _ *storage.LabelHints, _ ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	var values []string
+	for _, s := range m.series {
+		if v := s.Labels().Get(name); v != "" && !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	slices.Sort(values)
+	return values, nil, nil
+}
@@ -233,6 +233,22 @@ func (s *Stats) LoadSpunOffSubqueries() uint32 {
 	return atomic.LoadUint32(&s.SpunOffSubqueries)
 }
 
+func (s *Stats) AddSpilledBytes(b uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.SpilledBytes, b)
+}
+
+func (s *Stats) LoadSpilledBytes() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.SpilledBytes)
+}
+
 // Merge the provided Stats into this one.
 func (s *Stats) Merge(other *Stats) {
 	if s == nil || other == nil {
@@ -251,6 +267,7 @@ func (s *Stats) Merge(other *Stats) {
 	s.AddEncodeTime(other.LoadEncodeTime())
 	s.AddSamplesProcessed(other.LoadSamplesProcessed())
 	s.AddSpunOffSubqueries(other.LoadSpunOffSubqueries())
+	s.AddSpilledBytes(other.LoadSpilledBytes())
 }
 
 // Copy returns a copy of the stats. Use this rather than regular struct assignment
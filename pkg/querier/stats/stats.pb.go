@@ -54,6 +54,8 @@ type Stats struct {
 	SamplesProcessed uint64 `protobuf:"varint,11,opt,name=samples_processed,json=samplesProcessed,proto3" json:"samples_processed,omitempty"`
 	// The number of subqueries that were spun off as actual range queries in order to execute the full query
 	SpunOffSubqueries uint32 `protobuf:"varint,12,opt,name=spun_off_subqueries,json=spunOffSubqueries,proto3" json:"spun_off_subqueries,omitempty"`
+	// The number of bytes of intermediate results written to disk by the Mimir query engine because the query exceeded its memory budget.
+	SpilledBytes uint64 `protobuf:"varint,13,opt,name=spilled_bytes,json=spilledBytes,proto3" json:"spilled_bytes,omitempty"`
 }
 
 func (m *Stats) Reset()      { *m = Stats{} }
@@ -172,6 +174,13 @@ func (m *Stats) GetSpunOffSubqueries() uint32 {
 	return 0
 }
 
+func (m *Stats) GetSpilledBytes() uint64 {
+	if m != nil {
+		return m.SpilledBytes
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Stats)(nil), "stats.Stats")
 }
@@ -179,35 +188,36 @@ func init() {
 func init() { proto.RegisterFile("stats.proto", fileDescriptor_b4756a0aec8b9d44) }
 
 var fileDescriptor_b4756a0aec8b9d44 = []byte{
-	// 447 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xb1, 0x8e, 0xd3, 0x40,
-	0x10, 0x40, 0xbd, 0x90, 0x1c, 0xc9, 0xe6, 0x0e, 0x88, 0x89, 0x90, 0xb9, 0x62, 0x2f, 0x82, 0x82,
-	0x48, 0x48, 0x0e, 0x02, 0x3a, 0x1a, 0x94, 0xbb, 0x86, 0x0a, 0x48, 0xa8, 0x68, 0x2c, 0xc7, 0x1e,
-	0x27, 0x16, 0xb6, 0xd7, 0xe7, 0xdd, 0x15, 0xd0, 0xf1, 0x09, 0x94, 0x7c, 0x02, 0x9f, 0x72, 0x65,
-	0xca, 0xab, 0x80, 0x38, 0x0d, 0xe5, 0x95, 0x94, 0x68, 0x67, 0xd7, 0x51, 0x8e, 0x2a, 0x5d, 0x76,
-	0xde, 0xbc, 0x99, 0xc9, 0x8c, 0x69, 0x4f, 0xc8, 0x50, 0x0a, 0xbf, 0xac, 0xb8, 0xe4, 0x6e, 0x1b,
-	0x1f, 0xc7, 0x83, 0x05, 0x5f, 0x70, 0x8c, 0x8c, 0xf5, 0x2f, 0x03, 0x8f, 0xd9, 0x82, 0xf3, 0x45,
-	0x06, 0x63, 0x7c, 0xcd, 0x55, 0x32, 0x8e, 0x55, 0x15, 0xca, 0x94, 0x17, 0x86, 0x3f, 0xfc, 0xdb,
-	0xa2, 0xed, 0x99, 0xf6, 0xdd, 0x57, 0xb4, 0xfb, 0x29, 0xcc, 0xb2, 0x40, 0xa6, 0x39, 0x78, 0x64,
-	0x48, 0x46, 0xbd, 0x67, 0x0f, 0x7c, 0x63, 0xfb, 0x8d, 0xed, 0x9f, 0x59, 0x7b, 0xd2, 0xb9, 0xf8,
-	0x79, 0xe2, 0x7c, 0xff, 0x75, 0x42, 0xa6, 0x1d, 0x6d, 0xbd, 0x4f, 0x73, 0x70, 0x9f, 0xd2, 0x41,
-	0x02, 0x32, 0x5a, 0x42, 0x1c, 0x08, 0xa8, 0x52, 0x10, 0x41, 0xc4, 0x55, 0x21, 0xbd, 0x1b, 0x43,
-	0x32, 0x6a, 0x4d, 0x5d, 0xcb, 0x66, 0x88, 0x4e, 0x35, 0x71, 0x7d, 0x7a, 0xaf, 0x31, 0xa2, 0xa5,
-	0x2a, 0x3e, 0x06, 0xf3, 0x2f, 0x12, 0x84, 0x77, 0x13, 0x85, 0xbe, 0x45, 0xa7, 0x9a, 0x4c, 0x34,
-	0xd8, 0xed, 0x80, 0xf9, 0x4d, 0x87, 0xd6, 0xb5, 0x0e, 0x28, 0xd8, 0x0e, 0x8f, 0xe9, 0x1d, 0xb1,
-	0x0c, 0xab, 0x18, 0xe2, 0xe0, 0x5c, 0x61, 0x67, 0xaf, 0x3d, 0x24, 0xa3, 0xa3, 0xe9, 0x6d, 0x1b,
-	0x7e, 0x67, 0xa2, 0xee, 0x23, 0x7a, 0x24, 0xca, 0x2c, 0x95, 0xdb, 0xb4, 0x03, 0x4c, 0x3b, 0xc4,
-	0x60, 0x93, 0xb4, 0x33, 0x6f, 0x5a, 0xc4, 0xf0, 0xd9, 0xce, 0x7b, 0xeb, 0xda, 0xbc, 0xaf, 0x35,
-	0x31, 0xf3, 0xbe, 0xa0, 0xf7, 0x41, 0xc8, 0x34, 0x0f, 0xe5, 0xff, 0x3b, 0xe9, 0xa0, 0x32, 0xd8,
-	0xd2, 0xdd, 0xad, 0x4c, 0x28, 0x3d, 0x57, 0xa0, 0xc0, 0x9c, 0xa2, 0xbb, 0xff, 0x29, 0xba, 0xa8,
-	0xe1, 0x2d, 0xce, 0x68, 0x0f, 0x8a, 0x88, 0xc7, 0xb6, 0x08, 0xdd, 0xbf, 0x08, 0x35, 0x1e, 0x56,
-	0x79, 0x42, 0xfb, 0x22, 0xcc, 0xcb, 0x0c, 0x44, 0x50, 0x56, 0x3c, 0x02, 0x21, 0x20, 0xf6, 0x7a,
-	0x38, 0xfa, 0x5d, 0x0b, 0xde, 0x36, 0x71, 0xbd, 0x1c, 0x51, 0xaa, 0x22, 0xe0, 0x49, 0x12, 0x08,
-	0x35, 0x6f, 0xf6, 0x78, 0x88, 0x7b, 0xec, 0x6b, 0xf4, 0x26, 0x49, 0x66, 0x5b, 0x30, 0x79, 0xb9,
-	0x5a, 0x33, 0xe7, 0x72, 0xcd, 0x9c, 0xab, 0x35, 0x23, 0x5f, 0x6b, 0x46, 0x7e, 0xd4, 0x8c, 0x5c,
-	0xd4, 0x8c, 0xac, 0x6a, 0x46, 0x7e, 0xd7, 0x8c, 0xfc, 0xa9, 0x99, 0x73, 0x55, 0x33, 0xf2, 0x6d,
-	0xc3, 0x9c, 0xd5, 0x86, 0x39, 0x97, 0x1b, 0xe6, 0x7c, 0x30, 0x5f, 0xfb, 0xfc, 0x00, 0xff, 0xc2,
-	0xf3, 0x7f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x1f, 0x46, 0x5f, 0x3b, 0x0a, 0x03, 0x00, 0x00,
+	// 459 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x93, 0xb1, 0x8e, 0xd3, 0x40,
+	0x10, 0x86, 0xbd, 0x70, 0x39, 0x92, 0x4d, 0x02, 0xc4, 0x44, 0xc8, 0x5c, 0xb1, 0x17, 0x41, 0x41,
+	0x24, 0x24, 0x07, 0x01, 0x1d, 0x0d, 0xca, 0x5d, 0x43, 0x05, 0x24, 0x54, 0x34, 0x96, 0x63, 0x8f,
+	0x13, 0x0b, 0xdb, 0xeb, 0xf3, 0xee, 0x0a, 0xe8, 0x78, 0x04, 0x4a, 0x1a, 0x7a, 0x1e, 0xe5, 0xca,
+	0x94, 0x57, 0x01, 0x71, 0x1a, 0xca, 0x7b, 0x04, 0xb4, 0xb3, 0xeb, 0x28, 0x47, 0x75, 0x5d, 0xf6,
+	0xff, 0xe6, 0x9f, 0x99, 0xfc, 0x23, 0xd3, 0xae, 0x90, 0xa1, 0x14, 0x7e, 0x59, 0x71, 0xc9, 0xdd,
+	0x16, 0x3e, 0x8e, 0x86, 0x4b, 0xbe, 0xe4, 0xa8, 0x4c, 0xf4, 0x2f, 0x03, 0x8f, 0xd8, 0x92, 0xf3,
+	0x65, 0x06, 0x13, 0x7c, 0x2d, 0x54, 0x32, 0x89, 0x55, 0x15, 0xca, 0x94, 0x17, 0x86, 0x3f, 0xfc,
+	0xd1, 0xa2, 0xad, 0xb9, 0xf6, 0xbb, 0xaf, 0x68, 0xe7, 0x53, 0x98, 0x65, 0x81, 0x4c, 0x73, 0xf0,
+	0xc8, 0x88, 0x8c, 0xbb, 0xcf, 0x1e, 0xf8, 0xc6, 0xed, 0x37, 0x6e, 0xff, 0xd4, 0xba, 0xa7, 0xed,
+	0xf3, 0x5f, 0xc7, 0xce, 0xf7, 0xdf, 0xc7, 0x64, 0xd6, 0xd6, 0xae, 0xf7, 0x69, 0x0e, 0xee, 0x53,
+	0x3a, 0x4c, 0x40, 0x46, 0x2b, 0x88, 0x03, 0x01, 0x55, 0x0a, 0x22, 0x88, 0xb8, 0x2a, 0xa4, 0x77,
+	0x63, 0x44, 0xc6, 0x07, 0x33, 0xd7, 0xb2, 0x39, 0xa2, 0x13, 0x4d, 0x5c, 0x9f, 0xde, 0x6b, 0x1c,
+	0xd1, 0x4a, 0x15, 0x1f, 0x83, 0xc5, 0x17, 0x09, 0xc2, 0xbb, 0x89, 0x86, 0x81, 0x45, 0x27, 0x9a,
+	0x4c, 0x35, 0xd8, 0x9f, 0x80, 0xf5, 0xcd, 0x84, 0x83, 0x2b, 0x13, 0xd0, 0x60, 0x27, 0x3c, 0xa6,
+	0x77, 0xc4, 0x2a, 0xac, 0x62, 0x88, 0x83, 0x33, 0x85, 0x93, 0xbd, 0xd6, 0x88, 0x8c, 0xfb, 0xb3,
+	0xdb, 0x56, 0x7e, 0x67, 0x54, 0xf7, 0x11, 0xed, 0x8b, 0x32, 0x4b, 0xe5, 0xae, 0xec, 0x10, 0xcb,
+	0x7a, 0x28, 0x36, 0x45, 0x7b, 0xfb, 0xa6, 0x45, 0x0c, 0x9f, 0xed, 0xbe, 0xb7, 0xae, 0xec, 0xfb,
+	0x5a, 0x13, 0xb3, 0xef, 0x0b, 0x7a, 0x1f, 0x84, 0x4c, 0xf3, 0x50, 0xfe, 0x9f, 0x49, 0x1b, 0x2d,
+	0xc3, 0x1d, 0xdd, 0x4f, 0x65, 0x4a, 0xe9, 0x99, 0x02, 0x05, 0xe6, 0x14, 0x9d, 0xeb, 0x9f, 0xa2,
+	0x83, 0x36, 0xbc, 0xc5, 0x29, 0xed, 0x42, 0x11, 0xf1, 0xd8, 0x36, 0xa1, 0xd7, 0x6f, 0x42, 0x8d,
+	0x0f, 0xbb, 0x3c, 0xa1, 0x03, 0x11, 0xe6, 0x65, 0x06, 0x22, 0x28, 0x2b, 0x1e, 0x81, 0x10, 0x10,
+	0x7b, 0x5d, 0x5c, 0xfd, 0xae, 0x05, 0x6f, 0x1b, 0x5d, 0x87, 0x23, 0x4a, 0x55, 0x04, 0x3c, 0x49,
+	0x02, 0xa1, 0x16, 0x4d, 0x8e, 0x3d, 0xcc, 0x71, 0xa0, 0xd1, 0x9b, 0x24, 0x99, 0xef, 0x80, 0x49,
+	0x3c, 0xcd, 0x32, 0x88, 0x6d, 0x8c, 0x7d, 0x6c, 0xdc, 0xb3, 0x22, 0x26, 0x38, 0x7d, 0xb9, 0xde,
+	0x30, 0xe7, 0x62, 0xc3, 0x9c, 0xcb, 0x0d, 0x23, 0x5f, 0x6b, 0x46, 0x7e, 0xd6, 0x8c, 0x9c, 0xd7,
+	0x8c, 0xac, 0x6b, 0x46, 0xfe, 0xd4, 0x8c, 0xfc, 0xad, 0x99, 0x73, 0x59, 0x33, 0xf2, 0x6d, 0xcb,
+	0x9c, 0xf5, 0x96, 0x39, 0x17, 0x5b, 0xe6, 0x7c, 0x30, 0x9f, 0xc4, 0xe2, 0x10, 0xff, 0xe7, 0xf3,
+	0x7f, 0x03, 0x00, 0x49, 0x7a, 0x04, 0xe5, 0x2f, 0x03, 0x00, 0x00,
 }
 
 func (this *Stats) Equal(that interface{}) bool {
@@ -265,13 +275,16 @@ func (this *Stats) Equal(that interface{}) bool {
 	if this.SpunOffSubqueries != that1.SpunOffSubqueries {
 		return false
 	}
+	if this.SpilledBytes != that1.SpilledBytes {
+		return false
+	}
 	return true
 }
 func (this *Stats) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 16)
+	s := make([]string, 0, 17)
 	s = append(s, "&stats.Stats{")
 	s = append(s, "WallTime: "+fmt.Sprintf("%#v", this.WallTime)+",\n")
 	s = append(s, "FetchedSeriesCount: "+fmt.Sprintf("%#v", this.FetchedSeriesCount)+",\n")
@@ -285,6 +298,7 @@ func (this *Stats) GoString() string {
 	s = append(s, "EncodeTime: "+fmt.Sprintf("%#v", this.EncodeTime)+",\n")
 	s = append(s, "SamplesProcessed: "+fmt.Sprintf("%#v", this.SamplesProcessed)+",\n")
 	s = append(s, "SpunOffSubqueries: "+fmt.Sprintf("%#v", this.SpunOffSubqueries)+",\n")
+	s = append(s, "SpilledBytes: "+fmt.Sprintf("%#v", this.SpilledBytes)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -316,6 +330,11 @@ func (m *Stats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.SpilledBytes != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.SpilledBytes))
+		i--
+		dAtA[i] = 0x68
+	}
 	if m.SpunOffSubqueries != 0 {
 		i = encodeVarintStats(dAtA, i, uint64(m.SpunOffSubqueries))
 		i--
@@ -438,6 +457,9 @@ func (m *Stats) Size() (n int) {
 	if m.SpunOffSubqueries != 0 {
 		n += 1 + sovStats(uint64(m.SpunOffSubqueries))
 	}
+	if m.SpilledBytes != 0 {
+		n += 1 + sovStats(uint64(m.SpilledBytes))
+	}
 	return n
 }
 
@@ -464,6 +486,7 @@ func (this *Stats) String() string {
 		`EncodeTime:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EncodeTime), "Duration", "durationpb.Duration", 1), `&`, ``, 1) + `,`,
 		`SamplesProcessed:` + fmt.Sprintf("%v", this.SamplesProcessed) + `,`,
 		`SpunOffSubqueries:` + fmt.Sprintf("%v", this.SpunOffSubqueries) + `,`,
+		`SpilledBytes:` + fmt.Sprintf("%v", this.SpilledBytes) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -775,6 +798,25 @@ func (m *Stats) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SpilledBytes", wireType)
+			}
+			m.SpilledBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SpilledBytes |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipStats(dAtA[iNdEx:])
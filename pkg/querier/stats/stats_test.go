@@ -160,6 +160,7 @@ func TestStats_Merge(t *testing.T) {
 		stats1.AddSplitQueries(10)
 		stats1.AddQueueTime(5 * time.Second)
 		stats1.AddSamplesProcessed(10)
+		stats1.AddSpilledBytes(100)
 
 		stats2 := &Stats{}
 		stats2.AddWallTime(time.Second)
@@ -170,6 +171,7 @@ func TestStats_Merge(t *testing.T) {
 		stats2.AddSplitQueries(11)
 		stats2.AddQueueTime(10 * time.Second)
 		stats2.AddSamplesProcessed(20)
+		stats2.AddSpilledBytes(200)
 
 		stats1.Merge(stats2)
 
@@ -181,6 +183,7 @@ func TestStats_Merge(t *testing.T) {
 		assert.Equal(t, uint32(21), stats1.LoadSplitQueries())
 		assert.Equal(t, 15*time.Second, stats1.LoadQueueTime())
 		assert.Equal(t, uint64(30), stats1.LoadSamplesProcessed())
+		assert.Equal(t, uint64(300), stats1.LoadSpilledBytes())
 	})
 
 	t.Run("merge two nil stats objects", func(t *testing.T) {
@@ -197,6 +200,7 @@ func TestStats_Merge(t *testing.T) {
 		assert.Equal(t, uint32(0), stats1.LoadSplitQueries())
 		assert.Equal(t, time.Duration(0), stats1.LoadQueueTime())
 		assert.Equal(t, uint64(0), stats1.LoadSamplesProcessed())
+		assert.Equal(t, uint64(0), stats1.LoadSpilledBytes())
 	})
 }
 
@@ -212,6 +216,7 @@ func TestStats_Copy(t *testing.T) {
 		EstimatedSeriesCount: 8,
 		QueueTime:            9,
 		SamplesProcessed:     10,
+		SpilledBytes:         11,
 	}
 	s2 := s1.Copy()
 	assert.NotSame(t, s1, s2)
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/munnerz/goautoneg"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	prom_api "github.com/prometheus/prometheus/web/api/v1"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/api"
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/streamingpromql"
+	"github.com/grafana/mimir/pkg/util"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	// Same as the limit applied by Prometheus' API.
+	maxRangeQueryPointsPerSeries = 11000
+
+	// Write series to the response in batches of at least this size, rather than one at a time.
+	streamingRangeQueryFlushThresholdBytes = 64 * 1024
+)
+
+// NewStreamingRangeQueryHandler returns a http.Handler that evaluates range queries with the Mimir query engine
+// and writes each series in the result to the response as soon as it's produced, if the request has the
+// api.StreamQueryResultHeader header. Series in the result are not sorted.
+//
+// All other requests, and range queries that can't be evaluated this way, are passed to next.
+func NewStreamingRangeQueryHandler(engine promql.QueryEngine, queryable storage.Queryable, next http.Handler, logger log.Logger) http.Handler {
+	mqe := mimirQueryEngine(engine)
+	if mqe == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(api.StreamQueryResultHeader) != "true" || !acceptsJSON(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		q := newStreamingRangeQuery(r, mqe, queryable)
+		if q == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		defer q.Close()
+		writeStreamingRangeQueryResult(w, r, q, r.FormValue("query"), logger)
+	})
+}
+
+// newStreamingRangeQuery returns the query for r, or nil if r is not a valid range query that the Mimir query
+// engine can evaluate with streamingpromql.Query.ExecStreaming.
+//
+// Invalid requests are left to Prometheus' API to reject, so that errors are reported in the same way as for
+// any other query.
+func newStreamingRangeQuery(r *http.Request, mqe *streamingpromql.Engine, queryable storage.Queryable) *streamingpromql.Query {
+	// These parameters change the response in ways that aren't supported when streaming the result.
+	for _, param := range []string{"limit", "stats", "timeout"} {
+		if r.FormValue(param) != "" {
+			return nil
+		}
+	}
+
+	start, err := util.ParseTime(r.FormValue("start"))
+	if err != nil {
+		return nil
+	}
+
+	end, err := util.ParseTime(r.FormValue("end"))
+	if err != nil || end < start {
+		return nil
+	}
+
+	step, err := util.ParseDurationMS(r.FormValue("step"))
+	if err != nil || step <= 0 || (end-start)/step > maxRangeQueryPointsPerSeries {
+		return nil
+	}
+
+	var lookbackDelta time.Duration
+	if s := r.FormValue("lookback_delta"); s != "" {
+		ms, err := util.ParseDurationMS(s)
+		if err != nil {
+			return nil
+		}
+
+		lookbackDelta = time.Duration(ms) * time.Millisecond
+	}
+
+	opts := promql.NewPrometheusQueryOpts(false, lookbackDelta)
+	q, err := mqe.NewRangeQuery(r.Context(), queryable, opts, r.FormValue("query"), util.TimeFromMillis(start), util.TimeFromMillis(end), time.Duration(step)*time.Millisecond)
+	if err != nil {
+		return nil
+	}
+
+	mq := q.(*streamingpromql.Query)
+	if !mq.CanExecStreaming() {
+		mq.Close()
+		return nil
+	}
+
+	return mq
+}
+
+// writeStreamingRangeQueryResult evaluates q and writes the result in the same format as Prometheus' API.
+//
+// If evaluating q fails before any series have been written, an error response is written as usual. Otherwise,
+// the error response is sent in the api.StreamQueryResultErrorTrailer trailer, and the response is incomplete.
+func writeStreamingRangeQueryResult(w http.ResponseWriter, r *http.Request, q *streamingpromql.Query, qs string, logger log.Logger) {
+	ctx := r.Context()
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	stream := json.BorrowStream(w)
+	defer json.ReturnStream(stream)
+
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		stream.WriteRaw(`{"status":"success","data":{"resultType":"matrix","result":[`)
+		started = true
+	}
+
+	annos, err := q.ExecStreaming(ctx, func(s promql.Series) error {
+		if started {
+			stream.WriteMore()
+		} else {
+			start()
+		}
+
+		stream.WriteVal(s)
+
+		if stream.Buffered() >= streamingRangeQueryFlushThresholdBytes {
+			return stream.Flush()
+		}
+
+		return stream.Error
+	})
+
+	stats.FromContext(ctx).AddSamplesProcessed(uint64(q.Stats().Samples.TotalSamples))
+
+	if err != nil {
+		apiErr := streamingQueryError(err)
+		body, encodeErr := apiErr.EncodeJSON()
+		if encodeErr != nil {
+			level.Error(util_log.WithContext(ctx, logger)).Log("msg", "failed to encode error response", "err", encodeErr)
+			return
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(apiErr.StatusCode())
+			_, _ = w.Write(body)
+			return
+		}
+
+		_ = stream.Flush()
+		w.Header().Set(http.TrailerPrefix+api.StreamQueryResultErrorTrailer, string(body))
+		return
+	}
+
+	if !started {
+		start()
+	}
+
+	stream.WriteRaw(`]}`)
+
+	warnings, infos := annos.AsStrings(qs, 10, 10)
+	if len(warnings) > 0 {
+		stream.WriteMore()
+		stream.WriteObjectField("warnings")
+		stream.WriteVal(warnings)
+	}
+
+	if len(infos) > 0 {
+		stream.WriteMore()
+		stream.WriteObjectField("infos")
+		stream.WriteVal(infos)
+	}
+
+	stream.WriteObjectEnd()
+
+	if err := stream.Flush(); err != nil {
+		level.Warn(util_log.WithContext(ctx, logger)).Log("msg", "failed to write query result", "err", err)
+	}
+}
+
+// streamingQueryError converts err to the same error Prometheus' API would return for a failed query.
+func streamingQueryError(err error) *apierror.APIError {
+	var (
+		errQueryCanceled promql.ErrQueryCanceled
+		errQueryTimeout  promql.ErrQueryTimeout
+		errStorage       promql.ErrStorage
+	)
+
+	switch {
+	case errors.As(err, &errQueryCanceled):
+		return apierror.New(apierror.TypeCanceled, err.Error())
+	case errors.As(err, &errQueryTimeout):
+		return apierror.New(apierror.TypeTimeout, err.Error())
+	case errors.As(err, &errStorage):
+		return apierror.New(apierror.TypeInternal, err.Error())
+	case errors.Is(err, context.Canceled):
+		return apierror.New(apierror.TypeCanceled, err.Error())
+	default:
+		return apierror.New(apierror.TypeExec, err.Error())
+	}
+}
+
+// acceptsJSON returns true if Prometheus' API would encode the response to r as JSON.
+func acceptsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return true
+	}
+
+	// Prometheus' API uses the first content type in the Accept header that it supports.
+	jsonType := prom_api.JSONCodec{}.ContentType()
+	protobufType := prom_api.MIMEType{Type: mimirpb.QueryResponseMimeTypeType, SubType: mimirpb.QueryResponseMimeTypeSubType}
+
+	for _, clause := range goautoneg.ParseAccept(accept) {
+		if jsonType.Satisfies(clause) {
+			return true
+		}
+
+		if protobufType.Satisfies(clause) {
+			return false
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/promqltest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/querier/api"
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/streamingpromql"
+)
+
+func TestStreamingRangeQueryHandler(t *testing.T) {
+	storage := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{idx="1"} 0+1x5
+			some_metric{idx="2"} 0+2x5
+	`)
+	t.Cleanup(func() { require.NoError(t, storage.Close()) })
+
+	opts := streamingpromql.NewTestEngineOpts()
+	mqe, err := streamingpromql.NewEngine(opts, streamingpromql.NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	type series struct {
+		Metric map[string]string `json:"metric"`
+		Values [][2]any          `json:"values"`
+	}
+
+	type response struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Data      struct {
+			ResultType string   `json:"resultType"`
+			Result     []series `json:"result"`
+		} `json:"data"`
+		Infos []string `json:"infos"`
+	}
+
+	testCases := map[string]struct {
+		engine             promql.QueryEngine
+		url                string
+		header             http.Header
+		ctx                func() context.Context
+		expectedStatusCode int
+		verify             func(t *testing.T, resp response)
+	}{
+		"range query": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=some_metric&start=0&end=120&step=60",
+			expectedStatusCode: http.StatusOK,
+			verify: func(t *testing.T, resp response) {
+				require.Equal(t, "success", resp.Status)
+				require.Equal(t, "matrix", resp.Data.ResultType)
+
+				slices.SortFunc(resp.Data.Result, func(a, b series) int { return strings.Compare(a.Metric["idx"], b.Metric["idx"]) })
+				require.Equal(t, []series{
+					{
+						Metric: map[string]string{"__name__": "some_metric", "idx": "1"},
+						Values: [][2]any{{float64(0), "0"}, {float64(60), "1"}, {float64(120), "2"}},
+					},
+					{
+						Metric: map[string]string{"__name__": "some_metric", "idx": "2"},
+						Values: [][2]any{{float64(0), "0"}, {float64(60), "2"}, {float64(120), "4"}},
+					},
+				}, resp.Data.Result)
+			},
+		},
+		"range query with empty result": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=other_metric&start=0&end=120&step=60",
+			expectedStatusCode: http.StatusOK,
+			verify: func(t *testing.T, resp response) {
+				require.Equal(t, "success", resp.Status)
+				require.Equal(t, "matrix", resp.Data.ResultType)
+				require.Empty(t, resp.Data.Result)
+			},
+		},
+		"range query with annotations": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=rate(some_metric[2m])&start=0&end=120&step=60",
+			expectedStatusCode: http.StatusOK,
+			verify: func(t *testing.T, resp response) {
+				require.Equal(t, "success", resp.Status)
+				require.Len(t, resp.Data.Result, 2)
+				require.Len(t, resp.Infos, 1)
+				require.Contains(t, resp.Infos[0], "metric might not be a counter")
+			},
+		},
+		"range query that fails before any series are written": {
+			engine: mqe,
+			url:    "/api/v1/query_range?query=some_metric&start=0&end=120&step=60",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			expectedStatusCode: 499,
+			verify: func(t *testing.T, resp response) {
+				require.Equal(t, "error", resp.Status)
+				require.Equal(t, "canceled", resp.ErrorType)
+			},
+		},
+		"range query without header": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=some_metric&start=0&end=120&step=60",
+			header:             http.Header{},
+			expectedStatusCode: http.StatusTeapot,
+		},
+		"range query that doesn't accept JSON": {
+			engine: mqe,
+			url:    "/api/v1/query_range?query=some_metric&start=0&end=120&step=60",
+			header: http.Header{
+				api.StreamQueryResultHeader: []string{"true"},
+				"Accept":                    []string{"application/vnd.mimir.queryresponse+protobuf"},
+			},
+			expectedStatusCode: http.StatusTeapot,
+		},
+		"range query producing a scalar": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=1&start=0&end=120&step=60",
+			expectedStatusCode: http.StatusTeapot,
+		},
+		"range query with limit": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=some_metric&start=0&end=120&step=60&limit=1",
+			expectedStatusCode: http.StatusTeapot,
+		},
+		"range query with too many points": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=some_metric&start=0&end=12000&step=1",
+			expectedStatusCode: http.StatusTeapot,
+		},
+		"invalid range query": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=some_metric&start=120&end=0&step=60",
+			expectedStatusCode: http.StatusTeapot,
+		},
+		"range query with invalid expression": {
+			engine:             mqe,
+			url:                "/api/v1/query_range?query=some_metric{&start=0&end=120&step=60",
+			expectedStatusCode: http.StatusTeapot,
+		},
+		"Prometheus' engine": {
+			engine:             promql.NewEngine(opts.CommonOpts),
+			url:                "/api/v1/query_range?query=some_metric&start=0&end=120&step=60",
+			expectedStatusCode: http.StatusTeapot,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			handler := NewStreamingRangeQueryHandler(testCase.engine, storage, next, log.NewNopLogger())
+
+			ctx := context.Background()
+			if testCase.ctx != nil {
+				ctx = testCase.ctx()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, testCase.url, nil).WithContext(ctx)
+			if testCase.header != nil {
+				req.Header = testCase.header
+			} else {
+				req.Header.Set(api.StreamQueryResultHeader, "true")
+			}
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, testCase.expectedStatusCode, resp.Code)
+
+			if testCase.verify == nil {
+				return
+			}
+
+			require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+			var decoded response
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &decoded))
+			testCase.verify(t, decoded)
+		})
+	}
+}
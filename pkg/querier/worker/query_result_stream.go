@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cancellation"
+	"github.com/grafana/dskit/httpgrpc"
+	httpgrpc_server "github.com/grafana/dskit/httpgrpc/server"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/frontend/v2/frontendv2pb"
+	"github.com/grafana/mimir/pkg/querier/api"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+var errQueryResultStreamFailed = cancellation.NewErrorf("streaming query result to query-frontend failed")
+
+// StreamingRequestHandler is a RequestHandler that can also write responses as they are produced, so they can be
+// streamed to the query-frontend.
+type StreamingRequestHandler interface {
+	RequestHandler
+
+	// HandleStreaming handles the request and writes the response to w.
+	HandleStreaming(context.Context, *httpgrpc.HTTPRequest, http.ResponseWriter) error
+}
+
+type streamingRequestHandler struct {
+	*httpgrpc_server.Server
+	handler http.Handler
+}
+
+// NewStreamingRequestHandler returns a StreamingRequestHandler for handler. Handle returns 4xx and 5xx responses
+// as errors.
+func NewStreamingRequestHandler(handler http.Handler) StreamingRequestHandler {
+	return &streamingRequestHandler{
+		Server:  httpgrpc_server.NewServer(handler, httpgrpc_server.WithReturn4XXErrors),
+		handler: handler,
+	}
+}
+
+func (h *streamingRequestHandler) HandleStreaming(ctx context.Context, r *httpgrpc.HTTPRequest, w http.ResponseWriter) error {
+	req, err := httpgrpc.ToHTTPRequest(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	h.handler.ServeHTTP(w, req)
+	return nil
+}
+
+// isStreamQueryResultRequest returns true if the query-frontend has asked for the result of request to be streamed.
+func isStreamQueryResultRequest(request *httpgrpc.HTTPRequest) bool {
+	for _, h := range request.Headers {
+		if http.CanonicalHeaderKey(h.Key) == api.StreamQueryResultHeader {
+			return len(h.Values) > 0 && h.Values[0] == "true"
+		}
+	}
+
+	return false
+}
+
+// queryResultStreamWriter is a http.ResponseWriter that sends the response body to the query-frontend as it is
+// written, once more than responseStreamingBodyChunkSizeBytes has been written. Smaller responses are buffered,
+// and sent to the query-frontend in a single message once the handler has returned.
+type queryResultStreamWriter struct {
+	queryID uint64
+	logger  log.Logger
+
+	// openStream opens a stream to the query-frontend for this query.
+	openStream func() (frontendv2pb.FrontendForQuerier_QueryResultStreamClient, error)
+
+	// cancelRequest aborts evaluation of the query if the stream to the query-frontend fails.
+	cancelRequest context.CancelCauseFunc
+
+	header http.Header
+	code   int
+	body   bytes.Buffer
+
+	stream frontendv2pb.FrontendForQuerier_QueryResultStreamClient
+	err    error
+}
+
+func (w *queryResultStreamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *queryResultStreamWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *queryResultStreamWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.body.Write(p)
+
+	for w.body.Len() > responseStreamingBodyChunkSizeBytes {
+		if err := w.send(w.body.Next(responseStreamingBodyChunkSizeBytes)); err != nil {
+			w.err = err
+			level.Warn(w.logger).Log("msg", "error streaming response body to frontend, aborting query", "err", err, "query_id", w.queryID)
+			w.cancelRequest(errQueryResultStreamFailed)
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// streaming returns true if the response has started to be sent to the query-frontend.
+func (w *queryResultStreamWriter) streaming() bool {
+	return w.stream != nil
+}
+
+// send sends chunk to the query-frontend, opening the stream and sending the response metadata first if required.
+func (w *queryResultStreamWriter) send(chunk []byte) error {
+	if w.stream == nil {
+		stream, err := w.openStream()
+		if err != nil {
+			return err
+		}
+
+		w.stream = stream
+
+		err = stream.Send(&frontendv2pb.QueryResultStreamRequest{
+			QueryID: w.queryID,
+			Data: &frontendv2pb.QueryResultStreamRequest_Metadata{Metadata: &frontendv2pb.QueryResultMetadata{
+				Code:    int32(w.code),
+				Headers: httpgrpc.FromHeader(w.responseHeader()),
+			}},
+		})
+		if err != nil {
+			return fmt.Errorf("error sending initial response to frontend: %w", err)
+		}
+	}
+
+	return w.stream.Send(&frontendv2pb.QueryResultStreamRequest{
+		QueryID: w.queryID,
+		Data:    &frontendv2pb.QueryResultStreamRequest_Body{Body: &frontendv2pb.QueryResultBody{Chunk: chunk}},
+	})
+}
+
+// finish sends the rest of the response body and the trailer, with stats and any error, to the query-frontend.
+// It must only be called once the handler has returned, and if the response is being streamed.
+func (w *queryResultStreamWriter) finish(stats *querier_stats.Stats) {
+	if w.err == nil && w.body.Len() > 0 {
+		w.err = w.send(w.body.Bytes())
+	}
+
+	if w.err == nil {
+		errMsg := ""
+		if body := w.errorTrailer(); body != "" {
+			errMsg = decodeErrorResponse(body).Error()
+		}
+
+		w.err = w.stream.Send(&frontendv2pb.QueryResultStreamRequest{
+			QueryID: w.queryID,
+			Data: &frontendv2pb.QueryResultStreamRequest_Trailer{Trailer: &frontendv2pb.QueryResultTrailer{
+				Stats: stats,
+				Error: errMsg,
+			}},
+		})
+	}
+
+	if w.err != nil {
+		level.Warn(w.logger).Log("msg", "error streaming response to frontend", "err", w.err, "query_id", w.queryID)
+	}
+
+	// Ignore error here because there's nothing we can do about it.
+	_, _ = w.stream.CloseAndRecv()
+}
+
+// response returns the buffered response. It must only be called once the handler has returned, and if the
+// response is not being streamed.
+func (w *queryResultStreamWriter) response() *httpgrpc.HTTPResponse {
+	if body := w.errorTrailer(); body != "" {
+		// The handler failed after it started writing the response, so the response is incomplete.
+		resp, _ := apierror.HTTPResponseFromError(decodeErrorResponse(body))
+		return resp
+	}
+
+	code := w.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	return &httpgrpc.HTTPResponse{
+		Code:    int32(code),
+		Headers: httpgrpc.FromHeader(w.responseHeader()),
+		Body:    w.body.Bytes(),
+	}
+}
+
+// responseHeader returns the headers of the response that should be sent to the query-frontend.
+func (w *queryResultStreamWriter) responseHeader() http.Header {
+	header := make(http.Header, len(w.header))
+
+	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) || k == httpgrpc_server.DoNotLogErrorHeaderKey || k == httpgrpc_server.ErrorMessageHeaderKey {
+			continue
+		}
+
+		header[k] = v
+	}
+
+	return header
+}
+
+func (w *queryResultStreamWriter) errorTrailer() string {
+	return w.header.Get(http.TrailerPrefix + api.StreamQueryResultErrorTrailer)
+}
+
+// decodeErrorResponse returns the error in body, a JSON-encoded error response from Prometheus' API.
+func decodeErrorResponse(body string) error {
+	var resp struct {
+		ErrorType apierror.Type `json:"errorType"`
+		Error     string        `json:"error"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return apierror.New(apierror.TypeInternal, body)
+	}
+
+	return apierror.New(resp.ErrorType, resp.Error)
+}
@@ -271,18 +271,78 @@ func (sp *schedulerProcessor) runRequest(ctx context.Context, logger log.Logger,
 		stats.AddQueueTime(queueTime)
 	}
 
+	if handler, ok := sp.handler.(StreamingRequestHandler); ok && sp.streamingEnabled && isStreamQueryResultRequest(request) {
+		sp.runStreamingRequest(ctx, logger, queryID, frontendAddress, handler, request, stats)
+		return
+	}
+
 	response, err := sp.handler.Handle(ctx, request)
 	if err != nil {
-		var ok bool
-		response, ok = httpgrpc.HTTPResponseFromError(err)
-		if !ok {
-			response = &httpgrpc.HTTPResponse{
-				Code: http.StatusInternalServerError,
-				Body: []byte(err.Error()),
+		response = httpResponseFromError(err)
+	}
+
+	sp.notifyFrontend(ctx, logger, queryID, frontendAddress, response, stats)
+}
+
+// runStreamingRequest handles request, and sends the response to the query-frontend as it is produced if it is
+// large enough to be worth streaming.
+func (sp *schedulerProcessor) runStreamingRequest(ctx context.Context, logger log.Logger, queryID uint64, frontendAddress string, handler StreamingRequestHandler, request *httpgrpc.HTTPRequest, stats *querier_stats.Stats) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(errQueryEvaluationFinished)
+
+	// Even if this query has been cancelled, we still want to tell the frontend about it, otherwise the frontend will wait for a result until it times out.
+	frontendCtx := context.WithoutCancel(ctx)
+
+	w := &queryResultStreamWriter{
+		queryID:       queryID,
+		logger:        logger,
+		cancelRequest: cancel,
+		header:        http.Header{},
+		openStream: func() (frontendv2pb.FrontendForQuerier_QueryResultStreamClient, error) {
+			c, err := sp.frontendPool.GetClientFor(frontendAddress)
+			if err != nil {
+				return nil, err
 			}
+
+			sc, err := c.(frontendv2pb.FrontendForQuerierClient).QueryResultStream(frontendCtx)
+			if err != nil {
+				sp.frontendPool.RemoveClient(c, frontendAddress)
+				return nil, fmt.Errorf("error creating stream to frontend: %w", err)
+			}
+
+			return sc, nil
+		},
+	}
+
+	if err := handler.HandleStreaming(ctx, request, w); err != nil {
+		sp.notifyFrontend(ctx, logger, queryID, frontendAddress, httpResponseFromError(err), stats)
+		return
+	}
+
+	if !w.streaming() {
+		sp.notifyFrontend(ctx, logger, queryID, frontendAddress, w.response(), stats)
+		return
+	}
+
+	// Protect against not-yet-exited querier handler goroutines that could
+	// still be incrementing stats when sent for marshaling below.
+	w.finish(stats.Copy())
+}
+
+func httpResponseFromError(err error) *httpgrpc.HTTPResponse {
+	response, ok := httpgrpc.HTTPResponseFromError(err)
+	if !ok {
+		response = &httpgrpc.HTTPResponse{
+			Code: http.StatusInternalServerError,
+			Body: []byte(err.Error()),
 		}
 	}
 
+	return response
+}
+
+// notifyFrontend sends response to the query-frontend, retrying if it fails.
+func (sp *schedulerProcessor) notifyFrontend(ctx context.Context, logger log.Logger, queryID uint64, frontendAddress string, response *httpgrpc.HTTPResponse, stats *querier_stats.Stats) {
 	// Ensure responses that are too big are not retried.
 	if len(response.Body) >= sp.maxMessageSize {
 		level.Error(logger).Log("msg", "response larger than max message size", "size", len(response.Body), "maxMessageSize", sp.maxMessageSize)
@@ -293,7 +353,10 @@ func (sp *schedulerProcessor) runRequest(ctx context.Context, logger log.Logger,
 			Body: []byte(errMsg),
 		}
 	}
-	var c client.PoolClient
+	var (
+		c   client.PoolClient
+		err error
+	)
 
 	// Even if this query has been cancelled, we still want to tell the frontend about it, otherwise the frontend will wait for a result until it times out.
 	frontendCtx := context.WithoutCancel(ctx)
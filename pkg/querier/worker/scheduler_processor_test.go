@@ -30,6 +30,7 @@ import (
 	"google.golang.org/grpc/metadata"
 
 	"github.com/grafana/mimir/pkg/frontend/v2/frontendv2pb"
+	"github.com/grafana/mimir/pkg/querier/api"
 	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/scheduler/schedulerpb"
 	"github.com/grafana/mimir/pkg/util/test"
@@ -604,6 +605,130 @@ func TestSchedulerProcessor_ResponseStream(t *testing.T) {
 	})
 }
 
+func TestSchedulerProcessor_StreamQueryResult(t *testing.T) {
+	streamQueryResultHeader := &httpgrpc.Header{Key: api.StreamQueryResultHeader, Values: []string{"true"}}
+	largeBody := bytes.Repeat([]byte("a"), 2*responseStreamingBodyChunkSizeBytes+1)
+
+	for name, tc := range map[string]struct {
+		streamingEnabled bool
+		requestHeaders   []*httpgrpc.Header
+		handler          http.HandlerFunc
+
+		expectStreamed bool
+		expectCode     int32
+		expectBody     []byte
+		expectError    string
+	}{
+		"should stream large response": {
+			streamingEnabled: true,
+			requestHeaders:   []*httpgrpc.Header{streamQueryResultHeader},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(largeBody[:responseStreamingBodyChunkSizeBytes])
+				_, _ = w.Write(largeBody[responseStreamingBodyChunkSizeBytes:])
+			},
+			expectStreamed: true,
+			expectCode:     http.StatusOK,
+			expectBody:     largeBody,
+		},
+		"should send error in trailer if handler fails after response has been streamed": {
+			streamingEnabled: true,
+			requestHeaders:   []*httpgrpc.Header{streamQueryResultHeader},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(largeBody)
+				w.Header().Set(http.TrailerPrefix+api.StreamQueryResultErrorTrailer, `{"status":"error","errorType":"execution","error":"something went wrong"}`)
+			},
+			expectStreamed: true,
+			expectCode:     http.StatusOK,
+			expectBody:     largeBody,
+			expectError:    "something went wrong",
+		},
+		"should not stream small response": {
+			streamingEnabled: true,
+			requestHeaders:   []*httpgrpc.Header{streamQueryResultHeader},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("small"))
+			},
+			expectCode: http.StatusOK,
+			expectBody: []byte("small"),
+		},
+		"should send error response if handler fails before response has been streamed": {
+			streamingEnabled: true,
+			requestHeaders:   []*httpgrpc.Header{streamQueryResultHeader},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("small"))
+				w.Header().Set(http.TrailerPrefix+api.StreamQueryResultErrorTrailer, `{"status":"error","errorType":"execution","error":"something went wrong"}`)
+			},
+			expectCode: http.StatusUnprocessableEntity,
+			expectBody: []byte(`{"status":"error","errorType":"execution","error":"something went wrong"}`),
+		},
+		"should not stream response if not requested": {
+			streamingEnabled: true,
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(largeBody)
+			},
+			expectCode: http.StatusOK,
+			expectBody: largeBody,
+		},
+		"should not stream response if streaming is disabled": {
+			requestHeaders: []*httpgrpc.Header{streamQueryResultHeader},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(largeBody)
+			},
+			expectCode: http.StatusOK,
+			expectBody: largeBody,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			reqProcessor, processClient, _, frontend := prepareSchedulerProcessor(t)
+			reqProcessor.handler = NewStreamingRequestHandler(tc.handler)
+			reqProcessor.streamingEnabled = tc.streamingEnabled
+			reqProcessor.maxMessageSize = 5 * responseStreamingBodyChunkSizeBytes
+
+			queryID := uint64(1)
+			received := atomic.NewBool(false)
+			processClient.On("Recv").Return(func() (*schedulerpb.SchedulerToQuerier, error) {
+				if received.CompareAndSwap(false, true) {
+					return &schedulerpb.SchedulerToQuerier{
+						QueryID:         queryID,
+						HttpRequest:     &httpgrpc.HTTPRequest{Method: http.MethodGet, Url: "/api/v1/query_range", Headers: tc.requestHeaders},
+						FrontendAddress: frontend.addr,
+						UserID:          "test",
+						StatsEnabled:    true,
+					}, nil
+				}
+
+				// No more messages to process, wait until terminated.
+				<-processClient.Context().Done()
+				return nil, toRPCErr(processClient.Context().Err())
+			})
+
+			workerCtx, workerCancel := context.WithCancel(context.Background())
+			go reqProcessor.processQueriesOnSingleStream(workerCtx, nil, "127.0.0.1")
+			t.Cleanup(workerCancel)
+
+			require.Eventually(t, func() bool {
+				return frontend.queryResultCalls.Load()+frontend.queryResultStreamReturned.Load() == 1
+			}, time.Second, 10*time.Millisecond)
+
+			resp := frontend.responses[queryID]
+			require.Equal(t, tc.expectCode, resp.metadata.Code)
+			require.Equal(t, tc.expectBody, resp.body)
+
+			if !tc.expectStreamed {
+				require.Equal(t, int64(1), frontend.queryResultCalls.Load())
+				require.NotNil(t, resp.metadata.Stats)
+				return
+			}
+
+			require.Equal(t, int64(1), frontend.queryResultStreamMetadataCalls.Load())
+			require.Nil(t, resp.metadata.Stats)
+			require.NotNil(t, resp.trailer)
+			require.NotNil(t, resp.trailer.Stats)
+			require.Equal(t, tc.expectError, resp.trailer.Error)
+		})
+	}
+}
+
 func prepareSchedulerProcessor(t *testing.T) (*schedulerProcessor, *querierLoopClientMock, *requestHandlerMock, *frontendForQuerierMockServer) {
 	loopClient := &querierLoopClientMock{}
 	loopClient.On("Send", mock.Anything).Return(nil)
@@ -747,11 +872,15 @@ type frontendForQuerierMockServer struct {
 type queryResult struct {
 	metadata *frontendv2pb.QueryResultMetadata
 	body     []byte
+	trailer  *frontendv2pb.QueryResultTrailer
 }
 
 func (f *frontendForQuerierMockServer) QueryResult(_ context.Context, r *frontendv2pb.QueryResultRequest) (*frontendv2pb.QueryResultResponse, error) {
-	f.queryResultCalls.Inc()
-	f.responses[r.QueryID] = &queryResult{body: r.HttpResponse.Body}
+	defer f.queryResultCalls.Inc()
+	f.responses[r.QueryID] = &queryResult{
+		metadata: &frontendv2pb.QueryResultMetadata{Code: r.HttpResponse.Code, Headers: r.HttpResponse.Headers, Stats: r.Stats},
+		body:     r.HttpResponse.Body,
+	}
 
 	return &frontendv2pb.QueryResultResponse{}, nil
 }
@@ -789,6 +918,11 @@ func (f *frontendForQuerierMockServer) QueryResultStream(s frontendv2pb.Frontend
 				return errors.New("expected metadata to be sent before body")
 			}
 			f.responses[resp.QueryID].body = append(f.responses[resp.QueryID].body, data.Body.Chunk...)
+		case *frontendv2pb.QueryResultStreamRequest_Trailer:
+			if !metadataSent {
+				return errors.New("expected metadata to be sent before trailer")
+			}
+			f.responses[resp.QueryID].trailer = data.Trailer
 		default:
 			return errors.New("unexpected request type")
 		}
@@ -131,8 +131,9 @@ func (c *concreteSeriesIterator) atType() (int64, chunkenc.ValueType) {
 }
 
 func (c *concreteSeriesIterator) Seek(t int64) chunkenc.ValueType {
+	// Seek from the start if the iterator hasn't been advanced yet, even if t isn't after the zero timestamp.
 	oldTime, oldType := c.atType()
-	if oldTime >= t { // only advance via Seek
+	if started := c.curFloat >= 0 || c.curHisto >= 0; started && oldTime >= t { // only advance via Seek
 		return oldType
 	}
 
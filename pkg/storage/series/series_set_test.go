@@ -6,6 +6,7 @@
 package series
 
 import (
+	"math"
 	"testing"
 
 	"github.com/prometheus/common/model"
@@ -150,4 +151,11 @@ func TestConcreteSeriesSetIterator(t *testing.T) {
 	require.Equal(t, generateTestHistogram(12), h)
 	require.Equal(t, chunkenc.ValNone, it.Seek(13)) // Seek to past end
 	require.Equal(t, chunkenc.ValNone, it.Seek(13)) // Ensure that seeking to same end still returns ValNone
+
+	// test seek to before the first sample
+	it = series.Iterator(nil)
+	require.Equal(t, chunkenc.ValFloat, it.Seek(math.MinInt64))
+	ts, v = it.At()
+	require.Equal(t, int64(1), ts)
+	require.Equal(t, float64(2), v)
 }
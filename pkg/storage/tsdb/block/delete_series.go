@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// SeriesDeletion deletes the samples of the series matching all the matchers, between MinTime and MaxTime (both inclusive).
+type SeriesDeletion struct {
+	Matchers         []*labels.Matcher
+	MinTime, MaxTime int64
+}
+
+// DeleteSeries creates a new block in dir with the samples of the block in bdir, except the ones deleted
+// by the deletions, and returns its ID. The deletions are written as tombstones of the block in bdir, which
+// are applied when the new block is written. The new block has the same time range, compaction sources,
+// external labels and resolution as the input block.
+//
+// If all the samples of the input block are deleted, no block is created and a zero ID is returned.
+func DeleteSeries(ctx context.Context, logger log.Logger, meta *Meta, bdir, dir string, deletions []SeriesDeletion) (id ulid.ULID, err error) {
+	b, err := tsdb.OpenBlock(util_log.SlogFromGoKit(logger), bdir, nil, nil)
+	if err != nil {
+		return id, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "delete series block reader")
+
+	for _, d := range deletions {
+		if err := b.Delete(ctx, d.MinTime, d.MaxTime, d.Matchers...); err != nil {
+			return id, errors.Wrap(err, "write tombstones")
+		}
+	}
+
+	return writeWithoutDeleted(ctx, logger, meta, b, dir, CompactorDeleteSource)
+}
+
+// writeWithoutDeleted creates a new block in dir with the samples of the block b which aren't deleted by its
+// tombstones, and returns its ID. The new block has the same time range, compaction sources, external labels and
+// resolution as the input block, and the given source.
+func writeWithoutDeleted(ctx context.Context, logger log.Logger, meta *Meta, b *tsdb.Block, dir string, source SourceType) (id ulid.ULID, err error) {
+	compactor, err := tsdb.NewLeveledCompactor(ctx, nil, util_log.SlogFromGoKit(logger), []int64{meta.MaxTime - meta.MinTime}, nil, nil)
+	if err != nil {
+		return id, errors.Wrap(err, "create compactor")
+	}
+
+	ids, err := compactor.Write(dir, b, meta.MinTime, meta.MaxTime, nil)
+	if err != nil {
+		return id, errors.Wrap(err, "write block")
+	}
+	if len(ids) == 0 {
+		return id, nil
+	}
+	id = ids[0]
+	resdir := filepath.Join(dir, id.String())
+
+	// The written block has an empty tombstones file, which isn't uploaded.
+	if err := os.Remove(filepath.Join(resdir, "tombstones")); err != nil {
+		return id, errors.Wrap(err, "remove tombstones")
+	}
+
+	resmeta, err := ReadMetaFromDir(resdir)
+	if err != nil {
+		return id, errors.Wrap(err, "read new meta")
+	}
+	resmeta.Compaction = meta.Compaction
+	resmeta.Thanos = meta.Thanos
+	resmeta.Thanos.Labels = maps.Clone(meta.Thanos.Labels)
+	resmeta.Thanos.Source = source
+	resmeta.Thanos.Files = nil
+	resmeta.Thanos.SegmentFiles = GetSegmentFiles(resdir)
+
+	if err := resmeta.WriteToDir(logger, resdir); err != nil {
+		return id, err
+	}
+	return id, nil
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteSeries(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	var (
+		samples  []chunks.Sample
+		expected []downsampledSample
+	)
+	for ts := int64(0); ts < 10; ts++ {
+		samples = append(samples, downsampledSample{t: ts, f: float64(ts)})
+		expected = append(expected, downsampledSample{t: ts, f: float64(ts)})
+	}
+	chk, err := chunks.ChunkFromSamples(samples)
+	require.NoError(t, err)
+
+	meta, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a"), Chunks: []chunks.Meta{chk}},
+		{Labels: labels.FromStrings(labels.MetricName, "b"), Chunks: []chunks.Meta{chk}},
+		{Labels: labels.FromStrings(labels.MetricName, "c"), Chunks: []chunks.Meta{chk}},
+	})
+	require.NoError(t, err)
+	meta.Thanos.Labels = map[string]string{"key": "value"}
+	meta.Thanos.Downsample.Resolution = Resolution5m
+
+	id, err := DeleteSeries(ctx, log.NewNopLogger(), meta, filepath.Join(dir, meta.ULID.String()), dir, []SeriesDeletion{
+		{Matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a")}, MinTime: 2, MaxTime: 7},
+		{Matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "b")}, MinTime: 0, MaxTime: 100},
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, ulid.ULID{}, id)
+
+	newMeta, err := ReadMetaFromDir(filepath.Join(dir, id.String()))
+	require.NoError(t, err)
+	assert.Equal(t, meta.MinTime, newMeta.MinTime)
+	assert.Equal(t, meta.MaxTime, newMeta.MaxTime)
+	assert.Equal(t, meta.Compaction, newMeta.Compaction)
+	assert.Equal(t, meta.Thanos.Labels, newMeta.Thanos.Labels)
+	assert.Equal(t, Resolution5m, newMeta.Thanos.Downsample.Resolution)
+	assert.Equal(t, CompactorDeleteSource, newMeta.Thanos.Source)
+	assert.Equal(t, uint64(2), newMeta.Stats.NumSeries)
+	assert.Equal(t, uint64(14), newMeta.Stats.NumSamples)
+	require.NoError(t, VerifyBlock(ctx, log.NewNopLogger(), filepath.Join(dir, id.String()), newMeta.MinTime, newMeta.MaxTime, false))
+
+	assert.Equal(t, map[string][]downsampledSample{
+		`{__name__="a"}`: {{t: 0, f: 0}, {t: 1, f: 1}, {t: 8, f: 8}, {t: 9, f: 9}},
+		`{__name__="c"}`: expected,
+	}, readDownsampledBlock(t, filepath.Join(dir, id.String())))
+
+	// No block is created when all the samples are deleted.
+	id, err = DeleteSeries(ctx, log.NewNopLogger(), meta, filepath.Join(dir, meta.ULID.String()), dir, []SeriesDeletion{
+		{Matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")}, MinTime: 0, MaxTime: 100},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ulid.ULID{}, id)
+}
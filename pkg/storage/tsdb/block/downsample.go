@@ -0,0 +1,581 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	crypto_rand "crypto/rand"
+	"maps"
+	"math"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	// ResolutionRaw is the resolution of blocks which haven't been downsampled.
+	ResolutionRaw = int64(0)
+
+	// Resolution5m is the resolution of blocks downsampled from raw blocks.
+	Resolution5m = int64(5 * time.Minute / time.Millisecond)
+
+	// Resolution1h is the resolution of blocks downsampled from 5m blocks.
+	Resolution1h = int64(time.Hour / time.Millisecond)
+
+	// AggregateLabel is the label added to the series of downsampled blocks. Its value is the
+	// aggregate of the original samples stored in the series.
+	AggregateLabel = "__aggregate__"
+)
+
+const (
+	AggregateAvg     = "avg"
+	AggregateCount   = "count"
+	AggregateCounter = "counter"
+	AggregateMax     = "max"
+	AggregateMin     = "min"
+	AggregateSum     = "sum"
+)
+
+// Aggregates is the sorted list of aggregates stored in downsampled blocks.
+var Aggregates = []string{AggregateAvg, AggregateCount, AggregateCounter, AggregateMax, AggregateMin, AggregateSum}
+
+// Downsample creates a new block in dir with the samples of the block in bdir downsampled to the given
+// resolution, and returns its ID. Raw blocks can be downsampled to Resolution5m, and 5m blocks to Resolution1h.
+//
+// Each series of the input block is stored as one series per aggregate, with the AggregateLabel label set to
+// the aggregate. The samples of each resolution window are aggregated into a single sample, whose timestamp is
+// the timestamp of the last sample in the window. The counter aggregate is the last value of the window: counter
+// resets are detected at query time between the windows, like between raw samples, rather than removed, because
+// the blocks of a series are downsampled separately and removing the counter resets of a block would create fake
+// counter resets at its boundaries. The increase before a counter reset within a window is lost. Native histogram
+// series have no min and max aggregates.
+//
+// The new block is written one aggregate at a time, to avoid keeping its series in memory.
+func Downsample(ctx context.Context, logger log.Logger, meta *Meta, bdir, dir string, resolution int64) (id ulid.ULID, err error) {
+	from := meta.Thanos.Downsample.Resolution
+	if !(from == ResolutionRaw && resolution == Resolution5m) && !(from == Resolution5m && resolution == Resolution1h) {
+		return id, errors.Errorf("cannot downsample block with resolution %d to resolution %d", from, resolution)
+	}
+
+	b, err := tsdb.OpenBlock(util_log.SlogFromGoKit(logger), bdir, nil, nil)
+	if err != nil {
+		return id, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "downsample block reader")
+
+	id = ulid.MustNew(ulid.Now(), crypto_rand.Reader)
+	resdir := filepath.Join(dir, id.String())
+
+	resmeta := *meta
+	resmeta.ULID = id
+	resmeta.Stats = tsdb.BlockStats{}
+	resmeta.Thanos.Labels = maps.Clone(meta.Thanos.Labels)
+	resmeta.Thanos.Downsample.Resolution = resolution
+	resmeta.Thanos.Source = CompactorDownsampleSource
+	resmeta.Thanos.Files = nil
+
+	if err := writeDownsampledBlock(ctx, b, resdir, from, resolution, &resmeta.Stats); err != nil {
+		return id, errors.Wrap(err, "write downsampled block")
+	}
+
+	resmeta.Thanos.SegmentFiles = GetSegmentFiles(resdir)
+	if err := resmeta.WriteToDir(logger, resdir); err != nil {
+		return id, err
+	}
+	return id, nil
+}
+
+func writeDownsampledBlock(ctx context.Context, b *tsdb.Block, resdir string, from, resolution int64, stats *tsdb.BlockStats) (err error) {
+	indexr, err := b.Index()
+	if err != nil {
+		return errors.Wrap(err, "open index")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexr, "downsample index reader")
+
+	chunkr, err := b.Chunks()
+	if err != nil {
+		return errors.Wrap(err, "open chunks")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkr, "downsample chunk reader")
+
+	chunkw, err := chunks.NewWriter(filepath.Join(resdir, ChunksDirname))
+	if err != nil {
+		return errors.Wrap(err, "open chunk writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkw, "downsample chunk writer")
+
+	indexw, err := index.NewWriter(ctx, filepath.Join(resdir, IndexFilename))
+	if err != nil {
+		return errors.Wrap(err, "open index writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexw, "downsample index writer")
+
+	d := &downsampler{
+		indexr:     indexr,
+		chunkr:     chunkr,
+		indexw:     indexw,
+		chunkw:     chunkw,
+		raw:        from == ResolutionRaw,
+		resolution: resolution,
+		stats:      stats,
+	}
+
+	if err := d.writeSymbols(); err != nil {
+		return err
+	}
+
+	// Series are sorted by aggregate first, because the aggregate label sorts before the metric name label.
+	for _, aggr := range Aggregates {
+		if err := d.writeAggregate(ctx, aggr); err != nil {
+			return errors.Wrapf(err, "write %s aggregate", aggr)
+		}
+	}
+	return nil
+}
+
+type downsampler struct {
+	indexr tsdb.IndexReader
+	chunkr tsdb.ChunkReader
+	indexw tsdb.IndexWriter
+	chunkw tsdb.ChunkWriter
+
+	raw        bool
+	resolution int64
+	ref        storage.SeriesRef
+	stats      *tsdb.BlockStats
+}
+
+func (d *downsampler) writeSymbols() error {
+	symbols := append([]string{AggregateLabel}, Aggregates...)
+
+	it := d.indexr.Symbols()
+	for it.Next() {
+		symbols = append(symbols, it.At())
+	}
+	if it.Err() != nil {
+		return errors.Wrap(it.Err(), "next symbol")
+	}
+
+	slices.Sort(symbols)
+	for _, s := range slices.Compact(symbols) {
+		if err := d.indexw.AddSymbol(s); err != nil {
+			return errors.Wrap(err, "add symbol")
+		}
+	}
+	return nil
+}
+
+func (d *downsampler) writeAggregate(ctx context.Context, aggr string) error {
+	if d.raw {
+		name, value := index.AllPostingsKey()
+		series, err := d.openSeries(ctx, name, value)
+		if err != nil {
+			return err
+		}
+		for series.next() {
+			a := &rawAggregator{resolution: d.resolution, aggr: aggr}
+			if err := d.iterate(series.chks, a.add); err != nil {
+				return err
+			}
+			a.flush()
+
+			lset := labels.NewBuilder(series.lset).Set(AggregateLabel, aggr).Labels()
+			if err := d.writeSeries(lset, a.out); err != nil {
+				return err
+			}
+		}
+		return series.err()
+	}
+
+	if aggr != AggregateAvg {
+		series, err := d.openSeries(ctx, AggregateLabel, aggr)
+		if err != nil {
+			return err
+		}
+		for series.next() {
+			out, err := d.downsampleAggregate(series.chks, aggr)
+			if err != nil {
+				return err
+			}
+			if err := d.writeSeries(series.lset, out); err != nil {
+				return err
+			}
+		}
+		return series.err()
+	}
+
+	// The average is computed from the sum and count aggregates of the same series.
+	sums, err := d.openSeries(ctx, AggregateLabel, AggregateSum)
+	if err != nil {
+		return err
+	}
+	counts, err := d.openSeries(ctx, AggregateLabel, AggregateCount)
+	if err != nil {
+		return err
+	}
+
+	hasSum, hasCount := sums.next(), counts.next()
+	for hasSum && hasCount {
+		switch c := labels.Compare(sums.base(), counts.base()); {
+		case c < 0:
+			hasSum = sums.next()
+		case c > 0:
+			hasCount = counts.next()
+		default:
+			sum, err := d.downsampleAggregate(sums.chks, AggregateSum)
+			if err != nil {
+				return err
+			}
+			count, err := d.downsampleAggregate(counts.chks, AggregateCount)
+			if err != nil {
+				return err
+			}
+
+			lset := labels.NewBuilder(sums.lset).Set(AggregateLabel, AggregateAvg).Labels()
+			if err := d.writeSeries(lset, averageSamples(sum, count)); err != nil {
+				return err
+			}
+			hasSum, hasCount = sums.next(), counts.next()
+		}
+	}
+	if err := sums.err(); err != nil {
+		return err
+	}
+	return counts.err()
+}
+
+func (d *downsampler) downsampleAggregate(chks []chunks.Meta, aggr string) ([]chunks.Sample, error) {
+	a := &aggregateAggregator{resolution: d.resolution, aggr: aggr}
+	if err := d.iterate(chks, a.add); err != nil {
+		return nil, err
+	}
+	a.flush()
+	return a.out, nil
+}
+
+// iterate calls fn for each sample of the given chunks, skipping stale markers.
+func (d *downsampler) iterate(chks []chunks.Meta, fn func(t int64, f float64, fh *histogram.FloatHistogram) error) error {
+	var it chunkenc.Iterator
+	for _, meta := range chks {
+		chk, iter, err := d.chunkr.ChunkOrIterable(meta)
+		if err != nil {
+			return errors.Wrap(err, "chunk read")
+		}
+		if iter != nil {
+			return errors.New("unexpected chunk iterable returned")
+		}
+
+		it = chk.Iterator(it)
+		for typ := it.Next(); typ != chunkenc.ValNone; typ = it.Next() {
+			var (
+				t  int64
+				f  float64
+				fh *histogram.FloatHistogram
+			)
+			if typ == chunkenc.ValFloat {
+				t, f = it.At()
+				if value.IsStaleNaN(f) {
+					continue
+				}
+			} else {
+				t, fh = it.AtFloatHistogram(nil)
+				if value.IsStaleNaN(fh.Sum) {
+					continue
+				}
+			}
+			if err := fn(t, f, fh); err != nil {
+				return err
+			}
+		}
+		if it.Err() != nil {
+			return errors.Wrap(it.Err(), "iterate chunk")
+		}
+	}
+	return nil
+}
+
+func (d *downsampler) writeSeries(lset labels.Labels, samples []chunks.Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	var chks []chunks.Meta
+	it := storage.NewSeriesToChunkEncoder(storage.NewListSeries(lset, samples)).Iterator(nil)
+	for it.Next() {
+		chks = append(chks, it.At())
+	}
+	if it.Err() != nil {
+		return errors.Wrap(it.Err(), "encode chunks")
+	}
+
+	if err := d.chunkw.WriteChunks(chks...); err != nil {
+		return errors.Wrap(err, "write chunks")
+	}
+	if err := d.indexw.AddSeries(d.ref, lset, chks...); err != nil {
+		return errors.Wrap(err, "add series")
+	}
+	d.ref++
+
+	d.stats.NumSeries++
+	d.stats.NumChunks += uint64(len(chks))
+	d.stats.NumSamples += uint64(len(samples))
+	return nil
+}
+
+// downsamplerSeries iterates the series of a postings list, in labels order.
+type downsamplerSeries struct {
+	indexr   tsdb.IndexReader
+	postings index.Postings
+	builder  labels.ScratchBuilder
+
+	lset labels.Labels
+	chks []chunks.Meta
+	e    error
+}
+
+func (d *downsampler) openSeries(ctx context.Context, name string, values ...string) (*downsamplerSeries, error) {
+	p, err := d.indexr.Postings(ctx, name, values...)
+	if err != nil {
+		return nil, errors.Wrap(err, "postings")
+	}
+	return &downsamplerSeries{indexr: d.indexr, postings: d.indexr.SortedPostings(p)}, nil
+}
+
+func (s *downsamplerSeries) next() bool {
+	if s.e != nil || !s.postings.Next() {
+		return false
+	}
+	if err := s.indexr.Series(s.postings.At(), &s.builder, &s.chks); err != nil {
+		s.e = errors.Wrap(err, "series")
+		return false
+	}
+	s.builder.Sort()
+	s.lset = s.builder.Labels()
+	return true
+}
+
+// base returns the labels of the current series without the aggregate label.
+func (s *downsamplerSeries) base() labels.Labels {
+	return labels.NewBuilder(s.lset).Del(AggregateLabel).Labels()
+}
+
+func (s *downsamplerSeries) err() error {
+	if s.e != nil {
+		return s.e
+	}
+	return errors.Wrap(s.postings.Err(), "iterate series")
+}
+
+func windowStart(t, resolution int64) int64 {
+	return t - ((t%resolution)+resolution)%resolution
+}
+
+// rawAggregator aggregates the raw samples of a series into one of the Aggregates.
+type rawAggregator struct {
+	resolution int64
+	aggr       string
+	out        []chunks.Sample
+
+	// Current window.
+	t                              int64
+	histogram                      bool
+	count                          int
+	sum, min, max, counter         float64
+	histogramSum, histogramCounter *histogram.FloatHistogram
+}
+
+func (a *rawAggregator) add(t int64, f float64, fh *histogram.FloatHistogram) error {
+	if a.count > 0 && (windowStart(t, a.resolution) != windowStart(a.t, a.resolution) || (fh != nil) != a.histogram) {
+		a.flush()
+	}
+	a.t, a.histogram = t, fh != nil
+
+	if fh == nil {
+		if a.count == 0 {
+			a.sum, a.min, a.max = 0, f, f
+		}
+		a.sum += f
+		a.min = math.Min(a.min, f)
+		a.max = math.Max(a.max, f)
+		a.counter = f
+		a.count++
+		return nil
+	}
+
+	a.histogramCounter = fh.Copy()
+
+	if a.count == 0 {
+		a.histogramSum = fh.Copy()
+	} else if _, err := a.histogramSum.Add(fh); err != nil {
+		return errors.Wrap(err, "add histogram")
+	}
+	a.count++
+	return nil
+}
+
+func (a *rawAggregator) flush() {
+	if a.count == 0 {
+		return
+	}
+	defer func() { a.count = 0 }()
+
+	count := float64(a.count)
+	if !a.histogram {
+		switch a.aggr {
+		case AggregateAvg:
+			a.out = append(a.out, downsampledSample{t: a.t, f: a.sum / count})
+		case AggregateCount:
+			a.out = append(a.out, downsampledSample{t: a.t, f: count})
+		case AggregateCounter:
+			a.out = append(a.out, downsampledSample{t: a.t, f: a.counter})
+		case AggregateMax:
+			a.out = append(a.out, downsampledSample{t: a.t, f: a.max})
+		case AggregateMin:
+			a.out = append(a.out, downsampledSample{t: a.t, f: a.min})
+		case AggregateSum:
+			a.out = append(a.out, downsampledSample{t: a.t, f: a.sum})
+		}
+		return
+	}
+
+	switch a.aggr {
+	case AggregateAvg:
+		a.out = append(a.out, downsampledSample{t: a.t, fh: gaugeHistogram(a.histogramSum.Copy().Div(count))})
+	case AggregateCount:
+		a.out = append(a.out, downsampledSample{t: a.t, f: count})
+	case AggregateCounter:
+		a.out = append(a.out, downsampledSample{t: a.t, fh: counterHistogram(a.histogramCounter)})
+	case AggregateSum:
+		a.out = append(a.out, downsampledSample{t: a.t, fh: gaugeHistogram(a.histogramSum)})
+	}
+}
+
+// aggregateAggregator aggregates the samples of an aggregate series of a downsampled block
+// into the same aggregate at a lower resolution.
+type aggregateAggregator struct {
+	resolution int64
+	aggr       string
+	out        []chunks.Sample
+
+	// Current window.
+	t         int64
+	count     int
+	f         float64
+	histogram *histogram.FloatHistogram
+}
+
+func (a *aggregateAggregator) add(t int64, f float64, fh *histogram.FloatHistogram) error {
+	if a.count > 0 && (windowStart(t, a.resolution) != windowStart(a.t, a.resolution) || (fh != nil) != (a.histogram != nil)) {
+		a.flush()
+	}
+	a.t = t
+
+	switch {
+	case a.count == 0 || a.aggr == AggregateCounter:
+		a.f, a.histogram = f, fh
+	case fh != nil:
+		// Only the sum and counter aggregates of native histograms series are histograms.
+		if _, err := a.histogram.Add(fh); err != nil {
+			return errors.Wrap(err, "add histogram")
+		}
+	case a.aggr == AggregateMin:
+		a.f = math.Min(a.f, f)
+	case a.aggr == AggregateMax:
+		a.f = math.Max(a.f, f)
+	default:
+		a.f += f
+	}
+	a.count++
+	return nil
+}
+
+func (a *aggregateAggregator) flush() {
+	if a.count == 0 {
+		return
+	}
+	a.count = 0
+
+	switch {
+	case a.histogram == nil:
+		a.out = append(a.out, downsampledSample{t: a.t, f: a.f})
+	case a.aggr == AggregateCounter:
+		a.out = append(a.out, downsampledSample{t: a.t, fh: counterHistogram(a.histogram)})
+	default:
+		a.out = append(a.out, downsampledSample{t: a.t, fh: gaugeHistogram(a.histogram)})
+	}
+	a.histogram = nil
+}
+
+// averageSamples divides the sum samples by the count samples with the same timestamp.
+func averageSamples(sum, count []chunks.Sample) []chunks.Sample {
+	var out []chunks.Sample
+	for i, j := 0, 0; i < len(sum) && j < len(count); {
+		switch s, c := sum[i], count[j]; {
+		case s.T() < c.T():
+			i++
+		case s.T() > c.T():
+			j++
+		default:
+			if s.FH() != nil {
+				out = append(out, downsampledSample{t: s.T(), fh: gaugeHistogram(s.FH().Copy().Div(c.F()))})
+			} else {
+				out = append(out, downsampledSample{t: s.T(), f: s.F() / c.F()})
+			}
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+func gaugeHistogram(fh *histogram.FloatHistogram) *histogram.FloatHistogram {
+	fh.CounterResetHint = histogram.GaugeType
+	return fh
+}
+
+// counterHistogram clears the counter reset hint of the last histogram of a window, letting the chunk encoding
+// detect the counter resets between windows.
+func counterHistogram(fh *histogram.FloatHistogram) *histogram.FloatHistogram {
+	fh.CounterResetHint = histogram.UnknownCounterReset
+	return fh
+}
+
+type downsampledSample struct {
+	t  int64
+	f  float64
+	fh *histogram.FloatHistogram
+}
+
+func (s downsampledSample) T() int64                      { return s.t }
+func (s downsampledSample) F() float64                    { return s.f }
+func (s downsampledSample) H() *histogram.Histogram       { return nil }
+func (s downsampledSample) FH() *histogram.FloatHistogram { return s.fh }
+
+func (s downsampledSample) Type() chunkenc.ValueType {
+	if s.fh != nil {
+		return chunkenc.ValFloatHistogram
+	}
+	return chunkenc.ValFloat
+}
+
+func (s downsampledSample) Copy() chunks.Sample {
+	if s.fh != nil {
+		return downsampledSample{t: s.t, fh: s.fh.Copy()}
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownsample(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		dir    = t.TempDir()
+		minute = time.Minute.Milliseconds()
+
+		floats     []chunks.Sample
+		histograms []chunks.Sample
+	)
+
+	// A counter reset happens at the 8th sample.
+	for i := int64(0); i < 15; i++ {
+		v := 10 * i
+		if i >= 7 {
+			v = 10 * (i - 7)
+		}
+		floats = append(floats, downsampledSample{t: i * minute, f: float64(v)})
+		histograms = append(histograms, downsampledSample{t: i * minute, fh: &histogram.FloatHistogram{Count: float64(i + 1), ZeroCount: float64(i + 1), Sum: float64(i + 1)}})
+	}
+
+	floatsChunk, err := chunks.ChunkFromSamples(floats)
+	require.NoError(t, err)
+	histogramsChunk, err := chunks.ChunkFromSamples(histograms)
+	require.NoError(t, err)
+
+	meta, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "floats"), Chunks: []chunks.Meta{floatsChunk}},
+		{Labels: labels.FromStrings(labels.MetricName, "histograms"), Chunks: []chunks.Meta{histogramsChunk}},
+	})
+	require.NoError(t, err)
+
+	_, err = Downsample(ctx, log.NewNopLogger(), meta, filepath.Join(dir, meta.ULID.String()), dir, Resolution1h)
+	require.EqualError(t, err, "cannot downsample block with resolution 0 to resolution 3600000")
+
+	id5m, err := Downsample(ctx, log.NewNopLogger(), meta, filepath.Join(dir, meta.ULID.String()), dir, Resolution5m)
+	require.NoError(t, err)
+
+	meta5m, err := ReadMetaFromDir(filepath.Join(dir, id5m.String()))
+	require.NoError(t, err)
+	assert.Equal(t, Resolution5m, meta5m.Thanos.Downsample.Resolution)
+	assert.Equal(t, CompactorDownsampleSource, meta5m.Thanos.Source)
+	assert.Equal(t, meta.MinTime, meta5m.MinTime)
+	assert.Equal(t, meta.MaxTime, meta5m.MaxTime)
+	assert.Equal(t, meta.Compaction.Sources, meta5m.Compaction.Sources)
+	assert.Equal(t, uint64(10), meta5m.Stats.NumSeries)
+	require.NoError(t, VerifyBlock(ctx, log.NewNopLogger(), filepath.Join(dir, id5m.String()), meta5m.MinTime, meta5m.MaxTime, true))
+
+	assert.Equal(t, map[string][]downsampledSample{
+		`{__aggregate__="avg", __name__="floats"}`:     {{t: 4 * minute, f: 20}, {t: 9 * minute, f: 28}, {t: 14 * minute, f: 50}},
+		`{__aggregate__="count", __name__="floats"}`:   {{t: 4 * minute, f: 5}, {t: 9 * minute, f: 5}, {t: 14 * minute, f: 5}},
+		`{__aggregate__="counter", __name__="floats"}`: {{t: 4 * minute, f: 40}, {t: 9 * minute, f: 20}, {t: 14 * minute, f: 70}},
+		`{__aggregate__="max", __name__="floats"}`:     {{t: 4 * minute, f: 40}, {t: 9 * minute, f: 60}, {t: 14 * minute, f: 70}},
+		`{__aggregate__="min", __name__="floats"}`:     {{t: 4 * minute, f: 0}, {t: 9 * minute, f: 0}, {t: 14 * minute, f: 30}},
+		`{__aggregate__="sum", __name__="floats"}`:     {{t: 4 * minute, f: 100}, {t: 9 * minute, f: 140}, {t: 14 * minute, f: 250}},
+		`{__aggregate__="avg", __name__="histograms"}`: {
+			{t: 4 * minute, f: 3}, {t: 9 * minute, f: 8}, {t: 14 * minute, f: 13},
+		},
+		`{__aggregate__="count", __name__="histograms"}`: {{t: 4 * minute, f: 5}, {t: 9 * minute, f: 5}, {t: 14 * minute, f: 5}},
+		`{__aggregate__="counter", __name__="histograms"}`: {
+			{t: 4 * minute, f: 5}, {t: 9 * minute, f: 10}, {t: 14 * minute, f: 15},
+		},
+		`{__aggregate__="sum", __name__="histograms"}`: {
+			{t: 4 * minute, f: 15}, {t: 9 * minute, f: 40}, {t: 14 * minute, f: 65},
+		},
+	}, readDownsampledBlock(t, filepath.Join(dir, id5m.String())))
+
+	id1h, err := Downsample(ctx, log.NewNopLogger(), meta5m, filepath.Join(dir, id5m.String()), dir, Resolution1h)
+	require.NoError(t, err)
+	require.NoError(t, VerifyBlock(ctx, log.NewNopLogger(), filepath.Join(dir, id1h.String()), meta5m.MinTime, meta5m.MaxTime, true))
+
+	assert.Equal(t, map[string][]downsampledSample{
+		`{__aggregate__="avg", __name__="floats"}`:         {{t: 14 * minute, f: 490.0 / 15}},
+		`{__aggregate__="count", __name__="floats"}`:       {{t: 14 * minute, f: 15}},
+		`{__aggregate__="counter", __name__="floats"}`:     {{t: 14 * minute, f: 70}},
+		`{__aggregate__="max", __name__="floats"}`:         {{t: 14 * minute, f: 70}},
+		`{__aggregate__="min", __name__="floats"}`:         {{t: 14 * minute, f: 0}},
+		`{__aggregate__="sum", __name__="floats"}`:         {{t: 14 * minute, f: 490}},
+		`{__aggregate__="avg", __name__="histograms"}`:     {{t: 14 * minute, f: 8}},
+		`{__aggregate__="count", __name__="histograms"}`:   {{t: 14 * minute, f: 15}},
+		`{__aggregate__="counter", __name__="histograms"}`: {{t: 14 * minute, f: 15}},
+		`{__aggregate__="sum", __name__="histograms"}`:     {{t: 14 * minute, f: 120}},
+	}, readDownsampledBlock(t, filepath.Join(dir, id1h.String())))
+}
+
+func TestDownsample_CounterResetsAcrossBlocks(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		dir    = t.TempDir()
+		minute = time.Minute.Milliseconds()
+	)
+
+	downsample := func(minT int64, values ...float64) []downsampledSample {
+		var samples []chunks.Sample
+		for i, v := range values {
+			samples = append(samples, downsampledSample{t: minT + int64(i)*minute, f: v})
+		}
+		chk, err := chunks.ChunkFromSamples(samples)
+		require.NoError(t, err)
+		meta, err := GenerateBlockFromSpec(dir, SeriesSpecs{{Labels: labels.FromStrings(labels.MetricName, "counter"), Chunks: []chunks.Meta{chk}}})
+		require.NoError(t, err)
+
+		id, err := Downsample(ctx, log.NewNopLogger(), meta, filepath.Join(dir, meta.ULID.String()), dir, Resolution5m)
+		require.NoError(t, err)
+		return readDownsampledBlock(t, filepath.Join(dir, id.String()))[`{__aggregate__="counter", __name__="counter"}`]
+	}
+
+	// The counter is reset in the first block, and keeps increasing in the second block, which is downsampled
+	// separately: the counter aggregate of the first block isn't offset by its counter reset, so that the second
+	// block doesn't start with a fake counter reset.
+	assert.Equal(t, []downsampledSample{{t: 4 * minute, f: 10}}, downsample(0, 10, 20, 30, 5, 10))
+	assert.Equal(t, []downsampledSample{{t: 9 * minute, f: 35}}, downsample(5*minute, 15, 20, 25, 30, 35))
+}
+
+// readDownsampledBlock returns the samples of each series of a block. The count of histogram samples
+// is returned as the sample value.
+func readDownsampledBlock(t *testing.T, dir string) map[string][]downsampledSample {
+	b, err := tsdb.OpenBlock(nil, dir, nil, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, b.Close()) })
+
+	q, err := tsdb.NewBlockQuerier(b, b.MinTime(), b.MaxTime())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, q.Close()) })
+
+	result := map[string][]downsampledSample{}
+	set := q.Select(context.Background(), true, nil, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+"))
+	var it chunkenc.Iterator
+	for set.Next() {
+		s := set.At()
+		it = s.Iterator(it)
+		for typ := it.Next(); typ != chunkenc.ValNone; typ = it.Next() {
+			if typ == chunkenc.ValFloat {
+				ts, f := it.At()
+				result[s.Labels().String()] = append(result[s.Labels().String()], downsampledSample{t: ts, f: f})
+				continue
+			}
+			ts, fh := it.AtFloatHistogram(nil)
+			result[s.Labels().String()] = append(result[s.Labels().String()], downsampledSample{t: ts, f: fh.Count})
+		}
+		require.NoError(t, it.Err())
+	}
+	require.NoError(t, set.Err())
+	return result
+}
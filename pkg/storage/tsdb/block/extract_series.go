@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"math"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// ExtractSeries creates a new block in dir with the samples of the block in bdir between minTime and maxTime
+// (both inclusive), of the series matching all the matchers of any item of matchers, and returns its ID. All the
+// series are extracted if no matchers are given. The other samples are written as tombstones of the block in bdir,
+// replacing its existing tombstones, and are deleted when the new block is written. The new block has the same time
+// range, compaction sources, external labels and resolution as the input block.
+//
+// If no sample of the input block is extracted, no block is created and a zero ID is returned.
+func ExtractSeries(ctx context.Context, logger log.Logger, meta *Meta, bdir, dir string, matchers [][]*labels.Matcher, minTime, maxTime int64) (ulid.ULID, error) {
+	deleted, err := extractTombstones(ctx, logger, bdir, matchers, minTime, maxTime)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+
+	if _, err := tombstones.WriteFile(util_log.SlogFromGoKit(logger), bdir, deleted); err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "write tombstones")
+	}
+
+	return extractSeries(ctx, logger, meta, bdir, dir)
+}
+
+func extractSeries(ctx context.Context, logger log.Logger, meta *Meta, bdir, dir string) (id ulid.ULID, err error) {
+	b, err := tsdb.OpenBlock(util_log.SlogFromGoKit(logger), bdir, nil, nil)
+	if err != nil {
+		return id, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "extract series block reader")
+
+	return writeWithoutDeleted(ctx, logger, meta, b, dir, CompactorMigrateSource)
+}
+
+// extractTombstones returns the tombstones deleting the samples of the block in bdir which aren't extracted.
+func extractTombstones(ctx context.Context, logger log.Logger, bdir string, matchers [][]*labels.Matcher, minTime, maxTime int64) (_ *tombstones.MemTombstones, err error) {
+	b, err := tsdb.OpenBlock(util_log.SlogFromGoKit(logger), bdir, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "extract series block reader")
+
+	indexr, err := b.Index()
+	if err != nil {
+		return nil, errors.Wrap(err, "open index")
+	}
+	// The block waits for its index reader to be closed, so it's closed first.
+	defer runutil.CloseWithErrCapture(&err, indexr, "extract series index reader")
+
+	name, value := index.AllPostingsKey()
+	postings, err := indexr.Postings(ctx, name, value)
+	if err != nil {
+		return nil, errors.Wrap(err, "get postings")
+	}
+
+	var (
+		result  = tombstones.NewMemTombstones()
+		builder labels.ScratchBuilder
+	)
+	for postings.Next() {
+		if err := indexr.Series(postings.At(), &builder, nil); err != nil {
+			return nil, errors.Wrap(err, "read series")
+		}
+
+		if !matchesAny(builder.Labels(), matchers) {
+			result.AddInterval(postings.At(), tombstones.Interval{Mint: math.MinInt64, Maxt: math.MaxInt64})
+			continue
+		}
+		if minTime > math.MinInt64 {
+			result.AddInterval(postings.At(), tombstones.Interval{Mint: math.MinInt64, Maxt: minTime - 1})
+		}
+		if maxTime < math.MaxInt64 {
+			result.AddInterval(postings.At(), tombstones.Interval{Mint: maxTime + 1, Maxt: math.MaxInt64})
+		}
+	}
+	if postings.Err() != nil {
+		return nil, errors.Wrap(postings.Err(), "iterate postings")
+	}
+	return result, nil
+}
+
+// matchesAny returns whether the labels match all the matchers of any item of matchers, or true if there's no item.
+func matchesAny(lset labels.Labels, matchers [][]*labels.Matcher) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+
+	for _, ms := range matchers {
+		matches := true
+		for _, m := range ms {
+			if !m.Matches(lset.Get(m.Name)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractSeries(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	var (
+		samples  []chunks.Sample
+		expected []downsampledSample
+	)
+	for ts := int64(0); ts < 10; ts++ {
+		samples = append(samples, downsampledSample{t: ts, f: float64(ts)})
+		expected = append(expected, downsampledSample{t: ts, f: float64(ts)})
+	}
+	chk, err := chunks.ChunkFromSamples(samples)
+	require.NoError(t, err)
+
+	meta, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a", "job", "x"), Chunks: []chunks.Meta{chk}},
+		{Labels: labels.FromStrings(labels.MetricName, "b", "job", "x"), Chunks: []chunks.Meta{chk}},
+		{Labels: labels.FromStrings(labels.MetricName, "b", "job", "y"), Chunks: []chunks.Meta{chk}},
+		{Labels: labels.FromStrings(labels.MetricName, "c", "job", "y"), Chunks: []chunks.Meta{chk}},
+	})
+	require.NoError(t, err)
+	meta.Thanos.Labels = map[string]string{"key": "value"}
+	bdir := filepath.Join(dir, meta.ULID.String())
+
+	id, err := ExtractSeries(ctx, log.NewNopLogger(), meta, bdir, dir, [][]*labels.Matcher{
+		{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a")},
+		{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "b"), labels.MustNewMatcher(labels.MatchEqual, "job", "y")},
+	}, 2, 7)
+	require.NoError(t, err)
+	require.NotEqual(t, ulid.ULID{}, id)
+
+	newMeta, err := ReadMetaFromDir(filepath.Join(dir, id.String()))
+	require.NoError(t, err)
+	assert.Equal(t, meta.MinTime, newMeta.MinTime)
+	assert.Equal(t, meta.MaxTime, newMeta.MaxTime)
+	assert.Equal(t, meta.Compaction, newMeta.Compaction)
+	assert.Equal(t, meta.Thanos.Labels, newMeta.Thanos.Labels)
+	assert.Equal(t, CompactorMigrateSource, newMeta.Thanos.Source)
+	assert.Equal(t, uint64(2), newMeta.Stats.NumSeries)
+	assert.Equal(t, uint64(12), newMeta.Stats.NumSamples)
+	require.NoError(t, VerifyBlock(ctx, log.NewNopLogger(), filepath.Join(dir, id.String()), newMeta.MinTime, newMeta.MaxTime, false))
+
+	extracted := []downsampledSample{{t: 2, f: 2}, {t: 3, f: 3}, {t: 4, f: 4}, {t: 5, f: 5}, {t: 6, f: 6}, {t: 7, f: 7}}
+	assert.Equal(t, map[string][]downsampledSample{
+		`{__name__="a", job="x"}`: extracted,
+		`{__name__="b", job="y"}`: extracted,
+	}, readDownsampledBlock(t, filepath.Join(dir, id.String())))
+
+	// All the series are extracted when there's no matcher, and the tombstones of the previous extraction are replaced.
+	id, err = ExtractSeries(ctx, log.NewNopLogger(), meta, bdir, dir, nil, math.MinInt64, math.MaxInt64)
+	require.NoError(t, err)
+	require.NotEqual(t, ulid.ULID{}, id)
+	assert.Len(t, readDownsampledBlock(t, filepath.Join(dir, id.String())), 4)
+	assert.Equal(t, expected, readDownsampledBlock(t, filepath.Join(dir, id.String()))[`{__name__="c", job="y"}`])
+
+	// No block is created when no sample is extracted.
+	id, err = ExtractSeries(ctx, log.NewNopLogger(), meta, bdir, dir, [][]*labels.Matcher{
+		{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "d")},
+	}, math.MinInt64, math.MaxInt64)
+	require.NoError(t, err)
+	assert.Equal(t, ulid.ULID{}, id)
+}
@@ -26,13 +26,17 @@ import (
 type SourceType string
 
 const (
-	ReceiveSource         SourceType = "receive"
-	CompactorSource       SourceType = "compactor"
-	CompactorRepairSource SourceType = "compactor.repair"
-	BucketRepairSource    SourceType = "bucket.repair"
-	BlockBuilderSource    SourceType = "block-builder"
-	SplitBlocksSource     SourceType = "split-blocks"
-	TestSource            SourceType = "test"
+	ReceiveSource             SourceType = "receive"
+	CompactorSource           SourceType = "compactor"
+	CompactorRepairSource     SourceType = "compactor.repair"
+	CompactorDownsampleSource SourceType = "compactor.downsample"
+	CompactorDeleteSource     SourceType = "compactor.delete"
+	CompactorRewriteSource    SourceType = "compactor.rewrite"
+	CompactorMigrateSource    SourceType = "compactor.migrate"
+	BucketRepairSource        SourceType = "bucket.repair"
+	BlockBuilderSource        SourceType = "block-builder"
+	SplitBlocksSource         SourceType = "split-blocks"
+	TestSource                SourceType = "test"
 )
 
 const (
@@ -84,6 +88,18 @@ type ThanosMeta struct {
 	// Useful to avoid API call to get size of each file, as well as for debugging purposes.
 	// Optional, added in v0.17.0.
 	Files []File `json:"files,omitempty"`
+
+	// SeriesDeletionRequests is a sorted list of the IDs of the series deletion requests applied to the block.
+	// Optional.
+	SeriesDeletionRequests []string `json:"series_deletion_requests,omitempty"`
+
+	// SeriesRetentionRules is a sorted list of the series selectors of the series retention rules applied to the block.
+	// Optional.
+	SeriesRetentionRules []string `json:"series_retention_rules,omitempty"`
+
+	// BlockRewriteRequests is a sorted list of the IDs of the block rewrite requests applied to the block.
+	// Optional.
+	BlockRewriteRequests []string `json:"block_rewrite_requests,omitempty"`
 }
 
 type Matchers []*labels.Matcher
@@ -0,0 +1,386 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"cmp"
+	"context"
+	crypto_rand "crypto/rand"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// ErrSeriesCollision is returned by Relabel when different series end up with the same labels once relabeled, and
+// can't be summed.
+var ErrSeriesCollision = errors.New("different series have the same labels once relabeled")
+
+// Relabel creates new blocks in dir with the series of the blocks in bdirs relabeled with the relabel configs,
+// and returns their IDs. The metas are the metas of the input blocks, which must all have the same time range
+// and resolution. Series dropped by the relabel configs, or whose labels are all removed, aren't written. The
+// same series stored in several input blocks is merged into a single series. If different series end up with the
+// same labels, their samples with the same timestamp are summed if sumCollisions is true, and an error wrapping
+// ErrSeriesCollision is returned otherwise, since their samples can't be merged without being mixed up.
+//
+// One block is written per item of shardLabels, with these external labels. As with split compaction, each series
+// is written to the block with index labels.StableHash(lset) % len(shardLabels). A zero ID is returned for the
+// blocks without series, which aren't created. The new blocks have the compaction sources of all the input blocks,
+// and the resolution of the input blocks.
+//
+// The new blocks are written one at a time, and only the relabeled labels of the series of the block being written
+// are kept in memory, to write its series in order: the series of the input blocks are relabeled again for each
+// new block.
+func Relabel(ctx context.Context, logger log.Logger, metas []*Meta, bdirs []string, dir string, relabelConfigs []*relabel.Config, sumCollisions bool, shardLabels []map[string]string) (ids []ulid.ULID, err error) {
+	if len(metas) == 0 || len(metas) != len(bdirs) {
+		return nil, errors.Errorf("invalid number of blocks: %d metas and %d directories", len(metas), len(bdirs))
+	}
+	if len(shardLabels) == 0 {
+		return nil, errors.New("no shard labels")
+	}
+
+	r := &relabeler{sumCollisions: sumCollisions}
+	defer func() {
+		// Blocks wait for their index and chunk readers to be closed, so they're closed last.
+		for i := len(r.closers) - 1; i >= 0; i-- {
+			runutil.CloseWithErrCapture(&err, r.closers[i], "relabel block reader")
+		}
+	}()
+
+	for _, bdir := range bdirs {
+		if err := r.open(bdir, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	blockMetas := make([]*tsdb.BlockMeta, 0, len(metas))
+	for _, meta := range metas {
+		blockMetas = append(blockMetas, &meta.BlockMeta)
+	}
+
+	// All the new blocks share the same timestamp in their ULID, like the blocks created by split compaction.
+	now := ulid.Now()
+	ids = make([]ulid.ULID, len(shardLabels))
+	for ix, lbls := range shardLabels {
+		shardSeries, err := r.relabelSeries(ctx, relabelConfigs, func(lset labels.Labels) bool {
+			return labels.StableHash(lset)%uint64(len(shardLabels)) == uint64(ix)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(shardSeries) == 0 {
+			continue
+		}
+
+		id := ulid.MustNew(now, crypto_rand.Reader)
+		resdir := filepath.Join(dir, id.String())
+
+		resmeta := Meta{
+			BlockMeta: *tsdb.CompactBlockMetas(id, blockMetas...),
+			Thanos: ThanosMeta{
+				Version:    ThanosVersion1,
+				Labels:     maps.Clone(lbls),
+				Downsample: metas[0].Thanos.Downsample,
+				Source:     CompactorRewriteSource,
+			},
+		}
+		resmeta.Version = TSDBVersion1
+
+		if err := r.writeBlock(ctx, resdir, shardSeries, &resmeta.Stats); err != nil {
+			return nil, errors.Wrapf(err, "write relabeled block %d of %d", ix+1, len(shardLabels))
+		}
+
+		resmeta.Thanos.SegmentFiles = GetSegmentFiles(resdir)
+		if err := resmeta.WriteToDir(logger, resdir); err != nil {
+			return nil, err
+		}
+		ids[ix] = id
+	}
+	return ids, nil
+}
+
+// relabeledSeries is a series of an input block, with its relabeled labels.
+type relabeledSeries struct {
+	lset  labels.Labels
+	block int
+	ref   storage.SeriesRef
+}
+
+type relabeler struct {
+	sumCollisions bool
+
+	indexrs []tsdb.IndexReader
+	chunkrs []tsdb.ChunkReader
+	closers []interface{ Close() error }
+}
+
+func (r *relabeler) open(bdir string, logger log.Logger) error {
+	b, err := tsdb.OpenBlock(util_log.SlogFromGoKit(logger), bdir, nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "open block %s", bdir)
+	}
+	r.closers = append(r.closers, b)
+
+	indexr, err := b.Index()
+	if err != nil {
+		return errors.Wrapf(err, "open index of block %s", bdir)
+	}
+	r.closers = append(r.closers, indexr)
+
+	chunkr, err := b.Chunks()
+	if err != nil {
+		return errors.Wrapf(err, "open chunks of block %s", bdir)
+	}
+	r.closers = append(r.closers, chunkr)
+
+	r.indexrs = append(r.indexrs, indexr)
+	r.chunkrs = append(r.chunkrs, chunkr)
+	return nil
+}
+
+// relabelSeries returns the relabeled series of all the input blocks whose relabeled labels match the filter, sorted
+// by their relabeled labels.
+func (r *relabeler) relabelSeries(ctx context.Context, relabelConfigs []*relabel.Config, filter func(labels.Labels) bool) ([]relabeledSeries, error) {
+	var (
+		result  []relabeledSeries
+		builder labels.ScratchBuilder
+	)
+	for ix, indexr := range r.indexrs {
+		name, value := index.AllPostingsKey()
+		postings, err := indexr.Postings(ctx, name, value)
+		if err != nil {
+			return nil, errors.Wrap(err, "get postings")
+		}
+
+		for postings.Next() {
+			if err := indexr.Series(postings.At(), &builder, nil); err != nil {
+				return nil, errors.Wrap(err, "read series")
+			}
+
+			lset, keep := relabel.Process(builder.Labels(), relabelConfigs...)
+			if !keep || lset.IsEmpty() || !filter(lset) {
+				continue
+			}
+			result = append(result, relabeledSeries{lset: lset, block: ix, ref: postings.At()})
+		}
+		if postings.Err() != nil {
+			return nil, errors.Wrap(postings.Err(), "iterate postings")
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b relabeledSeries) int { return labels.Compare(a.lset, b.lset) })
+	return result, nil
+}
+
+// writeBlock writes the series, sorted by labels, to a new block in resdir.
+func (r *relabeler) writeBlock(ctx context.Context, resdir string, series []relabeledSeries, stats *tsdb.BlockStats) (err error) {
+	chunkw, err := chunks.NewWriter(filepath.Join(resdir, ChunksDirname))
+	if err != nil {
+		return errors.Wrap(err, "open chunk writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkw, "relabel chunk writer")
+
+	indexw, err := index.NewWriter(ctx, filepath.Join(resdir, IndexFilename))
+	if err != nil {
+		return errors.Wrap(err, "open index writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexw, "relabel index writer")
+
+	symbols := map[string]struct{}{}
+	for _, s := range series {
+		s.lset.Range(func(l labels.Label) {
+			symbols[l.Name] = struct{}{}
+			symbols[l.Value] = struct{}{}
+		})
+	}
+	for _, s := range slices.Sorted(maps.Keys(symbols)) {
+		if err := indexw.AddSymbol(s); err != nil {
+			return errors.Wrap(err, "add symbol")
+		}
+	}
+
+	merge := storage.NewCompactingChunkSeriesMerger(storage.ChainedSeriesMerge)
+	ref := storage.SeriesRef(0)
+	for len(series) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Series with the same relabeled labels are merged when they're the same series stored in different input
+		// blocks, and summed when they're different series.
+		n := 1
+		for n < len(series) && labels.Equal(series[0].lset, series[n].lset) {
+			n++
+		}
+
+		var (
+			originals []labels.Labels
+			toMerge   [][]storage.ChunkSeries
+			indexes   = map[string]int{}
+		)
+		for _, s := range series[:n] {
+			lset, chks, err := r.readSeries(s)
+			if err != nil {
+				return err
+			}
+			ix, ok := indexes[lset.String()]
+			if !ok {
+				if len(originals) > 0 && !r.sumCollisions {
+					return errors.Wrapf(ErrSeriesCollision, "series %s and %s are both relabeled to %s", originals[0], lset, s.lset)
+				}
+				ix = len(originals)
+				indexes[lset.String()] = ix
+				originals = append(originals, lset)
+				toMerge = append(toMerge, nil)
+			}
+			toMerge[ix] = append(toMerge[ix], &storage.ChunkSeriesEntry{
+				Lset:            s.lset,
+				ChunkIteratorFn: func(chunks.Iterator) chunks.Iterator { return storage.NewListChunkSeriesIterator(chks...) },
+			})
+		}
+
+		merged := make([][]chunks.Meta, 0, len(toMerge))
+		for _, m := range toMerge {
+			var chks []chunks.Meta
+			it := merge(m...).Iterator(nil)
+			for it.Next() {
+				chks = append(chks, it.At())
+			}
+			if it.Err() != nil {
+				return errors.Wrap(it.Err(), "merge chunks")
+			}
+			merged = append(merged, chks)
+		}
+
+		chks := merged[0]
+		if len(merged) > 1 {
+			var err error
+			if chks, err = sumChunks(series[0].lset, merged); err != nil {
+				return err
+			}
+		}
+
+		if len(chks) > 0 {
+			if err := chunkw.WriteChunks(chks...); err != nil {
+				return errors.Wrap(err, "write chunks")
+			}
+			if err := indexw.AddSeries(ref, series[0].lset, chks...); err != nil {
+				return errors.Wrap(err, "add series")
+			}
+			ref++
+
+			stats.NumSeries++
+			stats.NumChunks += uint64(len(chks))
+			for _, chk := range chks {
+				stats.NumSamples += uint64(chk.Chunk.NumSamples())
+			}
+		}
+		series = series[n:]
+	}
+	return nil
+}
+
+// sumChunks returns the chunks of the series with the sum of the samples with the same timestamp of the series with
+// the chunks. Stale markers are skipped. An error wrapping ErrSeriesCollision is returned if the series have both
+// float and histogram samples with the same timestamp, or histograms which can't be summed.
+func sumChunks(lset labels.Labels, series [][]chunks.Meta) ([]chunks.Meta, error) {
+	var (
+		floats     = map[int64]float64{}
+		histograms = map[int64]*histogram.FloatHistogram{}
+		it         chunkenc.Iterator
+	)
+	for _, chks := range series {
+		for _, chk := range chks {
+			it = chk.Chunk.Iterator(it)
+			for typ := it.Next(); typ != chunkenc.ValNone; typ = it.Next() {
+				if typ == chunkenc.ValFloat {
+					t, f := it.At()
+					if !value.IsStaleNaN(f) {
+						floats[t] += f
+					}
+					continue
+				}
+
+				t, fh := it.AtFloatHistogram(nil)
+				if value.IsStaleNaN(fh.Sum) {
+					continue
+				}
+				sum, ok := histograms[t]
+				if !ok {
+					histograms[t] = fh
+					continue
+				}
+				if _, err := sum.Add(fh); err != nil {
+					return nil, errors.Wrapf(ErrSeriesCollision, "histograms of the series relabeled to %s can't be summed at %d: %v", lset, t, err)
+				}
+			}
+			if it.Err() != nil {
+				return nil, errors.Wrap(it.Err(), "iterate chunk")
+			}
+		}
+	}
+
+	samples := make([]chunks.Sample, 0, len(floats)+len(histograms))
+	for t, f := range floats {
+		if _, ok := histograms[t]; ok {
+			return nil, errors.Wrapf(ErrSeriesCollision, "series relabeled to %s have both float and histogram samples at %d", lset, t)
+		}
+		samples = append(samples, downsampledSample{t: t, f: f})
+	}
+	for t, fh := range histograms {
+		// The counter resets of the summed histograms aren't counter resets of their sum.
+		if fh.CounterResetHint != histogram.GaugeType {
+			fh = counterHistogram(fh)
+		}
+		samples = append(samples, downsampledSample{t: t, fh: fh})
+	}
+	slices.SortFunc(samples, func(a, b chunks.Sample) int { return cmp.Compare(a.T(), b.T()) })
+
+	var chks []chunks.Meta
+	encoded := storage.NewSeriesToChunkEncoder(storage.NewListSeries(lset, samples)).Iterator(nil)
+	for encoded.Next() {
+		chks = append(chks, encoded.At())
+	}
+	if encoded.Err() != nil {
+		return nil, errors.Wrap(encoded.Err(), "encode chunks")
+	}
+	return chks, nil
+}
+
+// readSeries returns the original labels and the chunks of the series in its input block.
+func (r *relabeler) readSeries(s relabeledSeries) (labels.Labels, []chunks.Meta, error) {
+	var (
+		builder labels.ScratchBuilder
+		chks    []chunks.Meta
+	)
+	if err := r.indexrs[s.block].Series(s.ref, &builder, &chks); err != nil {
+		return labels.EmptyLabels(), nil, errors.Wrap(err, "read series")
+	}
+
+	for i := range chks {
+		chk, iter, err := r.chunkrs[s.block].ChunkOrIterable(chks[i])
+		if err != nil {
+			return labels.EmptyLabels(), nil, errors.Wrap(err, "chunk read")
+		}
+		if iter != nil {
+			return labels.EmptyLabels(), nil, errors.New("unexpected chunk iterable returned")
+		}
+		chks[i].Chunk = chk
+	}
+	return builder.Labels(), chks, nil
+}
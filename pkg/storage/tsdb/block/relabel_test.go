@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelabel(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	chunk := func(from, to int64) chunks.Meta {
+		var samples []chunks.Sample
+		for ts := from; ts < to; ts++ {
+			samples = append(samples, downsampledSample{t: ts, f: float64(ts)})
+		}
+		chk, err := chunks.ChunkFromSamples(samples)
+		require.NoError(t, err)
+		return chk
+	}
+
+	meta1, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "1"), Chunks: []chunks.Meta{chunk(0, 5)}},
+		{Labels: labels.FromStrings(labels.MetricName, "b", "pod", "1"), Chunks: []chunks.Meta{chunk(0, 10)}},
+	})
+	require.NoError(t, err)
+	meta2, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "1"), Chunks: []chunks.Meta{chunk(5, 10)}},
+		{Labels: labels.FromStrings(labels.MetricName, "c", "pod", "2"), Chunks: []chunks.Meta{chunk(0, 10)}},
+	})
+	require.NoError(t, err)
+	meta2.MinTime, meta2.MaxTime = meta1.MinTime, meta1.MaxTime
+
+	relabelConfigs := []*relabel.Config{
+		{SourceLabels: model.LabelNames{labels.MetricName}, Regex: relabel.MustNewRegexp("b"), Action: relabel.Drop},
+		{Regex: relabel.MustNewRegexp("pod"), Action: relabel.LabelDrop},
+	}
+	shardLabels := []map[string]string{{"shard": "1_of_2"}, {"shard": "2_of_2"}}
+
+	ids, err := Relabel(ctx, log.NewNopLogger(), []*Meta{meta1, meta2}, []string{filepath.Join(dir, meta1.ULID.String()), filepath.Join(dir, meta2.ULID.String())}, dir, relabelConfigs, false, shardLabels)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+
+	var expected []downsampledSample
+	for ts := int64(0); ts < 10; ts++ {
+		expected = append(expected, downsampledSample{t: ts, f: float64(ts)})
+	}
+	expectedLabels := map[string]labels.Labels{
+		`{__name__="a"}`: labels.FromStrings(labels.MetricName, "a"),
+		`{__name__="c"}`: labels.FromStrings(labels.MetricName, "c"),
+	}
+	series := map[string][]downsampledSample{}
+	for ix, id := range ids {
+		if id == (ulid.ULID{}) {
+			continue
+		}
+		resdir := filepath.Join(dir, id.String())
+
+		newMeta, err := ReadMetaFromDir(resdir)
+		require.NoError(t, err)
+		assert.Equal(t, meta1.MinTime, newMeta.MinTime)
+		assert.Equal(t, meta1.MaxTime, newMeta.MaxTime)
+		assert.ElementsMatch(t, []ulid.ULID{meta1.ULID, meta2.ULID}, newMeta.Compaction.Sources)
+		assert.Equal(t, shardLabels[ix], newMeta.Thanos.Labels)
+		assert.Equal(t, CompactorRewriteSource, newMeta.Thanos.Source)
+		require.NoError(t, VerifyBlock(ctx, log.NewNopLogger(), resdir, newMeta.MinTime, newMeta.MaxTime, false))
+
+		for lset, samples := range readDownsampledBlock(t, resdir) {
+			assert.Equal(t, uint64(ix), labels.StableHash(expectedLabels[lset])%2, lset)
+			series[lset] = samples
+		}
+	}
+
+	// The same series stored in both blocks is merged.
+	assert.Equal(t, map[string][]downsampledSample{
+		`{__name__="a"}`: expected,
+		`{__name__="c"}`: expected,
+	}, series)
+}
+
+func TestRelabel_SeriesCollision(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	chk, err := chunks.ChunkFromSamples([]chunks.Sample{downsampledSample{t: 0, f: 1}})
+	require.NoError(t, err)
+
+	meta, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "1"), Chunks: []chunks.Meta{chk}},
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "2"), Chunks: []chunks.Meta{chk}},
+	})
+	require.NoError(t, err)
+
+	// Dropping the pod label gives the same labels to different series.
+	relabelConfigs := []*relabel.Config{{Regex: relabel.MustNewRegexp("pod"), Action: relabel.LabelDrop}}
+
+	_, err = Relabel(ctx, log.NewNopLogger(), []*Meta{meta}, []string{filepath.Join(dir, meta.ULID.String())}, dir, relabelConfigs, false, []map[string]string{{}})
+	require.ErrorIs(t, err, ErrSeriesCollision)
+}
+
+func TestRelabel_SumCollisions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	chunk := func(samples ...downsampledSample) chunks.Meta {
+		s := make([]chunks.Sample, 0, len(samples))
+		for _, sample := range samples {
+			s = append(s, sample)
+		}
+		chk, err := chunks.ChunkFromSamples(s)
+		require.NoError(t, err)
+		return chk
+	}
+	floatHistogram := func(count float64) *histogram.FloatHistogram {
+		return &histogram.FloatHistogram{Count: count, ZeroCount: count, Sum: count}
+	}
+
+	pod1 := chunk(downsampledSample{t: 0, f: 1}, downsampledSample{t: 10, f: 2}, downsampledSample{t: 20, f: 3})
+	meta1, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "1"), Chunks: []chunks.Meta{pod1}},
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "2"), Chunks: []chunks.Meta{chunk(downsampledSample{t: 0, f: 10}, downsampledSample{t: 15, f: 20}, downsampledSample{t: 20, f: 30})}},
+		{Labels: labels.FromStrings(labels.MetricName, "h", "pod", "1"), Chunks: []chunks.Meta{chunk(downsampledSample{t: 0, fh: floatHistogram(1)}, downsampledSample{t: 10, fh: floatHistogram(2)})}},
+		{Labels: labels.FromStrings(labels.MetricName, "h", "pod", "2"), Chunks: []chunks.Meta{chunk(downsampledSample{t: 0, fh: floatHistogram(3)}, downsampledSample{t: 10, fh: floatHistogram(4)})}},
+	})
+	require.NoError(t, err)
+	// The same series stored in another block is merged before being summed with the other series.
+	meta2, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "1"), Chunks: []chunks.Meta{pod1}},
+	})
+	require.NoError(t, err)
+	meta2.MinTime, meta2.MaxTime = meta1.MinTime, meta1.MaxTime
+
+	relabelConfigs := []*relabel.Config{{Regex: relabel.MustNewRegexp("pod"), Action: relabel.LabelDrop}}
+
+	ids, err := Relabel(ctx, log.NewNopLogger(), []*Meta{meta1, meta2}, []string{filepath.Join(dir, meta1.ULID.String()), filepath.Join(dir, meta2.ULID.String())}, dir, relabelConfigs, true, []map[string]string{{}})
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	resdir := filepath.Join(dir, ids[0].String())
+	newMeta, err := ReadMetaFromDir(resdir)
+	require.NoError(t, err)
+	require.NoError(t, VerifyBlock(ctx, log.NewNopLogger(), resdir, newMeta.MinTime, newMeta.MaxTime, false))
+
+	// The samples with the same timestamp are summed, and the histograms are read back as their count.
+	assert.Equal(t, map[string][]downsampledSample{
+		`{__name__="a"}`: {{t: 0, f: 11}, {t: 10, f: 2}, {t: 15, f: 20}, {t: 20, f: 33}},
+		`{__name__="h"}`: {{t: 0, f: 4}, {t: 10, f: 6}},
+	}, readDownsampledBlock(t, resdir))
+}
+
+func TestRelabel_SumCollisions_FloatAndHistogram(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	floatChunk, err := chunks.ChunkFromSamples([]chunks.Sample{downsampledSample{t: 0, f: 1}})
+	require.NoError(t, err)
+	histogramChunk, err := chunks.ChunkFromSamples([]chunks.Sample{downsampledSample{t: 0, fh: &histogram.FloatHistogram{Count: 1, ZeroCount: 1, Sum: 1}}})
+	require.NoError(t, err)
+
+	meta, err := GenerateBlockFromSpec(dir, SeriesSpecs{
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "1"), Chunks: []chunks.Meta{floatChunk}},
+		{Labels: labels.FromStrings(labels.MetricName, "a", "pod", "2"), Chunks: []chunks.Meta{histogramChunk}},
+	})
+	require.NoError(t, err)
+
+	// A float sample and a histogram sample with the same timestamp can't be summed.
+	relabelConfigs := []*relabel.Config{{Regex: relabel.MustNewRegexp("pod"), Action: relabel.LabelDrop}}
+
+	_, err = Relabel(ctx, log.NewNopLogger(), []*Meta{meta}, []string{filepath.Join(dir, meta.ULID.String())}, dir, relabelConfigs, true, []map[string]string{{}})
+	require.ErrorIs(t, err, ErrSeriesCollision)
+}
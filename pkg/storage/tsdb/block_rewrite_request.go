@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type BlockRewriteRequestStatus string
+
+const (
+	// BlockRewriteRequestPending is the status of a request which may not have been applied to some blocks yet.
+	BlockRewriteRequestPending BlockRewriteRequestStatus = "pending"
+	// BlockRewriteRequestProcessed is the status of a request which has been applied to all blocks.
+	BlockRewriteRequestProcessed BlockRewriteRequestStatus = "processed"
+	// BlockRewriteRequestFailed is the status of a request which can't be applied to some blocks.
+	BlockRewriteRequestFailed BlockRewriteRequestStatus = "failed"
+)
+
+type BlockRewriteAggregation string
+
+const (
+	// BlockRewriteAggregationNone fails the request when its relabel configs give the same labels to different series.
+	BlockRewriteAggregationNone BlockRewriteAggregation = ""
+	// BlockRewriteAggregationSum sums the samples with the same timestamp of the different series given the same
+	// labels by the relabel configs of the request.
+	BlockRewriteAggregationSum BlockRewriteAggregation = "sum"
+)
+
+// BlockRewriteRequest is a request to rewrite the series of the blocks overlapping a time range, with their
+// labels relabeled by relabel configs.
+type BlockRewriteRequest struct {
+	RequestID string `json:"request_id"`
+
+	// Relabel configs applied to the labels of each series.
+	RelabelConfigs []*relabel.Config `json:"relabel_configs"`
+
+	// Aggregation of the different series given the same labels by the relabel configs.
+	Aggregation BlockRewriteAggregation `json:"aggregation,omitempty"`
+
+	// StartTime and EndTime specify the time range of the rewritten blocks (millis precision, both inclusive).
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time"`
+
+	Status BlockRewriteRequestStatus `json:"status"`
+
+	// Unix timestamp when the request was created.
+	CreatedTime util.UnixSeconds `json:"created_time"`
+
+	// Unix timestamp when the request was processed, or failed.
+	ProcessedTime util.UnixSeconds `json:"processed_time,omitempty"`
+
+	// Reason why the request failed.
+	Error string `json:"error,omitempty"`
+}
+
+// NewBlockRewriteRequest returns a pending BlockRewriteRequest, or an error if the relabel configs, aggregation or
+// time range are invalid.
+func NewBlockRewriteRequest(relabelConfigs []*relabel.Config, aggregation BlockRewriteAggregation, startTime, endTime int64, createdTime time.Time) (*BlockRewriteRequest, error) {
+	req := &BlockRewriteRequest{
+		RequestID:      ulid.MustNew(ulid.Timestamp(createdTime), rand.Reader).String(),
+		RelabelConfigs: relabelConfigs,
+		Aggregation:    aggregation,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Status:         BlockRewriteRequestPending,
+		CreatedTime:    util.UnixSecondsFromTime(createdTime),
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Validate returns an error if the relabel configs, aggregation or time range of the request are invalid.
+func (r *BlockRewriteRequest) Validate() error {
+	if len(r.RelabelConfigs) == 0 {
+		return errors.New("no relabel config specified")
+	}
+	for i, cfg := range r.RelabelConfigs {
+		if cfg == nil {
+			return errors.Errorf("relabel config %d is empty", i)
+		}
+		if err := cfg.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid relabel config %d", i)
+		}
+	}
+	if r.Aggregation != BlockRewriteAggregationNone && r.Aggregation != BlockRewriteAggregationSum {
+		return errors.Errorf("unsupported aggregation %q", r.Aggregation)
+	}
+	if r.EndTime < r.StartTime {
+		return errors.New("end time must not be before start time")
+	}
+	return nil
+}
+
+// WriteBlockRewriteRequest uploads the block rewrite request to the tenant location in the bucket.
+func WriteBlockRewriteRequest(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, req *BlockRewriteRequest) error {
+	return blockRewriteRequests.write(ctx, bkt, userID, cfgProvider, req.RequestID, req)
+}
+
+// ReadBlockRewriteRequests returns the block rewrite requests of the tenant, sorted by creation time.
+func ReadBlockRewriteRequests(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) ([]*BlockRewriteRequest, error) {
+	return blockRewriteRequests.read(ctx, bkt, userID, cfgProvider, logger)
+}
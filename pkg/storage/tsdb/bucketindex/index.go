@@ -99,6 +99,9 @@ type Block struct {
 	// Whether the block was from out of order samples
 	OutOfOrder bool `json:"out_of_order,omitempty"`
 
+	// Block's downsampling resolution, 0 for raw blocks.
+	Resolution int64 `json:"resolution,omitempty"`
+
 	// Labels contains the external labels from the block's metadata.
 	Labels map[string]string `json:"labels,omitempty"`
 }
@@ -147,6 +150,7 @@ func (m *Block) ThanosMeta() *block.Meta {
 			SegmentFiles: m.thanosMetaSegmentFiles(),
 			Source:       block.SourceType(m.Source),
 			Labels:       maps.Clone(m.Labels),
+			Downsample:   block.ThanosDownsample{Resolution: m.Resolution},
 		},
 	}
 }
@@ -186,6 +190,7 @@ func BlockFromThanosMeta(meta block.Meta) *Block {
 		Source:           string(meta.Thanos.Source),
 		CompactionLevel:  meta.Compaction.Level,
 		OutOfOrder:       meta.Compaction.FromOutOfOrder(),
+		Resolution:       meta.Thanos.Downsample.Resolution,
 		Labels:           maps.Clone(meta.Thanos.Labels),
 	}
 }
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+// Relative to user-specific prefix.
+const RequestsPath = "markers/requests"
+
+// requestStore stores the requests of a kind, such as the series deletion requests, in the bucket: each request is
+// a JSON object named after its ID, in the directory of the kind under RequestsPath. Request IDs are ULIDs, so
+// sorting requests by name sorts them by creation time.
+type requestStore[T any] struct {
+	// Directory of the requests under RequestsPath.
+	dir string
+
+	// Name of the kind of requests, used in error messages.
+	name string
+}
+
+func (s requestStore[T]) path(requestID string) string {
+	return path.Join(RequestsPath, s.dir, requestID+".json")
+}
+
+// write uploads the request to the tenant location in the bucket.
+func (s requestStore[T]) write(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, requestID string, req *T) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return errors.Wrapf(err, "serialize %s", s.name)
+	}
+
+	return errors.Wrapf(bkt.Upload(ctx, s.path(requestID), bytes.NewReader(data)), "upload %s", s.name)
+}
+
+// read returns the requests of the tenant, sorted by creation time.
+func (s requestStore[T]) read(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) ([]*T, error) {
+	userBkt := bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	var names []string
+	err := userBkt.Iter(ctx, path.Join(RequestsPath, s.dir)+"/", func(name string) error {
+		if strings.HasSuffix(name, ".json") {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "list %ss", s.name)
+	}
+
+	return s.readObjects(ctx, userBkt, names, logger)
+}
+
+// readObjects returns the requests stored in the objects with the given names, sorted by creation time.
+func (s requestStore[T]) readObjects(ctx context.Context, bkt objstore.BucketReader, names []string, logger log.Logger) ([]*T, error) {
+	slices.Sort(names)
+
+	result := make([]*T, 0, len(names))
+	for _, name := range names {
+		req, err := s.readObject(ctx, bkt, name, logger)
+		if err != nil {
+			return nil, err
+		}
+		if req != nil {
+			result = append(result, req)
+		}
+	}
+	return result, nil
+}
+
+func (s requestStore[T]) readObject(ctx context.Context, bkt objstore.BucketReader, name string, logger log.Logger) (*T, error) {
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		// The request may have been deleted since it's been listed.
+		if bkt.IsObjNotFoundErr(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to read %s object: %s", s.name, name)
+	}
+
+	req := new(T)
+	err = json.NewDecoder(r).Decode(req)
+
+	// Close reader before dealing with decode error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s object: %s", s.name, name)
+	}
+
+	return req, nil
+}
+
+var (
+	seriesDeletionRequests  = requestStore[SeriesDeletionRequest]{dir: "series-deletion", name: "series deletion request"}
+	blockRewriteRequests    = requestStore[BlockRewriteRequest]{dir: "block-rewrite", name: "block rewrite request"}
+	tenantMigrationRequests = requestStore[TenantMigrationRequest]{dir: "tenant-migration", name: "tenant migration request"}
+)
+
+// TenantRequests are the requests of all kinds of a tenant, each sorted by creation time.
+type TenantRequests struct {
+	SeriesDeletions  []*SeriesDeletionRequest
+	BlockRewrites    []*BlockRewriteRequest
+	TenantMigrations []*TenantMigrationRequest
+}
+
+// ReadTenantRequests returns the requests of all kinds of the tenant, listing the bucket once.
+func ReadTenantRequests(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) (*TenantRequests, error) {
+	userBkt := bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	// Object names grouped by the directory of their kind.
+	names := map[string][]string{}
+	err := userBkt.Iter(ctx, RequestsPath+"/", func(name string) error {
+		if strings.HasSuffix(name, ".json") {
+			dir := path.Base(path.Dir(name))
+			names[dir] = append(names[dir], name)
+		}
+		return nil
+	}, objstore.WithRecursiveIter())
+	if err != nil {
+		return nil, errors.Wrap(err, "list requests")
+	}
+
+	result := &TenantRequests{}
+	if result.SeriesDeletions, err = seriesDeletionRequests.readObjects(ctx, userBkt, names[seriesDeletionRequests.dir], logger); err != nil {
+		return nil, err
+	}
+	if result.BlockRewrites, err = blockRewriteRequests.readObjects(ctx, userBkt, names[blockRewriteRequests.dir], logger); err != nil {
+		return nil, err
+	}
+	if result.TenantMigrations, err = tenantMigrationRequests.readObjects(ctx, userBkt, names[tenantMigrationRequests.dir], logger); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
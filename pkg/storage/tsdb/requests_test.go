@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestReadTenantRequests(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	now := time.Now()
+
+	// No request.
+	requests, err := ReadTenantRequests(ctx, bkt, "user-1", nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Empty(t, requests.SeriesDeletions)
+	require.Empty(t, requests.BlockRewrites)
+	require.Empty(t, requests.TenantMigrations)
+
+	deletion2, err := NewSeriesDeletionRequest([]string{`{job="b"}`}, 0, 100, now)
+	require.NoError(t, err)
+	deletion1, err := NewSeriesDeletionRequest([]string{`{job="a"}`}, 0, 100, now.Add(-time.Minute))
+	require.NoError(t, err)
+	rewrite := &BlockRewriteRequest{RequestID: "01EQK4QKFHVSZYVJ908Y7HH9E0", Status: BlockRewriteRequestPending}
+	migration, err := NewTenantMigrationRequest("user-2", nil, 0, 100, false, now)
+	require.NoError(t, err)
+
+	require.NoError(t, WriteSeriesDeletionRequest(ctx, bkt, "user-1", nil, deletion2))
+	require.NoError(t, WriteSeriesDeletionRequest(ctx, bkt, "user-1", nil, deletion1))
+	require.NoError(t, WriteBlockRewriteRequest(ctx, bkt, "user-1", nil, rewrite))
+	require.NoError(t, WriteTenantMigrationRequest(ctx, bkt, "user-1", nil, migration))
+
+	// The requests of another tenant are not read.
+	require.NoError(t, WriteSeriesDeletionRequest(ctx, bkt, "user-2", nil, deletion1))
+
+	requests, err = ReadTenantRequests(ctx, bkt, "user-1", nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, []*SeriesDeletionRequest{deletion1, deletion2}, requests.SeriesDeletions)
+	require.Equal(t, []*BlockRewriteRequest{rewrite}, requests.BlockRewrites)
+	require.Equal(t, []*TenantMigrationRequest{migration}, requests.TenantMigrations)
+
+	// The requests of each kind are the same as when read separately.
+	deletions, err := ReadSeriesDeletionRequests(ctx, bkt, "user-1", nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, requests.SeriesDeletions, deletions)
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type SeriesDeletionRequestStatus string
+
+const (
+	// SeriesDeletionRequestPending is the status of a request whose deleted data may still be in some blocks.
+	SeriesDeletionRequestPending SeriesDeletionRequestStatus = "pending"
+	// SeriesDeletionRequestProcessed is the status of a request whose deleted data has been purged from all blocks.
+	SeriesDeletionRequestProcessed SeriesDeletionRequestStatus = "processed"
+)
+
+// SeriesDeletionRequest is a request to delete the samples of the series matching any of the selectors,
+// within a time range.
+type SeriesDeletionRequest struct {
+	RequestID string `json:"request_id"`
+
+	// Series selectors, in the PromQL format.
+	Selectors []string `json:"selectors"`
+
+	// StartTime and EndTime specify the time range of the deleted samples (millis precision, both inclusive).
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time"`
+
+	Status SeriesDeletionRequestStatus `json:"status"`
+
+	// Unix timestamp when the request was created.
+	CreatedTime util.UnixSeconds `json:"created_time"`
+
+	// Unix timestamp when the request was processed.
+	ProcessedTime util.UnixSeconds `json:"processed_time,omitempty"`
+}
+
+// NewSeriesDeletionRequest returns a pending SeriesDeletionRequest, or an error if the selectors or time range are invalid.
+func NewSeriesDeletionRequest(selectors []string, startTime, endTime int64, createdTime time.Time) (*SeriesDeletionRequest, error) {
+	req := &SeriesDeletionRequest{
+		RequestID:   ulid.MustNew(ulid.Timestamp(createdTime), rand.Reader).String(),
+		Selectors:   selectors,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Status:      SeriesDeletionRequestPending,
+		CreatedTime: util.UnixSecondsFromTime(createdTime),
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Validate returns an error if the selectors or time range of the request are invalid.
+func (r *SeriesDeletionRequest) Validate() error {
+	if len(r.Selectors) == 0 {
+		return errors.New("no series selector specified")
+	}
+	if r.EndTime < r.StartTime {
+		return errors.New("end time must not be before start time")
+	}
+	_, err := r.Matchers()
+	return err
+}
+
+// Matchers returns the matchers of each selector of the request.
+func (r *SeriesDeletionRequest) Matchers() ([][]*labels.Matcher, error) {
+	result := make([][]*labels.Matcher, 0, len(r.Selectors))
+	for _, s := range r.Selectors {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid series selector %q", s)
+		}
+		result = append(result, matchers)
+	}
+	return result, nil
+}
+
+// WriteSeriesDeletionRequest uploads the series deletion request to the tenant location in the bucket.
+func WriteSeriesDeletionRequest(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, req *SeriesDeletionRequest) error {
+	return seriesDeletionRequests.write(ctx, bkt, userID, cfgProvider, req.RequestID, req)
+}
+
+// ReadSeriesDeletionRequests returns the series deletion requests of the tenant, sorted by creation time.
+func ReadSeriesDeletionRequests(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) ([]*SeriesDeletionRequest, error) {
+	return seriesDeletionRequests.read(ctx, bkt, userID, cfgProvider, logger)
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/tenant"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type TenantMigrationRequestStatus string
+
+const (
+	// TenantMigrationRequestPending is the status of a request whose blocks may not have been migrated yet.
+	TenantMigrationRequestPending TenantMigrationRequestStatus = "pending"
+	// TenantMigrationRequestProcessed is the status of a request whose blocks have all been migrated.
+	TenantMigrationRequestProcessed TenantMigrationRequestStatus = "processed"
+)
+
+// TenantMigrationRequest is a request to migrate the samples of the series matching any of the selectors, within
+// a time range, from the blocks of the tenant the request is stored for to the blocks of a destination tenant.
+type TenantMigrationRequest struct {
+	RequestID string `json:"request_id"`
+
+	DestinationTenantID string `json:"destination_tenant_id"`
+
+	// Series selectors, in the PromQL format. All the series are migrated if there's no selector.
+	Selectors []string `json:"selectors,omitempty"`
+
+	// StartTime and EndTime specify the time range of the migrated samples (millis precision, both inclusive).
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time"`
+
+	// DeleteSource specifies whether the migrated samples are deleted from the source tenant.
+	DeleteSource bool `json:"delete_source"`
+
+	Status TenantMigrationRequestStatus `json:"status"`
+
+	// Sorted IDs of the source blocks which have been migrated.
+	MigratedBlocks []string `json:"migrated_blocks,omitempty"`
+
+	// ID of the series deletion request created to delete the migrated samples from the source tenant,
+	// when these are only part of the samples of the source blocks.
+	SourceDeletionRequestID string `json:"source_deletion_request_id,omitempty"`
+
+	// Unix timestamp when the request was created.
+	CreatedTime util.UnixSeconds `json:"created_time"`
+
+	// Unix timestamp when the request was processed.
+	ProcessedTime util.UnixSeconds `json:"processed_time,omitempty"`
+}
+
+// NewTenantMigrationRequest returns a pending TenantMigrationRequest, or an error if the destination tenant,
+// selectors or time range are invalid.
+func NewTenantMigrationRequest(destinationTenantID string, selectors []string, startTime, endTime int64, deleteSource bool, createdTime time.Time) (*TenantMigrationRequest, error) {
+	req := &TenantMigrationRequest{
+		RequestID:           ulid.MustNew(ulid.Timestamp(createdTime), rand.Reader).String(),
+		DestinationTenantID: destinationTenantID,
+		Selectors:           selectors,
+		StartTime:           startTime,
+		EndTime:             endTime,
+		DeleteSource:        deleteSource,
+		Status:              TenantMigrationRequestPending,
+		CreatedTime:         util.UnixSecondsFromTime(createdTime),
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Validate returns an error if the destination tenant, selectors or time range of the request are invalid.
+func (r *TenantMigrationRequest) Validate() error {
+	if r.DestinationTenantID == "" {
+		return errors.New("no destination tenant specified")
+	}
+	if err := tenant.ValidTenantID(r.DestinationTenantID); err != nil {
+		return errors.Wrap(err, "invalid destination tenant")
+	}
+	if r.EndTime < r.StartTime {
+		return errors.New("end time must not be before start time")
+	}
+	_, err := r.Matchers()
+	return err
+}
+
+// Matchers returns the matchers of each selector of the request.
+func (r *TenantMigrationRequest) Matchers() ([][]*labels.Matcher, error) {
+	result := make([][]*labels.Matcher, 0, len(r.Selectors))
+	for _, s := range r.Selectors {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid series selector %q", s)
+		}
+		result = append(result, matchers)
+	}
+	return result, nil
+}
+
+// WriteTenantMigrationRequest uploads the tenant migration request to the tenant location in the bucket.
+func WriteTenantMigrationRequest(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, req *TenantMigrationRequest) error {
+	return tenantMigrationRequests.write(ctx, bkt, userID, cfgProvider, req.RequestID, req)
+}
+
+// ReadTenantMigrationRequests returns the tenant migration requests of the tenant, sorted by creation time.
+func ReadTenantMigrationRequests(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) ([]*TenantMigrationRequest, error) {
+	return tenantMigrationRequests.read(ctx, bkt, userID, cfgProvider, logger)
+}
@@ -714,13 +714,25 @@ func openBlockSeriesChunkRefsSetsIterator(
 		return nil, errors.New("set size must be a positive number")
 	}
 
+	// The series of downsampled blocks have a label for each aggregate, which is removed
+	// when a single aggregate is queried.
+	stripAggregateLabel := false
+	if blockMeta.Thanos.Downsample.Resolution > block.ResolutionRaw {
+		matchers, stripAggregateLabel = downsampledAggregateMatchers(matchers)
+		seriesHasher = aggregateLabelStrippingSeriesHasher{seriesHasher}
+	}
+
 	ps, pendingMatchers, err := indexr.ExpandedPostings(ctx, matchers, stats)
 	if err != nil {
 		return nil, errors.Wrap(err, "expanded matching postings")
 	}
 
 	iteratorFactory := func(strategy seriesIteratorStrategy, psi *postingsSetsIterator) iterator[seriesChunkRefsSet] {
-		return openBlockSeriesChunkRefsSetsIteratorFromPostings(ctx, tenantID, indexr, indexCache, blockMeta, shard, seriesHasher, strategy, minTime, maxTime, stats, psi, pendingMatchers, logger)
+		it := openBlockSeriesChunkRefsSetsIteratorFromPostings(ctx, tenantID, indexr, indexCache, blockMeta, shard, seriesHasher, strategy, minTime, maxTime, stats, psi, pendingMatchers, logger)
+		if stripAggregateLabel {
+			it = newAggregateLabelStrippingSeriesChunkRefsSetIterator(it)
+		}
+		return it
 	}
 
 	if streamingIterators == nil {
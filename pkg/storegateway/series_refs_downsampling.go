@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"slices"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+// downsampledAggregateMatchers returns the matchers to query the series of a downsampled block.
+// If no matcher selects an aggregate, the average is selected. If the matchers select a single
+// aggregate, the matchers of the aggregate label are replaced by an equality matcher and the returned
+// bool is true, meaning that the aggregate label must be removed from the series labels, so that they
+// are the same as the labels of the series of raw blocks.
+func downsampledAggregateMatchers(matchers []*labels.Matcher) ([]*labels.Matcher, bool) {
+	var aggregateMatchers []*labels.Matcher
+	for _, m := range matchers {
+		if m.Name == block.AggregateLabel {
+			aggregateMatchers = append(aggregateMatchers, m)
+		}
+	}
+
+	aggregate := block.AggregateAvg
+	if len(aggregateMatchers) > 0 {
+		var selected []string
+		for _, a := range block.Aggregates {
+			if !slices.ContainsFunc(aggregateMatchers, func(m *labels.Matcher) bool { return !m.Matches(a) }) {
+				selected = append(selected, a)
+			}
+		}
+		if len(selected) != 1 {
+			return matchers, false
+		}
+		aggregate = selected[0]
+	}
+
+	result := make([]*labels.Matcher, 0, len(matchers)-len(aggregateMatchers)+1)
+	result = append(result, labels.MustNewMatcher(labels.MatchEqual, block.AggregateLabel, aggregate))
+	for _, m := range matchers {
+		if m.Name != block.AggregateLabel {
+			result = append(result, m)
+		}
+	}
+	return result, true
+}
+
+// aggregateLabelStrippingSeriesHasher hashes the labels of the series of downsampled blocks without
+// the aggregate label, so that they're sharded like the series of raw blocks.
+type aggregateLabelStrippingSeriesHasher struct {
+	seriesHasher
+}
+
+func (h aggregateLabelStrippingSeriesHasher) Hash(id storage.SeriesRef, lset labels.Labels, stats *queryStats) uint64 {
+	return h.seriesHasher.Hash(id, labels.NewBuilder(lset).Del(block.AggregateLabel).Labels(), stats)
+}
+
+// aggregateLabelStrippingSeriesChunkRefsSetIterator removes the aggregate label from the series
+// of a downsampled block. The series are still sorted by labels, because the series of a single
+// aggregate are selected and the aggregate label sorts before any other label.
+type aggregateLabelStrippingSeriesChunkRefsSetIterator struct {
+	from iterator[seriesChunkRefsSet]
+}
+
+func newAggregateLabelStrippingSeriesChunkRefsSetIterator(from iterator[seriesChunkRefsSet]) *aggregateLabelStrippingSeriesChunkRefsSetIterator {
+	return &aggregateLabelStrippingSeriesChunkRefsSetIterator{from: from}
+}
+
+func (s *aggregateLabelStrippingSeriesChunkRefsSetIterator) Next() bool {
+	if !s.from.Next() {
+		return false
+	}
+
+	set := s.from.At()
+	builder := labels.NewBuilder(labels.EmptyLabels())
+	for i := range set.series {
+		builder.Reset(set.series[i].lset)
+		set.series[i].lset = builder.Del(block.AggregateLabel).Labels()
+	}
+	return true
+}
+
+func (s *aggregateLabelStrippingSeriesChunkRefsSetIterator) At() seriesChunkRefsSet {
+	return s.from.At()
+}
+
+func (s *aggregateLabelStrippingSeriesChunkRefsSetIterator) Err() error {
+	return s.from.Err()
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+func TestDownsampledAggregateMatchers(t *testing.T) {
+	nameMatcher := labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "metric")
+
+	tests := map[string]struct {
+		matchers              []*labels.Matcher
+		expectedMatchers      []*labels.Matcher
+		expectedStripAggLabel bool
+	}{
+		"no aggregate matcher selects the average": {
+			matchers: []*labels.Matcher{nameMatcher},
+			expectedMatchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, block.AggregateLabel, block.AggregateAvg),
+				nameMatcher,
+			},
+			expectedStripAggLabel: true,
+		},
+		"matchers selecting a single aggregate": {
+			matchers: []*labels.Matcher{
+				nameMatcher,
+				labels.MustNewMatcher(labels.MatchRegexp, block.AggregateLabel, "counter|"),
+			},
+			expectedMatchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, block.AggregateLabel, block.AggregateCounter),
+				nameMatcher,
+			},
+			expectedStripAggLabel: true,
+		},
+		"matchers selecting multiple aggregates": {
+			matchers: []*labels.Matcher{
+				nameMatcher,
+				labels.MustNewMatcher(labels.MatchRegexp, block.AggregateLabel, "min|max"),
+			},
+			expectedMatchers: []*labels.Matcher{
+				nameMatcher,
+				labels.MustNewMatcher(labels.MatchRegexp, block.AggregateLabel, "min|max"),
+			},
+			expectedStripAggLabel: false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			matchers, stripAggLabel := downsampledAggregateMatchers(tc.matchers)
+			// Compare the string representation, because regexp matchers hold compiled state.
+			assert.Equal(t, matchersToStrings(tc.expectedMatchers), matchersToStrings(matchers))
+			assert.Equal(t, tc.expectedStripAggLabel, stripAggLabel)
+		})
+	}
+}
+
+func TestAggregateLabelStrippingSeriesChunkRefsSetIterator(t *testing.T) {
+	set := newSeriesChunkRefsSet(2, false)
+	set.series = append(set.series,
+		seriesChunkRefs{lset: labels.FromStrings(block.AggregateLabel, block.AggregateAvg, labels.MetricName, "a")},
+		seriesChunkRefs{lset: labels.FromStrings(block.AggregateLabel, block.AggregateAvg, labels.MetricName, "b")},
+	)
+
+	it := newAggregateLabelStrippingSeriesChunkRefsSetIterator(newSliceSeriesChunkRefsSetIterator(nil, set))
+	sets := readAllSeriesChunkRefsSet(it)
+	require.NoError(t, it.Err())
+	require.Len(t, sets, 1)
+	assert.Equal(t, []seriesChunkRefs{
+		{lset: labels.FromStrings(labels.MetricName, "a")},
+		{lset: labels.FromStrings(labels.MetricName, "b")},
+	}, sets[0].series)
+}
+
+func matchersToStrings(matchers []*labels.Matcher) []string {
+	result := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		result = append(result, m.String())
+	}
+	return result
+}
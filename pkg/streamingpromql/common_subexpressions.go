@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/operators"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// commonSubexpression is an instant vector expression that appears more than once in a query.
+//
+// It is evaluated once, and its result is returned to each place it is used through an
+// operators.InstantVectorDuplicationBuffer.
+type commonSubexpression struct {
+	buffer *operators.InstantVectorDuplicationBuffer // nil until the first occurrence of the expression has been converted to an operator.
+}
+
+// findCommonSubexpressions returns the instant vector expressions in expr that are identical to another instant vector
+// expression in expr and evaluated over the same time range.
+//
+// Each occurrence of an expression is present in the returned map, and all occurrences of the same expression share a
+// single commonSubexpression.
+func (q *Query) findCommonSubexpressions(expr parser.Expr, timeRange types.QueryTimeRange) map[parser.Expr]*commonSubexpression {
+	occurrences := map[string][]parser.Expr{}
+	q.findInstantVectorExpressions(expr, timeRange, occurrences)
+
+	subexpressions := map[parser.Expr]*commonSubexpression{}
+
+	for _, exprs := range occurrences {
+		if len(exprs) < 2 {
+			continue
+		}
+
+		s := &commonSubexpression{}
+
+		for _, e := range exprs {
+			subexpressions[e] = s
+		}
+	}
+
+	return subexpressions
+}
+
+// findInstantVectorExpressions adds each instant vector expression in expr to occurrences, keyed by its string
+// representation and time range.
+//
+// This must visit expressions in the same way that convertToOperator does, so that every expression recorded
+// here is converted with convertToInstantVectorOperator.
+func (q *Query) findInstantVectorExpressions(expr parser.Expr, timeRange types.QueryTimeRange, occurrences map[string][]parser.Expr) {
+	switch e := expr.(type) {
+	case *parser.ParenExpr:
+		q.findInstantVectorExpressions(e.Expr, timeRange, occurrences)
+		return
+	case *parser.StepInvariantExpr:
+		q.findInstantVectorExpressions(e.Expr, timeRange, occurrences)
+		return
+	case *parser.MatrixSelector:
+		// The vector selector in a matrix selector is not evaluated as an instant vector.
+		return
+	case *parser.SubqueryExpr:
+		q.findInstantVectorExpressions(e.Expr, q.subqueryTimeRange(e, timeRange), occurrences)
+		return
+	}
+
+	if expr.Type() == parser.ValueTypeVector {
+		key := fmt.Sprintf("%d:%d:%d:%s", timeRange.StartT, timeRange.EndT, timeRange.IntervalMilliseconds, expr.String())
+		occurrences[key] = append(occurrences[key], expr)
+
+		if len(occurrences[key]) > 1 {
+			// This expression will only be evaluated once, so there's no need to look at its children again.
+			return
+		}
+	}
+
+	if call, isCall := expr.(*parser.Call); isCall && call.Func.Name == "timestamp" {
+		if _, isSelector := unwrapParenAndStepInvariantExpr(call.Args[0]).(*parser.VectorSelector); isSelector {
+			// timestamp() changes the behaviour of a selector passed directly to it, so the selector's result can't be shared.
+			return
+		}
+	}
+
+	for _, child := range parser.Children(expr) {
+		if childExpr, ok := child.(parser.Expr); ok {
+			q.findInstantVectorExpressions(childExpr, timeRange, occurrences)
+		}
+	}
+}
+
+func unwrapParenAndStepInvariantExpr(expr parser.Expr) parser.Expr {
+	for {
+		switch e := expr.(type) {
+		case *parser.ParenExpr:
+			expr = e.Expr
+		case *parser.StepInvariantExpr:
+			expr = e.Expr
+		default:
+			return expr
+		}
+	}
+}
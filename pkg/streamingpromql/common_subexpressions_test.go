@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/promql/promqltest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/streamingpromql/testutils"
+)
+
+func TestFindCommonSubexpressions(t *testing.T) {
+	testCases := map[string]struct {
+		expr     string
+		expected []string // The common subexpressions found, in the order they appear in the query.
+	}{
+		"no duplicates": {
+			expr:     `sum(rate(foo[5m])) / sum(rate(bar[5m]))`,
+			expected: nil,
+		},
+		"same selector with different offsets": {
+			expr:     `sum(rate(foo[5m])) / sum(rate(foo[5m] offset 1h))`,
+			expected: nil,
+		},
+		"duplicated selector": {
+			expr:     `foo / on() group_left sum(foo)`,
+			expected: []string{`foo`, `foo`},
+		},
+		"duplicated aggregation": {
+			expr:     `sum(rate(foo[5m])) / (sum(rate(foo[5m])) + 1)`,
+			expected: []string{`sum(rate(foo[5m]))`, `sum(rate(foo[5m]))`},
+		},
+		"duplicated expression in parentheses": {
+			expr:     `(foo) + foo`,
+			expected: []string{`foo`, `foo`},
+		},
+		"duplicated expression containing another duplicated expression": {
+			expr:     `(foo + sum(foo)) * (foo + sum(foo))`,
+			expected: []string{`foo + sum(foo)`, `foo`, `foo`, `foo + sum(foo)`},
+		},
+		"duplicated selector inside range vector selector": {
+			expr:     `rate(foo[5m]) / foo`,
+			expected: nil,
+		},
+		"duplicated selector passed directly to timestamp()": {
+			expr:     `timestamp(foo) - foo`,
+			expected: nil,
+		},
+		"duplicated expression passed to timestamp()": {
+			expr:     `timestamp(abs(foo)) - abs(foo)`,
+			expected: []string{`abs(foo)`, `abs(foo)`},
+		},
+		"duplicated expression inside and outside subquery": {
+			expr:     `max_over_time(sum(foo)[5m:1m]) / sum(foo)`,
+			expected: nil,
+		},
+		"duplicated expression inside identical subqueries": {
+			expr:     `max_over_time(sum(foo)[5m:1m]) / min_over_time(sum(foo)[5m:1m])`,
+			expected: []string{`sum(foo)`, `sum(foo)`},
+		},
+	}
+
+	opts := NewTestEngineOpts()
+	engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			q, err := engine.NewRangeQuery(context.Background(), nil, nil, testCase.expr, timestamp.Time(0), timestamp.Time(0).Add(10*time.Minute), time.Minute)
+			require.NoError(t, err)
+			defer q.Close()
+
+			mimirQuery := q.(*Query)
+			var found []string
+
+			parser.Inspect(mimirQuery.statement.Expr, func(node parser.Node, _ []parser.Node) error {
+				if expr, ok := node.(parser.Expr); ok {
+					if _, isCommon := mimirQuery.commonSubexpressions[expr]; isCommon {
+						found = append(found, expr.String())
+					}
+				}
+
+				return nil
+			})
+
+			require.Equal(t, testCase.expected, found)
+		})
+	}
+}
+
+func TestCommonSubexpressionElimination(t *testing.T) {
+	// limitk() is an experimental function, so enable it for this test.
+	t.Cleanup(func() { parser.EnableExperimentalFunctions = false })
+	parser.EnableExperimentalFunctions = true
+
+	storage := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{idx="1"} 0+1x10
+			some_metric{idx="2"} 0+2x10
+			some_histogram{idx="1"} {{schema:0 sum:1 count:2 buckets:[1 1]}}+{{schema:0 sum:1 count:2 buckets:[1 1]}}x10
+	`)
+	t.Cleanup(func() { require.NoError(t, storage.Close()) })
+
+	optsWithCSE := NewTestEngineOpts()
+	engineWithCSE, err := NewEngine(optsWithCSE, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	optsWithoutCSE := NewTestEngineOpts()
+	optsWithoutCSE.Features.EnableCommonSubexpressionElimination = false
+	engineWithoutCSE, err := NewEngine(optsWithoutCSE, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	start := timestamp.Time(0)
+	end := start.Add(10 * time.Minute)
+
+	testCases := map[string]struct {
+		expr                           string
+		expectedTotalSamplesWithCSE    int64
+		expectedTotalSamplesWithoutCSE int64
+	}{
+		"selector used twice": {
+			expr:                           `some_metric / on() group_left sum(some_metric)`,
+			expectedTotalSamplesWithCSE:    22,
+			expectedTotalSamplesWithoutCSE: 44,
+		},
+		"aggregation used twice": {
+			expr:                           `sum(rate(some_metric[5m])) / (sum(rate(some_metric[5m])) + 1)`,
+			expectedTotalSamplesWithCSE:    90,
+			expectedTotalSamplesWithoutCSE: 2 * 90,
+		},
+		"selector used three times": {
+			expr:                           `some_metric + some_metric * some_metric`,
+			expectedTotalSamplesWithCSE:    22,
+			expectedTotalSamplesWithoutCSE: 66,
+		},
+		"histograms modified in place by one consumer": {
+			expr:                           `some_histogram * 2 + some_histogram`,
+			expectedTotalSamplesWithCSE:    132,
+			expectedTotalSamplesWithoutCSE: 2 * 132,
+		},
+		"one side of binary operation does not read all series": {
+			expr:                           `limitk(1, some_metric) + on() group_left sum(some_metric)`,
+			expectedTotalSamplesWithCSE:    22,
+			expectedTotalSamplesWithoutCSE: 44,
+		},
+	}
+
+	runQuery := func(t *testing.T, engine promql.QueryEngine, expr string) (*promql.Result, int64) {
+		q, err := engine.NewRangeQuery(context.Background(), storage, nil, expr, start, end, time.Minute)
+		require.NoError(t, err)
+		t.Cleanup(q.Close)
+
+		res := q.Exec(context.Background())
+		require.NoError(t, res.Err)
+
+		return res, q.Stats().Samples.TotalSamples
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			resultWithCSE, totalSamplesWithCSE := runQuery(t, engineWithCSE, testCase.expr)
+			resultWithoutCSE, totalSamplesWithoutCSE := runQuery(t, engineWithoutCSE, testCase.expr)
+
+			testutils.RequireEqualResults(t, testCase.expr, resultWithoutCSE, resultWithCSE, false)
+			require.Equal(t, testCase.expectedTotalSamplesWithCSE, totalSamplesWithCSE)
+			require.Equal(t, testCase.expectedTotalSamplesWithoutCSE, totalSamplesWithoutCSE)
+		})
+	}
+}
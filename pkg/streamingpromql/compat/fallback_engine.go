@@ -47,6 +47,11 @@ func NewEngineWithFallback(preferred, fallback promql.QueryEngine, reg prometheu
 	}
 }
 
+// Preferred returns the engine used for queries that it supports.
+func (e EngineWithFallback) Preferred() promql.QueryEngine {
+	return e.preferred
+}
+
 func (e EngineWithFallback) NewInstantQuery(ctx context.Context, q storage.Queryable, opts promql.QueryOpts, qs string, ts time.Time) (promql.Query, error) {
 	reason := ""
 
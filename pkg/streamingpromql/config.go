@@ -7,6 +7,8 @@ import (
 
 	"github.com/grafana/dskit/flagext"
 	"github.com/prometheus/prometheus/promql"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
 )
 
 type EngineOpts struct {
@@ -16,20 +18,30 @@ type EngineOpts struct {
 	// When operating in pedantic mode, we panic if memory consumption is > 0 after Query.Close()
 	// (indicating something was not returned to a pool).
 	Pedantic bool
+
+	// RemoteExecutionClient is used to evaluate the inner expressions of shardable aggregations on other queriers.
+	// If nil, all expressions are evaluated by this engine.
+	RemoteExecutionClient     remoteexec.RemoteExecutionClient
+	RemoteExecutionShardCount int
 }
 
 type Features struct {
 	EnableAggregationOperations                  bool `yaml:"enable_aggregation_operations" category:"experimental"`
 	EnableBinaryLogicalOperations                bool `yaml:"enable_binary_logical_operations" category:"experimental"`
+	EnableCommonSubexpressionElimination         bool `yaml:"enable_common_subexpression_elimination" category:"experimental"`
 	EnableOneToManyAndManyToOneBinaryOperations  bool `yaml:"enable_one_to_many_and_many_to_one_binary_operations" category:"experimental"`
 	EnableScalars                                bool `yaml:"enable_scalars" category:"experimental"`
 	EnableScalarScalarBinaryComparisonOperations bool `yaml:"enable_scalar_scalar_binary_comparison_operations" category:"experimental"`
+	EnableSpillToDisk                            bool `yaml:"enable_spill_to_disk" category:"experimental"`
 	EnableSubqueries                             bool `yaml:"enable_subqueries" category:"experimental"`
 	EnableVectorScalarBinaryComparisonOperations bool `yaml:"enable_vector_scalar_binary_comparison_operations" category:"experimental"`
 	EnableVectorVectorBinaryComparisonOperations bool `yaml:"enable_vector_vector_binary_comparison_operations" category:"experimental"`
 
 	DisabledAggregations flagext.StringSliceCSV `yaml:"disabled_aggregations" category:"experimental"`
 	DisabledFunctions    flagext.StringSliceCSV `yaml:"disabled_functions" category:"experimental"`
+
+	SpillToDiskDirectory       string  `yaml:"spill_to_disk_directory" category:"experimental"`
+	SpillToDiskMemoryThreshold float64 `yaml:"spill_to_disk_memory_threshold" category:"experimental"`
 }
 
 // EnableAllFeatures enables all features supported by MQE, including experimental or incomplete features.
@@ -43,20 +55,29 @@ var EnableAllFeatures = Features{
 	true,
 	true,
 	true,
+	true,
+	true,
 	[]string{},
 	[]string{},
+	"",
+	0.8,
 }
 
 func (t *Features) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&t.EnableAggregationOperations, "querier.mimir-query-engine.enable-aggregation-operations", true, "Enable support for aggregation operations in the Mimir query engine. Only applies if the MQE is in use.")
 	f.BoolVar(&t.EnableBinaryLogicalOperations, "querier.mimir-query-engine.enable-binary-logical-operations", true, "Enable support for binary logical operations in the Mimir query engine. Only applies if the MQE is in use.")
+	f.BoolVar(&t.EnableCommonSubexpressionElimination, "querier.mimir-query-engine.enable-common-subexpression-elimination", false, "Enable common subexpression elimination in the Mimir query engine, so that instant vector expressions that appear more than once in a query are only evaluated once. Only applies if the MQE is in use.")
 	f.BoolVar(&t.EnableOneToManyAndManyToOneBinaryOperations, "querier.mimir-query-engine.enable-one-to-many-and-many-to-one-binary-operations", true, "Enable support for one-to-many and many-to-one binary operations (group_left/group_right) in the Mimir query engine. Only applies if the MQE is in use.")
 	f.BoolVar(&t.EnableScalars, "querier.mimir-query-engine.enable-scalars", true, "Enable support for scalars in the Mimir query engine. Only applies if the MQE is in use.")
 	f.BoolVar(&t.EnableScalarScalarBinaryComparisonOperations, "querier.mimir-query-engine.enable-scalar-scalar-binary-comparison-operations", true, "Enable support for binary comparison operations between two scalars in the Mimir query engine. Only applies if the MQE is in use.")
+	f.BoolVar(&t.EnableSpillToDisk, "querier.mimir-query-engine.enable-spill-to-disk", false, "Enable writing intermediate series to local disk when a query's estimated memory consumption is close to its limit, rather than holding them in memory. Applies to aggregations, sort, sort_desc and binary operations with group_left or group_right. Only applies if the MQE is in use.")
 	f.BoolVar(&t.EnableSubqueries, "querier.mimir-query-engine.enable-subqueries", true, "Enable support for subqueries in the Mimir query engine. Only applies if the MQE is in use.")
 	f.BoolVar(&t.EnableVectorScalarBinaryComparisonOperations, "querier.mimir-query-engine.enable-vector-scalar-binary-comparison-operations", true, "Enable support for binary comparison operations between a vector and a scalar in the Mimir query engine. Only applies if the MQE is in use.")
 	f.BoolVar(&t.EnableVectorVectorBinaryComparisonOperations, "querier.mimir-query-engine.enable-vector-vector-binary-comparison-operations", true, "Enable support for binary comparison operations between two vectors in the Mimir query engine. Only applies if the MQE is in use.")
 
 	f.Var(&t.DisabledAggregations, "querier.mimir-query-engine.disabled-aggregations", "Comma-separated list of aggregations to disable support for. Only applies if MQE is in use.")
 	f.Var(&t.DisabledFunctions, "querier.mimir-query-engine.disabled-functions", "Comma-separated list of function names to disable support for. Only applies if MQE is in use.")
+
+	f.StringVar(&t.SpillToDiskDirectory, "querier.mimir-query-engine.spill-to-disk-directory", "", "Directory to write intermediate series to when spilling to disk is enabled. If empty, the operating system's default directory for temporary files is used. Only applies if MQE is in use.")
+	f.Float64Var(&t.SpillToDiskMemoryThreshold, "querier.mimir-query-engine.spill-to-disk-memory-threshold", 0.8, "Fraction of the maximum estimated memory consumption per query above which intermediate series are written to disk, when spilling to disk is enabled. Must be greater than 0 and at most 1. Only applies if MQE is in use.")
 }
@@ -21,6 +21,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/streamingpromql/operators/aggregations"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
 )
 
 const defaultLookbackDelta = 5 * time.Minute // This should be the same value as github.com/prometheus/prometheus/promql.defaultLookbackDelta.
@@ -47,6 +48,14 @@ func NewEngine(opts EngineOpts, limitsProvider QueryLimitsProvider, metrics *sta
 		return nil, errors.New("enabling delayed name removal not supported by Mimir query engine")
 	}
 
+	if opts.Features.EnableSpillToDisk && (opts.Features.SpillToDiskMemoryThreshold <= 0 || opts.Features.SpillToDiskMemoryThreshold > 1) {
+		return nil, fmt.Errorf("spill to disk memory threshold must be greater than 0 and at most 1, got %v", opts.Features.SpillToDiskMemoryThreshold)
+	}
+
+	if opts.RemoteExecutionClient != nil && opts.RemoteExecutionShardCount < 2 {
+		return nil, fmt.Errorf("remote execution shard count must be at least 2, got %v", opts.RemoteExecutionShardCount)
+	}
+
 	// We must sort DisabledFunctions as we use a binary search on it later.
 	slices.Sort(opts.Features.DisabledFunctions)
 
@@ -70,6 +79,8 @@ func NewEngine(opts EngineOpts, limitsProvider QueryLimitsProvider, metrics *sta
 		features:                  opts.Features,
 		disabledAggregationsItems: disabledAggregationsItems,
 		noStepSubqueryIntervalFn:  opts.CommonOpts.NoStepSubqueryIntervalFn,
+		remoteExecutionClient:     opts.RemoteExecutionClient,
+		remoteExecutionShardCount: opts.RemoteExecutionShardCount,
 
 		logger: logger,
 		estimatedPeakMemoryConsumption: promauto.With(opts.CommonOpts.Reg).NewHistogram(prometheus.HistogramOpts{
@@ -93,6 +104,9 @@ type Engine struct {
 
 	noStepSubqueryIntervalFn func(rangeMillis int64) int64
 
+	remoteExecutionClient     remoteexec.RemoteExecutionClient // nil if remote execution is disabled.
+	remoteExecutionShardCount int
+
 	logger                                    log.Logger
 	estimatedPeakMemoryConsumption            prometheus.Histogram
 	queriesRejectedDueToPeakMemoryConsumption prometheus.Counter
@@ -103,19 +117,27 @@ type Engine struct {
 }
 
 func (e *Engine) NewInstantQuery(ctx context.Context, q storage.Queryable, opts promql.QueryOpts, qs string, ts time.Time) (promql.Query, error) {
-	return newQuery(ctx, q, opts, qs, ts, ts, 0, e)
+	return newQuery(ctx, q, opts, qs, ts, ts, 0, e, false)
 }
 
 func (e *Engine) NewRangeQuery(ctx context.Context, q storage.Queryable, opts promql.QueryOpts, qs string, start, end time.Time, interval time.Duration) (promql.Query, error) {
+	if err := validateRangeQueryTimeRange(start, end, interval); err != nil {
+		return nil, err
+	}
+
+	return newQuery(ctx, q, opts, qs, start, end, interval, e, false)
+}
+
+func validateRangeQueryTimeRange(start, end time.Time, interval time.Duration) error {
 	if interval <= 0 {
-		return nil, fmt.Errorf("%v is not a valid interval for a range query, must be greater than 0", interval)
+		return fmt.Errorf("%v is not a valid interval for a range query, must be greater than 0", interval)
 	}
 
 	if end.Before(start) {
-		return nil, fmt.Errorf("range query time range is invalid: end time %v is before start time %v", end.Format(time.RFC3339), start.Format(time.RFC3339))
+		return fmt.Errorf("range query time range is invalid: end time %v is before start time %v", end.Format(time.RFC3339), start.Format(time.RFC3339))
 	}
 
-	return newQuery(ctx, q, opts, qs, start, end, interval, e)
+	return nil
 }
 
 type QueryLimitsProvider interface {
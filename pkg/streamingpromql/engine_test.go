@@ -11,7 +11,9 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -49,12 +51,12 @@ func TestUnsupportedPromQLFeatures(t *testing.T) {
 	features := EnableAllFeatures
 
 	// Disable experimental so that parser will parse it without the updating experiemental flag
-	parser.Functions["double_exponential_smoothing"].Experimental = false
+	parser.Functions["info"].Experimental = false
 
 	// The goal of this is not to list every conceivable expression that is unsupported, but to cover all the
 	// different cases and make sure we produce a reasonable error message when these cases are encountered.
 	unsupportedExpressions := map[string]string{
-		"double_exponential_smoothing(metric{}[1h], 1, 1)": "'double_exponential_smoothing' function",
+		"info(metric{})": "'info' function",
 	}
 
 	for expression, expectedError := range unsupportedExpressions {
@@ -304,6 +306,10 @@ func TestNewInstantQuery_Strings(t *testing.T) {
 // Test cases that are not supported by the streaming engine are commented out (or, if the entire file is not supported, .disabled is appended to the file name).
 // Once the streaming engine supports all PromQL features exercised by Prometheus' test cases, we can remove these files and instead call promql.RunBuiltinTests here instead.
 func TestUpstreamTestCases(t *testing.T) {
+	// Some upstream test cases use experimental functions, so enable them like promqltest.RunBuiltinTests does.
+	t.Cleanup(func() { parser.EnableExperimentalFunctions = false })
+	parser.EnableExperimentalFunctions = true
+
 	opts := NewTestEngineOpts()
 	engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
 	require.NoError(t, err)
@@ -327,6 +333,10 @@ func TestUpstreamTestCases(t *testing.T) {
 }
 
 func TestOurTestCases(t *testing.T) {
+	// Some of our test cases use experimental functions, so enable them like promqltest.RunBuiltinTests does.
+	t.Cleanup(func() { parser.EnableExperimentalFunctions = false })
+	parser.EnableExperimentalFunctions = true
+
 	opts := NewTestEngineOpts()
 	mimirEngine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
 	require.NoError(t, err)
@@ -1694,6 +1704,7 @@ func TestMemoryConsumptionLimit_SingleQueries(t *testing.T) {
 				otlog.String("level", "info"),
 				otlog.String("msg", "query stats"),
 				otlog.Uint64("estimatedPeakMemoryConsumption", expectedMemoryConsumptionEstimate),
+				otlog.Uint64("spilledBytes", 0),
 				otlog.String("expr", testCase.expr),
 				otlog.String("queryType", queryType),
 			}
@@ -1831,6 +1842,221 @@ func getHistogram(t *testing.T, reg *prometheus.Registry, name string) *dto.Hist
 	return nil
 }
 
+func TestSpillToDisk(t *testing.T) {
+	storage := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{zone="a", idx="1"} 0+1x10
+			some_metric{zone="b", idx="2"} 0+2x10
+			some_metric{zone="a", idx="3"} 0+3x10
+			some_metric{zone="b", idx="4"} 10-1x10
+			some_metric{zone="a", idx="5"} 5x10
+			some_metric{zone="b", idx="6"} _ 1x4 _ 2x4
+			some_histogram{zone="a", idx="1"} {{schema:1 sum:10 count:9 buckets:[3 3 3]}}+{{schema:1 sum:1 count:1 buckets:[1]}}x10
+			some_histogram{zone="b", idx="2"} {{schema:1 sum:5 count:4 buckets:[1 2 1]}}x10
+			some_histogram{zone="a", idx="3"} {{schema:0 sum:1 count:1 buckets:[1]}}x10
+			group_info{zone="b", name="first"} 2x10
+			group_info{zone="a", name="second"} 1x10
+	`)
+	t.Cleanup(func() { require.NoError(t, storage.Close()) })
+
+	// Series are returned in label order, so the series for each zone are interleaved: this forces operators
+	// to hold series for one group while they're producing the output for another.
+
+	testCases := map[string]struct {
+		instantQueryShouldSpill bool
+		rangeQueryShouldSpill   bool
+	}{
+		// sort() and sort_desc() have no effect for range queries, so there's nothing to spill.
+		`sort(some_metric)`:      {instantQueryShouldSpill: true},
+		`sort_desc(some_metric)`: {instantQueryShouldSpill: true},
+
+		// topk() and bottomk() only hold a single value for each series for instant queries, so there's nothing to spill.
+		`topk by (zone) (2, some_metric)`:    {rangeQueryShouldSpill: true},
+		`bottomk by (zone) (1, some_metric)`: {rangeQueryShouldSpill: true},
+		`topk by (zone) (1, some_histogram)`: {rangeQueryShouldSpill: true},
+
+		`sum by (zone) (some_metric)`:                           {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+		`avg by (zone) (some_histogram)`:                        {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+		`max by (zone) (some_metric)`:                           {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+		`quantile by (zone) (0.5, some_metric)`:                 {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+		`count_values by (zone) ("value", some_metric)`:         {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+		`count_values("value", some_histogram)`:                 {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+		`some_metric * on (zone) group_left (name) group_info`:  {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+		`group_info * on (zone) group_right (name) some_metric`: {instantQueryShouldSpill: true, rangeQueryShouldSpill: true},
+	}
+
+	start := timestamp.Time(0)
+	end := start.Add(10 * time.Minute)
+	step := time.Minute
+
+	createEngine := func(t *testing.T, enableSpillToDisk bool) promql.QueryEngine {
+		opts := NewTestEngineOpts()
+		opts.Features.EnableSpillToDisk = enableSpillToDisk
+		opts.Features.SpillToDiskDirectory = t.TempDir()
+
+		// Use the smallest possible threshold so that operators that support spilling to disk always do so.
+		opts.Features.SpillToDiskMemoryThreshold = math.SmallestNonzeroFloat64
+
+		engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(1024*1024*1024), stats.NewQueryMetrics(nil), log.NewNopLogger())
+		require.NoError(t, err)
+		return engine
+	}
+
+	withoutSpilling := createEngine(t, false)
+	withSpilling := createEngine(t, true)
+
+	for expr, testCase := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			queryTypes := map[string]struct {
+				createQuery func(engine promql.QueryEngine, ctx context.Context) (promql.Query, error)
+				shouldSpill bool
+			}{
+				"range": {
+					createQuery: func(engine promql.QueryEngine, ctx context.Context) (promql.Query, error) {
+						return engine.NewRangeQuery(ctx, storage, nil, expr, start, end, step)
+					},
+					shouldSpill: testCase.rangeQueryShouldSpill,
+				},
+				"instant": {
+					createQuery: func(engine promql.QueryEngine, ctx context.Context) (promql.Query, error) {
+						return engine.NewInstantQuery(ctx, storage, nil, expr, end)
+					},
+					shouldSpill: testCase.instantQueryShouldSpill,
+				},
+			}
+
+			for queryType, queryTypeCase := range queryTypes {
+				t.Run(queryType, func(t *testing.T) {
+					createQuery := queryTypeCase.createQuery
+
+					q, err := createQuery(withoutSpilling, context.Background())
+					require.NoError(t, err)
+					defer q.Close()
+					expected := q.Exec(context.Background())
+					require.NoError(t, expected.Err)
+
+					queryStats, ctx := stats.ContextWithEmptyStats(context.Background())
+					q, err = createQuery(withSpilling, ctx)
+					require.NoError(t, err)
+					defer q.Close()
+					actual := q.Exec(ctx)
+					require.NoError(t, actual.Err)
+
+					testutils.RequireEqualResults(t, expr, expected, actual, false)
+
+					if queryTypeCase.shouldSpill {
+						require.NotZero(t, queryStats.LoadSpilledBytes(), "expected query to spill series data to disk")
+					} else {
+						require.Zero(t, queryStats.LoadSpilledBytes(), "expected query to not spill series data to disk")
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestExecStreaming(t *testing.T) {
+	storage := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{zone="a", idx="1"} 0+1x10
+			some_metric{zone="b", idx="2"} 0+2x10
+			some_metric{zone="a", idx="3"} _x5 3x5
+			some_metric{zone="b", idx="4"} _x10
+			some_histogram{zone="a", idx="1"} {{schema:1 sum:10 count:9 buckets:[3 3 3]}}+{{schema:1 sum:1 count:1 buckets:[1]}}x10
+	`)
+	t.Cleanup(func() { require.NoError(t, storage.Close()) })
+
+	opts := NewTestEngineOpts()
+	engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	start := timestamp.Time(0)
+	end := start.Add(10 * time.Minute)
+	step := time.Minute
+
+	testCases := []string{
+		`some_metric`,
+		`sum by (zone) (some_metric)`,
+		`rate(some_histogram[5m])`,
+		`some_metric > 3`,
+		`{__name__=~"some_.*"}`,
+		`nonexistent_metric`,
+	}
+
+	for _, expr := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			q, err := engine.NewRangeQuery(context.Background(), storage, nil, expr, start, end, step)
+			require.NoError(t, err)
+			defer q.Close()
+			expected := q.Exec(context.Background())
+			require.NoError(t, expected.Err)
+			expectedMatrix, err := expected.Matrix()
+			require.NoError(t, err)
+
+			q, err = engine.NewRangeQuery(context.Background(), storage, nil, expr, start, end, step)
+			require.NoError(t, err)
+			defer q.Close()
+			require.True(t, q.(*Query).CanExecStreaming())
+
+			var actual promql.Matrix
+			annos, err := q.(*Query).ExecStreaming(context.Background(), func(s promql.Series) error {
+				require.True(t, len(s.Floats) > 0 || len(s.Histograms) > 0, "streamed series should have points")
+
+				// The points are returned to the pool once we return, so take a copy of them.
+				s.Floats = slices.Clone(s.Floats)
+				s.Histograms = slices.Clone(s.Histograms)
+				for i, p := range s.Histograms {
+					s.Histograms[i].H = p.H.Copy()
+				}
+
+				actual = append(actual, s)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, expected.Warnings, annos)
+
+			slices.SortFunc(actual, func(a, b promql.Series) int {
+				return labels.Compare(a.Metric, b.Metric)
+			})
+
+			if len(expectedMatrix) == 0 {
+				require.Empty(t, actual)
+			} else {
+				require.Equal(t, expectedMatrix, actual)
+			}
+		})
+	}
+
+	t.Run("callback error", func(t *testing.T) {
+		q, err := engine.NewRangeQuery(context.Background(), storage, nil, `some_metric`, start, end, step)
+		require.NoError(t, err)
+		defer q.Close()
+
+		callbackErr := errors.New("something went wrong")
+		_, err = q.(*Query).ExecStreaming(context.Background(), func(promql.Series) error { return callbackErr })
+		require.ErrorIs(t, err, callbackErr)
+	})
+
+	t.Run("unsupported expression types", func(t *testing.T) {
+		for _, expr := range []string{`1`, `time()`} {
+			q, err := engine.NewRangeQuery(context.Background(), storage, nil, expr, start, end, step)
+			require.NoError(t, err)
+			defer q.Close()
+			require.False(t, q.(*Query).CanExecStreaming())
+
+			_, err = q.(*Query).ExecStreaming(context.Background(), func(promql.Series) error { return nil })
+			require.EqualError(t, err, "streaming evaluation is only supported for range queries that produce an instant vector, but this query produces a scalar")
+		}
+	})
+
+	t.Run("instant query", func(t *testing.T) {
+		q, err := engine.NewInstantQuery(context.Background(), storage, nil, `some_metric`, end)
+		require.NoError(t, err)
+		defer q.Close()
+		require.False(t, q.(*Query).CanExecStreaming())
+	})
+}
+
 func TestActiveQueryTracker(t *testing.T) {
 	for _, shouldSucceed := range []bool{true, false} {
 		t.Run(fmt.Sprintf("successful query = %v", shouldSucceed), func(t *testing.T) {
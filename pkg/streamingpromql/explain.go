@@ -0,0 +1,403 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/operators/selectors"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// QueryPlan describes the tree of operators used by the Mimir query engine to evaluate a query.
+type QueryPlan struct {
+	Root *OperatorPlan `json:"root"`
+
+	// The fields below are only populated if the query was executed.
+	Executed                            bool    `json:"executed"`
+	WallTimeSeconds                     float64 `json:"wallTimeSeconds,omitempty"`
+	EstimatedPeakMemoryConsumptionBytes uint64  `json:"estimatedPeakMemoryConsumptionBytes,omitempty"`
+	TotalSamples                        int64   `json:"totalSamples,omitempty"`
+	Error                               string  `json:"error,omitempty"`
+}
+
+// OperatorPlan describes a single operator in a QueryPlan.
+type OperatorPlan struct {
+	Operator   string        `json:"operator"`
+	Expression string        `json:"expression"`
+	Selector   *SelectorPlan `json:"selector,omitempty"`
+	Steps      int           `json:"steps"`
+
+	// The fields below are only populated if the query was executed.
+	//
+	// WallTimeSeconds includes time spent in child operators, while SelfWallTimeSeconds does not.
+	//
+	// PeakMemoryConsumptionBytes is the peak estimated memory consumption of the whole query observed while
+	// this operator was running, so it includes memory held by child operators and other parts of the query.
+	Series                     *int    `json:"series,omitempty"`
+	WallTimeSeconds            float64 `json:"wallTimeSeconds,omitempty"`
+	SelfWallTimeSeconds        float64 `json:"selfWallTimeSeconds,omitempty"`
+	PeakMemoryConsumptionBytes uint64  `json:"peakMemoryConsumptionBytes,omitempty"`
+
+	Children []*OperatorPlan `json:"children,omitempty"`
+
+	wallTime time.Duration
+}
+
+// SelectorPlan describes the selector used by an instant or range vector selector operator.
+type SelectorPlan struct {
+	Matchers      []string `json:"matchers"`
+	Timestamp     *int64   `json:"timestamp,omitempty"`
+	Offset        string   `json:"offset,omitempty"`
+	Range         string   `json:"range,omitempty"`
+	LookbackDelta string   `json:"lookbackDelta,omitempty"`
+
+	// EstimatedSeries is the number of series matching the selector in its time range, looked up without their samples.
+	// If it couldn't be looked up, EstimatedSeriesUnavailable is the reason why.
+	EstimatedSeries            *int   `json:"estimatedSeries,omitempty"`
+	EstimatedSeriesUnavailable string `json:"estimatedSeriesUnavailable,omitempty"`
+}
+
+// Explain returns the tree of operators that would be used to evaluate qs.
+//
+// If execute is true, the query is also evaluated (and its result discarded), and the returned plan includes
+// the series returned, time spent and peak memory consumption of each operator. Errors encountered while
+// evaluating the query are returned in QueryPlan.Error rather than as an error.
+//
+// A compat.NotSupportedError is returned if the query is not supported by this engine.
+func (e *Engine) Explain(ctx context.Context, q storage.Queryable, qs string, start, end time.Time, interval time.Duration, execute bool) (*QueryPlan, error) {
+	if !start.Equal(end) || interval != 0 {
+		if err := validateRangeQueryTimeRange(start, end, interval); err != nil {
+			return nil, err
+		}
+	}
+
+	query, err := newQuery(ctx, q, nil, qs, start, end, interval, e, true)
+	if err != nil {
+		return nil, err
+	}
+
+	defer query.Close()
+
+	plan := &QueryPlan{Root: query.planBuilder.root}
+	query.planBuilder.estimateSeries(ctx)
+
+	if !execute {
+		query.root.Close()
+		return plan, nil
+	}
+
+	startTime := time.Now()
+	res := query.Exec(ctx)
+
+	plan.Executed = true
+	plan.WallTimeSeconds = time.Since(startTime).Seconds()
+	plan.EstimatedPeakMemoryConsumptionBytes = query.memoryConsumptionTracker.PeakEstimatedMemoryConsumptionBytes
+	plan.TotalSamples = query.stats.TotalSamples
+	plan.Root.populateWallTimes()
+
+	if res.Err != nil {
+		plan.Error = res.Err.Error()
+	}
+
+	return plan, nil
+}
+
+func (p *OperatorPlan) populateWallTimes() {
+	self := p.wallTime
+
+	for _, c := range p.Children {
+		c.populateWallTimes()
+		self -= c.wallTime
+	}
+
+	p.WallTimeSeconds = p.wallTime.Seconds()
+	p.SelfWallTimeSeconds = max(self, 0).Seconds()
+}
+
+// queryPlanBuilder records the operators created while converting a query's expression to operators,
+// and wraps each of them so that their execution can be measured.
+type queryPlanBuilder struct {
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	lookbackDelta            time.Duration
+
+	root      *OperatorPlan
+	stack     []*OperatorPlan
+	selectors map[*OperatorPlan]*selectors.Selector
+}
+
+func newQueryPlanBuilder(memoryConsumptionTracker *limiting.MemoryConsumptionTracker, lookbackDelta time.Duration) *queryPlanBuilder {
+	return &queryPlanBuilder{
+		memoryConsumptionTracker: memoryConsumptionTracker,
+		lookbackDelta:            lookbackDelta,
+		selectors:                map[*OperatorPlan]*selectors.Selector{},
+	}
+}
+
+// begin records the start of the conversion of expr to an operator.
+// Every call to begin must be followed by exactly one call to finish or abandon.
+func (b *queryPlanBuilder) begin(expr parser.Expr, timeRange types.QueryTimeRange) *OperatorPlan {
+	node := &OperatorPlan{
+		Expression: expr.String(),
+		Steps:      timeRange.StepCount,
+		Selector:   describeSelector(expr, b.lookbackDelta),
+	}
+
+	if len(b.stack) == 0 {
+		b.root = node
+	} else {
+		parent := b.stack[len(b.stack)-1]
+		parent.Children = append(parent.Children, node)
+	}
+
+	b.stack = append(b.stack, node)
+
+	return node
+}
+
+// abandon records that the conversion started by the last call to begin failed.
+func (b *queryPlanBuilder) abandon() {
+	b.stack = b.stack[:len(b.stack)-1]
+}
+
+// finish records that node was converted to o, and returns o wrapped so that its execution is measured.
+//
+// If o was returned as-is from the conversion of a child expression (eg. for parenthesised expressions),
+// node is replaced with the child's node and o is returned unchanged.
+func (b *queryPlanBuilder) finish(node *OperatorPlan, o types.Operator) types.Operator {
+	b.stack = b.stack[:len(b.stack)-1]
+
+	if existing, isInstrumented := o.(instrumentedOperator); isInstrumented {
+		b.replace(node, existing.plan())
+		return o
+	}
+
+	node.Operator = strings.TrimPrefix(fmt.Sprintf("%T", o), "*")
+	instrumentation := operatorInstrumentation{node: node, memoryConsumptionTracker: b.memoryConsumptionTracker}
+
+	switch o := o.(type) {
+	case *selectors.InstantVectorSelector:
+		b.selectors[node] = o.Selector
+	case *selectors.RangeVectorSelector:
+		b.selectors[node] = o.Selector
+	}
+
+	switch o := o.(type) {
+	case types.InstantVectorOperator:
+		return &instrumentedInstantVectorOperator{InstantVectorOperator: o, operatorInstrumentation: instrumentation}
+	case types.RangeVectorOperator:
+		return &instrumentedRangeVectorOperator{RangeVectorOperator: o, operatorInstrumentation: instrumentation}
+	case types.ScalarOperator:
+		return &instrumentedScalarOperator{ScalarOperator: o, operatorInstrumentation: instrumentation}
+	case types.StringOperator:
+		return &instrumentedStringOperator{StringOperator: o, operatorInstrumentation: instrumentation}
+	default:
+		panic(fmt.Sprintf("unknown operator type %T", o))
+	}
+}
+
+// estimateSeries populates the estimated number of series of each selector in the plan.
+func (b *queryPlanBuilder) estimateSeries(ctx context.Context) {
+	for node, selector := range b.selectors {
+		count, err := selector.EstimateSeriesCount(ctx)
+		if err != nil {
+			node.Selector.EstimatedSeriesUnavailable = err.Error()
+			continue
+		}
+
+		node.Selector.EstimatedSeries = &count
+	}
+}
+
+func (b *queryPlanBuilder) replace(node *OperatorPlan, replacement *OperatorPlan) {
+	if len(b.stack) == 0 {
+		b.root = replacement
+		return
+	}
+
+	parent := b.stack[len(b.stack)-1]
+	for i, c := range parent.Children {
+		if c == node {
+			parent.Children[i] = replacement
+			return
+		}
+	}
+}
+
+func describeSelector(expr parser.Expr, lookbackDelta time.Duration) *SelectorPlan {
+	var vs *parser.VectorSelector
+	var rng time.Duration
+
+	switch e := expr.(type) {
+	case *parser.VectorSelector:
+		vs = e
+	case *parser.MatrixSelector:
+		lookbackDelta = 0
+		vs = e.VectorSelector.(*parser.VectorSelector)
+		rng = e.Range
+	default:
+		return nil
+	}
+
+	p := &SelectorPlan{
+		Matchers:  make([]string, 0, len(vs.LabelMatchers)),
+		Timestamp: vs.Timestamp,
+	}
+
+	for _, m := range vs.LabelMatchers {
+		p.Matchers = append(p.Matchers, m.String())
+	}
+
+	if vs.OriginalOffset != 0 {
+		p.Offset = model.Duration(vs.OriginalOffset).String()
+	}
+
+	if rng != 0 {
+		p.Range = model.Duration(rng).String()
+	}
+
+	if lookbackDelta != 0 {
+		p.LookbackDelta = model.Duration(lookbackDelta).String()
+	}
+
+	return p
+}
+
+// unwrapOperator returns the operator wrapped by o if o was instrumented for a query plan, or o otherwise.
+func unwrapOperator(o types.Operator) types.Operator {
+	if i, isInstrumented := o.(instrumentedOperator); isInstrumented {
+		return i.unwrap()
+	}
+
+	return o
+}
+
+type instrumentedOperator interface {
+	plan() *OperatorPlan
+	unwrap() types.Operator
+}
+
+type operatorInstrumentation struct {
+	node                     *OperatorPlan
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker
+}
+
+func (i *operatorInstrumentation) plan() *OperatorPlan {
+	return i.node
+}
+
+// start begins measuring a call to the operator.
+//
+// The query's peak memory consumption is temporarily reset to its current memory consumption so that
+// the peak observed during the call can be measured. stop restores the query's overall peak.
+func (i *operatorInstrumentation) start() (time.Time, uint64) {
+	previousPeak := i.memoryConsumptionTracker.PeakEstimatedMemoryConsumptionBytes
+	i.memoryConsumptionTracker.PeakEstimatedMemoryConsumptionBytes = i.memoryConsumptionTracker.CurrentEstimatedMemoryConsumptionBytes
+
+	return time.Now(), previousPeak
+}
+
+func (i *operatorInstrumentation) stop(startTime time.Time, previousPeak uint64) {
+	i.node.wallTime += time.Since(startTime)
+
+	peak := i.memoryConsumptionTracker.PeakEstimatedMemoryConsumptionBytes
+	i.node.PeakMemoryConsumptionBytes = max(i.node.PeakMemoryConsumptionBytes, peak)
+	i.memoryConsumptionTracker.PeakEstimatedMemoryConsumptionBytes = max(previousPeak, peak)
+}
+
+func (i *operatorInstrumentation) recordSeries(series []types.SeriesMetadata) {
+	count := len(series)
+	i.node.Series = &count
+}
+
+type instrumentedInstantVectorOperator struct {
+	types.InstantVectorOperator
+	operatorInstrumentation
+}
+
+func (o *instrumentedInstantVectorOperator) unwrap() types.Operator {
+	return o.InstantVectorOperator
+}
+
+func (o *instrumentedInstantVectorOperator) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
+	startTime, previousPeak := o.start()
+	defer o.stop(startTime, previousPeak)
+
+	series, err := o.InstantVectorOperator.SeriesMetadata(ctx)
+	o.recordSeries(series)
+
+	return series, err
+}
+
+func (o *instrumentedInstantVectorOperator) NextSeries(ctx context.Context) (types.InstantVectorSeriesData, error) {
+	startTime, previousPeak := o.start()
+	defer o.stop(startTime, previousPeak)
+
+	return o.InstantVectorOperator.NextSeries(ctx)
+}
+
+type instrumentedRangeVectorOperator struct {
+	types.RangeVectorOperator
+	operatorInstrumentation
+}
+
+func (o *instrumentedRangeVectorOperator) unwrap() types.Operator {
+	return o.RangeVectorOperator
+}
+
+func (o *instrumentedRangeVectorOperator) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
+	startTime, previousPeak := o.start()
+	defer o.stop(startTime, previousPeak)
+
+	series, err := o.RangeVectorOperator.SeriesMetadata(ctx)
+	o.recordSeries(series)
+
+	return series, err
+}
+
+func (o *instrumentedRangeVectorOperator) NextSeries(ctx context.Context) error {
+	startTime, previousPeak := o.start()
+	defer o.stop(startTime, previousPeak)
+
+	return o.RangeVectorOperator.NextSeries(ctx)
+}
+
+func (o *instrumentedRangeVectorOperator) NextStepSamples() (*types.RangeVectorStepData, error) {
+	startTime, previousPeak := o.start()
+	defer o.stop(startTime, previousPeak)
+
+	return o.RangeVectorOperator.NextStepSamples()
+}
+
+type instrumentedScalarOperator struct {
+	types.ScalarOperator
+	operatorInstrumentation
+}
+
+func (o *instrumentedScalarOperator) unwrap() types.Operator {
+	return o.ScalarOperator
+}
+
+func (o *instrumentedScalarOperator) GetValues(ctx context.Context) (types.ScalarData, error) {
+	startTime, previousPeak := o.start()
+	defer o.stop(startTime, previousPeak)
+
+	return o.ScalarOperator.GetValues(ctx)
+}
+
+type instrumentedStringOperator struct {
+	types.StringOperator
+	operatorInstrumentation
+}
+
+func (o *instrumentedStringOperator) unwrap() types.Operator {
+	return o.StringOperator
+}
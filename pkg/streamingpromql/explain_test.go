@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/promqltest"
+	promstorage "github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/streamingpromql/compat"
+)
+
+func TestExplain(t *testing.T) {
+	storage := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{idx="1"} 0+1x5
+			some_metric{idx="2"} 0+1x5
+	`)
+	t.Cleanup(func() { require.NoError(t, storage.Close()) })
+
+	opts := NewTestEngineOpts()
+	engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+	mqe := engine.(*Engine)
+
+	ctx := context.Background()
+	start := timestamp.Time(0)
+	end := start.Add(4 * time.Minute)
+
+	t.Run("plan only", func(t *testing.T) {
+		plan, err := mqe.Explain(ctx, storage, `sum((rate(some_metric[2m] offset 1m)))`, start, end, time.Minute, false)
+		estimatedSeries := 2
+		require.NoError(t, err)
+		require.False(t, plan.Executed)
+
+		root := plan.Root
+		require.Equal(t, "aggregations.Aggregation", root.Operator)
+		require.Equal(t, `sum((rate(some_metric[2m] offset 1m)))`, root.Expression)
+		require.Equal(t, 5, root.Steps)
+		require.Nil(t, root.Series)
+		require.Zero(t, root.WallTimeSeconds)
+
+		// The parenthesised expression should not appear in the plan.
+		require.Len(t, root.Children, 1)
+		rate := root.Children[0]
+		require.Equal(t, "operators.DeduplicateAndMerge", rate.Operator)
+		require.Equal(t, `rate(some_metric[2m] offset 1m)`, rate.Expression)
+
+		require.Len(t, rate.Children, 1)
+		selector := rate.Children[0]
+		require.Equal(t, "selectors.RangeVectorSelector", selector.Operator)
+		require.Empty(t, selector.Children)
+		require.Equal(t, &SelectorPlan{
+			Matchers:        []string{`__name__="some_metric"`},
+			Offset:          "1m",
+			Range:           "2m",
+			EstimatedSeries: &estimatedSeries,
+		}, selector.Selector)
+	})
+
+	t.Run("executed", func(t *testing.T) {
+		plan, err := mqe.Explain(ctx, storage, `sum(some_metric) * 2`, start, end, time.Minute, true)
+		require.NoError(t, err)
+		require.True(t, plan.Executed)
+		require.Empty(t, plan.Error)
+		require.Equal(t, int64(10), plan.TotalSamples)
+		require.NotZero(t, plan.EstimatedPeakMemoryConsumptionBytes)
+
+		root := plan.Root
+		require.Equal(t, "operators.DeduplicateAndMerge", root.Operator)
+		require.Equal(t, 1, *root.Series)
+		require.NotZero(t, root.WallTimeSeconds)
+		require.NotZero(t, root.PeakMemoryConsumptionBytes)
+		require.LessOrEqual(t, root.PeakMemoryConsumptionBytes, plan.EstimatedPeakMemoryConsumptionBytes)
+
+		// The scalar side of a vector/scalar binary operation is converted first.
+		require.Len(t, root.Children, 2)
+		require.Equal(t, "scalars.ScalarConstant", root.Children[0].Operator)
+		require.Equal(t, "aggregations.Aggregation", root.Children[1].Operator)
+
+		selector := root.Children[1].Children[0]
+		require.Equal(t, "selectors.InstantVectorSelector", selector.Operator)
+		require.Equal(t, 2, *selector.Series)
+		require.Equal(t, "5m", selector.Selector.LookbackDelta)
+		require.Equal(t, 2, *selector.Selector.EstimatedSeries)
+		require.LessOrEqual(t, selector.WallTimeSeconds, root.WallTimeSeconds)
+	})
+
+	t.Run("instant query using timestamp() over a selector", func(t *testing.T) {
+		plan, err := mqe.Explain(ctx, storage, `timestamp(some_metric)`, end, end, 0, true)
+		require.NoError(t, err)
+		require.Empty(t, plan.Error)
+		require.Equal(t, 1, plan.Root.Steps)
+		require.Equal(t, 2, *plan.Root.Series)
+	})
+
+	t.Run("common subexpression", func(t *testing.T) {
+		plan, err := mqe.Explain(ctx, storage, `some_metric + some_metric`, start, end, time.Minute, true)
+		require.NoError(t, err)
+		require.Empty(t, plan.Error)
+		require.Equal(t, int64(10), plan.TotalSamples)
+
+		root := plan.Root
+		require.Len(t, root.Children, 2)
+
+		// The first use of the expression evaluates the selector, and the second use only reads its result.
+		first := root.Children[0]
+		require.Equal(t, "operators.InstantVectorDuplicationConsumer", first.Operator)
+		require.Len(t, first.Children, 1)
+		require.Equal(t, "selectors.InstantVectorSelector", first.Children[0].Operator)
+
+		second := root.Children[1]
+		require.Equal(t, "operators.InstantVectorDuplicationConsumer", second.Operator)
+		require.Empty(t, second.Children)
+		require.Equal(t, 2, *second.Series)
+	})
+
+	t.Run("series estimate unavailable", func(t *testing.T) {
+		failingStorage := promstorage.QueryableFunc(func(_, _ int64) (promstorage.Querier, error) {
+			return nil, errors.New("storage unavailable")
+		})
+
+		plan, err := mqe.Explain(ctx, failingStorage, `some_metric`, start, end, time.Minute, false)
+		require.NoError(t, err)
+		require.Nil(t, plan.Root.Selector.EstimatedSeries)
+		require.Equal(t, "storage unavailable", plan.Root.Selector.EstimatedSeriesUnavailable)
+	})
+
+	t.Run("unsupported expression", func(t *testing.T) {
+		opts := NewTestEngineOpts()
+		opts.Features.EnableSubqueries = false
+		engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+		require.NoError(t, err)
+
+		_, err = engine.(*Engine).Explain(ctx, storage, `max_over_time(some_metric[5m:1m])`, start, end, time.Minute, false)
+		require.ErrorIs(t, err, compat.NotSupportedError{})
+		require.EqualError(t, err, "not supported by streaming engine: subquery")
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		_, err := mqe.Explain(ctx, storage, `some_metric`, end, start, time.Minute, false)
+		require.EqualError(t, err, "range query time range is invalid: end time 1970-01-01T00:00:00Z is before start time 1970-01-01T00:04:00Z")
+	})
+}
@@ -195,6 +195,41 @@ func FunctionOverRangeVectorOperatorFactory(
 	}
 }
 
+func DoubleExponentialSmoothingFactory(args []types.Operator, memoryConsumptionTracker *limiting.MemoryConsumptionTracker, annotations *annotations.Annotations, expressionPosition posrange.PositionRange, timeRange types.QueryTimeRange, _ parser.Expressions) (types.InstantVectorOperator, error) {
+	f := functions.DoubleExponentialSmoothing
+
+	if len(args) != 3 {
+		// Should be caught by the PromQL parser, but we check here for safety.
+		return nil, fmt.Errorf("expected exactly 3 arguments for double_exponential_smoothing, got %v", len(args))
+	}
+
+	inner, ok := args[0].(types.RangeVectorOperator)
+	if !ok {
+		// Should be caught by the PromQL parser, but we check here for safety.
+		return nil, fmt.Errorf("expected first argument for double_exponential_smoothing to be a range vector, got %T", args[0])
+	}
+
+	smoothingFactor, ok := args[1].(types.ScalarOperator)
+	if !ok {
+		// Should be caught by the PromQL parser, but we check here for safety.
+		return nil, fmt.Errorf("expected second argument for double_exponential_smoothing to be a scalar, got %T", args[1])
+	}
+
+	trendFactor, ok := args[2].(types.ScalarOperator)
+	if !ok {
+		// Should be caught by the PromQL parser, but we check here for safety.
+		return nil, fmt.Errorf("expected third argument for double_exponential_smoothing to be a scalar, got %T", args[2])
+	}
+
+	var o types.InstantVectorOperator = functions.NewFunctionOverRangeVector(inner, []types.ScalarOperator{smoothingFactor, trendFactor}, memoryConsumptionTracker, f, annotations, expressionPosition, timeRange)
+
+	if f.SeriesMetadataFunction.NeedsSeriesDeduplication {
+		o = operators.NewDeduplicateAndMerge(o, memoryConsumptionTracker)
+	}
+
+	return o, nil
+}
+
 func PredictLinearFactory(args []types.Operator, memoryConsumptionTracker *limiting.MemoryConsumptionTracker, annotations *annotations.Annotations, expressionPosition posrange.PositionRange, timeRange types.QueryTimeRange, _ parser.Expressions) (types.InstantVectorOperator, error) {
 	f := functions.PredictLinear
 
@@ -527,7 +562,7 @@ func TimestampFunctionOperatorFactory(args []types.Operator, memoryConsumptionTr
 	}
 
 	f := functions.Timestamp
-	selector, isSelector := args[0].(*selectors.InstantVectorSelector)
+	selector, isSelector := unwrapOperator(args[0]).(*selectors.InstantVectorSelector)
 
 	if isSelector {
 		selector.ReturnSampleTimestamps = true
@@ -567,78 +602,120 @@ func SortOperatorFactory(descending bool) InstantVectorFunctionOperatorFactory {
 	}
 }
 
+func SortByLabelOperatorFactory(descending bool) InstantVectorFunctionOperatorFactory {
+	functionName := "sort_by_label"
+
+	if descending {
+		functionName = "sort_by_label_desc"
+	}
+
+	return func(args []types.Operator, memoryConsumptionTracker *limiting.MemoryConsumptionTracker, _ *annotations.Annotations, expressionPosition posrange.PositionRange, timeRange types.QueryTimeRange, _ parser.Expressions) (types.InstantVectorOperator, error) {
+		if len(args) < 1 {
+			// Should be caught by the PromQL parser, but we check here for safety.
+			return nil, fmt.Errorf("expected at least 1 argument for %s, got %v", functionName, len(args))
+		}
+
+		inner, ok := args[0].(types.InstantVectorOperator)
+		if !ok {
+			// Should be caught by the PromQL parser, but we check here for safety.
+			return nil, fmt.Errorf("expected an instant vector for 1st argument for %s, got %T", functionName, args[0])
+		}
+
+		sortLabels := make([]types.StringOperator, 0, len(args)-1)
+		for i := 1; i < len(args); i++ {
+			sortLabel, ok := args[i].(types.StringOperator)
+			if !ok {
+				// Should be caught by the PromQL parser, but we check here for safety.
+				return nil, fmt.Errorf("expected a string for %dth argument for %s, got %T", i+1, functionName, args[i])
+			}
+			sortLabels = append(sortLabels, sortLabel)
+		}
+
+		if timeRange.StepCount != 1 {
+			// If this is a range query, sort_by_label / sort_by_label_desc has no effect.
+			return inner, nil
+		}
+
+		return functions.NewSortByLabel(inner, descending, sortLabels, memoryConsumptionTracker, expressionPosition), nil
+	}
+}
+
 // These functions return an instant-vector.
 var instantVectorFunctionOperatorFactories = map[string]InstantVectorFunctionOperatorFactory{
 	//lint:sorted
-	"abs":                InstantVectorTransformationFunctionOperatorFactory("abs", functions.Abs),
-	"absent":             AbsentFunctionOperatorFactory,
-	"absent_over_time":   AbsentOverTimeFunctionOperatorFactory,
-	"acos":               InstantVectorTransformationFunctionOperatorFactory("acos", functions.Acos),
-	"acosh":              InstantVectorTransformationFunctionOperatorFactory("acosh", functions.Acosh),
-	"asin":               InstantVectorTransformationFunctionOperatorFactory("asin", functions.Asin),
-	"asinh":              InstantVectorTransformationFunctionOperatorFactory("asinh", functions.Asinh),
-	"atan":               InstantVectorTransformationFunctionOperatorFactory("atan", functions.Atan),
-	"atanh":              InstantVectorTransformationFunctionOperatorFactory("atanh", functions.Atanh),
-	"avg_over_time":      FunctionOverRangeVectorOperatorFactory("avg_over_time", functions.AvgOverTime),
-	"ceil":               InstantVectorTransformationFunctionOperatorFactory("ceil", functions.Ceil),
-	"changes":            FunctionOverRangeVectorOperatorFactory("changes", functions.Changes),
-	"clamp":              ClampFunctionOperatorFactory,
-	"clamp_max":          ClampMinMaxFunctionOperatorFactory("clamp_max", false),
-	"clamp_min":          ClampMinMaxFunctionOperatorFactory("clamp_min", true),
-	"cos":                InstantVectorTransformationFunctionOperatorFactory("cos", functions.Cos),
-	"cosh":               InstantVectorTransformationFunctionOperatorFactory("cosh", functions.Cosh),
-	"count_over_time":    FunctionOverRangeVectorOperatorFactory("count_over_time", functions.CountOverTime),
-	"day_of_month":       TimeTransformationFunctionOperatorFactory("day_of_month", functions.DayOfMonth),
-	"day_of_week":        TimeTransformationFunctionOperatorFactory("day_of_week", functions.DayOfWeek),
-	"day_of_year":        TimeTransformationFunctionOperatorFactory("day_of_year", functions.DayOfYear),
-	"days_in_month":      TimeTransformationFunctionOperatorFactory("days_in_month", functions.DaysInMonth),
-	"deg":                InstantVectorTransformationFunctionOperatorFactory("deg", functions.Deg),
-	"delta":              FunctionOverRangeVectorOperatorFactory("delta", functions.Delta),
-	"deriv":              FunctionOverRangeVectorOperatorFactory("deriv", functions.Deriv),
-	"exp":                InstantVectorTransformationFunctionOperatorFactory("exp", functions.Exp),
-	"floor":              InstantVectorTransformationFunctionOperatorFactory("floor", functions.Floor),
-	"histogram_avg":      InstantVectorTransformationFunctionOperatorFactory("histogram_avg", functions.HistogramAvg),
-	"histogram_count":    InstantVectorTransformationFunctionOperatorFactory("histogram_count", functions.HistogramCount),
-	"histogram_fraction": HistogramFractionFunctionOperatorFactory,
-	"histogram_quantile": HistogramQuantileFunctionOperatorFactory,
-	"histogram_stddev":   InstantVectorTransformationFunctionOperatorFactory("histogram_stddev", functions.HistogramStdDevStdVar(true)),
-	"histogram_stdvar":   InstantVectorTransformationFunctionOperatorFactory("histogram_stdvar", functions.HistogramStdDevStdVar(false)),
-	"histogram_sum":      InstantVectorTransformationFunctionOperatorFactory("histogram_sum", functions.HistogramSum),
-	"hour":               TimeTransformationFunctionOperatorFactory("hour", functions.Hour),
-	"idelta":             FunctionOverRangeVectorOperatorFactory("idelta", functions.Idelta),
-	"increase":           FunctionOverRangeVectorOperatorFactory("increase", functions.Increase),
-	"irate":              FunctionOverRangeVectorOperatorFactory("irate", functions.Irate),
-	"label_join":         LabelJoinFunctionOperatorFactory,
-	"label_replace":      LabelReplaceFunctionOperatorFactory,
-	"last_over_time":     FunctionOverRangeVectorOperatorFactory("last_over_time", functions.LastOverTime),
-	"ln":                 InstantVectorTransformationFunctionOperatorFactory("ln", functions.Ln),
-	"log10":              InstantVectorTransformationFunctionOperatorFactory("log10", functions.Log10),
-	"log2":               InstantVectorTransformationFunctionOperatorFactory("log2", functions.Log2),
-	"max_over_time":      FunctionOverRangeVectorOperatorFactory("max_over_time", functions.MaxOverTime),
-	"min_over_time":      FunctionOverRangeVectorOperatorFactory("min_over_time", functions.MinOverTime),
-	"minute":             TimeTransformationFunctionOperatorFactory("minute", functions.Minute),
-	"month":              TimeTransformationFunctionOperatorFactory("month", functions.Month),
-	"predict_linear":     PredictLinearFactory,
-	"present_over_time":  FunctionOverRangeVectorOperatorFactory("present_over_time", functions.PresentOverTime),
-	"quantile_over_time": QuantileOverTimeFactory,
-	"rad":                InstantVectorTransformationFunctionOperatorFactory("rad", functions.Rad),
-	"rate":               FunctionOverRangeVectorOperatorFactory("rate", functions.Rate),
-	"resets":             FunctionOverRangeVectorOperatorFactory("resets", functions.Resets),
-	"round":              RoundFunctionOperatorFactory,
-	"sgn":                InstantVectorTransformationFunctionOperatorFactory("sgn", functions.Sgn),
-	"sin":                InstantVectorTransformationFunctionOperatorFactory("sin", functions.Sin),
-	"sinh":               InstantVectorTransformationFunctionOperatorFactory("sinh", functions.Sinh),
-	"sort":               SortOperatorFactory(false),
-	"sort_desc":          SortOperatorFactory(true),
-	"sqrt":               InstantVectorTransformationFunctionOperatorFactory("sqrt", functions.Sqrt),
-	"stddev_over_time":   FunctionOverRangeVectorOperatorFactory("stddev_over_time", functions.StddevOverTime),
-	"stdvar_over_time":   FunctionOverRangeVectorOperatorFactory("stdvar_over_time", functions.StdvarOverTime),
-	"sum_over_time":      FunctionOverRangeVectorOperatorFactory("sum_over_time", functions.SumOverTime),
-	"tan":                InstantVectorTransformationFunctionOperatorFactory("tan", functions.Tan),
-	"tanh":               InstantVectorTransformationFunctionOperatorFactory("tanh", functions.Tanh),
-	"timestamp":          TimestampFunctionOperatorFactory,
-	"vector":             scalarToInstantVectorOperatorFactory,
-	"year":               TimeTransformationFunctionOperatorFactory("year", functions.Year),
+	"abs":                          InstantVectorTransformationFunctionOperatorFactory("abs", functions.Abs),
+	"absent":                       AbsentFunctionOperatorFactory,
+	"absent_over_time":             AbsentOverTimeFunctionOperatorFactory,
+	"acos":                         InstantVectorTransformationFunctionOperatorFactory("acos", functions.Acos),
+	"acosh":                        InstantVectorTransformationFunctionOperatorFactory("acosh", functions.Acosh),
+	"asin":                         InstantVectorTransformationFunctionOperatorFactory("asin", functions.Asin),
+	"asinh":                        InstantVectorTransformationFunctionOperatorFactory("asinh", functions.Asinh),
+	"atan":                         InstantVectorTransformationFunctionOperatorFactory("atan", functions.Atan),
+	"atanh":                        InstantVectorTransformationFunctionOperatorFactory("atanh", functions.Atanh),
+	"avg_over_time":                FunctionOverRangeVectorOperatorFactory("avg_over_time", functions.AvgOverTime),
+	"ceil":                         InstantVectorTransformationFunctionOperatorFactory("ceil", functions.Ceil),
+	"changes":                      FunctionOverRangeVectorOperatorFactory("changes", functions.Changes),
+	"clamp":                        ClampFunctionOperatorFactory,
+	"clamp_max":                    ClampMinMaxFunctionOperatorFactory("clamp_max", false),
+	"clamp_min":                    ClampMinMaxFunctionOperatorFactory("clamp_min", true),
+	"cos":                          InstantVectorTransformationFunctionOperatorFactory("cos", functions.Cos),
+	"cosh":                         InstantVectorTransformationFunctionOperatorFactory("cosh", functions.Cosh),
+	"count_over_time":              FunctionOverRangeVectorOperatorFactory("count_over_time", functions.CountOverTime),
+	"day_of_month":                 TimeTransformationFunctionOperatorFactory("day_of_month", functions.DayOfMonth),
+	"day_of_week":                  TimeTransformationFunctionOperatorFactory("day_of_week", functions.DayOfWeek),
+	"day_of_year":                  TimeTransformationFunctionOperatorFactory("day_of_year", functions.DayOfYear),
+	"days_in_month":                TimeTransformationFunctionOperatorFactory("days_in_month", functions.DaysInMonth),
+	"deg":                          InstantVectorTransformationFunctionOperatorFactory("deg", functions.Deg),
+	"delta":                        FunctionOverRangeVectorOperatorFactory("delta", functions.Delta),
+	"deriv":                        FunctionOverRangeVectorOperatorFactory("deriv", functions.Deriv),
+	"double_exponential_smoothing": DoubleExponentialSmoothingFactory,
+	"exp":                          InstantVectorTransformationFunctionOperatorFactory("exp", functions.Exp),
+	"floor":                        InstantVectorTransformationFunctionOperatorFactory("floor", functions.Floor),
+	"histogram_avg":                InstantVectorTransformationFunctionOperatorFactory("histogram_avg", functions.HistogramAvg),
+	"histogram_count":              InstantVectorTransformationFunctionOperatorFactory("histogram_count", functions.HistogramCount),
+	"histogram_fraction":           HistogramFractionFunctionOperatorFactory,
+	"histogram_quantile":           HistogramQuantileFunctionOperatorFactory,
+	"histogram_stddev":             InstantVectorTransformationFunctionOperatorFactory("histogram_stddev", functions.HistogramStdDevStdVar(true)),
+	"histogram_stdvar":             InstantVectorTransformationFunctionOperatorFactory("histogram_stdvar", functions.HistogramStdDevStdVar(false)),
+	"histogram_sum":                InstantVectorTransformationFunctionOperatorFactory("histogram_sum", functions.HistogramSum),
+	"hour":                         TimeTransformationFunctionOperatorFactory("hour", functions.Hour),
+	"idelta":                       FunctionOverRangeVectorOperatorFactory("idelta", functions.Idelta),
+	"increase":                     FunctionOverRangeVectorOperatorFactory("increase", functions.Increase),
+	"irate":                        FunctionOverRangeVectorOperatorFactory("irate", functions.Irate),
+	"label_join":                   LabelJoinFunctionOperatorFactory,
+	"label_replace":                LabelReplaceFunctionOperatorFactory,
+	"last_over_time":               FunctionOverRangeVectorOperatorFactory("last_over_time", functions.LastOverTime),
+	"ln":                           InstantVectorTransformationFunctionOperatorFactory("ln", functions.Ln),
+	"log10":                        InstantVectorTransformationFunctionOperatorFactory("log10", functions.Log10),
+	"log2":                         InstantVectorTransformationFunctionOperatorFactory("log2", functions.Log2),
+	"mad_over_time":                FunctionOverRangeVectorOperatorFactory("mad_over_time", functions.MadOverTime),
+	"max_over_time":                FunctionOverRangeVectorOperatorFactory("max_over_time", functions.MaxOverTime),
+	"min_over_time":                FunctionOverRangeVectorOperatorFactory("min_over_time", functions.MinOverTime),
+	"minute":                       TimeTransformationFunctionOperatorFactory("minute", functions.Minute),
+	"month":                        TimeTransformationFunctionOperatorFactory("month", functions.Month),
+	"predict_linear":               PredictLinearFactory,
+	"present_over_time":            FunctionOverRangeVectorOperatorFactory("present_over_time", functions.PresentOverTime),
+	"quantile_over_time":           QuantileOverTimeFactory,
+	"rad":                          InstantVectorTransformationFunctionOperatorFactory("rad", functions.Rad),
+	"rate":                         FunctionOverRangeVectorOperatorFactory("rate", functions.Rate),
+	"resets":                       FunctionOverRangeVectorOperatorFactory("resets", functions.Resets),
+	"round":                        RoundFunctionOperatorFactory,
+	"sgn":                          InstantVectorTransformationFunctionOperatorFactory("sgn", functions.Sgn),
+	"sin":                          InstantVectorTransformationFunctionOperatorFactory("sin", functions.Sin),
+	"sinh":                         InstantVectorTransformationFunctionOperatorFactory("sinh", functions.Sinh),
+	"sort":                         SortOperatorFactory(false),
+	"sort_by_label":                SortByLabelOperatorFactory(false),
+	"sort_by_label_desc":           SortByLabelOperatorFactory(true),
+	"sort_desc":                    SortOperatorFactory(true),
+	"sqrt":                         InstantVectorTransformationFunctionOperatorFactory("sqrt", functions.Sqrt),
+	"stddev_over_time":             FunctionOverRangeVectorOperatorFactory("stddev_over_time", functions.StddevOverTime),
+	"stdvar_over_time":             FunctionOverRangeVectorOperatorFactory("stdvar_over_time", functions.StdvarOverTime),
+	"sum_over_time":                FunctionOverRangeVectorOperatorFactory("sum_over_time", functions.SumOverTime),
+	"tan":                          InstantVectorTransformationFunctionOperatorFactory("tan", functions.Tan),
+	"tanh":                         InstantVectorTransformationFunctionOperatorFactory("tanh", functions.Tanh),
+	"timestamp":                    TimestampFunctionOperatorFactory,
+	"vector":                       scalarToInstantVectorOperatorFactory,
+	"year":                         TimeTransformationFunctionOperatorFactory("year", functions.Year),
 }
 
 func RegisterInstantVectorFunctionOperatorFactory(functionName string, factory InstantVectorFunctionOperatorFactory) error {
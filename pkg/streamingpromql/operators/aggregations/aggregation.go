@@ -21,6 +21,7 @@ import (
 	"github.com/grafana/mimir/pkg/streamingpromql/compat"
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
 	"github.com/grafana/mimir/pkg/streamingpromql/operators"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 )
 
@@ -31,12 +32,17 @@ type Aggregation struct {
 	Without                  bool
 	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
 
+	// If Spiller is not nil, series for groups other than the one currently being computed are written to disk
+	// rather than accumulated into their group when the query's memory consumption is close to its limit.
+	Spiller *spill.Spiller
+
 	aggregationGroupFactory AggregationGroupFactory
 
 	Annotations *annotations.Annotations
 
-	metricNames        *operators.MetricNames
-	currentSeriesIndex int
+	metricNames          *operators.MetricNames
+	currentSeriesIndex   int // The index of the series currently being accumulated, used to emit annotations.
+	nextInnerSeriesIndex int // The index of the next series to read from Inner.
 
 	expressionPosition posrange.PositionRange
 	emitAnnotationFunc types.EmitAnnotationFunc
@@ -105,14 +111,27 @@ type group struct {
 
 	// The aggregation for this group of series.
 	aggregation AggregationGroup
+
+	// Series for this group that were written to disk rather than accumulated, in the order they were read.
+	spilledSeries []spilledSeries
+}
+
+type spilledSeries struct {
+	ref         spill.Ref
+	seriesIndex int
 }
 
 var _ types.InstantVectorOperator = &Aggregation{}
+var _ spill.Spillable = &Aggregation{}
 
 var groupPool = zeropool.New(func() *group {
 	return &group{}
 })
 
+func (a *Aggregation) EnableSpilling(spiller *spill.Spiller) {
+	a.Spiller = spiller
+}
+
 func (a *Aggregation) ExpressionPosition() posrange.PositionRange {
 	return a.expressionPosition
 }
@@ -241,6 +260,22 @@ func (a *Aggregation) NextSeries(ctx context.Context) (types.InstantVectorSeries
 }
 
 func (a *Aggregation) accumulateUntilGroupComplete(ctx context.Context, g *group) error {
+	// Accumulate any series for this group that were written to disk while we were completing earlier groups.
+	// These series were read after all series already accumulated into this group, and before any series we
+	// haven't read yet, so accumulating them now preserves the order series are accumulated in.
+	for _, spilled := range g.spilledSeries {
+		s, err := a.Spiller.Read(spilled.ref)
+		if err != nil {
+			return err
+		}
+
+		if err := a.accumulateSeries(s, g, spilled.seriesIndex); err != nil {
+			return err
+		}
+	}
+
+	g.spilledSeries = nil
+
 	for g.remainingSeriesCount > 0 {
 		s, err := a.Inner.NextSeries(ctx)
 		if err != nil {
@@ -253,13 +288,38 @@ func (a *Aggregation) accumulateUntilGroupComplete(ctx context.Context, g *group
 
 		thisSeriesGroup := a.remainingInnerSeriesToGroup[0]
 		a.remainingInnerSeriesToGroup = a.remainingInnerSeriesToGroup[1:]
-		if err := thisSeriesGroup.aggregation.AccumulateSeries(s, a.TimeRange, a.MemoryConsumptionTracker, a.emitAnnotationFunc, thisSeriesGroup.remainingSeriesCount); err != nil {
+		seriesIndex := a.nextInnerSeriesIndex
+		a.nextInnerSeriesIndex++
+
+		if thisSeriesGroup != g && (len(thisSeriesGroup.spilledSeries) > 0 || a.Spiller.ShouldSpill()) {
+			// This series' group won't be returned until later, and accumulating this series into it now would hold
+			// it in memory until then, so write it to disk instead.
+			// Once we've done this for a group, we must continue to do so for all of its remaining series, so that
+			// they're accumulated in the order we read them.
+			ref, err := a.Spiller.Write(s)
+			if err != nil {
+				return err
+			}
+
+			thisSeriesGroup.spilledSeries = append(thisSeriesGroup.spilledSeries, spilledSeries{ref: ref, seriesIndex: seriesIndex})
+			continue
+		}
+
+		if err := a.accumulateSeries(s, thisSeriesGroup, seriesIndex); err != nil {
 			return err
 		}
-		thisSeriesGroup.remainingSeriesCount--
+	}
+	return nil
+}
+
+func (a *Aggregation) accumulateSeries(s types.InstantVectorSeriesData, g *group, seriesIndex int) error {
+	a.currentSeriesIndex = seriesIndex
 
-		a.currentSeriesIndex++
+	if err := g.aggregation.AccumulateSeries(s, a.TimeRange, a.MemoryConsumptionTracker, a.emitAnnotationFunc, g.remainingSeriesCount); err != nil {
+		return err
 	}
+
+	g.remainingSeriesCount--
 	return nil
 }
 
@@ -107,3 +107,47 @@ func GroupLabelsBytesFunc(grouping []string, without bool) SeriesToGroupLabelsBy
 }
 
 var groupToSingleSeriesLabelsBytesFunc = func(_ labels.Labels) []byte { return nil }
+
+// filterPoints removes the points in data for which keep returns false, and returns any slices that are left empty to the pool.
+// The filtering is done in place, so data must not be used after calling filterPoints.
+func filterPoints(data types.InstantVectorSeriesData, keep func(t int64) bool, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) types.InstantVectorSeriesData {
+	outputFloatCount := 0
+	for _, p := range data.Floats {
+		if keep(p.T) {
+			data.Floats[outputFloatCount] = p
+			outputFloatCount++
+		}
+	}
+
+	outputHistogramCount := 0
+	for idx, p := range data.Histograms {
+		if !keep(p.T) {
+			continue
+		}
+
+		data.Histograms[outputHistogramCount] = p
+
+		if idx > outputHistogramCount {
+			// Remove the histogram from the original point to ensure that it's not mutated unexpectedly when the HPoint slice is reused.
+			data.Histograms[idx].H = nil
+		}
+
+		outputHistogramCount++
+	}
+
+	if outputFloatCount > 0 {
+		data.Floats = data.Floats[:outputFloatCount]
+	} else {
+		types.FPointSlicePool.Put(data.Floats, memoryConsumptionTracker)
+		data.Floats = nil
+	}
+
+	if outputHistogramCount > 0 {
+		data.Histograms = data.Histograms[:outputHistogramCount]
+	} else {
+		types.HPointSlicePool.Put(data.Histograms, memoryConsumptionTracker)
+		data.Histograms = nil
+	}
+
+	return data
+}
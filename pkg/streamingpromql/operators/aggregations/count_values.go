@@ -18,6 +18,7 @@ import (
 	"github.com/prometheus/prometheus/promql/parser/posrange"
 
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 )
 
@@ -28,12 +29,13 @@ type CountValues struct {
 	Grouping                 []string // If this is a 'without' aggregation, NewCountValues will ensure that this slice contains __name__.
 	Without                  bool
 	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	Spiller                  *spill.Spiller
 
 	expressionPosition posrange.PositionRange
 
 	resolvedLabelName string
 
-	series [][]promql.FPoint
+	series []countValuesOutputSeries
 
 	// Reuse instances used to generate series labels rather than recreating them every time.
 	labelsBuilder     *labels.Builder
@@ -42,6 +44,7 @@ type CountValues struct {
 }
 
 var _ types.InstantVectorOperator = &CountValues{}
+var _ spill.Spillable = &CountValues{}
 
 func NewCountValues(
 	inner types.InstantVectorOperator,
@@ -69,12 +72,23 @@ func NewCountValues(
 	}
 }
 
+func (c *CountValues) EnableSpilling(spiller *spill.Spiller) {
+	c.Spiller = spiller
+}
+
 type countValuesSeries struct {
 	labels           labels.Labels
 	outputPointCount int
 	count            []int // One entry per timestamp.
 }
 
+type countValuesOutputSeries struct {
+	points []promql.FPoint
+
+	spilled    bool
+	spilledRef spill.Ref // Only set if spilled is true.
+}
+
 var countValuesSeriesPool = sync.Pool{
 	New: func() interface{} {
 		return &countValuesSeries{}
@@ -126,7 +140,7 @@ func (c *CountValues) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadat
 	}
 
 	outputMetadata := types.GetSeriesMetadataSlice(len(accumulator))
-	c.series = make([][]promql.FPoint, 0, len(accumulator))
+	c.series = make([]countValuesOutputSeries, 0, len(accumulator))
 
 	for _, s := range accumulator {
 		outputMetadata = append(outputMetadata, types.SeriesMetadata{Labels: s.labels})
@@ -136,7 +150,16 @@ func (c *CountValues) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadat
 			return nil, err
 		}
 
-		c.series = append(c.series, points)
+		if c.Spiller.ShouldSpill() {
+			ref, err := c.Spiller.Write(types.InstantVectorSeriesData{Floats: points})
+			if err != nil {
+				return nil, err
+			}
+
+			c.series = append(c.series, countValuesOutputSeries{spilled: true, spilledRef: ref})
+		} else {
+			c.series = append(c.series, countValuesOutputSeries{points: points})
+		}
 
 		types.IntSlicePool.Put(s.count, c.MemoryConsumptionTracker)
 		s.count = nil
@@ -232,10 +255,14 @@ func (c *CountValues) NextSeries(_ context.Context) (types.InstantVectorSeriesDa
 		return types.InstantVectorSeriesData{}, types.EOS
 	}
 
-	p := c.series[0]
+	s := c.series[0]
 	c.series = c.series[1:]
 
-	return types.InstantVectorSeriesData{Floats: p}, nil
+	if s.spilled {
+		return c.Spiller.Read(s.spilledRef)
+	}
+
+	return types.InstantVectorSeriesData{Floats: s.points}, nil
 }
 
 func (c *CountValues) ExpressionPosition() posrange.PositionRange {
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/prometheus/prometheus/blob/main/promql/engine.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Prometheus Authors
+
+package aggregations
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+	"github.com/prometheus/prometheus/util/annotations"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// LimitRatio implements limit_ratio().
+//
+// Like Prometheus' engine, the ratio is evaluated at the first time step of the query, and used for all time steps.
+// Whether a series is returned by limit_ratio() then only depends on the hash of its labels, so LimitRatio drops the
+// series that aren't returned using the series metadata alone, and never needs to buffer series data. Grouping has
+// no effect on the result.
+type LimitRatio struct {
+	Inner                    types.InstantVectorOperator
+	Param                    types.ScalarOperator
+	TimeRange                types.QueryTimeRange
+	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+
+	annotations        *annotations.Annotations
+	expressionPosition posrange.PositionRange
+
+	ratio float64 // Clamped to [-1, 1].
+
+	innerSeriesReturned []bool // One entry per series produced by Inner.
+	nextInnerSeriesIdx  int
+	seriesToReturn      int
+	seriesReturned      int
+}
+
+var _ types.InstantVectorOperator = &LimitRatio{}
+
+func NewLimitRatio(
+	inner types.InstantVectorOperator,
+	param types.ScalarOperator,
+	timeRange types.QueryTimeRange,
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker,
+	annotations *annotations.Annotations,
+	expressionPosition posrange.PositionRange,
+) *LimitRatio {
+	return &LimitRatio{
+		Inner:                    inner,
+		Param:                    param,
+		TimeRange:                timeRange,
+		MemoryConsumptionTracker: memoryConsumptionTracker,
+		annotations:              annotations,
+		expressionPosition:       expressionPosition,
+	}
+}
+
+func (l *LimitRatio) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
+	if err := l.getRatio(ctx); err != nil {
+		return nil, err
+	}
+
+	innerSeries, err := l.Inner.SeriesMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l.innerSeriesReturned, err = types.BoolSlicePool.Get(len(innerSeries), l.MemoryConsumptionTracker)
+	if err != nil {
+		return nil, err
+	}
+
+	l.innerSeriesReturned = l.innerSeriesReturned[:len(innerSeries)]
+	outputSeries := innerSeries[:0]
+
+	for idx, series := range innerSeries {
+		l.innerSeriesReturned[idx] = shouldSample(l.ratio, sampleOffset(series.Labels))
+
+		if l.innerSeriesReturned[idx] {
+			outputSeries = append(outputSeries, series)
+		}
+	}
+
+	l.seriesToReturn = len(outputSeries)
+
+	return outputSeries, nil
+}
+
+// getRatio evaluates the ratio at the first time step, like Prometheus' engine does.
+func (l *LimitRatio) getRatio(ctx context.Context) error {
+	paramValues, err := l.Param.GetValues(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer types.FPointSlicePool.Put(paramValues.Samples, l.MemoryConsumptionTracker)
+
+	ratio := paramValues.Samples[0].F
+
+	switch {
+	case math.IsNaN(ratio):
+		return fmt.Errorf("ratio value %v for limit_ratio is NaN", ratio)
+	case ratio < -1:
+		l.annotations.Add(annotations.NewInvalidRatioWarning(ratio, -1, l.Param.ExpressionPosition()))
+		ratio = -1
+	case ratio > 1:
+		l.annotations.Add(annotations.NewInvalidRatioWarning(ratio, 1, l.Param.ExpressionPosition()))
+		ratio = 1
+	}
+
+	l.ratio = ratio
+
+	return nil
+}
+
+// sampleOffset returns a value in [0, 1] derived from the hash of lbls.
+//
+// This must match the behaviour of HashRatioSampler in Prometheus' engine.
+func sampleOffset(lbls labels.Labels) float64 {
+	return float64(lbls.Hash()) / float64(math.MaxUint64)
+}
+
+// shouldSample returns true if a point from a series with the given offset should be returned by limit_ratio(ratio, ...).
+func shouldSample(ratio float64, offset float64) bool {
+	// If ratio >= 0, return series with offsets in the range [0, ratio).
+	// If ratio < 0, return the complement of the series returned for 1+ratio: series with offsets in the range [1+ratio, 1].
+	return (ratio >= 0 && offset < ratio) || (ratio < 0 && offset >= 1+ratio)
+}
+
+func (l *LimitRatio) NextSeries(ctx context.Context) (types.InstantVectorSeriesData, error) {
+	if l.seriesReturned >= l.seriesToReturn {
+		return types.InstantVectorSeriesData{}, types.EOS
+	}
+
+	for {
+		data, err := l.Inner.NextSeries(ctx)
+		if err != nil {
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		returned := l.innerSeriesReturned[l.nextInnerSeriesIdx]
+		l.nextInnerSeriesIdx++
+
+		if !returned {
+			// We don't need this series, return its slices to the pool now.
+			types.PutInstantVectorSeriesData(data, l.MemoryConsumptionTracker)
+			continue
+		}
+
+		l.seriesReturned++
+
+		return data, nil
+	}
+}
+
+func (l *LimitRatio) ExpressionPosition() posrange.PositionRange {
+	return l.expressionPosition
+}
+
+func (l *LimitRatio) Close() {
+	l.Inner.Close()
+	l.Param.Close()
+
+	types.BoolSlicePool.Put(l.innerSeriesReturned, l.MemoryConsumptionTracker)
+	l.innerSeriesReturned = nil
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/prometheus/prometheus/blob/main/promql/engine.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Prometheus Authors
+
+package aggregations
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// LimitK implements limitk().
+//
+// limitk() returns the first k series of each group at each time step, in the order they are produced by the inner operator.
+// Like topk() and bottomk(), k is evaluated at each time step.
+// Whether a point is returned therefore only depends on the series before it, so LimitK can filter points as series are read,
+// without buffering any series data.
+type LimitK struct {
+	Inner                    types.InstantVectorOperator
+	Param                    types.ScalarOperator
+	TimeRange                types.QueryTimeRange
+	Grouping                 []string // If this is a 'without' aggregation, NewLimitK will ensure that this slice contains __name__.
+	Without                  bool
+	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+
+	expressionPosition posrange.PositionRange
+	k                  []int64 // Maximum number of series to return at each time step for each group
+
+	groups                      []*limitKGroup
+	remainingInnerSeriesToGroup []*limitKGroup // One entry per series produced by Inner, value is the group for that series
+}
+
+var _ types.InstantVectorOperator = &LimitK{}
+
+type limitKGroup struct {
+	remainingSeries int     // Number of series from Inner that belong to this group and have not been read yet
+	seriesReturned  []int64 // One entry per time step, number of series that have returned a point at that time step. nil if every series in the group is returned unchanged.
+}
+
+func NewLimitK(
+	inner types.InstantVectorOperator,
+	param types.ScalarOperator,
+	timeRange types.QueryTimeRange,
+	grouping []string,
+	without bool,
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker,
+	expressionPosition posrange.PositionRange,
+) *LimitK {
+	if without {
+		grouping = append(grouping, labels.MetricName)
+	}
+
+	slices.Sort(grouping)
+
+	return &LimitK{
+		Inner:                    inner,
+		Param:                    param,
+		TimeRange:                timeRange,
+		Grouping:                 grouping,
+		Without:                  without,
+		MemoryConsumptionTracker: memoryConsumptionTracker,
+		expressionPosition:       expressionPosition,
+	}
+}
+
+func (l *LimitK) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
+	minK, maxK, err := l.getK(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxK < 1 {
+		// Nothing will be returned at any time step, so don't bother loading any series.
+		return nil, nil
+	}
+
+	innerSeries, err := l.Inner.SeriesMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*limitKGroup{}
+	groupLabelsBytesFunc := GroupLabelsBytesFunc(l.Grouping, l.Without)
+	l.remainingInnerSeriesToGroup = make([]*limitKGroup, 0, len(innerSeries))
+
+	for _, series := range innerSeries {
+		groupLabelsString := groupLabelsBytesFunc(series.Labels)
+		g, groupExists := groups[string(groupLabelsString)] // Important: don't extract the string(...) call here - passing it directly allows us to avoid allocating it.
+
+		if !groupExists {
+			g = &limitKGroup{}
+			groups[string(groupLabelsString)] = g
+			l.groups = append(l.groups, g)
+		}
+
+		g.remainingSeries++
+		l.remainingInnerSeriesToGroup = append(l.remainingInnerSeriesToGroup, g)
+	}
+
+	for _, g := range l.groups {
+		if int64(g.remainingSeries) <= minK {
+			// Every series in this group will be returned unchanged, so there's no need to count the series returned at each time step.
+			continue
+		}
+
+		g.seriesReturned, err = types.Int64SlicePool.Get(l.TimeRange.StepCount, l.MemoryConsumptionTracker)
+		if err != nil {
+			return nil, err
+		}
+
+		g.seriesReturned = g.seriesReturned[:l.TimeRange.StepCount]
+	}
+
+	// limitk() returns a subset of the input series, unchanged, so we can return the input series' metadata as-is.
+	// Series that end up not having any points returned will be dropped from the final query result.
+	return innerSeries, nil
+}
+
+// getK populates k and returns the smallest and largest value of k across all time steps.
+func (l *LimitK) getK(ctx context.Context) (int64, int64, error) {
+	paramValues, err := l.Param.GetValues(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer types.FPointSlicePool.Put(paramValues.Samples, l.MemoryConsumptionTracker)
+
+	l.k, err = types.Int64SlicePool.Get(l.TimeRange.StepCount, l.MemoryConsumptionTracker)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	l.k = l.k[:l.TimeRange.StepCount]
+	minK, maxK := int64(math.MaxInt64), int64(0)
+
+	for stepIdx := range l.TimeRange.StepCount {
+		v := paramValues.Samples[stepIdx].F
+
+		if !convertibleToInt64(v) {
+			return 0, 0, fmt.Errorf("scalar parameter %v for limitk overflows int64", v)
+		}
+
+		l.k[stepIdx] = max(int64(v), 0) // Ignore any negative values.
+		minK = min(minK, l.k[stepIdx])
+		maxK = max(maxK, l.k[stepIdx])
+	}
+
+	return minK, maxK, nil
+}
+
+func convertibleToInt64(v float64) bool {
+	return v <= math.MaxInt64 && v >= math.MinInt64
+}
+
+func (l *LimitK) NextSeries(ctx context.Context) (types.InstantVectorSeriesData, error) {
+	if len(l.remainingInnerSeriesToGroup) == 0 {
+		return types.InstantVectorSeriesData{}, types.EOS
+	}
+
+	data, err := l.Inner.NextSeries(ctx)
+	if err != nil {
+		return types.InstantVectorSeriesData{}, err
+	}
+
+	g := l.remainingInnerSeriesToGroup[0]
+	l.remainingInnerSeriesToGroup = l.remainingInnerSeriesToGroup[1:]
+	g.remainingSeries--
+
+	if g.seriesReturned == nil {
+		return data, nil
+	}
+
+	// Each time step has either a float or a histogram, so we can filter floats and histograms independently.
+	data = filterPoints(data, func(t int64) bool { return l.shouldReturnPoint(g, t) }, l.MemoryConsumptionTracker)
+
+	if g.remainingSeries == 0 {
+		types.Int64SlicePool.Put(g.seriesReturned, l.MemoryConsumptionTracker)
+		g.seriesReturned = nil
+	}
+
+	return data, nil
+}
+
+func (l *LimitK) shouldReturnPoint(g *limitKGroup, t int64) bool {
+	idx := l.TimeRange.PointIndex(t)
+
+	if g.seriesReturned[idx] >= l.k[idx] {
+		return false
+	}
+
+	g.seriesReturned[idx]++
+	return true
+}
+
+func (l *LimitK) ExpressionPosition() posrange.PositionRange {
+	return l.expressionPosition
+}
+
+func (l *LimitK) Close() {
+	l.Inner.Close()
+	l.Param.Close()
+
+	types.Int64SlicePool.Put(l.k, l.MemoryConsumptionTracker)
+	l.k = nil
+
+	for _, g := range l.groups {
+		types.Int64SlicePool.Put(g.seriesReturned, l.MemoryConsumptionTracker)
+		g.seriesReturned = nil
+	}
+
+	l.groups = nil
+}
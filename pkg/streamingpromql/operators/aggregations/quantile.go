@@ -17,6 +17,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/streamingpromql/floats"
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 	"github.com/grafana/mimir/pkg/util/pool"
 )
@@ -30,6 +31,8 @@ type QuantileAggregation struct {
 	Annotations              *annotations.Annotations
 }
 
+var _ spill.Spillable = &QuantileAggregation{}
+
 func NewQuantileAggregation(
 	inner types.InstantVectorOperator,
 	param types.ScalarOperator,
@@ -95,6 +98,10 @@ func (q *QuantileAggregation) Close() {
 	q.Aggregation.Close()
 }
 
+func (q *QuantileAggregation) EnableSpilling(spiller *spill.Spiller) {
+	q.Aggregation.EnableSpilling(spiller)
+}
+
 func (q *QuantileAggregation) ExpressionPosition() posrange.PositionRange {
 	return q.Aggregation.ExpressionPosition()
 }
@@ -20,6 +20,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
 	"github.com/grafana/mimir/pkg/streamingpromql/operators/aggregations"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 	"github.com/grafana/mimir/pkg/util/pool"
 )
@@ -32,6 +33,7 @@ type RangeQuery struct {
 	Grouping                 []string // If this is a 'without' aggregation, New will ensure that this slice contains __name__.
 	Without                  bool
 	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	Spiller                  *spill.Spiller
 	IsTopK                   bool // If false, this is operator is for bottomk().
 
 	expressionPosition posrange.PositionRange
@@ -51,6 +53,7 @@ type RangeQuery struct {
 }
 
 var _ types.InstantVectorOperator = &RangeQuery{}
+var _ spill.Spillable = &RangeQuery{}
 
 func (t *RangeQuery) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
 	if err := t.getK(ctx); err != nil {
@@ -200,6 +203,25 @@ func (t *RangeQuery) ensureCurrentGroupPopulated(ctx context.Context) error {
 	t.currentGroup = t.remainingGroups[0]
 	t.remainingGroups = t.remainingGroups[1:]
 
+	// Accumulate any series for this group that were written to disk while we were populating earlier groups.
+	// These series were read after all series already accumulated into this group, and before any series we
+	// haven't read yet, so accumulating them now preserves the order series are returned in.
+	for _, ref := range t.currentGroup.spilledSeries {
+		d, err := t.Spiller.Read(ref)
+		if err != nil {
+			return err
+		}
+
+		err = t.accumulateIntoGroup(d, t.currentGroup)
+		types.FPointSlicePool.Put(d.Floats, t.MemoryConsumptionTracker)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	t.currentGroup.spilledSeries = nil
+
 	for t.currentGroup.seriesRead() < t.currentGroup.totalSeries {
 		if err := t.readNextSeries(ctx); err != nil {
 			return err
@@ -245,9 +267,25 @@ func (t *RangeQuery) readNextSeries(ctx context.Context) error {
 
 	// topk() and bottomk() ignore histograms, so return the HPoint slice to the pool now.
 	types.HPointSlicePool.Put(nextSeries.Histograms, t.MemoryConsumptionTracker)
+	nextSeries.Histograms = nil
 
 	g := t.remainingInnerSeriesToGroup[0]
 	t.remainingInnerSeriesToGroup = t.remainingInnerSeriesToGroup[1:]
+
+	if g != t.currentGroup && (len(g.spilledSeries) > 0 || t.Spiller.ShouldSpill()) {
+		// This series' group won't be returned until later, and accumulating this series into it now would hold
+		// it in memory until then, so write it to disk instead.
+		// Once we've done this for a group, we must continue to do so for all of its remaining series, so that
+		// they're accumulated in the order we read them.
+		ref, err := t.Spiller.Write(nextSeries)
+		if err != nil {
+			return err
+		}
+
+		g.spilledSeries = append(g.spilledSeries, ref)
+		return nil
+	}
+
 	if err := t.accumulateIntoGroup(nextSeries, g); err != nil {
 		return err
 	}
@@ -267,7 +305,7 @@ func (t *RangeQuery) emitIgnoredHistogramsAnnotation() {
 }
 
 func (t *RangeQuery) accumulateIntoGroup(data types.InstantVectorSeriesData, g *rangeQueryGroup) error {
-	groupSeriesIndex := g.seriesRead()
+	groupSeriesIndex := len(g.series)
 
 	if g.seriesForTimestamps == nil {
 		var err error
@@ -399,6 +437,10 @@ func (t *RangeQuery) returnSeriesToPool(series rangeQuerySeries) {
 	types.Float64SlicePool.Put(series.values, t.MemoryConsumptionTracker)
 }
 
+func (t *RangeQuery) EnableSpilling(spiller *spill.Spiller) {
+	t.Spiller = spiller
+}
+
 func (t *RangeQuery) ExpressionPosition() posrange.PositionRange {
 	return t.expressionPosition
 }
@@ -414,13 +456,14 @@ type rangeQueryGroup struct {
 	lastSeriesIndex int // The index (from the inner operator) of the last series that will contribute to this group
 	totalSeries     int // The total number of series that will contribute to this group
 
-	series []rangeQuerySeries
+	series        []rangeQuerySeries
+	spilledSeries []spill.Ref // Series read but not yet accumulated into series above because they were written to disk, in the order they were read.
 
 	seriesForTimestamps [][]int // One entry per timestamp, each entry contains a slice of the series indices (from `series` above) used as a min-/max-heap for the current 'best' values seen (highest for topk / lowest for bottomk)
 }
 
 func (g *rangeQueryGroup) seriesRead() int {
-	return len(g.series)
+	return len(g.series) + len(g.spilledSeries)
 }
 
 type rangeQuerySeries struct {
@@ -39,6 +39,8 @@ func New(
 	// This requires us to hold the entire output result in memory.
 	// For range queries, no such requirement exists, and so we don't need to hold the
 	// entire output result in memory.
+	// The instant query implementation only holds a single value for each series it might return, so only the
+	// range query implementation writes series to disk when spilling is enabled.
 	// This is a significant enough difference that it is easier and clearer to have separate
 	// operators for the two cases rather than try to satisfy both in the one implementation.
 	if timeRange.StepCount == 1 {
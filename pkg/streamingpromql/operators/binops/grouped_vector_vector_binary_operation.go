@@ -19,6 +19,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
 	"github.com/grafana/mimir/pkg/streamingpromql/operators"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 )
 
@@ -32,6 +33,7 @@ type GroupedVectorVectorBinaryOperation struct {
 	Op                       parser.ItemType
 	ReturnBool               bool
 	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	Spiller                  *spill.Spiller
 
 	VectorMatching parser.VectorMatching
 
@@ -55,6 +57,7 @@ type GroupedVectorVectorBinaryOperation struct {
 }
 
 var _ types.InstantVectorOperator = &GroupedVectorVectorBinaryOperation{}
+var _ spill.Spillable = &GroupedVectorVectorBinaryOperation{}
 
 type groupedBinaryOperationOutputSeries struct {
 	manySide *manySide
@@ -195,8 +198,8 @@ func (g *GroupedVectorVectorBinaryOperation) SeriesMetadata(ctx context.Context)
 	g.sortSeries(allMetadata, allSeries)
 	g.remainingSeries = allSeries
 
-	g.oneSideBuffer = operators.NewInstantVectorOperatorBuffer(g.oneSide, oneSideSeriesUsed, g.MemoryConsumptionTracker)
-	g.manySideBuffer = operators.NewInstantVectorOperatorBuffer(g.manySide, manySideSeriesUsed, g.MemoryConsumptionTracker)
+	g.oneSideBuffer = operators.NewInstantVectorOperatorBuffer(g.oneSide, oneSideSeriesUsed, g.MemoryConsumptionTracker, g.Spiller)
+	g.manySideBuffer = operators.NewInstantVectorOperatorBuffer(g.manySide, manySideSeriesUsed, g.MemoryConsumptionTracker, g.Spiller)
 
 	return allMetadata, nil
 }
@@ -701,6 +704,10 @@ func (g *GroupedVectorVectorBinaryOperation) oneSideHandedness() string {
 	}
 }
 
+func (g *GroupedVectorVectorBinaryOperation) EnableSpilling(spiller *spill.Spiller) {
+	g.Spiller = spiller
+}
+
 func (g *GroupedVectorVectorBinaryOperation) ExpressionPosition() posrange.PositionRange {
 	return g.expressionPosition
 }
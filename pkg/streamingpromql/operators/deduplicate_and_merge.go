@@ -54,7 +54,7 @@ func (d *DeduplicateAndMerge) SeriesMetadata(ctx context.Context) ([]types.Serie
 	d.groups = groups
 	types.PutSeriesMetadataSlice(innerMetadata)
 
-	d.buffer = NewInstantVectorOperatorBuffer(d.Inner, nil, d.MemoryConsumptionTracker)
+	d.buffer = NewInstantVectorOperatorBuffer(d.Inner, nil, d.MemoryConsumptionTracker, nil)
 
 	return outputMetadata, nil
 }
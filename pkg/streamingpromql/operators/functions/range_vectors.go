@@ -6,6 +6,7 @@
 package functions
 
 import (
+	"fmt"
 	"math"
 
 	"github.com/prometheus/prometheus/model/histogram"
@@ -815,3 +816,125 @@ func quantileOverTime(step *types.RangeVectorStepData, _ float64, args []types.S
 
 	return floats.Quantile(q, values), true, nil, nil
 }
+
+var MadOverTime = FunctionOverRangeVectorDefinition{
+	SeriesMetadataFunction:         DropSeriesName,
+	StepFunc:                       madOverTime,
+	NeedsSeriesNamesForAnnotations: true,
+}
+
+func madOverTime(step *types.RangeVectorStepData, _ float64, _ []types.ScalarData, _ types.QueryTimeRange, emitAnnotation types.EmitAnnotationFunc, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) (float64, bool, *histogram.FloatHistogram, error) {
+	if !step.Floats.Any() {
+		return 0, false, nil, nil
+	}
+
+	if step.Histograms.Any() {
+		emitAnnotation(annotations.NewHistogramIgnoredInMixedRangeInfo)
+	}
+
+	head, tail := step.Floats.UnsafePoints()
+	values, err := types.Float64SlicePool.Get(len(head)+len(tail), memoryConsumptionTracker)
+	if err != nil {
+		return 0, false, nil, err
+	}
+
+	defer types.Float64SlicePool.Put(values, memoryConsumptionTracker)
+
+	for _, p := range head {
+		values = append(values, p.F)
+	}
+
+	for _, p := range tail {
+		values = append(values, p.F)
+	}
+
+	median := floats.Quantile(0.5, values)
+
+	// floats.Quantile may reorder values, but that doesn't matter here: we only need the median of the absolute deviations.
+	for i, v := range values {
+		values[i] = math.Abs(v - median)
+	}
+
+	return floats.Quantile(0.5, values), true, nil, nil
+}
+
+var DoubleExponentialSmoothing = FunctionOverRangeVectorDefinition{
+	SeriesMetadataFunction:         DropSeriesName,
+	StepFunc:                       doubleExponentialSmoothing,
+	NeedsSeriesNamesForAnnotations: true,
+}
+
+func doubleExponentialSmoothing(step *types.RangeVectorStepData, _ float64, args []types.ScalarData, timeRange types.QueryTimeRange, emitAnnotation types.EmitAnnotationFunc, _ *limiting.MemoryConsumptionTracker) (float64, bool, *histogram.FloatHistogram, error) {
+	if !step.Floats.Any() && !step.Histograms.Any() {
+		return 0, false, nil, nil
+	}
+
+	pointIndex := timeRange.PointIndex(step.StepT)
+	smoothingFactor := args[0].Samples[pointIndex].F
+	trendFactor := args[1].Samples[pointIndex].F
+
+	// Check that the input parameters are valid.
+	if smoothingFactor <= 0 || smoothingFactor >= 1 {
+		return 0, false, nil, fmt.Errorf("invalid smoothing factor. Expected: 0 < sf < 1, got: %f", smoothingFactor)
+	}
+
+	if trendFactor <= 0 || trendFactor >= 1 {
+		return 0, false, nil, fmt.Errorf("invalid trend factor. Expected: 0 < tf < 1, got: %f", trendFactor)
+	}
+
+	if step.Histograms.Any() && step.Floats.Any() {
+		emitAnnotation(annotations.NewHistogramIgnoredInMixedRangeInfo)
+	}
+
+	head, tail := step.Floats.UnsafePoints()
+
+	// Can't do the smoothing operation with less than two points.
+	if len(head)+len(tail) < 2 {
+		return 0, false, nil, nil
+	}
+
+	pointAt := func(i int) float64 {
+		if i < len(head) {
+			return head[i].F
+		}
+
+		return tail[i-len(head)].F
+	}
+
+	var s0, s1, b float64
+
+	// Set initial values.
+	s1 = pointAt(0)
+	b = pointAt(1) - pointAt(0)
+
+	// Run the smoothing operation.
+	for i := 1; i < len(head)+len(tail); i++ {
+		// Scale the raw value against the smoothing factor.
+		x := smoothingFactor * pointAt(i)
+
+		// Scale the last smoothed value with the trend at this point.
+		b = calcTrendValue(i-1, trendFactor, s0, s1, b)
+		y := (1 - smoothingFactor) * (s1 + b)
+
+		s0, s1 = s1, x+y
+	}
+
+	return s1, true, nil, nil
+}
+
+// calcTrendValue calculates the trend value at the given index i in raw data d.
+// This is somewhat analogous to the slope of the trend at the given index.
+// The argument "tf" is the trend factor.
+// The argument "s0" is the computed smoothed value.
+// The argument "s1" is the computed trend factor.
+// The argument "b" is the raw input value.
+func calcTrendValue(i int, tf, s0, s1, b float64) float64 {
+	if i == 0 {
+		return b
+	}
+
+	x := tf * (s1 - s0)
+	y := (1 - tf) * b
+
+	return x + y
+}
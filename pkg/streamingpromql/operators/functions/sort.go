@@ -11,6 +11,7 @@ import (
 	"github.com/prometheus/prometheus/promql/parser/posrange"
 
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 )
 
@@ -18,14 +19,24 @@ type Sort struct {
 	Inner                    types.InstantVectorOperator
 	Descending               bool
 	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	Spiller                  *spill.Spiller
 
 	expressionPosition posrange.PositionRange
 
-	allData        []types.InstantVectorSeriesData // Series data, in the order to be returned
-	seriesReturned int                             // Number of series already returned by NextSeries
+	allData        []sortSeries // Series data, in the order to be returned
+	seriesReturned int          // Number of series already returned by NextSeries
+}
+
+type sortSeries struct {
+	data  types.InstantVectorSeriesData
+	value float64 // The value to sort by.
+
+	spilled    bool
+	spilledRef spill.Ref // Only set if spilled is true.
 }
 
 var _ types.InstantVectorOperator = &Sort{}
+var _ spill.Spillable = &Sort{}
 
 func NewSort(
 	inner types.InstantVectorOperator,
@@ -41,13 +52,17 @@ func NewSort(
 	}
 }
 
+func (s *Sort) EnableSpilling(spiller *spill.Spiller) {
+	s.Spiller = spiller
+}
+
 func (s *Sort) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
 	allSeries, err := s.Inner.SeriesMetadata(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	s.allData = make([]types.InstantVectorSeriesData, len(allSeries))
+	s.allData = make([]sortSeries, len(allSeries))
 
 	for idx := range allSeries {
 		d, err := s.Inner.NextSeries(ctx)
@@ -65,7 +80,19 @@ func (s *Sort) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, erro
 			return nil, fmt.Errorf("expected series %v to have at most one point, but it had %v", allSeries[idx], pointCount)
 		}
 
-		s.allData[idx] = d
+		s.allData[idx].value = getValueForSorting(d)
+
+		if s.Spiller.ShouldSpill() {
+			ref, err := s.Spiller.Write(d)
+			if err != nil {
+				return nil, err
+			}
+
+			s.allData[idx].spilled = true
+			s.allData[idx].spilledRef = ref
+		} else {
+			s.allData[idx].data = d
+		}
 	}
 
 	if s.Descending {
@@ -78,7 +105,7 @@ func (s *Sort) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, erro
 }
 
 type sortAscending struct {
-	data   []types.InstantVectorSeriesData
+	data   []sortSeries
 	series []types.SeriesMetadata
 }
 
@@ -87,8 +114,8 @@ func (s *sortAscending) Len() int {
 }
 
 func (s *sortAscending) Less(idx1, idx2 int) bool {
-	v1 := getValueForSorting(s.data, idx1)
-	v2 := getValueForSorting(s.data, idx2)
+	v1 := s.data[idx1].value
+	v2 := s.data[idx2].value
 
 	// NaNs always sort to the end of the list, regardless of the sort order.
 	if math.IsNaN(v1) {
@@ -106,7 +133,7 @@ func (s *sortAscending) Swap(i, j int) {
 }
 
 type sortDescending struct {
-	data   []types.InstantVectorSeriesData
+	data   []sortSeries
 	series []types.SeriesMetadata
 }
 
@@ -115,8 +142,8 @@ func (s *sortDescending) Len() int {
 }
 
 func (s *sortDescending) Less(idx1, idx2 int) bool {
-	v1 := getValueForSorting(s.data, idx1)
-	v2 := getValueForSorting(s.data, idx2)
+	v1 := s.data[idx1].value
+	v2 := s.data[idx2].value
 
 	// NaNs always sort to the end of the list, regardless of the sort order.
 	if math.IsNaN(v1) {
@@ -133,9 +160,7 @@ func (s *sortDescending) Swap(i, j int) {
 	s.series[i], s.series[j] = s.series[j], s.series[i]
 }
 
-func getValueForSorting(allData []types.InstantVectorSeriesData, seriesIdx int) float64 {
-	series := allData[seriesIdx]
-
+func getValueForSorting(series types.InstantVectorSeriesData) float64 {
 	if len(series.Floats) == 1 {
 		return series.Floats[0].F
 	}
@@ -148,10 +173,14 @@ func (s *Sort) NextSeries(_ context.Context) (types.InstantVectorSeriesData, err
 		return types.InstantVectorSeriesData{}, types.EOS
 	}
 
-	data := s.allData[s.seriesReturned]
+	series := s.allData[s.seriesReturned]
 	s.seriesReturned++
 
-	return data, nil
+	if series.spilled {
+		return s.Spiller.Read(series.spilledRef)
+	}
+
+	return series.data, nil
 }
 
 func (s *Sort) ExpressionPosition() posrange.PositionRange {
@@ -161,5 +190,6 @@ func (s *Sort) ExpressionPosition() posrange.PositionRange {
 func (s *Sort) Close() {
 	s.Inner.Close()
 
-	// We don't need to do anything with s.allData here: we passed ownership of the data to the calling operator when we returned it in NextSeries.
+	// We don't need to do anything with s.allData here: we passed ownership of the data to the calling operator when we returned it in NextSeries,
+	// and any spilled data is removed when the query's Spiller is closed.
 }
@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package functions
+
+import (
+	"context"
+	"sort"
+
+	"github.com/facette/natsort"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/operators"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// SortByLabel implements sort_by_label() and sort_by_label_desc() for instant queries.
+//
+// Unlike sort() and sort_desc(), the output order only depends on the series' labels, so we can
+// determine it from the series metadata alone and only need to buffer series data that arrives
+// before it is needed.
+type SortByLabel struct {
+	Inner                    types.InstantVectorOperator
+	Descending               bool
+	Labels                   []types.StringOperator
+	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+
+	expressionPosition posrange.PositionRange
+
+	buffer         *operators.InstantVectorOperatorBuffer
+	outputOrder    []int // Index of the inner series for each output series, in the order to be returned
+	seriesReturned int   // Number of series already returned by NextSeries
+}
+
+var _ types.InstantVectorOperator = &SortByLabel{}
+
+func NewSortByLabel(
+	inner types.InstantVectorOperator,
+	descending bool,
+	labels []types.StringOperator,
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker,
+	expressionPosition posrange.PositionRange,
+) *SortByLabel {
+	return &SortByLabel{
+		Inner:                    inner,
+		Descending:               descending,
+		Labels:                   labels,
+		MemoryConsumptionTracker: memoryConsumptionTracker,
+		expressionPosition:       expressionPosition,
+	}
+}
+
+func (s *SortByLabel) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
+	allSeries, err := s.Inner.SeriesMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sortLabels := make([]string, len(s.Labels))
+	for i, l := range s.Labels {
+		sortLabels[i] = l.GetValue()
+	}
+
+	s.outputOrder = make([]int, len(allSeries))
+	for i := range s.outputOrder {
+		s.outputOrder[i] = i
+	}
+
+	sort.Sort(&sortByLabel{
+		series:     allSeries,
+		order:      s.outputOrder,
+		labels:     sortLabels,
+		descending: s.Descending,
+	})
+
+	s.buffer = operators.NewInstantVectorOperatorBuffer(s.Inner, nil, s.MemoryConsumptionTracker, nil)
+
+	return allSeries, nil
+}
+
+type sortByLabel struct {
+	series     []types.SeriesMetadata
+	order      []int
+	labels     []string
+	descending bool
+}
+
+func (s *sortByLabel) Len() int {
+	return len(s.series)
+}
+
+func (s *sortByLabel) Less(i, j int) bool {
+	c := s.compare(s.series[i].Labels, s.series[j].Labels)
+
+	if s.descending {
+		return c > 0
+	}
+
+	return c < 0
+}
+
+func (s *sortByLabel) compare(a, b labels.Labels) int {
+	for _, l := range s.labels {
+		lv1 := a.Get(l)
+		lv2 := b.Get(l)
+
+		if lv1 == lv2 {
+			continue
+		}
+
+		if natsort.Compare(lv1, lv2) {
+			return -1
+		}
+
+		return 1
+	}
+
+	// If all labels provided as arguments were equal, sort by the full label set. This ensures a consistent ordering.
+	return labels.Compare(a, b)
+}
+
+func (s *sortByLabel) Swap(i, j int) {
+	s.series[i], s.series[j] = s.series[j], s.series[i]
+	s.order[i], s.order[j] = s.order[j], s.order[i]
+}
+
+func (s *SortByLabel) NextSeries(ctx context.Context) (types.InstantVectorSeriesData, error) {
+	if s.seriesReturned >= len(s.outputOrder) {
+		return types.InstantVectorSeriesData{}, types.EOS
+	}
+
+	d, err := s.buffer.GetSeries(ctx, s.outputOrder[s.seriesReturned:s.seriesReturned+1])
+	if err != nil {
+		return types.InstantVectorSeriesData{}, err
+	}
+
+	s.seriesReturned++
+
+	return d[0], nil
+}
+
+func (s *SortByLabel) ExpressionPosition() posrange.PositionRange {
+	return s.expressionPosition
+}
+
+func (s *SortByLabel) Close() {
+	s.Inner.Close()
+
+	for _, l := range s.Labels {
+		l.Close()
+	}
+
+	if s.buffer != nil {
+		s.buffer.Close()
+	}
+}
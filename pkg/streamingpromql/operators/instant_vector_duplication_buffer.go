@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package operators
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// InstantVectorDuplicationBuffer evaluates an instant vector operator once and returns its results to multiple consumers.
+//
+// It is used to avoid evaluating the same expression multiple times when it appears more than once in a query
+// (eg. both sides of `a / on() group_left sum(a)` select `a`).
+//
+// Each consumer reads series in the order produced by the source operator, independently of other consumers.
+// Series that have been read from the source by one consumer but not yet by others are buffered until every
+// open consumer has read them. The last consumer to read a series receives the source's data, and every other
+// consumer receives a copy.
+type InstantVectorDuplicationBuffer struct {
+	source                   types.InstantVectorOperator
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker
+
+	consumers []*InstantVectorDuplicationConsumer
+
+	seriesMetadata            []types.SeriesMetadata // nil if the source's metadata has not been loaded yet, or has been returned to all consumers.
+	seriesMetadataLoaded      bool
+	seriesCount               int
+	consumersAwaitingMetadata int
+	nextSourceSeriesIndex     int
+	firstBufferedSeriesIndex  int
+	buffer                    []types.InstantVectorSeriesData // Data for series firstBufferedSeriesIndex to nextSourceSeriesIndex-1.
+	openConsumers             int
+	sourceClosed              bool
+}
+
+func NewInstantVectorDuplicationBuffer(source types.InstantVectorOperator, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) *InstantVectorDuplicationBuffer {
+	return &InstantVectorDuplicationBuffer{
+		source:                   source,
+		memoryConsumptionTracker: memoryConsumptionTracker,
+	}
+}
+
+// AddConsumer returns a new operator that returns the results of the source operator.
+//
+// All consumers must be added before any consumer is used, and every consumer must be closed.
+func (b *InstantVectorDuplicationBuffer) AddConsumer() *InstantVectorDuplicationConsumer {
+	c := &InstantVectorDuplicationConsumer{buffer: b}
+	b.consumers = append(b.consumers, c)
+	b.consumersAwaitingMetadata++
+	b.openConsumers++
+
+	return c
+}
+
+func (b *InstantVectorDuplicationBuffer) getSeriesMetadata(ctx context.Context, consumer *InstantVectorDuplicationConsumer) ([]types.SeriesMetadata, error) {
+	if !b.seriesMetadataLoaded {
+		var err error
+		b.seriesMetadata, err = b.source.SeriesMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		b.seriesMetadataLoaded = true
+		b.seriesCount = len(b.seriesMetadata)
+	}
+
+	consumer.hasSeriesMetadata = true
+	b.consumersAwaitingMetadata--
+
+	if b.consumersAwaitingMetadata == 0 {
+		// This is the last consumer to need the metadata, so it can have the source's slice.
+		metadata := b.seriesMetadata
+		b.seriesMetadata = nil
+		return metadata, nil
+	}
+
+	// Consumers are free to modify the slice returned to them, so each consumer gets its own copy.
+	// Labels are immutable, so there's no need to copy them.
+	metadata := types.GetSeriesMetadataSlice(len(b.seriesMetadata))
+	metadata = append(metadata, b.seriesMetadata...)
+
+	return metadata, nil
+}
+
+func (b *InstantVectorDuplicationBuffer) nextSeries(ctx context.Context, consumer *InstantVectorDuplicationConsumer) (types.InstantVectorSeriesData, error) {
+	seriesIndex := consumer.nextSeriesIndex
+
+	if seriesIndex >= b.seriesCount {
+		return types.InstantVectorSeriesData{}, types.EOS
+	}
+
+	if seriesIndex == b.nextSourceSeriesIndex {
+		d, err := b.source.NextSeries(ctx)
+		if err != nil {
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		b.buffer = append(b.buffer, d)
+		b.nextSourceSeriesIndex++
+	}
+
+	consumer.nextSeriesIndex++
+	bufferIndex := seriesIndex - b.firstBufferedSeriesIndex
+
+	if b.isSeriesNeededByOtherConsumer(seriesIndex, consumer) {
+		return cloneInstantVectorSeriesData(b.buffer[bufferIndex], b.memoryConsumptionTracker)
+	}
+
+	// No other consumer needs this series, so we can return it directly.
+	// Every consumer reads series in order, so this must be the first buffered series.
+	d := b.buffer[bufferIndex]
+	b.buffer[bufferIndex] = types.InstantVectorSeriesData{}
+	b.buffer = b.buffer[1:]
+	b.firstBufferedSeriesIndex++
+
+	return d, nil
+}
+
+func (b *InstantVectorDuplicationBuffer) isSeriesNeededByOtherConsumer(seriesIndex int, consumer *InstantVectorDuplicationConsumer) bool {
+	for _, other := range b.consumers {
+		if other != consumer && !other.closed && other.nextSeriesIndex <= seriesIndex {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *InstantVectorDuplicationBuffer) closeConsumer(consumer *InstantVectorDuplicationConsumer) {
+	if consumer.closed {
+		return
+	}
+
+	consumer.closed = true
+	b.openConsumers--
+
+	if !consumer.hasSeriesMetadata {
+		b.consumersAwaitingMetadata--
+
+		if b.consumersAwaitingMetadata == 0 && b.seriesMetadata != nil {
+			types.PutSeriesMetadataSlice(b.seriesMetadata)
+			b.seriesMetadata = nil
+		}
+	}
+
+	if b.openConsumers == 0 {
+		b.close()
+		return
+	}
+
+	// Return any buffered series that are no longer needed by any remaining consumer.
+	firstNeededSeriesIndex := b.nextSourceSeriesIndex
+	for _, other := range b.consumers {
+		if !other.closed {
+			firstNeededSeriesIndex = min(firstNeededSeriesIndex, other.nextSeriesIndex)
+		}
+	}
+
+	for b.firstBufferedSeriesIndex < firstNeededSeriesIndex {
+		types.PutInstantVectorSeriesData(b.buffer[0], b.memoryConsumptionTracker)
+		b.buffer[0] = types.InstantVectorSeriesData{}
+		b.buffer = b.buffer[1:]
+		b.firstBufferedSeriesIndex++
+	}
+}
+
+func (b *InstantVectorDuplicationBuffer) close() {
+	if b.sourceClosed {
+		return
+	}
+
+	b.sourceClosed = true
+	b.source.Close()
+
+	for _, d := range b.buffer {
+		types.PutInstantVectorSeriesData(d, b.memoryConsumptionTracker)
+	}
+
+	b.buffer = nil
+
+	if b.seriesMetadata != nil {
+		types.PutSeriesMetadataSlice(b.seriesMetadata)
+		b.seriesMetadata = nil
+	}
+}
+
+func cloneInstantVectorSeriesData(d types.InstantVectorSeriesData, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+	clone := types.InstantVectorSeriesData{}
+
+	if len(d.Floats) > 0 {
+		var err error
+		clone.Floats, err = types.FPointSlicePool.Get(len(d.Floats), memoryConsumptionTracker)
+		if err != nil {
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		clone.Floats = append(clone.Floats, d.Floats...)
+	}
+
+	if len(d.Histograms) > 0 {
+		var err error
+		clone.Histograms, err = types.HPointSlicePool.Get(len(d.Histograms), memoryConsumptionTracker)
+		if err != nil {
+			types.FPointSlicePool.Put(clone.Floats, memoryConsumptionTracker)
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		// Consumers may modify histograms in place, so each consumer needs its own copy.
+		for _, p := range d.Histograms {
+			clone.Histograms = append(clone.Histograms, promql.HPoint{T: p.T, H: p.H.Copy()})
+		}
+	}
+
+	return clone, nil
+}
+
+// InstantVectorDuplicationConsumer is an operator that returns the results of the source operator of an InstantVectorDuplicationBuffer.
+type InstantVectorDuplicationConsumer struct {
+	buffer *InstantVectorDuplicationBuffer
+
+	hasSeriesMetadata bool
+	nextSeriesIndex   int
+	closed            bool
+}
+
+var _ types.InstantVectorOperator = &InstantVectorDuplicationConsumer{}
+
+func (c *InstantVectorDuplicationConsumer) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
+	return c.buffer.getSeriesMetadata(ctx, c)
+}
+
+func (c *InstantVectorDuplicationConsumer) NextSeries(ctx context.Context) (types.InstantVectorSeriesData, error) {
+	return c.buffer.nextSeries(ctx, c)
+}
+
+func (c *InstantVectorDuplicationConsumer) ExpressionPosition() posrange.PositionRange {
+	return c.buffer.source.ExpressionPosition()
+}
+
+func (c *InstantVectorDuplicationConsumer) Close() {
+	c.buffer.closeConsumer(c)
+}
@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package operators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/testutils"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+func TestInstantVectorDuplicationBuffer_ConsumersReadingAtDifferentRates(t *testing.T) {
+	ctx := context.Background()
+	memoryConsumptionTracker := limiting.NewMemoryConsumptionTracker(0, nil)
+	inner := newClosableTestOperator(t, memoryConsumptionTracker, 3)
+
+	buffer := NewInstantVectorDuplicationBuffer(inner, memoryConsumptionTracker)
+	first := buffer.AddConsumer()
+	second := buffer.AddConsumer()
+
+	firstMetadata, err := first.SeriesMetadata(ctx)
+	require.NoError(t, err)
+	require.Equal(t, testutils.LabelsToSeriesMetadata(inner.Series), firstMetadata)
+
+	secondMetadata, err := second.SeriesMetadata(ctx)
+	require.NoError(t, err)
+	require.Equal(t, testutils.LabelsToSeriesMetadata(inner.Series), secondMetadata)
+
+	// Modifying the metadata returned to one consumer should not affect the other.
+	firstMetadata[0].Labels = labels.FromStrings("series", "modified")
+	require.Equal(t, labels.FromStrings("series", "0"), secondMetadata[0].Labels)
+
+	// Read all series with the first consumer: the data should be buffered for the second consumer.
+	for i := range 3 {
+		d, err := first.NextSeries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, float64(i), d.Floats[0].F)
+		require.Equal(t, float64(i), d.Histograms[0].H.Sum)
+
+		// Modify the data to ensure the second consumer receives an unmodified copy.
+		d.Floats[0].F = -1
+		d.Histograms[0].H.Sum = -1
+		types.PutInstantVectorSeriesData(d, memoryConsumptionTracker)
+	}
+
+	_, err = first.NextSeries(ctx)
+	require.Equal(t, types.EOS, err)
+	require.Len(t, buffer.buffer, 3)
+
+	for i := range 3 {
+		d, err := second.NextSeries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, float64(i), d.Floats[0].F)
+		require.Equal(t, float64(i), d.Histograms[0].H.Sum)
+		require.Len(t, buffer.buffer, 2-i, "series should be removed from the buffer once read by all consumers")
+		types.PutInstantVectorSeriesData(d, memoryConsumptionTracker)
+	}
+
+	_, err = second.NextSeries(ctx)
+	require.Equal(t, types.EOS, err)
+
+	first.Close()
+	require.False(t, inner.closed, "source should not be closed while a consumer is still open")
+	second.Close()
+	require.True(t, inner.closed)
+	require.Zero(t, memoryConsumptionTracker.CurrentEstimatedMemoryConsumptionBytes)
+}
+
+func TestInstantVectorDuplicationBuffer_ConsumerClosedEarly(t *testing.T) {
+	ctx := context.Background()
+	memoryConsumptionTracker := limiting.NewMemoryConsumptionTracker(0, nil)
+	inner := newClosableTestOperator(t, memoryConsumptionTracker, 3)
+
+	buffer := NewInstantVectorDuplicationBuffer(inner, memoryConsumptionTracker)
+	first := buffer.AddConsumer()
+	second := buffer.AddConsumer()
+	third := buffer.AddConsumer()
+
+	// Close the third consumer before it reads anything: it should not prevent series from being released.
+	third.Close()
+
+	metadata, err := first.SeriesMetadata(ctx)
+	require.NoError(t, err)
+	types.PutSeriesMetadataSlice(metadata)
+
+	metadata, err = second.SeriesMetadata(ctx)
+	require.NoError(t, err)
+	types.PutSeriesMetadataSlice(metadata)
+
+	for range 2 {
+		d, err := first.NextSeries(ctx)
+		require.NoError(t, err)
+		types.PutInstantVectorSeriesData(d, memoryConsumptionTracker)
+	}
+
+	require.Len(t, buffer.buffer, 2)
+
+	// Closing the second consumer should release the series buffered for it.
+	second.Close()
+	require.Empty(t, buffer.buffer)
+	require.False(t, inner.closed)
+
+	d, err := first.NextSeries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, float64(2), d.Floats[0].F)
+	require.Empty(t, buffer.buffer)
+	types.PutInstantVectorSeriesData(d, memoryConsumptionTracker)
+
+	first.Close()
+	require.True(t, inner.closed)
+	require.Zero(t, memoryConsumptionTracker.CurrentEstimatedMemoryConsumptionBytes)
+}
+
+func TestInstantVectorDuplicationBuffer_AllConsumersClosedWithoutReading(t *testing.T) {
+	memoryConsumptionTracker := limiting.NewMemoryConsumptionTracker(0, nil)
+	inner := newClosableTestOperator(t, memoryConsumptionTracker, 3)
+
+	buffer := NewInstantVectorDuplicationBuffer(inner, memoryConsumptionTracker)
+	first := buffer.AddConsumer()
+	second := buffer.AddConsumer()
+
+	metadata, err := first.SeriesMetadata(context.Background())
+	require.NoError(t, err)
+	types.PutSeriesMetadataSlice(metadata)
+
+	first.Close()
+	second.Close()
+	require.True(t, inner.closed)
+	require.Nil(t, buffer.seriesMetadata)
+}
+
+type closableTestOperator struct {
+	TestOperator
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	closed                   bool
+}
+
+func newClosableTestOperator(t *testing.T, memoryConsumptionTracker *limiting.MemoryConsumptionTracker, seriesCount int) *closableTestOperator {
+	o := &closableTestOperator{memoryConsumptionTracker: memoryConsumptionTracker}
+
+	for i := range seriesCount {
+		floats, err := types.FPointSlicePool.Get(1, memoryConsumptionTracker)
+		require.NoError(t, err)
+		histograms, err := types.HPointSlicePool.Get(1, memoryConsumptionTracker)
+		require.NoError(t, err)
+
+		o.Series = append(o.Series, labels.FromStrings("series", string(rune('0'+i))))
+		o.Data = append(o.Data, types.InstantVectorSeriesData{
+			Floats:     append(floats, promql.FPoint{T: 0, F: float64(i)}),
+			Histograms: append(histograms, promql.HPoint{T: 1, H: &histogram.FloatHistogram{Count: 1, Sum: float64(i)}}),
+		})
+	}
+
+	return o
+}
+
+func (o *closableTestOperator) Close() {
+	for _, d := range o.Data {
+		types.PutInstantVectorSeriesData(d, o.memoryConsumptionTracker)
+	}
+
+	o.Data = nil
+	o.closed = true
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 )
 
@@ -27,18 +28,26 @@ type InstantVectorOperatorBuffer struct {
 
 	memoryConsumptionTracker *limiting.MemoryConsumptionTracker
 
+	// If spiller is not nil, series that need to be buffered are written to disk rather than held in memory
+	// once the query's memory consumption is close to its limit.
+	spiller *spill.Spiller
+
 	// Stores series read but required for later series.
 	buffer map[int]types.InstantVectorSeriesData
 
+	// Stores references to series read but required for later series that were written to disk rather than stored in buffer.
+	spilled map[int]spill.Ref
+
 	// Reused to avoid allocating on every call to getSeries.
 	output []types.InstantVectorSeriesData
 }
 
-func NewInstantVectorOperatorBuffer(source types.InstantVectorOperator, seriesUsed []bool, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) *InstantVectorOperatorBuffer {
+func NewInstantVectorOperatorBuffer(source types.InstantVectorOperator, seriesUsed []bool, memoryConsumptionTracker *limiting.MemoryConsumptionTracker, spiller *spill.Spiller) *InstantVectorOperatorBuffer {
 	return &InstantVectorOperatorBuffer{
 		source:                   source,
 		seriesUsed:               seriesUsed,
 		memoryConsumptionTracker: memoryConsumptionTracker,
+		spiller:                  spiller,
 		buffer:                   map[int]types.InstantVectorSeriesData{},
 	}
 }
@@ -75,7 +84,9 @@ func (b *InstantVectorOperatorBuffer) getSingleSeries(ctx context.Context, serie
 
 		if b.seriesUsed == nil || b.seriesUsed[b.nextIndexToRead] {
 			// We need this series later, but not right now. Store it for later.
-			b.buffer[b.nextIndexToRead] = d
+			if err := b.store(b.nextIndexToRead, d); err != nil {
+				return types.InstantVectorSeriesData{}, err
+			}
 		} else {
 			// We don't need this series at all, return the slice to the pool now.
 			types.PutInstantVectorSeriesData(d, b.memoryConsumptionTracker)
@@ -90,12 +101,37 @@ func (b *InstantVectorOperatorBuffer) getSingleSeries(ctx context.Context, serie
 		return b.source.NextSeries(ctx)
 	}
 
+	if ref, isSpilled := b.spilled[seriesIndex]; isSpilled {
+		delete(b.spilled, seriesIndex)
+		return b.spiller.Read(ref)
+	}
+
 	d := b.buffer[seriesIndex]
 	delete(b.buffer, seriesIndex)
 
 	return d, nil
 }
 
+func (b *InstantVectorOperatorBuffer) store(seriesIndex int, d types.InstantVectorSeriesData) error {
+	if !b.spiller.ShouldSpill() {
+		b.buffer[seriesIndex] = d
+		return nil
+	}
+
+	ref, err := b.spiller.Write(d)
+	if err != nil {
+		return err
+	}
+
+	if b.spilled == nil {
+		b.spilled = map[int]spill.Ref{}
+	}
+
+	b.spilled[seriesIndex] = ref
+
+	return nil
+}
+
 func (b *InstantVectorOperatorBuffer) Close() {
 	// NOTE: source is expected to be closed by the caller as often the buffer is bypassed.
 	if b.seriesUsed != nil {
@@ -46,7 +46,7 @@ func TestInstantVectorOperatorBuffer_BufferingSubsetOfInputSeries(t *testing.T)
 	seriesUsed := []bool{true, false, true, true, true}
 	memoryConsumptionTracker := limiting.NewMemoryConsumptionTracker(0, nil)
 	require.NoError(t, memoryConsumptionTracker.IncreaseMemoryConsumption(types.FPointSize*6)) // We have 6 FPoints from the inner series.
-	buffer := NewInstantVectorOperatorBuffer(inner, seriesUsed, memoryConsumptionTracker)
+	buffer := NewInstantVectorOperatorBuffer(inner, seriesUsed, memoryConsumptionTracker, nil)
 	ctx := context.Background()
 
 	// Read first series.
@@ -111,7 +111,7 @@ func TestInstantVectorOperatorBuffer_BufferingAllInputSeries(t *testing.T) {
 
 	memoryConsumptionTracker := limiting.NewMemoryConsumptionTracker(0, nil)
 	require.NoError(t, memoryConsumptionTracker.IncreaseMemoryConsumption(types.FPointSize*6)) // We have 6 FPoints from the inner series.
-	buffer := NewInstantVectorOperatorBuffer(inner, nil, memoryConsumptionTracker)
+	buffer := NewInstantVectorOperatorBuffer(inner, nil, memoryConsumptionTracker, nil)
 	ctx := context.Background()
 
 	// Read first series.
@@ -46,6 +46,48 @@ func (s *Selector) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata,
 		return nil, errors.New("invalid Selector configuration: both LookbackDelta and Range are non-zero")
 	}
 
+	hints := s.selectHints()
+
+	var err error
+	s.querier, err = s.Queryable.Querier(hints.Start, hints.End)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := s.querier.Select(ctx, true, hints, s.Matchers...)
+	s.series = newSeriesList()
+
+	for ss.Next() {
+		s.series.Add(ss.At())
+	}
+
+	return s.series.ToSeriesMetadata(), ss.Err()
+}
+
+// EstimateSeriesCount returns the number of series matching this selector in its time range.
+//
+// Only the series labels are looked up, so the count is an upper bound of the number of series
+// the selector returns, as series with no samples in the time range evaluated may be included.
+func (s *Selector) EstimateSeriesCount(ctx context.Context) (int, error) {
+	hints := s.selectHints()
+	hints.Func = "series" // Mimir only looks up the series labels for /series requests, without their chunks.
+
+	querier, err := s.Queryable.Querier(hints.Start, hints.End)
+	if err != nil {
+		return 0, err
+	}
+	defer querier.Close()
+
+	ss := querier.Select(ctx, false, hints, s.Matchers...)
+	count := 0
+	for ss.Next() {
+		count++
+	}
+
+	return count, ss.Err()
+}
+
+func (s *Selector) selectHints() *storage.SelectHints {
 	startTimestamp := s.TimeRange.StartT
 	endTimestamp := s.TimeRange.EndT
 
@@ -60,7 +102,7 @@ func (s *Selector) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata,
 	startTimestamp = startTimestamp - s.LookbackDelta.Milliseconds() - rangeMilliseconds - s.Offset + 1 // +1 to exclude samples on the lower boundary of the range (queriers work with closed intervals, we use left-open).
 	endTimestamp = endTimestamp - s.Offset
 
-	hints := &storage.SelectHints{
+	return &storage.SelectHints{
 		Start: startTimestamp,
 		End:   endTimestamp,
 		Step:  s.TimeRange.IntervalMilliseconds,
@@ -75,21 +117,6 @@ func (s *Selector) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata,
 		// ShardCount and ShardIndex are set by ingesters and store-gateways when a sharding
 		// label matcher is present, and ingesters set DisableTrimming to true.
 	}
-
-	var err error
-	s.querier, err = s.Queryable.Querier(startTimestamp, endTimestamp)
-	if err != nil {
-		return nil, err
-	}
-
-	ss := s.querier.Select(ctx, true, hints, s.Matchers...)
-	s.series = newSeriesList()
-
-	for ss.Next() {
-		s.series.Add(ss.At())
-	}
-
-	return s.series.ToSeriesMetadata(), ss.Err()
 }
 
 func (s *Selector) Next(ctx context.Context, existing chunkenc.Iterator) (chunkenc.Iterator, error) {
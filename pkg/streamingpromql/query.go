@@ -22,6 +22,7 @@ import (
 	"github.com/prometheus/prometheus/util/annotations"
 	"github.com/prometheus/prometheus/util/stats"
 
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/streamingpromql/compat"
 	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
 	"github.com/grafana/mimir/pkg/streamingpromql/operators"
@@ -30,6 +31,8 @@ import (
 	"github.com/grafana/mimir/pkg/streamingpromql/operators/binops"
 	"github.com/grafana/mimir/pkg/streamingpromql/operators/scalars"
 	"github.com/grafana/mimir/pkg/streamingpromql/operators/selectors"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
+	"github.com/grafana/mimir/pkg/streamingpromql/spill"
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 )
@@ -48,6 +51,11 @@ type Query struct {
 	memoryConsumptionTracker *limiting.MemoryConsumptionTracker
 	annotations              *annotations.Annotations
 	stats                    *types.QueryStats
+	planBuilder              *queryPlanBuilder // Only set when the query is being explained.
+	spiller                  *spill.Spiller    // nil if spilling to disk is disabled.
+
+	// Instant vector expressions that appear more than once in the query, and so are only evaluated once.
+	commonSubexpressions map[parser.Expr]*commonSubexpression
 
 	// Time range of the top-level query.
 	// Subqueries may use a different range.
@@ -56,7 +64,7 @@ type Query struct {
 	result *promql.Result
 }
 
-func newQuery(ctx context.Context, queryable storage.Queryable, opts promql.QueryOpts, qs string, start, end time.Time, interval time.Duration, engine *Engine) (*Query, error) {
+func newQuery(ctx context.Context, queryable storage.Queryable, opts promql.QueryOpts, qs string, start, end time.Time, interval time.Duration, engine *Engine, explain bool) (*Query, error) {
 	if opts == nil {
 		opts = promql.NewPrometheusQueryOpts(false, 0)
 	}
@@ -95,6 +103,14 @@ func newQuery(ctx context.Context, queryable storage.Queryable, opts promql.Quer
 		},
 	}
 
+	if explain {
+		q.planBuilder = newQueryPlanBuilder(q.memoryConsumptionTracker, lookbackDelta)
+	}
+
+	if engine.features.EnableSpillToDisk {
+		q.spiller = spill.NewSpiller(engine.features.SpillToDiskDirectory, engine.features.SpillToDiskMemoryThreshold, q.memoryConsumptionTracker, q.stats)
+	}
+
 	if q.IsInstant() {
 		q.topLevelQueryTimeRange = types.NewInstantQueryTimeRange(start)
 	} else {
@@ -105,6 +121,10 @@ func newQuery(ctx context.Context, queryable storage.Queryable, opts promql.Quer
 		}
 	}
 
+	if engine.features.EnableCommonSubexpressionElimination {
+		q.commonSubexpressions = q.findCommonSubexpressions(expr, q.topLevelQueryTimeRange)
+	}
+
 	q.root, err = q.convertToOperator(expr, q.topLevelQueryTimeRange)
 	if err != nil {
 		return nil, err
@@ -129,6 +149,21 @@ func (q *Query) convertToOperator(expr parser.Expr, timeRange types.QueryTimeRan
 }
 
 func (q *Query) convertToStringOperator(expr parser.Expr) (types.StringOperator, error) {
+	if q.planBuilder == nil {
+		return q.buildStringOperator(expr)
+	}
+
+	node := q.planBuilder.begin(expr, q.topLevelQueryTimeRange)
+	o, err := q.buildStringOperator(expr)
+	if err != nil {
+		q.planBuilder.abandon()
+		return nil, err
+	}
+
+	return q.planBuilder.finish(node, o).(types.StringOperator), nil
+}
+
+func (q *Query) buildStringOperator(expr parser.Expr) (types.StringOperator, error) {
 	if expr.Type() != parser.ValueTypeString {
 		return nil, fmt.Errorf("cannot create string operator for expression that produces a %s", parser.DocumentedType(expr.Type()))
 	}
@@ -147,6 +182,74 @@ func (q *Query) convertToStringOperator(expr parser.Expr) (types.StringOperator,
 }
 
 func (q *Query) convertToInstantVectorOperator(expr parser.Expr, timeRange types.QueryTimeRange) (types.InstantVectorOperator, error) {
+	subexpression, isCommonSubexpression := q.commonSubexpressions[expr]
+	if !isCommonSubexpression {
+		return q.convertToUniqueInstantVectorOperator(expr, timeRange)
+	}
+
+	if q.planBuilder == nil {
+		return q.buildCommonSubexpressionConsumer(subexpression, expr, timeRange)
+	}
+
+	node := q.planBuilder.begin(expr, timeRange)
+	o, err := q.buildCommonSubexpressionConsumer(subexpression, expr, timeRange)
+	if err != nil {
+		q.planBuilder.abandon()
+		return nil, err
+	}
+
+	return q.planBuilder.finish(node, o).(types.InstantVectorOperator), nil
+}
+
+// buildCommonSubexpressionConsumer returns an operator that returns the result of subexpression, converting expr to an
+// operator if this is the first occurrence of subexpression.
+func (q *Query) buildCommonSubexpressionConsumer(subexpression *commonSubexpression, expr parser.Expr, timeRange types.QueryTimeRange) (types.InstantVectorOperator, error) {
+	if subexpression.buffer == nil {
+		inner, err := q.convertToUniqueInstantVectorOperator(expr, timeRange)
+		if err != nil {
+			return nil, err
+		}
+
+		subexpression.buffer = operators.NewInstantVectorDuplicationBuffer(inner, q.memoryConsumptionTracker)
+	}
+
+	return subexpression.buffer.AddConsumer(), nil
+}
+
+func (q *Query) convertToUniqueInstantVectorOperator(expr parser.Expr, timeRange types.QueryTimeRange) (types.InstantVectorOperator, error) {
+	if q.planBuilder == nil {
+		o, err := q.buildInstantVectorOperator(expr, timeRange)
+		if err != nil {
+			return nil, err
+		}
+
+		q.enableSpilling(o)
+		return o, nil
+	}
+
+	node := q.planBuilder.begin(expr, timeRange)
+	o, err := q.buildInstantVectorOperator(expr, timeRange)
+	if err != nil {
+		q.planBuilder.abandon()
+		return nil, err
+	}
+
+	q.enableSpilling(o)
+	return q.planBuilder.finish(node, o).(types.InstantVectorOperator), nil
+}
+
+// enableSpilling enables spilling to disk for o if spilling is enabled for this query and o supports it.
+func (q *Query) enableSpilling(o types.Operator) {
+	if q.spiller == nil {
+		return
+	}
+
+	if s, ok := o.(spill.Spillable); ok {
+		s.EnableSpilling(q.spiller)
+	}
+}
+
+func (q *Query) buildInstantVectorOperator(expr parser.Expr, timeRange types.QueryTimeRange) (types.InstantVectorOperator, error) {
 	if expr.Type() != parser.ValueTypeVector {
 		return nil, fmt.Errorf("cannot create instant vector operator for expression that produces a %s", parser.DocumentedType(expr.Type()))
 	}
@@ -178,6 +281,10 @@ func (q *Query) convertToInstantVectorOperator(expr parser.Expr, timeRange types
 			return nil, compat.NewNotSupportedError(fmt.Sprintf("'%s' aggregation disabled", e.Op.String()))
 		}
 
+		if q.engine.remoteExecutionClient != nil && remoteexec.IsShardableAggregation(e) {
+			return q.buildShardedAggregation(e, timeRange)
+		}
+
 		inner, err := q.convertToInstantVectorOperator(e.Expr, timeRange)
 		if err != nil {
 			return nil, err
@@ -207,6 +314,20 @@ func (q *Query) convertToInstantVectorOperator(expr parser.Expr, timeRange types
 					q.annotations,
 					e.PosRange,
 				)
+			case parser.LIMITK:
+				param, err := q.convertToScalarOperator(e.Param, timeRange)
+				if err != nil {
+					return nil, err
+				}
+
+				return aggregations.NewLimitK(inner, param, timeRange, e.Grouping, e.Without, q.memoryConsumptionTracker, e.PosRange), nil
+			case parser.LIMIT_RATIO:
+				param, err := q.convertToScalarOperator(e.Param, timeRange)
+				if err != nil {
+					return nil, err
+				}
+
+				return aggregations.NewLimitRatio(inner, param, timeRange, q.memoryConsumptionTracker, q.annotations, e.PosRange), nil
 			case parser.COUNT_VALUES:
 				param, err := q.convertToStringOperator(e.Param)
 				if err != nil {
@@ -371,6 +492,21 @@ func (q *Query) convertFunctionCallToInstantVectorOperator(e *parser.Call, timeR
 }
 
 func (q *Query) convertToRangeVectorOperator(expr parser.Expr, timeRange types.QueryTimeRange) (types.RangeVectorOperator, error) {
+	if q.planBuilder == nil {
+		return q.buildRangeVectorOperator(expr, timeRange)
+	}
+
+	node := q.planBuilder.begin(expr, timeRange)
+	o, err := q.buildRangeVectorOperator(expr, timeRange)
+	if err != nil {
+		q.planBuilder.abandon()
+		return nil, err
+	}
+
+	return q.planBuilder.finish(node, o).(types.RangeVectorOperator), nil
+}
+
+func (q *Query) buildRangeVectorOperator(expr parser.Expr, timeRange types.QueryTimeRange) (types.RangeVectorOperator, error) {
 	if expr.Type() != parser.ValueTypeMatrix {
 		return nil, fmt.Errorf("cannot create range vector operator for expression that produces a %s", parser.DocumentedType(expr.Type()))
 	}
@@ -396,42 +532,7 @@ func (q *Query) convertToRangeVectorOperator(expr parser.Expr, timeRange types.Q
 			return nil, compat.NewNotSupportedError("subquery")
 		}
 
-		// Subqueries are evaluated as a single range query with steps aligned to Unix epoch time 0.
-		// They are not evaluated as queries aligned to the individual step timestamps.
-		// See https://www.robustperception.io/promql-subqueries-and-alignment/ for an explanation.
-		// Subquery evaluation aligned to step timestamps is not supported by Prometheus, but may be
-		// introduced in the future in https://github.com/prometheus/prometheus/pull/9114.
-		//
-		// While this makes subqueries simpler to implement and more efficient in most cases, it does
-		// mean we could waste time evaluating steps that won't be used if the subquery range is less
-		// than the parent query step. For example, if the parent query is running with a step of 1h,
-		// and the subquery is for a 10m range with 1m steps, then we'll evaluate ~50m of steps that
-		// won't be used.
-		// This is relatively uncommon, and Prometheus' engine does the same thing. In the future, we
-		// could be smarter about this if it turns out to be a big problem.
-		step := e.Step.Milliseconds()
-
-		if step == 0 {
-			step = q.engine.noStepSubqueryIntervalFn(e.Range.Milliseconds())
-		}
-
-		start := timeRange.StartT
-		end := timeRange.EndT
-
-		if e.Timestamp != nil {
-			start = *e.Timestamp
-			end = *e.Timestamp
-		}
-
-		// Find the first timestamp inside the subquery range that is aligned to the step.
-		alignedStart := step * ((start - e.OriginalOffset.Milliseconds() - e.Range.Milliseconds()) / step)
-		if alignedStart < start-e.OriginalOffset.Milliseconds()-e.Range.Milliseconds() {
-			alignedStart += step
-		}
-
-		end = end - e.OriginalOffset.Milliseconds()
-
-		subqueryTimeRange := types.NewRangeQueryTimeRange(timestamp.Time(alignedStart), timestamp.Time(end), time.Duration(step)*time.Millisecond)
+		subqueryTimeRange := q.subqueryTimeRange(e, timeRange)
 		inner, err := q.convertToInstantVectorOperator(e.Expr, subqueryTimeRange)
 		if err != nil {
 			return nil, err
@@ -459,7 +560,62 @@ func (q *Query) convertToRangeVectorOperator(expr parser.Expr, timeRange types.Q
 	}
 }
 
+// subqueryTimeRange returns the time range used to evaluate the inner expression of e, given the parent time range.
+func (q *Query) subqueryTimeRange(e *parser.SubqueryExpr, timeRange types.QueryTimeRange) types.QueryTimeRange {
+	// Subqueries are evaluated as a single range query with steps aligned to Unix epoch time 0.
+	// They are not evaluated as queries aligned to the individual step timestamps.
+	// See https://www.robustperception.io/promql-subqueries-and-alignment/ for an explanation.
+	// Subquery evaluation aligned to step timestamps is not supported by Prometheus, but may be
+	// introduced in the future in https://github.com/prometheus/prometheus/pull/9114.
+	//
+	// While this makes subqueries simpler to implement and more efficient in most cases, it does
+	// mean we could waste time evaluating steps that won't be used if the subquery range is less
+	// than the parent query step. For example, if the parent query is running with a step of 1h,
+	// and the subquery is for a 10m range with 1m steps, then we'll evaluate ~50m of steps that
+	// won't be used.
+	// This is relatively uncommon, and Prometheus' engine does the same thing. In the future, we
+	// could be smarter about this if it turns out to be a big problem.
+	step := e.Step.Milliseconds()
+
+	if step == 0 {
+		step = q.engine.noStepSubqueryIntervalFn(e.Range.Milliseconds())
+	}
+
+	start := timeRange.StartT
+	end := timeRange.EndT
+
+	if e.Timestamp != nil {
+		start = *e.Timestamp
+		end = *e.Timestamp
+	}
+
+	// Find the first timestamp inside the subquery range that is aligned to the step.
+	alignedStart := step * ((start - e.OriginalOffset.Milliseconds() - e.Range.Milliseconds()) / step)
+	if alignedStart < start-e.OriginalOffset.Milliseconds()-e.Range.Milliseconds() {
+		alignedStart += step
+	}
+
+	end = end - e.OriginalOffset.Milliseconds()
+
+	return types.NewRangeQueryTimeRange(timestamp.Time(alignedStart), timestamp.Time(end), time.Duration(step)*time.Millisecond)
+}
+
 func (q *Query) convertToScalarOperator(expr parser.Expr, timeRange types.QueryTimeRange) (types.ScalarOperator, error) {
+	if q.planBuilder == nil {
+		return q.buildScalarOperator(expr, timeRange)
+	}
+
+	node := q.planBuilder.begin(expr, timeRange)
+	o, err := q.buildScalarOperator(expr, timeRange)
+	if err != nil {
+		q.planBuilder.abandon()
+		return nil, err
+	}
+
+	return q.planBuilder.finish(node, o).(types.ScalarOperator), nil
+}
+
+func (q *Query) buildScalarOperator(expr parser.Expr, timeRange types.QueryTimeRange) (types.ScalarOperator, error) {
 	if expr.Type() != parser.ValueTypeScalar {
 		return nil, fmt.Errorf("cannot create scalar operator for expression that produces a %s", parser.DocumentedType(expr.Type()))
 	}
@@ -544,11 +700,126 @@ func (q *Query) convertFunctionCallToScalarOperator(e *parser.Call, timeRange ty
 	return factory(args, q.memoryConsumptionTracker, q.annotations, e.PosRange, timeRange)
 }
 
+// buildShardedAggregation returns an operator that evaluates e by sending the aggregation of each shard of
+// e's inner expression to other queriers, and then aggregating the results from each shard.
+func (q *Query) buildShardedAggregation(e *parser.AggregateExpr, timeRange types.QueryTimeRange) (types.InstantVectorOperator, error) {
+	// @ start() and @ end() always refer to the time range of the whole query, even within subqueries.
+	exprs, err := remoteexec.ShardedExpressions(e, q.engine.remoteExecutionShardCount, timestamp.FromTime(q.statement.Start), timestamp.FromTime(q.statement.End))
+	if err != nil {
+		return nil, err
+	}
+
+	inner := remoteexec.NewInstantVectorOperator(
+		q.engine.remoteExecutionClient,
+		exprs,
+		timeRange,
+		q.statement.LookbackDelta,
+		q.memoryConsumptionTracker,
+		q.annotations,
+		q.stats,
+		e.Expr.PositionRange(),
+	)
+
+	return aggregations.NewAggregation(
+		inner,
+		timeRange,
+		e.Grouping,
+		e.Without,
+		remoteexec.MergeOperation(e.Op),
+		q.memoryConsumptionTracker,
+		q.annotations,
+		e.PosRange,
+	)
+}
+
 func (q *Query) IsInstant() bool {
 	return q.statement.Start == q.statement.End && q.statement.Interval == 0
 }
 
 func (q *Query) Exec(ctx context.Context) *promql.Result {
+	if err := q.execute(ctx, q.populateResult); err != nil {
+		return &promql.Result{Err: err}
+	}
+
+	// To make comparing to Prometheus' engine easier, only return the annotations if there are some, otherwise, return nil.
+	if len(*q.annotations) > 0 {
+		q.result.Warnings = *q.annotations
+	}
+
+	return q.result
+}
+
+// CanExecStreaming returns true if the query can be evaluated with ExecStreaming.
+func (q *Query) CanExecStreaming() bool {
+	return !q.IsInstant() && q.statement.Expr.Type() == parser.ValueTypeVector
+}
+
+// ExecStreaming evaluates a range query that produces an instant vector, calling onSeries with each series as
+// it is produced, rather than materialising the whole result in memory like Exec does.
+//
+// Unlike Exec, series are passed to onSeries in the order they are produced rather than sorted by their labels,
+// and series with no points are skipped. The points in each series are returned to their pools once onSeries
+// returns, so onSeries must not retain them.
+func (q *Query) ExecStreaming(ctx context.Context, onSeries func(promql.Series) error) (annotations.Annotations, error) {
+	if !q.CanExecStreaming() {
+		return nil, fmt.Errorf("streaming evaluation is only supported for range queries that produce an instant vector, but this query produces a %s", parser.DocumentedType(q.statement.Expr.Type()))
+	}
+
+	if err := q.execute(ctx, func(ctx context.Context) error { return q.streamMatrix(ctx, onSeries) }); err != nil {
+		return nil, err
+	}
+
+	// Like Exec, only return the annotations if there are some.
+	if len(*q.annotations) > 0 {
+		return *q.annotations, nil
+	}
+
+	return nil, nil
+}
+
+func (q *Query) streamMatrix(ctx context.Context, onSeries func(promql.Series) error) error {
+	root := q.root.(types.InstantVectorOperator)
+	series, err := root.SeriesMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer types.PutSeriesMetadataSlice(series)
+
+	for i, s := range series {
+		d, err := root.NextSeries(ctx)
+		if err != nil {
+			if errors.Is(err, types.EOS) {
+				return fmt.Errorf("expected %v series, but only received %v", len(series), i)
+			}
+
+			return err
+		}
+
+		if len(d.Floats) > 0 || len(d.Histograms) > 0 {
+			err = onSeries(promql.Series{
+				Metric:     s.Labels,
+				Floats:     d.Floats,
+				Histograms: d.Histograms,
+			})
+		}
+
+		types.PutInstantVectorSeriesData(d, q.memoryConsumptionTracker)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// execute runs evaluate with the query's timeout applied, the query registered with the active query tracker, and
+// logs the query's statistics once evaluate returns.
+func (q *Query) execute(ctx context.Context, evaluate func(ctx context.Context) error) error {
+	// Operators return all data they read back from disk before they're closed, so we can remove any spilled data
+	// as soon as the operators are closed.
+	defer q.closeSpiller(ctx)
 	defer q.root.Close()
 
 	ctx, cancel := context.WithCancelCause(ctx)
@@ -568,7 +839,7 @@ func (q *Query) Exec(ctx context.Context) *promql.Result {
 	if q.engine.activeQueryTracker != nil {
 		queryID, err := q.engine.activeQueryTracker.Insert(ctx, q.qs)
 		if err != nil {
-			return &promql.Result{Err: err}
+			return err
 		}
 
 		defer q.engine.activeQueryTracker.Delete(queryID)
@@ -576,11 +847,12 @@ func (q *Query) Exec(ctx context.Context) *promql.Result {
 
 	defer func() {
 		logger := spanlogger.FromContext(ctx, q.engine.logger)
-		msg := make([]interface{}, 0, 2*(3+4)) // 3 fields for all query types, plus worst case of 4 fields for range queries
+		msg := make([]interface{}, 0, 2*(4+4)) // 4 fields for all query types, plus worst case of 4 fields for range queries
 
 		msg = append(msg,
 			"msg", "query stats",
 			"estimatedPeakMemoryConsumption", q.memoryConsumptionTracker.PeakEstimatedMemoryConsumptionBytes,
+			"spilledBytes", q.stats.SpilledBytes,
 			"expr", q.qs,
 		)
 
@@ -602,18 +874,22 @@ func (q *Query) Exec(ctx context.Context) *promql.Result {
 		q.engine.estimatedPeakMemoryConsumption.Observe(float64(q.memoryConsumptionTracker.PeakEstimatedMemoryConsumptionBytes))
 	}()
 
+	return evaluate(ctx)
+}
+
+func (q *Query) populateResult(ctx context.Context) error {
 	switch q.statement.Expr.Type() {
 	case parser.ValueTypeMatrix:
 		root := q.root.(types.RangeVectorOperator)
 		series, err := root.SeriesMetadata(ctx)
 		if err != nil {
-			return &promql.Result{Err: err}
+			return err
 		}
 		defer types.PutSeriesMetadataSlice(series)
 
 		v, err := q.populateMatrixFromRangeVectorOperator(ctx, root, series)
 		if err != nil {
-			return &promql.Result{Err: err}
+			return err
 		}
 
 		q.result = &promql.Result{Value: v}
@@ -621,21 +897,21 @@ func (q *Query) Exec(ctx context.Context) *promql.Result {
 		root := q.root.(types.InstantVectorOperator)
 		series, err := root.SeriesMetadata(ctx)
 		if err != nil {
-			return &promql.Result{Err: err}
+			return err
 		}
 		defer types.PutSeriesMetadataSlice(series)
 
 		if q.IsInstant() {
 			v, err := q.populateVectorFromInstantVectorOperator(ctx, root, series)
 			if err != nil {
-				return &promql.Result{Err: err}
+				return err
 			}
 
 			q.result = &promql.Result{Value: v}
 		} else {
 			v, err := q.populateMatrixFromInstantVectorOperator(ctx, root, series)
 			if err != nil {
-				return &promql.Result{Err: err}
+				return err
 			}
 
 			q.result = &promql.Result{Value: v}
@@ -644,7 +920,7 @@ func (q *Query) Exec(ctx context.Context) *promql.Result {
 		root := q.root.(types.ScalarOperator)
 		d, err := root.GetValues(ctx)
 		if err != nil {
-			return &promql.Result{Err: err}
+			return err
 		}
 
 		if q.IsInstant() {
@@ -659,19 +935,26 @@ func (q *Query) Exec(ctx context.Context) *promql.Result {
 			q.result = &promql.Result{Value: q.populateStringFromStringOperator(str)}
 		} else {
 			// This should be caught in newQuery above
-			return &promql.Result{Err: fmt.Errorf("query expression produces a %s, but expression for range queries must produce an instant vector or scalar", parser.DocumentedType(q.statement.Expr.Type()))}
+			return fmt.Errorf("query expression produces a %s, but expression for range queries must produce an instant vector or scalar", parser.DocumentedType(q.statement.Expr.Type()))
 		}
 	default:
 		// This should be caught in newQuery above.
-		return &promql.Result{Err: compat.NewNotSupportedError(fmt.Sprintf("unsupported result type %s", parser.DocumentedType(q.statement.Expr.Type())))}
+		return compat.NewNotSupportedError(fmt.Sprintf("unsupported result type %s", parser.DocumentedType(q.statement.Expr.Type())))
 	}
 
-	// To make comparing to Prometheus' engine easier, only return the annotations if there are some, otherwise, return nil.
-	if len(*q.annotations) > 0 {
-		q.result.Warnings = *q.annotations
+	return nil
+}
+
+func (q *Query) closeSpiller(ctx context.Context) {
+	if q.spiller == nil {
+		return
 	}
 
-	return q.result
+	querier_stats.FromContext(ctx).AddSpilledBytes(q.stats.SpilledBytes)
+
+	if err := q.spiller.Close(); err != nil {
+		level.Warn(spanlogger.FromContext(ctx, q.engine.logger)).Log("msg", "failed to remove spilled series data", "err", err)
+	}
 }
 
 func (q *Query) populateStringFromStringOperator(str string) promql.String {
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// Why 1024 series per batch? It's large enough to keep the number of messages for queries with many series low,
+// while keeping the size of each message well below the default maximum gRPC message size.
+const remoteExecutionSeriesMetadataBatchSize = 1024
+
+// RemoteExecutionServer evaluates expressions sent by other queriers using remoteexec.InstantVectorOperator.
+type RemoteExecutionServer struct {
+	engine    *Engine
+	queryable storage.Queryable
+}
+
+var _ remoteexec.RemoteExecutionServer = &RemoteExecutionServer{}
+
+func NewRemoteExecutionServer(engine *Engine, queryable storage.Queryable) *RemoteExecutionServer {
+	// Expressions received from other queriers are already sharded, so always evaluate them locally rather than
+	// sending them on to other queriers again.
+	localEngine := *engine
+	localEngine.remoteExecutionClient = nil
+
+	return &RemoteExecutionServer{
+		engine:    &localEngine,
+		queryable: queryable,
+	}
+}
+
+func (s *RemoteExecutionServer) EvaluateInstantVector(req *remoteexec.EvaluateInstantVectorRequest, stream remoteexec.RemoteExecution_EvaluateInstantVectorServer) error {
+	return remoteexec.ErrorToStatus(s.evaluateInstantVector(req, stream))
+}
+
+func (s *RemoteExecutionServer) evaluateInstantVector(req *remoteexec.EvaluateInstantVectorRequest, stream remoteexec.RemoteExecution_EvaluateInstantVectorServer) error {
+	ctx := stream.Context()
+	opts := promql.NewPrometheusQueryOpts(false, time.Duration(req.LookbackDeltaMs)*time.Millisecond)
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+
+	q, err := newQuery(ctx, s.queryable, opts, req.Expr, timestamp.Time(req.StartTimestampMs), timestamp.Time(req.EndTimestampMs), interval, s.engine, false)
+	if err != nil {
+		return err
+	}
+
+	defer q.Close()
+
+	if q.statement.Expr.Type() != parser.ValueTypeVector {
+		return fmt.Errorf("expression produces a %s, but remote execution requires an expression that produces an instant vector", parser.DocumentedType(q.statement.Expr.Type()))
+	}
+
+	if err := q.execute(ctx, func(ctx context.Context) error { return streamInstantVector(ctx, q, stream) }); err != nil {
+		return err
+	}
+
+	warnings, infos := remoteexec.AnnotationsToStrings(*q.annotations)
+
+	return stream.Send(&remoteexec.EvaluateInstantVectorResponse{
+		Message: &remoteexec.EvaluateInstantVectorResponse_EvaluationCompleted{
+			EvaluationCompleted: &remoteexec.EvaluationCompleted{
+				Warnings:     warnings,
+				Infos:        infos,
+				TotalSamples: q.stats.TotalSamples,
+			},
+		},
+	})
+}
+
+func streamInstantVector(ctx context.Context, q *Query, stream remoteexec.RemoteExecution_EvaluateInstantVectorServer) error {
+	root := q.root.(types.InstantVectorOperator)
+	series, err := root.SeriesMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer types.PutSeriesMetadataSlice(series)
+
+	for start := 0; start < len(series); start += remoteExecutionSeriesMetadataBatchSize {
+		end := min(start+remoteExecutionSeriesMetadataBatchSize, len(series))
+		batch := make([]remoteexec.SeriesMetadata, 0, end-start)
+
+		for _, s := range series[start:end] {
+			batch = append(batch, remoteexec.SeriesMetadata{Labels: mimirpb.FromLabelsToLabelAdapters(s.Labels)})
+		}
+
+		if err := stream.Send(&remoteexec.EvaluateInstantVectorResponse{
+			Message: &remoteexec.EvaluateInstantVectorResponse_SeriesMetadata{
+				SeriesMetadata: &remoteexec.SeriesMetadataBatch{Series: batch},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	for i := range series {
+		d, err := root.NextSeries(ctx)
+		if err != nil {
+			if errors.Is(err, types.EOS) {
+				return fmt.Errorf("expected %v series, but only received %v", len(series), i)
+			}
+
+			return err
+		}
+
+		err = stream.Send(&remoteexec.EvaluateInstantVectorResponse{
+			Message: &remoteexec.EvaluateInstantVectorResponse_SeriesData{
+				SeriesData: &remoteexec.SeriesData{
+					Floats:     mimirpb.FromFPointsToSamples(d.Floats),
+					Histograms: mimirpb.FromHPointsToHistograms(d.Histograms),
+				},
+			},
+		})
+
+		// Send marshals the message before returning, so we can return the points to the pool immediately.
+		types.PutInstantVectorSeriesData(d, q.memoryConsumptionTracker)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
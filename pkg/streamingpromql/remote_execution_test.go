@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/promqltest"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/storage/sharding"
+	"github.com/grafana/mimir/pkg/streamingpromql/remoteexec"
+	"github.com/grafana/mimir/pkg/streamingpromql/testutils"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestRemoteExecution(t *testing.T) {
+	data := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{zone="a", idx="1"} 0+1x10
+			some_metric{zone="b", idx="2"} 0+2x10
+			some_metric{zone="a", idx="3"} 0+3x10
+			some_metric{zone="b", idx="4"} 10-1x10
+			some_metric{zone="a", idx="5"} 5x10
+			some_metric{zone="b", idx="6"} _ 1x4 _ 2x4
+			some_metric{zone="c", idx="7"} 3 _ 4 _ 5 _ 6
+			some_histogram{zone="a", idx="1"} {{schema:1 sum:10 count:9 buckets:[3 3 3]}}+{{schema:1 sum:1 count:1 buckets:[1]}}x10
+			some_histogram{zone="b", idx="2"} {{schema:1 sum:5 count:4 buckets:[1 2 1]}}x10
+			some_histogram{zone="a", idx="3"} {{schema:1 sum:1 count:1 buckets:[1]}}x10
+	`)
+	t.Cleanup(func() { require.NoError(t, data.Close()) })
+
+	shardedSelects := atomic.NewInt64(0)
+	queryable := &shardingQueryable{inner: data, shardedSelects: shardedSelects}
+
+	localEngine, err := NewEngine(NewTestEngineOpts(), NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	opts := NewTestEngineOpts()
+	opts.RemoteExecutionClient = newTestRemoteExecutionClientForEngine(t, localEngine.(*Engine), queryable)
+	opts.RemoteExecutionShardCount = 3
+	remoteEngine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		expectRemoteExecution bool
+	}{
+		`sum(some_metric)`:                                          {expectRemoteExecution: true},
+		`sum by (zone) (some_metric)`:                               {expectRemoteExecution: true},
+		`sum without (idx) (some_metric)`:                           {expectRemoteExecution: true},
+		`min by (zone) (some_metric)`:                               {expectRemoteExecution: true},
+		`max by (zone) (some_metric)`:                               {expectRemoteExecution: true},
+		`count by (zone) (some_metric)`:                             {expectRemoteExecution: true},
+		`count without (idx) (some_metric)`:                         {expectRemoteExecution: true},
+		`group by (zone) (some_metric)`:                             {expectRemoteExecution: true},
+		`sum by (zone) (rate(some_metric[5m]))`:                     {expectRemoteExecution: true},
+		`sum by (zone) (some_metric * 2 + 1)`:                       {expectRemoteExecution: true},
+		`sum by (zone) (some_metric offset 2m)`:                     {expectRemoteExecution: true},
+		`sum by (zone) (some_metric @ 300)`:                         {expectRemoteExecution: true},
+		`sum by (zone) (some_metric @ start())`:                     {expectRemoteExecution: true},
+		`max(max_over_time(some_metric[5m:2m] @ end()))`:            {expectRemoteExecution: true},
+		`max(max_over_time(some_metric[5m:2m]))`:                    {expectRemoteExecution: true},
+		`sum(some_histogram)`:                                       {expectRemoteExecution: true},
+		`sum by (zone) ({__name__=~"some_met.*"})`:                  {expectRemoteExecution: true},
+		`sum(some_metric) / count(some_metric offset 1m)`:           {expectRemoteExecution: true},
+		`sum(nonexistent_metric)`:                                   {expectRemoteExecution: true},
+		`topk(2, some_metric)`:                                      {expectRemoteExecution: false},
+		`sum(some_metric * on (idx) some_metric)`:                   {expectRemoteExecution: false},
+		`sum(label_replace(some_metric, "x", "$1", "idx", "(.*)"))`: {expectRemoteExecution: false},
+		`sum(sum by (zone) (some_metric))`:                          {expectRemoteExecution: true}, // The inner aggregation is sharded.
+		`avg(some_metric)`:                                          {expectRemoteExecution: false},
+	}
+
+	start := timestamp.Time(0)
+	end := start.Add(10 * time.Minute)
+	step := time.Minute
+
+	for expr, testCase := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			queryTypes := map[string]func(engine promql.QueryEngine) (promql.Query, error){
+				"range": func(engine promql.QueryEngine) (promql.Query, error) {
+					return engine.NewRangeQuery(context.Background(), queryable, nil, expr, start, end, step)
+				},
+				"instant": func(engine promql.QueryEngine) (promql.Query, error) {
+					return engine.NewInstantQuery(context.Background(), queryable, nil, expr, end)
+				},
+			}
+
+			for queryType, createQuery := range queryTypes {
+				t.Run(queryType, func(t *testing.T) {
+					q, err := createQuery(localEngine)
+					require.NoError(t, err)
+					defer q.Close()
+					expected := q.Exec(context.Background())
+					require.NoError(t, expected.Err)
+					expectedStats := q.Stats()
+
+					shardedSelects.Store(0)
+					q, err = createQuery(remoteEngine)
+					require.NoError(t, err)
+					defer q.Close()
+					actual := q.Exec(context.Background())
+					require.NoError(t, actual.Err)
+
+					// Annotations from other queriers don't include the position of the expression that caused them, so compare them separately.
+					testutils.RequireEqualResults(t, expr, expected, actual, true)
+					expectedWarnings, expectedInfos := expected.Warnings.AsStrings("", 0, 0)
+					actualWarnings, actualInfos := actual.Warnings.AsStrings("", 0, 0)
+					require.ElementsMatch(t, expectedWarnings, actualWarnings)
+					require.ElementsMatch(t, expectedInfos, actualInfos)
+					require.Equal(t, expectedStats.Samples.TotalSamples, q.Stats().Samples.TotalSamples)
+
+					if testCase.expectRemoteExecution {
+						require.NotZero(t, shardedSelects.Load(), "expected query to be sharded and evaluated remotely")
+					} else {
+						require.Zero(t, shardedSelects.Load(), "expected query to be evaluated locally")
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestRemoteExecution_Annotations(t *testing.T) {
+	data := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{idx="1"} 0+1x10
+			some_metric{idx="2"} 0+2x10
+			some_metric{idx="3"} {{schema:1 sum:1 count:1 buckets:[1]}}x10
+	`)
+	t.Cleanup(func() { require.NoError(t, data.Close()) })
+
+	queryable := &shardingQueryable{inner: data, shardedSelects: atomic.NewInt64(0)}
+	client := newTestRemoteExecutionClient(t, queryable)
+
+	opts := NewTestEngineOpts()
+	opts.RemoteExecutionClient = client
+	opts.RemoteExecutionShardCount = 2
+	engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	q, err := engine.NewInstantQuery(context.Background(), queryable, nil, `sum(rate(some_metric[5m]))`, timestamp.Time(0).Add(10*time.Minute))
+	require.NoError(t, err)
+	defer q.Close()
+
+	res := q.Exec(context.Background())
+	require.NoError(t, res.Err)
+
+	warnings, infos := res.Warnings.AsStrings("", 0, 0)
+	require.Equal(t, []string{`PromQL info: metric might not be a counter, name does not end in _total/_sum/_count/_bucket: "some_metric"`}, infos)
+	require.Equal(t, []string{`PromQL warning: encountered a mix of histograms and floats for aggregation`}, warnings)
+}
+
+func TestRemoteExecution_Errors(t *testing.T) {
+	data := promqltest.LoadedStorage(t, `
+		load 1m
+			some_metric{idx="1"} 0+1x10
+			some_metric{idx="2"} 0+2x10
+	`)
+	t.Cleanup(func() { require.NoError(t, data.Close()) })
+
+	queryable := &shardingQueryable{inner: data, shardedSelects: atomic.NewInt64(0)}
+
+	// Use a memory consumption limit that any query will exceed on the remote querier.
+	localOpts := NewTestEngineOpts()
+	localOpts.Pedantic = false
+	localEngine, err := NewEngine(localOpts, NewStaticQueryLimitsProvider(1), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	opts := NewTestEngineOpts()
+	opts.RemoteExecutionClient = newTestRemoteExecutionClientForEngine(t, localEngine.(*Engine), queryable)
+	opts.RemoteExecutionShardCount = 2
+	engine, err := NewEngine(opts, NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	q, err := engine.NewInstantQuery(context.Background(), queryable, nil, `sum(some_metric)`, timestamp.Time(0).Add(10*time.Minute))
+	require.NoError(t, err)
+	defer q.Close()
+
+	res := q.Exec(context.Background())
+	require.EqualError(t, res.Err, `the query exceeded the maximum allowed estimated amount of memory consumed by a single query (limit: 1 bytes) (err-mimir-max-estimated-memory-consumption-per-query). Consider reducing the time range and/or number of series selected by the query. One way to reduce the number of selected series is to add more label matchers to the query. Otherwise, to adjust the related per-tenant limit, configure -querier.max-estimated-memory-consumption-per-query, or contact your service administrator.`)
+	require.True(t, validation.IsLimitError(res.Err), "expected the remote querier's limit error to be returned as a limit error")
+}
+
+func newTestRemoteExecutionClient(t *testing.T, queryable storage.Queryable) remoteexec.RemoteExecutionClient {
+	engine, err := NewEngine(NewTestEngineOpts(), NewStaticQueryLimitsProvider(0), stats.NewQueryMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	return newTestRemoteExecutionClientForEngine(t, engine.(*Engine), queryable)
+}
+
+func newTestRemoteExecutionClientForEngine(t *testing.T, engine *Engine, queryable storage.Queryable) remoteexec.RemoteExecutionClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	remoteexec.RegisterRemoteExecutionServer(server, NewRemoteExecutionServer(engine, queryable))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	// nolint:staticcheck // grpc.DialContext() has been deprecated; we'll address it before upgrading to gRPC 2.
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, conn.Close()) })
+
+	return remoteexec.NewRemoteExecutionClient(conn)
+}
+
+// shardingQueryable returns only the series in the requested shard when a query shard label matcher is present,
+// like ingesters and store-gateways do.
+type shardingQueryable struct {
+	inner          storage.Queryable
+	shardedSelects *atomic.Int64
+}
+
+func (q *shardingQueryable) Querier(mint, maxt int64) (storage.Querier, error) {
+	inner, err := q.inner.Querier(mint, maxt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shardingQuerier{Querier: inner, shardedSelects: q.shardedSelects}, nil
+}
+
+type shardingQuerier struct {
+	storage.Querier
+	shardedSelects *atomic.Int64
+}
+
+func (q *shardingQuerier) Select(ctx context.Context, sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	shard, matchers, err := sharding.RemoveShardFromMatchers(matchers)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	set := q.Querier.Select(ctx, sortSeries, hints, matchers...)
+	if shard == nil {
+		return set
+	}
+
+	q.shardedSelects.Inc()
+
+	return &shardFilteringSeriesSet{SeriesSet: set, shard: shard}
+}
+
+type shardFilteringSeriesSet struct {
+	storage.SeriesSet
+	shard *sharding.ShardSelector
+}
+
+func (s *shardFilteringSeriesSet) Next() bool {
+	for s.SeriesSet.Next() {
+		if labels.StableHash(s.At().Labels())%s.shard.ShardCount == s.shard.ShardIndex {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package remoteexec
+
+import (
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+// remoteAnnotation is an annotation emitted while evaluating an expression on another querier.
+//
+// Annotations are sent between queriers as strings, so the original error types are lost, but
+// remoteAnnotation still wraps annotations.PromQLWarning or annotations.PromQLInfo so that
+// annotations.Annotations.AsStrings continues to classify it correctly.
+type remoteAnnotation struct {
+	msg  string
+	kind error
+}
+
+func (a remoteAnnotation) Error() string {
+	return a.msg
+}
+
+func (a remoteAnnotation) Unwrap() error {
+	return a.kind
+}
+
+// AnnotationsToStrings returns the messages of all warnings and infos in annos.
+// Position information is not included, as it refers to the expression evaluated
+// remotely, not the original query.
+func AnnotationsToStrings(annos annotations.Annotations) (warnings, infos []string) {
+	if len(annos) == 0 {
+		return nil, nil
+	}
+
+	return annos.AsStrings("", 0, 0)
+}
+
+func addRemoteAnnotations(annos *annotations.Annotations, warnings, infos []string) {
+	for _, msg := range warnings {
+		annos.Add(remoteAnnotation{msg: msg, kind: annotations.PromQLWarning})
+	}
+
+	for _, msg := range infos {
+		annos.Add(remoteAnnotation{msg: msg, kind: annotations.PromQLInfo})
+	}
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package remoteexec
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/grpcclient"
+	"github.com/grafana/dskit/middleware"
+	otgrpc "github.com/opentracing-contrib/go-grpc"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/grpcencoding/s2"
+)
+
+const serviceConfig = `{"loadBalancingPolicy": "round_robin"}`
+
+type Config struct {
+	Enabled    bool   `yaml:"enabled" category:"experimental"`
+	Address    string `yaml:"address" category:"experimental"`
+	ShardCount int    `yaml:"shard_count" category:"experimental"`
+
+	GRPCClientConfig grpcclient.Config `yaml:"grpc_client_config" doc:"description=Configures the gRPC client used to send expressions to other queriers for evaluation."`
+}
+
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "querier.mimir-query-engine.remote-execution.enabled", false, "Enable evaluating the inner expressions of shardable aggregations on other queriers. Only applies if the MQE is in use.")
+	f.StringVar(&cfg.Address, "querier.mimir-query-engine.remote-execution.address", "", "gRPC address of the queriers to send expressions to. Must be a DNS address (prefixed with dns:///) to enable client side load balancing.")
+	f.IntVar(&cfg.ShardCount, "querier.mimir-query-engine.remote-execution.shard-count", 4, "Number of shards to split the inner expression of a shardable aggregation into. Each shard is evaluated by a querier selected from -querier.mimir-query-engine.remote-execution.address.")
+
+	cfg.GRPCClientConfig.CustomCompressors = []string{s2.Name}
+	cfg.GRPCClientConfig.RegisterFlagsWithPrefix("querier.mimir-query-engine.remote-execution.grpc-client-config", f)
+}
+
+func (cfg *Config) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if cfg.Address == "" {
+		return errors.New("the Mimir query engine remote execution address must be set when remote execution is enabled")
+	}
+
+	if cfg.ShardCount < 2 {
+		return errors.New("the Mimir query engine remote execution shard count must be at least 2")
+	}
+
+	return cfg.GRPCClientConfig.Validate()
+}
+
+// ClosableRemoteExecutionClient is a RemoteExecutionClient that owns its underlying connection.
+type ClosableRemoteExecutionClient interface {
+	RemoteExecutionClient
+	Close() error
+}
+
+type closableRemoteExecutionClient struct {
+	RemoteExecutionClient
+	conn *grpc.ClientConn
+}
+
+func (c *closableRemoteExecutionClient) Close() error {
+	return c.conn.Close()
+}
+
+// NewClient creates a client that sends expressions to the queriers at cfg.Address, propagating the tenant ID of each request.
+func NewClient(cfg Config, reg prometheus.Registerer, logger log.Logger) (ClosableRemoteExecutionClient, error) {
+	invalidClusterValidation := util.NewRequestInvalidClusterValidationLabelsTotalCounter(reg, "querier-remote-execution", util.GRPCProtocol)
+	opts, err := cfg.GRPCClientConfig.DialOption(
+		[]grpc.UnaryClientInterceptor{
+			otgrpc.OpenTracingClientInterceptor(opentracing.GlobalTracer()),
+			middleware.ClientUserHeaderInterceptor,
+		},
+		[]grpc.StreamClientInterceptor{
+			otgrpc.OpenTracingStreamClientInterceptor(opentracing.GlobalTracer()),
+			middleware.StreamClientUserHeaderInterceptor,
+		},
+		util.NewInvalidClusterValidationReporter(cfg.GRPCClientConfig.ClusterValidation.Label, invalidClusterValidation, logger),
+	)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, grpc.WithDefaultServiceConfig(serviceConfig))
+
+	// nolint:staticcheck // grpc.Dial() has been deprecated; we'll address it before upgrading to gRPC 2.
+	conn, err := grpc.Dial(cfg.Address, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &closableRemoteExecutionClient{
+		RemoteExecutionClient: NewRemoteExecutionClient(conn),
+		conn:                  conn,
+	}, nil
+}
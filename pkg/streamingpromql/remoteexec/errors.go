@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package remoteexec
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/dskit/grpcutil"
+	"github.com/prometheus/prometheus/promql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// ErrorToStatus converts an error returned while evaluating an expression to a gRPC status error, so that the
+// querier that sent the expression can classify it in the same way as if it had evaluated the expression itself.
+//
+// Storage errors are returned as codes.Internal, cancellations and timeouts as codes.Canceled and codes.DeadlineExceeded,
+// and all other errors as codes.InvalidArgument. Limit errors are distinguished from other codes.InvalidArgument errors
+// by a mimirpb.ErrorDetails with cause mimirpb.TENANT_LIMIT.
+func ErrorToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		errStorage       promql.ErrStorage
+		errQueryCanceled promql.ErrQueryCanceled
+		errQueryTimeout  promql.ErrQueryTimeout
+	)
+
+	switch {
+	case errors.As(err, &errStorage):
+		return status.Error(codes.Internal, err.Error())
+	case errors.As(err, &errQueryCanceled) || errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.As(err, &errQueryTimeout) || errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case validation.IsLimitError(err):
+		return globalerror.WrapErrorWithGRPCStatus(err, codes.InvalidArgument, &mimirpb.ErrorDetails{Cause: mimirpb.TENANT_LIMIT}).Err()
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return globalerror.WrapErrorWithGRPCStatus(err, codes.InvalidArgument, &mimirpb.ErrorDetails{Cause: mimirpb.BAD_DATA}).Err()
+}
+
+// errorFromStatus is the inverse of ErrorToStatus: it converts a gRPC status error received from another querier
+// to the error the PromQL API uses to choose the response status code.
+//
+// Errors that are not gRPC status errors are returned unchanged. Errors with a code not produced by ErrorToStatus, such
+// as codes.Unavailable if the other querier could not be reached, are treated as storage errors.
+func errorFromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch s.Code() {
+	case codes.InvalidArgument:
+		if errorCause(err) == mimirpb.TENANT_LIMIT {
+			return validation.NewLimitError(s.Message())
+		}
+
+		return errors.New(s.Message())
+	case codes.Canceled:
+		return promql.ErrQueryCanceled(s.Message())
+	case codes.DeadlineExceeded:
+		return promql.ErrQueryTimeout(s.Message())
+	default:
+		return promql.ErrStorage{Err: errors.New(s.Message())}
+	}
+}
+
+// errorCause returns the cause from the mimirpb.ErrorDetails attached to the gRPC status of err, or mimirpb.UNKNOWN_CAUSE
+// if there is none.
+func errorCause(err error) mimirpb.ErrorCause {
+	s, ok := grpcutil.ErrorToStatus(err)
+	if !ok {
+		return mimirpb.UNKNOWN_CAUSE
+	}
+
+	for _, details := range s.Details() {
+		if errorDetails, ok := details.(*mimirpb.ErrorDetails); ok {
+			return errorDetails.GetCause()
+		}
+	}
+
+	return mimirpb.UNKNOWN_CAUSE
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package remoteexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestErrorStatusRoundTrip(t *testing.T) {
+	testCases := map[string]struct {
+		err          error
+		expectedCode codes.Code
+		check        func(t *testing.T, err error)
+	}{
+		"limit error": {
+			err:          validation.NewLimitError("the query exceeded a limit"),
+			expectedCode: codes.InvalidArgument,
+			check: func(t *testing.T, err error) {
+				require.EqualError(t, err, "the query exceeded a limit")
+				require.True(t, validation.IsLimitError(err))
+			},
+		},
+		"other error": {
+			err:          errors.New("invalid parameter"),
+			expectedCode: codes.InvalidArgument,
+			check: func(t *testing.T, err error) {
+				require.EqualError(t, err, "invalid parameter")
+				require.False(t, validation.IsLimitError(err))
+			},
+		},
+		"wrapped storage error with status": {
+			err:          promql.ErrStorage{Err: status.Error(codes.Unavailable, "ingester unavailable")},
+			expectedCode: codes.Internal,
+			check: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &promql.ErrStorage{})
+			},
+		},
+		"storage error": {
+			err:          promql.ErrStorage{Err: errors.New("store-gateway unavailable")},
+			expectedCode: codes.Internal,
+			check: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &promql.ErrStorage{})
+			},
+		},
+		"context canceled": {
+			err:          fmt.Errorf("evaluation failed: %w", context.Canceled),
+			expectedCode: codes.Canceled,
+			check: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, new(promql.ErrQueryCanceled))
+			},
+		},
+		"query timeout": {
+			err:          promql.ErrQueryTimeout("query evaluation"),
+			expectedCode: codes.DeadlineExceeded,
+			check: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, new(promql.ErrQueryTimeout))
+			},
+		},
+		"querier unreachable": {
+			err:          status.Error(codes.Unavailable, "connection refused"),
+			expectedCode: codes.Unavailable,
+			check: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &promql.ErrStorage{})
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			statusErr := ErrorToStatus(testCase.err)
+			require.Equal(t, testCase.expectedCode, status.Code(statusErr))
+
+			testCase.check(t, errorFromStatus(statusErr))
+		})
+	}
+}
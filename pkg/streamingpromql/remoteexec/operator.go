@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package remoteexec
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+	"github.com/prometheus/prometheus/util/annotations"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// InstantVectorOperator evaluates one or more expressions on other queriers, and returns the series produced by each
+// expression, one expression after another.
+type InstantVectorOperator struct {
+	Client                   RemoteExecutionClient
+	Expressions              []string
+	TimeRange                types.QueryTimeRange
+	LookbackDelta            time.Duration
+	MemoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	Annotations              *annotations.Annotations
+	Stats                    *types.QueryStats
+
+	expressionPosition posrange.PositionRange
+
+	cancel       context.CancelFunc
+	streams      []*remoteStream
+	currentIndex int
+}
+
+var _ types.InstantVectorOperator = &InstantVectorOperator{}
+
+type remoteStream struct {
+	stream          RemoteExecution_EvaluateInstantVectorClient
+	remainingSeries int
+
+	// The first message received after the series metadata, if it has been received already.
+	pending *EvaluateInstantVectorResponse
+}
+
+func NewInstantVectorOperator(
+	client RemoteExecutionClient,
+	expressions []string,
+	timeRange types.QueryTimeRange,
+	lookbackDelta time.Duration,
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker,
+	annotations *annotations.Annotations,
+	stats *types.QueryStats,
+	expressionPosition posrange.PositionRange,
+) *InstantVectorOperator {
+	return &InstantVectorOperator{
+		Client:                   client,
+		Expressions:              expressions,
+		TimeRange:                timeRange,
+		LookbackDelta:            lookbackDelta,
+		MemoryConsumptionTracker: memoryConsumptionTracker,
+		Annotations:              annotations,
+		Stats:                    stats,
+		expressionPosition:       expressionPosition,
+	}
+}
+
+func (r *InstantVectorOperator) SeriesMetadata(ctx context.Context) ([]types.SeriesMetadata, error) {
+	// Close cancels the streams if they haven't finished yet.
+	var streamCtx context.Context
+	streamCtx, r.cancel = context.WithCancel(ctx)
+
+	interval := r.TimeRange.IntervalMilliseconds
+	if r.TimeRange.StartT == r.TimeRange.EndT {
+		interval = 0
+	}
+
+	// Start evaluating all expressions before waiting for any of them, so that they're evaluated concurrently.
+	r.streams = make([]*remoteStream, 0, len(r.Expressions))
+	for _, expr := range r.Expressions {
+		stream, err := r.Client.EvaluateInstantVector(streamCtx, &EvaluateInstantVectorRequest{
+			Expr:             expr,
+			StartTimestampMs: r.TimeRange.StartT,
+			EndTimestampMs:   r.TimeRange.EndT,
+			IntervalMs:       interval,
+			LookbackDeltaMs:  r.LookbackDelta.Milliseconds(),
+		})
+		if err != nil {
+			return nil, r.convertError(ctx, err)
+		}
+
+		r.streams = append(r.streams, &remoteStream{stream: stream})
+	}
+
+	var metadata []types.SeriesMetadata
+
+	for _, s := range r.streams {
+		for {
+			msg, err := s.stream.Recv()
+			if err != nil {
+				return nil, r.convertError(ctx, err)
+			}
+
+			batch := msg.GetSeriesMetadata()
+			if batch == nil {
+				s.pending = msg
+				break
+			}
+
+			if metadata == nil {
+				metadata = types.GetSeriesMetadataSlice(len(batch.Series))
+			}
+
+			for _, series := range batch.Series {
+				metadata = append(metadata, types.SeriesMetadata{Labels: mimirpb.FromLabelAdaptersToLabelsWithCopy(series.Labels)})
+			}
+
+			s.remainingSeries += len(batch.Series)
+		}
+
+		if s.remainingSeries == 0 {
+			if err := r.finishStream(s); err != nil {
+				return nil, r.convertError(ctx, err)
+			}
+		}
+	}
+
+	return metadata, nil
+}
+
+func (r *InstantVectorOperator) NextSeries(ctx context.Context) (types.InstantVectorSeriesData, error) {
+	for r.currentIndex < len(r.streams) && r.streams[r.currentIndex].remainingSeries == 0 {
+		r.currentIndex++
+	}
+
+	if r.currentIndex >= len(r.streams) {
+		return types.InstantVectorSeriesData{}, types.EOS
+	}
+
+	s := r.streams[r.currentIndex]
+	msg, err := s.receive()
+	if err != nil {
+		return types.InstantVectorSeriesData{}, r.convertError(ctx, err)
+	}
+
+	series := msg.GetSeriesData()
+	if series == nil {
+		return types.InstantVectorSeriesData{}, fmt.Errorf("expected series data from remote querier, but got %T", msg.Message)
+	}
+
+	data, err := r.toSeriesData(series)
+	if err != nil {
+		return types.InstantVectorSeriesData{}, err
+	}
+
+	s.remainingSeries--
+
+	if s.remainingSeries == 0 {
+		// Read the evaluation summary now, rather than waiting for the next call to NextSeries: our caller won't call NextSeries
+		// again after reading the last series.
+		if err := r.finishStream(s); err != nil {
+			types.PutInstantVectorSeriesData(data, r.MemoryConsumptionTracker)
+			return types.InstantVectorSeriesData{}, r.convertError(ctx, err)
+		}
+	}
+
+	return data, nil
+}
+
+func (r *InstantVectorOperator) toSeriesData(series *SeriesData) (types.InstantVectorSeriesData, error) {
+	data := types.InstantVectorSeriesData{}
+
+	if len(series.Floats) > 0 {
+		floats, err := types.FPointSlicePool.Get(len(series.Floats), r.MemoryConsumptionTracker)
+		if err != nil {
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		for _, s := range series.Floats {
+			floats = append(floats, promql.FPoint{T: s.TimestampMs, F: s.Value})
+		}
+
+		data.Floats = floats
+	}
+
+	if len(series.Histograms) > 0 {
+		histograms, err := types.HPointSlicePool.Get(len(series.Histograms), r.MemoryConsumptionTracker)
+		if err != nil {
+			types.FPointSlicePool.Put(data.Floats, r.MemoryConsumptionTracker)
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		for _, h := range series.Histograms {
+			histograms = append(histograms, promql.HPoint{T: h.TimestampMs, H: h.Histogram.ToPrometheusModel()})
+		}
+
+		data.Histograms = histograms
+	}
+
+	return data, nil
+}
+
+func (r *InstantVectorOperator) finishStream(s *remoteStream) error {
+	msg, err := s.receive()
+	if err != nil {
+		return err
+	}
+
+	completed := msg.GetEvaluationCompleted()
+	if completed == nil {
+		return fmt.Errorf("expected evaluation to be complete, but got %T from remote querier", msg.Message)
+	}
+
+	addRemoteAnnotations(r.Annotations, completed.Warnings, completed.Infos)
+	r.Stats.TotalSamples += completed.TotalSamples
+
+	return nil
+}
+
+func (s *remoteStream) receive() (*EvaluateInstantVectorResponse, error) {
+	if s.pending != nil {
+		msg := s.pending
+		s.pending = nil
+		return msg, nil
+	}
+
+	return s.stream.Recv()
+}
+
+// convertError returns the original error message from the remote querier, or the cause of the cancellation if
+// the query was cancelled.
+func (r *InstantVectorOperator) convertError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+
+	return errorFromStatus(err)
+}
+
+func (r *InstantVectorOperator) ExpressionPosition() posrange.PositionRange {
+	return r.expressionPosition
+}
+
+func (r *InstantVectorOperator) Close() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	r.streams = nil
+}
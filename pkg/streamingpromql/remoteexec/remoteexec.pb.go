@@ -0,0 +1,2373 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: remoteexec.proto
+
+package remoteexec
+
+import (
+	context "context"
+	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
+	proto "github.com/gogo/protobuf/proto"
+	github_com_grafana_mimir_pkg_mimirpb "github.com/grafana/mimir/pkg/mimirpb"
+	mimirpb "github.com/grafana/mimir/pkg/mimirpb"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+	reflect "reflect"
+	strings "strings"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type EvaluateInstantVectorRequest struct {
+	// PromQL expression to evaluate. It must return an instant vector.
+	Expr             string `protobuf:"bytes,1,opt,name=expr,proto3" json:"expr,omitempty"`
+	StartTimestampMs int64  `protobuf:"varint,2,opt,name=start_timestamp_ms,json=startTimestampMs,proto3" json:"start_timestamp_ms,omitempty"`
+	EndTimestampMs   int64  `protobuf:"varint,3,opt,name=end_timestamp_ms,json=endTimestampMs,proto3" json:"end_timestamp_ms,omitempty"`
+	// Step between evaluations. 0 for instant queries.
+	IntervalMs      int64 `protobuf:"varint,4,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	LookbackDeltaMs int64 `protobuf:"varint,5,opt,name=lookback_delta_ms,json=lookbackDeltaMs,proto3" json:"lookback_delta_ms,omitempty"`
+}
+
+func (m *EvaluateInstantVectorRequest) Reset()      { *m = EvaluateInstantVectorRequest{} }
+func (*EvaluateInstantVectorRequest) ProtoMessage() {}
+func (*EvaluateInstantVectorRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4cb0fd8db5f0a6fb, []int{0}
+}
+func (m *EvaluateInstantVectorRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *EvaluateInstantVectorRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_EvaluateInstantVectorRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *EvaluateInstantVectorRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EvaluateInstantVectorRequest.Merge(m, src)
+}
+func (m *EvaluateInstantVectorRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *EvaluateInstantVectorRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EvaluateInstantVectorRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EvaluateInstantVectorRequest proto.InternalMessageInfo
+
+func (m *EvaluateInstantVectorRequest) GetExpr() string {
+	if m != nil {
+		return m.Expr
+	}
+	return ""
+}
+
+func (m *EvaluateInstantVectorRequest) GetStartTimestampMs() int64 {
+	if m != nil {
+		return m.StartTimestampMs
+	}
+	return 0
+}
+
+func (m *EvaluateInstantVectorRequest) GetEndTimestampMs() int64 {
+	if m != nil {
+		return m.EndTimestampMs
+	}
+	return 0
+}
+
+func (m *EvaluateInstantVectorRequest) GetIntervalMs() int64 {
+	if m != nil {
+		return m.IntervalMs
+	}
+	return 0
+}
+
+func (m *EvaluateInstantVectorRequest) GetLookbackDeltaMs() int64 {
+	if m != nil {
+		return m.LookbackDeltaMs
+	}
+	return 0
+}
+
+type EvaluateInstantVectorResponse struct {
+	// Types that are valid to be assigned to Message:
+	//	*EvaluateInstantVectorResponse_SeriesMetadata
+	//	*EvaluateInstantVectorResponse_SeriesData
+	//	*EvaluateInstantVectorResponse_EvaluationCompleted
+	Message isEvaluateInstantVectorResponse_Message `protobuf_oneof:"message"`
+}
+
+func (m *EvaluateInstantVectorResponse) Reset()      { *m = EvaluateInstantVectorResponse{} }
+func (*EvaluateInstantVectorResponse) ProtoMessage() {}
+func (*EvaluateInstantVectorResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4cb0fd8db5f0a6fb, []int{1}
+}
+func (m *EvaluateInstantVectorResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *EvaluateInstantVectorResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_EvaluateInstantVectorResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *EvaluateInstantVectorResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EvaluateInstantVectorResponse.Merge(m, src)
+}
+func (m *EvaluateInstantVectorResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *EvaluateInstantVectorResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_EvaluateInstantVectorResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EvaluateInstantVectorResponse proto.InternalMessageInfo
+
+type isEvaluateInstantVectorResponse_Message interface {
+	isEvaluateInstantVectorResponse_Message()
+	Equal(interface{}) bool
+	MarshalTo([]byte) (int, error)
+	Size() int
+}
+
+type EvaluateInstantVectorResponse_SeriesMetadata struct {
+	SeriesMetadata *SeriesMetadataBatch `protobuf:"bytes,1,opt,name=series_metadata,json=seriesMetadata,proto3,oneof" json:"series_metadata,omitempty"`
+}
+type EvaluateInstantVectorResponse_SeriesData struct {
+	SeriesData *SeriesData `protobuf:"bytes,2,opt,name=series_data,json=seriesData,proto3,oneof" json:"series_data,omitempty"`
+}
+type EvaluateInstantVectorResponse_EvaluationCompleted struct {
+	EvaluationCompleted *EvaluationCompleted `protobuf:"bytes,3,opt,name=evaluation_completed,json=evaluationCompleted,proto3,oneof" json:"evaluation_completed,omitempty"`
+}
+
+func (*EvaluateInstantVectorResponse_SeriesMetadata) isEvaluateInstantVectorResponse_Message()      {}
+func (*EvaluateInstantVectorResponse_SeriesData) isEvaluateInstantVectorResponse_Message()          {}
+func (*EvaluateInstantVectorResponse_EvaluationCompleted) isEvaluateInstantVectorResponse_Message() {}
+
+func (m *EvaluateInstantVectorResponse) GetMessage() isEvaluateInstantVectorResponse_Message {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *EvaluateInstantVectorResponse) GetSeriesMetadata() *SeriesMetadataBatch {
+	if x, ok := m.GetMessage().(*EvaluateInstantVectorResponse_SeriesMetadata); ok {
+		return x.SeriesMetadata
+	}
+	return nil
+}
+
+func (m *EvaluateInstantVectorResponse) GetSeriesData() *SeriesData {
+	if x, ok := m.GetMessage().(*EvaluateInstantVectorResponse_SeriesData); ok {
+		return x.SeriesData
+	}
+	return nil
+}
+
+func (m *EvaluateInstantVectorResponse) GetEvaluationCompleted() *EvaluationCompleted {
+	if x, ok := m.GetMessage().(*EvaluateInstantVectorResponse_EvaluationCompleted); ok {
+		return x.EvaluationCompleted
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*EvaluateInstantVectorResponse) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*EvaluateInstantVectorResponse_SeriesMetadata)(nil),
+		(*EvaluateInstantVectorResponse_SeriesData)(nil),
+		(*EvaluateInstantVectorResponse_EvaluationCompleted)(nil),
+	}
+}
+
+// SeriesMetadataBatch contains the labels of every series returned by the expression.
+// All series metadata is sent before any series data.
+type SeriesMetadataBatch struct {
+	Series []SeriesMetadata `protobuf:"bytes,1,rep,name=series,proto3" json:"series"`
+}
+
+func (m *SeriesMetadataBatch) Reset()      { *m = SeriesMetadataBatch{} }
+func (*SeriesMetadataBatch) ProtoMessage() {}
+func (*SeriesMetadataBatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4cb0fd8db5f0a6fb, []int{2}
+}
+func (m *SeriesMetadataBatch) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeriesMetadataBatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeriesMetadataBatch.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeriesMetadataBatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeriesMetadataBatch.Merge(m, src)
+}
+func (m *SeriesMetadataBatch) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeriesMetadataBatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeriesMetadataBatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeriesMetadataBatch proto.InternalMessageInfo
+
+func (m *SeriesMetadataBatch) GetSeries() []SeriesMetadata {
+	if m != nil {
+		return m.Series
+	}
+	return nil
+}
+
+type SeriesMetadata struct {
+	Labels []github_com_grafana_mimir_pkg_mimirpb.LabelAdapter `protobuf:"bytes,1,rep,name=labels,proto3,customtype=github.com/grafana/mimir/pkg/mimirpb.LabelAdapter" json:"labels"`
+}
+
+func (m *SeriesMetadata) Reset()      { *m = SeriesMetadata{} }
+func (*SeriesMetadata) ProtoMessage() {}
+func (*SeriesMetadata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4cb0fd8db5f0a6fb, []int{3}
+}
+func (m *SeriesMetadata) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeriesMetadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeriesMetadata.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeriesMetadata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeriesMetadata.Merge(m, src)
+}
+func (m *SeriesMetadata) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeriesMetadata) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeriesMetadata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeriesMetadata proto.InternalMessageInfo
+
+// SeriesData contains the points for one series, in the same order as the series in SeriesMetadataBatch.
+type SeriesData struct {
+	Floats     []mimirpb.Sample             `protobuf:"bytes,1,rep,name=floats,proto3" json:"floats"`
+	Histograms []mimirpb.FloatHistogramPair `protobuf:"bytes,2,rep,name=histograms,proto3" json:"histograms"`
+}
+
+func (m *SeriesData) Reset()      { *m = SeriesData{} }
+func (*SeriesData) ProtoMessage() {}
+func (*SeriesData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4cb0fd8db5f0a6fb, []int{4}
+}
+func (m *SeriesData) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeriesData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeriesData.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeriesData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeriesData.Merge(m, src)
+}
+func (m *SeriesData) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeriesData) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeriesData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeriesData proto.InternalMessageInfo
+
+func (m *SeriesData) GetFloats() []mimirpb.Sample {
+	if m != nil {
+		return m.Floats
+	}
+	return nil
+}
+
+func (m *SeriesData) GetHistograms() []mimirpb.FloatHistogramPair {
+	if m != nil {
+		return m.Histograms
+	}
+	return nil
+}
+
+type EvaluationCompleted struct {
+	Warnings     []string `protobuf:"bytes,1,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	Infos        []string `protobuf:"bytes,2,rep,name=infos,proto3" json:"infos,omitempty"`
+	TotalSamples int64    `protobuf:"varint,3,opt,name=total_samples,json=totalSamples,proto3" json:"total_samples,omitempty"`
+}
+
+func (m *EvaluationCompleted) Reset()      { *m = EvaluationCompleted{} }
+func (*EvaluationCompleted) ProtoMessage() {}
+func (*EvaluationCompleted) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4cb0fd8db5f0a6fb, []int{5}
+}
+func (m *EvaluationCompleted) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *EvaluationCompleted) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_EvaluationCompleted.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *EvaluationCompleted) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EvaluationCompleted.Merge(m, src)
+}
+func (m *EvaluationCompleted) XXX_Size() int {
+	return m.Size()
+}
+func (m *EvaluationCompleted) XXX_DiscardUnknown() {
+	xxx_messageInfo_EvaluationCompleted.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EvaluationCompleted proto.InternalMessageInfo
+
+func (m *EvaluationCompleted) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
+func (m *EvaluationCompleted) GetInfos() []string {
+	if m != nil {
+		return m.Infos
+	}
+	return nil
+}
+
+func (m *EvaluationCompleted) GetTotalSamples() int64 {
+	if m != nil {
+		return m.TotalSamples
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*EvaluateInstantVectorRequest)(nil), "remoteexec.EvaluateInstantVectorRequest")
+	proto.RegisterType((*EvaluateInstantVectorResponse)(nil), "remoteexec.EvaluateInstantVectorResponse")
+	proto.RegisterType((*SeriesMetadataBatch)(nil), "remoteexec.SeriesMetadataBatch")
+	proto.RegisterType((*SeriesMetadata)(nil), "remoteexec.SeriesMetadata")
+	proto.RegisterType((*SeriesData)(nil), "remoteexec.SeriesData")
+	proto.RegisterType((*EvaluationCompleted)(nil), "remoteexec.EvaluationCompleted")
+}
+
+func init() { proto.RegisterFile("remoteexec.proto", fileDescriptor_4cb0fd8db5f0a6fb) }
+
+var fileDescriptor_4cb0fd8db5f0a6fb = []byte{
+	// 648 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0x41, 0x4f, 0x13, 0x41,
+	0x14, 0xc7, 0x77, 0x28, 0x54, 0xfb, 0xaa, 0x50, 0xa7, 0x68, 0x9a, 0x06, 0xb7, 0xa4, 0x5e, 0xaa,
+	0xd1, 0x16, 0xf1, 0x22, 0x37, 0xad, 0x60, 0xaa, 0xb1, 0xd1, 0x2c, 0xc4, 0x83, 0x97, 0x66, 0xba,
+	0x7d, 0x2d, 0x1b, 0x76, 0x77, 0xd6, 0x99, 0x29, 0xf6, 0x88, 0x27, 0xaf, 0x7e, 0x0c, 0x3f, 0x0a,
+	0x47, 0x6e, 0x12, 0x0f, 0x44, 0x96, 0x8b, 0x47, 0x3e, 0x82, 0xd9, 0x99, 0x2e, 0x2d, 0xa1, 0x1a,
+	0x4e, 0x3b, 0xef, 0xcd, 0xef, 0xbd, 0xf7, 0x7f, 0x3b, 0x6f, 0x06, 0x0a, 0x02, 0x03, 0xae, 0x10,
+	0x47, 0xe8, 0xd6, 0x23, 0xc1, 0x15, 0xa7, 0x30, 0xf1, 0x94, 0x9f, 0x0c, 0x3c, 0xb5, 0x3b, 0xec,
+	0xd6, 0x5d, 0x1e, 0x34, 0x06, 0x7c, 0xc0, 0x1b, 0x1a, 0xe9, 0x0e, 0xfb, 0xda, 0xd2, 0x86, 0x5e,
+	0x99, 0xd0, 0xf2, 0xda, 0x34, 0x2e, 0x58, 0x9f, 0x85, 0xac, 0x11, 0x78, 0x81, 0x27, 0x1a, 0xd1,
+	0xde, 0xc0, 0xac, 0xa2, 0xae, 0xf9, 0x9a, 0x88, 0xea, 0x4f, 0x02, 0x2b, 0x5b, 0xfb, 0xcc, 0x1f,
+	0x32, 0x85, 0x6f, 0x42, 0xa9, 0x58, 0xa8, 0x3e, 0xa2, 0xab, 0xb8, 0x70, 0xf0, 0xf3, 0x10, 0xa5,
+	0xa2, 0x14, 0xe6, 0x71, 0x14, 0x89, 0x12, 0x59, 0x25, 0xb5, 0x9c, 0xa3, 0xd7, 0xf4, 0x31, 0x50,
+	0xa9, 0x98, 0x50, 0x1d, 0xe5, 0x05, 0x28, 0x15, 0x0b, 0xa2, 0x4e, 0x20, 0x4b, 0x73, 0xab, 0xa4,
+	0x96, 0x71, 0x0a, 0x7a, 0x67, 0x27, 0xdd, 0x68, 0x4b, 0x5a, 0x83, 0x02, 0x86, 0xbd, 0xcb, 0x6c,
+	0x46, 0xb3, 0x8b, 0x18, 0xf6, 0xa6, 0xc9, 0x0a, 0xe4, 0xbd, 0x50, 0xa1, 0xd8, 0x67, 0x7e, 0x02,
+	0xcd, 0x6b, 0x08, 0x52, 0x57, 0x5b, 0xd2, 0x47, 0x70, 0xc7, 0xe7, 0x7c, 0xaf, 0xcb, 0xdc, 0xbd,
+	0x4e, 0x0f, 0x7d, 0xc5, 0x12, 0x6c, 0x41, 0x63, 0x4b, 0xe9, 0xc6, 0x66, 0xe2, 0x6f, 0xcb, 0xea,
+	0xb7, 0x39, 0xb8, 0xff, 0x8f, 0xce, 0x64, 0xc4, 0x43, 0x89, 0xf4, 0x2d, 0x2c, 0x49, 0x14, 0x1e,
+	0xca, 0x4e, 0x80, 0x8a, 0xf5, 0x98, 0x62, 0xba, 0xcb, 0xfc, 0x7a, 0xa5, 0x3e, 0x75, 0x28, 0xdb,
+	0x1a, 0x69, 0x8f, 0x89, 0x26, 0x53, 0xee, 0x6e, 0xcb, 0x72, 0x16, 0xe5, 0x25, 0x37, 0xdd, 0x80,
+	0xfc, 0x38, 0x97, 0xce, 0x33, 0xa7, 0xf3, 0xdc, 0xbb, 0x9a, 0x67, 0x93, 0x29, 0xd6, 0xb2, 0x1c,
+	0x90, 0x17, 0x16, 0xdd, 0x81, 0x65, 0x34, 0x3a, 0x3d, 0x1e, 0x76, 0x5c, 0x1e, 0x44, 0x3e, 0x2a,
+	0xec, 0x95, 0x32, 0x57, 0xb5, 0x6c, 0x5d, 0x70, 0xaf, 0x52, 0xac, 0x65, 0x39, 0x45, 0xbc, 0xea,
+	0x6e, 0xe6, 0xe0, 0x46, 0x80, 0x52, 0xb2, 0x01, 0x56, 0xdf, 0x43, 0x71, 0x46, 0x13, 0xf4, 0x39,
+	0x64, 0x8d, 0x8a, 0x12, 0x59, 0xcd, 0xd4, 0xf2, 0xeb, 0xe5, 0xff, 0x74, 0x3d, 0x7f, 0x78, 0x52,
+	0xb1, 0x9c, 0x31, 0x5f, 0x1d, 0xc1, 0xe2, 0xe5, 0x7d, 0xda, 0x87, 0xac, 0xcf, 0xba, 0xe8, 0xa7,
+	0xb9, 0x8a, 0x75, 0x97, 0x0b, 0x85, 0xa3, 0xa8, 0x5b, 0x7f, 0x97, 0xf8, 0x3f, 0x30, 0x4f, 0x34,
+	0x37, 0x92, 0x24, 0xbf, 0x4e, 0x2a, 0x4f, 0xaf, 0x33, 0xa5, 0x26, 0xee, 0x65, 0x8f, 0x45, 0x0a,
+	0x85, 0x33, 0xce, 0x5e, 0x3d, 0x20, 0x00, 0x93, 0x1f, 0x49, 0xeb, 0x90, 0xed, 0xfb, 0x9c, 0xa9,
+	0xb4, 0x6c, 0x61, 0x52, 0x76, 0x9b, 0x25, 0x7f, 0x22, 0x15, 0x6e, 0x28, 0xda, 0x04, 0xd8, 0xf5,
+	0xa4, 0xe2, 0x03, 0xc1, 0xf4, 0xc0, 0x26, 0x31, 0x2b, 0x93, 0x98, 0xd7, 0x09, 0xd5, 0x4a, 0x01,
+	0xad, 0xd9, 0xc4, 0x4f, 0x45, 0x55, 0x7d, 0x28, 0xce, 0x38, 0x06, 0x5a, 0x86, 0x9b, 0x5f, 0x98,
+	0x08, 0xbd, 0x70, 0x60, 0xc4, 0xe4, 0x9c, 0x0b, 0x9b, 0x2e, 0xc3, 0x82, 0x17, 0xf6, 0xb9, 0xa9,
+	0x98, 0x73, 0x8c, 0x41, 0x1f, 0xc0, 0x6d, 0xc5, 0x15, 0xf3, 0x3b, 0x52, 0x4b, 0x4d, 0x2f, 0xc5,
+	0x2d, 0xed, 0x34, 0xf2, 0xe5, 0xfa, 0x57, 0x02, 0x4b, 0x8e, 0x3e, 0x96, 0xad, 0x11, 0xba, 0xc3,
+	0xa4, 0x26, 0x0d, 0xe1, 0xee, 0xcc, 0xc1, 0xa6, 0xb5, 0x19, 0xb3, 0x32, 0xf3, 0x56, 0x97, 0x1f,
+	0x5e, 0x83, 0x34, 0xb7, 0x64, 0x8d, 0x34, 0x5f, 0x1c, 0x9d, 0xda, 0xd6, 0xf1, 0xa9, 0x6d, 0x9d,
+	0x9f, 0xda, 0xe4, 0x20, 0xb6, 0xc9, 0x8f, 0xd8, 0x26, 0x87, 0xb1, 0x4d, 0x8e, 0x62, 0x9b, 0xfc,
+	0x8e, 0x6d, 0xf2, 0x27, 0xb6, 0xad, 0xf3, 0xd8, 0x26, 0xdf, 0xcf, 0x6c, 0xeb, 0xe8, 0xcc, 0xb6,
+	0x8e, 0xcf, 0x6c, 0xeb, 0xd3, 0xd4, 0x33, 0xd6, 0xcd, 0xea, 0xc7, 0xe6, 0xd9, 0xdf, 0x01, 0x00,
+	0x77, 0xab, 0x74, 0x5d, 0xed, 0x04, 0x00, 0x00,
+}
+
+func (this *EvaluateInstantVectorRequest) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*EvaluateInstantVectorRequest)
+	if !ok {
+		that2, ok := that.(EvaluateInstantVectorRequest)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if this.Expr != that1.Expr {
+		return false
+	}
+	if this.StartTimestampMs != that1.StartTimestampMs {
+		return false
+	}
+	if this.EndTimestampMs != that1.EndTimestampMs {
+		return false
+	}
+	if this.IntervalMs != that1.IntervalMs {
+		return false
+	}
+	if this.LookbackDeltaMs != that1.LookbackDeltaMs {
+		return false
+	}
+	return true
+}
+func (this *EvaluateInstantVectorResponse) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*EvaluateInstantVectorResponse)
+	if !ok {
+		that2, ok := that.(EvaluateInstantVectorResponse)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if that1.Message == nil {
+		if this.Message != nil {
+			return false
+		}
+	} else if this.Message == nil {
+		return false
+	} else if !this.Message.Equal(that1.Message) {
+		return false
+	}
+	return true
+}
+func (this *EvaluateInstantVectorResponse_SeriesMetadata) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*EvaluateInstantVectorResponse_SeriesMetadata)
+	if !ok {
+		that2, ok := that.(EvaluateInstantVectorResponse_SeriesMetadata)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.SeriesMetadata.Equal(that1.SeriesMetadata) {
+		return false
+	}
+	return true
+}
+func (this *EvaluateInstantVectorResponse_SeriesData) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*EvaluateInstantVectorResponse_SeriesData)
+	if !ok {
+		that2, ok := that.(EvaluateInstantVectorResponse_SeriesData)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.SeriesData.Equal(that1.SeriesData) {
+		return false
+	}
+	return true
+}
+func (this *EvaluateInstantVectorResponse_EvaluationCompleted) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*EvaluateInstantVectorResponse_EvaluationCompleted)
+	if !ok {
+		that2, ok := that.(EvaluateInstantVectorResponse_EvaluationCompleted)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.EvaluationCompleted.Equal(that1.EvaluationCompleted) {
+		return false
+	}
+	return true
+}
+func (this *SeriesMetadataBatch) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*SeriesMetadataBatch)
+	if !ok {
+		that2, ok := that.(SeriesMetadataBatch)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Series) != len(that1.Series) {
+		return false
+	}
+	for i := range this.Series {
+		if !this.Series[i].Equal(&that1.Series[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *SeriesMetadata) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*SeriesMetadata)
+	if !ok {
+		that2, ok := that.(SeriesMetadata)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Labels) != len(that1.Labels) {
+		return false
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *SeriesData) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*SeriesData)
+	if !ok {
+		that2, ok := that.(SeriesData)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Floats) != len(that1.Floats) {
+		return false
+	}
+	for i := range this.Floats {
+		if !this.Floats[i].Equal(&that1.Floats[i]) {
+			return false
+		}
+	}
+	if len(this.Histograms) != len(that1.Histograms) {
+		return false
+	}
+	for i := range this.Histograms {
+		if !this.Histograms[i].Equal(&that1.Histograms[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *EvaluationCompleted) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*EvaluationCompleted)
+	if !ok {
+		that2, ok := that.(EvaluationCompleted)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Warnings) != len(that1.Warnings) {
+		return false
+	}
+	for i := range this.Warnings {
+		if this.Warnings[i] != that1.Warnings[i] {
+			return false
+		}
+	}
+	if len(this.Infos) != len(that1.Infos) {
+		return false
+	}
+	for i := range this.Infos {
+		if this.Infos[i] != that1.Infos[i] {
+			return false
+		}
+	}
+	if this.TotalSamples != that1.TotalSamples {
+		return false
+	}
+	return true
+}
+func (this *EvaluateInstantVectorRequest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 9)
+	s = append(s, "&remoteexec.EvaluateInstantVectorRequest{")
+	s = append(s, "Expr: "+fmt.Sprintf("%#v", this.Expr)+",\n")
+	s = append(s, "StartTimestampMs: "+fmt.Sprintf("%#v", this.StartTimestampMs)+",\n")
+	s = append(s, "EndTimestampMs: "+fmt.Sprintf("%#v", this.EndTimestampMs)+",\n")
+	s = append(s, "IntervalMs: "+fmt.Sprintf("%#v", this.IntervalMs)+",\n")
+	s = append(s, "LookbackDeltaMs: "+fmt.Sprintf("%#v", this.LookbackDeltaMs)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *EvaluateInstantVectorResponse) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&remoteexec.EvaluateInstantVectorResponse{")
+	if this.Message != nil {
+		s = append(s, "Message: "+fmt.Sprintf("%#v", this.Message)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *EvaluateInstantVectorResponse_SeriesMetadata) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&remoteexec.EvaluateInstantVectorResponse_SeriesMetadata{` +
+		`SeriesMetadata:` + fmt.Sprintf("%#v", this.SeriesMetadata) + `}`}, ", ")
+	return s
+}
+func (this *EvaluateInstantVectorResponse_SeriesData) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&remoteexec.EvaluateInstantVectorResponse_SeriesData{` +
+		`SeriesData:` + fmt.Sprintf("%#v", this.SeriesData) + `}`}, ", ")
+	return s
+}
+func (this *EvaluateInstantVectorResponse_EvaluationCompleted) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&remoteexec.EvaluateInstantVectorResponse_EvaluationCompleted{` +
+		`EvaluationCompleted:` + fmt.Sprintf("%#v", this.EvaluationCompleted) + `}`}, ", ")
+	return s
+}
+func (this *SeriesMetadataBatch) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&remoteexec.SeriesMetadataBatch{")
+	if this.Series != nil {
+		vs := make([]SeriesMetadata, len(this.Series))
+		for i := range vs {
+			vs[i] = this.Series[i]
+		}
+		s = append(s, "Series: "+fmt.Sprintf("%#v", vs)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *SeriesMetadata) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&remoteexec.SeriesMetadata{")
+	s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *SeriesData) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&remoteexec.SeriesData{")
+	if this.Floats != nil {
+		vs := make([]mimirpb.Sample, len(this.Floats))
+		for i := range vs {
+			vs[i] = this.Floats[i]
+		}
+		s = append(s, "Floats: "+fmt.Sprintf("%#v", vs)+",\n")
+	}
+	if this.Histograms != nil {
+		vs := make([]mimirpb.FloatHistogramPair, len(this.Histograms))
+		for i := range vs {
+			vs[i] = this.Histograms[i]
+		}
+		s = append(s, "Histograms: "+fmt.Sprintf("%#v", vs)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *EvaluationCompleted) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&remoteexec.EvaluationCompleted{")
+	s = append(s, "Warnings: "+fmt.Sprintf("%#v", this.Warnings)+",\n")
+	s = append(s, "Infos: "+fmt.Sprintf("%#v", this.Infos)+",\n")
+	s = append(s, "TotalSamples: "+fmt.Sprintf("%#v", this.TotalSamples)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func valueToGoStringRemoteexec(v interface{}, typ string) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("func(v %v) *%v { return &v } ( %#v )", typ, typ, pv)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RemoteExecutionClient is the client API for RemoteExecution service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RemoteExecutionClient interface {
+	EvaluateInstantVector(ctx context.Context, in *EvaluateInstantVectorRequest, opts ...grpc.CallOption) (RemoteExecution_EvaluateInstantVectorClient, error)
+}
+
+type remoteExecutionClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRemoteExecutionClient(cc *grpc.ClientConn) RemoteExecutionClient {
+	return &remoteExecutionClient{cc}
+}
+
+func (c *remoteExecutionClient) EvaluateInstantVector(ctx context.Context, in *EvaluateInstantVectorRequest, opts ...grpc.CallOption) (RemoteExecution_EvaluateInstantVectorClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RemoteExecution_serviceDesc.Streams[0], "/remoteexec.RemoteExecution/EvaluateInstantVector", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &remoteExecutionEvaluateInstantVectorClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RemoteExecution_EvaluateInstantVectorClient interface {
+	Recv() (*EvaluateInstantVectorResponse, error)
+	grpc.ClientStream
+}
+
+type remoteExecutionEvaluateInstantVectorClient struct {
+	grpc.ClientStream
+}
+
+func (x *remoteExecutionEvaluateInstantVectorClient) Recv() (*EvaluateInstantVectorResponse, error) {
+	m := new(EvaluateInstantVectorResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RemoteExecutionServer is the server API for RemoteExecution service.
+type RemoteExecutionServer interface {
+	EvaluateInstantVector(*EvaluateInstantVectorRequest, RemoteExecution_EvaluateInstantVectorServer) error
+}
+
+// UnimplementedRemoteExecutionServer can be embedded to have forward compatible implementations.
+type UnimplementedRemoteExecutionServer struct {
+}
+
+func (*UnimplementedRemoteExecutionServer) EvaluateInstantVector(req *EvaluateInstantVectorRequest, srv RemoteExecution_EvaluateInstantVectorServer) error {
+	return status.Errorf(codes.Unimplemented, "method EvaluateInstantVector not implemented")
+}
+
+func RegisterRemoteExecutionServer(s *grpc.Server, srv RemoteExecutionServer) {
+	s.RegisterService(&_RemoteExecution_serviceDesc, srv)
+}
+
+func _RemoteExecution_EvaluateInstantVector_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EvaluateInstantVectorRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RemoteExecutionServer).EvaluateInstantVector(m, &remoteExecutionEvaluateInstantVectorServer{stream})
+}
+
+type RemoteExecution_EvaluateInstantVectorServer interface {
+	Send(*EvaluateInstantVectorResponse) error
+	grpc.ServerStream
+}
+
+type remoteExecutionEvaluateInstantVectorServer struct {
+	grpc.ServerStream
+}
+
+func (x *remoteExecutionEvaluateInstantVectorServer) Send(m *EvaluateInstantVectorResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _RemoteExecution_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "remoteexec.RemoteExecution",
+	HandlerType: (*RemoteExecutionServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "EvaluateInstantVector",
+			Handler:       _RemoteExecution_EvaluateInstantVector_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "remoteexec.proto",
+}
+
+func (m *EvaluateInstantVectorRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *EvaluateInstantVectorRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EvaluateInstantVectorRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.LookbackDeltaMs != 0 {
+		i = encodeVarintRemoteexec(dAtA, i, uint64(m.LookbackDeltaMs))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.IntervalMs != 0 {
+		i = encodeVarintRemoteexec(dAtA, i, uint64(m.IntervalMs))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.EndTimestampMs != 0 {
+		i = encodeVarintRemoteexec(dAtA, i, uint64(m.EndTimestampMs))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.StartTimestampMs != 0 {
+		i = encodeVarintRemoteexec(dAtA, i, uint64(m.StartTimestampMs))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Expr) > 0 {
+		i -= len(m.Expr)
+		copy(dAtA[i:], m.Expr)
+		i = encodeVarintRemoteexec(dAtA, i, uint64(len(m.Expr)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *EvaluateInstantVectorResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *EvaluateInstantVectorResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EvaluateInstantVectorResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Message != nil {
+		{
+			size := m.Message.Size()
+			i -= size
+			if _, err := m.Message.MarshalTo(dAtA[i:]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *EvaluateInstantVectorResponse_SeriesMetadata) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EvaluateInstantVectorResponse_SeriesMetadata) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.SeriesMetadata != nil {
+		{
+			size, err := m.SeriesMetadata.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRemoteexec(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+func (m *EvaluateInstantVectorResponse_SeriesData) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EvaluateInstantVectorResponse_SeriesData) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.SeriesData != nil {
+		{
+			size, err := m.SeriesData.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRemoteexec(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	return len(dAtA) - i, nil
+}
+func (m *EvaluateInstantVectorResponse_EvaluationCompleted) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EvaluateInstantVectorResponse_EvaluationCompleted) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.EvaluationCompleted != nil {
+		{
+			size, err := m.EvaluationCompleted.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRemoteexec(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	return len(dAtA) - i, nil
+}
+func (m *SeriesMetadataBatch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeriesMetadataBatch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeriesMetadataBatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Series) > 0 {
+		for iNdEx := len(m.Series) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Series[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintRemoteexec(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *SeriesMetadata) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeriesMetadata) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeriesMetadata) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		for iNdEx := len(m.Labels) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size := m.Labels[iNdEx].Size()
+				i -= size
+				if _, err := m.Labels[iNdEx].MarshalTo(dAtA[i:]); err != nil {
+					return 0, err
+				}
+				i = encodeVarintRemoteexec(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *SeriesData) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeriesData) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeriesData) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Histograms) > 0 {
+		for iNdEx := len(m.Histograms) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Histograms[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintRemoteexec(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Floats) > 0 {
+		for iNdEx := len(m.Floats) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Floats[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintRemoteexec(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *EvaluationCompleted) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *EvaluationCompleted) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EvaluationCompleted) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.TotalSamples != 0 {
+		i = encodeVarintRemoteexec(dAtA, i, uint64(m.TotalSamples))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Infos) > 0 {
+		for iNdEx := len(m.Infos) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Infos[iNdEx])
+			copy(dAtA[i:], m.Infos[iNdEx])
+			i = encodeVarintRemoteexec(dAtA, i, uint64(len(m.Infos[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Warnings) > 0 {
+		for iNdEx := len(m.Warnings) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Warnings[iNdEx])
+			copy(dAtA[i:], m.Warnings[iNdEx])
+			i = encodeVarintRemoteexec(dAtA, i, uint64(len(m.Warnings[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintRemoteexec(dAtA []byte, offset int, v uint64) int {
+	offset -= sovRemoteexec(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *EvaluateInstantVectorRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Expr)
+	if l > 0 {
+		n += 1 + l + sovRemoteexec(uint64(l))
+	}
+	if m.StartTimestampMs != 0 {
+		n += 1 + sovRemoteexec(uint64(m.StartTimestampMs))
+	}
+	if m.EndTimestampMs != 0 {
+		n += 1 + sovRemoteexec(uint64(m.EndTimestampMs))
+	}
+	if m.IntervalMs != 0 {
+		n += 1 + sovRemoteexec(uint64(m.IntervalMs))
+	}
+	if m.LookbackDeltaMs != 0 {
+		n += 1 + sovRemoteexec(uint64(m.LookbackDeltaMs))
+	}
+	return n
+}
+
+func (m *EvaluateInstantVectorResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Message != nil {
+		n += m.Message.Size()
+	}
+	return n
+}
+
+func (m *EvaluateInstantVectorResponse_SeriesMetadata) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.SeriesMetadata != nil {
+		l = m.SeriesMetadata.Size()
+		n += 1 + l + sovRemoteexec(uint64(l))
+	}
+	return n
+}
+func (m *EvaluateInstantVectorResponse_SeriesData) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.SeriesData != nil {
+		l = m.SeriesData.Size()
+		n += 1 + l + sovRemoteexec(uint64(l))
+	}
+	return n
+}
+func (m *EvaluateInstantVectorResponse_EvaluationCompleted) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.EvaluationCompleted != nil {
+		l = m.EvaluationCompleted.Size()
+		n += 1 + l + sovRemoteexec(uint64(l))
+	}
+	return n
+}
+func (m *SeriesMetadataBatch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Series) > 0 {
+		for _, e := range m.Series {
+			l = e.Size()
+			n += 1 + l + sovRemoteexec(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *SeriesMetadata) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovRemoteexec(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *SeriesData) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Floats) > 0 {
+		for _, e := range m.Floats {
+			l = e.Size()
+			n += 1 + l + sovRemoteexec(uint64(l))
+		}
+	}
+	if len(m.Histograms) > 0 {
+		for _, e := range m.Histograms {
+			l = e.Size()
+			n += 1 + l + sovRemoteexec(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *EvaluationCompleted) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Warnings) > 0 {
+		for _, s := range m.Warnings {
+			l = len(s)
+			n += 1 + l + sovRemoteexec(uint64(l))
+		}
+	}
+	if len(m.Infos) > 0 {
+		for _, s := range m.Infos {
+			l = len(s)
+			n += 1 + l + sovRemoteexec(uint64(l))
+		}
+	}
+	if m.TotalSamples != 0 {
+		n += 1 + sovRemoteexec(uint64(m.TotalSamples))
+	}
+	return n
+}
+
+func sovRemoteexec(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozRemoteexec(x uint64) (n int) {
+	return sovRemoteexec(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (this *EvaluateInstantVectorRequest) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&EvaluateInstantVectorRequest{`,
+		`Expr:` + fmt.Sprintf("%v", this.Expr) + `,`,
+		`StartTimestampMs:` + fmt.Sprintf("%v", this.StartTimestampMs) + `,`,
+		`EndTimestampMs:` + fmt.Sprintf("%v", this.EndTimestampMs) + `,`,
+		`IntervalMs:` + fmt.Sprintf("%v", this.IntervalMs) + `,`,
+		`LookbackDeltaMs:` + fmt.Sprintf("%v", this.LookbackDeltaMs) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *EvaluateInstantVectorResponse) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&EvaluateInstantVectorResponse{`,
+		`Message:` + fmt.Sprintf("%v", this.Message) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *EvaluateInstantVectorResponse_SeriesMetadata) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&EvaluateInstantVectorResponse_SeriesMetadata{`,
+		`SeriesMetadata:` + strings.Replace(fmt.Sprintf("%v", this.SeriesMetadata), "SeriesMetadataBatch", "SeriesMetadataBatch", 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *EvaluateInstantVectorResponse_SeriesData) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&EvaluateInstantVectorResponse_SeriesData{`,
+		`SeriesData:` + strings.Replace(fmt.Sprintf("%v", this.SeriesData), "SeriesData", "SeriesData", 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *EvaluateInstantVectorResponse_EvaluationCompleted) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&EvaluateInstantVectorResponse_EvaluationCompleted{`,
+		`EvaluationCompleted:` + strings.Replace(fmt.Sprintf("%v", this.EvaluationCompleted), "EvaluationCompleted", "EvaluationCompleted", 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *SeriesMetadataBatch) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForSeries := "[]SeriesMetadata{"
+	for _, f := range this.Series {
+		repeatedStringForSeries += strings.Replace(strings.Replace(f.String(), "SeriesMetadata", "SeriesMetadata", 1), `&`, ``, 1) + ","
+	}
+	repeatedStringForSeries += "}"
+	s := strings.Join([]string{`&SeriesMetadataBatch{`,
+		`Series:` + repeatedStringForSeries + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *SeriesMetadata) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&SeriesMetadata{`,
+		`Labels:` + fmt.Sprintf("%v", this.Labels) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *SeriesData) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForFloats := "[]Sample{"
+	for _, f := range this.Floats {
+		repeatedStringForFloats += fmt.Sprintf("%v", f) + ","
+	}
+	repeatedStringForFloats += "}"
+	repeatedStringForHistograms := "[]FloatHistogramPair{"
+	for _, f := range this.Histograms {
+		repeatedStringForHistograms += fmt.Sprintf("%v", f) + ","
+	}
+	repeatedStringForHistograms += "}"
+	s := strings.Join([]string{`&SeriesData{`,
+		`Floats:` + repeatedStringForFloats + `,`,
+		`Histograms:` + repeatedStringForHistograms + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *EvaluationCompleted) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&EvaluationCompleted{`,
+		`Warnings:` + fmt.Sprintf("%v", this.Warnings) + `,`,
+		`Infos:` + fmt.Sprintf("%v", this.Infos) + `,`,
+		`TotalSamples:` + fmt.Sprintf("%v", this.TotalSamples) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func valueToStringRemoteexec(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("*%v", pv)
+}
+func (m *EvaluateInstantVectorRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRemoteexec
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: EvaluateInstantVectorRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: EvaluateInstantVectorRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Expr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Expr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTimestampMs", wireType)
+			}
+			m.StartTimestampMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartTimestampMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EndTimestampMs", wireType)
+			}
+			m.EndTimestampMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.EndTimestampMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntervalMs", wireType)
+			}
+			m.IntervalMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IntervalMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LookbackDeltaMs", wireType)
+			}
+			m.LookbackDeltaMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LookbackDeltaMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRemoteexec(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *EvaluateInstantVectorResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRemoteexec
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: EvaluateInstantVectorResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: EvaluateInstantVectorResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesMetadata", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &SeriesMetadataBatch{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Message = &EvaluateInstantVectorResponse_SeriesMetadata{v}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &SeriesData{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Message = &EvaluateInstantVectorResponse_SeriesData{v}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EvaluationCompleted", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &EvaluationCompleted{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Message = &EvaluateInstantVectorResponse_EvaluationCompleted{v}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRemoteexec(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SeriesMetadataBatch) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRemoteexec
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeriesMetadataBatch: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeriesMetadataBatch: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Series", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Series = append(m.Series, SeriesMetadata{})
+			if err := m.Series[len(m.Series)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRemoteexec(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SeriesMetadata) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRemoteexec
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeriesMetadata: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeriesMetadata: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, github_com_grafana_mimir_pkg_mimirpb.LabelAdapter{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRemoteexec(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SeriesData) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRemoteexec
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeriesData: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeriesData: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Floats", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Floats = append(m.Floats, mimirpb.Sample{})
+			if err := m.Floats[len(m.Floats)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Histograms", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Histograms = append(m.Histograms, mimirpb.FloatHistogramPair{})
+			if err := m.Histograms[len(m.Histograms)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRemoteexec(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *EvaluationCompleted) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRemoteexec
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: EvaluationCompleted: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: EvaluationCompleted: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Warnings", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Warnings = append(m.Warnings, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Infos", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Infos = append(m.Infos, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalSamples", wireType)
+			}
+			m.TotalSamples = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TotalSamples |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRemoteexec(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRemoteexec
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipRemoteexec(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowRemoteexec
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowRemoteexec
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthRemoteexec
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupRemoteexec
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthRemoteexec
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthRemoteexec        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowRemoteexec          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupRemoteexec = fmt.Errorf("proto: unexpected end of group")
+)
@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package remoteexec
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/mimir/pkg/storage/sharding"
+)
+
+// nonShardableFunctions are functions whose result for a series depends on other series, or that produce series that
+// do not come from a selector, and so can't be evaluated independently for each shard.
+var nonShardableFunctions = map[string]struct{}{
+	"absent":             {},
+	"absent_over_time":   {},
+	"histogram_fraction": {},
+	"histogram_quantile": {},
+	"info":               {},
+	"label_join":         {},
+	"label_replace":      {},
+	"scalar":             {},
+	"vector":             {},
+}
+
+// IsShardableAggregation returns true if e can be computed by evaluating the same aggregation over each shard of its
+// inner expression, and then aggregating the results of each shard.
+func IsShardableAggregation(e *parser.AggregateExpr) bool {
+	if e.Param != nil {
+		return false
+	}
+
+	switch e.Op {
+	case parser.SUM, parser.MIN, parser.MAX, parser.COUNT, parser.GROUP:
+		// Supported.
+	default:
+		return false
+	}
+
+	return isShardable(e.Expr) && containsSelector(e.Expr)
+}
+
+func isShardable(expr parser.Expr) bool {
+	switch e := expr.(type) {
+	case *parser.VectorSelector, *parser.MatrixSelector, *parser.NumberLiteral, *parser.StringLiteral:
+		return true
+	case *parser.ParenExpr:
+		return isShardable(e.Expr)
+	case *parser.StepInvariantExpr:
+		return isShardable(e.Expr)
+	case *parser.UnaryExpr:
+		return isShardable(e.Expr)
+	case *parser.SubqueryExpr:
+		return isShardable(e.Expr)
+	case *parser.Call:
+		if _, nonShardable := nonShardableFunctions[e.Func.Name]; nonShardable {
+			return false
+		}
+
+		for _, arg := range e.Args {
+			if !isShardable(arg) {
+				return false
+			}
+		}
+
+		return true
+	case *parser.BinaryExpr:
+		if e.LHS.Type() == parser.ValueTypeVector && e.RHS.Type() == parser.ValueTypeVector {
+			// Series from one side may need to be matched with series from the other side in a different shard.
+			return false
+		}
+
+		return isShardable(e.LHS) && isShardable(e.RHS)
+	default:
+		return false
+	}
+}
+
+func containsSelector(expr parser.Expr) bool {
+	found := false
+
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if _, ok := node.(*parser.VectorSelector); ok {
+			found = true
+		}
+
+		return nil
+	})
+
+	return found
+}
+
+// ShardedExpressions returns one expression for each of shardCount shards that computes e over that shard's series.
+// e must be a shardable aggregation, as determined by IsShardableAggregation.
+//
+// The remote queriers evaluate the expressions over their own time range, so any @ start() or @ end() modifier is
+// replaced with the start or end timestamp of the original query, in milliseconds.
+func ShardedExpressions(e *parser.AggregateExpr, shardCount int, queryStartT, queryEndT int64) ([]string, error) {
+	// We format and parse the inner expression for each shard so that we have a copy we can safely modify.
+	innerExpr := e.Expr.String()
+	exprs := make([]string, 0, shardCount)
+
+	for shardIndex := 0; shardIndex < shardCount; shardIndex++ {
+		inner, err := parser.ParseExpr(innerExpr)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse inner expression of shardable aggregation: %w", err)
+		}
+
+		shard := sharding.ShardSelector{ShardIndex: uint64(shardIndex), ShardCount: uint64(shardCount)}
+
+		parser.Inspect(inner, func(node parser.Node, _ []parser.Node) error {
+			switch n := node.(type) {
+			case *parser.VectorSelector:
+				n.LabelMatchers = append(n.LabelMatchers, shard.Matcher())
+				n.Timestamp, n.StartOrEnd = pinStartOrEnd(n.Timestamp, n.StartOrEnd, queryStartT, queryEndT)
+			case *parser.SubqueryExpr:
+				n.Timestamp, n.StartOrEnd = pinStartOrEnd(n.Timestamp, n.StartOrEnd, queryStartT, queryEndT)
+			}
+
+			return nil
+		})
+
+		shardExpr := &parser.AggregateExpr{
+			Op:       e.Op,
+			Expr:     inner,
+			Grouping: e.Grouping,
+			Without:  e.Without,
+		}
+
+		exprs = append(exprs, shardExpr.String())
+	}
+
+	return exprs, nil
+}
+
+// pinStartOrEnd returns the timestamp and @ modifier of a selector or subquery with an @ start() or @ end() modifier
+// replaced with the absolute start or end timestamp of the query.
+func pinStartOrEnd(ts *int64, startOrEnd parser.ItemType, queryStartT, queryEndT int64) (*int64, parser.ItemType) {
+	switch startOrEnd {
+	case parser.START:
+		return &queryStartT, 0
+	case parser.END:
+		return &queryEndT, 0
+	default:
+		return ts, startOrEnd
+	}
+}
+
+// MergeOperation returns the aggregation used to combine the results of each shard of a sharded aggregation using op.
+func MergeOperation(op parser.ItemType) parser.ItemType {
+	if op == parser.COUNT {
+		return parser.SUM
+	}
+
+	return op
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package remoteexec
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsShardableAggregation(t *testing.T) {
+	testCases := map[string]bool{
+		`sum(foo)`:                                        true,
+		`sum by (env) (foo)`:                              true,
+		`min without (pod) (foo)`:                         true,
+		`max(rate(foo[5m]))`:                              true,
+		`count(foo offset 1h)`:                            true,
+		`group(foo @ 100)`:                                true,
+		`sum(-foo)`:                                       true,
+		`sum(foo * 2)`:                                    true,
+		`sum(2 * foo > bool 1)`:                           true,
+		`sum(quantile_over_time(0.9, foo[5m]))`:           true,
+		`sum(max_over_time(rate(foo[1m])[5m:1m]))`:        true,
+		`sum(foo * time())`:                               true,
+		`avg(foo)`:                                        false,
+		`stddev(foo)`:                                     false,
+		`topk(5, foo)`:                                    false,
+		`quantile(0.5, foo)`:                              false,
+		`count_values("value", foo)`:                      false,
+		`sum(foo * bar)`:                                  false,
+		`sum(foo and bar)`:                                false,
+		`sum(sum by (env) (foo))`:                         false,
+		`sum(max_over_time(sum(foo)[5m:1m]))`:             false,
+		`sum(absent(foo))`:                                false,
+		`sum(histogram_quantile(0.9, foo))`:               false,
+		`sum(label_replace(foo, "a", "$1", "b", "(.*)"))`: false,
+		`sum(vector(1))`:                                  false,
+		`sum(foo * scalar(bar))`:                          false,
+	}
+
+	for expr, expected := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			parsed, err := parser.ParseExpr(expr)
+			require.NoError(t, err)
+
+			require.Equal(t, expected, IsShardableAggregation(parsed.(*parser.AggregateExpr)))
+		})
+	}
+}
+
+func TestShardedExpressions(t *testing.T) {
+	testCases := map[string][]string{
+		`sum(foo)`: {
+			`sum(foo{__query_shard__="1_of_3"})`,
+			`sum(foo{__query_shard__="2_of_3"})`,
+			`sum(foo{__query_shard__="3_of_3"})`,
+		},
+		`count by (env) (rate(foo{env!="test"}[5m] offset 1h))`: {
+			`count by (env) (rate(foo{__query_shard__="1_of_3",env!="test"}[5m] offset 1h))`,
+			`count by (env) (rate(foo{__query_shard__="2_of_3",env!="test"}[5m] offset 1h))`,
+			`count by (env) (rate(foo{__query_shard__="3_of_3",env!="test"}[5m] offset 1h))`,
+		},
+		`max without (pod) (foo @ 100 * 2)`: {
+			`max without (pod) (foo{__query_shard__="1_of_3"} @ 100.000 * 2)`,
+			`max without (pod) (foo{__query_shard__="2_of_3"} @ 100.000 * 2)`,
+			`max without (pod) (foo{__query_shard__="3_of_3"} @ 100.000 * 2)`,
+		},
+		// @ start() and @ end() are pinned to the time range of the original query.
+		`sum(foo @ start() - foo @ end())`: {
+			`sum(foo{__query_shard__="1_of_3"} @ 1000.000 - foo{__query_shard__="1_of_3"} @ 2000.000)`,
+			`sum(foo{__query_shard__="2_of_3"} @ 1000.000 - foo{__query_shard__="2_of_3"} @ 2000.000)`,
+			`sum(foo{__query_shard__="3_of_3"} @ 1000.000 - foo{__query_shard__="3_of_3"} @ 2000.000)`,
+		},
+		`sum(max_over_time(foo[5m:1m] @ end()))`: {
+			`sum(max_over_time(foo{__query_shard__="1_of_3"}[5m:1m] @ 2000.000))`,
+			`sum(max_over_time(foo{__query_shard__="2_of_3"}[5m:1m] @ 2000.000))`,
+			`sum(max_over_time(foo{__query_shard__="3_of_3"}[5m:1m] @ 2000.000))`,
+		},
+	}
+
+	for expr, expected := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			parsed, err := parser.ParseExpr(expr)
+			require.NoError(t, err)
+
+			actual, err := ShardedExpressions(parsed.(*parser.AggregateExpr), 3, 1_000_000, 2_000_000)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestMergeOperation(t *testing.T) {
+	testCases := map[parser.ItemType]parser.ItemType{
+		parser.SUM:   parser.SUM,
+		parser.COUNT: parser.SUM,
+		parser.MIN:   parser.MIN,
+		parser.MAX:   parser.MAX,
+		parser.GROUP: parser.GROUP,
+	}
+
+	for op, expected := range testCases {
+		require.Equal(t, expected, MergeOperation(op), op.String())
+	}
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package spill
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/tsdb/encoding"
+	"github.com/prometheus/prometheus/tsdb/record"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// Spiller writes series data to a temporary file on local disk so that operators that would otherwise buffer
+// many series in memory can continue once a query's estimated memory consumption is close to its limit.
+//
+// A nil *Spiller is valid and never spills.
+//
+// It is not safe to use this type from multiple goroutines simultaneously.
+type Spiller struct {
+	dir                      string
+	threshold                float64
+	memoryConsumptionTracker *limiting.MemoryConsumptionTracker
+	stats                    *types.QueryStats
+
+	file       *os.File // Created the first time series data is written.
+	fileSize   int64
+	encodeBuf  encoding.Encbuf
+	readBuffer []byte
+}
+
+// Spillable is implemented by operators that can write the series they buffer to disk.
+type Spillable interface {
+	// EnableSpilling makes the operator write the series it buffers to disk with spiller once the query's
+	// memory consumption passes the spilling threshold, rather than holding them in memory.
+	EnableSpilling(spiller *Spiller)
+}
+
+// Ref identifies series data written with Spiller.Write.
+type Ref struct {
+	offset int64
+	length int
+}
+
+// NewSpiller creates a Spiller that writes to a file in dir once the current estimated memory consumption
+// of the query exceeds threshold (a fraction between 0 and 1) of the query's memory consumption limit.
+//
+// If dir is empty, the default directory for temporary files is used.
+func NewSpiller(dir string, threshold float64, memoryConsumptionTracker *limiting.MemoryConsumptionTracker, stats *types.QueryStats) *Spiller {
+	return &Spiller{
+		dir:                      dir,
+		threshold:                threshold,
+		memoryConsumptionTracker: memoryConsumptionTracker,
+		stats:                    stats,
+	}
+}
+
+// ShouldSpill returns true if operators should write series data they are holding to disk rather than keeping it in memory.
+func (s *Spiller) ShouldSpill() bool {
+	if s == nil || s.memoryConsumptionTracker.MaxEstimatedMemoryConsumptionBytes == 0 {
+		return false
+	}
+
+	return float64(s.memoryConsumptionTracker.CurrentEstimatedMemoryConsumptionBytes) > s.threshold*float64(s.memoryConsumptionTracker.MaxEstimatedMemoryConsumptionBytes)
+}
+
+// Write writes d to disk and returns a Ref that can be passed to Read to retrieve it later.
+//
+// Write takes ownership of d: its slices are returned to the pool, even if Write returns an error.
+func (s *Spiller) Write(d types.InstantVectorSeriesData) (Ref, error) {
+	defer types.PutInstantVectorSeriesData(d, s.memoryConsumptionTracker)
+
+	if s.file == nil {
+		var err error
+		s.file, err = os.CreateTemp(s.dir, "mimir-query-spill-*")
+		if err != nil {
+			return Ref{}, fmt.Errorf("could not create file to spill series data to: %w", err)
+		}
+	}
+
+	s.encodeBuf.Reset()
+	s.encodeBuf.PutUvarint(len(d.Floats))
+
+	for _, p := range d.Floats {
+		s.encodeBuf.PutVarint64(p.T)
+		s.encodeBuf.PutBEFloat64(p.F)
+	}
+
+	s.encodeBuf.PutUvarint(len(d.Histograms))
+
+	for _, p := range d.Histograms {
+		s.encodeBuf.PutVarint64(p.T)
+		record.EncodeFloatHistogram(&s.encodeBuf, p.H)
+	}
+
+	b := s.encodeBuf.Get()
+	if _, err := s.file.Write(b); err != nil {
+		return Ref{}, fmt.Errorf("could not write spilled series data: %w", err)
+	}
+
+	ref := Ref{offset: s.fileSize, length: len(b)}
+	s.fileSize += int64(len(b))
+	s.stats.SpilledBytes += uint64(len(b))
+
+	return ref, nil
+}
+
+// Read returns the series data previously written with Write.
+//
+// The returned slices are obtained from the pools, and so are subject to the query's memory consumption limit.
+func (s *Spiller) Read(ref Ref) (types.InstantVectorSeriesData, error) {
+	if cap(s.readBuffer) < ref.length {
+		s.readBuffer = make([]byte, ref.length)
+	}
+
+	s.readBuffer = s.readBuffer[:ref.length]
+
+	if _, err := s.file.ReadAt(s.readBuffer, ref.offset); err != nil {
+		return types.InstantVectorSeriesData{}, fmt.Errorf("could not read spilled series data: %w", err)
+	}
+
+	dec := encoding.Decbuf{B: s.readBuffer}
+	d := types.InstantVectorSeriesData{}
+	var err error
+
+	if floatCount := dec.Uvarint(); floatCount > 0 {
+		d.Floats, err = types.FPointSlicePool.Get(floatCount, s.memoryConsumptionTracker)
+		if err != nil {
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		for range floatCount {
+			t := dec.Varint64()
+			f := dec.Be64Float64()
+			d.Floats = append(d.Floats, promql.FPoint{T: t, F: f})
+		}
+	}
+
+	if histogramCount := dec.Uvarint(); histogramCount > 0 {
+		d.Histograms, err = types.HPointSlicePool.Get(histogramCount, s.memoryConsumptionTracker)
+		if err != nil {
+			types.FPointSlicePool.Put(d.Floats, s.memoryConsumptionTracker)
+			return types.InstantVectorSeriesData{}, err
+		}
+
+		for range histogramCount {
+			t := dec.Varint64()
+			h := &histogram.FloatHistogram{}
+			record.DecodeFloatHistogram(&dec, h)
+			d.Histograms = append(d.Histograms, promql.HPoint{T: t, H: h})
+		}
+	}
+
+	if dec.Err() != nil {
+		types.PutInstantVectorSeriesData(d, s.memoryConsumptionTracker)
+		return types.InstantVectorSeriesData{}, fmt.Errorf("could not decode spilled series data: %w", dec.Err())
+	}
+
+	return d, nil
+}
+
+// Close removes the file series data was written to, if any.
+//
+// Any Ref returned by Write is invalid after Close is called.
+func (s *Spiller) Close() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+
+	closeErr := s.file.Close()
+	removeErr := os.Remove(s.file.Name())
+	s.file = nil
+	s.fileSize = 0
+	s.readBuffer = nil
+
+	return errors.Join(closeErr, removeErr)
+}
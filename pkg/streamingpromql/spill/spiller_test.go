@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package spill
+
+import (
+	"os"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+func TestSpiller_ShouldSpill(t *testing.T) {
+	var nilSpiller *Spiller
+	require.False(t, nilSpiller.ShouldSpill())
+
+	unlimitedTracker := limiting.NewMemoryConsumptionTracker(0, nil)
+	require.NoError(t, unlimitedTracker.IncreaseMemoryConsumption(1000))
+	require.False(t, NewSpiller(t.TempDir(), 0.5, unlimitedTracker, &types.QueryStats{}).ShouldSpill(), "should never spill if there's no memory consumption limit")
+
+	tracker := limiting.NewMemoryConsumptionTracker(1000, nil)
+	spiller := NewSpiller(t.TempDir(), 0.5, tracker, &types.QueryStats{})
+
+	require.NoError(t, tracker.IncreaseMemoryConsumption(500))
+	require.False(t, spiller.ShouldSpill())
+
+	require.NoError(t, tracker.IncreaseMemoryConsumption(1))
+	require.True(t, spiller.ShouldSpill())
+
+	tracker.DecreaseMemoryConsumption(501)
+}
+
+func TestSpiller_WriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	tracker := limiting.NewMemoryConsumptionTracker(0, nil)
+	stats := &types.QueryStats{}
+	spiller := NewSpiller(dir, 0.5, tracker, stats)
+
+	floats := []promql.FPoint{{T: 0, F: 1.5}, {T: 60_000, F: -2}}
+	histograms := []promql.HPoint{
+		{T: 0, H: &histogram.FloatHistogram{Schema: 0, Count: 3, Sum: 4, ZeroThreshold: 0.001, ZeroCount: 1, PositiveSpans: []histogram.Span{{Offset: 0, Length: 2}}, PositiveBuckets: []float64{1, 1}}},
+		{T: 30_000, H: &histogram.FloatHistogram{Schema: histogram.CustomBucketsSchema, Count: 2, Sum: 10, PositiveSpans: []histogram.Span{{Offset: 0, Length: 1}}, PositiveBuckets: []float64{2}, CustomValues: []float64{5, 10}}},
+	}
+
+	refs := make([]Ref, 0, 3)
+	for _, d := range []types.InstantVectorSeriesData{
+		{Floats: floats},
+		{Histograms: histograms},
+		{},
+	} {
+		// Write returns the slices to the pool, so give it copies.
+		copied := copySeriesData(t, d, tracker)
+		ref, err := spiller.Write(copied)
+		require.NoError(t, err)
+		refs = append(refs, ref)
+	}
+
+	require.Equal(t, uint64(0), tracker.CurrentEstimatedMemoryConsumptionBytes, "Write should return slices to the pool")
+	require.NotZero(t, stats.SpilledBytes)
+
+	// Read the series back in a different order to the order they were written.
+	empty, err := spiller.Read(refs[2])
+	require.NoError(t, err)
+	require.Empty(t, empty.Floats)
+	require.Empty(t, empty.Histograms)
+
+	readHistograms, err := spiller.Read(refs[1])
+	require.NoError(t, err)
+	require.Empty(t, readHistograms.Floats)
+	require.Equal(t, histograms, readHistograms.Histograms)
+
+	readFloats, err := spiller.Read(refs[0])
+	require.NoError(t, err)
+	require.Equal(t, floats, readFloats.Floats)
+	require.Empty(t, readFloats.Histograms)
+
+	types.PutInstantVectorSeriesData(readFloats, tracker)
+	types.PutInstantVectorSeriesData(readHistograms, tracker)
+	require.Equal(t, uint64(0), tracker.CurrentEstimatedMemoryConsumptionBytes)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	require.NoError(t, spiller.Close())
+
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "spill file should be removed when the spiller is closed")
+}
+
+func TestSpiller_CloseWithoutWriting(t *testing.T) {
+	var nilSpiller *Spiller
+	require.NoError(t, nilSpiller.Close())
+
+	dir := t.TempDir()
+	spiller := NewSpiller(dir, 0.5, limiting.NewMemoryConsumptionTracker(0, nil), &types.QueryStats{})
+	require.NoError(t, spiller.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "spill file should only be created when series data is written")
+}
+
+func copySeriesData(t *testing.T, d types.InstantVectorSeriesData, tracker *limiting.MemoryConsumptionTracker) types.InstantVectorSeriesData {
+	var copied types.InstantVectorSeriesData
+	var err error
+
+	if len(d.Floats) > 0 {
+		copied.Floats, err = types.FPointSlicePool.Get(len(d.Floats), tracker)
+		require.NoError(t, err)
+		copied.Floats = append(copied.Floats, d.Floats...)
+	}
+
+	if len(d.Histograms) > 0 {
+		copied.Histograms, err = types.HPointSlicePool.Get(len(d.Histograms), tracker)
+		require.NoError(t, err)
+
+		for _, p := range d.Histograms {
+			copied.Histograms = append(copied.Histograms, promql.HPoint{T: p.T, H: p.H.Copy()})
+		}
+	}
+
+	return copied
+}
@@ -21,6 +21,10 @@ type QueryStats struct {
 	// For example, if a query is running with a step of 30s with a range vector selector with range 45s,
 	// then samples in the overlapping 15s are counted twice.
 	TotalSamples int64
+
+	// The total number of bytes of series data written to disk because the query's estimated memory consumption
+	// was close to its limit.
+	SpilledBytes uint64
 }
 
 const timestampFieldSize = int64(unsafe.Sizeof(int64(0)))
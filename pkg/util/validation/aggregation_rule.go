@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+const (
+	AggregationOutputSum          = "sum"
+	AggregationOutputCountSeries  = "count_series"
+	AggregationOutputCountSamples = "count_samples"
+	AggregationOutputMin          = "min"
+	AggregationOutputMax          = "max"
+	AggregationOutputAvg          = "avg"
+	AggregationOutputTotal        = "total"
+)
+
+// AggregationOutputs are the supported outputs of aggregation rules.
+var AggregationOutputs = []string{
+	AggregationOutputSum,
+	AggregationOutputCountSeries,
+	AggregationOutputCountSamples,
+	AggregationOutputMin,
+	AggregationOutputMax,
+	AggregationOutputAvg,
+	AggregationOutputTotal,
+}
+
+// AggregationRule is a rule aggregating the matching series ingested by a tenant over fixed windows in the
+// distributors, with the same output naming as the stream aggregation of vmagent.
+type AggregationRule struct {
+	// Match is the series selector of the input series of the rule.
+	Match    string         `yaml:"match" json:"match"`
+	Interval model.Duration `yaml:"interval" json:"interval"`
+	// By and Without are the labels the output series are grouped by or without. The output series keep all
+	// the labels of the input series if both are empty.
+	By      []string `yaml:"by,omitempty" json:"by,omitempty"`
+	Without []string `yaml:"without,omitempty" json:"without,omitempty"`
+	Outputs []string `yaml:"outputs" json:"outputs"`
+	// KeepInput is whether the input series are ingested too.
+	KeepInput bool `yaml:"keep_input,omitempty" json:"keep_input,omitempty"`
+}
+
+// Validate returns an error if the rule is invalid.
+func (r *AggregationRule) Validate() error {
+	if r == nil {
+		return fmt.Errorf("invalid aggregation_rules")
+	}
+	if r.Match == "" {
+		return fmt.Errorf("invalid aggregation rule: match is required")
+	}
+	if _, err := r.Matchers(); err != nil {
+		return r.errorf("invalid match: %w", err)
+	}
+	if r.Interval <= 0 {
+		return r.errorf("interval must be greater than 0")
+	}
+	if len(r.By) > 0 && len(r.Without) > 0 {
+		return r.errorf("by and without can't both be set")
+	}
+	if slices.Contains(r.Without, labels.MetricName) {
+		return r.errorf("without can't contain %s", labels.MetricName)
+	}
+	if len(r.Outputs) == 0 {
+		return r.errorf("outputs is required")
+	}
+	for _, output := range r.Outputs {
+		if !slices.Contains(AggregationOutputs, output) {
+			return r.errorf("unsupported output %q (supported values: %s)", output, strings.Join(AggregationOutputs, ", "))
+		}
+	}
+	return nil
+}
+
+// Matchers returns the matchers of the series selector of the rule.
+func (r *AggregationRule) Matchers() ([]*labels.Matcher, error) {
+	return (&IngestionPipelineStep{Match: r.Match}).Matchers()
+}
+
+// OutputMetricNameSuffix returns the suffix appended to the metric name of the input series for the output
+// series of the rule, before the name of the output. For example, ":1m_without_pod" for a rule aggregating
+// series without the pod label over 1m.
+func (r *AggregationRule) OutputMetricNameSuffix() string {
+	suffix := ":" + r.Interval.String()
+	switch {
+	case len(r.By) > 0:
+		suffix += "_by_" + strings.Join(r.By, "_")
+	case len(r.Without) > 0:
+		suffix += "_without_" + strings.Join(r.Without, "_")
+	}
+	return suffix
+}
+
+func (r *AggregationRule) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid aggregation rule %q: %w", r.Match, fmt.Errorf(format, args...))
+}
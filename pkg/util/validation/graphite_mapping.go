@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	GraphiteMappingMatchTypeGlob  = "glob"
+	GraphiteMappingMatchTypeRegex = "regex"
+
+	GraphiteMappingActionMap  = "map"
+	GraphiteMappingActionDrop = "drop"
+)
+
+// GraphiteMapping is a rule mapping dotted Graphite metric names to Prometheus metric names and labels,
+// with the same semantics as the mappings of the graphite_exporter.
+type GraphiteMapping struct {
+	Match     string            `yaml:"match" json:"match"`
+	MatchType string            `yaml:"match_type,omitempty" json:"match_type,omitempty"`
+	Name      string            `yaml:"name,omitempty" json:"name,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Action    string            `yaml:"action,omitempty" json:"action,omitempty"`
+}
+
+// Validate returns an error if the mapping is invalid.
+func (m *GraphiteMapping) Validate() error {
+	if m == nil {
+		return fmt.Errorf("invalid graphite_mappings")
+	}
+	if m.Match == "" {
+		return fmt.Errorf("invalid graphite mapping: match is required")
+	}
+
+	switch m.MatchType {
+	case "", GraphiteMappingMatchTypeGlob:
+	case GraphiteMappingMatchTypeRegex:
+		if _, err := regexp.Compile(m.Match); err != nil {
+			return fmt.Errorf("invalid graphite mapping %q: %w", m.Match, err)
+		}
+	default:
+		return fmt.Errorf("invalid graphite mapping %q: unsupported match_type %q (supported values: %s, %s)", m.Match, m.MatchType, GraphiteMappingMatchTypeGlob, GraphiteMappingMatchTypeRegex)
+	}
+
+	switch m.Action {
+	case "", GraphiteMappingActionMap:
+		if m.Name == "" {
+			return fmt.Errorf("invalid graphite mapping %q: name is required", m.Match)
+		}
+	case GraphiteMappingActionDrop:
+	default:
+		return fmt.Errorf("invalid graphite mapping %q: unsupported action %q (supported values: %s, %s)", m.Match, m.Action, GraphiteMappingActionMap, GraphiteMappingActionDrop)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+const (
+	IngestionPipelineStepRelabel      = "relabel"
+	IngestionPipelineStepDropByValue  = "drop_by_value"
+	IngestionPipelineStepDropByAge    = "drop_by_age"
+	IngestionPipelineStepRenameMetric = "rename_metric"
+	IngestionPipelineStepAddLabels    = "add_labels"
+)
+
+// IngestionPipelineStepTypes are the supported types of ingestion pipeline steps.
+var IngestionPipelineStepTypes = []string{
+	IngestionPipelineStepRelabel,
+	IngestionPipelineStepDropByValue,
+	IngestionPipelineStepDropByAge,
+	IngestionPipelineStepRenameMetric,
+	IngestionPipelineStepAddLabels,
+}
+
+// IngestionPipelineStep is a step of the pipeline transforming the series ingested by a tenant in the
+// distributors. Which fields apply depends on the type of the step.
+type IngestionPipelineStep struct {
+	Type string `yaml:"type" json:"type"`
+	// Match is a series selector restricting the step to the matching series. The step applies to all
+	// series when empty.
+	Match string `yaml:"match,omitempty" json:"match,omitempty"`
+
+	// relabel
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty" json:"relabel_configs,omitempty"`
+
+	// drop_by_value
+	MinValue *float64 `yaml:"min_value,omitempty" json:"min_value,omitempty"`
+	MaxValue *float64 `yaml:"max_value,omitempty" json:"max_value,omitempty"`
+
+	// drop_by_age
+	MaxAge model.Duration `yaml:"max_age,omitempty" json:"max_age,omitempty"`
+
+	// rename_metric
+	NameRegex string `yaml:"name_regex,omitempty" json:"name_regex,omitempty"`
+	Name      string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// add_labels
+	Labels    map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	SourceIPs []string          `yaml:"source_ips,omitempty" json:"source_ips,omitempty"`
+	Tokens    []flagext.Secret  `yaml:"tokens,omitempty" json:"tokens,omitempty"`
+}
+
+// Validate returns an error if the step is invalid.
+func (s *IngestionPipelineStep) Validate() error {
+	if s == nil {
+		return fmt.Errorf("invalid ingestion_pipeline")
+	}
+	if _, err := s.Matchers(); err != nil {
+		return s.errorf("invalid match: %w", err)
+	}
+
+	switch s.Type {
+	case IngestionPipelineStepRelabel:
+		if len(s.RelabelConfigs) == 0 {
+			return s.errorf("relabel_configs is required")
+		}
+		for _, cfg := range s.RelabelConfigs {
+			if cfg == nil {
+				return s.errorf("invalid relabel_configs")
+			}
+		}
+	case IngestionPipelineStepDropByValue:
+		if s.MinValue == nil && s.MaxValue == nil {
+			return s.errorf("min_value or max_value is required")
+		}
+		if s.MinValue != nil && s.MaxValue != nil && *s.MinValue > *s.MaxValue {
+			return s.errorf("min_value must be lower than or equal to max_value")
+		}
+	case IngestionPipelineStepDropByAge:
+		if s.MaxAge <= 0 {
+			return s.errorf("max_age must be greater than 0")
+		}
+	case IngestionPipelineStepRenameMetric:
+		if s.NameRegex == "" || s.Name == "" {
+			return s.errorf("name_regex and name are required")
+		}
+		if _, err := regexp.Compile(s.NameRegex); err != nil {
+			return s.errorf("invalid name_regex: %w", err)
+		}
+	case IngestionPipelineStepAddLabels:
+		if len(s.Labels) == 0 {
+			return s.errorf("labels is required")
+		}
+		for name := range s.Labels {
+			if name == labels.MetricName || !model.LabelName(name).IsValid() {
+				return s.errorf("invalid label name %q", name)
+			}
+		}
+		if _, err := s.SourceIPPrefixes(); err != nil {
+			return s.errorf("invalid source_ips: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid ingestion pipeline step: unsupported type %q (supported values: %s)", s.Type, strings.Join(IngestionPipelineStepTypes, ", "))
+	}
+
+	return nil
+}
+
+// Matchers returns the matchers of the series selector of the step, or nil if the step applies to all series.
+func (s *IngestionPipelineStep) Matchers() ([]*labels.Matcher, error) {
+	if s.Match == "" {
+		return nil, nil
+	}
+	return parser.ParseMetricSelector(s.Match)
+}
+
+// SourceIPPrefixes returns the parsed source_ips of the step. Addresses without a prefix length match a
+// single address.
+func (s *IngestionPipelineStep) SourceIPPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(s.SourceIPs))
+	for _, ip := range s.SourceIPs {
+		if !strings.Contains(ip, "/") {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(ip)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func (s *IngestionPipelineStep) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid %s ingestion pipeline step: %w", s.Type, fmt.Errorf(format, args...))
+}
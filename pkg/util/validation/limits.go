@@ -52,12 +52,14 @@ const (
 	MaxSeriesQueryLimitFlag                   = "querier.max-series-query-limit"
 	MaxTotalQueryLengthFlag                   = "query-frontend.max-total-query-length"
 	MaxQueryExpressionSizeBytesFlag           = "query-frontend.max-query-expression-size-bytes"
+	MaxEstimatedQueryCostFlag                 = "query-frontend.max-estimated-query-cost"
 	RequestRateFlag                           = "distributor.request-rate-limit"
 	RequestBurstSizeFlag                      = "distributor.request-burst-size"
 	IngestionRateFlag                         = "distributor.ingestion-rate-limit"
 	IngestionBurstSizeFlag                    = "distributor.ingestion-burst-size"
 	IngestionBurstFactorFlag                  = "distributor.ingestion-burst-factor"
 	HATrackerMaxClustersFlag                  = "distributor.ha-tracker.max-clusters"
+	HADeduplicationModeFlag                   = "distributor.ha-tracker.deduplication-mode"
 	resultsCacheTTLFlag                       = "query-frontend.results-cache-ttl"
 	resultsCacheTTLForOutOfOrderWindowFlag    = "query-frontend.results-cache-ttl-for-out-of-order-time-window"
 	alignQueriesWithStepFlag                  = "query-frontend.align-queries-with-step"
@@ -71,8 +73,21 @@ const (
 	MinCompactorPartialBlockDeletionDelay = 4 * time.Hour
 )
 
+const (
+	// HADeduplicationModeReplica accepts the samples of the replica elected by the HA tracker only.
+	HADeduplicationModeReplica = "replica"
+	// HADeduplicationModeSample accepts the samples of all the HA replicas, and merges them in the ingesters.
+	HADeduplicationModeSample = "sample"
+)
+
+// HADeduplicationModes are the supported HA deduplication modes.
+var HADeduplicationModes = []string{HADeduplicationModeReplica, HADeduplicationModeSample}
+
 var (
 	errInvalidIngestStorageReadConsistency         = fmt.Errorf("invalid ingest storage read consistency (supported values: %s)", strings.Join(api.ReadConsistencies, ", "))
+	errInvalidHADeduplicationMode                  = fmt.Errorf("invalid value for -%s (supported values: %s)", HADeduplicationModeFlag, strings.Join(HADeduplicationModes, ", "))
+	errWriteBufferMaxAgeOutOfOrder                 = errors.New("-distributor.write-buffer.max-size-bytes requires -distributor.write-buffer.max-age to be greater than 0 and lower than or equal to -ingester.out-of-order-time-window, because the buffered write requests are replayed after the newer samples sent through other distributors")
+	errHASampleDeduplicationWithOutOfOrder         = fmt.Errorf("-%s=%s requires the out-of-order time window to be disabled, because the samples of the HA replicas would be interleaved in their series", HADeduplicationModeFlag, HADeduplicationModeSample)
 	errInvalidMaxEstimatedChunksPerQueryMultiplier = errors.New("invalid value for -" + MaxEstimatedChunksPerQueryMultiplierFlag + ": must be 0 or greater than or equal to 1")
 	errCostAttributionLabelsLimitExceeded          = errors.New("invalid value for -" + costAttributionLabelsFlag + ": exceeds the limit defined by -" + maxCostAttributionLabelsPerUserFlag)
 	errInvalidMaxCostAttributionLabelsPerUser      = errors.New("invalid value for -" + maxCostAttributionLabelsPerUserFlag + ": must be less than or equal to 4")
@@ -116,6 +131,8 @@ type Limits struct {
 	HAClusterLabel                              string              `yaml:"ha_cluster_label" json:"ha_cluster_label"`
 	HAReplicaLabel                              string              `yaml:"ha_replica_label" json:"ha_replica_label"`
 	HAMaxClusters                               int                 `yaml:"ha_max_clusters" json:"ha_max_clusters"`
+	HADeduplicationMode                         string              `yaml:"ha_deduplication_mode" json:"ha_deduplication_mode" category:"experimental"`
+	HAReplicaPriorities                         map[string][]string `yaml:"ha_replica_priorities,omitempty" json:"ha_replica_priorities,omitempty" doc:"nocli|description=Replica priorities of each HA cluster, keyed by cluster name, with the replicas listed from the most to the least preferred. A replica with a higher priority than the elected replica of its cluster is elected once it sends samples, without waiting for the failover timeout. Replicas which aren't listed have the lowest priority." category:"experimental"`
 	DropLabels                                  flagext.StringSlice `yaml:"drop_labels" json:"drop_labels" category:"advanced"`
 	MaxLabelNameLength                          int                 `yaml:"max_label_name_length" json:"max_label_name_length"`
 	MaxLabelValueLength                         int                 `yaml:"max_label_value_length" json:"max_label_value_length"`
@@ -133,6 +150,8 @@ type Limits struct {
 	MetricRelabelingEnabled                     bool                `yaml:"metric_relabeling_enabled" json:"metric_relabeling_enabled" category:"experimental"`
 	ServiceOverloadStatusCodeOnRateLimitEnabled bool                `yaml:"service_overload_status_code_on_rate_limit_enabled" json:"service_overload_status_code_on_rate_limit_enabled" category:"experimental"`
 	IngestionArtificialDelay                    model.Duration      `yaml:"ingestion_artificial_delay" json:"ingestion_artificial_delay" category:"experimental" doc:"hidden"`
+	WriteBufferMaxSizeBytes                     int                 `yaml:"write_buffer_max_size_bytes" json:"write_buffer_max_size_bytes" category:"experimental"`
+	WriteBufferMaxAge                           model.Duration      `yaml:"write_buffer_max_age" json:"write_buffer_max_age" category:"experimental"`
 	// Ingester enforced limits.
 	// Series
 	MaxGlobalSeriesPerUser   int `yaml:"max_global_series_per_user" json:"max_global_series_per_user"`
@@ -186,8 +205,11 @@ type Limits struct {
 	ResultsCacheTTLForErrors               model.Duration         `yaml:"results_cache_ttl_for_errors" json:"results_cache_ttl_for_errors"`
 	ResultsCacheForUnalignedQueryEnabled   bool                   `yaml:"cache_unaligned_requests" json:"cache_unaligned_requests" category:"advanced"`
 	MaxQueryExpressionSizeBytes            int                    `yaml:"max_query_expression_size_bytes" json:"max_query_expression_size_bytes"`
+	MaxEstimatedQueryCost                  int                    `yaml:"max_estimated_query_cost" json:"max_estimated_query_cost" category:"experimental"`
+	LowPriorityEstimatedQueryCost          int                    `yaml:"low_priority_estimated_query_cost" json:"low_priority_estimated_query_cost" category:"experimental"`
 	BlockedQueries                         []*BlockedQuery        `yaml:"blocked_queries,omitempty" json:"blocked_queries,omitempty" doc:"nocli|description=List of queries to block." category:"experimental"`
 	BlockedRequests                        []*BlockedRequest      `yaml:"blocked_requests,omitempty" json:"blocked_requests,omitempty" doc:"nocli|description=List of http requests to block." category:"experimental"`
+	QueryRewrites                          []*QueryRewrite        `yaml:"query_rewrites,omitempty" json:"query_rewrites,omitempty" doc:"nocli|description=List of rules to rewrite queries with." category:"experimental"`
 	AlignQueriesWithStep                   bool                   `yaml:"align_queries_with_step" json:"align_queries_with_step"`
 	EnabledPromQLExperimentalFunctions     flagext.StringSliceCSV `yaml:"enabled_promql_experimental_functions" json:"enabled_promql_experimental_functions" category:"experimental"`
 	Prom2RangeCompat                       bool                   `yaml:"prom2_range_compat" json:"prom2_range_compat" category:"experimental"`
@@ -222,18 +244,23 @@ type Limits struct {
 	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
 
 	// Compactor.
-	CompactorBlocksRetentionPeriod        model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
-	CompactorSplitAndMergeShards          int            `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
-	CompactorSplitGroups                  int            `yaml:"compactor_split_groups" json:"compactor_split_groups"`
-	CompactorTenantShardSize              int            `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
-	CompactorPartialBlockDeletionDelay    model.Duration `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
-	CompactorBlockUploadEnabled           bool           `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
-	CompactorBlockUploadValidationEnabled bool           `yaml:"compactor_block_upload_validation_enabled" json:"compactor_block_upload_validation_enabled"`
-	CompactorBlockUploadVerifyChunks      bool           `yaml:"compactor_block_upload_verify_chunks" json:"compactor_block_upload_verify_chunks"`
-	CompactorBlockUploadMaxBlockSizeBytes int64          `yaml:"compactor_block_upload_max_block_size_bytes" json:"compactor_block_upload_max_block_size_bytes" category:"advanced"`
-	CompactorInMemoryTenantMetaCacheSize  int            `yaml:"compactor_in_memory_tenant_meta_cache_size" json:"compactor_in_memory_tenant_meta_cache_size" category:"experimental" doc:"hidden"`
-	CompactorMaxLookback                  model.Duration `yaml:"compactor_max_lookback" json:"compactor_max_lookback" category:"experimental"`
-	CompactorMaxPerBlockUploadConcurrency int            `yaml:"compactor_max_per_block_upload_concurrency" json:"compactor_max_per_block_upload_concurrency" category:"advanced"`
+	CompactorBlocksRetentionPeriod        model.Duration         `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
+	CompactorSplitAndMergeShards          int                    `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
+	CompactorSplitGroups                  int                    `yaml:"compactor_split_groups" json:"compactor_split_groups"`
+	CompactorTenantShardSize              int                    `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
+	CompactorPartialBlockDeletionDelay    model.Duration         `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
+	CompactorBlockUploadEnabled           bool                   `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorBlockUploadValidationEnabled bool                   `yaml:"compactor_block_upload_validation_enabled" json:"compactor_block_upload_validation_enabled"`
+	CompactorBlockUploadVerifyChunks      bool                   `yaml:"compactor_block_upload_verify_chunks" json:"compactor_block_upload_verify_chunks"`
+	CompactorBlockUploadMaxBlockSizeBytes int64                  `yaml:"compactor_block_upload_max_block_size_bytes" json:"compactor_block_upload_max_block_size_bytes" category:"advanced"`
+	CompactorInMemoryTenantMetaCacheSize  int                    `yaml:"compactor_in_memory_tenant_meta_cache_size" json:"compactor_in_memory_tenant_meta_cache_size" category:"experimental" doc:"hidden"`
+	CompactorMaxLookback                  model.Duration         `yaml:"compactor_max_lookback" json:"compactor_max_lookback" category:"experimental"`
+	CompactorMaxPerBlockUploadConcurrency int                    `yaml:"compactor_max_per_block_upload_concurrency" json:"compactor_max_per_block_upload_concurrency" category:"advanced"`
+	CompactorDownsamplingEnabled          bool                   `yaml:"compactor_downsampling_enabled" json:"compactor_downsampling_enabled" category:"experimental"`
+	CompactorRawBlocksRetentionPeriod     model.Duration         `yaml:"compactor_raw_blocks_retention_period" json:"compactor_raw_blocks_retention_period" category:"experimental"`
+	Compactor5mBlocksRetentionPeriod      model.Duration         `yaml:"compactor_5m_blocks_retention_period" json:"compactor_5m_blocks_retention_period" category:"experimental"`
+	Compactor1hBlocksRetentionPeriod      model.Duration         `yaml:"compactor_1h_blocks_retention_period" json:"compactor_1h_blocks_retention_period" category:"experimental"`
+	CompactorSeriesRetentionRules         []*SeriesRetentionRule `yaml:"compactor_series_retention_rules,omitempty" json:"compactor_series_retention_rules,omitempty" doc:"nocli|description=List of retention periods applied to the series matching a series selector. Queriers and rulers hide the samples older than the retention period of a matching rule, and the compactor deletes them once a block ends before the retention boundary. The retention period of a rule can only be shorter than the retention period of the tenant, because blocks are deleted once they exceed the retention period of the tenant." category:"experimental"`
 
 	// This config doesn't have a CLI flag registered here because they're registered in
 	// their own original config struct.
@@ -267,6 +294,17 @@ type Limits struct {
 	OTelCreatedTimestampZeroIngestionEnabled bool                   `yaml:"otel_created_timestamp_zero_ingestion_enabled" json:"otel_created_timestamp_zero_ingestion_enabled" category:"experimental"`
 	PromoteOTelResourceAttributes            flagext.StringSliceCSV `yaml:"promote_otel_resource_attributes" json:"promote_otel_resource_attributes" category:"experimental"`
 	OTelKeepIdentifyingResourceAttributes    bool                   `yaml:"otel_keep_identifying_resource_attributes" json:"otel_keep_identifying_resource_attributes" category:"experimental"`
+	OTelDeltaToCumulativeEnabled             bool                   `yaml:"otel_delta_to_cumulative_enabled" json:"otel_delta_to_cumulative_enabled" category:"experimental"`
+
+	// Prometheus remote-write 2.0
+	RemoteWrite2CreatedTimestampZeroIngestionEnabled bool `yaml:"remote_write2_created_timestamp_zero_ingestion_enabled" json:"remote_write2_created_timestamp_zero_ingestion_enabled" category:"experimental"`
+
+	// Ingestion pipeline
+	IngestionPipeline []*IngestionPipelineStep `yaml:"ingestion_pipeline,omitempty" json:"ingestion_pipeline,omitempty" doc:"nocli|description=List of steps transforming the series ingested by the tenant in the distributors, after the HA deduplication and metric_relabel_configs and before the validation. Supported step types: relabel, drop_by_value, drop_by_age, rename_metric and add_labels. Samples dropped by a step are reported with a discard reason specific to the type of the step." category:"experimental"`
+	AggregationRules  []*AggregationRule       `yaml:"aggregation_rules,omitempty" json:"aggregation_rules,omitempty" doc:"nocli|description=List of rules aggregating the series ingested by the tenant over fixed windows in the distributors, after the ingestion pipeline. The output series of a rule are named after the input series, the interval, the grouping labels and the output, for example foo:1m_without_pod_sum, and have a sample at the end of each window, ingested an interval after the window ended. Each distributor aggregates the series it receives, and adds the aggregator label with its aggregator name to the output series, so outputs are only exact for the groups whose series are all sent to the same distributor. Use the total output for counters, which accumulates the increases of each input series and doesn't drop when a series moves to another distributor. Input series are dropped unless keep_input is set." category:"experimental"`
+
+	// Graphite
+	GraphiteMappings []*GraphiteMapping `yaml:"graphite_mappings,omitempty" json:"graphite_mappings,omitempty" doc:"nocli|description=List of rules mapping dotted Graphite metric names ingested through the Graphite endpoints to Prometheus metric names and labels. The first matching rule applies. Graphite metrics not matching any rule are ingested with their name converted to a valid Prometheus metric name." category:"experimental"`
 
 	// Ingest storage.
 	IngestStorageReadConsistency       string `yaml:"ingest_storage_read_consistency" json:"ingest_storage_read_consistency" category:"experimental"`
@@ -287,6 +325,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&l.HAClusterLabel, "distributor.ha-tracker.cluster", "cluster", "Prometheus label to look for in samples to identify a Prometheus HA cluster.")
 	f.StringVar(&l.HAReplicaLabel, "distributor.ha-tracker.replica", "__replica__", "Prometheus label to look for in samples to identify a Prometheus HA replica.")
 	f.IntVar(&l.HAMaxClusters, HATrackerMaxClustersFlag, 100, "Maximum number of clusters that HA tracker will keep track of for a single tenant. 0 to disable the limit.")
+	f.StringVar(&l.HADeduplicationMode, HADeduplicationModeFlag, HADeduplicationModeReplica, fmt.Sprintf("How samples from Prometheus HA replicas are deduplicated. %q accepts the samples of the replica elected by the HA tracker only. %q accepts the samples of all the replicas and merges them in the ingesters, keeping the first sample ingested for each series and timestamp, and dropping the samples older than the latest sample of their series. %q requires the out-of-order time window to be disabled, because the replicas scrape at different offsets, so their samples would be interleaved in their series.", HADeduplicationModeReplica, HADeduplicationModeSample, HADeduplicationModeSample))
 	f.Var(&l.DropLabels, "distributor.drop-label", "This flag can be used to specify label names that to drop during sample ingestion within the distributor and can be repeated in order to drop multiple labels.")
 	f.IntVar(&l.MaxLabelNameLength, MaxLabelNameLengthFlag, 1024, "Maximum length accepted for label names")
 	f.IntVar(&l.MaxLabelValueLength, MaxLabelValueLengthFlag, 2048, "Maximum length accepted for label value. This setting also applies to the metric name")
@@ -302,10 +341,15 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&l.EnforceMetadataMetricName, "validation.enforce-metadata-metric-name", true, "Enforce every metadata has a metric name.")
 	f.BoolVar(&l.MetricRelabelingEnabled, "distributor.metric-relabeling-enabled", true, "Enable metric relabeling for the tenant. This configuration option can be used to forcefully disable metric relabeling on a per-tenant basis.")
 	f.BoolVar(&l.ServiceOverloadStatusCodeOnRateLimitEnabled, "distributor.service-overload-status-code-on-rate-limit-enabled", false, "If enabled, rate limit errors will be reported to the client with HTTP status code 529 (Service is overloaded). If disabled, status code 429 (Too Many Requests) is used. Enabling -distributor.retry-after-header.enabled before utilizing this option is strongly recommended as it helps prevent premature request retries by the client.")
+	f.IntVar(&l.WriteBufferMaxSizeBytes, "distributor.write-buffer.max-size-bytes", 0, "Maximum size in bytes of the write requests of a tenant buffered on the local disk of each distributor while the ingesters are unavailable, when the write buffer is enabled. Write requests which don't fit in the buffer are rejected while the ingesters are unavailable, and held until the buffered write requests are replayed otherwise, so that they aren't ingested before them. Each distributor replays its buffered write requests independently, after the newer samples sent through other distributors, so a value greater than 0 requires the out-of-order time window to be greater than or equal to -distributor.write-buffer.max-age. 0 to disable the buffering for the tenant.")
+	_ = l.WriteBufferMaxAge.Set("1h")
+	f.Var(&l.WriteBufferMaxAge, "distributor.write-buffer.max-age", "Maximum age of the write requests of a tenant buffered by the write buffer. Older write requests are dropped instead of being replayed to the ingesters. It must be greater than 0 and lower than or equal to the out-of-order time window when the buffering is enabled for the tenant.")
 	f.BoolVar(&l.OTelMetricSuffixesEnabled, "distributor.otel-metric-suffixes-enabled", false, "Whether to enable automatic suffixes to names of metrics ingested through OTLP.")
 	f.BoolVar(&l.OTelCreatedTimestampZeroIngestionEnabled, "distributor.otel-created-timestamp-zero-ingestion-enabled", false, "Whether to enable translation of OTel start timestamps to Prometheus zero samples in the OTLP endpoint.")
 	f.Var(&l.PromoteOTelResourceAttributes, "distributor.otel-promote-resource-attributes", "Optionally specify OTel resource attributes to promote to labels.")
 	f.BoolVar(&l.OTelKeepIdentifyingResourceAttributes, "distributor.otel-keep-identifying-resource-attributes", false, "Whether to keep identifying OTel resource attributes in the target_info metric on top of converting to job and instance labels.")
+	f.BoolVar(&l.OTelDeltaToCumulativeEnabled, "distributor.otel-delta-to-cumulative-enabled", false, "Whether to ingest OTel sums and histograms with delta temporality, which are otherwise dropped. Delta samples are accumulated into cumulative samples by the ingesters. Each ingester accumulates the samples it receives independently, so the cumulative samples of an ingester that misses a write request permanently diverge from the ones of the other ingesters holding the series, and queries may return different values depending on the ingesters they read from.")
+	f.BoolVar(&l.RemoteWrite2CreatedTimestampZeroIngestionEnabled, "distributor.remote-write2-created-timestamp-zero-ingestion-enabled", false, "Whether to enable translation of the created timestamps of Prometheus remote-write 2.0 series to zero samples.")
 	f.Var(&l.IngestionArtificialDelay, "distributor.ingestion-artificial-delay", "Target ingestion delay. If set to a non-zero value, the distributor will artificially delay ingestion time-frame by the specified duration by computing the difference between actual ingestion and the target. There is no delay on actual ingestion of samples, it is only the response back to the client.")
 
 	f.IntVar(&l.MaxGlobalSeriesPerUser, MaxSeriesPerUserFlag, 150000, "The maximum number of in-memory series per tenant, across the cluster before replication. 0 to disable.")
@@ -385,6 +429,10 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.CompactorInMemoryTenantMetaCacheSize, "compactor.in-memory-tenant-meta-cache-size", 0, "Size of per-tenant in-memory cache for parsed meta.json files. This is useful when meta.json files are big and parsing is expensive. Small meta.json files are not cached. 0 means this cache is disabled.")
 	f.Var(&l.CompactorMaxLookback, "compactor.max-lookback", "Blocks uploaded before the lookback aren't considered in compactor cycles. If set, this value should be larger than all values in `-blocks-storage.tsdb.block-ranges-period`. A value of 0s means that all blocks are considered regardless of their upload time.")
 	f.IntVar(&l.CompactorMaxPerBlockUploadConcurrency, "compactor.max-per-block-upload-concurrency", 8, "Maximum number of TSDB segment files that the compactor can upload concurrently per block.")
+	f.BoolVar(&l.CompactorDownsamplingEnabled, "compactor.downsampling-enabled", false, "Enable downsampling of the tenant's blocks. Compacted blocks spanning the largest block range are downsampled to 5m resolution blocks, which are downsampled to 1h resolution blocks. Queriers read downsampled blocks when the query step and range are large enough.")
+	f.Var(&l.CompactorRawBlocksRetentionPeriod, "compactor.raw-blocks-retention-period", "Delete raw blocks containing samples older than the specified retention period. 0 to use -compactor.blocks-retention-period.")
+	f.Var(&l.Compactor5mBlocksRetentionPeriod, "compactor.5m-blocks-retention-period", "Delete 5m resolution downsampled blocks containing samples older than the specified retention period. 0 to use -compactor.blocks-retention-period.")
+	f.Var(&l.Compactor1hBlocksRetentionPeriod, "compactor.1h-blocks-retention-period", "Delete 1h resolution downsampled blocks containing samples older than the specified retention period. 0 to use -compactor.blocks-retention-period.")
 
 	// Query-frontend.
 	f.Var(&l.MaxTotalQueryLength, MaxTotalQueryLengthFlag, "Limit the total query time range (end - start time). This limit is enforced in the query-frontend on the received instant, range or remote read query.")
@@ -398,6 +446,8 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.ResultsCacheTTLForErrors, "query-frontend.results-cache-ttl-for-errors", "Time to live duration for cached non-transient errors")
 	f.BoolVar(&l.ResultsCacheForUnalignedQueryEnabled, "query-frontend.cache-unaligned-requests", false, "Cache requests that are not step-aligned.")
 	f.IntVar(&l.MaxQueryExpressionSizeBytes, MaxQueryExpressionSizeBytesFlag, 0, "Max size of the raw query, in bytes. This limit is enforced by the query-frontend for instant, range and remote read queries. 0 to not apply a limit to the size of the query.")
+	f.IntVar(&l.MaxEstimatedQueryCost, MaxEstimatedQueryCostFlag, 0, "Maximum estimated cost of an instant or range query. The cost of a query is the estimated number of series it selects multiplied by the number of samples it reads or points it evaluates for each series, whichever is greater. Series estimates are based on previous executions of similar queries, so queries are only rejected once an estimate is available. This limit is enforced in the query-frontend, and requires -query-frontend.cache-results to be enabled. 0 to disable.")
+	f.IntVar(&l.LowPriorityEstimatedQueryCost, "query-frontend.low-priority-estimated-query-cost", 0, "Instant and range queries with an estimated cost greater than this are executed with low priority: the queries they are split and sharded into share a single query's parallelism (-querier.max-query-parallelism) with the split and sharded queries of all other low priority queries of the tenant, rather than each query having its own. This requires -query-frontend.cache-results to be enabled. 0 to disable.")
 	f.BoolVar(&l.AlignQueriesWithStep, alignQueriesWithStepFlag, false, "Mutate incoming queries to align their start and end with their step to improve result caching.")
 	f.Var(&l.EnabledPromQLExperimentalFunctions, "query-frontend.enabled-promql-experimental-functions", "Enable certain experimental PromQL functions, which are subject to being changed or removed at any time, on a per-tenant basis. Defaults to empty which means all experimental functions are disabled. Set to 'all' to enable all experimental functions.")
 	f.BoolVar(&l.Prom2RangeCompat, "query-frontend.prom2-range-compat", false, "Rewrite queries using the same range selector and resolution [X:X] which don't work in Prometheus 3.0 to a nearly identical form that works with Prometheus 3.0 semantics")
@@ -509,6 +559,36 @@ func (l *Limits) validate() error {
 		}
 	}
 
+	for _, s := range l.IngestionPipeline {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range l.AggregationRules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range l.CompactorSeriesRetentionRules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range l.QueryRewrites {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range l.GraphiteMappings {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if l.MaxEstimatedChunksPerQueryMultiplier < 1 && l.MaxEstimatedChunksPerQueryMultiplier != 0 {
 		return errInvalidMaxEstimatedChunksPerQueryMultiplier
 	}
@@ -517,6 +597,18 @@ func (l *Limits) validate() error {
 		return errInvalidIngestStorageReadConsistency
 	}
 
+	if !util.StringsContain(HADeduplicationModes, l.HADeduplicationMode) {
+		return errInvalidHADeduplicationMode
+	}
+
+	if l.WriteBufferMaxSizeBytes > 0 && (l.WriteBufferMaxAge <= 0 || l.WriteBufferMaxAge > l.OutOfOrderTimeWindow) {
+		return errWriteBufferMaxAgeOutOfOrder
+	}
+
+	if l.AcceptHASamples && l.HADeduplicationMode == HADeduplicationModeSample && l.OutOfOrderTimeWindow > 0 {
+		return errHASampleDeduplicationWithOutOfOrder
+	}
+
 	if len(l.CostAttributionLabels) > l.MaxCostAttributionLabelsPerUser {
 		return errCostAttributionLabelsLimitExceeded
 	}
@@ -616,11 +708,28 @@ func (o *Overrides) AcceptHASamples(userID string) bool {
 	return o.getOverridesForUser(userID).AcceptHASamples
 }
 
+// HADeduplicationMode returns how samples from Prometheus HA replicas are deduplicated.
+func (o *Overrides) HADeduplicationMode(userID string) string {
+	return o.getOverridesForUser(userID).HADeduplicationMode
+}
+
 // ServiceOverloadStatusCodeOnRateLimitEnabled return whether the distributor uses status code 529 instead of 429 when the rate limit is exceeded.
 func (o *Overrides) ServiceOverloadStatusCodeOnRateLimitEnabled(userID string) bool {
 	return o.getOverridesForUser(userID).ServiceOverloadStatusCodeOnRateLimitEnabled
 }
 
+// WriteBufferMaxSizeBytes returns the maximum size of the write requests of a tenant buffered by the distributor
+// write buffer. Zero means the write requests of the tenant aren't buffered.
+func (o *Overrides) WriteBufferMaxSizeBytes(userID string) int {
+	return o.getOverridesForUser(userID).WriteBufferMaxSizeBytes
+}
+
+// WriteBufferMaxAge returns the maximum age of the write requests of a tenant buffered by the distributor write
+// buffer. Zero means disabled.
+func (o *Overrides) WriteBufferMaxAge(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).WriteBufferMaxAge)
+}
+
 // HAClusterLabel returns the cluster label to look for when deciding whether to accept a sample from a Prometheus HA replica.
 func (o *Overrides) HAClusterLabel(userID string) string {
 	return o.getOverridesForUser(userID).HAClusterLabel
@@ -744,6 +853,17 @@ func (o *Overrides) MaxQueryExpressionSizeBytes(userID string) int {
 	return o.getOverridesForUser(userID).MaxQueryExpressionSizeBytes
 }
 
+// MaxEstimatedQueryCost returns the maximum estimated cost of a query. 0 means no limit.
+func (o *Overrides) MaxEstimatedQueryCost(userID string) int {
+	return o.getOverridesForUser(userID).MaxEstimatedQueryCost
+}
+
+// LowPriorityEstimatedQueryCost returns the estimated cost above which queries are executed with low priority.
+// 0 means queries are never executed with low priority.
+func (o *Overrides) LowPriorityEstimatedQueryCost(userID string) int {
+	return o.getOverridesForUser(userID).LowPriorityEstimatedQueryCost
+}
+
 // BlockedQueries returns the blocked queries.
 func (o *Overrides) BlockedQueries(userID string) []*BlockedQuery {
 	return o.getOverridesForUser(userID).BlockedQueries
@@ -754,6 +874,11 @@ func (o *Overrides) BlockedRequests(userID string) []*BlockedRequest {
 	return o.getOverridesForUser(userID).BlockedRequests
 }
 
+// QueryRewrites returns the rules to rewrite queries with.
+func (o *Overrides) QueryRewrites(userID string) []*QueryRewrite {
+	return o.getOverridesForUser(userID).QueryRewrites
+}
+
 // MaxLabelsQueryLength returns the limit of the length (in time) of a label names or values request.
 func (o *Overrides) MaxLabelsQueryLength(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxLabelsQueryLength)
@@ -932,6 +1057,34 @@ func (o *Overrides) CompactorBlocksRetentionPeriod(userID string) time.Duration
 	return time.Duration(o.getOverridesForUser(userID).CompactorBlocksRetentionPeriod)
 }
 
+// CompactorBlocksRetentionPeriodForResolution returns the retention period for a given user of the blocks
+// with the given downsampling resolution.
+func (o *Overrides) CompactorBlocksRetentionPeriodForResolution(userID string, resolution int64) time.Duration {
+	var retention model.Duration
+	switch resolution {
+	case block.ResolutionRaw:
+		retention = o.getOverridesForUser(userID).CompactorRawBlocksRetentionPeriod
+	case block.Resolution5m:
+		retention = o.getOverridesForUser(userID).Compactor5mBlocksRetentionPeriod
+	case block.Resolution1h:
+		retention = o.getOverridesForUser(userID).Compactor1hBlocksRetentionPeriod
+	}
+	if retention > 0 {
+		return time.Duration(retention)
+	}
+	return o.CompactorBlocksRetentionPeriod(userID)
+}
+
+// CompactorSeriesRetentionRules returns the retention periods applied to the series of a given user matching a series selector.
+func (o *Overrides) CompactorSeriesRetentionRules(userID string) []*SeriesRetentionRule {
+	return o.getOverridesForUser(userID).CompactorSeriesRetentionRules
+}
+
+// CompactorDownsamplingEnabled returns whether the blocks of a given user are downsampled.
+func (o *Overrides) CompactorDownsamplingEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).CompactorDownsamplingEnabled
+}
+
 // CompactorSplitAndMergeShards returns the number of shards to use when splitting blocks.
 func (o *Overrides) CompactorSplitAndMergeShards(userID string) int {
 	return o.getOverridesForUser(userID).CompactorSplitAndMergeShards
@@ -1066,6 +1219,11 @@ func (o *Overrides) MaxHAClusters(user string) int {
 	return o.getOverridesForUser(user).HAMaxClusters
 }
 
+// HAReplicaPriorities returns the replicas of each HA cluster of a user, from the most to the least preferred.
+func (o *Overrides) HAReplicaPriorities(user string) map[string][]string {
+	return o.getOverridesForUser(user).HAReplicaPriorities
+}
+
 // S3SSEType returns the per-tenant S3 SSE type.
 func (o *Overrides) S3SSEType(user string) string {
 	return o.getOverridesForUser(user).S3SSEType
@@ -1241,6 +1399,29 @@ func (o *Overrides) OTelKeepIdentifyingResourceAttributes(tenantID string) bool
 	return o.getOverridesForUser(tenantID).OTelKeepIdentifyingResourceAttributes
 }
 
+func (o *Overrides) OTelDeltaToCumulativeEnabled(tenantID string) bool {
+	return o.getOverridesForUser(tenantID).OTelDeltaToCumulativeEnabled
+}
+
+func (o *Overrides) RemoteWrite2CreatedTimestampZeroIngestionEnabled(tenantID string) bool {
+	return o.getOverridesForUser(tenantID).RemoteWrite2CreatedTimestampZeroIngestionEnabled
+}
+
+// IngestionPipeline returns the steps transforming the series ingested by the tenant.
+func (o *Overrides) IngestionPipeline(tenantID string) []*IngestionPipelineStep {
+	return o.getOverridesForUser(tenantID).IngestionPipeline
+}
+
+// AggregationRules returns the rules aggregating the series ingested by the tenant.
+func (o *Overrides) AggregationRules(tenantID string) []*AggregationRule {
+	return o.getOverridesForUser(tenantID).AggregationRules
+}
+
+// GraphiteMappings returns the rules mapping Graphite metric names to Prometheus metric names and labels.
+func (o *Overrides) GraphiteMappings(tenantID string) []*GraphiteMapping {
+	return o.getOverridesForUser(tenantID).GraphiteMappings
+}
+
 // DistributorIngestionArtificialDelay returns the artificial ingestion latency for a given use.
 func (o *Overrides) DistributorIngestionArtificialDelay(tenantID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(tenantID).IngestionArtificialDelay)
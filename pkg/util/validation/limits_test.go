@@ -1176,6 +1176,48 @@ metric_relabel_configs:
 			cfg:         `ingest_storage_read_consistency: xyz`,
 			expectedErr: errInvalidIngestStorageReadConsistency.Error(),
 		},
+		"should pass on ha_deduplication_mode = sample": {
+			cfg:         `ha_deduplication_mode: sample`,
+			expectedErr: "",
+		},
+		"should pass on ha_deduplication_mode = sample with out_of_order_time_window when HA samples aren't accepted": {
+			cfg: `
+ha_deduplication_mode: sample
+out_of_order_time_window: 10m`,
+			expectedErr: "",
+		},
+		"should fail on ha_deduplication_mode = sample with out_of_order_time_window": {
+			cfg: `
+accept_ha_samples: true
+ha_deduplication_mode: sample
+out_of_order_time_window: 10m`,
+			expectedErr: errHASampleDeduplicationWithOutOfOrder.Error(),
+		},
+		"should pass on write_buffer_max_size_bytes with write_buffer_max_age within out_of_order_time_window": {
+			cfg: `
+write_buffer_max_size_bytes: 1024
+write_buffer_max_age: 1h
+out_of_order_time_window: 1h`,
+			expectedErr: "",
+		},
+		"should fail on write_buffer_max_size_bytes with write_buffer_max_age greater than out_of_order_time_window": {
+			cfg: `
+write_buffer_max_size_bytes: 1024
+write_buffer_max_age: 1h
+out_of_order_time_window: 30m`,
+			expectedErr: errWriteBufferMaxAgeOutOfOrder.Error(),
+		},
+		"should fail on write_buffer_max_size_bytes without write_buffer_max_age": {
+			cfg: `
+write_buffer_max_size_bytes: 1024
+write_buffer_max_age: 0s
+out_of_order_time_window: 1h`,
+			expectedErr: errWriteBufferMaxAgeOutOfOrder.Error(),
+		},
+		"should fail on invalid ha_deduplication_mode": {
+			cfg:         `ha_deduplication_mode: xyz`,
+			expectedErr: errInvalidHADeduplicationMode.Error(),
+		},
 		"should fail when cost_attribution_labels exceed max_cost_attribution_labels_per_user": {
 			cfg: `
 cost_attribution_labels: label1, label2, label3,
@@ -1188,6 +1230,133 @@ cost_attribution_labels: label1, label2,
 max_cost_attribution_labels_per_user: 5`,
 			expectedErr: errInvalidMaxCostAttributionLabelsPerUser.Error(),
 		},
+		"should pass on valid ingestion_pipeline": {
+			cfg: `
+ingestion_pipeline:
+  - type: drop_by_value
+    match: '{__name__=~"temperature_.*"}'
+    min_value: -273.15
+  - type: add_labels
+    source_ips: [10.0.0.0/8, 192.168.1.1]
+    labels:
+      network: internal`,
+			expectedErr: "",
+		},
+		"should fail on unsupported ingestion_pipeline step type": {
+			cfg: `
+ingestion_pipeline:
+  - type: unknown`,
+			expectedErr: `invalid ingestion pipeline step: unsupported type "unknown"`,
+		},
+		"should fail on invalid ingestion_pipeline step match": {
+			cfg: `
+ingestion_pipeline:
+  - type: drop_by_age
+    match: '{'
+    max_age: 1h`,
+			expectedErr: "invalid drop_by_age ingestion pipeline step: invalid match",
+		},
+		"should fail on ingestion_pipeline add_labels step with invalid source IPs": {
+			cfg: `
+ingestion_pipeline:
+  - type: add_labels
+    source_ips: [10.0.0.0/33]
+    labels:
+      network: internal`,
+			expectedErr: "invalid add_labels ingestion pipeline step: invalid source_ips",
+		},
+		"should pass on valid aggregation_rules": {
+			cfg: `
+aggregation_rules:
+  - match: '{__name__=~"http_requests_.*"}'
+    interval: 1m
+    without: [pod, instance]
+    outputs: [sum, count_series, total]
+  - match: 'temperature'
+    interval: 5m
+    by: [room]
+    outputs: [avg, max]
+    keep_input: true`,
+			expectedErr: "",
+		},
+		"should fail on aggregation_rules without match": {
+			cfg: `
+aggregation_rules:
+  - interval: 1m
+    outputs: [sum]`,
+			expectedErr: "invalid aggregation rule: match is required",
+		},
+		"should fail on aggregation_rules with both by and without": {
+			cfg: `
+aggregation_rules:
+  - match: 'foo'
+    interval: 1m
+    by: [job]
+    without: [pod]
+    outputs: [sum]`,
+			expectedErr: `invalid aggregation rule "foo": by and without can't both be set`,
+		},
+		"should fail on aggregation_rules with unsupported output": {
+			cfg: `
+aggregation_rules:
+  - match: 'foo'
+    interval: 1m
+    outputs: [median]`,
+			expectedErr: `invalid aggregation rule "foo": unsupported output "median"`,
+		},
+		"should pass on valid compactor_series_retention_rules": {
+			cfg: `
+compactor_series_retention_rules:
+  - match: '{__name__=~"container_.*"}'
+    retention_period: 30d
+  - match: '{__name__=~"slo_.*", env="dev"}'
+    retention_period: 1y`,
+			expectedErr: "",
+		},
+		"should fail on compactor_series_retention_rules without match": {
+			cfg: `
+compactor_series_retention_rules:
+  - retention_period: 30d`,
+			expectedErr: "invalid series retention rule: match is required",
+		},
+		"should fail on compactor_series_retention_rules with invalid match": {
+			cfg: `
+compactor_series_retention_rules:
+  - match: '{__name__='
+    retention_period: 30d`,
+			expectedErr: `invalid series retention rule "{__name__=": invalid match`,
+		},
+		"should fail on compactor_series_retention_rules without retention_period": {
+			cfg: `
+compactor_series_retention_rules:
+  - match: 'foo'`,
+			expectedErr: `invalid series retention rule "foo": retention_period must be greater than 0`,
+		},
+		"should pass on valid query_rewrites": {
+			cfg: `
+query_rewrites:
+  - pattern: 'sum by (job) (rate(http_requests_total[5m]))'
+    replacement: 'job:http_requests_total:rate5m'
+  - pattern: 'rate\((\w+)\[5m\]\)'
+    regex: true
+    replacement: '${1}:rate5m'`,
+			expectedErr: "",
+		},
+		"should fail on query_rewrites with invalid regex pattern": {
+			cfg: `
+query_rewrites:
+  - pattern: '[a-9}'
+    regex: true
+    replacement: 'foo'`,
+			expectedErr: `invalid query rewrite rule "[a-9}": invalid pattern`,
+		},
+		"should fail on query_rewrites with invalid replacement": {
+			cfg: `
+query_rewrites:
+  - pattern: 'foo'
+    replacement: 'sum('`,
+			expectedErr: `invalid query rewrite rule "foo": invalid replacement`,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -1565,3 +1734,20 @@ func getDefaultLimits() Limits {
 	flagext.DefaultValues(&limits)
 	return limits
 }
+
+func TestIngestionPipelineTokensAreMasked(t *testing.T) {
+	var limits Limits
+	require.NoError(t, yaml.Unmarshal([]byte(`
+ingestion_pipeline:
+  - type: add_labels
+    tokens: [secret-token]
+    labels:
+      team: a`), &limits))
+	require.Len(t, limits.IngestionPipeline, 1)
+	require.Equal(t, []flagext.Secret{flagext.SecretWithValue("secret-token")}, limits.IngestionPipeline[0].Tokens)
+
+	val, err := yaml.Marshal(limits)
+	require.NoError(t, err)
+	require.NotContains(t, string(val), "secret-token")
+	require.Contains(t, string(val), "********")
+}
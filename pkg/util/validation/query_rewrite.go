@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+type QueryRewrite struct {
+	Pattern     string `yaml:"pattern"`
+	Regex       bool   `yaml:"regex"`
+	Replacement string `yaml:"replacement"`
+
+	// Set by Validate, so that rules are only compiled once when the limits are loaded.
+	regexp      *regexp.Regexp
+	pattern     parser.Expr
+	replacement parser.Expr
+}
+
+// Validate returns an error if the rule is invalid, and compiles it otherwise.
+func (r *QueryRewrite) Validate() error {
+	if r == nil {
+		return fmt.Errorf("invalid query_rewrites")
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("invalid query rewrite rule: pattern is required")
+	}
+
+	if r.Regex {
+		re, err := regexp.Compile("^(?:" + r.Pattern + ")$")
+		if err != nil {
+			return r.errorf("invalid pattern: %w", err)
+		}
+		r.regexp = re
+		return nil
+	}
+
+	pattern, err := parser.ParseExpr(r.Pattern)
+	if err != nil {
+		return r.errorf("invalid pattern: %w", err)
+	}
+	replacement, err := parser.ParseExpr(r.Replacement)
+	if err != nil {
+		return r.errorf("invalid replacement: %w", err)
+	}
+	r.pattern = pattern
+	r.replacement = replacement
+	return nil
+}
+
+// Regexp returns the compiled pattern of a regex rule, anchored to match the whole query.
+// It returns nil if the rule isn't a regex rule or hasn't been validated.
+func (r *QueryRewrite) Regexp() *regexp.Regexp {
+	return r.regexp
+}
+
+// Expressions returns the parsed pattern and replacement of a rule that isn't a regex rule.
+// They are nil if the rule is a regex rule or hasn't been validated. Callers must not modify them.
+func (r *QueryRewrite) Expressions() (pattern, replacement parser.Expr) {
+	return r.pattern, r.replacement
+}
+
+func (r *QueryRewrite) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid query rewrite rule %q: %w", r.Pattern, fmt.Errorf(format, args...))
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// SeriesRetentionRule is a retention period applied to the series of a tenant matching a series selector.
+type SeriesRetentionRule struct {
+	// Match is the series selector of the series the rule applies to.
+	Match           string         `yaml:"match" json:"match"`
+	RetentionPeriod model.Duration `yaml:"retention_period" json:"retention_period"`
+}
+
+// Validate returns an error if the rule is invalid.
+func (r *SeriesRetentionRule) Validate() error {
+	if r == nil {
+		return fmt.Errorf("invalid compactor_series_retention_rules")
+	}
+	if r.Match == "" {
+		return fmt.Errorf("invalid series retention rule: match is required")
+	}
+	if _, err := r.Matchers(); err != nil {
+		return r.errorf("invalid match: %w", err)
+	}
+	if r.RetentionPeriod <= 0 {
+		return r.errorf("retention_period must be greater than 0")
+	}
+	return nil
+}
+
+// Matchers returns the matchers of the series selector of the rule.
+func (r *SeriesRetentionRule) Matchers() ([]*labels.Matcher, error) {
+	return (&IngestionPipelineStep{Match: r.Match}).Matchers()
+}
+
+func (r *SeriesRetentionRule) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid series retention rule %q: %w", r.Match, fmt.Errorf(format, args...))
+}
@@ -369,8 +369,20 @@ func getFieldCustomType(t reflect.Type) (string, bool) {
 		return "blocked_queries_config...", true
 	case reflect.TypeOf([]*validation.BlockedRequest{}).String():
 		return "blocked_requests_config...", true
+	case reflect.TypeOf([]*validation.QueryRewrite{}).String():
+		return "query_rewrites_config...", true
+	case reflect.TypeOf([]*validation.GraphiteMapping{}).String():
+		return "graphite_mappings_config...", true
+	case reflect.TypeOf([]*validation.IngestionPipelineStep{}).String():
+		return "ingestion_pipeline_config...", true
+	case reflect.TypeOf([]*validation.AggregationRule{}).String():
+		return "aggregation_rules_config...", true
+	case reflect.TypeOf([]*validation.SeriesRetentionRule{}).String():
+		return "series_retention_rules_config...", true
 	case reflect.TypeOf(asmodel.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
+	case reflect.TypeOf(map[string][]string{}).String():
+		return "map of cluster (string) to replicas (list of strings)", true
 	default:
 		return "", false
 	}
@@ -459,8 +471,20 @@ func getCustomFieldType(t reflect.Type) (string, bool) {
 		return "blocked_queries_config...", true
 	case reflect.TypeOf([]*validation.BlockedRequest{}).String():
 		return "blocked_requests_config...", true
+	case reflect.TypeOf([]*validation.QueryRewrite{}).String():
+		return "query_rewrites_config...", true
+	case reflect.TypeOf([]*validation.GraphiteMapping{}).String():
+		return "graphite_mappings_config...", true
+	case reflect.TypeOf([]*validation.IngestionPipelineStep{}).String():
+		return "ingestion_pipeline_config...", true
+	case reflect.TypeOf([]*validation.AggregationRule{}).String():
+		return "aggregation_rules_config...", true
+	case reflect.TypeOf([]*validation.SeriesRetentionRule{}).String():
+		return "series_retention_rules_config...", true
 	case reflect.TypeOf(asmodel.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
+	case reflect.TypeOf(map[string][]string{}).String():
+		return "map of cluster (string) to replicas (list of strings)", true
 	default:
 		return "", false
 	}
@@ -494,6 +518,18 @@ func ReflectType(typ string) reflect.Type {
 		return reflect.TypeOf([]*validation.BlockedQuery{})
 	case "blocked_requests_config...":
 		return reflect.TypeOf([]*validation.BlockedRequest{})
+	case "query_rewrites_config...":
+		return reflect.TypeOf([]*validation.QueryRewrite{})
+	case "graphite_mappings_config...":
+		return reflect.TypeOf([]*validation.GraphiteMapping{})
+	case "ingestion_pipeline_config...":
+		return reflect.TypeOf([]*validation.IngestionPipelineStep{})
+	case "aggregation_rules_config...":
+		return reflect.TypeOf([]*validation.AggregationRule{})
+	case "series_retention_rules_config...":
+		return reflect.TypeOf([]*validation.SeriesRetentionRule{})
+	case "map of cluster (string) to replicas (list of strings)":
+		return reflect.TypeOf(map[string][]string{})
 	case "map of string to float64":
 		return reflect.TypeOf(flagext.LimitsMap[float64]{})
 	case "map of string to int":